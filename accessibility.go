@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// keyboardList 支持用Delete键删除条目的列表。
+// 方向键移动焦点、空格选中条目由 widget.List 处理
+type keyboardList struct {
+	widget.List
+	onDelete func() // 按下Delete或Backspace时调用，此时焦点所在的条目已被选中
+}
+
+// 创建支持键盘删除的列表
+func newKeyboardList(length func() int, createItem func() fyne.CanvasObject, updateItem func(widget.ListItemID, fyne.CanvasObject)) *keyboardList {
+	list := &keyboardList{}
+	list.Length = length
+	list.CreateItem = createItem
+	list.UpdateItem = updateItem
+	list.ExtendBaseWidget(list)
+	return list
+}
+
+// TypedKey 处理键盘输入，Delete/Backspace 删除焦点所在的条目
+func (l *keyboardList) TypedKey(event *fyne.KeyEvent) {
+	switch event.Name {
+	case fyne.KeyDelete, fyne.KeyBackspace:
+		if l.onDelete == nil || l.Length == nil || l.Length() == 0 {
+			return
+		}
+		// 先选中焦点所在的条目（等同于按空格），再删除选中的条目
+		l.List.TypedKey(&fyne.KeyEvent{Name: fyne.KeySpace})
+		l.onDelete()
+	default:
+		l.List.TypedKey(event)
+	}
+}
+
+// 显示对话框：打开时把焦点放到initialFocus（可为nil），关闭后焦点回到打开对话框前的控件。
+// 对话框显示期间Tab只在对话框内部切换
+func (fo *FileOrganizer) showDialog(d dialog.Dialog, initialFocus fyne.Focusable) {
+	canvas := fo.Window.Canvas()
+	previous := canvas.Focused()
+	d.SetOnClosed(func() {
+		if previous != nil {
+			canvas.Focus(previous)
+		}
+	})
+	d.Show()
+	if initialFocus != nil {
+		canvas.Focus(initialFocus)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 只用键盘：方向键移动到条目，Delete 或 Backspace 删除焦点所在的条目
+func TestKeyboardListDelete(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []string
+		keys  []fyne.KeyName
+		want  []string
+	}{
+		{"删除第一个", []string{"a", "b", "c"}, []fyne.KeyName{fyne.KeyDelete}, []string{"b", "c"}},
+		{"移动后删除", []string{"a", "b", "c"}, []fyne.KeyName{fyne.KeyDown, fyne.KeyDown, fyne.KeyDelete}, []string{"a", "b"}},
+		{"Backspace", []string{"a", "b", "c"}, []fyne.KeyName{fyne.KeyDown, fyne.KeyBackspace}, []string{"a", "c"}},
+		{"连续删除", []string{"a", "b", "c"}, []fyne.KeyName{fyne.KeyDelete, fyne.KeyDelete}, []string{"c"}},
+		{"其他按键不删除", []string{"a", "b"}, []fyne.KeyName{fyne.KeyDown, fyne.KeySpace}, []string{"a", "b"}},
+		{"空列表", nil, []fyne.KeyName{fyne.KeyDelete}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestEnv(t)
+			items := append([]string(nil), tt.items...)
+			selected := -1
+			var list *keyboardList
+			list = newKeyboardList(func() int { return len(items) },
+				func() fyne.CanvasObject { return widget.NewLabel("") },
+				func(id widget.ListItemID, obj fyne.CanvasObject) { obj.(*widget.Label).SetText(items[id]) })
+			list.OnSelected = func(id widget.ListItemID) { selected = id }
+			list.onDelete = func() {
+				if selected < 0 || selected >= len(items) {
+					return
+				}
+				items = append(items[:selected], items[selected+1:]...)
+				selected = -1
+				list.UnselectAll()
+				list.Refresh()
+			}
+			w := test.NewWindow(list)
+			defer w.Close()
+			w.Resize(fyne.NewSize(200, 200))
+			w.Canvas().Focus(list)
+			for _, key := range tt.keys {
+				list.TypedKey(&fyne.KeyEvent{Name: key})
+			}
+			if !reflect.DeepEqual(items, tt.want) {
+				t.Fatalf("剩余的条目 = %v, 期望 %v", items, tt.want)
+			}
+		})
+	}
+}
+
+// 对话框打开时焦点放到指定的控件，关闭后回到打开对话框前的控件
+func TestShowDialogFocus(t *testing.T) {
+	tests := []struct {
+		name         string
+		initialFocus bool
+	}{
+		{"指定初始焦点", true},
+		{"不指定初始焦点", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			entry := widget.NewEntry()
+			fo.Window = test.NewWindow(container.NewVBox(entry, widget.NewButton("打开", nil)))
+			defer fo.Window.Close()
+			fo.Window.Resize(fyne.NewSize(400, 300))
+			fo.Window.Canvas().Focus(entry)
+
+			closeBtn := widget.NewButton("关闭", nil)
+			d := dialog.NewCustomWithoutButtons("测试", closeBtn, fo.Window)
+			var initial fyne.Focusable
+			if tt.initialFocus {
+				initial = closeBtn
+			}
+			fo.showDialog(d, initial)
+			if tt.initialFocus && fo.Window.Canvas().Focused() != closeBtn {
+				t.Fatalf("打开后的焦点 = %v", fo.Window.Canvas().Focused())
+			}
+			d.Hide()
+			if fo.Window.Canvas().Focused() != entry {
+				t.Fatalf("关闭后的焦点 = %v, 应回到输入框", fo.Window.Canvas().Focused())
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// 文件年龄分组：今天、本周、本月、今年、更早
+const (
+	AgeToday = iota
+	AgeThisWeek
+	AgeThisMonth
+	AgeThisYear
+	AgeOlder
+	ageBucketCount
+)
+
+// 默认的年龄分组文件夹名称
+var defaultAgeBucketLabels = []string{"今天", "本周", "本月", "今年", "更早"}
+
+// 计算修改时间相对于now所属的年龄分组，一周从周一开始，未来的时间算作今天
+func ageBucket(modTime, now time.Time) int {
+	modTime = modTime.In(now.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	yearStart := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+
+	switch {
+	case !modTime.Before(today):
+		return AgeToday
+	case !modTime.Before(weekStart):
+		return AgeThisWeek
+	case !modTime.Before(monthStart):
+		return AgeThisMonth
+	case !modTime.Before(yearStart):
+		return AgeThisYear
+	default:
+		return AgeOlder
+	}
+}
+
+// 获取年龄分组的文件夹名称，未配置的分组使用默认名称
+func ageBucketLabel(bucket int, labels []string) string {
+	if bucket < len(labels) {
+		if label := strings.TrimSpace(labels[bucket]); label != "" {
+			return sanitizeFolderName(label)
+		}
+	}
+	return defaultAgeBucketLabels[bucket]
+}
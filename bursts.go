@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 连拍识别的默认设置：相邻两张照片最多相隔2秒，超过50张的序列不当作连拍
+const (
+	defaultBurstMaxGap    = 2
+	defaultBurstMaxFrames = 50
+)
+
+// 文件名末尾的编号，例如 IMG_0451 → ("IMG_", 451)
+var burstNumberPattern = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// burstIndex 识别出的连拍序列。同一序列的照片按第一张的日期整理，跨过午夜也放在一起
+type burstIndex struct {
+	anchorOf   map[string]string     // 每张照片所属序列的第一张
+	frames     map[string][]string   // 第一张 → 序列中的全部照片（按拍摄先后）
+	anchorDate map[string]time.Time  // 第一张的日期
+	anchorFrom map[string]DateSource // 第一张的日期来源，划分日期时与单独的照片一样处理时区
+}
+
+// 连拍中的一张照片
+type burstFrame struct {
+	path   string
+	date   time.Time
+	source DateSource
+	exif   bool // 日期来自EXIF，秒以下的部分可信
+	prefix string
+	number int // 没有编号时为-1
+}
+
+// 照片所属序列的第一张，不属于任何序列时返回false
+func (b *burstIndex) anchor(path string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+	anchor, ok := b.anchorOf[path]
+	return anchor, ok
+}
+
+// 照片所属序列的第一张及其日期和日期来源，整理时代替照片自己的日期
+func (b *burstIndex) dateFor(path string) (string, time.Time, DateSource, bool) {
+	anchor, ok := b.anchor(path)
+	if !ok {
+		return "", time.Time{}, "", false
+	}
+	return anchor, b.anchorDate[anchor], b.anchorFrom[anchor], true
+}
+
+// 序列的数量
+func (b *burstIndex) count() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.frames)
+}
+
+// 在扫描结果中识别连拍序列：同一文件夹中的照片按拍摄时间排序，相邻两张相隔不超过maxGap，
+// 并且文件编号连续或者EXIF日期带有秒以下的部分（SubSecTimeOriginal）时属于同一序列。
+// 照片数超过maxFrames的序列不当作连拍，按各自的日期整理
+func detectBursts(files []string, infos map[string]os.FileInfo, date func(path string, info os.FileInfo) (time.Time, DateSource), maxGap time.Duration, maxFrames int) *burstIndex {
+	var photos []string
+	for _, path := range files {
+		if infos[path] != nil && fileCategory(path) == "图片" {
+			photos = append(photos, path)
+		}
+	}
+
+	// 读取日期需要打开文件，并行进行
+	frames := make([]burstFrame, len(photos))
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				path := photos[i]
+				frame := burstFrame{path: path, number: -1}
+				var source DateSource
+				frame.date, source = date(path, infos[path])
+				frame.source = source
+				frame.exif = source == DateSourceExif
+				stem, _ := splitExtension(path)
+				if match := burstNumberPattern.FindStringSubmatch(stem); match != nil {
+					if n, err := strconv.Atoi(match[2]); err == nil {
+						frame.prefix, frame.number = match[1], n
+					}
+				}
+				frames[i] = frame
+			}
+		}()
+	}
+	for i := range photos {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	byDir := make(map[string][]burstFrame)
+	for _, frame := range frames {
+		dir := filepath.Dir(frame.path)
+		byDir[dir] = append(byDir[dir], frame)
+	}
+
+	index := &burstIndex{
+		anchorOf:   make(map[string]string),
+		frames:     make(map[string][]string),
+		anchorDate: make(map[string]time.Time),
+		anchorFrom: make(map[string]DateSource),
+	}
+	for _, dirFrames := range byDir {
+		sort.Slice(dirFrames, func(i, j int) bool {
+			if !dirFrames[i].date.Equal(dirFrames[j].date) {
+				return dirFrames[i].date.Before(dirFrames[j].date)
+			}
+			return dirFrames[i].path < dirFrames[j].path
+		})
+		start := 0
+		for i := 1; i <= len(dirFrames); i++ {
+			if i < len(dirFrames) && sameBurst(dirFrames[i-1], dirFrames[i], maxGap) {
+				continue
+			}
+			if length := i - start; length >= 2 && length <= maxFrames {
+				index.add(dirFrames[start:i])
+			}
+			start = i
+		}
+	}
+	return index
+}
+
+// 相邻的两张照片是否属于同一序列
+func sameBurst(prev, cur burstFrame, maxGap time.Duration) bool {
+	if cur.date.Sub(prev.date) > maxGap {
+		return false
+	}
+	if prev.number >= 0 && cur.number == prev.number+1 && cur.prefix == prev.prefix {
+		return true
+	}
+	return prev.exif && cur.exif && prev.date.Nanosecond() != 0 && cur.date.Nanosecond() != 0
+}
+
+// 记录一个序列
+func (b *burstIndex) add(frames []burstFrame) {
+	anchor := frames[0].path
+	b.anchorDate[anchor] = frames[0].date
+	b.anchorFrom[anchor] = frames[0].source
+	for _, frame := range frames {
+		b.anchorOf[frame.path] = anchor
+		b.frames[anchor] = append(b.frames[anchor], frame.path)
+	}
+}
+
+// 按当前设置重新识别扫描结果中的连拍序列，未开启时清除
+// 按当前设置重新识别扫描结果中的连拍序列，未开启时清除。在扫描结束时由扫描的协程调用，这时扫描结果不会被修改
+func (fo *FileOrganizer) updateBursts() {
+	fo.bursts = fo.burstDetector()(fo.scannedFiles, fo.scannedFileInfos)
+	fo.logBursts()
+}
+
+// 连拍设置改变后在后台重新识别：在界面线程中复制扫描结果，识别期间开始了新的扫描时丢弃结果，
+// 新的扫描结束时会按新的设置识别。返回的通道在结果应用或丢弃后关闭
+func (fo *FileOrganizer) refreshBursts() <-chan struct{} {
+	done := make(chan struct{})
+	if fo.isScanning.Load() {
+		close(done)
+		return done
+	}
+	generation := fo.scanGeneration
+	files := append([]string(nil), fo.scannedFiles...)
+	infos := make(map[string]os.FileInfo, len(files))
+	for _, path := range files {
+		infos[path] = fo.scannedFileInfos[path]
+	}
+	detect := fo.burstDetector()
+	go func() {
+		defer close(done)
+		bursts := detect(files, infos)
+		fo.safeUpdateUI(func() {
+			if fo.scanGeneration != generation || fo.isScanning.Load() {
+				return
+			}
+			fo.bursts = bursts
+			fo.logBursts()
+			fo.refreshFileTable()
+		})
+	}()
+	return done
+}
+
+// 按当前设置识别连拍序列的函数，设置在调用时读取
+func (fo *FileOrganizer) burstDetector() func(files []string, infos map[string]os.FileInfo) *burstIndex {
+	enabled := fo.BurstDetection
+	sources := append([]DateSource(nil), fo.DateSources...)
+	maxGap, maxFrames := time.Duration(fo.BurstMaxGap)*time.Second, fo.BurstMaxFrames
+	return func(files []string, infos map[string]os.FileInfo) *burstIndex {
+		if !enabled || len(files) == 0 {
+			return nil
+		}
+		return detectBursts(files, infos, func(path string, info os.FileInfo) (time.Time, DateSource) {
+			return fo.dates.Resolve(path, info, sources)
+		}, maxGap, maxFrames)
+	}
+}
+
+// 记录识别到的连拍序列数
+func (fo *FileOrganizer) logBursts() {
+	if n := fo.bursts.count(); n > 0 {
+		fo.log(fmt.Sprintf("识别到 %d 组连拍照片，每组按第一张的日期整理", n))
+	}
+}
+
+// 浏览扫描结果时折叠显示的连拍序列：没有过滤关键字时，序列只显示第一张，其余照片在展开后显示。
+// filePath是折叠的序列的第一张时返回序列中的全部照片，否则返回nil
+func (fo *FileOrganizer) collapsedBurstFrames(filePath string) []string {
+	if strings.TrimSpace(fo.fileTableFilter) != "" || fo.expandedBursts[filePath] {
+		return nil
+	}
+	if anchor, ok := fo.bursts.anchor(filePath); !ok || anchor != filePath {
+		return nil
+	}
+	return fo.bursts.frames[filePath]
+}
+
+// 扫描结果表格中路径列显示的文字，连拍序列的第一张显示张数，展开后其余照片缩进显示
+func (fo *FileOrganizer) burstRowLabel(filePath string) string {
+	anchor, ok := fo.bursts.anchor(filePath)
+	if !ok || strings.TrimSpace(fo.fileTableFilter) != "" {
+		return filePath
+	}
+	if anchor != filePath {
+		return "    ↳ " + filePath
+	}
+	if fo.expandedBursts[filePath] {
+		return fmt.Sprintf("▾ %s（连拍 %d 张）", filePath, len(fo.bursts.frames[filePath]))
+	}
+	return fmt.Sprintf("▸ %s（连拍 %d 张，点击展开）", filePath, len(fo.bursts.frames[filePath]))
+}
+
+// 展开或折叠连拍序列，filePath不是序列的第一张时返回false
+func (fo *FileOrganizer) toggleBurst(filePath string) bool {
+	if strings.TrimSpace(fo.fileTableFilter) != "" {
+		return false
+	}
+	if anchor, ok := fo.bursts.anchor(filePath); !ok || anchor != filePath {
+		return false
+	}
+	if fo.expandedBursts[filePath] {
+		delete(fo.expandedBursts, filePath)
+	} else {
+		fo.expandedBursts[filePath] = true
+	}
+	fo.refreshFileTable()
+	return true
+}
+
+// 设置文件的排除状态，折叠的连拍序列整组排除或取消排除
+func (fo *FileOrganizer) setFileExcluded(filePath string, excluded bool) {
+	paths := fo.collapsedBurstFrames(filePath)
+	if paths == nil {
+		paths = []string{filePath}
+	}
+	for _, path := range paths {
+		if excluded {
+			fo.excludedFiles[path] = true
+		} else {
+			delete(fo.excludedFiles, path)
+		}
+	}
+}
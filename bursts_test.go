@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 连拍设置改变后在后台重新识别；扫描进行中时不识别，由扫描结束时按新的设置识别
+func TestRefreshBursts(t *testing.T) {
+	tests := []struct {
+		name      string
+		scanning  bool
+		enabled   bool
+		wantCount int
+	}{
+		{"开启识别", false, true, 1},
+		{"关闭识别", false, false, 0},
+		{"正在扫描", true, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			dir := t.TempDir()
+			fo.scannedFileInfos = make(map[string]os.FileInfo)
+			first := time.Date(2024, 6, 5, 12, 0, 0, 0, time.Local)
+			for i, name := range []string{"IMG_0001.jpg", "IMG_0002.jpg"} {
+				path := writeTestFile(t, filepath.Join(dir, name), name)
+				date := first.Add(time.Duration(i) * time.Second)
+				if err := os.Chtimes(path, date, date); err != nil {
+					t.Fatal(err)
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				fo.scannedFiles = append(fo.scannedFiles, path)
+				fo.scannedFileInfos[path] = info
+			}
+			fo.BurstDetection = tt.enabled
+			fo.DateSources = []DateSource{DateSourceMtime}
+			fo.BurstMaxGap, fo.BurstMaxFrames = 2, 10
+			fo.bursts = &burstIndex{}
+			fo.isScanning.Store(tt.scanning)
+
+			// 等待识别结果应用或丢弃之后再读取
+			select {
+			case <-fo.refreshBursts():
+			case <-time.After(5 * time.Second):
+				t.Fatal("识别连拍序列没有结束")
+			}
+			if tt.scanning {
+				if fo.bursts == nil {
+					t.Fatal("扫描进行中时不应替换连拍序列")
+				}
+				return
+			}
+			if count := fo.bursts.count(); count != tt.wantCount {
+				t.Fatalf("连拍序列 = %d, 期望 %d", count, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 没有相机型号的文件（不是照片或EXIF中没有型号）存放的文件夹
+const UnknownCameraFolderName = "未知相机"
+
+// EXIF中型号的最大长度，超过时视为损坏的数据
+const exifModelLimit = 128
+
+var errNoExifModel = errors.New("没有相机型号")
+
+// 读取照片EXIF中的相机型号，例如 "Canon EOS R5"，与读取拍摄日期使用相同的解析
+func readExifModel(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	r, err := exifTIFFReader(file)
+	if err != nil {
+		return "", errNoExifModel
+	}
+	order, ifd0, err := readTIFFIFD0(r)
+	if err != nil {
+		return "", errNoExifModel
+	}
+	model, ok := exifEntryString(r, order, ifd0[exifTagModel], exifModelLimit)
+	// 有的相机用空格补齐固定长度，连续的空白合并为一个空格
+	model = strings.Join(strings.Fields(model), " ")
+	if !ok || model == "" {
+		return "", errNoExifModel
+	}
+	return model, nil
+}
+
+// cameraModelCache 缓存照片的相机型号文件夹，规划目标和整理时只读取一次EXIF
+type cameraModelCache struct {
+	mu      sync.Mutex
+	folders map[string]string
+}
+
+// 创建相机型号缓存
+func newCameraModelCache() *cameraModelCache {
+	return &cameraModelCache{folders: make(map[string]string)}
+}
+
+// 相机型号文件夹的名称，没有型号时返回「未知相机」
+func (c *cameraModelCache) folder(path string) string {
+	c.mu.Lock()
+	folder, ok := c.folders[path]
+	c.mu.Unlock()
+	if ok {
+		return folder
+	}
+	folder = UnknownCameraFolderName
+	if model, err := readExifModel(path); err == nil {
+		folder = sanitizeFolderName(model)
+	}
+	c.mu.Lock()
+	c.folders[path] = folder
+	c.mu.Unlock()
+	return folder
+}
+
+// 清空缓存，每次扫描后重新读取
+func (c *cameraModelCache) reset() {
+	c.mu.Lock()
+	c.folders = make(map[string]string)
+	c.mu.Unlock()
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 按容量分卷的文件夹名称前缀，分卷依次为 VOL_001、VOL_002……
+const capacityVolumePrefix = "VOL_"
+
+// 分卷文件夹的名称
+func capacityVolumeName(n int) string {
+	return fmt.Sprintf("%s%03d", capacityVolumePrefix, n)
+}
+
+// 解析分卷文件夹的序号，不是分卷文件夹时返回false
+func parseCapacityVolumeName(name string) (int, bool) {
+	digits, ok := strings.CutPrefix(name, capacityVolumePrefix)
+	if !ok || len(digits) < 3 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// capacityFill 一个分卷已有的和本次计划放入的大小
+type capacityFill struct {
+	name     string
+	existing int64 // 整理前分卷中已有文件的总大小
+	added    int64 // 本次计划放入的文件总大小
+	files    int   // 本次计划放入的文件数
+}
+
+// capacityPlan 按容量分卷时每个文件放入的分卷。规则计算出的目标文件夹放到分卷文件夹下，
+// 例如 目标/VOL_002/2024-03-15
+type capacityPlan struct {
+	target   string            // 目标文件夹，分卷文件夹都在它下面
+	capBytes int64             // 每个分卷的容量
+	files    map[string]string // 文件路径 → 分卷文件夹名称
+	fills    []capacityFill    // 所有分卷，按序号排列
+	warnings []string          // 超过每卷容量、只能拆开或单独存放的文件
+}
+
+// 将目标文件夹下的路径换到文件所在的分卷中，没有分配分卷的文件保持不变
+func (p *capacityPlan) remap(filePath, targetDir string) string {
+	if p == nil {
+		return targetDir
+	}
+	volume, ok := p.files[filePath]
+	if !ok {
+		return targetDir
+	}
+	rel, err := filepath.Rel(p.target, targetDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return targetDir
+	}
+	return filepath.Join(p.target, volume, rel)
+}
+
+// 附属文件的后缀，附属文件与去掉这个后缀后的文件属于同一组
+var capacitySidecarExtensions = map[string]bool{".xmp": true, ".json": true, ".aae": true}
+
+// 同一组的文件放在同一个分卷中：同一文件夹下去掉附属文件后缀和最后一个后缀后名称相同的文件，
+// 例如 IMG_0001.CR2、IMG_0001.jpg、IMG_0001.xmp 和 IMG_0001.jpg.xmp；a.b.jpg 与 a.c.mov 不是同一组
+func capacityGroupKey(filePath string) string {
+	base := filepath.Base(filePath)
+	stem := base
+	if ext := filepath.Ext(stem); capacitySidecarExtensions[strings.ToLower(ext)] && len(ext) < len(stem) {
+		stem = strings.TrimSuffix(stem, ext)
+	}
+	if ext := filepath.Ext(stem); len(ext) < len(stem) {
+		stem = strings.TrimSuffix(stem, ext)
+	}
+	return filepath.Join(filepath.Dir(filePath), strings.ToLower(stem))
+}
+
+// 统计目标文件夹中已有的分卷及其大小，按序号排列
+func existingCapacityVolumes(target string) ([]capacityFill, error) {
+	entries, err := os.ReadDir(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取目标文件夹失败: %w", err)
+	}
+	var fills []capacityFill
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, ok := parseCapacityVolumeName(entry.Name()); !ok {
+			continue
+		}
+		fill := capacityFill{name: entry.Name()}
+		err := filepath.WalkDir(filepath.Join(target, entry.Name()), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			if info, err := d.Info(); err == nil {
+				fill.existing += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("统计分卷 %s 的大小失败: %w", entry.Name(), err)
+		}
+		fills = append(fills, fill)
+	}
+	sort.Slice(fills, func(i, j int) bool {
+		a, _ := parseCapacityVolumeName(fills[i].name)
+		b, _ := parseCapacityVolumeName(fills[j].name)
+		return a < b
+	})
+	return fills, nil
+}
+
+// capacityGroup 需要放在同一个分卷中的一组文件
+type capacityGroup struct {
+	key   string
+	files []string
+	sizes []int64 // 每个文件的大小，与files对应
+	size  int64
+	date  time.Time // 组中最早的文件日期
+}
+
+// 按容量为本次整理的文件分配分卷：文件按日期分组排序，每组依次放入第一个放得下的分卷，
+// 已有的分卷先补满，都放不下时新建下一个分卷。一组超过每卷容量时拆开逐个放入，
+// 单个文件超过容量时单独放入一个新分卷。重新归档的文件留在原来的分卷中
+func (fo *FileOrganizer) planCapacityVolumes(config Config, files []string) (*capacityPlan, error) {
+	if config.VolumeCapMB <= 0 {
+		return nil, nil
+	}
+	if len(config.Volumes) > 0 {
+		return nil, errors.New("按容量分卷时不支持多个目标卷")
+	}
+	capBytes := config.VolumeCapMB << 20
+	fills, err := existingCapacityVolumes(config.TargetDir)
+	if err != nil {
+		return nil, err
+	}
+	plan := &capacityPlan{target: config.TargetDir, capBytes: capBytes, files: make(map[string]string)}
+
+	planConfig := config
+	planConfig.CapacityPlan = nil
+	groups := make(map[string]*capacityGroup)
+	for _, filePath := range files {
+		if config.ExcludedFiles[filePath] || config.Pins.matches(filePath, nil) ||
+			!fo.isTargetFile(fileExtension(filePath), config.FileExtensions) {
+			continue
+		}
+		if isRefile(filePath, config) {
+			if top, _, ok := topFolder(config.TargetDir, filepath.Dir(filePath)); ok {
+				if _, isVolume := parseCapacityVolumeName(top); isVolume {
+					plan.files[filePath] = top
+				}
+			}
+			continue
+		}
+		info := fo.scannedFileInfos[filePath]
+		if info == nil {
+			if info, err = os.Stat(filePath); err != nil {
+				continue
+			}
+		}
+		key := capacityGroupKey(filePath)
+		group := groups[key]
+		date := fo.fileDate(filePath, info, planConfig)
+		if group == nil {
+			group = &capacityGroup{key: key, date: date}
+			groups[key] = group
+		}
+		size := packageSize(filePath, info)
+		group.files = append(group.files, filePath)
+		group.sizes = append(group.sizes, size)
+		group.size += size
+		if date.Before(group.date) {
+			group.date = date
+		}
+	}
+
+	sorted := make([]*capacityGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].date.Equal(sorted[j].date) {
+			return sorted[i].date.Before(sorted[j].date)
+		}
+		return sorted[i].key < sorted[j].key
+	})
+
+	next := 1
+	if len(fills) > 0 {
+		last, _ := parseCapacityVolumeName(fills[len(fills)-1].name)
+		next = last + 1
+	}
+	place := func(files []string, size int64) {
+		index := -1
+		for i := range fills {
+			if fills[i].existing+fills[i].added+size <= capBytes {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			fills = append(fills, capacityFill{name: capacityVolumeName(next)})
+			next++
+			index = len(fills) - 1
+		}
+		fills[index].added += size
+		fills[index].files += len(files)
+		for _, filePath := range files {
+			plan.files[filePath] = fills[index].name
+		}
+	}
+	for _, group := range sorted {
+		if group.size <= capBytes {
+			place(group.files, group.size)
+			continue
+		}
+		if len(group.files) > 1 {
+			plan.warnings = append(plan.warnings, fmt.Sprintf("%s 等 %d 个文件共 %s，超过每卷容量 %s，拆开放入不同的分卷",
+				group.files[0], len(group.files), formatFileSize(group.size), formatFileSize(capBytes)))
+		}
+		for i, filePath := range group.files {
+			if group.sizes[i] > capBytes {
+				plan.warnings = append(plan.warnings, fmt.Sprintf("%s 有 %s，超过每卷容量 %s，单独放入一个分卷",
+					filePath, formatFileSize(group.sizes[i]), formatFileSize(capBytes)))
+			}
+			place([]string{filePath}, group.sizes[i])
+		}
+	}
+	plan.fills = fills
+	return plan, nil
+}
+
+// 描述各分卷的填充情况，用于预览和日志
+func describeCapacityPlan(plan *capacityPlan) []string {
+	var lines []string
+	for _, fill := range plan.fills {
+		used := fill.existing + fill.added
+		line := fmt.Sprintf("%s: %s / %s（%d%%）", fill.name, formatFileSize(used), formatFileSize(plan.capBytes), used*100/plan.capBytes)
+		switch {
+		case fill.files > 0 && fill.existing > 0:
+			line += fmt.Sprintf("，已有 %s，补入 %d 个文件", formatFileSize(fill.existing), fill.files)
+		case fill.files > 0:
+			line += fmt.Sprintf("，新分卷，放入 %d 个文件", fill.files)
+		default:
+			line += "，本次不放入文件"
+		}
+		lines = append(lines, line)
+	}
+	return append(lines, plan.warnings...)
+}
+
+// 在后台计算扫描结果的分卷，完成后在日志中显示各分卷的填充情况并刷新计划目标列
+func (fo *FileOrganizer) previewCapacityPlan() {
+	fo.capacityPlan = nil
+	config := fo.currentConfig()
+	if config.VolumeCapMB <= 0 {
+		return
+	}
+	files := fo.scannedFiles
+	go func() {
+		plan, err := fo.planCapacityVolumes(config, files)
+		fo.safeUpdateUI(func() {
+			if err != nil {
+				fo.log("按容量分卷预览失败: " + err.Error())
+				return
+			}
+			fo.capacityPlan = plan
+			fo.log(fmt.Sprintf("按容量分卷预览（每卷 %s）:", formatFileSize(plan.capBytes)))
+			for _, line := range describeCapacityPlan(plan) {
+				fo.log("  " + line)
+			}
+			fo.refreshFileTable()
+		})
+	}()
+}
+
+// 监视模式下为一个新文件分配分卷：放入第一个放得下的已有分卷，都放不下时新建下一个分卷，
+// 已在分卷中的文件留在原来的分卷中。分卷的大小在每个文件到来时重新统计，需要在锁定目标文件夹后调用
+func watchCapacityVolume(config Config, filePath string, info os.FileInfo, targetDir string) (string, error) {
+	if config.VolumeCapMB <= 0 {
+		return targetDir, nil
+	}
+	if len(config.Volumes) > 0 {
+		return "", errors.New("按容量分卷时不支持多个目标卷")
+	}
+	plan := &capacityPlan{target: config.TargetDir, capBytes: config.VolumeCapMB << 20, files: make(map[string]string)}
+	if top, _, ok := topFolder(config.TargetDir, filepath.Dir(filePath)); ok {
+		if _, isVolume := parseCapacityVolumeName(top); isVolume {
+			plan.files[filePath] = top
+			return plan.remap(filePath, targetDir), nil
+		}
+	}
+	fills, err := existingCapacityVolumes(config.TargetDir)
+	if err != nil {
+		return "", err
+	}
+	size := packageSize(filePath, info)
+	for _, fill := range fills {
+		if fill.existing+size <= plan.capBytes {
+			plan.files[filePath] = fill.name
+			return plan.remap(filePath, targetDir), nil
+		}
+	}
+	next := 1
+	if len(fills) > 0 {
+		last, _ := parseCapacityVolumeName(fills[len(fills)-1].name)
+		next = last + 1
+	}
+	plan.files[filePath] = capacityVolumeName(next)
+	return plan.remap(filePath, targetDir), nil
+}
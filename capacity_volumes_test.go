@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 同一组的文件只有扩展名和附属文件后缀不同
+func TestCapacityGroupKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"IMG_0001.CR2", "IMG_0001.jpg", true},
+		{"IMG_0001.jpg", "IMG_0001.jpg.xmp", true},
+		{"IMG_0001.CR2", "IMG_0001.xmp", true},
+		{"a.jpg", "a.jpg.json", true},
+		{"IMG_0001.JPG", "img_0001.mov", true},
+		{"a.b.jpg", "a.c.mov", false},
+		{"a.b.jpg", "a.jpg", false},
+		{"IMG_0001.jpg", "IMG_0002.jpg", false},
+		{".xmp", ".jpg", false},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		a := capacityGroupKey(filepath.Join(dir, tt.a))
+		b := capacityGroupKey(filepath.Join(dir, tt.b))
+		if (a == b) != tt.same {
+			t.Errorf("%s 与 %s 同组 = %v, 期望 %v（%s, %s）", tt.a, tt.b, a == b, tt.same, a, b)
+		}
+	}
+}
+
+// 超过每卷容量的组拆开放入不同的分卷，单个文件超过容量时单独放入一个分卷，不中止整理
+func TestPlanCapacityOversize(t *testing.T) {
+	const kb = 1 << 10
+	tests := []struct {
+		name  string
+		files map[string]int // 文件名 → 大小（KB）
+		want  map[string]string
+		warns int
+	}{
+		{"同组的文件放在一起", map[string]int{"a.jpg": 400, "a.cr2": 400, "b.jpg": 400},
+			map[string]string{"a.jpg": "VOL_001", "a.cr2": "VOL_001", "b.jpg": "VOL_002"}, 0},
+		{"超过容量的组拆开", map[string]int{"a.jpg": 700, "a.cr2": 700},
+			map[string]string{"a.cr2": "VOL_001", "a.jpg": "VOL_002"}, 1},
+		{"超过容量的单个文件单独放入", map[string]int{"a.jpg": 1500, "b.jpg": 100},
+			map[string]string{"a.jpg": "VOL_001", "b.jpg": "VOL_002"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			date := time.Date(2024, 6, 5, 12, 0, 0, 0, time.Local)
+			var files []string
+			for name, size := range tt.files {
+				path := writeTestFile(t, filepath.Join(source, name), strings.Repeat("x", size*kb))
+				if err := os.Chtimes(path, date, date); err != nil {
+					t.Fatal(err)
+				}
+				files = append(files, path)
+			}
+			sort.Strings(files)
+			config := Config{
+				SourceDirs:     []string{source},
+				TargetDir:      t.TempDir(),
+				FileExtensions: []string{".jpg", ".cr2"},
+				DateSources:    []DateSource{DateSourceMtime},
+				VolumeCapMB:    1,
+				ExcludedFiles:  map[string]bool{},
+			}
+			plan, err := fo.planCapacityVolumes(config, files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.want {
+				if got := plan.files[filepath.Join(source, name)]; got != want {
+					t.Errorf("%s 的分卷 = %s, 期望 %s", name, got, want)
+				}
+			}
+			if len(plan.warnings) != tt.warns {
+				t.Errorf("提示 = %v, 期望 %d 条", plan.warnings, tt.warns)
+			}
+		})
+	}
+}
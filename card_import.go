@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 检测新挂载存储卡的轮询间隔
+const cardPollInterval = 3 * time.Second
+
+// 相机存储卡上保存照片的文件夹
+const cameraDCIMFolder = "DCIM"
+
+// 导入存储卡时日期的来源：优先使用照片EXIF中的拍摄日期，存储卡上的修改时间可能因相机时钟或复制而不准
+var cardImportDateSources = []DateSource{DateSourceExif, DateSourceFilename, DateSourceMtime}
+
+// 开始检测新插入的相机存储卡
+func (fo *FileOrganizer) startCardDetection() {
+	if fo.cardDetectStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	fo.cardDetectStop = stop
+	go fo.pollRemovableVolumes(stop)
+}
+
+// 停止检测相机存储卡
+func (fo *FileOrganizer) stopCardDetection() {
+	if fo.cardDetectStop == nil {
+		return
+	}
+	close(fo.cardDetectStop)
+	fo.cardDetectStop = nil
+}
+
+// 轮询可移动卷，发现新挂载且带有DCIM文件夹的卷时提示导入
+func (fo *FileOrganizer) pollRemovableVolumes(stop chan struct{}) {
+	// 启动时已挂载的卷不提示，只处理之后新插入的卷
+	known := make(map[string]bool)
+	for _, volume := range listRemovableVolumes() {
+		known[volume] = true
+	}
+
+	ticker := time.NewTicker(cardPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			current := make(map[string]bool)
+			for _, volume := range listRemovableVolumes() {
+				current[volume] = true
+				if known[volume] {
+					continue
+				}
+				dcimDir := filepath.Join(volume, cameraDCIMFolder)
+				if info, err := os.Stat(dcimDir); err == nil && info.IsDir() {
+					fo.log(fmt.Sprintf("检测到相机存储卡: %s", volume))
+					fo.safeUpdateUI(func() {
+						fo.promptCardImport(volume, dcimDir)
+					})
+				}
+			}
+			// 已拔出的卷从记录中移除，重新插入时会再次提示
+			known = current
+		}
+	}
+}
+
+// 提示用户是否导入存储卡，只有确认后才会开始导入
+func (fo *FileOrganizer) promptCardImport(volume, dcimDir string) {
+	if fo.ReadOnlyMode {
+		fo.log("只读模式: 不导入存储卡 " + volume)
+		return
+	}
+	if fo.cardImporting.Load() {
+		fo.log("正在导入其他存储卡，忽略: " + volume)
+		return
+	}
+
+	targetText := fo.CardImportTarget
+	if targetText == "" {
+		targetText = "（确认后选择）"
+	}
+	message := widget.NewLabel(fmt.Sprintf("检测到相机存储卡 %s，是否导入？\n\n照片将按日期复制到: %s\n复制后会逐个校验文件内容。", volume, targetText))
+	message.Wrapping = fyne.TextWrapWord
+	cleanupCheck := widget.NewCheck("全部校验通过后删除存储卡上已导入的文件", nil)
+	cleanupCheck.SetChecked(fo.CardCleanup)
+	ejectCheck := widget.NewCheck("全部导入后弹出存储卡", nil)
+	ejectCheck.SetChecked(true)
+
+	content := container.NewVBox(message, cleanupCheck, ejectCheck)
+	dialog.ShowCustomConfirm("检测到相机存储卡", "导入", "忽略", content, func(confirm bool) {
+		if !confirm {
+			fo.log("已忽略存储卡: " + volume)
+			return
+		}
+		cleanup, eject := cleanupCheck.Checked, ejectCheck.Checked
+		start := func(targetDir string) {
+			config := fo.cardImportConfig(dcimDir, targetDir)
+			go fo.importCameraCard(volume, config, cleanup, eject)
+		}
+		if fo.CardImportTarget != "" {
+			start(fo.CardImportTarget)
+			return
+		}
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err != nil || dir == nil {
+				fo.log("未选择导入目标，已取消导入: " + volume)
+				return
+			}
+			fo.CardImportTarget = dir.Path()
+			fo.saveUserConfig()
+			start(fo.CardImportTarget)
+		}, fo.Window)
+	}, fo.Window)
+}
+
+// 导入存储卡使用的配置，在界面线程中生成，导入期间设置改变不影响导入
+func (fo *FileOrganizer) cardImportConfig(dcimDir, targetDir string) Config {
+	return Config{
+		SourceDir:        dcimDir,
+		TargetDir:        targetDir,
+		OrganizeRule:     string(RuleByDate),
+		FolderDateFormat: fo.FolderDateFormat,
+		DateSources:      cardImportDateSources,
+		PreserveXattrs:   fo.PreserveXattrs,
+	}
+}
+
+// 导入相机存储卡：按拍摄日期复制、校验，全部通过后按需清理存储卡，最后刷新缓存并按需弹出
+func (fo *FileOrganizer) importCameraCard(volume string, config Config, cleanup, eject bool) {
+	if !fo.cardImporting.CompareAndSwap(false, true) {
+		fo.log("正在导入其他存储卡，忽略: " + volume)
+		return
+	}
+	defer fo.cardImporting.Store(false)
+
+	dcimDir, targetDir := config.SourceDir, config.TargetDir
+	if err := validateTargetDir(targetDir); err != nil {
+		fo.log("存储卡导入失败: " + err.Error())
+		return
+	}
+	// 与整理一样锁定目标文件夹，导入期间关闭窗口时等待导入完成
+	unlock, err := fo.lockTarget(targetDir, newRunID())
+	if err != nil {
+		fo.log("存储卡导入失败: " + err.Error())
+		return
+	}
+	defer unlock()
+
+	fo.log(fmt.Sprintf("开始导入存储卡 %s -> %s", volume, targetDir))
+	var imported []string
+	failed := 0
+	cancelled := false
+	err = filepath.Walk(dcimDir, func(path string, info os.FileInfo, err error) error {
+		// 退出时取消整理，未导入的文件留在存储卡上
+		if fo.quitting.Load() && fo.cancelProcessing.Load() {
+			cancelled = true
+			return filepath.SkipAll
+		}
+		if err != nil {
+			fo.log(fmt.Sprintf("读取存储卡失败 %s: %v", path, err))
+			failed++
+			return nil
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+
+		dateDir := fo.planTargetDir(path, info, config)
+		copiedPath, err := fo.copyFile(path, dateDir, config.PreserveXattrs)
+		if err != nil {
+			fo.log(fmt.Sprintf("复制失败 %s: %v", path, err))
+			failed++
+			return nil
+		}
+
+		// 比较源文件和副本的哈希值，确认内容完全一致
+		sourceHash, err := hashFile(path)
+		if err == nil {
+			var targetHash string
+			targetHash, err = hashFile(copiedPath)
+			if err == nil && targetHash != sourceHash {
+				err = fmt.Errorf("内容校验不一致")
+			}
+		}
+		if err != nil {
+			fo.log(fmt.Sprintf("校验失败 %s: %v", path, err))
+			failed++
+			return nil
+		}
+
+		imported = append(imported, path)
+		fo.log(fmt.Sprintf("已导入: %s -> %s", filepath.Base(path), dateDir))
+		return nil
+	})
+	if err != nil {
+		fo.log(fmt.Sprintf("读取存储卡失败: %v", err))
+		failed++
+	}
+
+	if cancelled {
+		fo.log(fmt.Sprintf("存储卡导入已取消: 已导入 %d 个，失败 %d 个，其余文件留在存储卡上", len(imported), failed))
+	} else {
+		fo.log(fmt.Sprintf("存储卡导入完成: 成功 %d 个，失败 %d 个", len(imported), failed))
+	}
+
+	// 只有所有文件都校验通过才清理存储卡
+	if cleanup {
+		if failed > 0 || cancelled {
+			fo.log("存在导入失败或未导入的文件，未清理存储卡")
+		} else {
+			removed := 0
+			for _, path := range imported {
+				if err := os.Remove(path); err != nil {
+					fo.log(fmt.Sprintf("删除存储卡文件失败 %s: %v", path, err))
+					continue
+				}
+				removed++
+			}
+			fo.log(fmt.Sprintf("已从存储卡删除 %d 个已导入的文件", removed))
+		}
+	}
+
+	if err := flushVolume(volume); err != nil {
+		fo.log(fmt.Sprintf("刷新存储卡缓存失败: %v", err))
+		return
+	}
+	// 有失败的文件时不弹出，方便重新导入
+	if eject && failed == 0 && !cancelled {
+		if err := ejectVolume(volume); err != nil {
+			fo.log(fmt.Sprintf("弹出存储卡失败: %v", err))
+			return
+		}
+		fo.log("存储卡已弹出，可以移除: " + volume)
+		return
+	}
+	fo.log("数据已写入磁盘，可以安全移除存储卡: " + volume)
+}
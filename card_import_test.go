@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 写入只有 EXIF 修改日期的最小 JPEG
+func writeExifJPEG(t *testing.T, path, date string) string {
+	t.Helper()
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, exifTagDateTime)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	tiff = binary.LittleEndian.AppendUint32(tiff, uint32(len(date)+1))
+	tiff = binary.LittleEndian.AppendUint32(tiff, 26)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+	tiff = append(tiff, date+"\x00"...)
+
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	data = binary.BigEndian.AppendUint16(data, uint16(2+6+len(tiff)))
+	data = append(data, "Exif\x00\x00"...)
+	data = append(data, tiff...)
+	data = append(data, 0xFF, 0xD9)
+	return writeTestFile(t, path, string(data))
+}
+
+// 存储卡按 EXIF 拍摄日期导入，没有 EXIF 时使用修改时间；只有全部校验通过才清理存储卡
+func TestImportCameraCard(t *testing.T) {
+	mtime := time.Date(2024, 1, 2, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		exif     string // 为空时写入普通文件
+		cleanup  bool
+		wantDir  string
+		wantKept bool // 存储卡上的文件保留
+	}{
+		{"按EXIF日期", "2021:05:06 07:08:09", false, "2021-05-06", true},
+		{"没有EXIF时按修改时间", "", false, "2024-01-02", true},
+		{"校验通过后清理", "2021:05:06 07:08:09", true, "2021-05-06", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			volume := t.TempDir()
+			target := t.TempDir()
+			dcim := filepath.Join(volume, cameraDCIMFolder)
+			photo := filepath.Join(dcim, "100CANON", "IMG_0001.JPG")
+			if tt.exif != "" {
+				writeExifJPEG(t, photo, tt.exif)
+			} else {
+				writeTestFile(t, photo, "photo")
+			}
+			if err := os.Chtimes(photo, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+
+			fo.importCameraCard(volume, fo.cardImportConfig(dcim, target), tt.cleanup, false)
+			if _, err := os.Stat(filepath.Join(target, tt.wantDir, "IMG_0001.JPG")); err != nil {
+				t.Fatalf("导入的文件: %v", err)
+			}
+			if _, err := os.Stat(photo); (err == nil) != tt.wantKept {
+				t.Fatalf("存储卡上的文件保留 = %v, 期望 %v", err == nil, tt.wantKept)
+			}
+		})
+	}
+}
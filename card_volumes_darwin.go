@@ -0,0 +1,45 @@
+//go:build darwin
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// 列出当前挂载的外部卷（macOS 上外部卷挂载在 /Volumes 下）
+func listRemovableVolumes() []string {
+	entries, err := os.ReadDir("/Volumes")
+	if err != nil {
+		return nil
+	}
+
+	var volumes []string
+	for _, entry := range entries {
+		path := filepath.Join("/Volumes", entry.Name())
+		// 系统盘在 /Volumes 下是指向 / 的符号链接，跳过
+		if target, err := filepath.EvalSymlinks(path); err == nil && target == "/" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			volumes = append(volumes, path)
+		}
+	}
+	return volumes
+}
+
+// 将缓存数据写入磁盘，保证存储卡可以安全移除
+func flushVolume(path string) error {
+	return syncFilesystems()
+}
+
+// 弹出存储卡，与在访达中推出相同
+func ejectVolume(path string) error {
+	if output, err := exec.Command("diskutil", "eject", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("弹出 %s 失败: %v %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// 列出当前挂载的外部卷（udisks 挂载在 /media/<用户> 或 /run/media/<用户> 下）
+func listRemovableVolumes() []string {
+	var mountRoots []string
+	if current, err := user.Current(); err == nil {
+		mountRoots = append(mountRoots,
+			filepath.Join("/media", current.Username),
+			filepath.Join("/run/media", current.Username))
+	}
+	mountRoots = append(mountRoots, "/media")
+
+	seen := make(map[string]bool)
+	var volumes []string
+	for _, root := range mountRoots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(root, entry.Name())
+			if !entry.IsDir() || seen[path] {
+				continue
+			}
+			seen[path] = true
+			volumes = append(volumes, path)
+		}
+	}
+	return volumes
+}
+
+// 将缓存数据写入磁盘，保证存储卡可以安全移除
+func flushVolume(path string) error {
+	return syncFilesystems()
+}
+
+// 弹出存储卡：桌面环境中用 gio 卸载并弹出，没有 gio 时用 umount 卸载
+func ejectVolume(path string) error {
+	command := exec.Command("gio", "mount", "--eject", path)
+	if _, err := exec.LookPath("gio"); err != nil {
+		command = exec.Command("umount", path)
+	}
+	if output, err := command.CombinedOutput(); err != nil {
+		return fmt.Errorf("弹出 %s 失败: %v %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
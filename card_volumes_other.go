@@ -0,0 +1,20 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+import "errors"
+
+// 当前系统不支持检测可移动卷
+func listRemovableVolumes() []string {
+	return nil
+}
+
+// 将缓存数据写入磁盘
+func flushVolume(path string) error {
+	return nil
+}
+
+// 当前系统不支持弹出存储卡
+func ejectVolume(path string) error {
+	return errors.New("当前系统不支持弹出存储卡，请手动移除")
+}
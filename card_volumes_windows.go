@@ -0,0 +1,98 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// 卷和可移动存储的控制码（winioctl.h）
+const (
+	fsctlLockVolume          = 0x00090018
+	fsctlDismountVolume      = 0x00090020
+	ioctlStorageMediaRemoval = 0x002D4804
+	ioctlStorageEjectMedia   = 0x002D4808
+)
+
+// 列出当前连接的可移动磁盘（盘符类型为 DRIVE_REMOVABLE）
+func listRemovableVolumes() []string {
+	mask, err := windows.GetLogicalDrives()
+	if err != nil {
+		return nil
+	}
+
+	var volumes []string
+	for i := 0; i < 26; i++ {
+		if mask&(1<<uint(i)) == 0 {
+			continue
+		}
+		root := string(rune('A'+i)) + `:\`
+		rootPtr, err := windows.UTF16PtrFromString(root)
+		if err != nil {
+			continue
+		}
+		if windows.GetDriveType(rootPtr) != windows.DRIVE_REMOVABLE {
+			continue
+		}
+		if _, err := os.Stat(root); err == nil {
+			volumes = append(volumes, root)
+		}
+	}
+	return volumes
+}
+
+// 打开盘符对应的卷，例如 E:\ 对应 \\.\E:
+func openVolume(path string) (windows.Handle, error) {
+	name := `\\.\` + strings.TrimRight(path, `\`)
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	handle, err := windows.CreateFile(namePtr, windows.GENERIC_READ|windows.GENERIC_WRITE,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return windows.InvalidHandle, fmt.Errorf("打开卷 %s 失败: %w", name, err)
+	}
+	return handle, nil
+}
+
+// 将卷的缓存数据写入磁盘，保证存储卡可以安全移除
+func flushVolume(path string) error {
+	handle, err := openVolume(path)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(handle)
+	if err := windows.FlushFileBuffers(handle); err != nil {
+		return fmt.Errorf("刷新卷 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// 弹出存储卡：锁定并卸载卷后弹出介质。卷上有打开的文件时锁定失败，不弹出
+func ejectVolume(path string) error {
+	handle, err := openVolume(path)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(handle)
+	var returned uint32
+	if err := windows.DeviceIoControl(handle, fsctlLockVolume, nil, 0, nil, 0, &returned, nil); err != nil {
+		return fmt.Errorf("卷 %s 正在使用，无法弹出: %w", path, err)
+	}
+	if err := windows.DeviceIoControl(handle, fsctlDismountVolume, nil, 0, nil, 0, &returned, nil); err != nil {
+		return fmt.Errorf("卸载卷 %s 失败: %w", path, err)
+	}
+	// 解除禁止移除介质的设置，部分读卡器在弹出前需要
+	allow := byte(0)
+	windows.DeviceIoControl(handle, ioctlStorageMediaRemoval, (*byte)(unsafe.Pointer(&allow)), 1, nil, 0, &returned, nil)
+	if err := windows.DeviceIoControl(handle, ioctlStorageEjectMedia, nil, 0, nil, 0, &returned, nil); err != nil {
+		return fmt.Errorf("弹出 %s 失败: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// caseCollision 计划创建的文件夹与已有文件夹只有大小写不同
+type caseCollision struct {
+	parent   string // 所在的上级文件夹
+	existing string // 已有文件夹的名称
+	planned  string // 按当前设置计划使用的名称
+}
+
+// 检测文件夹所在的卷是否不区分大小写。
+// 在文件夹中创建一个小写名称的临时文件，再用大写名称访问；无法创建时按平台默认值判断
+func isCaseInsensitiveDir(dir string) bool {
+	// 目标文件夹可能尚未创建，向上找到已存在的文件夹进行检测
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return defaultCaseInsensitive()
+		}
+		dir = parent
+	}
+
+	probe, err := os.CreateTemp(dir, "fo_case_probe_")
+	if err != nil {
+		return defaultCaseInsensitive()
+	}
+	probePath := probe.Name()
+	probe.Close()
+	defer os.Remove(probePath)
+
+	probeInfo, err := os.Stat(probePath)
+	if err != nil {
+		return defaultCaseInsensitive()
+	}
+	upperPath := filepath.Join(dir, strings.ToUpper(filepath.Base(probePath)))
+	upperInfo, err := os.Stat(upperPath)
+	return err == nil && os.SameFile(probeInfo, upperInfo)
+}
+
+// 平台默认的文件系统是否不区分大小写（macOS 和 Windows 默认不区分）
+func defaultCaseInsensitive() bool {
+	return runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+}
+
+// 查找计划的目标文件夹中与已有文件夹只有大小写不同的部分
+func (fo *FileOrganizer) findCaseCollisions(config Config) []caseCollision {
+	planned := make(map[string]bool)
+	for _, filePath := range fo.scannedFiles {
+		info := fo.scannedFileInfos[filePath]
+		if info == nil || config.ExcludedFiles[filePath] || config.Pins.matches(filePath, nil) || !fo.isTargetFile(fileExtension(filePath), config.FileExtensions) {
+			continue
+		}
+		if targetDir := fo.planTargetDir(filePath, info, config); targetDir != "" {
+			planned[targetDir] = true
+		}
+	}
+
+	listings := make(map[string][]string)
+	listDir := func(dir string) []string {
+		if names, ok := listings[dir]; ok {
+			return names
+		}
+		var names []string
+		if entries, err := os.ReadDir(dir); err == nil {
+			for _, entry := range entries {
+				if entry.IsDir() {
+					names = append(names, entry.Name())
+				}
+			}
+		}
+		listings[dir] = names
+		return names
+	}
+
+	found := make(map[string]caseCollision)
+	for targetDir := range planned {
+		rel, err := filepath.Rel(config.TargetDir, targetDir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		// 逐级检查目标文件夹下的每一级子文件夹
+		parent := config.TargetDir
+		for _, name := range strings.Split(rel, string(filepath.Separator)) {
+			if name == "." || name == "" {
+				continue
+			}
+			for _, existing := range listDir(parent) {
+				if existing != name && strings.EqualFold(existing, name) {
+					key := filepath.Join(parent, existing)
+					found[key] = caseCollision{parent: parent, existing: existing, planned: name}
+				}
+			}
+			parent = filepath.Join(parent, name)
+		}
+	}
+
+	collisions := make([]caseCollision, 0, len(found))
+	for _, collision := range found {
+		collisions = append(collisions, collision)
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return filepath.Join(collisions[i].parent, collisions[i].existing) < filepath.Join(collisions[j].parent, collisions[j].existing)
+	})
+	return collisions
+}
+
+// 将已有文件夹改名为计划使用的大小写。不区分大小写的卷上先改为临时名称再改回，避免被视为同名
+func (fo *FileOrganizer) mergeCaseCollision(collision caseCollision) error {
+	if err := fo.checkWritable(); err != nil {
+		return err
+	}
+	existingPath := filepath.Join(collision.parent, collision.existing)
+	plannedPath := filepath.Join(collision.parent, collision.planned)
+	tmpPath := filepath.Join(collision.parent, fmt.Sprintf("%s.case_%d", collision.planned, time.Now().UnixNano()))
+	if err := os.Rename(existingPath, tmpPath); err != nil {
+		return fmt.Errorf("重命名文件夹失败 %s: %w", existingPath, err)
+	}
+	if err := os.Rename(tmpPath, plannedPath); err != nil {
+		// 尽量恢复原来的名称
+		os.Rename(tmpPath, existingPath)
+		return fmt.Errorf("重命名文件夹失败 %s: %w", existingPath, err)
+	}
+	return nil
+}
+
+// 在不区分大小写的卷上检查目标文件夹的大小写冲突，有冲突时提示用户选择合并方式。
+// 用户确认后调用proceed继续整理，取消时不调用
+func (fo *FileOrganizer) confirmCaseCollisions(config Config, proceed func()) {
+	if !isCaseInsensitiveDir(config.TargetDir) {
+		proceed()
+		return
+	}
+	collisions := fo.findCaseCollisions(config)
+	if len(collisions) == 0 {
+		proceed()
+		return
+	}
+
+	var lines []string
+	for _, collision := range collisions {
+		lines = append(lines, fmt.Sprintf("%s  →  %s", filepath.Join(collision.parent, collision.existing), collision.planned))
+	}
+	fo.log(fmt.Sprintf("警告: 目标所在的卷不区分大小写，%d 个文件夹只有大小写与计划不同", len(collisions)))
+
+	message := widget.NewLabel(fmt.Sprintf(
+		"目标所在的卷不区分大小写，以下已有文件夹与计划创建的文件夹只有大小写不同，文件将合并到同一个文件夹:\n\n%s\n\n"+
+			"可以将已有文件夹改名为当前设置的大小写后再整理，或保持原名直接整理。",
+		strings.Join(lines, "\n")))
+	message.Wrapping = fyne.TextWrapWord
+
+	var collisionDialog dialog.Dialog
+	renameBtn := widget.NewButton("改为当前大小写并整理", func() {
+		collisionDialog.Hide()
+		for _, collision := range collisions {
+			if err := fo.mergeCaseCollision(collision); err != nil {
+				fo.log(err.Error())
+				dialog.ShowError(err, fo.Window)
+				return
+			}
+			fo.log(fmt.Sprintf("已将文件夹 %s 改名为 %s", filepath.Join(collision.parent, collision.existing), collision.planned))
+		}
+		proceed()
+	})
+	renameBtn.Importance = widget.HighImportance
+	keepBtn := widget.NewButton("保持原名并整理", func() {
+		collisionDialog.Hide()
+		proceed()
+	})
+	cancelBtn := widget.NewButton("取消", func() {
+		collisionDialog.Hide()
+		fo.log("已取消整理")
+	})
+
+	collisionDialog = dialog.NewCustomWithoutButtons("文件夹大小写冲突",
+		container.NewVBox(message, container.NewHBox(layout.NewSpacer(), cancelBtn, keepBtn, renameBtn)), fo.Window)
+	collisionDialog.Resize(fyne.NewSize(560, 0))
+	fo.showDialog(collisionDialog, renameBtn)
+}
@@ -0,0 +1,642 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// 整理目录：记录每个整理过的文件的原路径和最终路径，以后不用遍历归档就能找到文件。
+// 目录是SQLite数据库（modernc.org/sqlite，不需要cgo），每批记录在一个事务中写入，
+// 按文件名、哈希值和路径查找时使用索引。数据库损坏时停用目录，整理照常进行
+const catalogFileName = "catalog.db"
+
+// 整理编号的格式：整理开始的时间，精确到毫秒。按字符串比较就是按时间比较
+const runIDLayout = "20060102_150405.000"
+
+// 上一个生成的整理编号的时间，保证同一进程中的编号不重复
+var runIDClock struct {
+	sync.Mutex
+	last time.Time
+}
+
+// 生成新的整理编号。同一毫秒内再次生成时顺延一毫秒，连续快速开始的整理不会共用一个编号
+func newRunID() string {
+	runIDClock.Lock()
+	defer runIDClock.Unlock()
+	now := time.Now().Truncate(time.Millisecond)
+	if !now.After(runIDClock.last) {
+		now = runIDClock.last.Add(time.Millisecond)
+	}
+	runIDClock.last = now
+	return now.Format(runIDLayout)
+}
+
+// 解析整理编号中的时间，兼容旧版本精确到秒的编号
+func parseRunID(runID string) (time.Time, bool) {
+	for _, layout := range []string{runIDLayout, "20060102_150405"} {
+		if t, err := time.ParseInLocation(layout, runID, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// 一个事务写入的最大记录数，工作协程只把记录放进通道，由后台协程批量写入
+const (
+	catalogBatchSize     = 256
+	catalogFlushInterval = time.Second
+)
+
+// 搜索结果的最大数量
+const catalogSearchLimit = 500
+
+// 记录的操作，旧的记录没有这个字段，都是移动
+const (
+	CatalogMove = ""     // 移动到目标，撤销时移回
+	CatalogCopy = "copy" // 从只读源文件夹复制，源文件留在原处，撤销时删除副本
+)
+
+// CatalogEntry 目录中的一条记录
+type CatalogEntry struct {
+	RunID        string    `json:"run_id"`
+	OriginalPath string    `json:"original_path"`
+	FinalPath    string    `json:"final_path"`
+	Size         int64     `json:"size"`
+	Hash         string    `json:"hash,omitempty"`          // 去重或校验时计算过才有
+	Checksum     string    `json:"checksum,omitempty"`      // 导出校验清单时的校验值，"算法:哈希值"，例如 md5:9e10...
+	Volume       string    `json:"volume,omitempty"`        // 使用多个目标卷时文件所在的卷
+	Date         string    `json:"date,omitempty"`          // 目标修改时间精度低（FAT/exFAT）时记录整理所用的日期，YYYY-MM-DD
+	Replaced     bool      `json:"replaced,omitempty"`      // 覆盖前移到覆盖备份文件夹的已有文件
+	Member       string    `json:"member,omitempty"`        // 打包的小文件在压缩包中的名称，这时FinalPath是压缩包
+	OriginalName string    `json:"original_name,omitempty"` // 整理开始时的文件名，整理前在源文件夹中改过名（例如合并副本时恢复原文件名）时与OriginalPath中的不同
+	FinalName    string    `json:"final_name,omitempty"`    // 整理后的文件名（冲突时加的时间戳、规范化后的名称），打包的小文件是压缩包中的名称
+	Operation    string    `json:"operation,omitempty"`     // CatalogMove 或 CatalogCopy
+	NamePrefix   string    `json:"name_prefix,omitempty"`   // 文件名前缀模式下加在文件名前的前缀，重新归档时按此去掉
+	MovedAt      time.Time `json:"moved_at"`
+}
+
+// 校验值中的哈希值部分，没有校验值时为空
+func (e CatalogEntry) checksumHash() string {
+	_, hash, _ := strings.Cut(e.Checksum, ":")
+	return hash
+}
+
+// 整理开始时的文件名，旧的记录没有时使用OriginalPath中的
+func (e CatalogEntry) originalName() string {
+	if e.OriginalName != "" {
+		return e.OriginalName
+	}
+	return filepath.Base(e.OriginalPath)
+}
+
+// 整理后的文件名，旧的记录没有时使用FinalPath中的
+func (e CatalogEntry) finalName() string {
+	switch {
+	case e.FinalName != "":
+		return e.FinalName
+	case e.Member != "":
+		return e.Member
+	}
+	return filepath.Base(e.FinalPath)
+}
+
+// 记录的最终位置，打包的小文件显示为 压缩包 › 文件名
+func (e CatalogEntry) displayPath() string {
+	if e.Member != "" {
+		return e.FinalPath + " › " + e.Member
+	}
+	return e.FinalPath
+}
+
+// 目录文件路径
+func catalogPath() string {
+	return filepath.Join(appDataDir(), catalogFileName)
+}
+
+// 目录的表结构。文件名转为小写后单独保存一列用于按子串搜索，哈希值的各种前缀查询、
+// 按整理编号、原路径、最终路径和目标卷的查询都使用索引
+const catalogSchema = `
+CREATE TABLE IF NOT EXISTS entries (
+	id            INTEGER PRIMARY KEY,
+	run_id        TEXT NOT NULL,
+	original_path TEXT NOT NULL,
+	original_name TEXT NOT NULL DEFAULT '',
+	final_path    TEXT NOT NULL,
+	final_name    TEXT NOT NULL DEFAULT '',
+	member        TEXT NOT NULL DEFAULT '',
+	size          INTEGER NOT NULL DEFAULT 0,
+	hash          TEXT NOT NULL DEFAULT '',
+	checksum      TEXT NOT NULL DEFAULT '',
+	checksum_hash TEXT NOT NULL DEFAULT '',
+	volume        TEXT NOT NULL DEFAULT '',
+	date          TEXT NOT NULL DEFAULT '',
+	replaced      INTEGER NOT NULL DEFAULT 0,
+	operation     TEXT NOT NULL DEFAULT '',
+	moved_at      INTEGER NOT NULL DEFAULT 0,
+	search_names  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS entries_run_id ON entries(run_id);
+CREATE INDEX IF NOT EXISTS entries_original_path ON entries(original_path);
+CREATE INDEX IF NOT EXISTS entries_final_path ON entries(final_path);
+CREATE INDEX IF NOT EXISTS entries_hash ON entries(hash) WHERE hash != '';
+CREATE INDEX IF NOT EXISTS entries_checksum_hash ON entries(checksum_hash) WHERE checksum_hash != '';
+CREATE INDEX IF NOT EXISTS entries_volume ON entries(volume) WHERE volume != '';
+`
+
+// 建立表结构之后新增的列，按数据库的 user_version 依次执行，已有的目录升级后保留原有记录
+var catalogMigrations = []string{
+	`ALTER TABLE entries ADD COLUMN name_prefix TEXT NOT NULL DEFAULT ''`,
+}
+
+// 读取记录时的列，顺序与 scanCatalogEntry 一致
+const catalogColumns = `run_id, original_path, original_name, final_path, final_name, member, size, hash,
+	checksum, volume, date, replaced, operation, moved_at, name_prefix`
+
+// 写入一条记录
+const catalogInsert = `INSERT INTO entries (run_id, original_path, original_name, final_path, final_name, member, size, hash,
+	checksum, checksum_hash, volume, date, replaced, operation, moved_at, search_names, name_prefix)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+var errCatalogCorrupt = errors.New("整理目录已损坏")
+
+// 把 SQLite 报告的文件损坏转换为 errCatalogCorrupt，调用者据此停用目录而不是中止整理
+func catalogError(action string, err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_CORRUPT, sqlite3.SQLITE_NOTADB:
+			return fmt.Errorf("%w: %s: %v", errCatalogCorrupt, action, err)
+		}
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
+
+// 打开目录数据库并建立表结构。使用WAL日志，整理时写入和搜索可以同时进行，
+// 程序崩溃时已提交的批次不会丢失，未提交的批次整个回滚
+func openCatalogDB(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return nil, catalogError("打开整理目录失败", err)
+	}
+	if _, err := db.Exec(catalogSchema); err != nil {
+		db.Close()
+		return nil, catalogError("打开整理目录失败", err)
+	}
+	if err := migrateCatalog(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := importLegacyCatalog(db, legacyCatalogPath(path)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// 执行数据库尚未执行的升级，每一步和记录的版本号在同一个事务中提交
+func migrateCatalog(db *sql.DB) error {
+	for {
+		done, err := migrateCatalogStep(db)
+		if err != nil {
+			return catalogError("升级整理目录失败", err)
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// 执行下一步升级，已是最新版本时返回true
+func migrateCatalogStep(db *sql.DB) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	var version int
+	if err := tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return false, err
+	}
+	if version >= len(catalogMigrations) {
+		return true, nil
+	}
+	if _, err := tx.Exec(catalogMigrations[version]); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+		return false, err
+	}
+	return false, tx.Commit()
+}
+
+// 打开已有的目录用于读取，目录还不存在时返回nil，不创建空的目录文件
+func openCatalogForRead(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("打开整理目录失败: %w", err)
+		}
+		// 只有旧版本的目录时先导入
+		if _, err := os.Stat(legacyCatalogPath(path)); err != nil {
+			return nil, nil
+		}
+	}
+	return openCatalogDB(path)
+}
+
+// 旧版本按行追加的JSON目录，与数据库位于同一文件夹
+func legacyCatalogPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".jsonl"
+}
+
+// 把旧版本的JSON目录导入数据库，导入后改名保留，不再重复导入。无法解析的行跳过
+func importLegacyCatalog(db *sql.DB, legacyPath string) error {
+	file, err := os.Open(legacyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("打开旧的整理目录失败: %w", err)
+	}
+	defer file.Close()
+
+	var entries []CatalogEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry CatalogEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.FinalPath != "" {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取旧的整理目录失败: %w", err)
+	}
+	if err := insertCatalogEntries(db, entries); err != nil {
+		return err
+	}
+	file.Close()
+	if err := os.Rename(legacyPath, legacyPath+".imported"); err != nil {
+		return fmt.Errorf("导入旧的整理目录后改名失败: %w", err)
+	}
+	return nil
+}
+
+// 在一个事务中写入一批记录
+func insertCatalogEntries(db *sql.DB, entries []CatalogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return catalogError("写入整理目录失败", err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(catalogInsert)
+	if err != nil {
+		return catalogError("写入整理目录失败", err)
+	}
+	defer stmt.Close()
+	for _, entry := range entries {
+		// 文件名（不含所在文件夹）转为小写，按子串搜索时不区分大小写
+		names := strings.ToLower(filepath.Base(entry.FinalPath) + "\n" + filepath.Base(entry.OriginalPath) + "\n" + entry.Member)
+		if _, err := stmt.Exec(entry.RunID, entry.OriginalPath, entry.originalName(), entry.FinalPath, entry.finalName(),
+			entry.Member, entry.Size, entry.Hash, entry.Checksum, strings.ToLower(entry.checksumHash()), entry.Volume,
+			entry.Date, entry.Replaced, entry.Operation, entry.MovedAt.UnixNano(), names, entry.NamePrefix); err != nil {
+			return catalogError("写入整理目录失败", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return catalogError("写入整理目录失败", err)
+	}
+	return nil
+}
+
+// fileCatalog 整理过程中批量写入目录。写入失败时目录被停用，整理继续进行
+type fileCatalog struct {
+	db      *sql.DB
+	entries chan CatalogEntry
+	syncs   chan chan struct{} // 立即写入已收到的记录的请求
+	done    chan struct{}
+	mu      sync.Mutex
+	err     error // 第一次写入失败的原因，之后的记录都会被丢弃
+	written int
+}
+
+// 打开目录准备写入记录。目录损坏时返回 errCatalogCorrupt，这次整理不记录目录
+func openCatalog(path string) (*fileCatalog, error) {
+	db, err := openCatalogDB(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &fileCatalog{
+		db:      db,
+		entries: make(chan CatalogEntry, catalogBatchSize*4),
+		syncs:   make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go c.writeLoop()
+	return c, nil
+}
+
+// 记录一个整理完成的文件，不会阻塞工作协程等待磁盘写入
+func (c *fileCatalog) add(entry CatalogEntry) {
+	// 每条记录都带上整理前后的文件名，撤销时按整理前的文件名恢复
+	entry.OriginalName = entry.originalName()
+	entry.FinalName = entry.finalName()
+	c.entries <- entry
+}
+
+// 后台批量写入：凑满一批或等待一段时间后在一个事务中写入
+func (c *fileCatalog) writeLoop() {
+	defer close(c.done)
+	ticker := time.NewTicker(catalogFlushInterval)
+	defer ticker.Stop()
+	batch := make([]CatalogEntry, 0, catalogBatchSize)
+	for {
+		select {
+		case entry, ok := <-c.entries:
+			if !ok {
+				c.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= catalogBatchSize {
+				c.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			c.flush(batch)
+			batch = batch[:0]
+		case ack := <-c.syncs:
+			// 写入通道中已有的全部记录
+			for drained := false; !drained; {
+				select {
+				case entry, ok := <-c.entries:
+					if ok {
+						batch = append(batch, entry)
+					} else {
+						drained = true
+					}
+				default:
+					drained = true
+				}
+			}
+			c.flush(batch)
+			batch = batch[:0]
+			close(ack)
+		}
+	}
+}
+
+// 写入一批记录，出错后停用目录
+func (c *fileCatalog) flush(batch []CatalogEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if err := insertCatalogEntries(c.db, batch); err != nil {
+		c.err = err
+		return
+	}
+	c.written += len(batch)
+}
+
+// 立即写入已经记录的文件，用于强制退出前保证目录和已移动的文件一致。目录已关闭时直接返回
+func (c *fileCatalog) sync() {
+	ack := make(chan struct{})
+	select {
+	case c.syncs <- ack:
+		<-ack
+	case <-c.done:
+	}
+}
+
+// 写入剩余的记录并关闭目录，返回写入的记录数和写入过程中的错误
+func (c *fileCatalog) close() (int, error) {
+	close(c.entries)
+	<-c.done
+	c.db.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.written, c.err
+}
+
+// 读取一行记录
+func scanCatalogEntry(rows *sql.Rows) (CatalogEntry, error) {
+	var entry CatalogEntry
+	var movedAt int64
+	err := rows.Scan(&entry.RunID, &entry.OriginalPath, &entry.OriginalName, &entry.FinalPath, &entry.FinalName,
+		&entry.Member, &entry.Size, &entry.Hash, &entry.Checksum, &entry.Volume, &entry.Date, &entry.Replaced,
+		&entry.Operation, &movedAt, &entry.NamePrefix)
+	entry.MovedAt = time.Unix(0, movedAt)
+	return entry, err
+}
+
+// 按条件查询目录，where 为空时返回全部记录，结果按写入顺序排列（order 为 "DESC" 时从新到旧）。
+// 目录不存在时返回空结果；读到一半发现文件损坏时返回已读取的记录和 errCatalogCorrupt
+func queryCatalog(path, where, order string, limit int, args ...any) ([]CatalogEntry, error) {
+	db, err := openCatalogForRead(path)
+	if err != nil || db == nil {
+		return nil, err
+	}
+	defer db.Close()
+	query := "SELECT " + catalogColumns + " FROM entries"
+	if where != "" {
+		query += " WHERE " + where
+	}
+	query += " ORDER BY id " + order
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, catalogError("读取整理目录失败", err)
+	}
+	defer rows.Close()
+	var entries []CatalogEntry
+	for rows.Next() {
+		entry, err := scanCatalogEntry(rows)
+		if err != nil {
+			return entries, catalogError("读取整理目录失败", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return entries, catalogError("读取整理目录失败", err)
+	}
+	return entries, nil
+}
+
+// 按原路径查找每个文件最近一次移动到的位置，不包括覆盖前备份的已有文件。找不到的路径不在结果中
+func latestCatalogMoves(path string, originals []string) (map[string]CatalogEntry, error) {
+	moved := make(map[string]CatalogEntry)
+	db, err := openCatalogForRead(path)
+	if err != nil || db == nil || len(originals) == 0 {
+		if db != nil {
+			db.Close()
+		}
+		return moved, err
+	}
+	defer db.Close()
+	stmt, err := db.Prepare("SELECT " + catalogColumns + " FROM entries WHERE original_path = ? AND replaced = 0 ORDER BY id DESC LIMIT 1")
+	if err != nil {
+		return moved, catalogError("读取整理目录失败", err)
+	}
+	defer stmt.Close()
+	for _, original := range originals {
+		rows, err := stmt.Query(original)
+		if err != nil {
+			return moved, catalogError("读取整理目录失败", err)
+		}
+		if rows.Next() {
+			entry, err := scanCatalogEntry(rows)
+			if err != nil {
+				rows.Close()
+				return moved, catalogError("读取整理目录失败", err)
+			}
+			moved[original] = entry
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return moved, catalogError("读取整理目录失败", err)
+		}
+	}
+	return moved, nil
+}
+
+// 位于文件夹中的路径的查询范围 [下限, 上限)，最终路径的索引按字节比较
+func pathPrefixRange(dir string) (string, string) {
+	prefix := filepath.Clean(dir)
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	return prefix, prefix + "\U0010FFFF"
+}
+
+// 在目录中搜索：按文件名（原文件名或最终文件名）的子串匹配，不区分大小写；
+// 输入看起来像哈希值时也按哈希前缀匹配（使用索引）。结果按整理时间从新到旧排列
+func searchCatalog(path, query string) ([]CatalogEntry, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, nil
+	}
+	where, args := "instr(search_names, ?) > 0", []any{query}
+	if len(query) >= 6 && strings.Trim(query, "0123456789abcdef") == "" {
+		// 哈希值只含小写十六进制字符，前缀查询换成索引上的范围查询
+		upper := query + "g"
+		where = "(hash >= ? AND hash < ?) OR (checksum_hash >= ? AND checksum_hash < ?) OR " + where
+		args = []any{query, upper, query, upper, query}
+	}
+	return queryCatalog(path, where, "DESC", catalogSearchLimit, args...)
+}
+
+// 在系统的文件管理器中打开文件所在的文件夹
+func openContainingFolder(path string) error {
+	u, err := url.Parse(storage.NewFileURI(filepath.Dir(path)).String())
+	if err != nil {
+		return err
+	}
+	return fyne.CurrentApp().OpenURL(u)
+}
+
+// 显示整理目录的搜索对话框
+func (fo *FileOrganizer) showCatalogSearchDialog() {
+	// 关闭整理目录后已有的记录仍可搜索，只是之后整理的文件不再记录
+	hint := "输入文件名的一部分或哈希值的前几位"
+	if !fo.CatalogEnabled {
+		if _, err := os.Stat(catalogPath()); err != nil {
+			dialog.ShowInformation("提示", "整理目录未开启，还没有任何记录。请在「更多设置」中开启后再整理文件", fo.Window)
+			return
+		}
+		hint = "整理目录已关闭，只能搜索关闭之前的记录。" + hint
+	}
+
+	var results []CatalogEntry
+	status := widget.NewLabel(hint)
+	resultList := widget.NewList(
+		func() int { return len(results) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, nil, widget.NewButton("打开所在文件夹", nil), label)
+		},
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			if id >= len(results) {
+				return
+			}
+			entry := results[id]
+			row := o.(*fyne.Container)
+			text := fmt.Sprintf("%s  ←  %s  （%s）", entry.displayPath(), entry.OriginalPath, entry.MovedAt.Local().Format("2006-01-02 15:04"))
+			if entry.Checksum != "" {
+				text += "  " + entry.Checksum
+			}
+			row.Objects[0].(*widget.Label).SetText(text)
+			row.Objects[1].(*widget.Button).OnTapped = func() {
+				if _, err := os.Stat(entry.FinalPath); err != nil {
+					dialog.ShowError(fmt.Errorf("文件已不在记录的位置: %s", entry.FinalPath), fo.Window)
+					return
+				}
+				if err := openContainingFolder(entry.FinalPath); err != nil {
+					dialog.ShowError(err, fo.Window)
+				}
+			}
+		},
+	)
+
+	queryEntry := widget.NewEntry()
+	queryEntry.SetPlaceHolder("例如 IMG_4021 或 3fa9c2")
+	search := func() {
+		found, err := searchCatalog(catalogPath(), queryEntry.Text)
+		results = found
+		resultList.Refresh()
+		switch {
+		case errors.Is(err, errCatalogCorrupt):
+			status.SetText(fmt.Sprintf("整理目录已损坏，只显示能读取的 %d 条记录", len(found)))
+		case err != nil:
+			status.SetText(err.Error())
+		case len(found) >= catalogSearchLimit:
+			status.SetText(fmt.Sprintf("找到超过 %d 条记录，只显示最新的 %d 条", catalogSearchLimit, catalogSearchLimit))
+		default:
+			status.SetText(fmt.Sprintf("找到 %d 条记录", len(found)))
+		}
+	}
+	queryEntry.OnSubmitted = func(string) { search() }
+	searchBtn := widget.NewButtonWithIcon("搜索", theme.SearchIcon(), search)
+	var searchDialog dialog.Dialog
+	rollbackBtn := widget.NewButtonWithIcon("撤销部分文件...", theme.ContentUndoIcon(), func() {
+		searchDialog.Hide()
+		fo.showRollbackDialog()
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(container.NewBorder(nil, nil, nil, container.NewHBox(searchBtn, rollbackBtn), queryEntry), status),
+		nil, nil, nil,
+		resultList,
+	)
+	searchDialog = dialog.NewCustom("搜索整理目录", "关闭", content, fo.Window)
+	searchDialog.Resize(fyne.NewSize(760, 520))
+	fo.showDialog(searchDialog, queryEntry)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 把记录写入目录数据库，返回目录路径
+func writeTestCatalog(t *testing.T, path string, entries []CatalogEntry) string {
+	t.Helper()
+	catalog, err := openCatalog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		catalog.add(entry)
+	}
+	if written, err := catalog.close(); err != nil || written != len(entries) {
+		t.Fatalf("写入目录: %d, %v", written, err)
+	}
+	return path
+}
+
+// 连续生成的整理编号不重复，按字符串排序就是生成顺序
+func TestNewRunIDUnique(t *testing.T) {
+	ids := make([]string, 1000)
+	seen := make(map[string]bool)
+	for i := range ids {
+		ids[i] = newRunID()
+		if seen[ids[i]] {
+			t.Fatalf("重复的整理编号 %s", ids[i])
+		}
+		seen[ids[i]] = true
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Fatal("整理编号没有按时间排序")
+	}
+}
+
+func TestParseRunID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+		ok   bool
+	}{
+		{"20240102_030405.678", "2024-01-02 03:04:05.678", true},
+		{"20240102_030405", "2024-01-02 03:04:05.000", true},
+		{"备份", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRunID(tt.id)
+		if ok != tt.ok || (ok && got.Format("2006-01-02 15:04:05.000") != tt.want) {
+			t.Errorf("parseRunID(%q) = %v, %v", tt.id, got, ok)
+		}
+	}
+}
+
+// 同一秒内的两次整理分别记录，撤销最近一次只撤销第二次
+func TestQuickRunsHaveSeparateCatalogRuns(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	config := Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      t.TempDir(),
+		FileExtensions: []string{".jpg"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		CatalogEnabled: true,
+		ExcludedFiles:  map[string]bool{},
+	}
+	for _, name := range []string{"a.jpg", "b.jpg"} {
+		file := writeTestFile(t, filepath.Join(source, name), name)
+		if _, err := fo.processFiles(config, []string{file}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, entries, err := lastCatalogRun(catalogPath())
+	if err != nil || len(entries) != 1 || filepath.Base(entries[0].OriginalPath) != "b.jpg" {
+		t.Fatalf("最近一次整理: %+v, %v", entries, err)
+	}
+}
+
+// 按文件名子串（不区分大小写）或哈希前缀搜索，结果从新到旧
+func TestSearchCatalog(t *testing.T) {
+	dir := t.TempDir()
+	moved := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	path := writeTestCatalog(t, filepath.Join(dir, catalogFileName), []CatalogEntry{
+		{RunID: "1", OriginalPath: "/in/IMG_4021.JPG", FinalPath: "/out/.jpg/IMG_4021.JPG", Hash: "3fa9c2d1", MovedAt: moved},
+		{RunID: "2", OriginalPath: "/in/Café.txt", FinalPath: "/out/.txt/Café.txt", Checksum: "sha256:ABCDEF0123", MovedAt: moved},
+		{RunID: "2", OriginalPath: "/in/notes.md", FinalPath: "/out/2024-03.zip", Member: "notes.md", MovedAt: moved},
+		{RunID: "3", OriginalPath: "/in/img_4021.jpg", FinalPath: "/out/.jpg/img_4021_20240301_120000.jpg", MovedAt: moved},
+	})
+	tests := []struct {
+		query string
+		want  []string // 结果的最终路径
+	}{
+		{"img_4021", []string{"/out/.jpg/img_4021_20240301_120000.jpg", "/out/.jpg/IMG_4021.JPG"}},
+		{"CAFÉ", []string{"/out/.txt/Café.txt"}},
+		{"notes", []string{"/out/2024-03.zip"}},
+		{"3fa9c2", []string{"/out/.jpg/IMG_4021.JPG"}},
+		{"abcdef", []string{"/out/.txt/Café.txt"}},
+		{"out", nil},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			entries, err := searchCatalog(path, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.FinalPath)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("搜索 %q = %v, 期望 %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// 读取最近一次整理、删除撤销的记录、按原路径查找和按目标文件夹读取都只返回对应的记录
+func TestCatalogQueries(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestCatalog(t, filepath.Join(dir, catalogFileName), []CatalogEntry{
+		{RunID: "20240301_120000.000", OriginalPath: "/in/a.jpg", FinalPath: "/out/a.jpg"},
+		{RunID: "20240302_120000.000", OriginalPath: "/in/b.jpg", FinalPath: "/out/b.jpg"},
+		{RunID: "20240302_120000.000", OriginalPath: "/in/c.txt", FinalPath: "/out/2024.zip", Member: "c.txt"},
+		{RunID: "20240302_120000.000", OriginalPath: "/out/b.jpg", FinalPath: "/backup/b.jpg", Replaced: true},
+	})
+	tests := []struct {
+		name string
+		run  func(t *testing.T) []string
+		want []string
+	}{
+		{"最近一次整理", func(t *testing.T) []string {
+			runID, entries, err := lastCatalogRun(path)
+			if err != nil || runID != "20240302_120000.000" {
+				t.Fatalf("最近一次整理 = %s, %v", runID, err)
+			}
+			return finalPaths(entries)
+		}, []string{"/out/b.jpg", "/out/2024.zip", "/backup/b.jpg"}},
+		{"删除撤销的记录", func(t *testing.T) []string {
+			remove := map[catalogKey]bool{{runID: "20240302_120000.000", finalPath: "/out/2024.zip", member: "c.txt"}: true}
+			if err := removeCatalogEntries(path, remove); err != nil {
+				t.Fatal(err)
+			}
+			_, entries, err := lastCatalogRun(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return finalPaths(entries)
+		}, []string{"/out/b.jpg", "/backup/b.jpg"}},
+		{"按原路径查找", func(t *testing.T) []string {
+			moved, err := latestCatalogMoves(path, []string{"/in/a.jpg", "/out/b.jpg", "/in/missing.jpg"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for original, entry := range moved {
+				got = append(got, original+" → "+entry.FinalPath)
+			}
+			return got
+		}, []string{"/in/a.jpg → /out/a.jpg"}},
+		{"目录不存在", func(t *testing.T) []string {
+			runID, entries, err := lastCatalogRun(filepath.Join(dir, "missing.db"))
+			if err != nil || runID != "" {
+				t.Fatalf("最近一次整理 = %s, %v", runID, err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "missing.db")); !os.IsNotExist(err) {
+				t.Fatal("读取时不应创建目录")
+			}
+			return finalPaths(entries)
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.run(t); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("结果 = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 记录的最终路径
+func finalPaths(entries []CatalogEntry) []string {
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.FinalPath)
+	}
+	return paths
+}
+
+// 旧版本的JSON目录在第一次打开时导入，之后改名保留，不会重复导入；无法解析的行跳过
+func TestImportLegacyCatalog(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, catalogFileName)
+	var lines []string
+	for _, entry := range []CatalogEntry{
+		{RunID: "1", OriginalPath: "/in/a.jpg", FinalPath: "/out/a.jpg", Hash: "abc123"},
+		{RunID: "2", OriginalPath: "/in/b.jpg", FinalPath: "/out/b.jpg"},
+	} {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(data))
+	}
+	lines = append(lines, "{broken")
+	legacy := writeTestFile(t, legacyCatalogPath(path), strings.Join(lines, "\n")+"\n")
+
+	for i := 0; i < 2; i++ {
+		runID, entries, err := lastCatalogRun(path)
+		if err != nil || runID != "2" || len(entries) != 1 || entries[0].FinalPath != "/out/b.jpg" {
+			t.Fatalf("第 %d 次读取: %s %+v, %v", i+1, runID, entries, err)
+		}
+	}
+	if found, err := searchCatalog(path, "abc123"); err != nil || len(found) != 1 {
+		t.Fatalf("搜索导入的记录: %+v, %v", found, err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatal("导入后旧的目录应改名")
+	}
+}
+
+// 没有新增列的旧目录打开时升级，原有记录保留，之后写入的记录带有新增的列
+func TestMigrateCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), catalogFileName)
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(catalogSchema); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO entries (run_id, original_path, final_path) VALUES ('1', '/in/a.jpg', '/out/2024_a.jpg')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	writeTestCatalog(t, path, []CatalogEntry{
+		{RunID: "2", OriginalPath: "/in/b.jpg", FinalPath: "/out/jpg_b.jpg", NamePrefix: "jpg"},
+	})
+	prefixes, err := loadNamePrefixes(path, "/out")
+	if err != nil || !reflect.DeepEqual(prefixes, map[string]string{"/out/jpg_b.jpg": "jpg"}) {
+		t.Fatalf("记录的前缀 = %v, %v", prefixes, err)
+	}
+	// 再次打开不重复升级
+	if entries, err := queryCatalog(path, "", "", 0); err != nil || len(entries) != 2 {
+		t.Fatalf("升级后的记录 = %+v, %v", entries, err)
+	}
+}
+
+// 目录文件损坏时读取返回 errCatalogCorrupt，整理时停用目录，文件照常整理
+func TestCorruptCatalogDisablesCatalog(t *testing.T) {
+	fo := newTestOrganizer(t)
+	writeTestFile(t, catalogPath(), strings.Repeat("not a database\n", 1000))
+	if _, err := openCatalog(catalogPath()); !errors.Is(err, errCatalogCorrupt) {
+		t.Fatalf("打开损坏的目录: %v", err)
+	}
+	if _, _, err := lastCatalogRun(catalogPath()); !errors.Is(err, errCatalogCorrupt) {
+		t.Fatalf("读取损坏的目录: %v", err)
+	}
+
+	source, target := t.TempDir(), t.TempDir()
+	file := writeTestFile(t, filepath.Join(source, "a.jpg"), "photo")
+	config := Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      target,
+		FileExtensions: []string{".jpg"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		CatalogEnabled: true,
+		ExcludedFiles:  map[string]bool{},
+	}
+	if summary, err := fo.processFiles(config, []string{file}); err != nil || summary.Failed != 0 {
+		t.Fatalf("整理: %+v, %v", summary, err)
+	}
+	if _, err := os.Stat(filepath.Join(target, ".jpg", "a.jpg")); err != nil {
+		t.Fatalf("目录损坏时文件也应整理: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 整理时生成校验清单使用的哈希算法
+const (
+	ChecksumNone   = "none"
+	ChecksumMD5    = "md5"
+	ChecksumSHA1   = "sha1"
+	ChecksumSHA256 = "sha256"
+)
+
+// 创建指定算法的哈希计算器
+func newChecksumHasher(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case ChecksumMD5:
+		return md5.New(), nil
+	case ChecksumSHA1:
+		return sha1.New(), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("不支持的校验算法: %s", algorithm)
+}
+
+// 用指定算法计算文件内容的哈希值（十六进制）
+func hashFileWith(path, algorithm string) (string, error) {
+	hasher, err := newChecksumHasher(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	started := time.Now()
+	read, err := io.Copy(hasher, file)
+	perfCounters.bytesRead.Add(read)
+	if err != nil {
+		return "", fmt.Errorf("读取文件失败: %w", err)
+	}
+	observePerf(perfOpHash, started)
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// 校验清单所在的文件夹，位于目标根文件夹中，整理时跳过
+const ChecksumsFolderName = "_checksums"
+
+// 校验清单的文件夹
+func checksumsRoot(targetDir string) string {
+	return filepath.Join(targetDir, ChecksumsFolderName)
+}
+
+// checksumManifest 本次整理的文件及其哈希值，整理完成后导出为
+// 与 md5sum/sha1sum/sha256sum 兼容的校验文件
+type checksumManifest struct {
+	mu        sync.Mutex
+	algorithm string
+	root      string
+	entries   map[string]string // 最终路径 → 哈希值
+}
+
+// 创建校验清单，root为目标根文件夹，清单中的路径相对于它
+func newChecksumManifest(root, algorithm string) *checksumManifest {
+	return &checksumManifest{algorithm: algorithm, root: root, entries: make(map[string]string)}
+}
+
+// 记录一个已整理的文件，hash为空时计算文件的哈希值。返回记录的哈希值，同时写入整理目录
+func (m *checksumManifest) add(path, hash string) (string, error) {
+	if hash == "" {
+		var err error
+		hash, err = hashFileWith(path, m.algorithm)
+		if err != nil {
+			return "", err
+		}
+	}
+	m.mu.Lock()
+	m.entries[path] = hash
+	m.mu.Unlock()
+	return hash, nil
+}
+
+// 校验文件中的一行。与 GNU coreutils 相同，路径含反斜杠或换行时转义，并在行首加反斜杠
+func checksumLine(hash, name string) string {
+	if !strings.ContainsAny(name, "\\\n\r") {
+		return hash + "  " + name + "\n"
+	}
+	escaped := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(name)
+	return "\\" + hash + "  " + escaped + "\n"
+}
+
+// 清单中的文件数量
+func (m *checksumManifest) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// 将清单写入目标根文件夹中的 _checksums，返回校验文件的路径。每行为 "哈希值  相对于目标根文件夹的路径"，
+// 在目标根文件夹中运行 sha256sum -c _checksums/<校验文件> 即可校验
+func (m *checksumManifest) write(now time.Time) (string, error) {
+	m.mu.Lock()
+	paths := make([]string, 0, len(m.entries))
+	for path := range m.entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, path := range paths {
+		name := path
+		if rel, err := filepath.Rel(m.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+		sb.WriteString(checksumLine(m.entries[path], filepath.ToSlash(name)))
+	}
+	m.mu.Unlock()
+
+	if err := os.MkdirAll(checksumsRoot(m.root), 0755); err != nil {
+		return "", fmt.Errorf("保存校验文件失败: %w", err)
+	}
+	manifestPath := filepath.Join(checksumsRoot(m.root), fmt.Sprintf("checksums_%s.%s", now.Format("20060102_150405"), m.algorithm))
+	if err := os.WriteFile(manifestPath, []byte(sb.String()), 0644); err != nil {
+		return "", fmt.Errorf("保存校验文件失败: %w", err)
+	}
+	return manifestPath, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// 路径含反斜杠或换行时与 sha256sum 一样转义，并在行首加反斜杠
+func TestChecksumLine(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"2024/a.jpg", "abc  2024/a.jpg\n"},
+		{"a b.jpg", "abc  a b.jpg\n"},
+		{`a\b.jpg`, `\abc  a\\b.jpg` + "\n"},
+		{"a\nb.jpg", `\abc  a\nb.jpg` + "\n"},
+		{"a\rb.jpg", `\abc  a\rb.jpg` + "\n"},
+	}
+	for _, tt := range tests {
+		if got := checksumLine("abc", tt.name); got != tt.want {
+			t.Errorf("checksumLine(%q) = %q, 期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 整理时每个文件的校验值写入整理目录，校验清单放在 _checksums 中，下次整理不会整理清单
+func TestChecksumManifest(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		tool      string
+		want      map[string]string // 文件名 → 校验值
+	}{
+		{ChecksumMD5, "md5sum", map[string]string{"a.txt": "md5:0cc175b9c0f1b6a831c399e269772661", "b.txt": "md5:92eb5ffee6ae2fec3ad71c777531578f"}},
+		{ChecksumSHA1, "sha1sum", map[string]string{"a.txt": "sha1:86f7e437faa5a7fce15d1ddcb9eaeaea377667b8", "b.txt": "sha1:e9d71f5ee7c92d6dc9e92ffdad17b8bd49418f98"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			target := t.TempDir()
+			files := []string{
+				writeTestFile(t, filepath.Join(source, "a.txt"), "a"),
+				writeTestFile(t, filepath.Join(source, "b.txt"), "b"),
+			}
+			config := Config{
+				SourceDir:         source,
+				SourceDirs:        []string{source},
+				TargetDir:         target,
+				FileExtensions:    []string{".txt", "." + tt.algorithm},
+				OrganizeRule:      string(RuleByExtension),
+				ExtensionCase:     "lowercase",
+				ConflictPolicy:    ConflictRename,
+				ChecksumAlgorithm: tt.algorithm,
+				CatalogEnabled:    true,
+				ExcludedFiles:     map[string]bool{},
+			}
+			if summary, err := fo.processFiles(config, files); err != nil || summary.Failed != 0 {
+				t.Fatalf("整理: %+v, %v", summary, err)
+			}
+			_, entries, err := lastCatalogRun(catalogPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, entry := range entries {
+				got[filepath.Base(entry.FinalPath)] = entry.Checksum
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("整理目录中的校验值 = %v, 期望 %v", got, tt.want)
+			}
+
+			manifests, err := filepath.Glob(filepath.Join(checksumsRoot(target), "*."+tt.algorithm))
+			if err != nil || len(manifests) != 1 {
+				t.Fatalf("校验清单 = %v, %v", manifests, err)
+			}
+			if _, err := exec.LookPath(tt.tool); err == nil && runtime.GOOS != "windows" {
+				cmd := exec.Command(tt.tool, "-c", filepath.Join(ChecksumsFolderName, filepath.Base(manifests[0])))
+				cmd.Dir = target
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("%s -c: %v\n%s", tt.tool, err, out)
+				}
+			}
+
+			// 再次整理目标文件夹，清单留在原处
+			config.SourceDir, config.SourceDirs = target, []string{target}
+			if _, err := fo.processFiles(config, []string{manifests[0]}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(manifests[0]); err != nil {
+				t.Fatalf("校验清单被整理: %v", err)
+			}
+			for path := range snapshotTree(t, target) {
+				if strings.HasPrefix(path, "."+tt.algorithm) {
+					t.Fatalf("校验清单被移到 %s", path)
+				}
+			}
+		})
+	}
+}
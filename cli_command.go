@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 生成与本次整理等效的命令行调用，便于用脚本重现同样的整理
+func cliCommandFor(sourceDirs []string, config Config) string {
+	args := []string{"file_organizer", "-headless"}
+	for _, dir := range sourceDirs {
+		args = append(args, "-source", quoteShellArg(dir))
+	}
+	for _, dir := range config.ReadOnlySources {
+		args = append(args, "-read-only-source", quoteShellArg(dir))
+	}
+	args = append(args,
+		"-target", quoteShellArg(config.TargetDir),
+		"-rule", quoteShellArg(config.OrganizeRule),
+		"-ext", quoteShellArg(strings.Join(config.FileExtensions, ",")),
+		"-date-format", quoteShellArg(config.FolderDateFormat),
+		"-ext-case", quoteShellArg(config.ExtensionCase),
+		"-layout", quoteShellArg(config.FolderLayout),
+		"-multi-tag", quoteShellArg(config.MultiTagMode),
+	)
+	if len(config.Volumes) > 0 {
+		if config.Volumes[0].MinFreeMB > 0 {
+			args = append(args, "-target-min-free", strconv.FormatInt(config.Volumes[0].MinFreeMB, 10))
+		}
+		for _, volume := range config.Volumes[1:] {
+			args = append(args, "-spill-target", quoteShellArg(fmt.Sprintf("%s=%d", volume.Root, volume.MinFreeMB)))
+		}
+	}
+	if OrganizeRule(config.OrganizeRule) == RuleByHash {
+		args = append(args, "-hash-shards", fmt.Sprintf("%dx%d", config.HashShardDepth, config.HashShardWidth))
+		if config.HashRename {
+			args = append(args, "-hash-rename")
+		}
+	}
+	if config.Profile != "" {
+		args = append(args, "-profile", quoteShellArg(config.Profile))
+	}
+	if config.FolderTemplate != "" && OrganizeRule(config.OrganizeRule) != RuleByHash {
+		args = append(args, "-folder-template", quoteShellArg(config.FolderTemplate))
+	}
+	if OrganizeRule(config.OrganizeRule) == RuleByExtension && len(config.RareExtensions) > 0 {
+		args = append(args, "-compact-extensions", strconv.Itoa(config.CompactExtensionsMin))
+	}
+	if len(config.ExtensionRanks) > 0 {
+		args = append(args, "-extension-ranks", quoteShellArg(formatExtensionRanks(config.ExtensionRanks)))
+	}
+	if config.VolumeCapMB > 0 {
+		args = append(args, "-volume-cap-mb", strconv.FormatInt(config.VolumeCapMB, 10))
+	}
+	if OrganizeRule(config.OrganizeRule) == RuleComposite {
+		args = append(args, "-rule-segments", formatRuleSegments(effectiveRuleSegments(config)))
+		if config.RuleSegmentSeparator != "" {
+			args = append(args, "-segment-separator", quoteShellArg(config.RuleSegmentSeparator))
+		}
+	}
+	if OrganizeRule(config.OrganizeRule) == RuleByAge && strings.Join(config.AgeBucketLabels, ",") != strings.Join(defaultAgeBucketLabels, ",") {
+		args = append(args, "-age-labels", quoteShellArg(strings.Join(config.AgeBucketLabels, ",")))
+	}
+	if OrganizeRule(config.OrganizeRule) == RuleByTypeSize && len(config.SizeBucketsMB) > 0 &&
+		formatSizeBuckets(config.SizeBucketsMB) != formatSizeBuckets(defaultSizeBucketsMB) {
+		args = append(args, "-size-buckets", quoteShellArg(formatSizeBuckets(config.SizeBucketsMB)))
+	}
+	if len(config.DateSources) > 0 && formatDateSources(config.DateSources) != formatDateSources(defaultDateSources) {
+		args = append(args, "-date-sources", formatDateSources(config.DateSources))
+	}
+	if loc := config.location(); loc != time.Local {
+		args = append(args, "-timezone", loc.String())
+	}
+	if config.Bursts != nil {
+		args = append(args, "-bursts", fmt.Sprintf("%dx%d", config.BurstMaxGap, config.BurstMaxFrames))
+	}
+	if config.DateFolderMtime {
+		args = append(args, "-date-folder-mtime")
+	}
+	if config.PackSmallFiles && OrganizeRule(config.OrganizeRule) == RuleByDate {
+		args = append(args, "-pack-small-kb", strconv.Itoa(config.PackThresholdKB))
+	}
+	if config.EmailSenderFolders && OrganizeRule(config.OrganizeRule) == RuleByDate {
+		args = append(args, "-email-sender")
+	}
+	if config.ConvertImages {
+		args = append(args, "-convert-heic")
+		if !config.KeepConverted {
+			args = append(args, "-discard-converted-originals")
+		}
+	}
+	if config.LowPriority {
+		args = append(args, "-low-priority")
+	}
+	if config.IncomingOnly {
+		args = append(args, "-incoming-only")
+	}
+	if config.DedupTarget {
+		args = append(args, "-dedup")
+	}
+	if config.CatalogEnabled {
+		args = append(args, "-catalog")
+	}
+	if config.DedupEmptyFiles {
+		args = append(args, "-dedup-empty")
+	}
+	if config.RemoveEmptiedDirs {
+		args = append(args, "-remove-empty-dirs")
+	}
+	if config.MoveEmptyDirs {
+		args = append(args, "-move-empty-dirs")
+	}
+	// 令牌不写入命令行，避免出现在日志和剪贴板中
+	if config.PostRunHook != "" {
+		args = append(args, "-post-run-hook", quoteShellArg(config.PostRunHook))
+	}
+	if config.PerFileHook != "" {
+		args = append(args, "-per-file-hook", quoteShellArg(config.PerFileHook))
+	}
+	if (config.PostRunHook != "" || config.PerFileHook != "") && config.HookTimeoutSec != defaultHookTimeoutSec {
+		args = append(args, "-hook-timeout", strconv.Itoa(config.HookTimeoutSec))
+	}
+	if config.StatsEndpoint != "" {
+		args = append(args, "-stats-endpoint", quoteShellArg(config.StatsEndpoint))
+	}
+	if config.TakeoutMode {
+		args = append(args, "-takeout")
+	}
+	if config.CopyMerge != "" && config.CopyMerge != CopyMergeOff {
+		args = append(args, "-merge-copies", config.CopyMerge)
+	}
+	if config.SourceSnapshot != "" {
+		args = append(args, "-snapshot", quoteShellArg(config.SourceSnapshot))
+	}
+	if config.ChecksumAlgorithm != "" && config.ChecksumAlgorithm != ChecksumNone {
+		args = append(args, "-checksum", config.ChecksumAlgorithm)
+	}
+	if config.EmptyFilePolicy != "" && config.EmptyFilePolicy != EmptyFileOrganize {
+		args = append(args, "-empty-files", config.EmptyFilePolicy)
+	}
+	if config.LogVerbosity != "" && config.LogVerbosity != LogNormal {
+		args = append(args, "-verbosity", config.LogVerbosity)
+	}
+	if config.ShortcutPolicy != "" && config.ShortcutPolicy != ShortcutOrganize {
+		args = append(args, "-shortcuts", config.ShortcutPolicy)
+	}
+	if config.OrganizeLibraries {
+		args = append(args, "-organize-libraries")
+	}
+	if config.TextLanguageFolders {
+		args = append(args, "-text-language")
+	}
+	if len(config.TextKeywordRules) > 0 {
+		args = append(args, "-text-keywords", quoteShellArg(formatTextKeywordRules(config.TextKeywordRules, ";")))
+		if config.TextKeywordCase {
+			args = append(args, "-text-case-sensitive")
+		}
+	}
+	if textRulesEnabled(config) && config.TextPrefixKB > 0 && config.TextPrefixKB != defaultTextPrefixKB {
+		args = append(args, "-text-prefix-kb", strconv.Itoa(config.TextPrefixKB))
+	}
+	switch config.ConflictPolicy {
+	case ConflictOverwrite:
+		args = append(args, "-on-conflict", ConflictOverwrite)
+		if !config.BackupReplaced {
+			args = append(args, "-backup-replaced=false")
+		}
+	case ConflictMerge:
+		args = append(args, "-on-conflict", ConflictMerge)
+	}
+	if config.ParallelThreshold != defaultParallelThreshold {
+		args = append(args, "-parallel-threshold", strconv.Itoa(config.ParallelThreshold))
+	}
+	if config.SmallSetWorkers != defaultSmallSetWorkers {
+		args = append(args, "-small-set-workers", strconv.Itoa(config.SmallSetWorkers))
+	}
+	return strings.Join(args, " ")
+}
+
+// 按当前平台的shell规则给参数加引号，只含安全字符的参数保持原样
+func quoteShellArg(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			strings.ContainsRune("-_./:,=+@", r) || (runtime.GOOS == "windows" && r == '\\'))
+	}) < 0 {
+		return arg
+	}
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// FAT和exFAT的修改时间精度为2秒，比较修改时间时允许的误差
+const coarseMtimeResolution = 2 * time.Second
+
+// 修改时间精度低、并且往往没有时区的文件系统（U盘和存储卡常用）。exFAT的UTC偏移字段是可选的，
+// 写入设备经常不填写
+var coarseMtimeFilesystems = map[string]bool{
+	"vfat":  true,
+	"msdos": true,
+	"fat":   true,
+	"fat16": true,
+	"fat32": true,
+	"exfat": true,
+}
+
+// 路径所在的文件系统是否只有粗略的修改时间，返回文件系统类型。路径不存在时检查最近的已存在的上级
+func coarseMtimeFilesystem(path string) (string, bool) {
+	for current := filepath.Clean(path); ; {
+		if _, err := os.Stat(current); err == nil {
+			fsType, err := filesystemType(current)
+			if err != nil {
+				return "", false
+			}
+			return fsType, coarseMtimeFilesystems[strings.ToLower(fsType)]
+		} else if !os.IsNotExist(err) && !errors.Is(err, syscall.ENOTDIR) {
+			return "", false
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", false
+		}
+		current = parent
+	}
+}
+
+// 两个修改时间是否相同。coarse为true时允许文件系统精度造成的误差
+func sameModTime(a, b time.Time, coarse bool) bool {
+	if !coarse {
+		return a.Equal(b)
+	}
+	diff := a.Sub(b)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= coarseMtimeResolution
+}
+
+// recordedDate 整理目录中记录的整理所用的日期，以及当时的文件大小
+type recordedDate struct {
+	date string // YYYY-MM-DD
+	size int64
+}
+
+// 读取整理目录中位于目标文件夹中的文件记录的日期，同一个路径以最后一次整理为准。
+// 打包的小文件和没有记录日期的文件不在其中
+func loadRecordedDates(path, targetDir string) (map[string]recordedDate, error) {
+	low, high := pathPrefixRange(targetDir)
+	entries, err := queryCatalog(path, "member = '' AND final_path >= ? AND final_path < ?", "", 0, low, high)
+	dates := make(map[string]recordedDate)
+	for _, entry := range entries {
+		if !isWithinAny(entry.FinalPath, []string{targetDir}) {
+			continue
+		}
+		if entry.Date == "" {
+			delete(dates, entry.FinalPath)
+			continue
+		}
+		dates[entry.FinalPath] = recordedDate{date: entry.Date, size: entry.Size}
+	}
+	return dates, err
+}
+
+// 文件在整理目录中记录的日期，按整理使用的时区的当天零点返回。文件大小与记录时不同时认为是另一个文件
+func (c Config) recordedDateOf(filePath string, fileInfo os.FileInfo) (time.Time, bool) {
+	recorded, ok := c.RecordedDates[filePath]
+	if !ok || fileInfo == nil || fileInfo.Size() != recorded.size {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation(eventDateLayout, recorded.date, c.location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// 目标在FAT/exFAT上时，每个目标文件夹在日志中说明一次对应的处理
+func (fo *FileOrganizer) warnCoarseMtime(targetDir, fsType string) {
+	if _, warned := fo.coarseMtimeWarned.LoadOrStore(targetDir, true); warned {
+		return
+	}
+	fo.log(fmt.Sprintf("注意: 目标文件夹 %s 位于 %s 文件系统，修改时间精度只有2秒并且可能没有记录时区。"+
+		"复制的文件保留源文件的修改时间，比较修改时间时允许2秒误差；开启整理目录时同时记录整理所用的日期，"+
+		"以后从这个磁盘重新整理时，修改时间可能因时区不同而偏移，重新归档的文件按整理目录中记录的日期整理", targetDir, fsType))
+}
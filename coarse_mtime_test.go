@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 整理目录中记录的日期：只读取目标中的文件，同一个路径以最后一次整理为准，打包的小文件不在其中
+func TestLoadRecordedDates(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "usb")
+	entries := []CatalogEntry{
+		{RunID: "1", FinalPath: filepath.Join(target, "a.jpg"), Size: 1, Date: "2024-01-01"},
+		{RunID: "2", FinalPath: filepath.Join(target, "a.jpg"), Size: 2, Date: "2024-02-02"},
+		{RunID: "1", FinalPath: filepath.Join(target, "b.jpg"), Size: 1, Date: "2024-01-01"},
+		{RunID: "2", FinalPath: filepath.Join(target, "b.jpg"), Size: 1},
+		{RunID: "1", FinalPath: filepath.Join(target, "2024-01.zip"), Member: "c.txt", Size: 1, Date: "2024-01-01"},
+		{RunID: "1", FinalPath: filepath.Join(dir, "other", "d.jpg"), Size: 1, Date: "2024-01-01"},
+	}
+	path := writeTestCatalog(t, filepath.Join(dir, catalogFileName), entries)
+
+	dates, err := loadRecordedDates(path, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]recordedDate{filepath.Join(target, "a.jpg"): {date: "2024-02-02", size: 2}}
+	if len(dates) != len(want) || dates[filepath.Join(target, "a.jpg")] != want[filepath.Join(target, "a.jpg")] {
+		t.Fatalf("记录的日期 = %v, 期望 %v", dates, want)
+	}
+}
+
+// 重新归档FAT/exFAT目标中的文件时按记录的日期划分，文件已被替换（大小不同）时按修改时间
+func TestFileDateUsesRecordedDate(t *testing.T) {
+	fo := newTestOrganizer(t)
+	mtime := time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		recorded *recordedDate
+		want     string
+	}{
+		{"没有记录", nil, "2024-03-01"},
+		{"有记录", &recordedDate{date: "2024-02-29", size: 5}, "2024-02-29"},
+		{"大小不同", &recordedDate{date: "2024-02-29", size: 6}, "2024-03-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, filepath.Join(t.TempDir(), "a.jpg"), "photo")
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := Config{Location: time.UTC, DateSources: []DateSource{DateSourceMtime}}
+			if tt.recorded != nil {
+				config.RecordedDates = map[string]recordedDate{path: *tt.recorded}
+			}
+			if got := getFileModifyDate(fo.fileDate(path, info, config), "YYYY-MM-DD", config.location()); got != tt.want {
+				t.Fatalf("日期文件夹 = %s, 期望 %s", got, tt.want)
+			}
+		})
+	}
+}
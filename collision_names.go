@@ -0,0 +1,65 @@
+package main
+
+import "fmt"
+
+// 目标中已有同名文件时，新文件名中时间戳与原文件名之间的分隔方式
+const (
+	CollisionSeparatorUnderscore = "_"  // name_20240315_101500.ext
+	CollisionSeparatorDash       = "-"  // name-20240315_101500.ext
+	CollisionSeparatorSpace      = " "  // name 20240315_101500.ext
+	CollisionSeparatorParens     = "()" // name (20240315_101500).ext
+)
+
+// 时间戳的位置
+const (
+	CollisionSuffix = "suffix" // 放在文件名之后、后缀之前
+	CollisionPrefix = "prefix" // 放在文件名之前
+)
+
+// 分隔方式及其在设置中显示的名称，按显示顺序排列
+var collisionSeparatorNames = []struct {
+	separator string
+	name      string
+}{
+	{CollisionSeparatorUnderscore, "下划线 name_时间"},
+	{CollisionSeparatorDash, "短横线 name-时间"},
+	{CollisionSeparatorSpace, "空格 name 时间"},
+	{CollisionSeparatorParens, "括号 name (时间)"},
+}
+
+// 是否是支持的分隔方式
+func validCollisionSeparator(separator string) bool {
+	for _, s := range collisionSeparatorNames {
+		if s.separator == separator {
+			return true
+		}
+	}
+	return false
+}
+
+// 生成带时间戳的文件名。stem 和 ext 由 splitExtension 拆分，多段后缀（例如 .tar.gz）保持完整，
+// 时间戳不会插到 .tar 和 .gz 之间
+func collisionFileName(stem, ext, timestamp, separator, position string) string {
+	if separator == CollisionSeparatorParens {
+		if position == CollisionPrefix {
+			return fmt.Sprintf("(%s) %s%s", timestamp, stem, ext)
+		}
+		return fmt.Sprintf("%s (%s)%s", stem, timestamp, ext)
+	}
+	if !validCollisionSeparator(separator) {
+		separator = CollisionSeparatorUnderscore
+	}
+	if position == CollisionPrefix {
+		return timestamp + separator + stem + ext
+	}
+	return stem + separator + timestamp + ext
+}
+
+// 第 n 个（从1开始）带时间戳的候选文件名。时间戳只精确到秒，同一秒内有多个同名文件时，
+// 从第二个起在时间戳后加序号，例如 name_20240315_101500_2.ext
+func collisionCandidate(stem, ext, timestamp string, n int, separator, position string) string {
+	if n > 1 {
+		timestamp = fmt.Sprintf("%s_%d", timestamp, n)
+	}
+	return collisionFileName(stem, ext, timestamp, separator, position)
+}
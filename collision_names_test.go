@@ -0,0 +1,70 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// 带时间戳的文件名保持多段后缀完整，分隔方式和位置按设置
+func TestCollisionCandidate(t *testing.T) {
+	const timestamp = "20240315_101500"
+	tests := []struct {
+		file      string
+		n         int
+		separator string
+		position  string
+		want      string
+	}{
+		{"archive.tar.gz", 1, CollisionSeparatorUnderscore, CollisionSuffix, "archive_20240315_101500.tar.gz"},
+		{"archive.tar.gz", 1, CollisionSeparatorParens, CollisionSuffix, "archive (20240315_101500).tar.gz"},
+		{"archive.tar.gz", 1, CollisionSeparatorDash, CollisionPrefix, "20240315_101500-archive.tar.gz"},
+		{"archive.tar.gz", 1, CollisionSeparatorParens, CollisionPrefix, "(20240315_101500) archive.tar.gz"},
+		{"archive.tar.gz", 2, CollisionSeparatorUnderscore, CollisionSuffix, "archive_20240315_101500_2.tar.gz"},
+		{"Backup.TAR.BZ2", 1, CollisionSeparatorSpace, CollisionSuffix, "Backup 20240315_101500.TAR.BZ2"},
+		{"dark-mode.user.js", 3, CollisionSeparatorParens, CollisionSuffix, "dark-mode (20240315_101500_3).user.js"},
+		{"my.report.v2.pdf", 1, CollisionSeparatorUnderscore, CollisionSuffix, "my.report.v2_20240315_101500.pdf"},
+		{"notes.2024-03-15", 1, CollisionSeparatorUnderscore, CollisionSuffix, "notes.2024-03-15_20240315_101500"},
+		{"README", 1, CollisionSeparatorDash, CollisionSuffix, "README-20240315_101500"},
+		{"archive.tar.gz", 1, "+", CollisionSuffix, "archive_20240315_101500.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			stem, ext := splitExtension(tt.file)
+			if got := collisionCandidate(stem, ext, timestamp, tt.n, tt.separator, tt.position); got != tt.want {
+				t.Fatalf("collisionCandidate(%q) = %q, 期望 %q", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+// 目标中已有同名的多段后缀文件时，新文件名在 .tar.gz 之前加时间戳
+func TestFreeTargetPathDoubleExtension(t *testing.T) {
+	tests := []struct {
+		file      string
+		separator string
+		position  string
+		want      string // 新文件名的格式
+	}{
+		{"archive.tar.gz", CollisionSeparatorUnderscore, CollisionSuffix, `^archive_\d{8}_\d{6}\.tar\.gz$`},
+		{"archive.tar.gz", CollisionSeparatorParens, CollisionSuffix, `^archive \(\d{8}_\d{6}\)\.tar\.gz$`},
+		{"archive.tar.gz", CollisionSeparatorDash, CollisionPrefix, `^\d{8}_\d{6}-archive\.tar\.gz$`},
+		{"script.user.js", CollisionSeparatorSpace, CollisionSuffix, `^script \d{8}_\d{6}\.user\.js$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file+tt.separator+tt.position, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			fo.CollisionSeparator = tt.separator
+			fo.CollisionPosition = tt.position
+			target := t.TempDir()
+			if got := fo.freeTargetPath(target, tt.file); got != filepath.Join(target, tt.file) {
+				t.Fatalf("没有同名文件时 = %s", got)
+			}
+			writeTestFile(t, filepath.Join(target, tt.file), "old")
+			got := fo.freeTargetPath(target, tt.file)
+			if filepath.Dir(got) != target || !regexp.MustCompile(tt.want).MatchString(filepath.Base(got)) {
+				t.Fatalf("新文件名 = %s, 期望格式 %s", filepath.Base(got), tt.want)
+			}
+		})
+	}
+}
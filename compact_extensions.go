@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 按后缀整理时文件数太少的后缀合并到的文件夹
+const CompactedExtensionsFolderName = "其他"
+
+// 扫描结果中文件数少于minFiles的后缀，minFiles为0时不合并任何后缀
+func rareExtensions(counts map[string]int, minFiles int) map[string]bool {
+	if minFiles <= 0 {
+		return nil
+	}
+	rare := make(map[string]bool)
+	for ext, n := range counts {
+		if n < minFiles {
+			rare[ext] = true
+		}
+	}
+	return rare
+}
+
+// 按当前设置合并的后缀，只在按后缀整理时生效
+func (fo *FileOrganizer) currentRareExtensions(rule string) map[string]bool {
+	if OrganizeRule(rule) != RuleByExtension {
+		return nil
+	}
+	return rareExtensions(fo.scannedFileExtensions, fo.CompactExtensionsMin)
+}
+
+// 记录本次整理合并到「其他」的后缀，只列出选择整理的后缀
+func (fo *FileOrganizer) logCompactedExtensions(config Config) {
+	if len(config.RareExtensions) == 0 {
+		return
+	}
+	var compacted []string
+	for ext := range config.RareExtensions {
+		if fo.isTargetFile(ext, config.FileExtensions) {
+			compacted = append(compacted, fmt.Sprintf("%s（%d）", ext, fo.scannedFileExtensions[ext]))
+		}
+	}
+	if len(compacted) == 0 {
+		return
+	}
+	sort.Strings(compacted)
+	fo.log(fmt.Sprintf("以下 %d 种后缀的文件少于 %d 个，合并到「%s」文件夹: %s",
+		len(compacted), config.CompactExtensionsMin, CompactedExtensionsFolderName, strings.Join(compacted, ", ")))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 按内容哈希整理时默认的分片层数和每层的字符数，例如 ab/cd/<哈希>.jpg
+const (
+	defaultHashShardDepth = 2
+	defaultHashShardWidth = 2
+)
+
+// 分片层数和每层字符数的上限，SHA-256的十六进制哈希共64个字符
+const (
+	maxHashShardDepth = 4
+	maxHashShardWidth = 8
+)
+
+// 检查分片设置是否可用
+func validateHashShards(depth, width int) error {
+	if depth < 1 || depth > maxHashShardDepth {
+		return fmt.Errorf("分片层数应在 1 到 %d 之间", maxHashShardDepth)
+	}
+	if width < 1 || width > maxHashShardWidth {
+		return fmt.Errorf("每层字符数应在 1 到 %d 之间", maxHashShardWidth)
+	}
+	return nil
+}
+
+// 由哈希值的前缀生成分片路径，例如 depth=2、width=2 时 "abcdef..." → "ab/cd"
+func hashShardPath(hash string, depth, width int) string {
+	parts := make([]string, 0, depth)
+	for i := 0; i < depth && (i+1)*width <= len(hash); i++ {
+		parts = append(parts, hash[i*width:(i+1)*width])
+	}
+	return filepath.Join(parts...)
+}
+
+// 以哈希值命名的文件名，保留后缀并按扩展名大小写设置转换
+func hashFileName(hash, filePath, extensionCase string) string {
+	ext := fileExtension(filePath)
+	if extensionCase == "uppercase" {
+		ext = strings.ToUpper(ext)
+	} else {
+		ext = strings.ToLower(ext)
+	}
+	return hash + ext
+}
+
+// contentHashEntry 缓存的哈希，文件大小或修改时间变化时需要重新计算
+type contentHashEntry struct {
+	size    int64
+	modTime int64
+	hash    string
+}
+
+// contentHashCache 缓存按内容哈希整理时计算的SHA-256，规划目标和整理时只计算一次。键为文件路径
+type contentHashCache struct {
+	mu     sync.Mutex
+	hashes map[string]contentHashEntry
+}
+
+// 创建内容哈希缓存
+func newContentHashCache() *contentHashCache {
+	return &contentHashCache{hashes: make(map[string]contentHashEntry)}
+}
+
+// 获取文件内容的SHA-256，已计算过且文件没有变化时直接返回
+func (c *contentHashCache) get(path string, info os.FileInfo) (string, error) {
+	size, modTime := info.Size(), info.ModTime().UnixNano()
+	c.mu.Lock()
+	entry, ok := c.hashes[path]
+	c.mu.Unlock()
+	if ok && entry.size == size && entry.modTime == modTime {
+		return entry.hash, nil
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.hashes[path] = contentHashEntry{size: size, modTime: modTime, hash: hash}
+	c.mu.Unlock()
+	return hash, nil
+}
+
+// 整理完一个文件后丢弃它的哈希，文件已不在原处，缓存不随整理的文件数增长
+func (c *contentHashCache) forget(path string) {
+	c.mu.Lock()
+	delete(c.hashes, path)
+	c.mu.Unlock()
+}
+
+// 清空缓存，每次扫描后重新计算
+func (c *contentHashCache) reset() {
+	c.mu.Lock()
+	c.hashes = make(map[string]contentHashEntry)
+	c.mu.Unlock()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 分片路径按哈希前缀逐层截取，哈希不够长时只取完整的层
+func TestHashShardPath(t *testing.T) {
+	tests := []struct {
+		hash         string
+		depth, width int
+		want         string
+	}{
+		{"abcdef", 2, 2, filepath.Join("ab", "cd")},
+		{"abcdef", 1, 3, "abc"},
+		{"abcdef", 4, 2, filepath.Join("ab", "cd", "ef")},
+		{"ab", 2, 4, ""},
+	}
+	for _, tt := range tests {
+		if got := hashShardPath(tt.hash, tt.depth, tt.width); got != tt.want {
+			t.Errorf("hashShardPath(%q, %d, %d) = %q, 期望 %q", tt.hash, tt.depth, tt.width, got, tt.want)
+		}
+	}
+}
+
+// 按内容哈希整理并改名：内容相同的文件只保留一份，整理后不再缓存整理过的文件的哈希
+func TestOrganizeByHashRename(t *testing.T) {
+	// "a" 的 SHA-256
+	const hashA = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	shard := filepath.Join("ca", "97")
+	tests := []struct {
+		name       string
+		source     map[string]string
+		target     map[string]string
+		wantSource map[string]string
+		wantTarget map[string]string
+	}{
+		{"改名为哈希值",
+			map[string]string{"a.jpg": "a"}, nil,
+			map[string]string{},
+			map[string]string{filepath.Join(shard, hashA+".jpg"): "a"}},
+		{"目标中已有相同内容",
+			map[string]string{"a.jpg": "a"},
+			map[string]string{filepath.Join(shard, hashA+".jpg"): "a"},
+			map[string]string{"a.jpg": "a"},
+			map[string]string{filepath.Join(shard, hashA+".jpg"): "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			target := t.TempDir()
+			var files []string
+			for name, content := range tt.source {
+				files = append(files, writeTestFile(t, filepath.Join(source, name), content))
+			}
+			for name, content := range tt.target {
+				writeTestFile(t, filepath.Join(target, name), content)
+			}
+			config := Config{
+				SourceDir:      source,
+				SourceDirs:     []string{source},
+				TargetDir:      target,
+				FileExtensions: []string{".jpg"},
+				OrganizeRule:   string(RuleByHash),
+				HashShardDepth: 2,
+				HashShardWidth: 2,
+				HashRename:     true,
+				ExtensionCase:  "lowercase",
+				ConflictPolicy: ConflictRename,
+				ExcludedFiles:  map[string]bool{},
+			}
+			if summary, err := fo.processFiles(config, files); err != nil || summary.Failed != 0 {
+				t.Fatalf("整理: %+v, %v", summary, err)
+			}
+			if got := snapshotTree(t, source); !reflect.DeepEqual(got, tt.wantSource) {
+				t.Errorf("源文件夹 = %v, 期望 %v", got, tt.wantSource)
+			}
+			if got := snapshotTree(t, target); !reflect.DeepEqual(got, tt.wantTarget) {
+				t.Errorf("目标文件夹 = %v, 期望 %v", got, tt.wantTarget)
+			}
+			if n := len(fo.contentHashes.hashes); n != 0 {
+				t.Errorf("整理后仍缓存了 %d 个哈希", n)
+			}
+		})
+	}
+}
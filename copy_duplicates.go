@@ -0,0 +1,386 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 副本的处理方式
+const (
+	CopyMergeOff        = "off"        // 不处理
+	CopyMergeQuarantine = "quarantine" // 内容相同的副本移到隔离文件夹
+	CopyMergeDelete     = "delete"     // 删除内容相同的副本
+)
+
+// 内容相同的副本移入的文件夹，位于目标根目录下
+const DuplicatesFolderName = "_duplicates"
+
+// 默认的副本文件名规则，作用于去掉扩展名后的文件名，第一个捕获组是原文件名。
+// 依次匹配 "report (1)"、"report - 副本"、"report - Copy (2)"、"report copy 2"、"report 的副本"、"Copy of report"
+var defaultCopySuffixPatterns = []string{
+	`^(.+?) ?\(\d+\)$`,
+	`^(.+?) - (?:副本|复制|Copy)(?: ?\(\d+\))?$`,
+	`^(.+?) (?:copy|副本)(?: \d+)?$`,
+	`^(.+?) ?的副本(?: ?\d+)?$`,
+	`^Copy (?:\(\d+\) )?of (.+)$`,
+}
+
+// 编译副本文件名规则，每条规则必须包含一个表示原文件名的捕获组
+func compileCopyPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("副本规则 %s 无效: %w", pattern, err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("副本规则 %s 缺少表示原文件名的捕获组", pattern)
+		}
+		compiled = append(compiled, re)
+	}
+	if len(compiled) == 0 {
+		return nil, errors.New("至少需要一条副本规则")
+	}
+	return compiled, nil
+}
+
+// 去掉文件名中的副本后缀，返回原文件名以及是否为副本。
+// 后缀可能叠加（例如 "report - 副本 (1).pdf"），反复去除直到没有匹配
+func canonicalCopyName(name string, patterns []*regexp.Regexp) (string, bool) {
+	stem, ext := splitExtension(name)
+	isCopy := false
+	for changed := true; changed; {
+		changed = false
+		for _, re := range patterns {
+			if match := re.FindStringSubmatch(stem); match != nil {
+				if original := strings.TrimSpace(match[1]); original != "" && original != stem {
+					stem = original
+					isCopy = true
+					changed = true
+				}
+			}
+		}
+	}
+	return stem + ext, isCopy
+}
+
+// copyGroup 同一文件夹中原文件名相同的一组文件
+type copyGroup struct {
+	Canonical string     // 原文件名
+	Identical [][]string // 内容相同的文件，每组第一个是保留的文件
+	Different []string   // 与组内其他文件内容都不同的文件，只报告不处理
+}
+
+// 按文件夹和原文件名分组查找副本，比较内容后区分相同和不同的副本。
+// 只有同一文件夹中同时有原文件名的文件时才算副本：不同文件夹中的同名文件往往无关，
+// "Movie (2019).mp4" 这样本身带括号的文件名没有对应的原文件，也不会被当作副本
+func findCopyGroups(files []string, patterns []*regexp.Regexp) []copyGroup {
+	type member struct {
+		path   string
+		isCopy bool
+	}
+	byName := make(map[string][]member)
+	hasCopy := make(map[string]bool)
+	hasOriginal := make(map[string]bool)
+	canonicalNames := make(map[string]string)
+	for _, path := range files {
+		canonical, isCopy := canonicalCopyName(filepath.Base(path), patterns)
+		key := filepath.Join(filepath.Dir(path), strings.ToLower(canonical))
+		byName[key] = append(byName[key], member{path: path, isCopy: isCopy})
+		if isCopy {
+			hasCopy[key] = true
+		} else {
+			hasOriginal[key] = true
+		}
+		canonicalNames[key] = canonical
+	}
+
+	keys := make([]string, 0, len(hasCopy))
+	for key := range hasCopy {
+		if hasOriginal[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var groups []copyGroup
+	for _, key := range keys {
+		members := byName[key]
+		// 原文件排在前面，其次是较短的文件名，保证保留的是最像原文件的那个
+		sort.SliceStable(members, func(i, j int) bool {
+			if members[i].isCopy != members[j].isCopy {
+				return !members[i].isCopy
+			}
+			if len(filepath.Base(members[i].path)) != len(filepath.Base(members[j].path)) {
+				return len(filepath.Base(members[i].path)) < len(filepath.Base(members[j].path))
+			}
+			return members[i].path < members[j].path
+		})
+
+		// 先按大小分组，只有大小相同的文件才需要计算哈希
+		bySize := make(map[int64][]string)
+		var sizes []int64
+		group := copyGroup{Canonical: canonicalNames[key]}
+		for _, m := range members {
+			info, err := os.Stat(m.path)
+			if err != nil {
+				continue
+			}
+			if _, ok := bySize[info.Size()]; !ok {
+				sizes = append(sizes, info.Size())
+			}
+			bySize[info.Size()] = append(bySize[info.Size()], m.path)
+		}
+		for _, size := range sizes {
+			paths := bySize[size]
+			if len(paths) == 1 || size == 0 {
+				// 不同的空文件不视为副本
+				group.Different = append(group.Different, paths...)
+				continue
+			}
+			byHash := make(map[string][]string)
+			var hashes []string
+			for _, path := range paths {
+				hash, err := hashFile(path)
+				if err != nil {
+					group.Different = append(group.Different, path)
+					continue
+				}
+				if _, ok := byHash[hash]; !ok {
+					hashes = append(hashes, hash)
+				}
+				byHash[hash] = append(byHash[hash], path)
+			}
+			for _, hash := range hashes {
+				if len(byHash[hash]) > 1 {
+					group.Identical = append(group.Identical, byHash[hash])
+				} else {
+					group.Different = append(group.Different, byHash[hash]...)
+				}
+			}
+		}
+		if len(group.Identical) > 0 || len(group.Different) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// 处理内容相同的副本：删除或移到隔离文件夹，保留的文件改回原文件名（同一文件夹中没有同名文件时）。
+// 返回路径变化，被删除或隔离的文件对应空字符串
+func (fo *FileOrganizer) mergeCopies(groups []copyGroup, mode, targetRoot string, xattrs bool) map[string]string {
+	changed := make(map[string]string)
+	if err := fo.checkWritable(); err != nil {
+		fo.log("处理副本: " + err.Error())
+		return changed
+	}
+	quarantineDir := filepath.Join(targetRoot, DuplicatesFolderName)
+	for _, group := range groups {
+		for _, set := range group.Identical {
+			keeper := set[0]
+			for _, duplicate := range set[1:] {
+				var err error
+				if mode == CopyMergeDelete {
+					err = os.Remove(duplicate)
+				} else {
+					_, err = fo.moveFile(duplicate, quarantineDir, xattrs)
+				}
+				if err != nil {
+					fo.log(fmt.Sprintf("处理副本失败 %s: %v", duplicate, err))
+					continue
+				}
+				changed[duplicate] = ""
+				if mode == CopyMergeDelete {
+					fo.log(fmt.Sprintf("已删除副本: %s（与 %s 内容相同）", duplicate, keeper))
+				} else {
+					fo.log(fmt.Sprintf("已隔离副本: %s（与 %s 内容相同）", duplicate, keeper))
+				}
+			}
+
+			canonicalPath := filepath.Join(filepath.Dir(keeper), group.Canonical)
+			if filepath.Base(keeper) == group.Canonical {
+				continue
+			}
+			if _, err := os.Lstat(canonicalPath); err == nil {
+				continue
+			}
+			if err := os.Rename(keeper, canonicalPath); err != nil {
+				fo.log(fmt.Sprintf("恢复原文件名失败 %s: %v", keeper, err))
+				continue
+			}
+			changed[keeper] = canonicalPath
+			fo.log(fmt.Sprintf("已恢复原文件名: %s -> %s", filepath.Base(keeper), group.Canonical))
+		}
+	}
+	return changed
+}
+
+// 整理前按配置合并副本，只考虑会被整理的文件，返回更新后的待整理文件列表，
+// 以及恢复了原文件名的文件改名前的文件名（新路径 -> 改名前的文件名），撤销时按改名前的文件名恢复。
+// 带 Google 相册元数据文件的照片已和元数据文件一起合并过，这里不再删除或改名，元数据文件仍能对应
+func (fo *FileOrganizer) mergeCopiesBeforeProcessing(config Config, files []string, takeout *takeoutPlan) ([]string, map[string]string) {
+	patterns, err := compileCopyPatterns(config.CopySuffixPatterns)
+	if err != nil {
+		fo.log("副本合并不可用: " + err.Error())
+		return files, nil
+	}
+	var candidates []string
+	for _, path := range files {
+		// 只读源文件夹中的文件不能删除或改名
+		if !config.ExcludedFiles[path] && !config.Pins.matches(path, nil) && !isFromReadOnlySource(path, config) &&
+			fo.isTargetFile(fileExtension(path), config.FileExtensions) && takeout.sidecarOf(path) == "" {
+			candidates = append(candidates, path)
+		}
+	}
+
+	groups := findCopyGroups(candidates, patterns)
+	if len(groups) == 0 {
+		return files, nil
+	}
+	identical, different := summarizeCopyGroups(groups)
+	fo.log(fmt.Sprintf("发现 %d 个内容相同的副本，%d 个内容不同的同名文件（保留，请检查）", identical, different))
+	for _, group := range groups {
+		for _, path := range group.Different {
+			fo.log("内容不同的同名文件: " + path)
+		}
+	}
+
+	changed := fo.mergeCopies(groups, config.CopyMerge, config.TargetDir, config.PreserveXattrs)
+	remaining := make([]string, 0, len(files))
+	renamedFrom := make(map[string]string)
+	for _, path := range files {
+		newPath, ok := changed[path]
+		switch {
+		case !ok:
+			remaining = append(remaining, path)
+		case newPath != "":
+			remaining = append(remaining, newPath)
+			renamedFrom[newPath] = filepath.Base(path)
+		}
+	}
+	return remaining, renamedFrom
+}
+
+// 统计副本分组的结果
+func summarizeCopyGroups(groups []copyGroup) (identical, different int) {
+	for _, group := range groups {
+		for _, set := range group.Identical {
+			identical += len(set) - 1
+		}
+		different += len(group.Different)
+	}
+	return identical, different
+}
+
+// 副本分组的文字报告
+func describeCopyGroups(groups []copyGroup) string {
+	var sb strings.Builder
+	for _, group := range groups {
+		fmt.Fprintf(&sb, "%s\n", group.Canonical)
+		for _, set := range group.Identical {
+			fmt.Fprintf(&sb, "  保留: %s\n", set[0])
+			for _, duplicate := range set[1:] {
+				fmt.Fprintf(&sb, "    相同: %s\n", duplicate)
+			}
+		}
+		if len(group.Different) > 0 {
+			for _, path := range group.Different {
+				fmt.Fprintf(&sb, "  内容不同: %s\n", path)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// 分析扫描结果中的副本，显示报告并让用户选择如何处理内容相同的副本
+func (fo *FileOrganizer) showCopyAnalysisDialog() {
+	patterns, err := compileCopyPatterns(fo.CopySuffixPatterns)
+	if err != nil {
+		dialog.ShowError(err, fo.Window)
+		return
+	}
+	var files []string
+	for _, path := range fo.scannedFiles {
+		if !fo.excludedFiles[path] {
+			files = append(files, path)
+		}
+	}
+	targetRoot := fo.currentConfig().TargetDir
+
+	fo.log("正在查找副本...")
+	go func() {
+		groups := findCopyGroups(files, patterns)
+		identical, different := summarizeCopyGroups(groups)
+		fo.log(fmt.Sprintf("副本分析完成: %d 组，%d 个内容相同的副本，%d 个内容不同的同名文件", len(groups), identical, different))
+
+		fo.safeUpdateUI(func() {
+			if len(groups) == 0 {
+				dialog.ShowInformation("副本分析", "没有发现带副本后缀的文件", fo.Window)
+				return
+			}
+			report := widget.NewLabel(describeCopyGroups(groups))
+			report.TextStyle = fyne.TextStyle{Monospace: true}
+			report.Selectable = true
+			reportScroll := container.NewScroll(report)
+			reportScroll.SetMinSize(fyne.NewSize(760, 360))
+			summary := widget.NewLabel(fmt.Sprintf("%d 个副本与保留的文件内容完全相同；%d 个同名文件内容不同，只列出不处理。", identical, different))
+			summary.Wrapping = fyne.TextWrapWord
+
+			var analysisDialog dialog.Dialog
+			apply := func(mode string) {
+				if fo.refuseInReadOnlyMode() {
+					return
+				}
+				analysisDialog.Hide()
+				xattrs := fo.PreserveXattrs
+				go func() {
+					// 隔离的副本放入目标文件夹，与整理一样先锁定
+					unlock, err := fo.lockTarget(targetRoot, newRunID())
+					if err != nil {
+						fo.log("副本处理失败: " + err.Error())
+						fo.safeUpdateUI(func() { dialog.ShowError(err, fo.Window) })
+						return
+					}
+					changed := fo.mergeCopies(groups, mode, targetRoot, xattrs)
+					unlock()
+					fo.log(fmt.Sprintf("副本处理完成，%d 个文件发生变化", len(changed)))
+					fo.safeUpdateUI(fo.rescan)
+				}()
+			}
+			quarantineBtn := widget.NewButton("隔离相同的副本到 "+DuplicatesFolderName, func() {
+				apply(CopyMergeQuarantine)
+			})
+			deleteBtn := widget.NewButton("删除相同的副本", func() {
+				dialog.ShowConfirm("删除副本", fmt.Sprintf("确定要删除 %d 个内容相同的副本吗？此操作无法撤销。", identical), func(confirm bool) {
+					if confirm {
+						apply(CopyMergeDelete)
+					}
+				}, fo.Window)
+			})
+			deleteBtn.Importance = widget.DangerImportance
+			if identical == 0 {
+				quarantineBtn.Disable()
+				deleteBtn.Disable()
+			}
+
+			analysisDialog = dialog.NewCustom("副本分析", "关闭", container.NewBorder(
+				summary, container.NewGridWithColumns(2, quarantineBtn, deleteBtn), nil, nil, reportScroll,
+			), fo.Window)
+			fo.showDialog(analysisDialog, quarantineBtn)
+		})
+	}()
+}
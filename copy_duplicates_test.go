@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalCopyName(t *testing.T) {
+	patterns, err := compileCopyPatterns(defaultCopySuffixPatterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		want   string
+		isCopy bool
+	}{
+		{"report.pdf", "report.pdf", false},
+		{"report (1).pdf", "report.pdf", true},
+		{"report - 副本.pdf", "report.pdf", true},
+		{"report - Copy (2).pdf", "report.pdf", true},
+		{"report copy 2.pdf", "report.pdf", true},
+		{"report 的副本.pdf", "report.pdf", true},
+		{"Copy of report.pdf", "report.pdf", true},
+		{"report - 副本 (1).pdf", "report.pdf", true},
+	}
+	for _, tt := range tests {
+		got, isCopy := canonicalCopyName(tt.name, patterns)
+		if got != tt.want || isCopy != tt.isCopy {
+			t.Errorf("canonicalCopyName(%q) = %q, %v, want %q, %v", tt.name, got, isCopy, tt.want, tt.isCopy)
+		}
+	}
+}
+
+func TestFindCopyGroups(t *testing.T) {
+	patterns, err := compileCopyPatterns(defaultCopySuffixPatterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		files map[string]string // 相对路径 -> 内容
+		want  [][]string        // 每组内容相同的文件，第一个是保留的文件
+	}{
+		{
+			name:  "同一文件夹中的副本",
+			files: map[string]string{"a/report.pdf": "x", "a/report (1).pdf": "x"},
+			want:  [][]string{{"a/report.pdf", "a/report (1).pdf"}},
+		},
+		{
+			name:  "不同文件夹中的同名文件不是副本",
+			files: map[string]string{"a/report.pdf": "x", "b/report (1).pdf": "x"},
+		},
+		{
+			name:  "本身带括号的文件名没有原文件",
+			files: map[string]string{"a/Movie (2019).mp4": "x", "b/Movie.mp4": "x"},
+		},
+		{
+			name:  "只有副本没有原文件",
+			files: map[string]string{"a/report (1).pdf": "x", "a/report (2).pdf": "x"},
+		},
+		{
+			name:  "内容不同只报告",
+			files: map[string]string{"a/report.pdf": "x", "a/report (1).pdf": "y"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			var files []string
+			for rel, content := range tt.files {
+				files = append(files, writeTestFile(t, filepath.Join(root, rel), content))
+			}
+			var got [][]string
+			for _, group := range findCopyGroups(files, patterns) {
+				for _, set := range group.Identical {
+					var rels []string
+					for _, path := range set {
+						rel, _ := filepath.Rel(root, path)
+						rels = append(rels, filepath.ToSlash(rel))
+					}
+					got = append(got, rels)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("内容相同的副本 = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if len(got[i]) != len(tt.want[i]) {
+					t.Fatalf("内容相同的副本 = %v, want %v", got, tt.want)
+				}
+				for j := range got[i] {
+					if got[i][j] != tt.want[i][j] {
+						t.Fatalf("内容相同的副本 = %v, want %v", got, tt.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// 删除模式只删除同一文件夹中与原文件内容相同的副本，其他文件夹中的同名文件保持不变
+func TestMergeCopiesDeleteStaysInFolder(t *testing.T) {
+	fo := newTestOrganizer(t)
+	patterns, err := compileCopyPatterns(defaultCopySuffixPatterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	original := writeTestFile(t, filepath.Join(root, "a", "report.pdf"), "x")
+	duplicate := writeTestFile(t, filepath.Join(root, "a", "report (1).pdf"), "x")
+	unrelated := writeTestFile(t, filepath.Join(root, "b", "report (1).pdf"), "x")
+
+	changed := fo.mergeCopies(findCopyGroups([]string{original, duplicate, unrelated}, patterns), CopyMergeDelete, root, false)
+	if len(changed) != 1 || changed[duplicate] != "" {
+		t.Fatalf("changed = %v", changed)
+	}
+	for _, path := range []string{original, unrelated} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s 不应被删除: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(duplicate); !os.IsNotExist(err) {
+		t.Errorf("副本应被删除: %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+import "time"
+
+// 本进程使用的CPU时间（当前系统无法获取）
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// 今日摘要中整理的来源：监视模式自动整理，或手动开始的整理（包括定时整理和任务队列）
+const (
+	DigestAuto   = "auto"
+	DigestManual = "manual"
+)
+
+// 不在固定时间显示今日摘要
+const digestHourOff = -1
+
+// 活动记录文件保留的天数，更早的在记录新一天的活动时删除
+const digestKeepDays = 7
+
+// digestEvent 一次整理活动的增量，每次发生时追加到当天的记录文件中，程序重启后重新累加
+type digestEvent struct {
+	Time        time.Time      `json:"time"`
+	Kind        string         `json:"kind"`
+	Sources     []string       `json:"sources,omitempty"`      // 旧的记录：涉及的源文件夹，每个都按全部文件计
+	SourceFiles map[string]int `json:"source_files,omitempty"` // 源文件夹 → 整理的文件数
+	Folders     map[string]int `json:"folders,omitempty"`      // 目标文件夹 → 放入的文件数
+	Files       int            `json:"files"`
+	Errors      int            `json:"errors"`
+}
+
+// digestCounts 一种来源当天的累计
+type digestCounts struct {
+	Runs    int
+	Files   int
+	Errors  int
+	Sources map[string]int
+	Folders map[string]int
+}
+
+// dailyDigest 一天的整理活动
+type dailyDigest struct {
+	day    string
+	counts map[string]*digestCounts // 来源 → 累计
+}
+
+func newDailyDigest(day string) *dailyDigest {
+	return &dailyDigest{day: day, counts: make(map[string]*digestCounts)}
+}
+
+// 累加一次活动
+func (d *dailyDigest) add(event digestEvent) {
+	counts := d.counts[event.Kind]
+	if counts == nil {
+		counts = &digestCounts{Sources: make(map[string]int), Folders: make(map[string]int)}
+		d.counts[event.Kind] = counts
+	}
+	counts.Runs++
+	counts.Files += event.Files
+	counts.Errors += event.Errors
+	for _, source := range event.Sources {
+		counts.Sources[source] += event.Files
+	}
+	for source, n := range event.SourceFiles {
+		counts.Sources[source] += n
+	}
+	for folder, n := range event.Folders {
+		counts.Folders[folder] += n
+	}
+}
+
+// 复制一份摘要，调用者可以在锁外读取
+func (d *dailyDigest) clone() *dailyDigest {
+	copied := newDailyDigest(d.day)
+	for kind, counts := range d.counts {
+		c := *counts
+		c.Sources = make(map[string]int, len(counts.Sources))
+		for source, n := range counts.Sources {
+			c.Sources[source] = n
+		}
+		c.Folders = make(map[string]int, len(counts.Folders))
+		for folder, n := range counts.Folders {
+			c.Folders[folder] = n
+		}
+		copied.counts[kind] = &c
+	}
+	return copied
+}
+
+// 摘要记录所在的文件夹
+func digestDir() string {
+	return filepath.Join(appDataDir(), "digests")
+}
+
+// 某一天的活动记录文件
+func digestEventsPath(day string) string {
+	return filepath.Join(digestDir(), "events_"+day+".jsonl")
+}
+
+// 每日摘要日志，每天显示摘要时追加一段
+func digestLogPath() string {
+	return filepath.Join(digestDir(), "daily_digest.log")
+}
+
+// 删除 digestKeepDays 天之前的活动记录文件
+func pruneDigestEvents(today time.Time) error {
+	entries, err := os.ReadDir(digestDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("读取摘要文件夹失败: %w", err)
+	}
+	oldest := today.AddDate(0, 0, -digestKeepDays).Format("2006-01-02")
+	for _, entry := range entries {
+		name := entry.Name()
+		day, ok := strings.CutPrefix(name, "events_")
+		if !ok || !strings.HasSuffix(day, ".jsonl") {
+			continue
+		}
+		day = strings.TrimSuffix(day, ".jsonl")
+		if _, err := time.Parse("2006-01-02", day); err != nil || day >= oldest {
+			continue
+		}
+		if err := os.Remove(filepath.Join(digestDir(), name)); err != nil {
+			return fmt.Errorf("删除旧的摘要记录失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// 从记录文件重建某一天的摘要，无法解析的行跳过
+func loadDailyDigest(day string) (*dailyDigest, error) {
+	digest := newDailyDigest(day)
+	file, err := os.Open(digestEventsPath(day))
+	if os.IsNotExist(err) {
+		return digest, nil
+	}
+	if err != nil {
+		return digest, fmt.Errorf("读取今日摘要失败: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event digestEvent
+		if json.Unmarshal(scanner.Bytes(), &event) == nil {
+			digest.add(event)
+		}
+	}
+	return digest, scanner.Err()
+}
+
+// 记录一次整理活动：累加到当天的摘要，并追加到当天的记录文件，跨过午夜时从新的一天开始
+func (fo *FileOrganizer) recordDigest(event digestEvent) {
+	event.Time = time.Now()
+	day := event.Time.Format("2006-01-02")
+
+	fo.digestMu.Lock()
+	defer fo.digestMu.Unlock()
+	if fo.digest == nil || fo.digest.day != day {
+		digest, err := loadDailyDigest(day)
+		if err != nil {
+			fo.log(err.Error())
+		}
+		fo.digest = digest
+		// 开始记录新的一天时删除旧的记录
+		if err := pruneDigestEvents(event.Time); err != nil {
+			fo.log(err.Error())
+		}
+	}
+	fo.digest.add(event)
+
+	data, err := json.Marshal(event)
+	if err == nil {
+		err = os.MkdirAll(digestDir(), 0755)
+	}
+	if err == nil {
+		var file *os.File
+		file, err = os.OpenFile(digestEventsPath(day), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err == nil {
+			_, err = file.Write(append(data, '\n'))
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		fo.log(fmt.Sprintf("记录今日摘要失败: %v", err))
+	}
+}
+
+// 当天的摘要，返回在锁内复制的一份，之后的活动不影响返回的摘要
+func (fo *FileOrganizer) todayDigest() *dailyDigest {
+	day := time.Now().Format("2006-01-02")
+	fo.digestMu.Lock()
+	defer fo.digestMu.Unlock()
+	if fo.digest == nil || fo.digest.day != day {
+		digest, err := loadDailyDigest(day)
+		if err != nil {
+			fo.log(err.Error())
+		}
+		fo.digest = digest
+	}
+	return fo.digest.clone()
+}
+
+// 按数量从多到少列出，最多列出limit项
+func describeDigestTop(counts map[string]int, limit int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	var parts []string
+	for i, key := range keys {
+		if i == limit {
+			parts = append(parts, fmt.Sprintf("等 %d 个", len(keys)))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s（%d）", key, counts[key]))
+	}
+	return strings.Join(parts, "，")
+}
+
+// 描述一天的摘要，自动整理和手动整理分开列出
+func describeDailyDigest(d *dailyDigest) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s 的整理摘要\n", d.day)
+	sections := []struct {
+		kind  string
+		title string
+	}{
+		{DigestAuto, "监视模式自动整理"},
+		{DigestManual, "手动整理（包括定时整理和任务）"},
+	}
+	for _, section := range sections {
+		counts := d.counts[section.kind]
+		fmt.Fprintf(&sb, "\n【%s】\n", section.title)
+		if counts == nil {
+			sb.WriteString("没有整理记录\n")
+			continue
+		}
+		if section.kind == DigestManual {
+			fmt.Fprintf(&sb, "%d 次整理，整理了 %d 个文件，%d 个失败\n", counts.Runs, counts.Files, counts.Errors)
+		} else {
+			fmt.Fprintf(&sb, "整理了 %d 个文件，%d 个失败\n", counts.Files, counts.Errors)
+		}
+		if len(counts.Sources) > 0 {
+			sb.WriteString("来自: " + describeDigestTop(counts.Sources, 5) + "\n")
+		}
+		if len(counts.Folders) > 0 {
+			sb.WriteString("放入: " + describeDigestTop(counts.Folders, 8) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// 一句话的摘要，用于系统通知
+func (d *dailyDigest) shortSummary() string {
+	files := func(kind string) int {
+		if counts := d.counts[kind]; counts != nil {
+			return counts.Files
+		}
+		return 0
+	}
+	return fmt.Sprintf("今天自动整理了 %d 个文件，手动整理了 %d 个文件", files(DigestAuto), files(DigestManual))
+}
+
+// 将摘要追加到每日摘要日志
+func appendDigestLog(text string) error {
+	if err := os.MkdirAll(digestDir(), 0755); err != nil {
+		return fmt.Errorf("创建摘要文件夹失败: %w", err)
+	}
+	file, err := os.OpenFile(digestLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开摘要日志失败: %w", err)
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintf(file, "==== %s ====\n%s\n", time.Now().Format("2006-01-02 15:04:05"), text); err != nil {
+		return fmt.Errorf("写入摘要日志失败: %w", err)
+	}
+	return nil
+}
+
+// 显示今日摘要
+func (fo *FileOrganizer) showDailyDigest() {
+	dialog.ShowInformation("今日摘要", describeDailyDigest(fo.todayDigest()), fo.Window)
+}
+
+// 按设置的时间每天显示一次今日摘要，并追加到每日摘要日志。已显示的日期保存在偏好设置中，重启后不重复显示
+func (fo *FileOrganizer) startDigestTimer() {
+	fo.stopDigestTimer()
+	if fo.DigestHour == digestHourOff {
+		return
+	}
+	stop := make(chan struct{})
+	fo.digestStop = stop
+	hour := fo.DigestHour
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				if now.Hour() != hour {
+					continue
+				}
+				day := now.Format("2006-01-02")
+				prefs := fyne.CurrentApp().Preferences()
+				if prefs.StringWithFallback("digest_shown_day", "") == day {
+					continue
+				}
+				prefs.SetString("digest_shown_day", day)
+				digest := fo.todayDigest()
+				text := describeDailyDigest(digest)
+				if err := appendDigestLog(text); err != nil {
+					fo.log(err.Error())
+				}
+				fyne.CurrentApp().SendNotification(fyne.NewNotification("今日摘要", digest.shortSummary()))
+				fo.safeUpdateUI(func() {
+					dialog.ShowInformation("今日摘要", text, fo.Window)
+				})
+			}
+		}
+	}()
+}
+
+// 停止定时显示今日摘要
+func (fo *FileOrganizer) stopDigestTimer() {
+	if fo.digestStop == nil {
+		return
+	}
+	close(fo.digestStop)
+	fo.digestStop = nil
+}
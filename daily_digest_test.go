@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 多个源文件夹的整理，每个源文件夹只计入其中的文件
+func TestDigestCreditsOwnSource(t *testing.T) {
+	fo := newTestOrganizer(t)
+	first, second := t.TempDir(), t.TempDir()
+	files := []string{
+		writeTestFile(t, filepath.Join(first, "a.jpg"), "a"),
+		writeTestFile(t, filepath.Join(first, "b.jpg"), "b"),
+		writeTestFile(t, filepath.Join(second, "c.jpg"), "c"),
+	}
+	config := Config{
+		SourceDir:      first,
+		SourceDirs:     []string{first, second},
+		TargetDir:      t.TempDir(),
+		FileExtensions: []string{".jpg"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		ExcludedFiles:  map[string]bool{},
+	}
+	if _, err := fo.processFiles(config, files); err != nil {
+		t.Fatal(err)
+	}
+	counts := fo.todayDigest().counts[DigestManual]
+	if counts == nil || counts.Files != 3 || counts.Sources[first] != 2 || counts.Sources[second] != 1 {
+		t.Fatalf("今日摘要 = %+v", counts)
+	}
+}
+
+// 返回的摘要是复制的，之后的活动不会改变它
+func TestTodayDigestIsCopy(t *testing.T) {
+	fo := newTestOrganizer(t)
+	fo.recordDigest(digestEvent{Kind: DigestAuto, SourceFiles: map[string]int{"/in": 1}, Files: 1})
+	digest := fo.todayDigest()
+	fo.recordDigest(digestEvent{Kind: DigestAuto, SourceFiles: map[string]int{"/in": 1}, Files: 1})
+	if counts := digest.counts[DigestAuto]; counts.Files != 1 || counts.Sources["/in"] != 1 {
+		t.Fatalf("之前返回的摘要被修改了: %+v", counts)
+	}
+	if counts := fo.todayDigest().counts[DigestAuto]; counts.Files != 2 {
+		t.Fatalf("今日摘要 = %+v", counts)
+	}
+}
+
+func TestPruneDigestEvents(t *testing.T) {
+	newTestEnv(t)
+	today := time.Date(2024, 3, 20, 10, 0, 0, 0, time.Local)
+	names := map[string]bool{
+		"events_2024-03-20.jsonl": true,
+		"events_2024-03-13.jsonl": true,
+		"events_2024-03-12.jsonl": false,
+		"events_2023-12-31.jsonl": false,
+		"daily_digest.log":        true,
+		"events_notes.jsonl":      true,
+	}
+	for name := range names {
+		writeTestFile(t, filepath.Join(digestDir(), name), "")
+	}
+	if err := pruneDigestEvents(today); err != nil {
+		t.Fatal(err)
+	}
+	for name, keep := range names {
+		_, err := os.Stat(filepath.Join(digestDir(), name))
+		if (err == nil) != keep {
+			t.Errorf("%s: 保留 = %v，期望 %v", name, err == nil, keep)
+		}
+	}
+}
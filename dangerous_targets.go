@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 各系统的系统文件夹，目标位于其中时需要确认。/var 和 /private 中还有临时文件夹
+// （macOS 的 /var/folders、/private/tmp）和网站、用户数据，只列出系统使用的部分
+var systemFoldersByOS = map[string][]string{
+	"linux": {"/bin", "/boot", "/dev", "/etc", "/lib", "/lib32", "/lib64", "/proc", "/sbin", "/sys", "/usr",
+		"/var/cache", "/var/lib", "/var/log", "/var/spool"},
+	"darwin": {"/Applications", "/Library", "/System", "/bin", "/dev", "/etc", "/sbin", "/usr",
+		"/private/etc", "/private/var/db", "/private/var/log", "/private/var/root",
+		"/var/db", "/var/log", "/var/root"},
+}
+
+// 只有目标就是这些文件夹本身时才需要确认，例如所有用户的主目录所在的文件夹
+var parentFoldersByOS = map[string][]string{
+	"linux":  {"/home", "/media", "/mnt", "/var"},
+	"darwin": {"/Users", "/Volumes", "/private", "/private/var", "/var"},
+}
+
+// 当前系统的系统文件夹。Windows 从环境变量获取，不同的安装位置也能识别
+func systemFolders() (system, parents []string) {
+	if runtime.GOOS != "windows" {
+		return systemFoldersByOS[runtime.GOOS], parentFoldersByOS[runtime.GOOS]
+	}
+	for _, key := range []string{"SystemRoot", "ProgramFiles", "ProgramFiles(x86)", "ProgramData"} {
+		if dir := os.Getenv(key); dir != "" {
+			system = append(system, dir)
+		}
+	}
+	if drive := os.Getenv("SystemDrive"); drive != "" {
+		parents = append(parents, filepath.Join(drive+`\`, "Users"))
+	}
+	return system, parents
+}
+
+// 比较路径，Windows 和 macOS 默认不区分大小写
+func samePath(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// 路径是否是root或位于root之中
+func pathWithin(path, root string) bool {
+	for current := filepath.Clean(path); ; {
+		if samePath(current, root) {
+			return true
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return false
+		}
+		current = parent
+	}
+}
+
+// 目标文件夹是否明显危险（文件系统根目录、用户主目录、系统文件夹），返回原因，安全时返回空字符串
+func dangerousTargetReason(target string) string {
+	target = filepath.Clean(target)
+	if filepath.Dir(target) == target {
+		return "目标文件夹是磁盘的根目录"
+	}
+	if home, err := os.UserHomeDir(); err == nil && samePath(target, home) {
+		return "目标文件夹是用户主目录"
+	}
+	system, parents := systemFolders()
+	return systemFolderReason(target, system, parents)
+}
+
+// 目标文件夹是否位于系统文件夹中，或者就是只有本身需要确认的文件夹
+func systemFolderReason(target string, system, parents []string) string {
+	for _, dir := range system {
+		if pathWithin(target, dir) {
+			return fmt.Sprintf("目标文件夹位于系统文件夹 %s 中", dir)
+		}
+	}
+	for _, dir := range parents {
+		if samePath(target, dir) {
+			return fmt.Sprintf("目标文件夹是系统文件夹 %s", dir)
+		}
+	}
+	return ""
+}
+
+// 开启检查时目标文件夹危险的原因，安全或未开启检查时返回空字符串
+func (fo *FileOrganizer) dangerousTargetCheck(target string) string {
+	if !fo.CheckDangerousTarget {
+		return ""
+	}
+	return dangerousTargetReason(target)
+}
+
+// 目标文件夹危险时要求用户明确确认后再继续，安全或未开启检查时直接继续
+func (fo *FileOrganizer) confirmDangerousTarget(target string, proceed func()) {
+	reason := fo.dangerousTargetCheck(target)
+	if reason == "" {
+		proceed()
+		return
+	}
+
+	fo.log(fmt.Sprintf("警告: %s: %s", reason, target))
+	message := widget.NewLabel(fmt.Sprintf("%s:\n%s\n\n整理会在这里创建大量文件夹并移动文件，通常是目标文件夹填写错误。"+
+		"确认无误时请点击「我了解风险」继续。", reason, target))
+	message.Wrapping = fyne.TextWrapWord
+	var confirmDialog dialog.Dialog
+	cancelBtn := widget.NewButton("取消", func() {
+		confirmDialog.Hide()
+	})
+	proceedBtn := widget.NewButton("我了解风险", func() {
+		confirmDialog.Hide()
+		fo.log("已确认使用危险的目标文件夹: " + target)
+		proceed()
+	})
+	proceedBtn.Importance = widget.DangerImportance
+	confirmDialog = dialog.NewCustomWithoutButtons("危险的目标文件夹",
+		container.NewVBox(message, container.NewHBox(layout.NewSpacer(), cancelBtn, proceedBtn)), fo.Window)
+	confirmDialog.Resize(fyne.NewSize(520, 0))
+	fo.showDialog(confirmDialog, cancelBtn)
+}
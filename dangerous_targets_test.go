@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// 开启检查时识别根目录、主目录和系统文件夹；关闭检查时都不拦截
+func TestDangerousTargetCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("系统文件夹按 Unix 路径编写")
+	}
+	tests := []struct {
+		name       string
+		target     func(home string) string
+		check      bool
+		wantReason bool
+	}{
+		{"根目录", func(string) string { return "/" }, true, true},
+		{"用户主目录", func(home string) string { return home }, true, true},
+		{"主目录中的文件夹", func(home string) string { return filepath.Join(home, "Pictures") }, true, false},
+		{"系统文件夹中", func(string) string { return "/usr/share/photos" }, true, true},
+		{"关闭检查", func(string) string { return "/" }, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			fo.CheckDangerousTarget = tt.check
+			home, err := os.UserHomeDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+			reason := fo.dangerousTargetCheck(tt.target(home))
+			if (reason != "") != tt.wantReason {
+				t.Fatalf("原因 = %q, 期望危险 = %v", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+// 系统文件夹只包括系统使用的部分，/var 和 /private 中的临时文件夹和用户数据可以作为目标
+func TestSystemFolderReason(t *testing.T) {
+	tests := []struct {
+		goos       string
+		target     string
+		wantReason bool
+	}{
+		{"linux", "/usr/share/photos", true},
+		{"linux", "/var/lib/docker", true},
+		{"linux", "/var", true},
+		{"linux", "/var/www/photos", false},
+		{"linux", "/var/tmp/sorted", false},
+		{"linux", "/home", true},
+		{"linux", "/home/alex/Pictures", false},
+		{"darwin", "/System/Library", true},
+		{"darwin", "/private/var/db/sorted", true},
+		{"darwin", "/private/etc", true},
+		{"darwin", "/private", true},
+		{"darwin", "/var/folders/xy/T/sorted", false},
+		{"darwin", "/private/var/folders/xy/T/sorted", false},
+		{"darwin", "/private/tmp/sorted", false},
+		{"darwin", "/Users/alex/Pictures", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos+tt.target, func(t *testing.T) {
+			reason := systemFolderReason(tt.target, systemFoldersByOS[tt.goos], parentFoldersByOS[tt.goos])
+			if (reason != "") != tt.wantReason {
+				t.Fatalf("原因 = %q, 期望危险 = %v", reason, tt.wantReason)
+			}
+		})
+	}
+}
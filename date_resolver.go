@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DateSource 文件日期的来源
+type DateSource string
+
+const (
+	DateSourceExif     DateSource = "exif"     // 照片EXIF中的拍摄日期
+	DateSourceFilename DateSource = "filename" // 文件名中的日期，例如 IMG_20240615_123456.jpg
+	DateSourceSidecar  DateSource = "sidecar"  // XMP或Google相册导出的JSON附属文件
+	DateSourceEmail    DateSource = "email"    // 导出邮件（.eml/.msg）的 Date 头
+	DateSourceCreated  DateSource = "created"  // 文件的创建时间
+	DateSourceMtime    DateSource = "mtime"    // 文件的修改时间
+)
+
+// 默认只使用修改时间，与之前的行为保持一致
+var defaultDateSources = []DateSource{DateSourceMtime}
+
+// 来源顺序中没有邮件头时插入到第一个文件时间（创建时间或修改时间）之前，
+// 默认的来源顺序也能按 Date 头整理导出的邮件
+func withEmailDateSource(sources []DateSource) []DateSource {
+	if len(sources) == 0 {
+		sources = defaultDateSources
+	}
+	at := len(sources)
+	for i, source := range sources {
+		if source == DateSourceEmail {
+			return sources
+		}
+		if at == len(sources) && (source == DateSourceCreated || source == DateSourceMtime) {
+			at = i
+		}
+	}
+	withEmail := make([]DateSource, 0, len(sources)+1)
+	withEmail = append(withEmail, sources[:at]...)
+	withEmail = append(withEmail, DateSourceEmail)
+	return append(withEmail, sources[at:]...)
+}
+
+// 日期来源的显示名称
+var dateSourceNames = map[DateSource]string{
+	DateSourceExif:     "EXIF",
+	DateSourceFilename: "文件名",
+	DateSourceSidecar:  "附属文件",
+	DateSourceEmail:    "邮件头",
+	DateSourceCreated:  "创建时间",
+	DateSourceMtime:    "修改时间",
+}
+
+// 解析逗号分隔的日期来源列表，例如 "exif,filename,mtime"
+func parseDateSources(text string) ([]DateSource, error) {
+	var sources []DateSource
+	seen := make(map[DateSource]bool)
+	for _, part := range strings.Split(text, ",") {
+		source := DateSource(strings.ToLower(strings.TrimSpace(part)))
+		if source == "" {
+			continue
+		}
+		if _, ok := dateSourceNames[source]; !ok {
+			return nil, fmt.Errorf("未知的日期来源: %s", source)
+		}
+		if !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("至少需要一个日期来源")
+	}
+	return sources, nil
+}
+
+// 将日期来源列表格式化为逗号分隔的文本
+func formatDateSources(sources []DateSource) string {
+	parts := make([]string, len(sources))
+	for i, source := range sources {
+		parts[i] = string(source)
+	}
+	return strings.Join(parts, ",")
+}
+
+// resolvedDate 一个文件解析出的日期
+type resolvedDate struct {
+	date   time.Time
+	source DateSource
+}
+
+// dateCacheKey 文件大小或修改时间变化、来源顺序变化时都需要重新解析
+type dateCacheKey struct {
+	path    string
+	chain   string
+	size    int64
+	modTime int64
+}
+
+// DateResolver 按配置的来源顺序解析文件日期，并缓存每个文件的结果。
+// 日期规则、年龄分组、事件标签和日期文件夹的修改时间都通过它获取日期
+type DateResolver struct {
+	mu    sync.Mutex
+	cache map[dateCacheKey]resolvedDate
+}
+
+// 创建日期解析器
+func newDateResolver() *DateResolver {
+	return &DateResolver{cache: make(map[dateCacheKey]resolvedDate)}
+}
+
+// 清空缓存，每次扫描后重新解析
+func (r *DateResolver) reset() {
+	r.mu.Lock()
+	r.cache = make(map[dateCacheKey]resolvedDate)
+	r.mu.Unlock()
+}
+
+// 按来源顺序解析文件日期，所有来源都没有结果时使用修改时间
+func (r *DateResolver) Resolve(path string, info os.FileInfo, sources []DateSource) (time.Time, DateSource) {
+	if len(sources) == 0 {
+		sources = defaultDateSources
+	}
+	key := dateCacheKey{path: path, chain: formatDateSources(sources), size: info.Size(), modTime: info.ModTime().UnixNano()}
+
+	r.mu.Lock()
+	cached, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok {
+		return cached.date, cached.source
+	}
+
+	result := resolvedDate{date: info.ModTime(), source: DateSourceMtime}
+	for _, source := range sources {
+		if date, ok := resolveDateFrom(source, path, info); ok {
+			result = resolvedDate{date: date, source: source}
+			break
+		}
+	}
+
+	r.mu.Lock()
+	r.cache[key] = result
+	r.mu.Unlock()
+	return result.date, result.source
+}
+
+// 从单个来源读取日期
+func resolveDateFrom(source DateSource, path string, info os.FileInfo) (time.Time, bool) {
+	switch source {
+	case DateSourceExif:
+		date, err := readExifDate(path)
+		return date, err == nil
+	case DateSourceFilename:
+		return parseFilenameDate(filepath.Base(path))
+	case DateSourceSidecar:
+		return readSidecarDate(path)
+	case DateSourceEmail:
+		return readEmailDate(path)
+	case DateSourceCreated:
+		return fileBirthTime(path)
+	case DateSourceMtime:
+		return info.ModTime(), true
+	}
+	return time.Time{}, false
+}
+
+// 文件名中的日期和可选的时间，例如 20240615、2024-06-15、2024_06_15_123456、2024-06-15 12.30.45
+var filenameDatePattern = regexp.MustCompile(`(?:^|\D)((?:19|20)\d{2})[-_.]?(0[1-9]|1[0-2])[-_.]?(0[1-9]|[12]\d|3[01])(?:[ _T-]?([01]\d|2[0-3])[-_.:]?([0-5]\d)[-_.:]?([0-5]\d))?`)
+
+// 从文件名中解析日期（按本地时间）
+func parseFilenameDate(name string) (time.Time, bool) {
+	for _, match := range filenameDatePattern.FindAllStringSubmatchIndex(name, -1) {
+		// 日期后面紧跟数字时不是日期，例如更长的数字序列
+		if match[1] < len(name) && name[match[1]] >= '0' && name[match[1]] <= '9' {
+			continue
+		}
+		part := func(i int) int {
+			if match[2*i] < 0 {
+				return 0
+			}
+			n, _ := strconv.Atoi(name[match[2*i]:match[2*i+1]])
+			return n
+		}
+		year, month, day := part(1), part(2), part(3)
+		// 闰秒 23:59:60 按 23:59:59 处理，否则会进位到第二天
+		second := min(part(6), 59)
+		date := time.Date(year, time.Month(month), day, part(4), part(5), second, 0, time.Local)
+		// 排除不存在的日期，例如 2月30日
+		if date.Day() != day || int(date.Month()) != month {
+			continue
+		}
+		return date, true
+	}
+	return time.Time{}, false
+}
+
+// 按本地时区解析日期。闰秒 23:59:60 会被拒绝，按 23:59:59 重新解析，日期仍是当天
+func parseLocalDate(layout, text string) (time.Time, error) {
+	date, err := time.ParseInLocation(layout, text, time.Local)
+	if err != nil && strings.Contains(text, "59:60") {
+		return time.ParseInLocation(layout, strings.Replace(text, "59:60", "59:59", 1), time.Local)
+	}
+	return date, err
+}
+
+// XMP附属文件中的日期
+var xmpDatePattern = regexp.MustCompile(`(?:exif:DateTimeOriginal|xmp:CreateDate|photoshop:DateCreated)(?:="|>)([^"<]+)`)
+
+// XMP中可能出现的日期格式
+var xmpDateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// 附属文件最多读取的字节数
+const sidecarReadLimit = 1024 * 1024
+
+// 从附属文件读取日期：先找XMP（photo.xmp 或 photo.jpg.xmp），再找Google相册导出的 photo.jpg.json
+func readSidecarDate(path string) (time.Time, bool) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, candidate := range []string{path + ".xmp", base + ".xmp", path + ".XMP", base + ".XMP"} {
+		data, err := readFileLimited(candidate, sidecarReadLimit)
+		if err != nil {
+			continue
+		}
+		if match := xmpDatePattern.FindSubmatch(data); match != nil {
+			text := strings.TrimSpace(string(match[1]))
+			for _, layout := range xmpDateLayouts {
+				if date, err := parseLocalDate(layout, text); err == nil {
+					return date, true
+				}
+			}
+		}
+	}
+
+	// 元数据文件的名称可能被截断或带有序号，编辑过的照片使用原照片的元数据文件
+	sidecar := path + ".json"
+	if found := findTakeoutSidecar(path, true); found != "" {
+		sidecar = found
+	}
+	if data, err := readFileLimited(sidecar, sidecarReadLimit); err == nil {
+		var takeout struct {
+			PhotoTakenTime struct {
+				Timestamp string `json:"timestamp"`
+			} `json:"photoTakenTime"`
+		}
+		if json.Unmarshal(data, &takeout) == nil {
+			if seconds, err := strconv.ParseInt(takeout.PhotoTakenTime.Timestamp, 10, 64); err == nil && seconds > 0 {
+				return time.Unix(seconds, 0), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// 读取文件的前limit个字节
+func readFileLimited(path string, limit int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, limit))
+}
+
+// 格式化各日期来源的使用次数，例如 "EXIF 120, 文件名 30, 修改时间 5"
+func formatDateSourceHits(hits map[DateSource]int) string {
+	var parts []string
+	for _, source := range []DateSource{DateSourceExif, DateSourceFilename, DateSourceSidecar, DateSourceEmail, DateSourceCreated, DateSourceMtime} {
+		if hits[source] > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", dateSourceNames[source], hits[source]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
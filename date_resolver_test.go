@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 按来源顺序依次尝试，前面的来源没有结果时退到下一个，全部没有结果时使用修改时间
+func TestDateResolverFallback(t *testing.T) {
+	modified := time.Date(2025, 9, 1, 12, 0, 0, 0, time.Local)
+	fromName := time.Date(2024, 6, 15, 12, 34, 56, 0, time.Local)
+	fromSidecar := time.Date(2023, 3, 5, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name       string
+		file       string
+		sidecar    bool // 是否有XMP附属文件
+		sources    []DateSource
+		wantDate   time.Time
+		wantSource DateSource
+	}{
+		{"默认顺序", "IMG_20240615_123456.jpg", false, nil, modified, DateSourceMtime},
+		{"文件名", "IMG_20240615_123456.jpg", false, []DateSource{DateSourceFilename, DateSourceMtime}, fromName, DateSourceFilename},
+		{"没有EXIF退到文件名", "IMG_20240615_123456.jpg", false, []DateSource{DateSourceExif, DateSourceFilename, DateSourceMtime}, fromName, DateSourceFilename},
+		{"附属文件优先", "IMG_20240615_123456.jpg", true, []DateSource{DateSourceSidecar, DateSourceFilename}, fromSidecar, DateSourceSidecar},
+		{"没有附属文件退到文件名", "IMG_20240615_123456.jpg", false, []DateSource{DateSourceSidecar, DateSourceFilename}, fromName, DateSourceFilename},
+		{"文件名中没有日期", "holiday.jpg", false, []DateSource{DateSourceFilename}, modified, DateSourceMtime},
+		{"文件名中的日期不存在", "IMG_20240230.jpg", false, []DateSource{DateSourceFilename}, modified, DateSourceMtime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := writeTestFile(t, filepath.Join(dir, tt.file), "photo")
+			if tt.sidecar {
+				writeTestFile(t, file+".xmp", `<x:xmpmeta exif:DateTimeOriginal="2023-03-05T10:00:00"/>`)
+			}
+			if err := os.Chtimes(file, modified, modified); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			date, source := newDateResolver().Resolve(file, info, tt.sources)
+			if !date.Equal(tt.wantDate) || source != tt.wantSource {
+				t.Fatalf("日期 = %v (%s), 期望 %v (%s)", date, source, tt.wantDate, tt.wantSource)
+			}
+		})
+	}
+}
+
+// 同一文件、同一来源顺序使用缓存；文件修改、来源顺序变化或重置后重新解析
+func TestDateResolverCache(t *testing.T) {
+	modified := time.Date(2025, 9, 1, 12, 0, 0, 0, time.Local)
+	sources := []DateSource{DateSourceSidecar, DateSourceMtime}
+	tests := []struct {
+		name       string
+		change     func(t *testing.T, r *DateResolver, file string) []DateSource
+		wantSource DateSource
+	}{
+		{"未变化时使用缓存", func(*testing.T, *DateResolver, string) []DateSource { return sources }, DateSourceMtime},
+		{"修改时间变化", func(t *testing.T, _ *DateResolver, file string) []DateSource {
+			later := modified.Add(time.Hour)
+			if err := os.Chtimes(file, later, later); err != nil {
+				t.Fatal(err)
+			}
+			return sources
+		}, DateSourceSidecar},
+		{"来源顺序变化", func(*testing.T, *DateResolver, string) []DateSource {
+			return []DateSource{DateSourceSidecar, DateSourceFilename, DateSourceMtime}
+		}, DateSourceSidecar},
+		{"重置缓存", func(_ *testing.T, r *DateResolver, _ string) []DateSource {
+			r.reset()
+			return sources
+		}, DateSourceSidecar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeTestFile(t, filepath.Join(t.TempDir(), "a.jpg"), "photo")
+			if err := os.Chtimes(file, modified, modified); err != nil {
+				t.Fatal(err)
+			}
+			r := newDateResolver()
+			resolve := func(sources []DateSource) DateSource {
+				info, err := os.Stat(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, source := r.Resolve(file, info, sources)
+				return source
+			}
+			if source := resolve(sources); source != DateSourceMtime {
+				t.Fatalf("首次解析来源 = %s", source)
+			}
+			// 解析后才出现的附属文件只有重新解析时才会用到
+			writeTestFile(t, file+".xmp", `<x:xmpmeta exif:DateTimeOriginal="2023-03-05T10:00:00"/>`)
+			if source := resolve(tt.change(t, r, file)); source != tt.wantSource {
+				t.Fatalf("来源 = %s, 期望 %s", source, tt.wantSource)
+			}
+		})
+	}
+}
+
+// 文件名中的日期和可选的时间，更长的数字序列和不存在的日期不算
+func TestParseFilenameDate(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   time.Time
+		wantOK bool
+	}{
+		{"20240615.jpg", time.Date(2024, 6, 15, 0, 0, 0, 0, time.Local), true},
+		{"2024-06-15 12.30.45.jpg", time.Date(2024, 6, 15, 12, 30, 45, 0, time.Local), true},
+		{"IMG_2024_06_15_123456.jpg", time.Date(2024, 6, 15, 12, 34, 56, 0, time.Local), true},
+		{"PXL_20241231-0805.jpg", time.Date(2024, 12, 31, 0, 0, 0, 0, time.Local), true},
+		{"scan_2023-02-29.pdf", time.Time{}, false},
+		{"order_202406151.pdf", time.Time{}, false},
+		{"1234567890.jpg", time.Time{}, false},
+		{"holiday.jpg", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, ok := parseFilenameDate(tt.name)
+			if ok != tt.wantOK || !date.Equal(tt.want) {
+				t.Fatalf("parseFilenameDate(%q) = %v, %v, 期望 %v, %v", tt.name, date, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
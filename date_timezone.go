@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Windows等没有时区数据库的系统上也能使用指定的时区
+)
+
+// 按日期整理时使用的时区。默认使用本机时区；指定时区后日期文件夹按该时区的日期划分，
+// 例如在外地整理时仍按家里的日期归档
+
+// 设置中可以直接选择的常用时区，也可以输入其他IANA时区名称。Local 表示本机时区
+var commonDateTimeZones = []string{"Local", "UTC", "Asia/Shanghai", "Asia/Tokyo", "Europe/London", "Europe/Berlin", "America/New_York", "America/Los_Angeles"}
+
+// 解析时区名称，空字符串和 Local 表示本机时区
+func loadDateLocation(name string) (*time.Location, error) {
+	if name == "" || strings.EqualFold(name, "local") {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("无法识别的时区 %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// 当前设置的时区，无法识别时使用本机时区
+func (fo *FileOrganizer) dateLocation() *time.Location {
+	loc, err := loadDateLocation(fo.DateTimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// 不带时区的日期来源：EXIF和文件名中的日期是拍摄设备的本地时间
+func floatingDateSource(source DateSource) bool {
+	return source == DateSourceExif || source == DateSourceFilename
+}
+
+// FAT/exFAT上的修改时间和创建时间：
+//
+// FAT不记录时区，系统读取时把磁盘上的时间当作本机时区的时间。exFAT有记录UTC偏移的字段，写入设备填写时
+// 系统能换算出准确的时刻，但很多相机和读卡设备不填写（标记为无效），这时与FAT一样按本机时区解释；
+// 读取到的时间无法区分这两种情况，因此两者同样处理。这里假设写入时间的设备
+// （相机、手机或另一台电脑）与本机使用同一个时区的墙上时间，因此按墙上时间划分日期——
+// 相机在 23:30 写入的照片无论整理时选择哪个时区都放在当天，不会因为换算时区跑到前一天或后一天。
+// 时间同时截到偶数秒，与FAT的2秒精度一致，同一个文件在FAT上和复制到其他磁盘后按同样的时间整理。
+// 写入设备的时区与本机不同时，按墙上时间得到的日期仍是设备所在地的日期
+
+// coarseRootCache 缓存源文件夹和目标文件夹是否位于FAT/exFAT，每次扫描后重新检查
+type coarseRootCache struct {
+	mu    sync.Mutex
+	roots map[string]string // 文件夹 -> 文件系统类型，不是FAT/exFAT时为空字符串
+}
+
+func newCoarseRootCache() *coarseRootCache {
+	return &coarseRootCache{roots: make(map[string]string)}
+}
+
+// 文件夹是否位于FAT/exFAT，返回文件系统类型。第一次检查时返回isNew为true
+func (c *coarseRootCache) check(root string) (fsType string, isNew bool) {
+	c.mu.Lock()
+	fsType, ok := c.roots[root]
+	c.mu.Unlock()
+	if ok {
+		return fsType, false
+	}
+	if detected, coarse := coarseMtimeFilesystem(root); coarse {
+		fsType = detected
+	}
+	c.mu.Lock()
+	c.roots[root] = fsType
+	c.mu.Unlock()
+	return fsType, true
+}
+
+func (c *coarseRootCache) reset() {
+	c.mu.Lock()
+	c.roots = make(map[string]string)
+	c.mu.Unlock()
+}
+
+// 文件是否位于FAT/exFAT上的源文件夹或目标文件夹中
+func (fo *FileOrganizer) onCoarseRoot(filePath string, config Config) bool {
+	roots := append([]string{config.TargetDir}, config.SourceDirs...)
+	for _, root := range roots {
+		if root == "" || !isWithinAny(filePath, []string{root}) {
+			continue
+		}
+		fsType, isNew := fo.coarseRoots.check(root)
+		if fsType == "" {
+			continue
+		}
+		if isNew && root != config.TargetDir {
+			fo.log(fmt.Sprintf("注意: 源文件夹 %s 位于 %s 文件系统，修改时间可能没有记录时区，按写入时的墙上时间划分日期", root, fsType))
+		}
+		return true
+	}
+	return false
+}
+
+// 文件用于划分日期的时间：带时区的时间换算到整理使用的时区；
+// 不带时区的时间（EXIF、文件名、FAT/exFAT上的修改时间）保持墙上时间，只换成整理使用的时区
+func (fo *FileOrganizer) bucketTime(filePath string, date time.Time, source DateSource, config Config) time.Time {
+	loc := config.location()
+	floating := floatingDateSource(source)
+	if (source == DateSourceMtime || source == DateSourceCreated) && fo.onCoarseRoot(filePath, config) {
+		floating = true
+		date = date.Truncate(coarseMtimeResolution)
+	}
+	if !floating || loc == time.Local {
+		return date.In(loc)
+	}
+	wall := date.In(time.Local)
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc)
+}
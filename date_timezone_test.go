@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 测试期间把本机时区换成name
+func setLocalZone(t *testing.T, name string) {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = saved })
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := loadDateLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// 夏令时切换、重复的一小时、午夜切换、+13时区的跨年和闰秒，日期文件夹只由时间和整理使用的时区决定，
+// 与本机时区和整理的次数无关
+func TestNastyTimestampFolders(t *testing.T) {
+	tests := []struct {
+		name   string
+		zone   string
+		mtime  time.Time // 修改时间，为零时使用文件名中的日期
+		file   string
+		format string
+		want   string
+	}{
+		{"纽约夏令时开始前一秒", "America/New_York", time.Date(2024, 3, 10, 6, 59, 59, 0, time.UTC), "a.jpg", "YYYY-MM-DD", "2024-03-10"},
+		{"纽约跳过的 02:30", "America/New_York", time.Date(2024, 3, 10, 7, 30, 0, 0, time.UTC), "a.jpg", "YYYY-MM-DD", "2024-03-10"},
+		{"纽约重复的 01:30（夏令时）", "America/New_York", time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), "a.jpg", "YYYYMMDD", "20241103"},
+		{"纽约重复的 01:30（标准时）", "America/New_York", time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC), "a.jpg", "YYYYMMDD", "20241103"},
+		{"圣地亚哥午夜切换前一秒", "America/Santiago", time.Date(2024, 9, 8, 3, 59, 59, 0, time.UTC), "a.jpg", "YYYY-MM-DD", "2024-09-07"},
+		{"圣地亚哥跳过的午夜", "America/Santiago", time.Date(2024, 9, 8, 4, 0, 0, 0, time.UTC), "a.jpg", "YYYY-MM-DD", "2024-09-08"},
+		{"汤加跨年前一秒", "Pacific/Tongatapu", time.Date(2023, 12, 31, 10, 59, 59, 0, time.UTC), "a.jpg", "YYYY-MM", "2023-12"},
+		{"汤加跨年", "Pacific/Tongatapu", time.Date(2023, 12, 31, 11, 0, 0, 0, time.UTC), "a.jpg", "YY-MM-DD", "24-01-01"},
+		{"文件名中的闰秒", "Pacific/Tongatapu", time.Time{}, "IMG_20161231_235960.jpg", "YYYY-MM-DD", "2016-12-31"},
+		{"文件名中的跨年前一秒", "America/New_York", time.Time{}, "IMG_20231231_235959.jpg", "YYYYMM", "202312"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			// 在不同的本机时区下各整理两次
+			for _, local := range []string{"UTC", "Asia/Tokyo", tt.zone} {
+				setLocalZone(t, local)
+				fo := newTestOrganizer(t)
+				path := writeTestFile(t, filepath.Join(t.TempDir(), tt.file), "photo")
+				sources := []DateSource{DateSourceMtime}
+				if tt.mtime.IsZero() {
+					sources = []DateSource{DateSourceFilename}
+				} else if err := os.Chtimes(path, tt.mtime, tt.mtime); err != nil {
+					t.Fatal(err)
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				config := Config{Location: mustLoadLocation(t, tt.zone), DateSources: sources, FolderDateFormat: tt.format}
+				for i := 0; i < 2; i++ {
+					got = append(got, getFileModifyDate(fo.fileDate(path, info, config), tt.format, config.location()))
+				}
+			}
+			for _, folder := range got {
+				if folder != tt.want {
+					t.Fatalf("日期文件夹 = %v, 期望都是 %s", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// 连拍序列按第一张的日期整理时，与第一张单独整理时一样按墙上时间划分日期
+func TestBurstDateUsesBucketTime(t *testing.T) {
+	setLocalZone(t, "UTC")
+	fo := newTestOrganizer(t)
+	config := Config{Location: mustLoadLocation(t, "Asia/Tokyo"), FolderDateFormat: "YYYY-MM-DD"}
+	first := time.Date(2024, 1, 1, 23, 59, 59, 0, time.Local)
+	dates := map[string]time.Time{"IMG_0001.jpg": first, "IMG_0002.jpg": first.Add(time.Second)}
+
+	dir := t.TempDir()
+	var files []string
+	infos := make(map[string]os.FileInfo)
+	for name := range dates {
+		path := writeTestFile(t, filepath.Join(dir, name), name)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+		infos[path] = info
+	}
+	config.Bursts = detectBursts(files, infos, func(path string, info os.FileInfo) (time.Time, DateSource) {
+		return dates[filepath.Base(path)], DateSourceFilename
+	}, 2*time.Second, 10)
+	if config.Bursts.count() != 1 {
+		t.Fatalf("连拍序列 = %d, 期望 1", config.Bursts.count())
+	}
+
+	alone := getFileModifyDate(fo.bucketTime(files[0], first, DateSourceFilename, config), config.FolderDateFormat, config.location())
+	if alone != "2024-01-01" {
+		t.Fatalf("单独整理的日期文件夹 = %s", alone)
+	}
+	for _, path := range files {
+		if got := getFileModifyDate(fo.fileDate(path, infos[path], config), config.FolderDateFormat, config.location()); got != alone {
+			t.Errorf("%s 的日期文件夹 = %s, 期望 %s", filepath.Base(path), got, alone)
+		}
+	}
+}
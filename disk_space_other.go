@@ -0,0 +1,10 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+import "errors"
+
+// 获取路径所在磁盘的可用空间（当前系统不支持）
+func diskFreeSpace(path string) (int64, error) {
+	return 0, errors.New("当前系统不支持获取磁盘可用空间")
+}
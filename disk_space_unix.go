@@ -0,0 +1,18 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// 获取路径所在磁盘的可用空间（普通用户可用的部分）
+func diskFreeSpace(path string) (int64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("获取 %s 的可用空间失败: %w", path, err)
+	}
+	return int64(uint64(stat.Bavail) * uint64(stat.Bsize)), nil
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// 获取路径所在磁盘的可用空间（当前用户可用的部分，考虑磁盘配额）
+func diskFreeSpace(path string) (int64, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var freeToCaller, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &freeToCaller, &total, &totalFree); err != nil {
+		return 0, fmt.Errorf("获取 %s 的可用空间失败: %w", path, err)
+	}
+	return int64(freeToCaller), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 邮件头最多读取的字节数，只读取头部，不读取正文和附件
+const emailHeaderReadLimit = 256 * 1024
+
+// emailHeader 导出邮件中用于整理的头信息
+type emailHeader struct {
+	Date       time.Time
+	HasDate    bool
+	SenderHost string // 发件人地址的域名，小写
+}
+
+// 文件是否为导出的邮件（.eml 或 Outlook 的 .msg）
+func isEmailFile(path string) bool {
+	switch strings.ToLower(fileExtension(path)) {
+	case ".eml", ".msg":
+		return true
+	}
+	return false
+}
+
+// 读取导出邮件的 Date 和 From 头。.msg 文件读取其中保存的原始传输头。
+// 头部无法解析时返回错误，调用方改用其他日期来源
+func readEmailHeader(path string) (emailHeader, error) {
+	var headerText io.Reader
+	if strings.ToLower(fileExtension(path)) == ".msg" {
+		text, err := readMsgTransportHeaders(path)
+		if err != nil {
+			return emailHeader{}, err
+		}
+		// 旧版 Outlook 在传输头前加一行 "Microsoft Mail Internet Headers Version 2.0"，不是有效的邮件头
+		if line, rest, ok := strings.Cut(text, "\n"); ok && !strings.Contains(line, ":") {
+			text = rest
+		}
+		headerText = strings.NewReader(text)
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return emailHeader{}, err
+		}
+		defer file.Close()
+		headerText = io.LimitReader(file, emailHeaderReadLimit)
+	}
+	return parseEmailHeader(headerText)
+}
+
+// 解析邮件头部分，遇到空行（正文开始）即停止
+func parseEmailHeader(r io.Reader) (emailHeader, error) {
+	msg, err := mail.ReadMessage(bufio.NewReader(r))
+	if err != nil {
+		return emailHeader{}, fmt.Errorf("解析邮件头失败: %w", err)
+	}
+
+	var header emailHeader
+	if date, err := msg.Header.Date(); err == nil && !date.IsZero() {
+		header.Date = date
+		header.HasDate = true
+	}
+	if from, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		if at := strings.LastIndexByte(from.Address, '@'); at >= 0 && at < len(from.Address)-1 {
+			header.SenderHost = strings.ToLower(strings.TrimSpace(from.Address[at+1:]))
+		}
+	}
+	return header, nil
+}
+
+// 从邮件头读取日期，不是邮件或没有有效的 Date 头时返回false
+func readEmailDate(path string) (time.Time, bool) {
+	if !isEmailFile(path) {
+		return time.Time{}, false
+	}
+	header, err := readEmailHeader(path)
+	if err != nil || !header.HasDate {
+		return time.Time{}, false
+	}
+	return header.Date, true
+}
+
+// emailSenderCache 缓存邮件的发件人域名，规划目标和整理后设置日期文件夹时只读取一次
+type emailSenderCache struct {
+	mu      sync.Mutex
+	senders map[string]string
+}
+
+// 创建发件人缓存
+func newEmailSenderCache() *emailSenderCache {
+	return &emailSenderCache{senders: make(map[string]string)}
+}
+
+// 邮件发件人域名文件夹的名称，不是邮件或没有发件人时返回空字符串
+func (c *emailSenderCache) folder(path string) string {
+	if !isEmailFile(path) {
+		return ""
+	}
+	c.mu.Lock()
+	folder, ok := c.senders[path]
+	c.mu.Unlock()
+	if ok {
+		return folder
+	}
+	if header, err := readEmailHeader(path); err == nil && header.SenderHost != "" {
+		folder = sanitizeFolderName(header.SenderHost)
+	}
+	c.mu.Lock()
+	c.senders[path] = folder
+	c.mu.Unlock()
+	return folder
+}
+
+// 清空缓存，每次扫描后重新读取
+func (c *emailSenderCache) reset() {
+	c.mu.Lock()
+	c.senders = make(map[string]string)
+	c.mu.Unlock()
+}
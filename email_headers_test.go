@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 来源顺序中没有邮件头时插入到文件时间之前，已有时保持用户的顺序
+func TestWithEmailDateSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []DateSource
+		want    []DateSource
+	}{
+		{"默认顺序", nil, []DateSource{DateSourceEmail, DateSourceMtime}},
+		{"内容来源在前", []DateSource{DateSourceExif, DateSourceFilename, DateSourceCreated, DateSourceMtime},
+			[]DateSource{DateSourceExif, DateSourceFilename, DateSourceEmail, DateSourceCreated, DateSourceMtime}},
+		{"没有文件时间", []DateSource{DateSourceExif}, []DateSource{DateSourceExif, DateSourceEmail}},
+		{"已有邮件头", []DateSource{DateSourceMtime, DateSourceEmail}, []DateSource{DateSourceMtime, DateSourceEmail}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withEmailDateSource(tt.sources); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("来源顺序 = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 默认的来源顺序下导出的邮件按 Date 头整理，头部无法解析的邮件和其他文件按修改时间整理
+func TestEmailDateDefaultSources(t *testing.T) {
+	exported := time.Date(2025, 9, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"有 Date 头", "a.eml", "Date: Tue, 05 Mar 2024 10:00:00 +0000\r\nFrom: a@example.com\r\n\r\nbody", "2024-03"},
+		{"Date 头无效", "b.eml", "Date: not a date\r\nFrom: a@example.com\r\n\r\nbody", "2025-09"},
+		{"不是邮件", "c.txt", "Date: Tue, 05 Mar 2024 10:00:00 +0000\r\n\r\nbody", "2025-09"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			target := t.TempDir()
+			file := writeTestFile(t, filepath.Join(source, tt.file), tt.content)
+			if err := os.Chtimes(file, exported, exported); err != nil {
+				t.Fatal(err)
+			}
+			config := Config{
+				SourceDir:        source,
+				SourceDirs:       []string{source},
+				TargetDir:        target,
+				FileExtensions:   []string{".eml", ".txt"},
+				OrganizeRule:     string(RuleByDate),
+				FolderDateFormat: "YYYY-MM",
+				ExtensionCase:    "lowercase",
+				ConflictPolicy:   ConflictRename,
+				ExcludedFiles:    map[string]bool{},
+			}
+			if summary, err := fo.processFiles(config, []string{file}); err != nil || summary.Failed != 0 {
+				t.Fatalf("整理: %+v, %v", summary, err)
+			}
+			if _, err := os.Stat(filepath.Join(target, tt.want, tt.file)); err != nil {
+				t.Fatalf("应整理到 %s: %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 空文件夹移入的文件夹，位于目标根目录下
+const EmptyDirsFolderName = "空文件夹"
+
+// 删除整理后变空的源子文件夹，并逐级向上删除变空的上级文件夹。
+// 源文件夹本身和目标文件夹不会被删除，返回删除的文件夹数量
+func removeEmptiedDirs(dirs map[string]bool, roots []string, targetDir string) int {
+	// 先处理较深的文件夹，上级文件夹才可能变空
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	removed := 0
+	for _, dir := range sorted {
+		for isWithinAny(dir, roots) && !isSourceRoot(dir, roots) && dir != targetDir {
+			entries, err := os.ReadDir(dir)
+			if err != nil || len(entries) > 0 {
+				break
+			}
+			if err := os.Remove(dir); err != nil {
+				break
+			}
+			removed++
+			dir = filepath.Dir(dir)
+		}
+	}
+	return removed
+}
+
+// 是否为某个源文件夹本身
+func isSourceRoot(dir string, roots []string) bool {
+	for _, root := range roots {
+		if filepath.Clean(root) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// 文件夹中是否没有任何文件（可以包含空的子文件夹）
+func isEmptyTree(dir string) bool {
+	empty := true
+	filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			empty = false
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return empty
+}
+
+// 将源文件夹下第一层的空文件夹移到目标根目录的「空文件夹」中等待检查，返回移动的数量。只读模式下不移动
+func (fo *FileOrganizer) moveEmptyTopLevelDirs(config Config) int {
+	if err := fo.checkWritable(); err != nil {
+		fo.log("跳过移动空文件夹: " + err.Error())
+		return 0
+	}
+	reviewDir := filepath.Join(config.TargetDir, EmptyDirsFolderName)
+	moved := 0
+	for _, root := range config.SourceDirs {
+		if isSourceRoot(root, config.ReadOnlySources) {
+			continue
+		}
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			fo.log(fmt.Sprintf("读取源文件夹失败 %s: %v", root, err))
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() || strings.HasPrefix(name, ".") || name == EmptyDirsFolderName || name == EmptyFilesFolderName || name == ConvertedOriginalsFolderName ||
+				name == ReplacedFolderName {
+				continue
+			}
+			dir := filepath.Join(root, name)
+			if dir == config.TargetDir || !isEmptyTree(dir) {
+				continue
+			}
+			if err := os.MkdirAll(reviewDir, 0755); err != nil {
+				fo.log(fmt.Sprintf("创建文件夹失败 %s: %v", reviewDir, err))
+				return moved
+			}
+			target := fo.uniqueTargetPath(reviewDir, name)
+			if err := os.Rename(dir, target); err != nil {
+				fo.log(fmt.Sprintf("移动空文件夹失败 %s: %v", dir, err))
+				continue
+			}
+			fo.log(fmt.Sprintf("已移动空文件夹: %s -> %s", dir, target))
+			moved++
+		}
+	}
+	return moved
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 事件标签日期的格式
+const eventDateLayout = "2006-01-02"
+
+// EventLabel 事件标签：日期范围内按日期整理的文件夹名称后追加标签
+type EventLabel struct {
+	Start string `json:"start"` // 开始日期（含），YYYY-MM-DD
+	End   string `json:"end"`   // 结束日期（含），YYYY-MM-DD
+	Label string `json:"label"`
+}
+
+// 日期是否在事件范围内
+func (e EventLabel) contains(t time.Time) bool {
+	day := t.Format(eventDateLayout)
+	return day >= e.Start && day <= e.End
+}
+
+// 两个事件的日期范围是否重叠
+func (e EventLabel) overlaps(other EventLabel) bool {
+	return e.Start <= other.End && other.Start <= e.End
+}
+
+// 查找日期所在的事件标签
+func eventLabelFor(t time.Time, labels []EventLabel) (EventLabel, bool) {
+	for _, label := range labels {
+		if label.contains(t) {
+			return label, true
+		}
+	}
+	return EventLabel{}, false
+}
+
+// 检查事件标签是否有效，且与已有的事件日期范围不重叠
+func validateEventLabel(label EventLabel, existing []EventLabel) error {
+	start, err := time.Parse(eventDateLayout, label.Start)
+	if err != nil {
+		return fmt.Errorf("开始日期格式应为 YYYY-MM-DD: %s", label.Start)
+	}
+	end, err := time.Parse(eventDateLayout, label.End)
+	if err != nil {
+		return fmt.Errorf("结束日期格式应为 YYYY-MM-DD: %s", label.End)
+	}
+	if end.Before(start) {
+		return errors.New("结束日期不能早于开始日期")
+	}
+	if strings.TrimSpace(label.Label) == "" {
+		return errors.New("请输入事件标签")
+	}
+	for _, other := range existing {
+		if label.overlaps(other) {
+			return fmt.Errorf("日期范围与事件「%s」(%s ~ %s) 重叠", other.Label, other.Start, other.End)
+		}
+	}
+	return nil
+}
+
+// 加载保存的事件标签
+func (fo *FileOrganizer) loadEventLabels() {
+	fo.EventLabels = nil
+	if data := fyne.CurrentApp().Preferences().StringWithFallback("event_labels", ""); data != "" {
+		if err := json.Unmarshal([]byte(data), &fo.EventLabels); err != nil {
+			fo.log(fmt.Sprintf("加载事件标签失败: %v", err))
+			fo.EventLabels = nil
+		}
+	}
+}
+
+// 保存事件标签
+func (fo *FileOrganizer) saveEventLabels() {
+	data, err := json.Marshal(fo.EventLabels)
+	if err != nil {
+		fo.log(fmt.Sprintf("保存事件标签失败: %v", err))
+		return
+	}
+	fyne.CurrentApp().Preferences().SetString("event_labels", string(data))
+}
+
+// 将已有的未加标签的日期文件夹中属于事件范围的文件合并到带标签的文件夹。只处理本次待整理的文件
+// 按规则不加标签时会放入的文件夹，即规则生成的日期文件夹，不会改动目标中其他同名的文件夹。
+// pending中的文件（本次待整理的文件）由整理过程按规则移动，这里跳过
+func (fo *FileOrganizer) mergeEventFolders(config Config, pending map[string]bool) {
+	if OrganizeRule(config.OrganizeRule) != RuleByDate || len(config.EventLabels) == 0 {
+		return
+	}
+	if err := fo.checkWritable(); err != nil {
+		fo.log(fmt.Sprintf("未合并事件文件夹: %v", err))
+		return
+	}
+
+	// 未加标签的文件夹 → 事件标签 → 加标签的文件夹。按月命名时一个文件夹可能跨多个事件
+	unlabeledConfig := config
+	unlabeledConfig.EventLabels = nil
+	merges := make(map[string]map[EventLabel]string)
+	for filePath := range pending {
+		info := fo.scannedFileInfos[filePath]
+		if info == nil {
+			var err error
+			if info, err = os.Stat(filePath); err != nil {
+				continue
+			}
+		}
+		label, ok := eventLabelFor(fo.fileDate(filePath, info, config), config.EventLabels)
+		if !ok {
+			continue
+		}
+		unlabeled := fo.planTargetDir(filePath, info, unlabeledConfig)
+		labeled := fo.planTargetDir(filePath, info, config)
+		if unlabeled == "" || labeled == "" || unlabeled == labeled {
+			continue
+		}
+		if merges[unlabeled] == nil {
+			merges[unlabeled] = make(map[EventLabel]string)
+		}
+		merges[unlabeled][label] = labeled
+	}
+
+	merged := 0
+	for dir, targets := range merges {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || pending[path] {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			label, ok := eventLabelFor(fo.fileDate(path, info, config), config.EventLabels)
+			if !ok {
+				continue
+			}
+			labeledDir, ok := targets[label]
+			if !ok {
+				continue
+			}
+			if _, err := fo.moveFile(path, labeledDir, config.PreserveXattrs); err != nil {
+				fo.log(fmt.Sprintf("合并到事件文件夹失败 %s: %v", path, err))
+				continue
+			}
+			merged++
+		}
+		// 文件都合并走后删除变空的日期文件夹
+		os.Remove(dir)
+	}
+	if merged > 0 {
+		fo.log(fmt.Sprintf("已将 %d 个文件从未加标签的日期文件夹合并到事件文件夹", merged))
+	}
+}
+
+// 从下往上删除空文件夹（包括dir本身）
+func removeEmptyDirs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			removeEmptyDirs(filepath.Join(dir, entry.Name()))
+		}
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+		os.Remove(dir)
+	}
+}
+
+// 显示事件标签编辑对话框
+func (fo *FileOrganizer) showEventLabelsDialog() {
+	selected := -1
+	labelList := widget.NewList(
+		func() int {
+			return len(fo.EventLabels)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			label := fo.EventLabels[i]
+			o.(*widget.Label).SetText(fmt.Sprintf("%s ~ %s  %s", label.Start, label.End, label.Label))
+		},
+	)
+	labelList.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+	labelList.OnUnselected = func(id widget.ListItemID) {
+		selected = -1
+	}
+
+	changed := func() {
+		sort.Slice(fo.EventLabels, func(i, j int) bool {
+			return fo.EventLabels[i].Start < fo.EventLabels[j].Start
+		})
+		fo.saveEventLabels()
+		selected = -1
+		labelList.UnselectAll()
+		labelList.Refresh()
+		fo.refreshFileTable()
+	}
+
+	dateValidator := func(text string) error {
+		if _, err := time.Parse(eventDateLayout, strings.TrimSpace(text)); err != nil {
+			return errors.New("格式应为 YYYY-MM-DD")
+		}
+		return nil
+	}
+	startEntry := widget.NewEntry()
+	startEntry.SetPlaceHolder("开始日期 YYYY-MM-DD")
+	startEntry.Validator = dateValidator
+	endEntry := widget.NewEntry()
+	endEntry.SetPlaceHolder("结束日期 YYYY-MM-DD")
+	endEntry.Validator = dateValidator
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("事件标签，例如 Japan")
+
+	addBtn := widget.NewButton("添加", func() {
+		label := EventLabel{
+			Start: strings.TrimSpace(startEntry.Text),
+			End:   strings.TrimSpace(endEntry.Text),
+			Label: strings.TrimSpace(nameEntry.Text),
+		}
+		if err := validateEventLabel(label, fo.EventLabels); err != nil {
+			dialog.ShowError(err, fo.Window)
+			return
+		}
+		fo.EventLabels = append(fo.EventLabels, label)
+		fo.log(fmt.Sprintf("已添加事件标签: %s ~ %s %s", label.Start, label.End, label.Label))
+		startEntry.SetText("")
+		endEntry.SetText("")
+		nameEntry.SetText("")
+		changed()
+	})
+	deleteBtn := widget.NewButton("删除选中", func() {
+		if selected < 0 || selected >= len(fo.EventLabels) {
+			dialog.ShowInformation("提示", "请先选择一个事件标签", fo.Window)
+			return
+		}
+		label := fo.EventLabels[selected]
+		fo.EventLabels = append(fo.EventLabels[:selected], fo.EventLabels[selected+1:]...)
+		fo.log(fmt.Sprintf("已删除事件标签: %s", label.Label))
+		changed()
+	})
+
+	listScroll := container.NewVScroll(labelList)
+	listScroll.SetMinSize(fyne.NewSize(420, 180))
+	content := container.NewVBox(
+		widget.NewLabel("按日期整理时，日期在范围内的文件夹名称后会追加事件标签:"),
+		listScroll,
+		deleteBtn,
+		widget.NewSeparator(),
+		container.NewGridWithColumns(2, startEntry, endEntry),
+		container.NewBorder(nil, nil, nil, addBtn, nameEntry),
+	)
+
+	fo.showDialog(dialog.NewCustom("事件标签", "关闭", content, fo.Window), startEntry)
+}
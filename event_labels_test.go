@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 写入测试文件并设置修改时间
+func writeDatedFile(t *testing.T, path string, date time.Time) string {
+	t.Helper()
+	writeTestFile(t, path, filepath.Base(path))
+	if err := os.Chtimes(path, date, date); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 只合并规则生成的日期文件夹中属于事件的文件，其他位置的同名文件夹和事件之外的文件不动
+func TestMergeEventFolders(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	target := t.TempDir()
+	day := func(d int) time.Time { return time.Date(2024, 6, d, 12, 0, 0, 0, time.Local) }
+	incoming := writeDatedFile(t, filepath.Join(source, "new.jpg"), day(3))
+	old := writeDatedFile(t, filepath.Join(target, "2024-06", "old.jpg"), day(5))
+	outside := writeDatedFile(t, filepath.Join(target, "2024-06", "outside.jpg"), day(20))
+	nested := writeDatedFile(t, filepath.Join(target, "archive", "2024-06", "nested.jpg"), day(5))
+
+	config := Config{
+		SourceDir:        source,
+		SourceDirs:       []string{source},
+		TargetDir:        target,
+		FileExtensions:   []string{".jpg"},
+		OrganizeRule:     string(RuleByDate),
+		FolderDateFormat: "YYYY-MM",
+		EventLabels:      []EventLabel{{Start: "2024-06-01", End: "2024-06-10", Label: "Japan"}},
+		ExcludedFiles:    map[string]bool{},
+	}
+	if _, err := fo.processFiles(config, []string{incoming}); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		filepath.Join(target, "2024-06 Japan", "new.jpg"),
+		filepath.Join(target, "2024-06 Japan", "old.jpg"),
+		outside,
+		nested,
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("应存在 %s", path)
+		}
+	}
+	if _, err := os.Stat(old); err == nil {
+		t.Errorf("%s 应已合并到事件文件夹", old)
+	}
+}
+
+// 只读模式下不合并
+func TestMergeEventFoldersReadOnly(t *testing.T) {
+	fo := newTestOrganizer(t)
+	target := t.TempDir()
+	date := time.Date(2024, 6, 5, 12, 0, 0, 0, time.Local)
+	old := writeDatedFile(t, filepath.Join(target, "2024-06", "old.jpg"), date)
+	incoming := writeDatedFile(t, filepath.Join(t.TempDir(), "new.jpg"), date)
+	fo.ReadOnlyMode = true
+	config := Config{
+		TargetDir:        target,
+		OrganizeRule:     string(RuleByDate),
+		FolderDateFormat: "YYYY-MM",
+		EventLabels:      []EventLabel{{Start: "2024-06-01", End: "2024-06-10", Label: "Japan"}},
+	}
+	fo.mergeEventFolders(config, map[string]bool{incoming: true})
+	if _, err := os.Stat(old); err != nil {
+		t.Fatalf("只读模式下不应移动文件: %v", err)
+	}
+}
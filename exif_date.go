@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// EXIF中的日期标签
+const (
+	exifTagModel             = 0x0110 // IFD0 相机型号
+	exifTagDateTime          = 0x0132 // IFD0 修改日期
+	exifTagExifIFD           = 0x8769 // 指向 Exif IFD 的指针
+	exifTagDateTimeOriginal  = 0x9003 // 拍摄日期
+	exifTagDateTimeDigitized = 0x9004 // 数字化日期
+	exifTagSubSecTime        = 0x9290 // 修改日期的秒以下部分
+	exifTagSubSecOriginal    = 0x9291 // 拍摄日期的秒以下部分，连拍照片靠它区分先后
+	exifTagSubSecDigitized   = 0x9292 // 数字化日期的秒以下部分
+)
+
+// EXIF日期的格式（没有时区，按本地时间解释）
+const exifDateLayout = "2006:01:02 15:04:05"
+
+// 读取JPEG的APP1段时最多查找的字节数
+const exifSearchLimit = 512 * 1024
+
+var errNoExifDate = errors.New("没有EXIF日期")
+
+// 读取照片的EXIF拍摄日期，支持JPEG和基于TIFF的格式（TIFF、DNG、CR2、NEF、ARW等）
+func readExifDate(path string) (time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+
+	r, err := exifTIFFReader(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseTIFFDate(r)
+}
+
+// 找到文件中EXIF的TIFF数据：JPEG在Exif APP1段中，TIFF格式的原始文件本身就是
+func exifTIFFReader(file *os.File) (io.ReaderAt, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, errNoExifDate
+	}
+
+	var tiffOffset int64
+	switch {
+	case header[0] == 0xFF && header[1] == 0xD8:
+		offset, err := findJPEGExif(file)
+		if err != nil {
+			return nil, err
+		}
+		tiffOffset = offset
+	case bytes.Equal(header, []byte("II*\x00")) || bytes.Equal(header, []byte("MM\x00*")):
+		tiffOffset = 0
+	default:
+		return nil, errNoExifDate
+	}
+	return io.NewSectionReader(file, tiffOffset, 1<<40), nil
+}
+
+// 在JPEG文件中查找Exif APP1段，返回其中TIFF数据的偏移量
+func findJPEGExif(file *os.File) (int64, error) {
+	offset := int64(2)
+	marker := make([]byte, 4)
+	for offset < exifSearchLimit {
+		if _, err := file.ReadAt(marker, offset); err != nil {
+			return 0, errNoExifDate
+		}
+		if marker[0] != 0xFF {
+			return 0, errNoExifDate
+		}
+		// SOS之后是图像数据，不会再有EXIF
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return 0, errNoExifDate
+		}
+		length := int64(binary.BigEndian.Uint16(marker[2:]))
+		if marker[1] == 0xE1 && length >= 8 {
+			ident := make([]byte, 6)
+			if _, err := file.ReadAt(ident, offset+4); err == nil && bytes.Equal(ident, []byte("Exif\x00\x00")) {
+				return offset + 10, nil
+			}
+		}
+		offset += 2 + length
+	}
+	return 0, errNoExifDate
+}
+
+// 读取TIFF头的字节序和第一个IFD
+func readTIFFIFD0(r io.ReaderAt) (binary.ByteOrder, map[uint16]ifdEntry, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, nil, errNoExifDate
+	}
+	var order binary.ByteOrder
+	switch string(header[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, nil, errNoExifDate
+	}
+
+	ifd0, err := readIFD(r, order, int64(order.Uint32(header[4:])))
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, ifd0, nil
+}
+
+// 从TIFF结构中读取日期，优先使用拍摄日期
+func parseTIFFDate(r io.ReaderAt) (time.Time, error) {
+	order, ifd0, err := readTIFFIFD0(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if exifOffset, ok := ifd0[exifTagExifIFD]; ok {
+		if exifIFD, err := readIFD(r, order, int64(order.Uint32(exifOffset.value))); err == nil {
+			for _, tags := range [][2]uint16{{exifTagDateTimeOriginal, exifTagSubSecOriginal}, {exifTagDateTimeDigitized, exifTagSubSecDigitized}} {
+				if date, ok := exifEntryDate(r, order, exifIFD[tags[0]]); ok {
+					return date.Add(exifEntrySubSec(r, order, exifIFD[tags[1]])), nil
+				}
+			}
+			// 修改日期在IFD0中，秒以下部分在Exif IFD中
+			if date, ok := exifEntryDate(r, order, ifd0[exifTagDateTime]); ok {
+				return date.Add(exifEntrySubSec(r, order, exifIFD[exifTagSubSecTime])), nil
+			}
+		}
+	}
+	if date, ok := exifEntryDate(r, order, ifd0[exifTagDateTime]); ok {
+		return date, nil
+	}
+	return time.Time{}, errNoExifDate
+}
+
+// ifdEntry IFD中的一个条目，value是原始的4字节值（或偏移量）
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// 读取一个IFD中的所有条目
+func readIFD(r io.ReaderAt, order binary.ByteOrder, offset int64) (map[uint16]ifdEntry, error) {
+	countBuf := make([]byte, 2)
+	if _, err := r.ReadAt(countBuf, offset); err != nil {
+		return nil, errNoExifDate
+	}
+	count := int(order.Uint16(countBuf))
+	if count == 0 || count > 1000 {
+		return nil, errNoExifDate
+	}
+	data := make([]byte, count*12)
+	if _, err := r.ReadAt(data, offset+2); err != nil {
+		return nil, errNoExifDate
+	}
+	entries := make(map[uint16]ifdEntry, count)
+	for i := 0; i < count; i++ {
+		entry := data[i*12 : i*12+12]
+		entries[order.Uint16(entry)] = ifdEntry{
+			typ:   order.Uint16(entry[2:]),
+			count: order.Uint32(entry[4:]),
+			value: entry[8:12],
+		}
+	}
+	return entries, nil
+}
+
+// 解析ASCII类型的日期条目
+func exifEntryDate(r io.ReaderAt, order binary.ByteOrder, entry ifdEntry) (time.Time, bool) {
+	const asciiType = 2
+	if entry.typ != asciiType || entry.count < 19 || entry.count > 64 {
+		return time.Time{}, false
+	}
+	buf := make([]byte, entry.count)
+	if _, err := r.ReadAt(buf, int64(order.Uint32(entry.value))); err != nil {
+		return time.Time{}, false
+	}
+	text := strings.TrimRight(string(buf), "\x00 ")
+	if len(text) > len(exifDateLayout) {
+		text = text[:len(exifDateLayout)]
+	}
+	date, err := parseLocalDate(exifDateLayout, text)
+	if err != nil || date.Year() < 1900 {
+		// 未设置日期的相机会写入全零日期
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// 读取ASCII类型的条目，不超过4个字节的值直接保存在条目中
+func exifEntryString(r io.ReaderAt, order binary.ByteOrder, entry ifdEntry, limit uint32) (string, bool) {
+	const asciiType = 2
+	if entry.typ != asciiType || entry.count == 0 || entry.count > limit {
+		return "", false
+	}
+	buf := entry.value[:min(int(entry.count), 4)]
+	if entry.count > 4 {
+		buf = make([]byte, entry.count)
+		if _, err := r.ReadAt(buf, int64(order.Uint32(entry.value))); err != nil {
+			return "", false
+		}
+	}
+	return strings.TrimSpace(strings.TrimRight(string(buf), "\x00")), true
+}
+
+// 解析日期的秒以下部分，例如 "045" 表示0.045秒。没有该条目或格式不对时返回0
+func exifEntrySubSec(r io.ReaderAt, order binary.ByteOrder, entry ifdEntry) time.Duration {
+	digits, ok := exifEntryString(r, order, entry, 16)
+	if !ok || digits == "" || len(digits) > 9 || strings.Trim(digits, "0123456789") != "" {
+		return 0
+	}
+	fraction := time.Duration(0)
+	scale := time.Second
+	for _, digit := range digits {
+		scale /= 10
+		fraction += time.Duration(digit-'0') * scale
+	}
+	return fraction
+}
@@ -0,0 +1,103 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// logCaptureNotifier 记下写入界面的日志
+type logCaptureNotifier struct {
+	UINotifier
+	mu   sync.Mutex
+	logs strings.Builder
+}
+
+func (n *logCaptureNotifier) AppendLog(text string) {
+	n.mu.Lock()
+	n.logs.WriteString(text)
+	n.mu.Unlock()
+	n.UINotifier.AppendLog(text)
+}
+
+// 等待日志中出现want，日志处理器定时刷新
+func (n *logCaptureNotifier) waitFor(want string) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		n.mu.Lock()
+		found := strings.Contains(n.logs.String(), want)
+		n.mu.Unlock()
+		if found {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
+
+// 跨磁盘移动和复制按设置保留扩展属性；扩展属性复制失败只记录警告，文件照常移动
+func TestPreserveXattrs(t *testing.T) {
+	tests := []struct {
+		name        string
+		copy        bool
+		xattrs      bool
+		dropSource  bool // 复制扩展属性前源文件已不存在，复制扩展属性失败
+		wantAttr    bool
+		wantWarning bool
+	}{
+		{"跨磁盘移动，保留扩展属性", false, true, false, true, false},
+		{"跨磁盘移动，不保留扩展属性", false, false, false, false, false},
+		{"复制，保留扩展属性", true, true, false, true, false},
+		{"复制，不保留扩展属性", true, false, false, false, false},
+		{"复制扩展属性失败", true, true, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			capture := &logCaptureNotifier{UINotifier: fo.ui}
+			fo.ui = capture
+			useFaults(t, "exdev=xattr-target")
+			source := writeTestFile(t, filepath.Join(t.TempDir(), "a.jpg"), "photo")
+			if err := unix.Setxattr(source, "user.origin", []byte("camera"), 0); err != nil {
+				if errors.Is(err, unix.ENOTSUP) {
+					t.Skip("临时文件夹不支持扩展属性")
+				}
+				t.Fatal(err)
+			}
+			targetDir := filepath.Join(t.TempDir(), "xattr-target")
+
+			var target string
+			var err error
+			switch {
+			case tt.dropSource:
+				target = writeTestFile(t, filepath.Join(targetDir, "a.jpg"), "photo")
+				fo.preserveXattrs(filepath.Join(filepath.Dir(source), "gone.jpg"), target, tt.xattrs)
+			case tt.copy:
+				target, err = fo.copyFile(source, targetDir, tt.xattrs)
+			default:
+				target, err = fo.moveFile(source, targetDir, tt.xattrs)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestFile(t, target); got != "photo" {
+				t.Fatalf("目标文件内容 = %q", got)
+			}
+			buf := make([]byte, 64)
+			n, attrErr := unix.Getxattr(target, "user.origin", buf)
+			if hasAttr := attrErr == nil && string(buf[:n]) == "camera"; hasAttr != tt.wantAttr {
+				t.Fatalf("目标文件有扩展属性 = %v, 期望 %v (%v)", hasAttr, tt.wantAttr, attrErr)
+			}
+			if tt.wantWarning && !capture.waitFor("警告: 保留扩展属性失败") {
+				t.Fatal("扩展属性复制失败时应记录警告")
+			}
+		})
+	}
+}
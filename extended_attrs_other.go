@@ -0,0 +1,11 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+// 其他系统不复制扩展属性
+const extendedAttrsSupported = false
+
+// 复制扩展属性（当前系统不支持，什么也不做）
+func copyExtendedAttrs(sourcePath, targetPath string) error {
+	return nil
+}
@@ -0,0 +1,80 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// macOS 和 Linux 上支持复制扩展属性
+const extendedAttrsSupported = true
+
+// 将源文件的全部扩展属性复制到目标文件（例如 macOS 的 Finder 标签和隔离标记）
+func copyExtendedAttrs(sourcePath, targetPath string) error {
+	names, err := listExtendedAttrs(sourcePath)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		value, err := readExtendedAttr(sourcePath, name)
+		if err != nil {
+			return err
+		}
+		if err := unix.Lsetxattr(targetPath, name, value, 0); err != nil {
+			if errors.Is(err, unix.ENOTSUP) {
+				return fmt.Errorf("目标文件系统不支持扩展属性 %s: %w", name, err)
+			}
+			return fmt.Errorf("写入扩展属性 %s 失败: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// 列出文件的扩展属性名称
+func listExtendedAttrs(path string) ([]string, error) {
+	size, err := unix.Llistxattr(path, nil)
+	if err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取扩展属性列表失败: %w", err)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, size)
+	size, err = unix.Llistxattr(path, buf)
+	if err != nil {
+		return nil, fmt.Errorf("读取扩展属性列表失败: %w", err)
+	}
+
+	// 名称以 \0 分隔
+	var names []string
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
+// 读取一个扩展属性的值
+func readExtendedAttr(path, name string) ([]byte, error) {
+	size, err := unix.Lgetxattr(path, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("读取扩展属性 %s 失败: %w", name, err)
+	}
+	value := make([]byte, size)
+	if size == 0 {
+		return value, nil
+	}
+	size, err = unix.Lgetxattr(path, name, value)
+	if err != nil {
+		return nil, fmt.Errorf("读取扩展属性 %s 失败: %w", name, err)
+	}
+	return value[:size], nil
+}
@@ -0,0 +1,95 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows 上支持复制备用数据流（例如记录下载来源的 Zone.Identifier）
+const extendedAttrsSupported = true
+
+var (
+	modkernel32          = windows.NewLazySystemDLL("kernel32.dll")
+	procFindFirstStreamW = modkernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = modkernel32.NewProc("FindNextStreamW")
+)
+
+// FindFirstStreamW 返回的流信息（WIN32_FIND_STREAM_DATA）
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [windows.MAX_PATH + 36]uint16
+}
+
+// 将源文件的备用数据流复制到目标文件，主数据流已由调用方复制
+func copyExtendedAttrs(sourcePath, targetPath string) error {
+	streams, err := listAlternateStreams(sourcePath)
+	if err != nil {
+		return err
+	}
+	for _, stream := range streams {
+		if err := copyAlternateStream(sourcePath+stream, targetPath+stream); err != nil {
+			return fmt.Errorf("复制备用数据流 %s 失败: %w", stream, err)
+		}
+	}
+	return nil
+}
+
+// 列出文件的备用数据流，返回 ":名称" 形式的后缀
+func listAlternateStreams(path string) ([]string, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var data win32FindStreamData
+	handle, _, callErr := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(pathPtr)), 0, uintptr(unsafe.Pointer(&data)), 0)
+	if windows.Handle(handle) == windows.InvalidHandle {
+		// 没有任何流或文件系统不支持备用数据流（例如FAT32）
+		if errors.Is(callErr, windows.ERROR_HANDLE_EOF) || errors.Is(callErr, windows.ERROR_INVALID_PARAMETER) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取备用数据流列表失败: %w", callErr)
+	}
+	defer windows.FindClose(windows.Handle(handle))
+
+	var streams []string
+	for {
+		// 名称形如 ":Zone.Identifier:$DATA"，主数据流为 "::$DATA"
+		name := windows.UTF16ToString(data.StreamName[:])
+		if name != "::$DATA" && strings.HasSuffix(name, ":$DATA") {
+			streams = append(streams, strings.TrimSuffix(name, ":$DATA"))
+		}
+		ok, _, callErr := procFindNextStreamW.Call(handle, uintptr(unsafe.Pointer(&data)))
+		if ok == 0 {
+			if errors.Is(callErr, windows.ERROR_HANDLE_EOF) {
+				return streams, nil
+			}
+			return nil, fmt.Errorf("读取备用数据流列表失败: %w", callErr)
+		}
+	}
+}
+
+// 复制一个备用数据流
+func copyAlternateStream(sourceStream, targetStream string) error {
+	source, err := os.Open(sourceStream)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	target, err := os.Create(targetStream)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+	return target.Close()
+}
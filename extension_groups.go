@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 选择后缀对话框中按类别快速选择的顺序，类别与文件夹名称模板的 {category} 相同
+var extensionGroupOrder = []string{"图片", "视频", "音频", "文档", "压缩包", "安装包", "代码", "邮件"}
+
+// 属于类别的后缀（已排序）
+func extensionsInCategory(category string, exts []string) []string {
+	var matched []string
+	for _, ext := range exts {
+		if fileCategories[ext] == category {
+			matched = append(matched, ext)
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+// 按通配符选择后缀，例如 "*.cr?" 或 "cr?, mp*"，多个模式用逗号或空白分隔。
+// 通配符只与扫描到的后缀比较，返回匹配的后缀（已排序）
+func matchExtensionPatterns(text string, exts []string) ([]string, error) {
+	var patterns []string
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ';' || unicode.IsSpace(r)
+	}) {
+		pattern := strings.ToLower(strings.TrimLeft(field, "*"))
+		if !strings.HasPrefix(pattern, ".") {
+			pattern = "." + pattern
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("无效的通配符 %q: %w", field, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	var matched []string
+	for _, ext := range exts {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, ext); ok {
+				matched = append(matched, ext)
+				break
+			}
+		}
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
+
+// extensionGroupToggles 选择后缀对话框顶部的类别开关，类别中部分后缀被选中时显示为半选
+type extensionGroupToggles struct {
+	checks   map[string]*widget.Check
+	members  map[string][]string
+	boxes    map[string]*widget.Check
+	updating bool
+}
+
+// 为扫描到的后缀中出现的类别创建开关。boxes 是每个后缀的复选框
+func newExtensionGroupToggles(exts []string, boxes map[string]*widget.Check) *extensionGroupToggles {
+	g := &extensionGroupToggles{
+		checks:  make(map[string]*widget.Check),
+		members: make(map[string][]string),
+		boxes:   boxes,
+	}
+	for _, category := range extensionGroupOrder {
+		members := extensionsInCategory(category, exts)
+		if len(members) == 0 {
+			continue
+		}
+		g.members[category] = members
+		check := widget.NewCheck(fmt.Sprintf("所有%s（%d）", category, len(members)), nil)
+		check.OnChanged = func(checked bool) {
+			if g.updating {
+				return
+			}
+			g.setAll(g.members[category], checked)
+		}
+		g.checks[category] = check
+	}
+	return g
+}
+
+// 选中或取消一组后缀，然后刷新类别开关
+func (g *extensionGroupToggles) setAll(exts []string, checked bool) {
+	g.updating = true
+	for _, ext := range exts {
+		g.boxes[ext].SetChecked(checked)
+	}
+	g.updating = false
+	g.refresh()
+}
+
+// 按后缀复选框更新类别开关：全部选中、部分选中或都未选中
+func (g *extensionGroupToggles) refresh() {
+	g.updating = true
+	defer func() { g.updating = false }()
+	for category, check := range g.checks {
+		selected := 0
+		for _, ext := range g.members[category] {
+			if g.boxes[ext].Checked {
+				selected++
+			}
+		}
+		switch {
+		case selected == 0:
+			check.SetChecked(false)
+		case selected == len(g.members[category]):
+			check.SetChecked(true)
+		default:
+			check.Partial = true
+			check.Checked = false
+			check.Refresh()
+		}
+	}
+}
+
+// 类别开关的界面，没有任何类别时返回nil
+func (g *extensionGroupToggles) content() fyne.CanvasObject {
+	var objects []fyne.CanvasObject
+	for _, category := range extensionGroupOrder {
+		if check, ok := g.checks[category]; ok {
+			objects = append(objects, check)
+		}
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+	return container.NewGridWithColumns(4, objects...)
+}
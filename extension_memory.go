@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// 最多记住的源文件夹组合数，超过时丢弃最久未使用的
+const maxExtensionSelections = 30
+
+// extensionSelection 一组源文件夹上次选择的文件后缀
+type extensionSelection struct {
+	Sources    []string  `json:"sources"` // 排序后的源文件夹
+	Extensions []string  `json:"extensions"`
+	UsedAt     time.Time `json:"used_at"`
+}
+
+// 源文件夹组合的键：与顺序无关，路径先规范化
+func sourceSetKey(dirs []string) []string {
+	key := make([]string, len(dirs))
+	for i, dir := range dirs {
+		key[i] = filepath.Clean(dir)
+	}
+	sort.Strings(key)
+	return key
+}
+
+// 加载记住的后缀选择，按最近使用排在前面
+func loadExtensionSelections() []extensionSelection {
+	var selections []extensionSelection
+	if data := fyne.CurrentApp().Preferences().StringWithFallback("extension_selections", ""); data != "" {
+		if err := json.Unmarshal([]byte(data), &selections); err != nil {
+			return nil
+		}
+	}
+	return selections
+}
+
+// 上次为这组源文件夹选择的后缀。没有记录时使用最近一次在任意源文件夹上的选择
+func (fo *FileOrganizer) rememberedExtensions(dirs []string) []string {
+	selections := loadExtensionSelections()
+	key := strings.Join(sourceSetKey(dirs), "\n")
+	for _, selection := range selections {
+		if strings.Join(selection.Sources, "\n") == key {
+			return selection.Extensions
+		}
+	}
+	if len(selections) > 0 {
+		return selections[0].Extensions
+	}
+	return nil
+}
+
+// 记住这组源文件夹选择的后缀，放在最前面
+func (fo *FileOrganizer) rememberExtensions(dirs, extensions []string) {
+	if len(dirs) == 0 || len(extensions) == 0 {
+		return
+	}
+	sources := sourceSetKey(dirs)
+	key := strings.Join(sources, "\n")
+	selections := []extensionSelection{{Sources: sources, Extensions: normalizeExtensions(extensions), UsedAt: time.Now()}}
+	for _, selection := range loadExtensionSelections() {
+		if strings.Join(selection.Sources, "\n") != key && len(selections) < maxExtensionSelections {
+			selections = append(selections, selection)
+		}
+	}
+	data, err := json.Marshal(selections)
+	if err != nil {
+		return
+	}
+	fyne.CurrentApp().Preferences().SetString("extension_selections", string(data))
+}
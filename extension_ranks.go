@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 重新编号时文件夹先改成的临时名称后缀，避免两个文件夹互换编号时冲突
+const renumberTempSuffix = ".renumbering"
+
+// 按后缀整理时带序号的文件夹名称，例如 01_jpg
+func extensionRankFolder(name string, rank int) string {
+	return fmt.Sprintf("%02d_%s", rank, strings.TrimPrefix(name, "."))
+}
+
+// 为还没有序号的后缀分配序号：排在已有序号之后，文件多的在前。已有的序号保持不变，
+// 返回新的序号表和新分配的后缀
+func assignExtensionRanks(ranks map[string]int, counts map[string]int) (map[string]int, []string) {
+	next := 0
+	assigned := make(map[string]int, len(ranks)+len(counts))
+	for ext, rank := range ranks {
+		assigned[ext] = rank
+		if rank > next {
+			next = rank
+		}
+	}
+	var added []string
+	for ext := range counts {
+		if _, ok := assigned[ext]; !ok && ext != "" {
+			added = append(added, ext)
+		}
+	}
+	sortByCount(added, counts)
+	for _, ext := range added {
+		next++
+		assigned[ext] = next
+	}
+	return assigned, added
+}
+
+// 按文件数从多到少重新编号，文件数相同时按后缀排序
+func rerankExtensions(ranks map[string]int, counts map[string]int) map[string]int {
+	exts := make([]string, 0, len(ranks))
+	for ext := range ranks {
+		exts = append(exts, ext)
+	}
+	sortByCount(exts, counts)
+	reranked := make(map[string]int, len(exts))
+	for i, ext := range exts {
+		reranked[ext] = i + 1
+	}
+	return reranked
+}
+
+// 按文件数从多到少排序后缀
+func sortByCount(exts []string, counts map[string]int) {
+	sort.Slice(exts, func(i, j int) bool {
+		if counts[exts[i]] != counts[exts[j]] {
+			return counts[exts[i]] > counts[exts[j]]
+		}
+		return exts[i] < exts[j]
+	})
+}
+
+// 本次整理使用的后缀序号，只在按后缀整理并开启序号前缀时生效。
+// 扫描结果中还没有序号的后缀按文件数分配新的序号并保存，已有后缀的序号不会改变
+func (fo *FileOrganizer) currentExtensionRanks(rule string, extensions []string, rare map[string]bool) map[string]int {
+	if OrganizeRule(rule) != RuleByExtension || !fo.ExtensionRankPrefix {
+		return nil
+	}
+	counts := make(map[string]int)
+	for ext, n := range fo.scannedFileExtensions {
+		if !rare[ext] && fo.isTargetFile(ext, extensions) {
+			counts[ext] = n
+		}
+	}
+	ranks, added := assignExtensionRanks(fo.ExtensionRanks, counts)
+	if len(added) > 0 {
+		fo.ExtensionRanks = ranks
+		fo.saveExtensionRanks()
+		names := make([]string, len(added))
+		for i, ext := range added {
+			names[i] = rankedFolderName(ext, ranks[ext], fo.ExtensionCase)
+		}
+		fo.log("新的后缀文件夹序号: " + strings.Join(names, ", "))
+	}
+	return copyExtensionRanks(ranks)
+}
+
+// 复制后缀序号，整理过程和配置方案使用各自的副本
+func copyExtensionRanks(ranks map[string]int) map[string]int {
+	if ranks == nil {
+		return nil
+	}
+	copied := make(map[string]int, len(ranks))
+	for ext, rank := range ranks {
+		copied[ext] = rank
+	}
+	return copied
+}
+
+// 解析 jpg=1,pdf=2 形式的后缀序号，用于命令行
+func parseExtensionRanks(text string) (map[string]int, error) {
+	ranks := make(map[string]int)
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		ext, value, ok := strings.Cut(part, "=")
+		rank, err := strconv.Atoi(strings.TrimSpace(value))
+		exts := normalizeExtensions([]string{ext})
+		if !ok || err != nil || rank <= 0 || len(exts) == 0 {
+			return nil, fmt.Errorf("无效的后缀序号 %q，格式为 jpg=1", part)
+		}
+		ranks[exts[0]] = rank
+	}
+	return ranks, nil
+}
+
+// 格式化后缀序号，按序号排列，与 parseExtensionRanks 对应
+func formatExtensionRanks(ranks map[string]int) string {
+	exts := make([]string, 0, len(ranks))
+	for ext := range ranks {
+		exts = append(exts, ext)
+	}
+	sort.Slice(exts, func(i, j int) bool { return ranks[exts[i]] < ranks[exts[j]] })
+	parts := make([]string, len(exts))
+	for i, ext := range exts {
+		parts[i] = fmt.Sprintf("%s=%d", strings.TrimPrefix(ext, "."), ranks[ext])
+	}
+	return strings.Join(parts, ",")
+}
+
+// 加载已分配的后缀序号
+func (fo *FileOrganizer) loadExtensionRanks() {
+	fo.ExtensionRanks = nil
+	if data := fyne.CurrentApp().Preferences().StringWithFallback("extension_ranks", ""); data != "" {
+		if err := json.Unmarshal([]byte(data), &fo.ExtensionRanks); err != nil {
+			fo.log(fmt.Sprintf("加载后缀序号失败: %v", err))
+			fo.ExtensionRanks = nil
+		}
+	}
+}
+
+// 保存已分配的后缀序号
+func (fo *FileOrganizer) saveExtensionRanks() {
+	data, err := json.Marshal(fo.ExtensionRanks)
+	if err != nil {
+		fo.log(fmt.Sprintf("保存后缀序号失败: %v", err))
+		return
+	}
+	fyne.CurrentApp().Preferences().SetString("extension_ranks", string(data))
+}
+
+// 后缀按大小写设置带序号的文件夹名称
+func rankedFolderName(ext string, rank int, extCase string) string {
+	if extCase == "uppercase" {
+		ext = strings.ToUpper(ext)
+	}
+	return extensionRankFolder(ext, rank)
+}
+
+// 按后缀序号文件夹名称查找后缀
+func rankedFolderNames(ranks map[string]int, extCase string) map[string]string {
+	names := make(map[string]string, len(ranks))
+	for ext, rank := range ranks {
+		names[rankedFolderName(ext, rank, extCase)] = ext
+	}
+	return names
+}
+
+// 查找目标文件夹中带序号的后缀文件夹（目录结构为源结构优先时可能在多个子文件夹中）
+func findRankedFolders(targetDir string, names map[string]string) map[string]string {
+	found := make(map[string]string)
+	filepath.Walk(targetDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() || path == targetDir {
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if ext, ok := names[info.Name()]; ok {
+			found[path] = ext
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return found
+}
+
+// 统计目标文件夹中每个后缀文件夹里已有的文件数
+func countRankedFolderFiles(folders map[string]string) map[string]int {
+	counts := make(map[string]int)
+	for dir, ext := range folders {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				counts[ext]++
+			}
+			return nil
+		})
+	}
+	return counts
+}
+
+// 按新的序号重命名目标文件夹中的后缀文件夹。先全部改为临时名称再改为新名称，
+// 新名称已存在时把文件合并进去，不会在旧文件夹旁边再建一个。只读模式下不做任何修改
+func (fo *FileOrganizer) renumberExtensionFolders(targetDir, extCase string, oldRanks, newRanks map[string]int, xattrs bool) (renamed, merged int, err error) {
+	if err := fo.checkWritable(); err != nil {
+		return 0, 0, err
+	}
+	// 改名期间不能同时整理到同一个目标
+	unlock, err := fo.lockTarget(targetDir, newRunID())
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+	type pendingRename struct{ temp, final string }
+	var pending []pendingRename
+	for dir, ext := range findRankedFolders(targetDir, rankedFolderNames(oldRanks, extCase)) {
+		if oldRanks[ext] == newRanks[ext] {
+			continue
+		}
+		final := filepath.Join(filepath.Dir(dir), rankedFolderName(ext, newRanks[ext], extCase))
+		temp := dir + renumberTempSuffix
+		if err := renameFile(dir, temp); err != nil {
+			fo.log(fmt.Sprintf("[重新编号] 重命名失败 %s: %v", dir, err))
+			continue
+		}
+		pending = append(pending, pendingRename{temp: temp, final: final})
+	}
+
+	for _, p := range pending {
+		if _, err := os.Stat(p.final); os.IsNotExist(err) {
+			if err := renameFile(p.temp, p.final); err != nil {
+				fo.log(fmt.Sprintf("[重新编号] 重命名失败 %s: %v", p.final, err))
+				continue
+			}
+			renamed++
+			continue
+		}
+		// 新名称的文件夹已存在，按相对路径把文件合并进去
+		filepath.Walk(p.temp, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(p.temp, filepath.Dir(path))
+			if err != nil {
+				return nil
+			}
+			if _, err := fo.moveFile(path, filepath.Join(p.final, rel), xattrs); err != nil {
+				fo.log(fmt.Sprintf("[重新编号] 合并文件失败 %s: %v", path, err))
+				return nil
+			}
+			merged++
+			return nil
+		})
+		removeEmptyDirs(p.temp)
+		renamed++
+	}
+	return renamed, merged, nil
+}
+
+// 按目标文件夹和扫描结果中的文件数重新编号后缀文件夹，确认后重命名已有的文件夹
+func (fo *FileOrganizer) showRerankExtensionsDialog() {
+	if len(fo.ExtensionRanks) == 0 {
+		dialog.ShowInformation("提示", "还没有分配后缀序号，开启序号前缀并整理一次后再重新编号", fo.Window)
+		return
+	}
+	targetDir := strings.TrimSpace(fo.TargetDirEntry.Text)
+	if targetDir == "" {
+		dialog.ShowInformation("提示", "请先选择目标文件夹", fo.Window)
+		return
+	}
+	if fo.runActive() {
+		dialog.ShowInformation("提示", "正在整理文件，请等待整理完成后再重新编号", fo.Window)
+		return
+	}
+
+	extCase := fo.ExtensionCase
+	oldRanks := fo.ExtensionRanks
+	counts := countRankedFolderFiles(findRankedFolders(targetDir, rankedFolderNames(oldRanks, extCase)))
+	for ext, n := range fo.scannedFileExtensions {
+		if _, ok := oldRanks[ext]; ok {
+			counts[ext] += n
+		}
+	}
+	newRanks := rerankExtensions(oldRanks, counts)
+
+	var changes []string
+	for ext, rank := range newRanks {
+		if oldRanks[ext] != rank {
+			changes = append(changes, ext)
+		}
+	}
+	if len(changes) == 0 {
+		dialog.ShowInformation("重新编号", "当前序号已经按文件数排列，不需要重新编号", fo.Window)
+		return
+	}
+	sort.Slice(changes, func(i, j int) bool { return newRanks[changes[i]] < newRanks[changes[j]] })
+	lines := make([]string, len(changes))
+	for i, ext := range changes {
+		lines[i] = fmt.Sprintf("%s → %s（%d 个文件）", rankedFolderName(ext, oldRanks[ext], extCase), rankedFolderName(ext, newRanks[ext], extCase), counts[ext])
+	}
+
+	message := widget.NewLabel(fmt.Sprintf("将按文件数重新编号 %d 个后缀，目标文件夹中已有的后缀文件夹会一起改名:", len(changes)))
+	message.Wrapping = fyne.TextWrapWord
+	changeList := widget.NewLabel(strings.Join(lines, "\n"))
+	var rerankDialog dialog.Dialog
+	cancelBtn := widget.NewButton("取消", func() {
+		rerankDialog.Hide()
+	})
+	confirmBtn := widget.NewButton("重新编号", func() {
+		rerankDialog.Hide()
+		xattrs := fo.PreserveXattrs
+		go func() {
+			renamed, merged, err := fo.renumberExtensionFolders(targetDir, extCase, oldRanks, newRanks, xattrs)
+			fo.safeUpdateUI(func() {
+				if err != nil {
+					fo.log("[重新编号] 失败: " + err.Error())
+					return
+				}
+				fo.ExtensionRanks = newRanks
+				fo.saveExtensionRanks()
+				fo.log(fmt.Sprintf("[重新编号] 完成: 重命名 %d 个文件夹，合并 %d 个文件", renamed, merged))
+			})
+		}()
+	})
+	confirmBtn.Importance = widget.HighImportance
+	rerankDialog = dialog.NewCustomWithoutButtons("重新编号后缀文件夹", container.NewBorder(message,
+		container.NewHBox(layout.NewSpacer(), cancelBtn, confirmBtn), nil, nil, container.NewVScroll(changeList)), fo.Window)
+	rerankDialog.Resize(fyne.NewSize(520, 420))
+	fo.showDialog(rerankDialog, cancelBtn)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 重新编号时锁定目标文件夹，目标正在被整理时不改名
+func TestRenumberExtensionFoldersLock(t *testing.T) {
+	for _, locked := range []bool{false, true} {
+		fo := newTestOrganizer(t)
+		target := t.TempDir()
+		oldDir := filepath.Join(target, rankedFolderName(".jpg", 1, "lowercase"))
+		writeTestFile(t, filepath.Join(oldDir, "a.jpg"), "a")
+		if locked {
+			lock, err := acquireTargetLock(target, "other-run")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { lock.release() })
+		}
+
+		renamed, _, err := fo.renumberExtensionFolders(target, "lowercase", map[string]int{".jpg": 1}, map[string]int{".jpg": 2}, false)
+		if locked != (err != nil) || renamed != map[bool]int{false: 1, true: 0}[locked] {
+			t.Fatalf("锁定 %v: 重命名 %d, %v", locked, renamed, err)
+		}
+		if _, err := os.Stat(oldDir); (err == nil) != locked {
+			t.Fatalf("锁定 %v: 原文件夹 %v", locked, err)
+		}
+		if fo.runActive() {
+			t.Fatal("重新编号结束后仍计为正在整理")
+		}
+	}
+}
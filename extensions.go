@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 由多个部分组成的后缀，按整体识别，例如 backup.tar.gz 的后缀是 .tar.gz 而不是 .gz
+var multiPartExtensions = []string{
+	".tar.gz", ".tar.bz2", ".tar.xz", ".tar.zst", ".tar.lz", ".tar.lzma", ".tar.z",
+	".user.js", ".user.css",
+}
+
+// 后缀（不含点）的最大长度，更长的通常是文件名的一部分
+const maxExtensionLength = 10
+
+// 拆分文件名和后缀，后缀是文件名真实的结尾部分。识别多段后缀，不像后缀的部分
+// （例如 file.2024-03-15 中的 .2024-03-15）不算后缀
+func splitExtension(name string) (stem, ext string) {
+	base := filepath.Base(name)
+	lower := strings.ToLower(base)
+	for _, multi := range multiPartExtensions {
+		if len(base) > len(multi) && strings.HasSuffix(lower, multi) {
+			return base[:len(base)-len(multi)], base[len(base)-len(multi):]
+		}
+	}
+	ext = filepath.Ext(base)
+	// 程序包和图库的后缀可能超过一般后缀的长度，例如 .photoslibrary
+	if !isPlausibleExtension(ext) && !isPackageExtension(ext) {
+		return base, ""
+	}
+	return base[:len(base)-len(ext)], ext
+}
+
+// 获取用于分类的文件后缀（保留原大小写）。浏览器下载的文件名可能带有网址的查询参数或锚点，
+// 例如 photo.jpg?width=800，这时按 ? 或 # 之前的部分识别后缀。
+// 扫描统计后缀、按后缀筛选和按后缀整理都使用这个函数，保证结果一致
+func fileExtension(name string) string {
+	base := filepath.Base(name)
+	if i := strings.IndexAny(base, "?#"); i > 0 {
+		// 只有 ? 或 # 紧跟在一个有效后缀之后时才当作网址后缀去掉，避免误伤 "C# 笔记.txt" 这样的文件名
+		if _, ext := splitExtension(base[:i]); ext != "" {
+			return ext
+		}
+	}
+	_, ext := splitExtension(base)
+	return ext
+}
+
+// 后缀是否合理：只含字母、数字和少数符号，长度有限，并且至少含一个字母
+// （纯数字的后缀只允许很短的，例如分卷压缩的 .001）
+func isPlausibleExtension(ext string) bool {
+	if len(ext) < 2 || len(ext)-1 > maxExtensionLength || ext[0] != '.' {
+		return false
+	}
+	hasLetter := false
+	for _, r := range ext[1:] {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r), r == '_', r == '+', r == '~':
+		default:
+			return false
+		}
+	}
+	return hasLetter || len(ext)-1 <= 3
+}
+
+// 规范化用户或配置文件给出的后缀列表：去掉空白和开头的 *，转为小写，补上开头的点，去掉重复。
+// 扫描结果中的后缀都是这种形式，手写的 "JPG"、"jpg"、"*.Jpg" 都会变成 ".jpg"
+func normalizeExtensions(exts []string) []string {
+	var normalized []string
+	seen := make(map[string]bool, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimLeft(strings.TrimSpace(ext), "*"))
+		if ext == "" || ext == "." {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if !seen[ext] {
+			seen[ext] = true
+			normalized = append(normalized, ext)
+		}
+	}
+	return normalized
+}
+
+// 解析逗号或空白分隔的后缀列表，例如命令行中的 "jpg, .PNG"
+func parseExtensionList(text string) []string {
+	return normalizeExtensions(strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ';' || unicode.IsSpace(r)
+	}))
+}
+
+// 因后缀不在所选列表中而跳过的文件超过检查的文件的这个百分比时，在总结中提示后缀可能设置错误
+const extensionMismatchHintPercent = 50
+
+// 总结中列出的未选择后缀的最大数量
+const extensionMismatchHintTop = 5
+
+// 跳过的文件太多，并且所选的后缀中有的没有匹配任何文件时生成提示，列出跳过最多的后缀和没有匹配的后缀，
+// 否则返回空字符串。只选择了文件夹中一部分类型的普通整理不提示
+func extensionMismatchHint(skipped, matched map[string]int, checked int, selected []string) string {
+	total := 0
+	for _, n := range skipped {
+		total += n
+	}
+	if checked == 0 || total*100 <= checked*extensionMismatchHintPercent {
+		return ""
+	}
+	var missing []string
+	for _, ext := range selected {
+		if matched[ext] == 0 {
+			missing = append(missing, ext)
+		}
+	}
+	if len(missing) == 0 {
+		return ""
+	}
+	exts := make([]string, 0, len(skipped))
+	for ext := range skipped {
+		exts = append(exts, ext)
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		if skipped[exts[i]] != skipped[exts[j]] {
+			return skipped[exts[i]] > skipped[exts[j]]
+		}
+		return exts[i] < exts[j]
+	})
+	if len(exts) > extensionMismatchHintTop {
+		exts = exts[:extensionMismatchHintTop]
+	}
+	found := make([]string, len(exts))
+	for i, ext := range exts {
+		label := ext
+		if label == "" {
+			label = "（无后缀）"
+		}
+		found[i] = fmt.Sprintf("%s（%d）", label, skipped[ext])
+	}
+	return fmt.Sprintf("警告: %d 个文件中有 %d 个因后缀不在所选列表中被跳过，请检查所选的后缀是否正确。\n"+
+		"  跳过最多的后缀: %s\n  没有匹配任何文件的所选后缀: %s",
+		checked, total, strings.Join(found, ", "), strings.Join(missing, ", "))
+}
+
+// 扫描结果中各后缀的文件数，按文件数从多到少排列，例如 ".jpg（1203）, .png（87）"
+func describeExtensionCounts(counts map[string]int) string {
+	exts := make([]string, 0, len(counts))
+	for ext := range counts {
+		exts = append(exts, ext)
+	}
+	sortByCount(exts, counts)
+	parts := make([]string, len(exts))
+	for i, ext := range exts {
+		parts[i] = fmt.Sprintf("%s（%d）", ext, counts[ext])
+	}
+	return strings.Join(parts, ", ")
+}
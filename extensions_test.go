@@ -0,0 +1,151 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 各个入口给出的后缀都规范化为小写、带点、不重复的形式
+func TestNormalizeExtensionEntryPoints(t *testing.T) {
+	want := []string{".jpg", ".png", ".tar.gz"}
+	tests := []struct {
+		name  string
+		parse func() []string
+	}{
+		{"列表", func() []string { return normalizeExtensions([]string{"JPG", " .Png ", "*.jpg", "", ".", "tar.gz"}) }},
+		{"命令行", func() []string { return parseExtensionList("jpg, .PNG;*.Jpg  tar.GZ,,") }},
+		{"预设", func() []string {
+			return Preset{FileExtensions: []string{"Jpg", "png", "TAR.GZ"}}.config("/x").FileExtensions
+		}},
+		{"配置方案", func() []string {
+			profiles, err := parseProfiles([]byte(`[{"name":"a","rules":{"file_extensions":["JPG","png",".jpg","*.tar.gz"]}}]`))
+			if err != nil || len(profiles) != 1 {
+				t.Fatalf("解析配置方案: %v, %v", profiles, err)
+			}
+			return profiles[0].Rules.FileExtensions
+		}},
+	}
+	for _, tt := range tests {
+		if got := tt.parse(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: %v, 期望 %v", tt.name, got, want)
+		}
+	}
+}
+
+// 只有跳过的文件过半并且有所选的后缀没有匹配任何文件时才提示
+func TestExtensionMismatchHint(t *testing.T) {
+	tests := []struct {
+		name     string
+		skipped  map[string]int
+		matched  map[string]int
+		selected []string
+		want     string // 为空表示不提示
+	}{
+		{"所选的后缀都没有匹配", map[string]int{".jpeg": 80, ".txt": 5}, nil, []string{".jpg"}, ".jpeg（80）"},
+		{"只选择了一部分类型", map[string]int{".txt": 80}, map[string]int{".jpg": 20}, []string{".jpg"}, ""},
+		{"部分所选的后缀没有匹配", map[string]int{".tif": 80}, map[string]int{".jpg": 20}, []string{".jpg", ".tiff"}, ".tiff"},
+		{"跳过的文件不多", map[string]int{".jpeg": 20}, nil, []string{".jpg"}, ""},
+	}
+	for _, tt := range tests {
+		got := extensionMismatchHint(tt.skipped, tt.matched, 100, tt.selected)
+		if (got == "") != (tt.want == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: 提示 = %q, 期望包含 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 多段后缀、网址后缀和不像后缀的结尾
+func TestFileExtension(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"photo.JPG", ".JPG"},
+		{"backup.tar.gz", ".tar.gz"},
+		{"Backup.TAR.BZ2", ".TAR.BZ2"},
+		{"logs.tar.xz", ".tar.xz"},
+		{"data.gz", ".gz"},
+		{".tar.gz", ".gz"},
+		{"dark-mode.user.js", ".user.js"},
+		{"app.js", ".js"},
+		{"photo.jpg?width=800", ".jpg"},
+		{"page.html#section-2", ".html"},
+		{"photo.jpg?v=1.5", ".jpg"},
+		{"C# 笔记.txt", ".txt"},
+		{"what?", ""},
+		{"file.2024-03-15", ""},
+		{"report.v2.2024", ""},
+		{"archive.001", ".001"},
+		{"notes.averyveryverylongsuffix", ""},
+		{"Photos Library.photoslibrary", ".photoslibrary"},
+		{"README", ""},
+		{"/downloads/a.b/c.PDF", ".PDF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fileExtension(tt.name); got != tt.want {
+				t.Fatalf("fileExtension(%q) = %q, 期望 %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// 扫描统计和整理使用相同的后缀：按扫描结果选择的后缀能整理到对应的文件夹
+func TestExtensionScanAndOrganize(t *testing.T) {
+	tests := []struct {
+		file       string
+		wantFolder string
+	}{
+		{"backup.tar.gz", ".tar.gz"},
+		{"photo.JPG?width=800", ".jpg"},
+		{"dark-mode.user.js", ".user.js"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			notifier := &eventNotifier{UINotifier: fo.ui, scanned: make(chan struct{})}
+			fo.ui = notifier
+			source := t.TempDir()
+			target := t.TempDir()
+			writeTestFile(t, filepath.Join(source, tt.file), "data")
+			writeTestFile(t, filepath.Join(source, "plain.gz"), "other")
+			fo.SourceDirs = []string{source}
+			fo.OrganizeRule = RuleByExtension
+
+			fo.scanFiles()
+			select {
+			case <-notifier.scanned:
+			case <-time.After(5 * time.Second):
+				t.Fatal("扫描没有结束")
+			}
+			if fo.scannedFileExtensions[tt.wantFolder] != 1 {
+				t.Fatalf("扫描统计 = %v, 期望有 %s", fo.scannedFileExtensions, tt.wantFolder)
+			}
+			config := Config{
+				SourceDir:      source,
+				SourceDirs:     []string{source},
+				TargetDir:      target,
+				FileExtensions: []string{tt.wantFolder},
+				OrganizeRule:   string(RuleByExtension),
+				ExtensionCase:  "lowercase",
+				ConflictPolicy: ConflictRename,
+				ExcludedFiles:  map[string]bool{},
+			}
+			if summary, err := fo.processFiles(config, fo.scannedFiles); err != nil || summary.Failed != 0 {
+				t.Fatalf("整理: %+v, %v", summary, err)
+			}
+			got := snapshotTree(t, target)
+			if len(got) != 1 {
+				t.Fatalf("目标文件夹 = %v, 期望只整理 %s", got, tt.file)
+			}
+			for rel := range got {
+				if filepath.Dir(rel) != tt.wantFolder {
+					t.Fatalf("整理到 %s, 期望在 %s 中", rel, tt.wantFolder)
+				}
+			}
+		})
+	}
+}
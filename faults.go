@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// 开发者模式：通过环境变量注入文件操作失败，用于在测试数据上检验撤销、重试和校验等恢复功能。
+// 格式为逗号分隔的 键=值，例如
+//
+//	FILE_ORGANIZER_FAULTS=rename-every=5,copy-fail-after=1048576,exdev=DCIM,stat-delay=200ms
+//
+// rename-every=N 每第N次重命名失败；copy-fail-after=K 复制写入K字节后失败（0表示第一次写入就失败）；
+// exdev=子串 路径包含该子串的重命名返回跨设备错误（退回到复制后删除）；stat-delay=时长 每次检查目标文件前等待
+const faultsEnvVar = "FILE_ORGANIZER_FAULTS"
+
+// faultInjector 注入的失败。为nil时所有文件操作直接调用系统
+type faultInjector struct {
+	renameEvery   int64
+	copyFailAfter int64 // 小于0时不注入复制失败
+	exdevPaths    []string
+	statDelay     time.Duration
+
+	renames atomic.Int64
+}
+
+// 从环境变量读取的失败设置和解析错误，启动时写入日志
+var faults, faultsErr = parseFaults(os.Getenv(faultsEnvVar))
+
+// 解析失败设置，空字符串表示不注入失败
+func parseFaults(spec string) (*faultInjector, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	f := &faultInjector{copyFailAfter: -1}
+	for _, part := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("无效的失败设置 %q，应为 键=值", part)
+		}
+		var err error
+		switch key {
+		case "rename-every":
+			f.renameEvery, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && f.renameEvery <= 0 {
+				err = fmt.Errorf("必须大于0")
+			}
+		case "copy-fail-after":
+			f.copyFailAfter, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && f.copyFailAfter < 0 {
+				err = fmt.Errorf("不能小于0")
+			}
+		case "exdev":
+			if value == "" {
+				err = fmt.Errorf("路径不能为空")
+			}
+			f.exdevPaths = append(f.exdevPaths, value)
+		case "stat-delay":
+			f.statDelay, err = time.ParseDuration(value)
+		default:
+			return nil, fmt.Errorf("未知的失败设置 %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("失败设置 %s 无效: %w", key, err)
+		}
+	}
+	return f, nil
+}
+
+// 启动时在日志中提醒注入了失败，界面和无界面整理都会记录
+func (fo *FileOrganizer) logFaults() {
+	if faultsErr != nil {
+		fo.log(fmt.Sprintf("警告: 环境变量 %s 无效，未注入失败: %v", faultsEnvVar, faultsErr))
+	} else if faults != nil {
+		fo.log(fmt.Sprintf("警告: 已通过环境变量 %s 注入文件操作失败（%s），请只在测试数据上整理", faultsEnvVar, faults))
+	}
+}
+
+// 描述注入的失败，用于日志
+func (f *faultInjector) String() string {
+	var parts []string
+	if f.renameEvery > 0 {
+		parts = append(parts, fmt.Sprintf("每第 %d 次重命名失败", f.renameEvery))
+	}
+	if f.copyFailAfter >= 0 {
+		parts = append(parts, fmt.Sprintf("复制 %d 字节后失败", f.copyFailAfter))
+	}
+	for _, path := range f.exdevPaths {
+		parts = append(parts, fmt.Sprintf("路径包含 %q 时按跨设备处理", path))
+	}
+	if f.statDelay > 0 {
+		parts = append(parts, fmt.Sprintf("检查目标文件前等待 %v", f.statDelay))
+	}
+	return strings.Join(parts, "，")
+}
+
+// 重命名文件，按设置注入失败
+func renameFile(oldPath, newPath string) error {
+	if faults != nil {
+		for _, path := range faults.exdevPaths {
+			if strings.Contains(oldPath, path) || strings.Contains(newPath, path) {
+				return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: syscall.EXDEV}
+			}
+		}
+		if faults.renameEvery > 0 && faults.renames.Add(1)%faults.renameEvery == 0 {
+			return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: fmt.Errorf("模拟的重命名失败")}
+		}
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+// 获取文件信息，按设置延迟
+func statFile(path string) (os.FileInfo, error) {
+	if faults != nil && faults.statDelay > 0 {
+		time.Sleep(faults.statDelay)
+	}
+	return os.Stat(path)
+}
+
+// 复制时使用的写入目标，按设置在写入一定字节后失败
+func faultyWriter(w io.Writer) io.Writer {
+	if faults == nil || faults.copyFailAfter < 0 {
+		return w
+	}
+	return &failingWriter{w: w, remaining: faults.copyFailAfter}
+}
+
+// failingWriter 写入remaining字节后返回错误
+type failingWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= fw.remaining {
+		n, err := fw.w.Write(p)
+		fw.remaining -= int64(n)
+		return n, err
+	}
+	n, err := fw.w.Write(p[:fw.remaining])
+	fw.remaining -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, fmt.Errorf("模拟的写入失败")
+}
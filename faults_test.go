@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// 解析失败设置，无效的设置返回错误
+func TestParseFaults(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantErr  bool
+		wantDesc string // 描述中应包含的文字，为空时不检查
+	}{
+		{"", false, ""},
+		{"rename-every=5", false, "每第 5 次重命名失败"},
+		{"copy-fail-after=0", false, "复制 0 字节后失败"},
+		{"copy-fail-after=1024,exdev=DCIM", false, `路径包含 "DCIM"`},
+		{"stat-delay=200ms", false, "200ms"},
+		{"rename-every=0", true, ""},
+		{"copy-fail-after=-1", true, ""},
+		{"exdev=", true, ""},
+		{"stat-delay=soon", true, ""},
+		{"unknown=1", true, ""},
+		{"rename-every", true, ""},
+	}
+	for _, tt := range tests {
+		f, err := parseFaults(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: 错误 = %v", tt.spec, err)
+			continue
+		}
+		if tt.wantDesc != "" && !strings.Contains(f.String(), tt.wantDesc) {
+			t.Errorf("%q: 描述 = %q, 应包含 %q", tt.spec, f.String(), tt.wantDesc)
+		}
+	}
+}
+
+// 复制在写入设定的字节数后失败，copy-fail-after=0 时第一次写入就失败
+func TestFaultyWriter(t *testing.T) {
+	tests := []struct {
+		spec      string
+		wantBytes string
+		wantErr   bool
+	}{
+		{"", "hello", false},
+		{"copy-fail-after=0", "", true},
+		{"copy-fail-after=3", "hel", true},
+		{"copy-fail-after=5", "hello", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			useFaults(t, tt.spec)
+			var buf bytes.Buffer
+			_, err := faultyWriter(&buf).Write([]byte("hello"))
+			if (err != nil) != tt.wantErr || buf.String() != tt.wantBytes {
+				t.Fatalf("写入 %q, 错误 %v; 期望 %q, 出错 %v", buf.String(), err, tt.wantBytes, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 重命名按设置每第N次失败，路径包含指定子串时返回跨设备错误
+func TestRenameFaults(t *testing.T) {
+	tests := []struct {
+		desc      string // 子测试的名称，也是临时文件夹的名称，不能包含 exdev 的子串
+		spec      string
+		name      string
+		wantFails []bool // 依次重命名的结果
+		wantEXDEV bool
+	}{
+		{"每第2次失败", "rename-every=2", "a.jpg", []bool{false, true, false, true}, false},
+		{"路径包含子串", "exdev=DCIM", "DCIM_a.jpg", []bool{true, true}, true},
+		{"路径不包含子串", "exdev=DCIM", "a.jpg", []bool{false, false}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			useFaults(t, tt.spec)
+			dir := t.TempDir()
+			path := writeTestFile(t, filepath.Join(dir, tt.name), "x")
+			for i, wantFail := range tt.wantFails {
+				next := filepath.Join(dir, tt.name+strings.Repeat("_", i+1))
+				err := renameFile(path, next)
+				if (err != nil) != wantFail {
+					t.Fatalf("第 %d 次重命名: %v", i+1, err)
+				}
+				if err != nil {
+					if errors.Is(err, syscall.EXDEV) != tt.wantEXDEV {
+						t.Fatalf("第 %d 次重命名的错误 = %v", i+1, err)
+					}
+					continue
+				}
+				path = next
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+//go:build darwin
+
+package main
+
+import (
+	"syscall"
+	"time"
+)
+
+// 读取文件的创建时间
+func fileBirthTime(path string) (time.Time, bool) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(stat.Birthtimespec.Sec, stat.Birthtimespec.Nsec), true
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+import "time"
+
+// 当前系统不支持读取文件的创建时间
+func fileBirthTime(path string) (time.Time, bool) {
+	return time.Time{}, false
+}
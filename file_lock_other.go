@@ -0,0 +1,15 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+import "os"
+
+// 当前系统不支持文件锁，总是成功，不检查是否已有程序在运行
+func tryLockFile(file *os.File) error {
+	return nil
+}
+
+// 释放 tryLockFile 加的锁
+func unlockFile(file *os.File) error {
+	return nil
+}
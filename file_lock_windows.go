@@ -0,0 +1,31 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows 的文件锁是强制的，锁住的范围其他进程不能读取。锁放在远超文件内容的位置，
+// 其他进程仍能读取锁文件中持有者的信息
+const fileLockOffsetHigh = 0x7fffffff
+
+// 对打开的文件加排他锁，不等待。进程退出（包括崩溃）时系统自动释放
+func tryLockFile(file *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: fileLockOffsetHigh}
+	err := windows.LockFileEx(windows.Handle(file.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errFileLocked
+	}
+	return err
+}
+
+// 释放 tryLockFile 加的锁
+func unlockFile(file *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: fileLockOffsetHigh}
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &overlapped)
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -22,12 +29,106 @@ import (
 
 // Config 配置结构体
 type Config struct {
-	SourceDir        string
-	TargetDir        string
-	FileExtensions   []string
-	FolderDateFormat string
-	OrganizeRule     string
-	ExtensionCase    string // "uppercase" 或 "lowercase"
+	SourceDir            string
+	TargetDir            string
+	FileExtensions       []string
+	FolderDateFormat     string
+	OrganizeRule         string
+	ExtensionCase        string                  // "uppercase" 或 "lowercase"
+	ExcludedFiles        map[string]bool         // 用户在扫描结果中排除的文件
+	MultiTagMode         string                  // "first" 或 "duplicate"
+	DateFolderMtime      bool                    // 按日期整理时将日期文件夹的修改时间设为对应日期
+	SourceDirs           []string                // 所有源文件夹，用于计算文件在源文件夹中的相对路径
+	FolderLayout         string                  // "flat"、"rule_first" 或 "source_first"
+	DedupTarget          bool                    // 目标中已有内容相同的文件时跳过移动
+	EventLabels          []EventLabel            // 按日期整理时追加到文件夹名称的事件标签
+	ParallelThreshold    int                     // 文件数少于该值时使用SmallSetWorkers个工作协程
+	SmallSetWorkers      int                     // 少量文件时的工作协程数
+	AgeBucketLabels      []string                // 按年龄整理时各分组的文件夹名称
+	SizeBucketsMB        []int                   // 按类别和大小整理时各大小分组的上限（MB），为空时使用默认的上限
+	DateSources          []DateSource            // 文件日期的来源顺序
+	EmptyFilePolicy      string                  // 空文件的处理方式: "organize"、"skip" 或 "quarantine"
+	ConflictPolicy       string                  // 目标中已有同名文件时: "rename" 加时间戳，"overwrite" 覆盖，"merge" 内容相同时跳过
+	BackupReplaced       bool                    // 覆盖前把已有文件移到目标的 _replaced 文件夹
+	ChecksumAlgorithm    string                  // 生成校验清单的算法: "none"、"md5"、"sha1" 或 "sha256"
+	SourceSnapshot       string                  // 整理前把源文件的路径和哈希追加到这个文件，为空时不记录
+	RemoveEmptiedDirs    bool                    // 删除整理后变空的源子文件夹
+	MoveEmptyDirs        bool                    // 将源文件夹第一层的空文件夹移到目标的「空文件夹」中
+	DedupEmptyFiles      bool                    // 目标去重时是否把空文件视为相同内容
+	CopyMerge            string                  // 内容相同的副本的处理方式: "off"、"quarantine" 或 "delete"
+	CopySuffixPatterns   []string                // 副本文件名规则
+	StatsEndpoint        string                  // 整理完成后POST统计JSON的地址，为空时不发送
+	StatsToken           string                  // 发送统计时使用的Bearer令牌
+	PostRunHook          string                  // 整理成功后运行的命令，为空时不运行
+	PerFileHook          string                  // 每个文件整理后运行的命令，为空时不运行
+	HookTimeoutSec       int                     // 命令的超时（秒）
+	ReadOnlySources      []string                // 只读的源文件夹，其中的文件只复制不移动
+	CatalogEnabled       bool                    // 在整理目录中记录每个文件的去向
+	Pins                 *pinList                // 固定的文件和文件夹，始终跳过
+	HashShardDepth       int                     // 按内容哈希整理时的分片层数
+	HashShardWidth       int                     // 按内容哈希整理时每层分片的字符数
+	HashRename           bool                    // 按内容哈希整理时把文件改名为哈希值
+	EmailSenderFolders   bool                    // 按日期整理邮件时先按发件人域名分文件夹，例如 example.com/2024-03
+	PackSmallFiles       bool                    // 按日期整理时小文件放入日期文件夹对应的压缩包
+	PackThresholdKB      int                     // 打包小文件的大小上限（KB）
+	FolderTemplate       string                  // 当前规则的文件夹名称模板，为空时使用规则原本的名称
+	Bursts               *burstIndex             // 识别出的连拍序列，为nil时不按连拍整理
+	RecordedDates        map[string]recordedDate // 目标在FAT/exFAT上时整理目录中记录的日期（最终路径 → 日期），重新归档时使用
+	NamePrefixes         map[string]string       // 文件名前缀模式下整理目录中记录的前缀（最终路径 → 前缀），重新归档时去掉
+	BurstMaxGap          int                     // 识别连拍时相邻照片最多相隔的秒数
+	BurstMaxFrames       int                     // 识别连拍时序列的最多张数
+	CompactExtensionsMin int                     // 按后缀整理时文件数少于该值的后缀合并到「其他」
+	RareExtensions       map[string]bool         // 按后缀整理时合并到「其他」文件夹的后缀（小写）
+	ExtensionRanks       map[string]int          // 按后缀整理时文件夹名称的序号前缀，为nil时不加序号
+	RuleSegments         []string                // 组合规则的各层，例如 ["category", "date"]，为空时使用日期/后缀
+	RuleSegmentSeparator string                  // 组合规则的分隔符，不为空时各层连接成一级文件夹，例如 "2024-01 - 图片"
+	ConvertImages        bool                    // 整理时将HEIC转换为JPEG，转换失败时按原样整理
+	KeepConverted        bool                    // 转换后把原始文件保留在目标的 _originals 文件夹中
+	Volumes              []TargetVolume          // 目标卷（目标文件夹和溢出目标），为空时只使用目标文件夹
+	VolumePlan           *volumePlan             // 各目标文件夹分配到的卷，为nil时都放在目标文件夹中
+	VolumeCapMB          int64                   // 按容量分卷时每卷的容量（MB），0表示不分卷
+	CapacityPlan         *capacityPlan           // 按容量分卷时每个文件放入的分卷，为nil时在整理开始时计算
+	LowPriority          bool                    // 整理期间降低进程的CPU和磁盘优先级
+	IncomingOnly         bool                    // 仅处理新增：跳过上次成功整理时已在源文件夹中的文件
+	IncomingBaseline     *incomingBaseline       // 仅处理新增的基准，为nil时在整理开始时建立并在成功后保存
+	ShortcutPolicy       string                  // 快捷方式的处理方式: "organize"、"skip" 或 "resolve"
+	OrganizeLibraries    bool                    // 整理照片、音乐等程序的图库（.photoslibrary 等），默认跳过
+	TextLanguageFolders  bool                    // 文本文件按识别出的语言在规则文件夹下再分一层
+	TextKeywordRules     []TextKeywordRule       // 文本文件按关键词在规则文件夹下再分一层，先于语言，第一条匹配的规则生效
+	TextKeywordCase      bool                    // 关键词区分大小写
+	TextPrefixKB         int                     // 按内容整理时读取文件开头的KB数
+	TakeoutMode          bool                    // Google 相册导出：JSON 元数据随照片移动，内容相同的「(n)」副本只保留一份
+	LogVerbosity         string                  // 日志详细程度: "quiet"、"normal" 或 "verbose"
+	Location             *time.Location          // 整理开始时确定的时区，日期文件夹、事件和年龄分组都按这个时区计算，为空时使用本地时区
+	PlanTime             time.Time               // 整理开始的时间，年龄分组的边界按这个时间计算，整理中途不再改变
+	Profile              string                  // 整理使用的配置方案，与同一方案的上一次整理比较用时
+	PreserveXattrs       bool                    // 跨磁盘移动或复制时同时复制扩展属性
+}
+
+// 日期计算使用的时区
+func (c Config) location() *time.Location {
+	if c.Location != nil {
+		return c.Location
+	}
+	return time.Local
+}
+
+// 年龄分组的参考时间，整理开始前（例如预览时）使用当前时间
+func (c Config) planNow() time.Time {
+	if c.PlanTime.IsZero() {
+		return time.Now().In(c.location())
+	}
+	return c.PlanTime.In(c.location())
+}
+
+// 整理开始时固定时区和年龄分组的参考时间，所有工作协程和批次使用同样的值，
+// 跨越夏令时切换或整理时间很长时文件夹也不会因处理的先后而不同
+func (c Config) frozen() Config {
+	c.Location = c.location()
+	if c.PlanTime.IsZero() {
+		c.PlanTime = time.Now()
+	}
+	return c
 }
 
 // OrganizeRule 组织规则类型
@@ -36,17 +137,153 @@ type OrganizeRule string
 const (
 	RuleByDate      OrganizeRule = "date"
 	RuleByExtension OrganizeRule = "extension"
+	RuleByTag       OrganizeRule = "tag"
+	RuleByAge       OrganizeRule = "age"
+	RuleByHash      OrganizeRule = "hash"      // 按内容哈希前缀分片，适合内容寻址的归档
+	RuleByCamera    OrganizeRule = "camera"    // 按照片EXIF中的相机型号
+	RuleByTypeSize  OrganizeRule = "type-size" // 类别在上、大小分组在下的两层文件夹，例如 图片/10MB-100MB
+	RuleComposite   OrganizeRule = "composite" // 按RuleSegments依次组合多层文件夹，例如 类别/日期
+)
+
+// 多标签文件的处理方式
+const (
+	MultiTagFirst     = "first"     // 按第一个标签整理
+	MultiTagDuplicate = "duplicate" // 每个标签文件夹各放一份
+)
+
+// 规则文件夹与源文件夹结构的组合方式
+const (
+	LayoutFlat        = "flat"         // 规则文件夹直接位于目标根目录
+	LayoutRuleFirst   = "rule_first"   // 目标/规则文件夹/源相对路径
+	LayoutSourceFirst = "source_first" // 目标/源相对路径/规则文件夹
+	LayoutNamePrefix  = "name_prefix"  // 不建文件夹，文件都在目标根目录，文件名加上规则前缀
+)
+
+// 空文件（0字节）的处理方式
+const (
+	EmptyFileOrganize   = "organize"   // 与其他文件一样整理
+	EmptyFileSkip       = "skip"       // 跳过并在日志中报告
+	EmptyFileQuarantine = "quarantine" // 移到目标根目录下的隔离文件夹等待检查
+)
+
+// 日志详细程度
+const (
+	LogQuiet   = "quiet"   // 精简：不逐个列出跳过的文件，只在总结中报告数量
+	LogNormal  = "normal"  // 正常
+	LogVerbose = "verbose" // 详细：整理的文件附带源路径、大小和日期来源
+)
+
+// 空文件的隔离文件夹
+const EmptyFilesFolderName = "_empty_files"
+
+// 默认的并行阈值和少量文件时的工作协程数
+const (
+	defaultParallelThreshold = 20
+	defaultSmallSetWorkers   = 2
 )
 
+// 默认在出现这么多扫描错误后中止扫描
+const defaultScanErrorLimit = 100
+
+// 没有标签的文件存放的文件夹
+const UntaggedFolderName = "未标记"
+
+// 按后缀整理时没有后缀的文件（只在流式整理时整理）存放的文件夹
+const NoExtensionFolderName = "无后缀"
+
 // FileOrganizer 结构体封装所有功能
 type FileOrganizer struct {
-	SourceDirs       []string
-	TargetDir        string
-	FileExtensions   []string
-	FolderDateFormat string
-	OrganizeRule     OrganizeRule
-	SizeRanges       []string
-	ExtensionCase    string // "uppercase" 或 "lowercase"
+	SourceDirs           []string
+	TargetDir            string
+	FileExtensions       []string
+	FolderDateFormat     string
+	OrganizeRule         OrganizeRule
+	SizeRanges           []string
+	ExtensionCase        string            // "uppercase" 或 "lowercase"
+	MultiTagMode         string            // "first" 或 "duplicate"
+	DateFolderMtime      bool              // 按日期整理时将日期文件夹的修改时间设为对应日期
+	EmailSenderFolders   bool              // 按日期整理邮件时先按发件人域名分文件夹
+	PackSmallFiles       bool              // 按日期整理时小文件放入日期文件夹对应的压缩包
+	PackThresholdKB      int               // 打包小文件的大小上限（KB）
+	FolderTemplates      map[string]string // 各规则的文件夹名称模板，键为规则
+	BurstDetection       bool              // 识别连拍照片，同一序列按第一张的日期放在一起
+	BurstMaxGap          int               // 连拍中相邻两张照片最多相隔的秒数
+	BurstMaxFrames       int               // 超过该张数的序列不当作连拍
+	CompactExtensionsMin int               // 按后缀整理时文件数少于该值的后缀合并到「其他」，0表示不合并
+	ExtensionRankPrefix  bool              // 按后缀整理时按文件数为文件夹加序号前缀，例如 01_jpg
+	RuleSegments         []string          // 组合规则的各层，按顺序生成文件夹
+	RuleSegmentSeparator string            // 组合规则的分隔符，为空时每层一级文件夹
+	ExtensionRanks       map[string]int    // 已分配的后缀序号，键为小写后缀，只在重新编号时改变
+	FolderLayout         string            // "flat"、"rule_first" 或 "source_first"
+	DedupTarget          bool              // 目标去重：跳过目标中已有相同内容的文件
+	EventLabels          []EventLabel
+	LogCLICommand        bool // 每次整理开始时记录等效的命令行
+	ForceFullScan        bool // 不使用扫描缓存，每次完全扫描
+	ValidateExtensions   bool // 整理前检查所选后缀是否出现在扫描结果中
+	CheckDangerousTarget bool // 目标是根目录、主目录或系统文件夹时要求确认
+	ParallelThreshold    int  // 文件数少于该值时使用SmallSetWorkers个工作协程
+	SmallSetWorkers      int  // 少量文件时的工作协程数
+	ScanConcurrency      int  // 同时扫描的源文件夹数量
+	ScanErrorLimit       int  // 扫描错误达到该数量时中止扫描，0表示不限制
+	PlanStaleMinutes     int  // 扫描结果生成超过该分钟数后，执行前先检查文件变化，0表示不检查
+	StreamingThreshold   int  // 扫描到的文件超过该数量时改用流式整理，不保留文件列表，0表示不使用
+	TargetIndexRateMB    int  // 后台建立目标文件索引时的读取速度上限（MB/秒），0表示不限制
+	AgeBucketLabels      []string
+	SizeBucketsMB        []int             // 按类别和大小整理时各大小分组的上限（MB）
+	DateSources          []DateSource      // 文件日期的来源顺序，例如 EXIF → 文件名 → 修改时间
+	DateTimeZone         string            // 划分日期使用的时区（IANA名称），为空时使用本机时区
+	EmptyFilePolicy      string            // 空文件的处理方式
+	ShortcutPolicy       string            // 快捷方式按自身整理、跳过，还是整理其指向的文件
+	OrganizeLibraries    bool              // 整理照片、音乐等程序的图库，默认跳过
+	TextLanguageFolders  bool              // 文本文件（.txt/.md/.pdf/.docx）按语言再分一层
+	TextKeywordRules     []TextKeywordRule // 文本文件按关键词再分一层，先于语言
+	TextKeywordCase      bool              // 关键词区分大小写
+	TextPrefixKB         int               // 按内容整理时读取文件开头的KB数
+	TakeoutMode          bool              // 整理 Google 相册导出（Takeout）的照片
+	LogVerbosity         string            // 日志详细程度：精简时不逐个列出跳过的文件
+	ConflictPolicy       string            // 目标中已有同名文件时加时间戳、覆盖还是合并
+	BackupReplaced       bool              // 覆盖前备份已有文件，撤销时可以恢复
+	ReplacedKeepDays     int               // 清理覆盖备份时保留的天数，0不限
+	ReplacedMaxMB        int64             // 清理覆盖备份时的总大小上限，0不限
+	DedupEmptyFiles      bool              // 目标去重时是否把空文件视为相同内容
+	ChecksumAlgorithm    string            // 整理后导出校验清单使用的算法，"none" 表示不导出
+	SourceSnapshot       string            // 整理前记录源快照的文件，为空时不记录
+	RemoveEmptiedDirs    bool              // 删除整理后变空的源子文件夹
+	MoveEmptyDirs        bool              // 将源文件夹第一层的空文件夹移到目标的「空文件夹」中
+	CopyMerge            string            // 整理前如何处理内容相同的副本
+	CopySuffixPatterns   []string          // 副本文件名规则（正则表达式，第一个捕获组为原文件名）
+	StatsEndpoint        string            // 整理完成后POST统计JSON的地址，为空时不发送
+	StatsToken           string            // 发送统计时使用的Bearer令牌
+	StatusAddr           string            // 状态页面的监听地址，例如 :8383，为空时不开启
+	StatusToken          string            // 状态页面的令牌（HTTP基本认证的密码），为空时不需要认证
+	HooksEnabled         bool              // 整理后运行命令，默认关闭
+	PostRunHook          string            // 整理成功后运行的命令模板
+	PerFileHook          string            // 每个文件整理后运行的命令模板
+	HookTimeoutSec       int               // 命令的超时（秒）
+	CardDetection        bool              // 检测新插入的相机存储卡
+	ScheduleInterval     int               // 定时整理的间隔（分钟），0表示关闭
+	ScheduleStartHour    int               // 定时整理允许的时段，开始时等于结束时表示全天
+	ScheduleEndHour      int
+	DigestHour           int            // 每天在这个整点显示今日摘要，-1表示不显示
+	CardImportTarget     string         // 存储卡导入的目标文件夹
+	CardCleanup          bool           // 导入并校验后删除存储卡上的文件
+	UnicodeNormalization string         // 比较文件名时使用的Unicode规范化形式: "none"、"NFC" 或 "NFD"
+	CollisionSeparator   string         // 重名时时间戳与文件名之间的分隔方式
+	CollisionPosition    string         // 重名时时间戳放在文件名之后还是之前
+	WindowResizable      bool           // 允许调整窗口大小，关闭时窗口固定为默认大小
+	ReadOnlyMode         bool           // 只读模式：只扫描、预览和报告，拒绝移动、复制或删除文件
+	LowPriority          bool           // 后台低优先级：整理期间降低进程的CPU和磁盘优先级
+	IncomingOnly         bool           // 仅处理新增：只整理上次成功整理后放入源文件夹的文件
+	HashShardDepth       int            // 按内容哈希整理时的分片层数
+	HashShardWidth       int            // 按内容哈希整理时每层分片的字符数
+	HashRename           bool           // 按内容哈希整理时把文件改名为哈希值
+	CatalogEnabled       bool           // 在整理目录中记录每个文件的去向，可以搜索
+	PreserveXattrs       bool           // 跨磁盘移动时同时复制扩展属性（Windows上为备用数据流）
+	ConvertImages        bool           // 整理时将HEIC转换为JPEG
+	KeepConverted        bool           // 转换后保留原始文件
+	SpillTargets         []TargetVolume // 目标文件夹空间不足时依次使用的溢出目标
+	TargetMinFreeMB      int64          // 目标文件夹所在磁盘至少保留的空间（MB）
+	VolumeCapMB          int64          // 按容量分卷时每卷的容量（MB），例如刻录光盘前分批暂存，0表示不分卷
 
 	// GUI组件
 	SourceDirEntry      *widget.Label
@@ -56,54 +293,337 @@ type FileOrganizer struct {
 	RuleSelect          *widget.Select
 	ExtensionCaseSelect *widget.Select
 	LogTextLabel        *widget.Label
-	SourceDirsList      *widget.List
+	SourceDirsList      *keyboardList
 	Window              fyne.Window
 	// 存储选中的源文件夹索引（支持多选）
 	selectedSourceDirs map[int]bool
+	// 标记为只读的源文件夹，其中的文件只复制不移动
+	readOnlySources map[string]bool
 
 	// 额外的UI组件
 	selectExtensionsBtn    *widget.Button
 	selectDateFormatBtn    *widget.Button
 	selectExtensionCaseBtn *widget.Button
 	processBtn             *widget.Button
+	browseFilesBtn         *widget.Button
+	rescanBtn              *widget.Button
+	settingsBtn            *widget.Button
+	presetsBtn             *widget.Button
+	watchBtn               *widget.Button
+	welcomePanel           fyne.CanvasObject // 没有源文件夹时显示的入门面板
+
+	// 扫描完成后执行一次的操作，例如入门预设在扫描后选择后缀并打开预览
+	afterScan func()
+
+	// 扫描结果表格（对话框打开时有效）
+	fileTable         *widget.Table
+	fileTableFiltered []string
+	fileTableFilter   string
+	fileTableStatus   *widget.Label
 
 	// 日志相关
 	logChan          chan string
 	logProcessorDone chan struct{}
+	logMu            sync.RWMutex // 保护日志通道的关闭，退出后仍在运行的工作协程的日志直接丢弃
+	logClosed        bool
+	logFile          *os.File // 完整日志文件，界面上合并的日志在这里保留每一条
+	logFilePath      string
 
 	// 配置相关
 	lastConfigPath string
+	lastCLICommand string // 最近一次整理的等效命令行
+	statsMu        sync.Mutex
+	lastStatsJSON  string         // 最近一次整理的统计JSON，整理协程写入，界面读取，用 statsMu 保护
+	statsPosts     sync.WaitGroup // 正在后台发送的统计，无界面运行时退出前等待发送完成
+	presets        []Preset
+	profiles       []Profile         // 完整的配置方案
+	activeProfile  string            // 最近切换或保存的配置方案
+	profileSelect  *widget.Select    // 主界面的配置方案下拉框
+	pins           *pinList          // 固定的文件和文件夹
+	queueJobs      []QueueJob        // 任务队列
+	watchBindings  map[string]string // 监视文件夹 -> 预设名称
+
+	// 监视模式
+	watchMu    sync.Mutex
+	watchRoots []*watchedRoot
+
+	// 规则测试窗口，没有打开时为nil
+	ruleTestWindow fyne.Window
+
+	// 目标文件夹中已有文件名的规范化索引
+	nameIndex *normalizedNameIndex
+	// 文件日期解析器，缓存每个文件解析出的日期
+	dates *DateResolver
+	// 按内容哈希整理时缓存每个文件的哈希值
+	contentHashes *contentHashCache
+	// 按发件人域名整理邮件时缓存每封邮件的发件人
+	emailSenders *emailSenderCache
+	cameraModels *cameraModelCache
+	// 源文件夹和目标文件夹是否位于FAT/exFAT
+	coarseRoots *coarseRootCache
+	// 后台建立目标文件索引的任务
+	indexBuildMu sync.Mutex
+	indexBuilder *targetIndexBuilder
+	// 按内容整理文本文件时缓存每个文件的文件夹
+	textFolders *textFolderCache
+	// 启动时找到的HEIC转换程序，没有时为nil
+	imageConverter *imageConverter
+	// 扫描结果中的连拍序列，以及浏览扫描结果时展开的序列
+	bursts         *burstIndex
+	expandedBursts map[string]bool
+	scanGeneration int // 每次开始扫描时加一，后台识别连拍期间开始了新的扫描时丢弃识别的结果
+	// 预览扫描结果时计算的目标卷分配和容量分卷
+	volumePlan   *volumePlan
+	capacityPlan *capacityPlan
+
+	// 相机存储卡检测
+	cardDetectStop chan struct{}
+	// 定时整理
+	scheduleStop chan struct{}
+	// 今日摘要：当天的整理活动和定时显示
+	digestMu      sync.Mutex
+	digest        *dailyDigest
+	digestStop    chan struct{}
+	cardImporting atomic.Bool
+
+	// 任务队列
+	queueRunning     atomic.Bool
+	cancelProcessing atomic.Bool // 取消当前的整理，未处理的文件计为已中止
+	cancelQueue      atomic.Bool // 当前任务结束后不再执行队列中剩余的任务
+	queueProgress    *queueProgressView
+
+	// 正在进行的整理，关闭窗口时等待或取消
+	activeRuns  atomic.Int32
+	priority    processPriority             // 后台低优先级整理的状态
+	runCatalog  atomic.Pointer[fileCatalog] // 正在整理时写入的整理目录，强制退出前写入已记录的文件
+	targetLocks sync.Map                    // 正在整理时持有的目标文件夹锁，锁文件路径 → *targetLockHandle，强制退出前释放
+	runJournals sync.Map                    // 正在整理时的整理记录，运行ID → *runJournal，强制退出前写入未处理的文件
+	quitting    atomic.Bool
+
+	instanceLock *instanceLockHandle // 单实例锁，再次启动程序时通过它切换到本窗口
+
+	coarseMtimeWarned sync.Map // 已经提示过修改时间精度低的目标文件夹
 
 	// 存储扫描到的文件信息
 	scannedFiles          []string
-	scannedFileExtensions map[string]bool
+	scannedFileExtensions map[string]int // 扫描到的后缀（小写）及其文件数
+	scannedFileInfos      map[string]os.FileInfo
+	scannedAt             time.Time       // 扫描完成的时间，用于判断扫描结果是否过时
+	streaming             bool            // 扫描到的文件超过流式整理阈值，文件列表没有保留
+	streamedCount         int             // 流式整理时扫描到的文件数
+	excludedFiles         map[string]bool // 从本次整理中排除的文件
+	isScanning            atomic.Bool
+
+	// 界面通知，无界面运行时使用控制台实现。status 包装在外层，记录进度供状态页面读取
+	ui     UINotifier
+	status *statusNotifier
 }
 
-// NewFileOrganizer 创建新的文件组织器实例
-func NewFileOrganizer() *FileOrganizer {
+// NewFileOrganizer 创建新的文件组织器实例，headless为true时不依赖图形界面
+func NewFileOrganizer(headless bool) *FileOrganizer {
 	fo := &FileOrganizer{
 		logChan:               make(chan string, 1000), // 增大通道缓冲区
 		logProcessorDone:      make(chan struct{}),
 		lastConfigPath:        filepath.Join(os.TempDir(), "file_organizer_last_config.yaml"),
-		scannedFileExtensions: make(map[string]bool),
+		scannedFileExtensions: make(map[string]int),
+		scannedFileInfos:      make(map[string]os.FileInfo),
+		excludedFiles:         make(map[string]bool),
 		FolderDateFormat:      "YYYY-MM-DD", // 默认文件夹命名规则
 		ExtensionCase:         "lowercase",  // 默认扩展名大小写
+		MultiTagMode:          MultiTagFirst,
+		FolderLayout:          LayoutFlat,
+		EmptyFilePolicy:       EmptyFileOrganize,
+		LogVerbosity:          LogNormal,
+		HookTimeoutSec:        defaultHookTimeoutSec,
+		ShortcutPolicy:        ShortcutOrganize,
+		ConflictPolicy:        ConflictRename,
+		BackupReplaced:        true,
+		ReplacedKeepDays:      defaultReplacedKeepDays,
+		ReplacedMaxMB:         defaultReplacedMaxMB,
+		ChecksumAlgorithm:     ChecksumNone,
+		CopyMerge:             CopyMergeOff,
+		CopySuffixPatterns:    append([]string(nil), defaultCopySuffixPatterns...),
+		ParallelThreshold:     defaultParallelThreshold,
+		SmallSetWorkers:       defaultSmallSetWorkers,
+		ScanConcurrency:       runtime.NumCPU(),
+		ScanErrorLimit:        defaultScanErrorLimit,
+		PlanStaleMinutes:      defaultPlanStaleMinutes,
+		StreamingThreshold:    defaultStreamingThreshold,
+		TextPrefixKB:          defaultTextPrefixKB,
+		TargetIndexRateMB:     defaultTargetIndexRateMB,
+		BurstMaxGap:           defaultBurstMaxGap,
+		BurstMaxFrames:        defaultBurstMaxFrames,
+		PackThresholdKB:       defaultPackThresholdKB,
+		expandedBursts:        make(map[string]bool),
+		AgeBucketLabels:       append([]string(nil), defaultAgeBucketLabels...),
+		SizeBucketsMB:         append([]int(nil), defaultSizeBucketsMB...),
+		RuleSegments:          append([]string(nil), defaultRuleSegments...),
+		UnicodeNormalization:  NormalizationNone,
+		CollisionSeparator:    CollisionSeparatorUnderscore,
+		CollisionPosition:     CollisionSuffix,
+		nameIndex:             newNormalizedNameIndex(),
+		dates:                 newDateResolver(),
+		contentHashes:         newContentHashCache(),
+		emailSenders:          newEmailSenderCache(),
+		cameraModels:          newCameraModelCache(),
+		coarseRoots:           newCoarseRootCache(),
+		textFolders:           newTextFolderCache(),
+		imageConverter:        detectImageConverter(),
+		HashShardDepth:        defaultHashShardDepth,
+		HashShardWidth:        defaultHashShardWidth,
+		DateSources:           append([]DateSource(nil), defaultDateSources...),
 		SourceDirs:            []string{},
 		selectedSourceDirs:    make(map[int]bool), // 初始化多选map
+		readOnlySources:       make(map[string]bool),
+	}
+
+	// 根据运行方式选择界面通知的实现
+	if headless {
+		fo.ui = consoleNotifier{}
+	} else {
+		fo.ui = &fyneNotifier{fo: fo}
 	}
+	fo.status = newStatusNotifier(fo.ui)
+	fo.ui = fo.status
 
-	// 启动日志处理器
+	// 打开完整日志文件并启动日志处理器
+	fo.openLogFile()
 	fo.startLogProcessor()
 
 	return fo
 }
 
+// 获取应用数据目录，无法获取用户配置目录时使用临时目录
+func appDataDir() string {
+	baseDir, err := os.UserConfigDir()
+	if err != nil {
+		baseDir = os.TempDir()
+	}
+	dir := filepath.Join(baseDir, "FileOrganizer")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return os.TempDir()
+	}
+	return dir
+}
+
+// 打开完整日志文件，超过大小上限时先将旧日志改名保留
+func (fo *FileOrganizer) openLogFile() {
+	const maxLogFileSize = 10 * 1024 * 1024
+	fo.logFilePath = filepath.Join(appDataDir(), "file_organizer.log")
+	if info, err := os.Stat(fo.logFilePath); err == nil && info.Size() > maxLogFileSize {
+		os.Rename(fo.logFilePath, fo.logFilePath+".old")
+	}
+
+	file, err := os.OpenFile(fo.logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "警告: 无法打开日志文件 %s: %v\n", fo.logFilePath, err)
+		return
+	}
+	fo.logFile = file
+}
+
+// 将日志原样写入日志文件（每行带时间戳）
+func (fo *FileOrganizer) writeLogFile(msg string) {
+	if fo.logFile == nil {
+		return
+	}
+	timestamp := time.Now().Format("2006-01-02 15:04:05 ")
+	var buf strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(msg, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		buf.WriteString(timestamp)
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	fo.logFile.WriteString(buf.String())
+}
+
 // 保存用户配置
 func (fo *FileOrganizer) saveUserConfig() {
 	// 使用fyne的Preferences API保存配置
 	prefs := fyne.CurrentApp().Preferences()
 	prefs.SetString("folder_date_format", fo.FolderDateFormat)
 	prefs.SetString("extension_case", fo.ExtensionCase)
+	prefs.SetString("multi_tag_mode", fo.MultiTagMode)
+	prefs.SetBool("date_folder_mtime", fo.DateFolderMtime)
+	prefs.SetBool("email_sender_folders", fo.EmailSenderFolders)
+	prefs.SetBool("pack_small_files", fo.PackSmallFiles)
+	prefs.SetInt("pack_threshold_kb", fo.PackThresholdKB)
+	prefs.SetBool("burst_detection", fo.BurstDetection)
+	prefs.SetInt("burst_max_gap", fo.BurstMaxGap)
+	prefs.SetInt("burst_max_frames", fo.BurstMaxFrames)
+	prefs.SetString("folder_layout", fo.FolderLayout)
+	prefs.SetBool("target_dedup", fo.DedupTarget)
+	prefs.SetBool("log_cli_command", fo.LogCLICommand)
+	prefs.SetString("log_verbosity", fo.LogVerbosity)
+	prefs.SetBool("force_full_scan", fo.ForceFullScan)
+	prefs.SetBool("validate_extensions", fo.ValidateExtensions)
+	prefs.SetBool("check_dangerous_target", fo.CheckDangerousTarget)
+	prefs.SetInt("parallel_threshold", fo.ParallelThreshold)
+	prefs.SetInt("small_set_workers", fo.SmallSetWorkers)
+	prefs.SetInt("scan_concurrency", fo.ScanConcurrency)
+	prefs.SetInt("scan_error_limit", fo.ScanErrorLimit)
+	prefs.SetInt("plan_stale_minutes", fo.PlanStaleMinutes)
+	prefs.SetInt("streaming_threshold", fo.StreamingThreshold)
+	prefs.SetInt("target_index_rate_mb", fo.TargetIndexRateMB)
+	prefs.SetInt("compact_extensions_min", fo.CompactExtensionsMin)
+	prefs.SetBool("extension_rank_prefix", fo.ExtensionRankPrefix)
+	prefs.SetString("rule_segments", formatRuleSegments(fo.RuleSegments))
+	prefs.SetString("rule_segment_separator", fo.RuleSegmentSeparator)
+	prefs.SetInt("volume_cap_mb", int(fo.VolumeCapMB))
+	prefs.SetStringList("age_bucket_labels", fo.AgeBucketLabels)
+	prefs.SetString("size_buckets_mb", formatSizeBuckets(fo.SizeBucketsMB))
+	prefs.SetString("date_sources", formatDateSources(fo.DateSources))
+	prefs.SetString("date_time_zone", fo.DateTimeZone)
+	prefs.SetString("empty_file_policy", fo.EmptyFilePolicy)
+	prefs.SetString("shortcut_policy", fo.ShortcutPolicy)
+	prefs.SetBool("organize_libraries", fo.OrganizeLibraries)
+	prefs.SetBool("text_language_folders", fo.TextLanguageFolders)
+	prefs.SetString("text_keyword_rules", formatTextKeywordRules(fo.TextKeywordRules, "\n"))
+	prefs.SetBool("text_keyword_case", fo.TextKeywordCase)
+	prefs.SetInt("text_prefix_kb", fo.TextPrefixKB)
+	prefs.SetBool("takeout_mode", fo.TakeoutMode)
+	prefs.SetString("conflict_policy", fo.ConflictPolicy)
+	prefs.SetBool("backup_replaced", fo.BackupReplaced)
+	prefs.SetInt("replaced_keep_days", fo.ReplacedKeepDays)
+	prefs.SetInt("replaced_max_mb", int(fo.ReplacedMaxMB))
+	prefs.SetBool("dedup_empty_files", fo.DedupEmptyFiles)
+	prefs.SetString("checksum_algorithm", fo.ChecksumAlgorithm)
+	prefs.SetString("source_snapshot", fo.SourceSnapshot)
+	prefs.SetBool("remove_emptied_dirs", fo.RemoveEmptiedDirs)
+	prefs.SetBool("move_empty_dirs", fo.MoveEmptyDirs)
+	prefs.SetString("copy_merge", fo.CopyMerge)
+	prefs.SetStringList("copy_suffix_patterns", fo.CopySuffixPatterns)
+	prefs.SetString("stats_endpoint", fo.StatsEndpoint)
+	prefs.SetString("status_addr", fo.StatusAddr)
+	prefs.SetString("status_token", fo.StatusToken)
+	prefs.SetBool("hooks_enabled", fo.HooksEnabled)
+	prefs.SetString("post_run_hook", fo.PostRunHook)
+	prefs.SetString("per_file_hook", fo.PerFileHook)
+	prefs.SetInt("hook_timeout_sec", fo.HookTimeoutSec)
+	prefs.SetBool("window_resizable", fo.WindowResizable)
+	prefs.SetBool("read_only_mode", fo.ReadOnlyMode)
+	prefs.SetBool("catalog_enabled", fo.CatalogEnabled)
+	prefs.SetInt("hash_shard_depth", fo.HashShardDepth)
+	prefs.SetInt("hash_shard_width", fo.HashShardWidth)
+	prefs.SetBool("hash_rename", fo.HashRename)
+	prefs.SetBool("preserve_xattrs", fo.PreserveXattrs)
+	prefs.SetBool("low_priority", fo.LowPriority)
+	prefs.SetBool("incoming_only", fo.IncomingOnly)
+	prefs.SetBool("convert_images", fo.ConvertImages)
+	prefs.SetBool("keep_converted_originals", fo.KeepConverted)
+	prefs.SetBool("card_detection", fo.CardDetection)
+	prefs.SetInt("schedule_interval", fo.ScheduleInterval)
+	prefs.SetInt("schedule_start_hour", fo.ScheduleStartHour)
+	prefs.SetInt("schedule_end_hour", fo.ScheduleEndHour)
+	prefs.SetInt("digest_hour", fo.DigestHour)
+	prefs.SetString("card_import_target", fo.CardImportTarget)
+	prefs.SetBool("card_cleanup", fo.CardCleanup)
+	prefs.SetString("unicode_normalization", fo.UnicodeNormalization)
+	prefs.SetString("collision_separator", fo.CollisionSeparator)
+	prefs.SetString("collision_position", fo.CollisionPosition)
 }
 
 // 加载用户配置
@@ -117,15 +637,201 @@ func (fo *FileOrganizer) loadUserConfig() {
 	if extCase := prefs.StringWithFallback("extension_case", ""); extCase != "" {
 		fo.ExtensionCase = extCase
 	}
+	if tagMode := prefs.StringWithFallback("multi_tag_mode", ""); tagMode != "" {
+		fo.MultiTagMode = tagMode
+	}
+	fo.DateFolderMtime = prefs.BoolWithFallback("date_folder_mtime", false)
+	fo.EmailSenderFolders = prefs.BoolWithFallback("email_sender_folders", false)
+	fo.PackSmallFiles = prefs.BoolWithFallback("pack_small_files", false)
+	if threshold := prefs.IntWithFallback("pack_threshold_kb", 0); threshold > 0 {
+		fo.PackThresholdKB = threshold
+	}
+	fo.BurstDetection = prefs.BoolWithFallback("burst_detection", false)
+	if gap := prefs.IntWithFallback("burst_max_gap", 0); gap > 0 {
+		fo.BurstMaxGap = gap
+	}
+	if frames := prefs.IntWithFallback("burst_max_frames", 0); frames > 1 {
+		fo.BurstMaxFrames = frames
+	}
+	if layout := prefs.StringWithFallback("folder_layout", ""); layout != "" {
+		fo.FolderLayout = layout
+	}
+	fo.DedupTarget = prefs.BoolWithFallback("target_dedup", false)
+	fo.LogCLICommand = prefs.BoolWithFallback("log_cli_command", true)
+	if verbosity := prefs.StringWithFallback("log_verbosity", ""); verbosity != "" {
+		fo.LogVerbosity = verbosity
+	}
+	fo.ForceFullScan = prefs.BoolWithFallback("force_full_scan", false)
+	fo.ValidateExtensions = prefs.BoolWithFallback("validate_extensions", true)
+	fo.CheckDangerousTarget = prefs.BoolWithFallback("check_dangerous_target", true)
+	if threshold := prefs.IntWithFallback("parallel_threshold", 0); threshold > 0 {
+		fo.ParallelThreshold = threshold
+	}
+	if workers := prefs.IntWithFallback("small_set_workers", 0); workers > 0 {
+		fo.SmallSetWorkers = workers
+	}
+	if concurrency := prefs.IntWithFallback("scan_concurrency", 0); concurrency > 0 {
+		fo.ScanConcurrency = concurrency
+	}
+	if limit := prefs.IntWithFallback("scan_error_limit", -1); limit >= 0 {
+		fo.ScanErrorLimit = limit
+	}
+	if threshold := prefs.IntWithFallback("streaming_threshold", -1); threshold >= 0 {
+		fo.StreamingThreshold = threshold
+	}
+	if rate := prefs.IntWithFallback("target_index_rate_mb", -1); rate >= 0 {
+		fo.TargetIndexRateMB = rate
+	}
+	if minutes := prefs.IntWithFallback("plan_stale_minutes", -1); minutes >= 0 {
+		fo.PlanStaleMinutes = minutes
+	}
+	if n := prefs.IntWithFallback("compact_extensions_min", 0); n >= 0 {
+		fo.CompactExtensionsMin = n
+	}
+	fo.ExtensionRankPrefix = prefs.BoolWithFallback("extension_rank_prefix", false)
+	if segments, err := parseRuleSegments(prefs.StringWithFallback("rule_segments", "")); err == nil {
+		fo.RuleSegments = segments
+	}
+	if separator := prefs.StringWithFallback("rule_segment_separator", ""); validateRuleSegmentSeparator(separator) == nil {
+		fo.RuleSegmentSeparator = separator
+	}
+	if mb := prefs.IntWithFallback("volume_cap_mb", 0); mb >= 0 {
+		fo.VolumeCapMB = int64(mb)
+	}
+	if limits, err := parseSizeBuckets(prefs.String("size_buckets_mb")); err == nil {
+		fo.SizeBucketsMB = limits
+	}
+	if labels := prefs.StringList("age_bucket_labels"); len(labels) == ageBucketCount {
+		fo.AgeBucketLabels = labels
+	}
+	if zone := prefs.StringWithFallback("date_time_zone", ""); zone != "" {
+		if _, err := loadDateLocation(zone); err == nil {
+			fo.DateTimeZone = zone
+		}
+	}
+	if sources, err := parseDateSources(prefs.StringWithFallback("date_sources", "")); err == nil {
+		fo.DateSources = sources
+	}
+	if policy := prefs.StringWithFallback("empty_file_policy", ""); policy != "" {
+		fo.EmptyFilePolicy = policy
+	}
+	if policy := prefs.StringWithFallback("shortcut_policy", ""); policy != "" {
+		fo.ShortcutPolicy = policy
+	}
+	fo.OrganizeLibraries = prefs.BoolWithFallback("organize_libraries", false)
+	fo.TextLanguageFolders = prefs.BoolWithFallback("text_language_folders", false)
+	if rules, err := parseTextKeywordRules(prefs.StringWithFallback("text_keyword_rules", "")); err == nil {
+		fo.TextKeywordRules = rules
+	}
+	fo.TextKeywordCase = prefs.BoolWithFallback("text_keyword_case", false)
+	fo.TakeoutMode = prefs.BoolWithFallback("takeout_mode", false)
+	if kb := prefs.IntWithFallback("text_prefix_kb", defaultTextPrefixKB); kb > 0 && kb <= maxTextPrefixKB {
+		fo.TextPrefixKB = kb
+	}
+	switch policy := prefs.StringWithFallback("conflict_policy", ConflictRename); policy {
+	case ConflictOverwrite, ConflictMerge:
+		fo.ConflictPolicy = policy
+	default:
+		fo.ConflictPolicy = ConflictRename
+	}
+	fo.BackupReplaced = prefs.BoolWithFallback("backup_replaced", true)
+	if days := prefs.IntWithFallback("replaced_keep_days", defaultReplacedKeepDays); days >= 0 {
+		fo.ReplacedKeepDays = days
+	}
+	if mb := prefs.IntWithFallback("replaced_max_mb", defaultReplacedMaxMB); mb >= 0 {
+		fo.ReplacedMaxMB = int64(mb)
+	}
+	fo.DedupEmptyFiles = prefs.BoolWithFallback("dedup_empty_files", false)
+	fo.SourceSnapshot = prefs.StringWithFallback("source_snapshot", "")
+	if algorithm := prefs.StringWithFallback("checksum_algorithm", ""); algorithm != "" {
+		fo.ChecksumAlgorithm = algorithm
+	}
+	fo.RemoveEmptiedDirs = prefs.BoolWithFallback("remove_emptied_dirs", false)
+	fo.MoveEmptyDirs = prefs.BoolWithFallback("move_empty_dirs", false)
+	if mode := prefs.StringWithFallback("copy_merge", ""); mode != "" {
+		fo.CopyMerge = mode
+	}
+	if patterns := prefs.StringList("copy_suffix_patterns"); len(patterns) > 0 {
+		if _, err := compileCopyPatterns(patterns); err == nil {
+			fo.CopySuffixPatterns = patterns
+		}
+	}
+	fo.StatsEndpoint = prefs.StringWithFallback("stats_endpoint", "")
+	// 令牌不保存在设置中（设置是明文文件），每次启动从环境变量读取，或在设置中输入后只在本次运行中使用。
+	// 删除旧版本保存的令牌
+	fo.StatsToken = os.Getenv(statsTokenEnvVar)
+	prefs.RemoveValue("stats_token")
+	fo.StatusAddr = prefs.StringWithFallback("status_addr", "")
+	fo.StatusToken = prefs.StringWithFallback("status_token", "")
+	fo.HooksEnabled = prefs.BoolWithFallback("hooks_enabled", false)
+	fo.PostRunHook = prefs.StringWithFallback("post_run_hook", "")
+	fo.PerFileHook = prefs.StringWithFallback("per_file_hook", "")
+	if timeout := prefs.IntWithFallback("hook_timeout_sec", 0); timeout > 0 {
+		fo.HookTimeoutSec = timeout
+	}
+	fo.WindowResizable = prefs.BoolWithFallback("window_resizable", true)
+	fo.ReadOnlyMode = prefs.BoolWithFallback("read_only_mode", false)
+	fo.CatalogEnabled = prefs.BoolWithFallback("catalog_enabled", false)
+	depth := prefs.IntWithFallback("hash_shard_depth", defaultHashShardDepth)
+	width := prefs.IntWithFallback("hash_shard_width", defaultHashShardWidth)
+	if validateHashShards(depth, width) == nil {
+		fo.HashShardDepth, fo.HashShardWidth = depth, width
+	}
+	fo.HashRename = prefs.BoolWithFallback("hash_rename", false)
+	fo.PreserveXattrs = prefs.BoolWithFallback("preserve_xattrs", false)
+	fo.LowPriority = prefs.BoolWithFallback("low_priority", false)
+	fo.IncomingOnly = prefs.BoolWithFallback("incoming_only", false)
+	fo.ConvertImages = prefs.BoolWithFallback("convert_images", false)
+	fo.KeepConverted = prefs.BoolWithFallback("keep_converted_originals", true)
+	fo.CardDetection = prefs.BoolWithFallback("card_detection", false)
+	if minutes := prefs.IntWithFallback("schedule_interval", 0); minutes >= 0 {
+		fo.ScheduleInterval = minutes
+	}
+	if hour := prefs.IntWithFallback("schedule_start_hour", defaultScheduleStartHour); hour >= 0 && hour < 24 {
+		fo.ScheduleStartHour = hour
+	}
+	if hour := prefs.IntWithFallback("schedule_end_hour", defaultScheduleEndHour); hour >= 0 && hour < 24 {
+		fo.ScheduleEndHour = hour
+	}
+	if hour := prefs.IntWithFallback("digest_hour", digestHourOff); hour >= digestHourOff && hour < 24 {
+		fo.DigestHour = hour
+	}
+	fo.CardImportTarget = prefs.StringWithFallback("card_import_target", "")
+	fo.CardCleanup = prefs.BoolWithFallback("card_cleanup", false)
+	if form := prefs.StringWithFallback("unicode_normalization", ""); form != "" {
+		fo.UnicodeNormalization = form
+	}
+	if separator := prefs.StringWithFallback("collision_separator", CollisionSeparatorUnderscore); validCollisionSeparator(separator) {
+		fo.CollisionSeparator = separator
+	}
+	if prefs.StringWithFallback("collision_position", CollisionSuffix) == CollisionPrefix {
+		fo.CollisionPosition = CollisionPrefix
+	} else {
+		fo.CollisionPosition = CollisionSuffix
+	}
+	fo.loadPresets()
+	fo.loadProfiles()
+	fo.loadPins(prefs)
+	fo.loadFolderTemplates()
+	fo.loadSpillTargets()
+	fo.loadExtensionRanks()
+	fo.loadReadOnlySources()
+	fo.loadQueue()
+	fo.loadEventLabels()
+	fo.loadWatchBindings()
 }
 
-// 安全更新UI的函数 - 修复Fyne线程调用错误
+// 安全更新UI的函数 - 修复Fyne线程调用错误（只用于界面代码，引擎通过UINotifier更新界面）
 func (fo *FileOrganizer) safeUpdateUI(updateFunc func()) {
 	if updateFunc != nil {
 		// 在Fyne v2中，使用DoAndWait确保UI更新在主线程中执行
+		started := time.Now()
 		fyne.DoAndWait(func() {
 			updateFunc()
 		})
+		// 等待界面线程的时间计入性能报告
+		perfCounters.uiWait.Add(int64(time.Since(started)))
+		perfCounters.uiCalls.Add(1)
 	}
 }
 
@@ -138,68 +844,46 @@ func (fo *FileOrganizer) startLogProcessor() {
 		ticker := time.NewTicker(100 * time.Millisecond) // 100ms的刷新间隔，减少UI更新频率
 		defer ticker.Stop()
 		messageCount := 0
+		// 合并大量重复的错误日志，完整内容写入日志文件
+		coalescer := newLogCoalescer(logCoalesceWindow, logCoalesceMaxGroups)
 
 		for {
 			select {
 			case msg, ok := <-fo.logChan:
 				if !ok {
 					// 通道关闭，刷新剩余的日志
-					if buffer.Len() > 0 && fo.LogTextLabel != nil {
-						logContent := buffer.String()
-						fo.safeUpdateUI(func() {
-							if fo.LogTextLabel != nil {
-								currentText := fo.LogTextLabel.Text
-								fo.LogTextLabel.SetText(currentText + logContent)
-							}
-						})
+					buffer.WriteString(coalescer.Flush(time.Now(), true))
+					if buffer.Len() > 0 {
+						fo.ui.AppendLog(buffer.String())
+					}
+					if fo.logFile != nil {
+						fo.logFile.Close()
 					}
 					close(fo.logProcessorDone)
 					return
 				}
 
+				fo.writeLogFile(msg)
+				msg = coalescer.Add(msg, time.Now())
+				if msg == "" {
+					continue
+				}
+
 				// 增加消息计数
 				messageCount++
 				buffer.WriteString(msg)
 
-				// 如果消息数量达到阈值，立即刷新
-				if messageCount >= bulkUpdateThreshold {
-					logContent := buffer.String()
-					fo.safeUpdateUI(func() {
-						if fo.LogTextLabel != nil {
-							currentText := fo.LogTextLabel.Text
-							fo.LogTextLabel.SetText(currentText + logContent)
-						}
-					})
-					buffer.Reset()
-					messageCount = 0
-				} else if buffer.Len() > maxBufferSize {
-					// 缓冲区过大时也刷新
-					logContent := buffer.String()
-					fo.safeUpdateUI(func() {
-						if fo.LogTextLabel != nil {
-							currentText := fo.LogTextLabel.Text
-							fo.LogTextLabel.SetText(currentText + logContent)
-						}
-					})
+				// 如果消息数量达到阈值或缓冲区过大，立即刷新
+				if messageCount >= bulkUpdateThreshold || buffer.Len() > maxBufferSize {
+					fo.ui.AppendLog(buffer.String())
 					buffer.Reset()
 					messageCount = 0
 				}
 			case <-ticker.C:
-				if buffer.Len() > 0 && fo.LogTextLabel != nil {
-					logContent := buffer.String()
-					fo.safeUpdateUI(func() {
-						if fo.LogTextLabel != nil {
-							currentText := fo.LogTextLabel.Text
-							fo.LogTextLabel.SetText(currentText + logContent)
-							// 限制日志长度，避免内存占用过大
-							const maxLogLength = 1024 * 200 // 增大到200KB
-							if len(fo.LogTextLabel.Text) > maxLogLength {
-								// 保留最后一部分日志
-								fo.LogTextLabel.SetText("[日志过长，已截断前部分]\n" +
-									fo.LogTextLabel.Text[len(fo.LogTextLabel.Text)-maxLogLength/2:])
-							}
-						}
-					})
+				// 输出已过合并窗口的同类日志汇总
+				buffer.WriteString(coalescer.Flush(time.Now(), false))
+				if buffer.Len() > 0 {
+					fo.ui.AppendLog(buffer.String())
 					buffer.Reset()
 					messageCount = 0
 				}
@@ -210,7 +894,10 @@ func (fo *FileOrganizer) startLogProcessor() {
 
 // 停止日志处理器
 func (fo *FileOrganizer) stopLogProcessor() {
+	fo.logMu.Lock()
+	fo.logClosed = true
 	close(fo.logChan)
+	fo.logMu.Unlock()
 	<-fo.logProcessorDone
 }
 
@@ -220,13 +907,19 @@ func (fo *FileOrganizer) log(message string) {
 	// 对于普通日志，不添加时间戳，只添加时间戳到重要日志
 	logMsg := message + "\n"
 
+	fo.logMu.RLock()
+	defer fo.logMu.RUnlock()
+	if fo.logClosed {
+		return
+	}
+
 	// 使用非阻塞方式发送日志，避免阻塞主流程
 	select {
 	case fo.logChan <- logMsg:
 	default:
 		// 当通道满时，直接丢弃低优先级日志以确保主流程不被阻塞
 		// 只在控制台打印警告，不阻塞GUI
-		fmt.Printf("警告: 日志缓冲区已满，丢弃部分日志\n")
+		fmt.Fprintf(os.Stderr, "警告: 日志缓冲区已满，丢弃部分日志\n")
 	}
 }
 
@@ -237,9 +930,11 @@ func (fo *FileOrganizer) createGUI() {
 	fo.Window = myApp.NewWindow("文件整理工具")
 	// 现在应用已经创建，可以加载用户配置了
 	fo.loadUserConfig()
-	fo.Window.Resize(fyne.NewSize(880, 745))
-	// 禁止用户调整窗口大小
-	fo.Window.SetFixedSize(true)
+	fo.Window.Resize(fo.initialWindowSize())
+	// 按设置决定是否允许用户调整窗口大小
+	fo.Window.SetFixedSize(!fo.WindowResizable)
+	fo.Window.SetOnClosed(fo.saveWindowSize)
+	fo.serveInstanceFocus(fo.instanceLock)
 
 	// 初始化源文件夹列表
 	fo.SourceDirs = []string{}
@@ -247,9 +942,10 @@ func (fo *FileOrganizer) createGUI() {
 	fo.SourceDirEntry.TextStyle = fyne.TextStyle{Italic: true}
 
 	// 初始化RuleSelect组件（在使用前创建）
-	rules := []string{string(RuleByDate), string(RuleByExtension)}
+	rules := []string{string(RuleByDate), string(RuleByExtension), string(RuleByTag), string(RuleByAge), string(RuleByHash), string(RuleByCamera), string(RuleByTypeSize), string(RuleComposite)}
 	fo.RuleSelect = widget.NewSelect(rules, nil)
 	fo.RuleSelect.SetSelected(string(RuleByDate))
+	fo.OrganizeRule = RuleByDate
 	fo.RuleSelect.Disable() // 初始时禁用，直到选择了源文件夹
 
 	// 初始化LogTextLabel组件（在使用前创建）
@@ -257,9 +953,11 @@ func (fo *FileOrganizer) createGUI() {
 	fo.LogTextLabel.Wrapping = fyne.TextWrapWord
 	fo.LogTextLabel.Alignment = fyne.TextAlignLeading
 	fo.LogTextLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	fo.LogTextLabel.Selectable = true // 允许选中并复制日志内容
 
 	// 初始化源文件夹列表组件
-	fo.SourceDirsList = widget.NewList(
+	// 列表获得焦点后可以用方向键移动、空格选中、Delete删除
+	fo.SourceDirsList = newKeyboardList(
 		func() int {
 			return len(fo.SourceDirs)
 		},
@@ -267,7 +965,7 @@ func (fo *FileOrganizer) createGUI() {
 			return widget.NewLabel("")
 		},
 		func(i widget.ListItemID, o fyne.CanvasObject) {
-			o.(*widget.Label).SetText(fo.SourceDirs[i])
+			o.(*widget.Label).SetText(fo.sourceDirLabel(fo.SourceDirs[i]))
 		},
 	)
 	// 监听列表选择变化 - 支持多选
@@ -297,37 +995,7 @@ func (fo *FileOrganizer) createGUI() {
 							selectFolders()
 						} else {
 							// 处理选择的所有文件夹
-							addedCount := 0
-							for _, folderPath := range selectedDirs {
-								// 检查是否已存在该目录
-								isDuplicate := false
-								for _, existingPath := range fo.SourceDirs {
-									if existingPath == folderPath {
-										isDuplicate = true
-										break
-									}
-								}
-
-								if !isDuplicate {
-									// 添加新文件夹
-									fo.SourceDirs = append(fo.SourceDirs, folderPath)
-									addedCount++
-								}
-							}
-
-							if addedCount > 0 {
-								fo.SourceDirEntry.SetText(fmt.Sprintf("已选择 %d 个源文件夹", len(fo.SourceDirs)))
-								fo.SourceDirsList.Refresh()
-
-								// 在按钮完全初始化后设置回调函数
-								fo.RuleSelect.OnChanged = func(value string) {
-									fo.scanFiles() // 选择规则后自动扫描文件
-								}
-								fo.RuleSelect.Enable() // 选择了源文件夹后启用规则选择
-								fo.log(fmt.Sprintf("已添加 %d 个源文件夹", addedCount))
-								// 选择源文件夹后自动扫描文件
-								fo.scanFiles()
-							}
+							fo.addSourceDirs(selectedDirs)
 							// 清空临时列表
 							selectedDirs = make([]string, 0)
 						}
@@ -342,51 +1010,13 @@ func (fo *FileOrganizer) createGUI() {
 
 	// 创建删除选中源文件夹按钮 - 支持多选删除
 	removeSourceBtn := widget.NewButtonWithIcon("删除选中", theme.DeleteIcon(), func() {
-		if len(fo.selectedSourceDirs) > 0 {
-			// 创建确认对话框
-			var dirNames []string
-			for idx := range fo.selectedSourceDirs {
-				dirNames = append(dirNames, fo.SourceDirs[idx])
-			}
-			dialog.ShowConfirm("确认删除", fmt.Sprintf("确定要从源文件夹列表中删除 %d 个文件夹吗？", len(fo.selectedSourceDirs)), func(confirm bool) {
-				if confirm {
-					// 创建一个映射来跟踪要删除的索引
-					toDelete := make(map[int]bool)
-					for idx := range fo.selectedSourceDirs {
-						toDelete[idx] = true
-					}
-
-					// 创建新的源文件夹列表，跳过要删除的项
-					var newSourceDirs []string
-					for idx, dir := range fo.SourceDirs {
-						if !toDelete[idx] {
-							newSourceDirs = append(newSourceDirs, dir)
-						}
-					}
+		fo.removeSelectedSourceDirs()
+	})
+	fo.SourceDirsList.onDelete = fo.removeSelectedSourceDirs
 
-					// 更新源文件夹列表
-					fo.SourceDirs = newSourceDirs
-					// 更新标签显示
-					fo.SourceDirEntry.SetText(fmt.Sprintf("已选择 %d 个源文件夹", len(fo.SourceDirs)))
-					// 刷新列表
-					fo.SourceDirsList.Refresh()
-					// 清空选中索引
-					fo.selectedSourceDirs = make(map[int]bool)
-					// 如果删除后没有文件夹了，禁用相关按钮
-					if len(fo.SourceDirs) == 0 {
-						fo.safeUpdateUI(func() {
-							fo.selectExtensionsBtn.Disable()
-							fo.selectDateFormatBtn.Disable()
-							fo.selectExtensionCaseBtn.Disable()
-							fo.processBtn.Disable()
-							fo.RuleSelect.Disable()
-						})
-					}
-				}
-			}, fo.Window)
-		} else {
-			dialog.ShowInformation("提示", "请先选择要删除的源文件夹", fo.Window)
-		}
+	// 切换选中源文件夹的只读标记
+	readOnlySourceBtn := widget.NewButtonWithIcon("只读/取消只读", theme.FileIcon(), func() {
+		fo.toggleSelectedReadOnly()
 	})
 
 	// 选择文件后缀按钮
@@ -413,20 +1043,60 @@ func (fo *FileOrganizer) createGUI() {
 	})
 	fo.processBtn.Disable() // 初始时禁用
 
+	// 目标文件夹输入框，留空时使用第一个源文件夹
+	fo.TargetDirEntry = widget.NewEntry()
+	fo.TargetDirEntry.SetPlaceHolder("留空则使用第一个源文件夹")
+	fo.TargetDirEntry.Validator = func(text string) error {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return validateTargetDir(strings.TrimSpace(text))
+	}
+	fo.TargetDirEntry.OnChanged = func(string) {
+		fo.refreshFileTable()
+	}
+	targetBrowseBtn := widget.NewButtonWithIcon("选择目标文件夹", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err == nil && dir != nil {
+				fo.TargetDirEntry.SetText(dir.Path())
+			}
+		}, fo.Window)
+	})
+
+	// 浏览扫描结果按钮
+	fo.browseFilesBtn = widget.NewButtonWithIcon("浏览扫描结果", theme.SearchIcon(), func() {
+		fo.showScannedFilesDialog()
+	})
+	fo.browseFilesBtn.Disable() // 初始时禁用，扫描完成后启用
+
+	// 重新扫描按钮，手动修改过源文件夹后按当前设置重新扫描
+	fo.rescanBtn = widget.NewButtonWithIcon("重新扫描", theme.ViewRefreshIcon(), func() {
+		fo.rescan()
+	})
+	fo.rescanBtn.Disable() // 初始时禁用，选择源文件夹后启用
+
 	// 源文件夹区域
 	// 创建带滚动功能的源文件夹列表，并设置其最小大小以显示更多内容
 	scrollableSourceList := container.NewScroll(fo.SourceDirsList)
 	scrollableSourceList.SetMinSize(fyne.NewSize(400, 200))
+	fo.welcomePanel = fo.newWelcomePanel()
 
-	sourceArea := container.NewVBox(
-		container.NewHBox(
-			widget.NewLabel("源文件夹:"),
-			fo.SourceDirEntry,
-			layout.NewSpacer(),
-			sourceBrowseBtn,
-		),
-		container.NewPadded(scrollableSourceList),
-		removeSourceBtn,
+	// 配置方案：一次切换源文件夹、规则和全部设置
+	profilesBtn := widget.NewButton("管理方案", func() {
+		fo.showProfilesDialog()
+	})
+	sourceHeader := container.NewHBox(
+		widget.NewLabel("源文件夹:"),
+		fo.SourceDirEntry,
+		layout.NewSpacer(),
+		fo.newProfileSelect(),
+		profilesBtn,
+		sourceBrowseBtn,
+	)
+	sourceList := container.NewPadded(container.NewStack(scrollableSourceList, fo.welcomePanel))
+	sourceButtons := container.NewVBox(
+		container.NewGridWithColumns(4, removeSourceBtn, readOnlySourceBtn, fo.rescanBtn, fo.browseFilesBtn),
+		container.NewBorder(nil, nil, widget.NewLabel("目标文件夹:"), targetBrowseBtn, fo.TargetDirEntry),
 	)
 
 	// 整理规则和文件后缀选择
@@ -445,14 +1115,12 @@ func (fo *FileOrganizer) createGUI() {
 		fo.selectExtensionCaseBtn,
 	)
 
-	// 日志区域 - 降低日志区域高度
+	// 日志区域，窗口变大时日志区域随之变高
 	logScroll := container.NewScroll(fo.LogTextLabel)
 	logScroll.SetMinSize(fyne.NewSize(0, 200))
-	logSection := container.NewVBox(
-		widget.NewLabel("处理日志:"),
-		logScroll,
+	logButtons := container.NewVBox(
 		widget.NewSeparator(),
-		container.NewGridWithColumns(2,
+		container.NewGridWithColumns(5,
 			widget.NewButtonWithIcon("清空日志", theme.DeleteIcon(), func() {
 				fo.LogTextLabel.SetText("")
 			}),
@@ -481,40 +1149,137 @@ func (fo *FileOrganizer) createGUI() {
 				saveDialog.SetFileName(fmt.Sprintf("file_organizer_log_%s.txt", time.Now().Format("20060102_150405")))
 				saveDialog.Show()
 			}),
+			widget.NewButtonWithIcon("复制命令", theme.ContentCopyIcon(), func() {
+				if fo.lastCLICommand == "" {
+					dialog.ShowInformation("提示", "还没有整理记录，开始整理后可复制等效命令", fo.Window)
+					return
+				}
+				fyne.CurrentApp().Clipboard().SetContent(fo.lastCLICommand)
+				fo.log("已复制等效命令到剪贴板")
+			}),
+			widget.NewButtonWithIcon("复制统计", theme.ContentCopyIcon(), func() {
+				fo.statsMu.Lock()
+				statsJSON := fo.lastStatsJSON
+				fo.statsMu.Unlock()
+				if statsJSON == "" {
+					dialog.ShowInformation("提示", "还没有整理记录，整理完成后可复制统计JSON", fo.Window)
+					return
+				}
+				fyne.CurrentApp().Clipboard().SetContent(statsJSON)
+				fo.log("已复制统计JSON到剪贴板")
+			}),
+			widget.NewButtonWithIcon("累计统计", theme.InfoIcon(), func() {
+				fo.showLifetimeStatsDialog()
+			}),
 		),
 	)
 
+	// 更多设置按钮
+	fo.settingsBtn = widget.NewButtonWithIcon("更多设置", theme.SettingsIcon(), func() {
+		fo.showSettingsDialog()
+	})
+
+	// 规则预设按钮
+	fo.presetsBtn = widget.NewButtonWithIcon("规则预设", theme.DocumentIcon(), func() {
+		fo.showPresetsDialog()
+	})
+
+	// 搜索整理目录按钮
+	catalogBtn := widget.NewButtonWithIcon("搜索目录", theme.SearchIcon(), func() {
+		fo.showCatalogSearchDialog()
+	})
+
+	// 规则测试按钮
+	ruleTestBtn := widget.NewButtonWithIcon("规则测试", theme.QuestionIcon(), func() {
+		fo.showRuleTestWindow()
+	})
+
+	// 任务队列按钮
+	queueBtn := widget.NewButtonWithIcon("任务队列", theme.ListIcon(), func() {
+		fo.showQueueDialog()
+	})
+
+	// 监视模式按钮
+	fo.watchBtn = widget.NewButtonWithIcon("监视模式", theme.VisibilityIcon(), func() {
+		fo.showWatchDialog()
+	})
+
+	// 只读模式开关，开启时在窗口顶部显示提示
+	readOnlyCheck, readOnlyBanner := fo.createReadOnlyModeWidgets()
+
 	// 开始整理按钮区域
-	processBtnBox := container.NewMax(fo.processBtn)
+	processBtnBox := container.NewBorder(nil, nil, readOnlyCheck,
+		container.NewHBox(fo.presetsBtn, ruleTestBtn, queueBtn, catalogBtn, fo.watchBtn, fo.settingsBtn), fo.processBtn)
 
-	// 主布局
-	mainContent := container.NewVBox(
-		container.NewPadded(sourceArea), // 添加内边距
+	// 主布局，Tab键按从上到下的顺序切换焦点：
+	// 源文件夹 → 规则与后缀 → 命名规则 → 开始整理及其他功能 → 日志。
+	// 源文件夹列表和日志区域分享窗口多出的高度，中间的分隔条可以拖动
+	settingsArea := container.NewVBox(
+		container.NewPadded(sourceButtons),
 		container.NewPadded(ruleSection),
 		container.NewPadded(optionSection),
 		container.NewPadded(processBtnBox),
-		container.NewPadded(logSection),
+		widget.NewLabel("处理日志:"),
 	)
+	logArea := container.NewBorder(settingsArea, container.NewPadded(logButtons), nil, nil, container.NewPadded(logScroll))
+	split := container.NewVSplit(sourceList, logArea)
+	split.SetOffset(0.25)
+	mainContent := container.NewBorder(container.NewVBox(readOnlyBanner, container.NewPadded(sourceHeader)), nil, nil, nil, split)
+
+	fo.Window.SetContent(withMinWindowSize(container.NewScroll(mainContent)))
+	// 启动时焦点放在第一个可操作的按钮上，方便只用键盘操作
+	fo.Window.Canvas().Focus(sourceBrowseBtn)
+
+	// 检测新插入的相机存储卡
+	if fo.CardDetection {
+		fo.startCardDetection()
+	}
+	// 定时整理
+	fo.startSchedule()
+	// 每天定时显示今日摘要
+	fo.startDigestTimer()
+	// 状态页面
+	fo.applyStatusServer()
+	// 继续上次未完成的目标文件索引
+	fo.resumeTargetIndexBuild()
+	// 上次被中断的整理
+	fo.offerRunResume()
+
+	// 开发者模式注入的文件操作失败
+	fo.logFaults()
+
+	// 整理进行中关闭窗口时先确认，等待或取消整理后再退出
+	fo.Window.SetCloseIntercept(fo.confirmClose)
 
-	fo.Window.SetContent(container.NewScroll(mainContent))
 	fo.Window.ShowAndRun()
 
-	// 应用退出时停止日志处理器
+	// 应用退出时停止监视和日志处理器
+	fo.stopCardDetection()
+	fo.stopSchedule()
+	fo.stopDigestTimer()
+	fo.stopWatching()
+	fo.status.stopServing()
 	fo.stopLogProcessor()
 }
 
 // 扫描文件
 func (fo *FileOrganizer) scanFiles() {
-	fo.safeUpdateUI(func() {
-		fo.selectExtensionsBtn.Disable()
-		fo.selectDateFormatBtn.Disable()
-		fo.selectExtensionCaseBtn.Disable()
-		fo.processBtn.Disable()
-	})
-
 	// 清空之前的扫描结果
 	fo.scannedFiles = []string{}
-	fo.scannedFileExtensions = make(map[string]bool)
+	fo.scannedFileExtensions = make(map[string]int)
+	fo.scannedFileInfos = make(map[string]os.FileInfo)
+	fo.streaming = false
+	fo.streamedCount = 0
+	fo.dates.reset()
+	fo.contentHashes.reset()
+	fo.emailSenders.reset()
+	fo.cameraModels.reset()
+	fo.coarseRoots.reset()
+	fo.textFolders.reset()
+	fo.bursts = nil
+	fo.expandedBursts = make(map[string]bool)
+	fo.scanGeneration++
+	fo.ui.ScanStarted()
 
 	// 检查是否选择了源文件夹
 	if len(fo.SourceDirs) == 0 {
@@ -523,131 +1288,455 @@ func (fo *FileOrganizer) scanFiles() {
 	}
 
 	// 清空日志
-	fo.LogTextLabel.SetText("")
+	fo.ui.ClearLog()
+	fo.isScanning.Store(true)
 	fo.log("开始扫描文件...")
 	fo.log(fmt.Sprintf("共选择了 %d 个源文件夹", len(fo.SourceDirs)))
 
+	// 未变化的文件夹复用上次扫描的结果，过滤条件或排除列表变化时缓存失效
+	cacheKey := scanCacheKey(fo.FileExtensions, fo.excludedFiles)
+	scanner := newDirScanner(cacheKey, fo.ForceFullScan)
+	roots := append([]string(nil), fo.SourceDirs...)
+	if fo.ForceFullScan {
+		fo.log("强制完全扫描，不使用扫描缓存")
+	}
+
+	// 限制同时扫描的源文件夹数量，避免同一块慢速磁盘上的多个文件夹互相争抢
+	concurrency := fo.ScanConcurrency
+	if concurrency < 1 {
+		concurrency = runtime.NumCPU()
+	}
+	if len(roots) > concurrency {
+		fo.log(fmt.Sprintf("同时最多扫描 %d 个文件夹", concurrency))
+	}
+	errorLimit := fo.ScanErrorLimit
+
 	// 在goroutine中扫描文件
 	go func() {
 		var wg sync.WaitGroup
 		var mu sync.Mutex // 用于保护共享数据
 		errors := []string{}
+		sem := make(chan struct{}, concurrency)
+		// 各源文件夹的错误数，错误总数达到上限时中止扫描
+		errorCounts := make(map[string]int)
+		aborted := false
+		// 扫描过程中被删除或卸载的源文件夹，不影响其他源文件夹的扫描
+		missing := make(map[string]bool)
 
-		// 为每个源文件夹创建一个goroutine进行扫描
-		for _, sourceDir := range fo.SourceDirs {
+		// 按顺序为每个源文件夹创建一个goroutine进行扫描，达到并发数时等待前面的扫描完成
+		for _, sourceDir := range roots {
+			sem <- struct{}{}
 			wg.Add(1)
 			go func(dir string) {
 				defer wg.Done()
+				defer func() { <-sem }()
 
 				// 记录当前扫描的文件夹
 				fo.log(fmt.Sprintf("正在扫描: %s", dir))
 
-				err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						mu.Lock()
-						errors = append(errors, fmt.Sprintf("扫描 %s 时出错: %v", path, err))
-						mu.Unlock()
-						return filepath.SkipDir // 跳过有错误的目录
-					}
-					if !info.IsDir() {
-						mu.Lock()
+				scanner.scan(dir, func(path string, info os.FileInfo) {
+					mu.Lock()
+					if fo.streaming {
+						fo.streamedCount++
+					} else {
 						fo.scannedFiles = append(fo.scannedFiles, path)
-						fileExt := strings.ToLower(filepath.Ext(path))
-						if fileExt != "" {
-							fo.scannedFileExtensions[fileExt] = true
+						fo.scannedFileInfos[path] = info
+						if fo.StreamingThreshold > 0 && len(fo.scannedFiles) > fo.StreamingThreshold {
+							fo.enterStreamingMode(scanner)
 						}
+					}
+					fileExt := strings.ToLower(fileExtension(path))
+					if fileExt != "" {
+						fo.scannedFileExtensions[fileExt]++
+					}
+					mu.Unlock()
+				}, func(path string, err error) {
+					// 源文件夹本身已不存在时只记录一次，不计入错误上限
+					if isSourceRootMissing(dir, err) {
+						mu.Lock()
+						missing[dir] = true
 						mu.Unlock()
+						return
 					}
-					return nil
-				})
-
-				if err != nil {
+					// 跳过有错误的目录
 					mu.Lock()
-					errors = append(errors, fmt.Sprintf("扫描 %s 时出错: %v", dir, err))
+					errors = append(errors, fmt.Sprintf("扫描 %s 时出错: %v", path, err))
+					errorCounts[dir]++
+					if errorLimit > 0 && len(errors) >= errorLimit && !aborted {
+						aborted = true
+						scanner.abort()
+					}
 					mu.Unlock()
-				}
+				})
 			}(sourceDir)
 		}
 
 		// 等待所有扫描完成
 		wg.Wait()
 
-		fo.safeUpdateUI(func() {
-			// 显示所有错误信息
+		if aborted {
+			// 错误最多的源文件夹最可能有问题
+			badSource := ""
+			for dir, count := range errorCounts {
+				if badSource == "" || count > errorCounts[badSource] {
+					badSource = dir
+				}
+			}
 			for _, errMsg := range errors {
 				fo.log(errMsg)
 			}
+			fo.log(fmt.Sprintf("扫描已中止: 出现 %d 个错误，达到上限 %d。错误最多的源文件夹: %s（%d 个错误）",
+				len(errors), errorLimit, badSource, errorCounts[badSource]))
+			// 不完整的扫描结果不能用于整理，也不保存到扫描缓存
+			fo.scannedFiles = []string{}
+			fo.scannedFileExtensions = make(map[string]int)
+			fo.scannedFileInfos = make(map[string]os.FileInfo)
+			fo.streaming = false
+			fo.scannedAt = time.Time{}
+			fo.isScanning.Store(false)
+			fo.ui.ScanAborted(len(errors), badSource, errorCounts[badSource])
+			return
+		}
 
-			fo.log(fmt.Sprintf("扫描完成，共发现 %d 个文件", len(fo.scannedFiles)))
-			fo.log(fmt.Sprintf("发现 %d 种文件后缀", len(fo.scannedFileExtensions)))
+		// 已不可用的源文件夹中扫描到的文件不能再整理，也不保存到扫描缓存
+		var missingRoots, availableRoots []string
+		for _, root := range roots {
+			if missing[root] {
+				missingRoots = append(missingRoots, root)
+			} else {
+				availableRoots = append(availableRoots, root)
+			}
+		}
+		if len(missingRoots) > 0 {
+			fo.dropScannedFilesUnder(missingRoots)
+			for _, root := range missingRoots {
+				fo.log("源文件夹已不可用: " + root)
+			}
+		}
 
-			// 根据选择的规则显示相应的选项
-			rule := OrganizeRule(fo.RuleSelect.Selected)
-			switch rule {
-			case RuleByDate:
-				fo.selectExtensionsBtn.Enable()
-				fo.selectDateFormatBtn.Enable()
-				fo.selectExtensionCaseBtn.Disable()
-			case RuleByExtension:
-				fo.selectExtensionsBtn.Enable()
-				fo.selectDateFormatBtn.Disable()
-				fo.selectExtensionCaseBtn.Enable()
+		// 流式整理时扫描结果没有保留，不更新扫描缓存
+		if !fo.streaming {
+			if err := scanner.save(cacheKey, availableRoots); err != nil {
+				fo.log(err.Error())
 			}
-			// 保存当前规则选择
-			fo.saveUserConfig()
-		})
+		}
+		fo.log(fmt.Sprintf("遍历了 %d 个有变化的文件夹，%d 个文件夹使用扫描缓存", scanner.walked.Load(), scanner.reused.Load()))
+
+		fo.isScanning.Store(false)
+		// 显示所有错误信息
+		for _, errMsg := range errors {
+			fo.log(errMsg)
+		}
+
+		fo.log(fmt.Sprintf("扫描完成，共发现 %d 个文件", fo.scannedCount()))
+		if fo.streaming {
+			fo.log("流式整理: 将处理扫描到的所有后缀，无法选择后缀、浏览或排除单个文件")
+		}
+		fo.log(fmt.Sprintf("发现 %d 种文件后缀", len(fo.scannedFileExtensions)))
+		if len(fo.scannedFileExtensions) > 0 {
+			fo.log("各后缀的文件数: " + describeExtensionCounts(fo.scannedFileExtensions))
+		}
+
+		fo.updateBursts()
+
+		// 根据选择的规则更新界面
+		fo.scannedAt = time.Now()
+		fo.ui.ScanFinished(fo.OrganizeRule)
+		if len(missingRoots) > 0 {
+			fo.ui.SourcesMissing(missingRoots)
+		}
 	}()
 }
 
-// 显示选择文件后缀对话框
-func (fo *FileOrganizer) showSelectExtensionsDialog() {
-	if len(fo.scannedFileExtensions) == 0 {
-		dialog.ShowInformation("提示", "请先扫描文件", fo.Window)
-		return
+// 源文件夹本身是否已不存在（被删除、改名或所在磁盘被卸载）
+func isSourceRootMissing(root string, err error) bool {
+	if !errors.Is(err, fs.ErrNotExist) {
+		return false
 	}
+	_, statErr := os.Stat(root)
+	return statErr != nil
+}
 
-	// 创建复选框列表
-	var checkboxes []fyne.CanvasObject
-	extensionMap := make(map[string]*widget.Check)
+// 删除扫描结果中位于指定文件夹内的文件
+func (fo *FileOrganizer) dropScannedFilesUnder(roots []string) {
+	kept := fo.scannedFiles[:0]
+	for _, path := range fo.scannedFiles {
+		if isWithinAny(filepath.Dir(path), roots) {
+			delete(fo.scannedFileInfos, path)
+			continue
+		}
+		kept = append(kept, path)
+	}
+	fo.scannedFiles = kept
+	fo.scannedFileExtensions = make(map[string]int)
+	for _, path := range fo.scannedFiles {
+		if ext := strings.ToLower(fileExtension(path)); ext != "" {
+			fo.scannedFileExtensions[ext]++
+		}
+	}
+}
 
-	for ext := range fo.scannedFileExtensions {
-		checkbox := widget.NewCheck(ext, nil)
-		checkboxes = append(checkboxes, checkbox)
-		extensionMap[ext] = checkbox
+// 按当前的源文件夹和设置重新扫描，扫描进行中时忽略
+func (fo *FileOrganizer) rescan() {
+	if fo.isScanning.Load() {
+		fo.log("正在扫描，请稍候")
+		return
+	}
+	if len(fo.SourceDirs) == 0 {
+		dialog.ShowInformation("提示", "请先选择源文件夹", fo.Window)
+		return
 	}
+	fo.scanFiles()
+}
 
-	// 创建滚动容器
-	scroll := container.NewVScroll(container.NewVBox(checkboxes...))
-	scroll.SetMinSize(fyne.NewSize(400, 300))
+// 整理规则变化时校验规则是否可用，然后重新扫描
+func (fo *FileOrganizer) onRuleChanged(value string) {
+	if err := validateTagRule(Config{OrganizeRule: value}); err != nil {
+		dialog.ShowInformation("提示", err.Error(), fo.Window)
+		// 恢复之前的规则，临时移除回调避免重复扫描
+		callback := fo.RuleSelect.OnChanged
+		fo.RuleSelect.OnChanged = nil
+		fo.RuleSelect.SetSelected(string(fo.OrganizeRule))
+		fo.RuleSelect.OnChanged = callback
+		return
+	}
 
-	// 创建对话框
-	dialog := dialog.NewCustom("选择文件后缀", "确定", scroll, fo.Window)
-	dialog.SetOnClosed(func() {
-		// 收集选中的后缀
-		var selectedExtensions []string
-		for ext, checkbox := range extensionMap {
-			if checkbox.Checked {
-				selectedExtensions = append(selectedExtensions, ext)
+	fo.OrganizeRule = OrganizeRule(value)
+	fo.scanFiles()
+}
+
+// 添加源文件夹（忽略已存在的），有新文件夹时启用规则选择并自动扫描
+func (fo *FileOrganizer) addSourceDirs(dirs []string) {
+	addedCount := 0
+	for _, folderPath := range dirs {
+		// 检查是否已存在该目录
+		isDuplicate := false
+		for _, existingPath := range fo.SourceDirs {
+			if existingPath == folderPath {
+				isDuplicate = true
+				break
 			}
 		}
 
-		if len(selectedExtensions) > 0 {
-			fo.FileExtensions = selectedExtensions
-			fo.log(fmt.Sprintf("已选择 %d 种文件后缀进行处理", len(selectedExtensions)))
-			fo.processBtn.Enable() // 选择了后缀后启用处理按钮
-		} else {
-			fo.log("未选择任何文件后缀")
-			fo.processBtn.Disable()
+		if !isDuplicate {
+			// 添加新文件夹
+			fo.SourceDirs = append(fo.SourceDirs, folderPath)
+			addedCount++
 		}
-	})
+	}
+
+	if addedCount > 0 {
+		fo.SourceDirEntry.SetText(fmt.Sprintf("已选择 %d 个源文件夹", len(fo.SourceDirs)))
+		fo.SourceDirsList.Refresh()
+		fo.updateWelcomePanel()
 
-	dialog.Show()
+		// 在按钮完全初始化后设置回调函数
+		fo.RuleSelect.OnChanged = func(value string) {
+			fo.onRuleChanged(value) // 选择规则后自动扫描文件
+		}
+		fo.RuleSelect.Enable() // 选择了源文件夹后启用规则选择
+		fo.log(fmt.Sprintf("已添加 %d 个源文件夹", addedCount))
+		// 选择源文件夹后自动扫描文件
+		fo.scanFiles()
+	}
 }
 
-// 显示选择日期格式对话框
-func (fo *FileOrganizer) showSelectDateFormatDialog() {
-	dateFormats := []string{"YYYY-MM-DD", "YYYYMMDD", "YY-MM-DD", "YYMMDD"}
-	formatSelect := widget.NewSelect(dateFormats, nil)
+// 确认后从源文件夹列表中删除选中的文件夹
+func (fo *FileOrganizer) removeSelectedSourceDirs() {
+	if len(fo.selectedSourceDirs) > 0 {
+		// 创建确认对话框
+		var dirNames []string
+		for idx := range fo.selectedSourceDirs {
+			dirNames = append(dirNames, fo.SourceDirs[idx])
+		}
+		dialog.ShowConfirm("确认删除", fmt.Sprintf("确定要从源文件夹列表中删除 %d 个文件夹吗？", len(fo.selectedSourceDirs)), func(confirm bool) {
+			if confirm {
+				// 创建一个映射来跟踪要删除的文件夹
+				toDelete := make(map[string]bool)
+				for idx := range fo.selectedSourceDirs {
+					if idx < len(fo.SourceDirs) {
+						toDelete[fo.SourceDirs[idx]] = true
+					}
+				}
+				fo.removeSourceDirs(toDelete)
+			}
+		}, fo.Window)
+	} else {
+		dialog.ShowInformation("提示", "请先选择要删除的源文件夹", fo.Window)
+	}
+}
+
+// 从源文件夹列表中删除指定的文件夹
+func (fo *FileOrganizer) removeSourceDirs(toDelete map[string]bool) {
+	// 创建新的源文件夹列表，跳过要删除的项
+	var newSourceDirs []string
+	for _, dir := range fo.SourceDirs {
+		if !toDelete[dir] {
+			newSourceDirs = append(newSourceDirs, dir)
+		}
+	}
+
+	// 更新源文件夹列表
+	fo.SourceDirs = newSourceDirs
+	// 更新标签显示
+	fo.SourceDirEntry.SetText(fmt.Sprintf("已选择 %d 个源文件夹", len(fo.SourceDirs)))
+	// 刷新列表
+	fo.SourceDirsList.UnselectAll()
+	fo.SourceDirsList.Refresh()
+	fo.updateWelcomePanel()
+	// 清空选中索引
+	fo.selectedSourceDirs = make(map[int]bool)
+	// 源文件夹变化后停止监视，避免继续整理已移除的文件夹
+	if fo.isWatching() {
+		fo.stopWatching()
+		fo.updateWatchButton()
+	}
+	// 如果删除后没有文件夹了，禁用相关按钮
+	if len(fo.SourceDirs) == 0 {
+		fo.safeUpdateUI(func() {
+			fo.selectExtensionsBtn.Disable()
+			fo.selectDateFormatBtn.Disable()
+			fo.selectExtensionCaseBtn.Disable()
+			fo.processBtn.Disable()
+			fo.browseFilesBtn.Disable()
+			fo.rescanBtn.Disable()
+			fo.RuleSelect.Disable()
+		})
+	}
+}
+
+// 显示选择文件后缀对话框
+func (fo *FileOrganizer) showSelectExtensionsDialog() {
+	if fo.streaming {
+		dialog.ShowInformation("流式整理", "扫描到的文件超过流式整理阈值，文件列表没有保留，将处理所有后缀的文件。\n需要选择后缀时请在更多设置中调高流式整理阈值后重新扫描。", fo.Window)
+		return
+	}
+	if len(fo.scannedFileExtensions) == 0 {
+		dialog.ShowInformation("提示", "请先扫描文件", fo.Window)
+		return
+	}
+
+	// 创建复选框列表（按后缀排序，保证Tab顺序稳定）
+	var checkboxes []fyne.CanvasObject
+	extensionMap := make(map[string]*widget.Check)
+
+	var sortedExtensions []string
+	for ext := range fo.scannedFileExtensions {
+		sortedExtensions = append(sortedExtensions, ext)
+	}
+	sort.Strings(sortedExtensions)
+	var firstCheckbox *widget.Check
+	for _, ext := range sortedExtensions {
+		checkbox := widget.NewCheck(fmt.Sprintf("%s（%d 个文件）", ext, fo.scannedFileExtensions[ext]), nil)
+		if firstCheckbox == nil {
+			firstCheckbox = checkbox
+		}
+		checkboxes = append(checkboxes, checkbox)
+		extensionMap[ext] = checkbox
+	}
+	// 勾选上次为这组源文件夹选择的后缀，本次扫描中已没有的后缀忽略
+	for _, ext := range fo.rememberedExtensions(fo.SourceDirs) {
+		if checkbox := extensionMap[ext]; checkbox != nil {
+			checkbox.SetChecked(true)
+		}
+	}
+
+	// 已选后缀覆盖的文件总数，勾选变化时更新
+	scannedTotal := 0
+	for _, count := range fo.scannedFileExtensions {
+		scannedTotal += count
+	}
+	totalLabel := widget.NewLabel("")
+	updateTotal := func() {
+		selectedCount, fileCount := 0, 0
+		for ext, checkbox := range extensionMap {
+			if checkbox.Checked {
+				selectedCount++
+				fileCount += fo.scannedFileExtensions[ext]
+			}
+		}
+		totalLabel.SetText(fmt.Sprintf("已选择 %d 种后缀，共 %d 个文件（扫描到 %d 个文件）", selectedCount, fileCount, scannedTotal))
+	}
+	updateTotal()
+
+	// 按类别快速选择，单个后缀变化时更新类别的全选/半选状态
+	groups := newExtensionGroupToggles(sortedExtensions, extensionMap)
+	groups.refresh()
+	for _, checkbox := range extensionMap {
+		checkbox.OnChanged = func(bool) {
+			if !groups.updating {
+				groups.refresh()
+			}
+			updateTotal()
+		}
+	}
+
+	// 按通配符选择扫描到的后缀，例如 *.cr?
+	patternStatus := widget.NewLabel("")
+	patternStatus.Wrapping = fyne.TextWrapWord
+	patternEntry := widget.NewEntry()
+	patternEntry.SetPlaceHolder("通配符，例如 *.cr? 或 mp*")
+	selectMatching := func() {
+		matched, err := matchExtensionPatterns(patternEntry.Text, sortedExtensions)
+		switch {
+		case err != nil:
+			patternStatus.SetText(err.Error())
+		case len(matched) == 0:
+			patternStatus.SetText("没有匹配的后缀")
+		default:
+			groups.setAll(matched, true)
+			patternStatus.SetText(fmt.Sprintf("已选中 %d 种后缀: %s", len(matched), strings.Join(matched, ", ")))
+		}
+	}
+	patternEntry.OnSubmitted = func(string) { selectMatching() }
+	patternBox := container.NewVBox(
+		container.NewBorder(nil, nil, nil, widget.NewButton("选中匹配的后缀", selectMatching), patternEntry),
+		patternStatus,
+	)
+	header := container.NewVBox(patternBox)
+	if toggles := groups.content(); toggles != nil {
+		header = container.NewVBox(toggles, widget.NewSeparator(), patternBox)
+	}
+
+	// 创建滚动容器
+	scroll := container.NewVScroll(container.NewVBox(checkboxes...))
+	scroll.SetMinSize(fyne.NewSize(400, 300))
+
+	// 创建对话框
+	dialog := dialog.NewCustom("选择文件后缀", "确定", container.NewBorder(header, totalLabel, nil, nil, scroll), fo.Window)
+	dialog.SetOnClosed(func() {
+		// 收集选中的后缀
+		var selectedExtensions []string
+		for ext, checkbox := range extensionMap {
+			if checkbox.Checked {
+				selectedExtensions = append(selectedExtensions, ext)
+			}
+		}
+
+		if len(selectedExtensions) > 0 {
+			fo.FileExtensions = normalizeExtensions(selectedExtensions)
+			fo.rememberExtensions(fo.SourceDirs, fo.FileExtensions)
+			fo.log(fmt.Sprintf("已选择 %d 种文件后缀进行处理", len(selectedExtensions)))
+			fo.processBtn.Enable() // 选择了后缀后启用处理按钮
+		} else {
+			fo.log("未选择任何文件后缀")
+			fo.processBtn.Disable()
+		}
+		fo.refreshFileTable()
+	})
+
+	fo.showDialog(dialog, firstCheckbox)
+}
+
+// 日期文件夹可用的命名规则
+var folderDateFormats = []string{"YYYY-MM-DD", "YYYYMMDD", "YY-MM-DD", "YYMMDD", "YYYY-MM", "YYYYMM"}
+
+// 后缀文件夹可用的大小写
+var extensionCases = []string{"uppercase", "lowercase"}
+
+// 显示选择日期格式对话框
+func (fo *FileOrganizer) showSelectDateFormatDialog() {
+	formatSelect := widget.NewSelect(folderDateFormats, nil)
 	// 使用之前保存的文件夹命名规则
 	formatSelect.SetSelected(fo.FolderDateFormat)
 
@@ -657,14 +1746,14 @@ func (fo *FileOrganizer) showSelectDateFormatDialog() {
 		fo.log(fmt.Sprintf("已选择文件夹命名规则: %s", fo.FolderDateFormat))
 		// 保存用户选择的文件夹命名规则
 		fo.saveUserConfig()
+		fo.refreshFileTable()
 	})
 
-	dialog.Show()
+	fo.showDialog(dialog, formatSelect)
 }
 
 // 显示选择扩展名大小写对话框
 func (fo *FileOrganizer) showSelectExtensionCaseDialog() {
-	extensionCases := []string{"uppercase", "lowercase"}
 	caseSelect := widget.NewSelect(extensionCases, nil)
 	// 使用之前保存的扩展名大小写设置
 	caseSelect.SetSelected(fo.ExtensionCase)
@@ -675,52 +1764,1867 @@ func (fo *FileOrganizer) showSelectExtensionCaseDialog() {
 		fo.log(fmt.Sprintf("已选择扩展名大小写: %s", fo.ExtensionCase))
 		// 保存用户选择的扩展名大小写设置
 		fo.saveUserConfig()
+		fo.refreshFileTable()
+	})
+
+	fo.showDialog(dialog, caseSelect)
+}
+
+// 显示扫描结果浏览对话框（支持搜索和排除单个文件）
+func (fo *FileOrganizer) showScannedFilesDialog() {
+	if fo.streaming {
+		dialog.ShowInformation("流式整理", "扫描到的文件超过流式整理阈值，文件列表没有保留，无法浏览或排除单个文件。", fo.Window)
+		return
+	}
+	if len(fo.scannedFiles) == 0 {
+		dialog.ShowInformation("提示", "请先扫描文件", fo.Window)
+		return
+	}
+
+	headers := []string{"排除", "固定", "路径", "大小", "修改时间", "计划目标"}
+	fo.fileTableFilter = ""
+	fo.previewVolumePlan()
+	fo.previewCapacityPlan()
+	fo.fileTableStatus = widget.NewLabel("")
+
+	// 表格是虚拟化的，只会为可见行创建单元格，适合数万个文件的列表
+	fo.fileTable = widget.NewTableWithHeaders(
+		func() (int, int) {
+			return len(fo.fileTableFiltered), len(headers)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return container.NewStack(widget.NewCheck("", nil), label)
+		},
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			cell := o.(*fyne.Container)
+			check := cell.Objects[0].(*widget.Check)
+			label := cell.Objects[1].(*widget.Label)
+			if id.Row >= len(fo.fileTableFiltered) {
+				return
+			}
+			filePath := fo.fileTableFiltered[id.Row]
+
+			switch id.Col {
+			case 0:
+				label.Hide()
+				check.Show()
+				// 先清除回调，避免复用单元格时触发上一行的回调
+				check.OnChanged = nil
+				check.SetChecked(fo.excludedFiles[filePath])
+				check.OnChanged = func(checked bool) {
+					fo.setFileExcluded(filePath, checked)
+					fo.updateFileTableStatus()
+					fo.fileTable.RefreshItem(widget.TableCellID{Row: id.Row, Col: 5})
+				}
+				return
+			case 1:
+				// 固定的文件以后整理和监视时都会跳过，不只是本次整理
+				label.Hide()
+				check.Show()
+				check.OnChanged = nil
+				check.SetChecked(fo.pins.matches(filePath, nil))
+				check.OnChanged = func(checked bool) {
+					fo.setFilePinned(filePath, checked)
+				}
+				return
+			}
+
+			check.Hide()
+			label.Show()
+			info := fo.scannedFileInfos[filePath]
+			switch id.Col {
+			case 2:
+				label.SetText(fo.burstRowLabel(filePath))
+			case 3:
+				if info != nil {
+					label.SetText(formatFileSize(info.Size()))
+				} else {
+					label.SetText("-")
+				}
+			case 4:
+				if info != nil {
+					label.SetText(info.ModTime().Format("2006-01-02 15:04:05"))
+				} else {
+					label.SetText("-")
+				}
+			case 5:
+				label.SetText(fo.describePlannedTarget(filePath, info))
+			}
+		},
+	)
+	fo.fileTable.ShowHeaderColumn = false
+	fo.fileTable.CreateHeader = func() fyne.CanvasObject {
+		return widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	fo.fileTable.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		if id.Row == -1 && id.Col >= 0 && id.Col < len(headers) {
+			o.(*widget.Label).SetText(headers[id.Col])
+		}
+	}
+	// 键盘操作：在“排除”“固定”列按空格切换该行的状态；点击连拍序列的路径展开或折叠
+	fo.fileTable.OnSelected = func(id widget.TableCellID) {
+		fo.fileTable.Unselect(id)
+		if id.Row < 0 || id.Row >= len(fo.fileTableFiltered) {
+			return
+		}
+		filePath := fo.fileTableFiltered[id.Row]
+		switch id.Col {
+		case 0:
+			fo.setFileExcluded(filePath, !fo.excludedFiles[filePath])
+			fo.updateFileTableStatus()
+			fo.fileTable.RefreshItem(widget.TableCellID{Row: id.Row, Col: 0})
+			fo.fileTable.RefreshItem(widget.TableCellID{Row: id.Row, Col: 5})
+		case 1:
+			fo.setFilePinned(filePath, !fo.pins.matches(filePath, nil))
+		case 2:
+			fo.toggleBurst(filePath)
+		}
+	}
+	fo.fileTable.SetColumnWidth(0, 50)
+	fo.fileTable.SetColumnWidth(1, 50)
+	fo.fileTable.SetColumnWidth(2, 360)
+	fo.fileTable.SetColumnWidth(3, 90)
+	fo.fileTable.SetColumnWidth(4, 160)
+	fo.fileTable.SetColumnWidth(5, 260)
+
+	// 搜索框，输入停止300ms后再过滤，避免大列表上每次按键都重新过滤
+	var debounceTimer *time.Timer
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("输入文件名或路径中的关键字进行过滤")
+	searchEntry.OnChanged = func(text string) {
+		if debounceTimer != nil {
+			debounceTimer.Stop()
+		}
+		debounceTimer = time.AfterFunc(300*time.Millisecond, func() {
+			fo.safeUpdateUI(func() {
+				fo.fileTableFilter = text
+				fo.refreshFileTable()
+			})
+		})
+	}
+
+	// 固定当前过滤结果，以后整理和监视时都会跳过
+	pinAllBtn := widget.NewButton("固定当前结果", func() {
+		if len(fo.fileTableFiltered) == 0 {
+			return
+		}
+		var files []string
+		for _, filePath := range fo.fileTableFiltered {
+			if frames := fo.collapsedBurstFrames(filePath); frames != nil {
+				files = append(files, frames...)
+			} else {
+				files = append(files, filePath)
+			}
+		}
+		dialog.ShowConfirm("固定文件", fmt.Sprintf("固定当前显示的 %d 个文件？固定的文件以后不会被整理。", len(files)), func(confirm bool) {
+			if confirm {
+				fo.pinPaths(files)
+			}
+		}, fo.Window)
 	})
+	pinsBtn := widget.NewButton("固定列表...", func() {
+		fo.showPinsDialog()
+	})
+
+	// 查找带副本后缀的文件
+	copyAnalysisBtn := widget.NewButton("查找副本", func() {
+		fo.showCopyAnalysisDialog()
+	})
+
+	// 查找源文件夹中几乎相同的子文件夹
+	folderDuplicatesBtn := widget.NewButton("查找重复文件夹", func() {
+		fo.showFolderDuplicatesDialog()
+	})
+
+	// 按当前规则会用到的目标文件夹
+	plannedFoldersBtn := widget.NewButton("目标文件夹...", func() {
+		fo.showPlannedFoldersDialog()
+	})
+
+	// 批量排除/取消排除当前过滤结果
+	excludeAllBtn := widget.NewButton("排除当前结果", func() {
+		for _, filePath := range fo.fileTableFiltered {
+			fo.setFileExcluded(filePath, true)
+		}
+		fo.refreshFileTable()
+	})
+	includeAllBtn := widget.NewButton("取消排除当前结果", func() {
+		for _, filePath := range fo.fileTableFiltered {
+			fo.setFileExcluded(filePath, false)
+		}
+		fo.refreshFileTable()
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(searchEntry, fo.fileTableStatus),
+		container.NewVBox(
+			container.NewGridWithColumns(4, excludeAllBtn, includeAllBtn, copyAnalysisBtn, folderDuplicatesBtn),
+			container.NewGridWithColumns(3, pinAllBtn, pinsBtn, plannedFoldersBtn),
+		),
+		nil, nil,
+		fo.fileTable,
+	)
+
+	fileDialog := dialog.NewCustom("浏览扫描结果", "关闭", content, fo.Window)
+	fileDialog.Resize(fyne.NewSize(860, 600))
+	fileDialog.SetOnClosed(func() {
+		if debounceTimer != nil {
+			debounceTimer.Stop()
+		}
+		fo.fileTable = nil
+		fo.fileTableStatus = nil
+		fo.fileTableFiltered = nil
+		if len(fo.excludedFiles) > 0 {
+			fo.log(fmt.Sprintf("已排除 %d 个文件，它们不会在本次整理中被移动", len(fo.excludedFiles)))
+		}
+	})
+
+	fo.refreshFileTable()
+	fo.showDialog(fileDialog, searchEntry)
+}
+
+// 重新过滤并刷新扫描结果表格（需在UI线程中调用）
+func (fo *FileOrganizer) refreshFileTable() {
+	if fo.fileTable == nil {
+		return
+	}
+
+	// 扫描进行中时文件列表仍在变化，等扫描完成后再刷新
+	fo.fileTableFiltered = fo.fileTableFiltered[:0]
+	if !fo.isScanning.Load() {
+		keyword := strings.ToLower(strings.TrimSpace(fo.fileTableFilter))
+		for _, filePath := range fo.scannedFiles {
+			if keyword != "" {
+				if strings.Contains(strings.ToLower(filePath), keyword) {
+					fo.fileTableFiltered = append(fo.fileTableFiltered, filePath)
+				}
+				continue
+			}
+			// 连拍序列显示为一行，展开后其余照片跟在第一张后面
+			if anchor, ok := fo.bursts.anchor(filePath); ok {
+				if anchor == filePath {
+					fo.fileTableFiltered = append(fo.fileTableFiltered, filePath)
+					if fo.expandedBursts[filePath] {
+						fo.fileTableFiltered = append(fo.fileTableFiltered, fo.bursts.frames[filePath][1:]...)
+					}
+				}
+				continue
+			}
+			fo.fileTableFiltered = append(fo.fileTableFiltered, filePath)
+		}
+	}
+
+	fo.updateFileTableStatus()
+	fo.fileTable.Refresh()
+}
+
+// 更新扫描结果表格的统计信息
+func (fo *FileOrganizer) updateFileTableStatus() {
+	if fo.fileTableStatus == nil {
+		return
+	}
+	if fo.isScanning.Load() {
+		fo.fileTableStatus.SetText("正在扫描...")
+		return
+	}
+	fo.fileTableStatus.SetText(fmt.Sprintf("显示 %d / %d 个文件，已排除 %d 个",
+		len(fo.fileTableFiltered), len(fo.scannedFiles), len(fo.excludedFiles)))
+}
+
+// 描述文件在当前规则下的计划去向
+func (fo *FileOrganizer) describePlannedTarget(filePath string, info os.FileInfo) string {
+	if fo.excludedFiles[filePath] {
+		return "（已排除）"
+	}
+	if fo.pins.matches(filePath, nil) {
+		return "（已固定）"
+	}
+	if OrganizeRule(fo.RuleSelect.Selected) == RuleByHash {
+		return "（按内容哈希，整理时计算）"
+	}
+	if info == nil || len(fo.SourceDirs) == 0 {
+		return "-"
+	}
+
+	config := fo.currentConfig()
+	if len(config.FileExtensions) > 0 && !fo.isTargetFile(fileExtension(filePath), config.FileExtensions) {
+		return "（后缀未选择，不处理）"
+	}
+	// 按内容再分一层需要读取文件，列表中不读取，只显示已判断过的结果
+	textPending := false
+	if needsTextFolder(filePath, config) {
+		if _, ok := fo.textFolders.cached(filePath, config); !ok {
+			config.TextLanguageFolders, config.TextKeywordRules = false, nil
+			textPending = true
+		}
+	}
+	targetDir := fo.planTargetDir(filePath, info, config)
+	volume := ""
+	if config.VolumePlan != nil {
+		volume = volumeOf(targetDir, config.Volumes)
+	}
+	if isRefile(filePath, config) {
+		if filepath.Dir(filePath) == targetDir {
+			return "（已在正确位置）"
+		}
+		targetDir = "重新归档: " + targetDir
+	}
+	if OrganizeRule(config.OrganizeRule) == RuleByDate {
+		if label, ok := eventLabelFor(fo.fileDate(filePath, info, config), config.EventLabels); ok {
+			targetDir += fmt.Sprintf("（事件: %s）", label.Label)
+		}
+	}
+	if volume != "" && volume != config.TargetDir {
+		targetDir += fmt.Sprintf("（目标卷: %s）", volume)
+	}
+	if textPending {
+		targetDir += "（按内容再分一层，整理时判断）"
+	}
+	return targetDir
+}
+
+// 格式化文件大小
+func formatFileSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// 显示更多设置对话框
+func (fo *FileOrganizer) showSettingsDialog() {
+	// 多标签文件的处理方式
+	tagModes := map[string]string{
+		"按第一个标签":  MultiTagFirst,
+		"每个标签各一份": MultiTagDuplicate,
+	}
+	tagModeSelect := widget.NewSelect([]string{"按第一个标签", "每个标签各一份"}, nil)
+	for label, mode := range tagModes {
+		if mode == fo.MultiTagMode {
+			tagModeSelect.SetSelected(label)
+		}
+	}
+
+	// 规则文件夹与源文件夹结构的组合方式
+	layouts := map[string]string{
+		"扁平":    LayoutFlat,
+		"规则优先":  LayoutRuleFirst,
+		"源结构优先": LayoutSourceFirst,
+		"文件名前缀": LayoutNamePrefix,
+	}
+	layoutSelect := widget.NewSelect([]string{"扁平", "规则优先", "源结构优先", "文件名前缀"}, nil)
+	for label, layout := range layouts {
+		if layout == fo.FolderLayout {
+			layoutSelect.SetSelected(label)
+		}
+	}
+	layoutHint := widget.NewLabel("扁平: 目标/规则文件夹\n规则优先: 目标/规则文件夹/源子目录\n源结构优先: 目标/源子目录/规则文件夹\n文件名前缀: 目标/规则_原文件名，例如 2024-01-15_photo.jpg")
+
+	// 日期文件夹的修改时间
+	dateFolderMtimeCheck := widget.NewCheck("将日期文件夹的修改时间设为对应日期（仅按日期整理）", nil)
+	dateFolderMtimeCheck.SetChecked(fo.DateFolderMtime)
+	compactExtensionsEntry := widget.NewEntry()
+	compactExtensionsEntry.SetText(strconv.Itoa(fo.CompactExtensionsMin))
+	compactExtensionsEntry.Validator = func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 0 {
+			return errors.New("请输入非负整数，0表示不合并")
+		}
+		return nil
+	}
+	extensionRankCheck := widget.NewCheck("按文件数为后缀文件夹加序号前缀，例如 01_jpg（已分配的序号保持不变）", nil)
+	extensionRankCheck.SetChecked(fo.ExtensionRankPrefix)
+	rerankBtn := widget.NewButton("按文件数重新编号...", func() {
+		fo.showRerankExtensionsDialog()
+	})
+	// 组合规则的各层，第一层必选，后面的层可以为空
+	segmentOptions := []string{"（无）"}
+	for _, s := range ruleSegmentNames {
+		segmentOptions = append(segmentOptions, s.name)
+	}
+	segmentSelects := make([]fyne.CanvasObject, maxRuleSegments)
+	for i := range segmentSelects {
+		options := segmentOptions
+		if i == 0 {
+			options = segmentOptions[1:]
+		}
+		segmentSelect := widget.NewSelect(options, nil)
+		segmentSelect.SetSelected("（无）")
+		if i < len(fo.RuleSegments) {
+			segmentSelect.SetSelected(ruleSegmentName(fo.RuleSegments[i]))
+		}
+		segmentSelects[i] = segmentSelect
+	}
+	// 组合规则的分隔符，为空时每层一级文件夹，输入时预览效果
+	selectedSegments := func() []string {
+		var segments []string
+		for _, obj := range segmentSelects {
+			if segment, ok := ruleSegmentByName(obj.(*widget.Select).Selected); ok {
+				segments = append(segments, segment)
+			}
+		}
+		return segments
+	}
+	segmentSeparatorEntry := widget.NewEntry()
+	segmentSeparatorEntry.SetText(fo.RuleSegmentSeparator)
+	segmentSeparatorEntry.SetPlaceHolder("为空时每层一级文件夹，例如 \" - \" 得到 2024-01 - 图片")
+	segmentSeparatorEntry.Validator = validateRuleSegmentSeparator
+	segmentPreview := widget.NewLabel(fo.previewRuleSegments(selectedSegments(), fo.RuleSegmentSeparator, fo.currentConfig()))
+	segmentPreview.Wrapping = fyne.TextWrapWord
+	updateSegmentPreview := func() {
+		segmentPreview.SetText(fo.previewRuleSegments(selectedSegments(), segmentSeparatorEntry.Text, fo.currentConfig()))
+	}
+	segmentSeparatorEntry.OnChanged = func(string) { updateSegmentPreview() }
+	for _, obj := range segmentSelects {
+		obj.(*widget.Select).OnChanged = func(string) { updateSegmentPreview() }
+	}
+	emailSenderCheck := widget.NewCheck("邮件（.eml/.msg）先按发件人域名分文件夹，例如 example.com/2024-03", nil)
+	emailSenderCheck.SetChecked(fo.EmailSenderFolders)
+	packSmallCheck := widget.NewCheck("小文件放入日期文件夹对应的压缩包（例如 2024-03.zip），较大的文件照常移动", nil)
+	packSmallCheck.SetChecked(fo.PackSmallFiles)
+	packThresholdEntry := widget.NewEntry()
+	packThresholdEntry.SetText(strconv.Itoa(fo.PackThresholdKB))
+	packThresholdEntry.Validator = func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 1 {
+			return errors.New("请输入正整数")
+		}
+		return nil
+	}
+
+	// 按内容整理文本文件：关键词规则和语言在规则文件夹下再分一层
+	textLanguageCheck := widget.NewCheck("按识别出的语言再分一层，例如 2024-03/English", nil)
+	textLanguageCheck.SetChecked(fo.TextLanguageFolders)
+	textKeywordsEntry := widget.NewMultiLineEntry()
+	textKeywordsEntry.SetText(formatTextKeywordRules(fo.TextKeywordRules, "\n"))
+	textKeywordsEntry.SetPlaceHolder("发票=财务\ncontract=合同")
+	textKeywordsEntry.SetMinRowsVisible(3)
+	textKeywordsEntry.Validator = func(text string) error {
+		_, err := parseTextKeywordRules(text)
+		return err
+	}
+	textKeywordCaseCheck := widget.NewCheck("关键词区分大小写", nil)
+	textKeywordCaseCheck.SetChecked(fo.TextKeywordCase)
+	textPrefixEntry := widget.NewEntry()
+	textPrefixEntry.SetText(strconv.Itoa(fo.TextPrefixKB))
+	textPrefixEntry.Validator = func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 1 || n > maxTextPrefixKB {
+			return fmt.Errorf("请输入1到%d之间的整数", maxTextPrefixKB)
+		}
+		return nil
+	}
+	textRulesHint := widget.NewLabel("只读取 .txt/.md/.pdf/.docx 文件开头的文字。判断顺序：先按关键词规则从上到下检查，第一条匹配的规则生效；都不匹配时按语言；仍没有结果时直接放在规则文件夹中。二进制或无法读取的文件不再分层")
+	textRulesHint.Wrapping = fyne.TextWrapWord
+
+	// 连拍照片
+	burstCheck := widget.NewCheck("识别连拍照片，同一序列按第一张的日期放在一起", nil)
+	burstCheck.SetChecked(fo.BurstDetection)
+	burstGapEntry := widget.NewEntry()
+	burstGapEntry.SetText(strconv.Itoa(fo.BurstMaxGap))
+	burstGapEntry.Validator = func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 1 {
+			return errors.New("请输入正整数")
+		}
+		return nil
+	}
+	burstFramesEntry := widget.NewEntry()
+	burstFramesEntry.SetText(strconv.Itoa(fo.BurstMaxFrames))
+	burstFramesEntry.Validator = func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 2 {
+			return errors.New("请输入不小于2的整数")
+		}
+		return nil
+	}
+
+	// 日期来源顺序
+	dateSourcesEntry := widget.NewEntry()
+	dateSourcesEntry.SetText(formatDateSources(fo.DateSources))
+	dateSourcesEntry.SetPlaceHolder("exif,filename,sidecar,email,created,mtime")
+	dateSourcesEntry.Validator = func(text string) error {
+		_, err := parseDateSources(text)
+		return err
+	}
+	dateSourcesHint := widget.NewLabel("按顺序尝试: exif=EXIF拍摄日期, filename=文件名中的日期, sidecar=XMP/JSON附属文件, email=邮件的Date头, created=创建时间, mtime=修改时间")
+	dateSourcesHint.Wrapping = fyne.TextWrapWord
+
+	// 划分日期使用的时区
+	dateZoneEntry := widget.NewSelectEntry(commonDateTimeZones)
+	dateZoneEntry.SetText(fo.DateTimeZone)
+	dateZoneEntry.SetPlaceHolder("留空或 Local 使用本机时区（" + time.Local.String() + "）")
+	dateZoneEntry.Validator = func(text string) error {
+		_, err := loadDateLocation(strings.TrimSpace(text))
+		return err
+	}
+	dateZoneHint := widget.NewLabel("修改时间等带时区的时间换算到这个时区后划分日期；EXIF、文件名中的日期以及U盘、存储卡（FAT/exFAT）上的修改时间往往没有时区，按写入时的墙上时间划分")
+	dateZoneHint.Wrapping = fyne.TextWrapWord
+
+	// 当前规则的文件夹名称模板，输入时预览效果
+	templateRule := OrganizeRule(fo.RuleSelect.Selected)
+	folderTemplateEntry := widget.NewEntry()
+	folderTemplateEntry.SetText(fo.FolderTemplates[string(templateRule)])
+	folderTemplateEntry.SetPlaceHolder("例如 照片-{year} 或 {category}/{ext}文件")
+	folderTemplateEntry.Validator = validateFolderTemplate
+	folderTemplatePreview := widget.NewLabel(fo.previewFolderTemplate(folderTemplateEntry.Text, fo.currentConfig()))
+	folderTemplatePreview.Wrapping = fyne.TextWrapWord
+	folderTemplateEntry.OnChanged = func(text string) {
+		folderTemplatePreview.SetText(fo.previewFolderTemplate(text, fo.currentConfig()))
+	}
+	folderTemplateHintLabel := widget.NewLabel(folderTemplateHint)
+	folderTemplateHintLabel.Wrapping = fyne.TextWrapWord
+	if templateRule == RuleByHash || templateRule == "" {
+		folderTemplateEntry.Disable()
+		folderTemplatePreview.SetText("按内容哈希整理时使用哈希分片，不使用模板")
+		if templateRule == "" {
+			folderTemplatePreview.SetText("请先选择整理规则")
+		}
+	}
+
+	// 年龄分组的文件夹名称
+	ageLabelEntries := make([]fyne.CanvasObject, ageBucketCount)
+	for i := 0; i < ageBucketCount; i++ {
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder(defaultAgeBucketLabels[i])
+		entry.SetText(ageBucketLabel(i, fo.AgeBucketLabels))
+		ageLabelEntries[i] = entry
+	}
+
+	// 按类别和大小整理时大小分组的上限
+	sizeBucketsEntry := widget.NewEntry()
+	sizeBucketsEntry.SetText(formatSizeBuckets(fo.SizeBucketsMB))
+	sizeBucketsEntry.SetPlaceHolder(formatSizeBuckets(defaultSizeBucketsMB))
+	sizeBucketsEntry.Validator = func(text string) error {
+		_, err := parseSizeBuckets(text)
+		return err
+	}
+
+	// 事件标签
+	eventLabelsBtn := widget.NewButton("编辑事件标签...", func() {
+		fo.showEventLabelsDialog()
+	})
+
+	// 多个目标卷
+	targetVolumesBtn := widget.NewButton("溢出目标和保留空间...", func() {
+		fo.showTargetVolumesDialog()
+	})
+
+	// 按容量分卷
+	volumeCapEntry := widget.NewEntry()
+	volumeCapEntry.SetText(strconv.FormatInt(fo.VolumeCapMB, 10))
+	volumeCapEntry.Validator = func(text string) error {
+		if n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64); err != nil || n < 0 {
+			return errors.New("请输入非负整数（MB），0表示不分卷")
+		}
+		return nil
+	}
+	volumeCapHint := widget.NewLabel(fmt.Sprintf("文件按日期依次放入 %s、%s……，每卷不超过设置的容量，已有的分卷先补满；"+
+		"同名不同后缀的文件（例如 RAW+JPG、附属文件）放在同一卷。25GB 蓝光盘约为 23800 MB",
+		capacityVolumeName(1), capacityVolumeName(2)))
+	volumeCapHint.Wrapping = fyne.TextWrapWord
+
+	// 扫描缓存
+	forceFullScanCheck := widget.NewCheck("强制完全扫描（不使用扫描缓存）", nil)
+	forceFullScanCheck.SetChecked(fo.ForceFullScan)
+
+	// 后缀检查
+	validateExtensionsCheck := widget.NewCheck("整理前检查所选后缀是否出现在扫描结果中", nil)
+	validateExtensionsCheck.SetChecked(fo.ValidateExtensions)
+	dangerousTargetCheck := widget.NewCheck("目标是磁盘根目录、主目录或系统文件夹时要求确认", nil)
+	dangerousTargetCheck.SetChecked(fo.CheckDangerousTarget)
+
+	// 并行处理
+	positiveInt := func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 1 {
+			return errors.New("请输入正整数")
+		}
+		return nil
+	}
+	parallelThresholdEntry := widget.NewEntry()
+	parallelThresholdEntry.SetText(strconv.Itoa(fo.ParallelThreshold))
+	parallelThresholdEntry.Validator = positiveInt
+	smallSetWorkersEntry := widget.NewEntry()
+	smallSetWorkersEntry.SetText(strconv.Itoa(fo.SmallSetWorkers))
+	smallSetWorkersEntry.Validator = positiveInt
+	scanConcurrencyEntry := widget.NewEntry()
+	scanConcurrencyEntry.SetText(strconv.Itoa(fo.ScanConcurrency))
+	scanConcurrencyEntry.Validator = positiveInt
+	scanErrorLimitEntry := widget.NewEntry()
+	scanErrorLimitEntry.SetText(strconv.Itoa(fo.ScanErrorLimit))
+	scanErrorLimitEntry.Validator = func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 0 {
+			return errors.New("请输入非负整数，0表示不限制")
+		}
+		return nil
+	}
+	planStaleEntry := widget.NewEntry()
+	planStaleEntry.SetText(strconv.Itoa(fo.PlanStaleMinutes))
+	planStaleEntry.Validator = func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 0 {
+			return errors.New("请输入非负整数，0表示不检查")
+		}
+		return nil
+	}
+	streamingThresholdEntry := widget.NewEntry()
+	streamingThresholdEntry.SetText(strconv.Itoa(fo.StreamingThreshold))
+	streamingThresholdEntry.Validator = func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 0 {
+			return errors.New("请输入非负整数，0表示不使用流式整理")
+		}
+		return nil
+	}
+
+	// 等效命令行
+	logCLICommandCheck := widget.NewCheck("整理开始时在日志中记录等效命令", nil)
+	logCLICommandCheck.SetChecked(fo.LogCLICommand)
+
+	// 日志详细程度
+	logVerbosities := map[string]string{
+		"精简（不列出跳过的文件）": LogQuiet,
+		"正常":           LogNormal,
+		"详细（附带源路径、大小和日期来源）": LogVerbose,
+	}
+	logVerbositySelect := widget.NewSelect([]string{"精简（不列出跳过的文件）", "正常", "详细（附带源路径、大小和日期来源）"}, nil)
+	for label, verbosity := range logVerbosities {
+		if verbosity == fo.LogVerbosity {
+			logVerbositySelect.SetSelected(label)
+		}
+	}
+
+	// 目标去重
+	dedupTargetCheck := widget.NewCheck("目标文件夹中已有内容相同的文件时跳过移动", nil)
+	dedupTargetCheck.SetChecked(fo.DedupTarget)
+	dedupEmptyFilesCheck := widget.NewCheck("把不同的空文件也视为相同内容（谨慎开启）", nil)
+	dedupEmptyFilesCheck.SetChecked(fo.DedupEmptyFiles)
+	indexRateEntry := widget.NewEntry()
+	indexRateEntry.SetText(strconv.Itoa(fo.TargetIndexRateMB))
+	indexRateEntry.Validator = func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 0 {
+			return errors.New("请输入非负整数，0表示不限速")
+		}
+		return nil
+	}
+	targetIndexBtn := widget.NewButton("目标文件索引...", fo.showTargetIndexDialog)
+
+	// 空文件的处理方式
+	emptyFilePolicies := map[string]string{
+		"正常整理":                       EmptyFileOrganize,
+		"跳过并报告":                      EmptyFileSkip,
+		"移到 " + EmptyFilesFolderName: EmptyFileQuarantine,
+	}
+	emptyFileSelect := widget.NewSelect([]string{"正常整理", "跳过并报告", "移到 " + EmptyFilesFolderName}, nil)
+	for label, policy := range emptyFilePolicies {
+		if policy == fo.EmptyFilePolicy {
+			emptyFileSelect.SetSelected(label)
+		}
+	}
+
+	// 快捷方式的处理方式
+	shortcutPolicies := map[string]string{
+		"正常整理":    ShortcutOrganize,
+		"跳过，留在原处": ShortcutSkip,
+		"整理指向的文件，网址放入「" + LinksFolderName + "」": ShortcutResolve,
+	}
+	shortcutSelect := widget.NewSelect([]string{"正常整理", "跳过，留在原处", "整理指向的文件，网址放入「" + LinksFolderName + "」"}, nil)
+	for label, policy := range shortcutPolicies {
+		if policy == fo.ShortcutPolicy {
+			shortcutSelect.SetSelected(label)
+		}
+	}
+	shortcutHint := widget.NewLabel("整理指向的文件时快捷方式留在原处，.desktop 和 .webloc 会更新为新位置，Windows快捷方式不更新；无法解析的快捷方式跳过并在日志中列出")
+	shortcutHint.Wrapping = fyne.TextWrapWord
+
+	// 程序包（.app 等）作为一个文件整理，图库默认跳过
+	organizeLibrariesCheck := widget.NewCheck("也整理照片、音乐等程序的图库（.photoslibrary/.musiclibrary，移动后需要在程序中重新打开）", nil)
+	organizeLibrariesCheck.SetChecked(fo.OrganizeLibraries)
+
+	// 目标中已有同名文件
+	conflictSelect := widget.NewSelect([]string{"加时间戳，保留两个文件", "覆盖已有文件", "合并：内容相同时跳过，不同时加时间戳"}, nil)
+	switch fo.ConflictPolicy {
+	case ConflictOverwrite:
+		conflictSelect.SetSelectedIndex(1)
+	case ConflictMerge:
+		conflictSelect.SetSelectedIndex(2)
+	default:
+		conflictSelect.SetSelectedIndex(0)
+	}
+	backupReplacedCheck := widget.NewCheck("覆盖前备份（移到目标的「"+ReplacedFolderName+"」文件夹，撤销时一起恢复）", nil)
+	backupReplacedCheck.SetChecked(fo.BackupReplaced)
+	conflictSelect.OnChanged = func(string) {
+		if conflictSelect.SelectedIndex() == 1 {
+			backupReplacedCheck.Enable()
+		} else {
+			backupReplacedCheck.Disable()
+		}
+	}
+	conflictSelect.OnChanged(conflictSelect.Selected)
+	replacedCleanupBtn := widget.NewButton("清理覆盖备份...", fo.showReplacedCleanupDialog)
+	orphanCleanupBtn := widget.NewButton("清理残留文件...", fo.showOrphanCleanupDialog)
+
+	// 空文件夹
+	removeEmptiedDirsCheck := widget.NewCheck("删除整理后变空的源子文件夹", nil)
+	removeEmptiedDirsCheck.SetChecked(fo.RemoveEmptiedDirs)
+	moveEmptyDirsCheck := widget.NewCheck("将源文件夹第一层的空文件夹移到目标的「"+EmptyDirsFolderName+"」中", nil)
+	moveEmptyDirsCheck.SetChecked(fo.MoveEmptyDirs)
+
+	// 扩展属性，逐个读写会拖慢跨磁盘移动，默认关闭
+	preserveXattrsCheck := widget.NewCheck("跨磁盘移动时保留扩展属性和备用数据流", nil)
+	preserveXattrsCheck.SetChecked(fo.PreserveXattrs)
+	if !extendedAttrsSupported {
+		preserveXattrsCheck.Disable()
+	}
+
+	// 后台低优先级，适合定时整理和监视模式
+	lowPriorityCheck := widget.NewCheck("后台低优先级（整理期间降低CPU和磁盘优先级，减少对其他程序的影响）", nil)
+	lowPriorityCheck.SetChecked(fo.LowPriority)
+
+	// 仅处理新增，适合多人放入文件的共享文件夹
+	incomingOnlyCheck := widget.NewCheck("仅处理新增（跳过上次成功整理时已在源文件夹中的文件，与修改时间无关）", nil)
+	incomingOnlyCheck.SetChecked(fo.IncomingOnly)
+	incomingOnlyHint := widget.NewLabel(describeIncomingStore(fo.SourceDirs) + "。监视模式只处理新出现的文件，不使用此设置")
+	incomingOnlyHint.Wrapping = fyne.TextWrapWord
+	clearIncomingBtn := widget.NewButton("清除记录", func() {
+		dialog.ShowConfirm("清除新增记录", "清除后，下次整理时源文件夹中的所有文件都按新增文件处理。确定清除吗？", func(ok bool) {
+			if !ok {
+				return
+			}
+			cleared, err := clearIncomingStore(fo.SourceDirs)
+			if err != nil {
+				dialog.ShowError(err, fo.Window)
+				return
+			}
+			fo.log(fmt.Sprintf("已清除新增记录中的 %d 个文件", cleared))
+			incomingOnlyHint.SetText(describeIncomingStore(fo.SourceDirs) + "。监视模式只处理新出现的文件，不使用此设置")
+		}, fo.Window)
+	})
+
+	// HEIC转换，需要外部转换程序
+	convertImagesCheck := widget.NewCheck("整理时将HEIC/HEIF转换为JPEG（保留EXIF，转换失败时按原样整理）", nil)
+	convertImagesCheck.SetChecked(fo.ConvertImages)
+	keepConvertedCheck := widget.NewCheck("转换后保留原始文件（放入目标的 "+ConvertedOriginalsFolderName+" 文件夹）", nil)
+	keepConvertedCheck.SetChecked(fo.KeepConverted)
+	converterHint := widget.NewLabel("")
+	if fo.imageConverter != nil {
+		converterHint.SetText("转换程序: " + fo.imageConverter.path)
+	} else {
+		converterHint.SetText("未找到转换程序，请安装 heif-convert（libheif）或 ImageMagick 后重新打开程序")
+		convertImagesCheck.Disable()
+	}
+	converterHint.Wrapping = fyne.TextWrapWord
+
+	// 副本
+	copyMergeModes := map[string]string{
+		"不处理":                         CopyMergeOff,
+		"隔离到 " + DuplicatesFolderName: CopyMergeQuarantine,
+		"删除":                          CopyMergeDelete,
+	}
+	copyMergeSelect := widget.NewSelect([]string{"不处理", "隔离到 " + DuplicatesFolderName, "删除"}, nil)
+	for label, mode := range copyMergeModes {
+		if mode == fo.CopyMerge {
+			copyMergeSelect.SetSelected(label)
+		}
+	}
+	copyPatternsEntry := widget.NewMultiLineEntry()
+	copyPatternsEntry.SetText(strings.Join(fo.CopySuffixPatterns, "\n"))
+	copyPatternsEntry.SetMinRowsVisible(3)
+	copyPatternsEntry.Validator = func(text string) error {
+		_, err := compileCopyPatterns(strings.Split(text, "\n"))
+		return err
+	}
+	copyPatternsHint := widget.NewLabel("每行一个正则表达式，作用于去掉扩展名的文件名，第一个捕获组是原文件名。只有同一文件夹中有原文件名的文件时才算副本，整理前只处理内容完全相同的副本")
+	copyPatternsHint.Wrapping = fyne.TextWrapWord
+
+	// Google 相册导出
+	takeoutCheck := widget.NewCheck("整理 Google 相册导出（Takeout）的照片", nil)
+	takeoutCheck.SetChecked(fo.TakeoutMode)
+	takeoutHint := widget.NewLabel("照片的 .json 元数据文件随照片移动并改名为「照片名.json」，日期优先使用元数据中的拍摄时间；" +
+		"内容相同的「(1)」副本连同元数据文件隔离到 " + DuplicatesFolderName + "（副本合并设为删除时删除），内容不同的同名照片都保留")
+	takeoutHint.Wrapping = fyne.TextWrapWord
+
+	// 统计上报
+	statsEndpointEntry := widget.NewEntry()
+	statsEndpointEntry.SetPlaceHolder("https://example.com/stats（留空不发送）")
+	statsEndpointEntry.SetText(fo.StatsEndpoint)
+	statsEndpointEntry.Validator = func(text string) error {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil
+		}
+		if u, err := url.Parse(text); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("请输入 http:// 或 https:// 开头的地址")
+		}
+		return nil
+	}
+	statsTokenEntry := widget.NewPasswordEntry()
+	statsTokenEntry.SetPlaceHolder("Bearer令牌（可选，不保存，也可用环境变量 " + statsTokenEnvVar + "）")
+	statsTokenEntry.SetText(fo.StatsToken)
+
+	// 状态页面
+	statusAddrEntry := widget.NewEntry()
+	statusAddrEntry.SetPlaceHolder(":8383（留空不开启）")
+	statusAddrEntry.SetText(fo.StatusAddr)
+	statusAddrEntry.Validator = func(text string) error {
+		return validateStatusAddr(strings.TrimSpace(text))
+	}
+	statusTokenEntry := widget.NewPasswordEntry()
+	statusTokenEntry.SetPlaceHolder("访问密码（可选，用户名任意）")
+	statusTokenEntry.SetText(fo.StatusToken)
+	statusHint := widget.NewLabel("在手机等设备的浏览器中查看整理进度，页面只读，不能控制整理。监听所有地址（例如 :8383）时同一网络中的设备都能访问，建议设置访问密码")
+	statusHint.Wrapping = fyne.TextWrapWord
+
+	// 整理后运行的命令，默认关闭，开启时提示风险
+	hooksCheck := widget.NewCheck("整理后运行命令（高级）", nil)
+	hooksCheck.SetChecked(fo.HooksEnabled)
+	hooksCheck.OnChanged = func(checked bool) {
+		if !checked || fo.HooksEnabled {
+			return
+		}
+		dialog.ShowConfirm("运行命令", "命令以当前用户的权限通过系统shell运行，可以读取、修改和删除任何文件。\n\n只填写你自己编写或完全信任的命令。确定开启吗？",
+			func(ok bool) {
+				if !ok {
+					hooksCheck.SetChecked(false)
+				}
+			}, fo.Window)
+	}
+	postRunHookEntry := widget.NewEntry()
+	postRunHookEntry.SetPlaceHolder("例如 rsync -a {target} /backup/（留空不运行）")
+	postRunHookEntry.SetText(fo.PostRunHook)
+	postRunHookEntry.Validator = func(text string) error {
+		return validateHookCommand(text, runHookTokens)
+	}
+	perFileHookEntry := widget.NewEntry()
+	perFileHookEntry.SetPlaceHolder("例如 xattr -w organized 1 {path}（留空不运行）")
+	perFileHookEntry.SetText(fo.PerFileHook)
+	perFileHookEntry.Validator = func(text string) error {
+		return validateHookCommand(text, fileHookTokens)
+	}
+	hookTimeoutEntry := widget.NewEntry()
+	hookTimeoutEntry.SetText(strconv.Itoa(fo.HookTimeoutSec))
+	hookTimeoutEntry.Validator = positiveInt
+	hooksHint := widget.NewLabel("警告: 命令以当前用户的权限运行。整理后命令只在没有失败和中止的文件时运行，可用 " + strings.Join(runHookTokens, " ") +
+		"；文件命令在每个文件整理后运行，可用 " + strings.Join(fileHookTokens, " ") + "。变量的值通过环境变量传给命令（例如 {path} 是 FILEORGANIZER_PATH），不需要另加引号，命令的输出写入日志")
+	hooksHint.Wrapping = fyne.TextWrapWord
+
+	// 校验清单
+	checksumAlgorithms := map[string]string{
+		"不导出":     ChecksumNone,
+		"MD5":     ChecksumMD5,
+		"SHA-1":   ChecksumSHA1,
+		"SHA-256": ChecksumSHA256,
+	}
+	checksumSelect := widget.NewSelect([]string{"不导出", "MD5", "SHA-1", "SHA-256"}, nil)
+	for label, algorithm := range checksumAlgorithms {
+		if algorithm == fo.ChecksumAlgorithm {
+			checksumSelect.SetSelected(label)
+		}
+	}
+	checksumHint := widget.NewLabel("整理完成后在目标文件夹中生成 checksums_时间.算法 文件，可用 sha256sum -c 等命令校验")
+	checksumHint.Wrapping = fyne.TextWrapWord
+
+	// 源快照
+	snapshotEntry := widget.NewEntry()
+	snapshotEntry.SetText(fo.SourceSnapshot)
+	snapshotEntry.SetPlaceHolder("留空不记录")
+	// 只选择快照所在的文件夹：保存对话框会创建并清空选中的文件，已有的快照会丢失
+	snapshotBrowseBtn := widget.NewButton("选择...", func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, fo.Window)
+				return
+			}
+			if dir == nil {
+				return
+			}
+			name := "source_snapshot.jsonl"
+			if current := strings.TrimSpace(snapshotEntry.Text); current != "" {
+				name = filepath.Base(current)
+			}
+			snapshotEntry.SetText(filepath.Join(dir.Path(), name))
+		}, fo.Window)
+	})
+	snapshotVerifyBtn := widget.NewButton("核对...", func() {
+		fo.showSnapshotVerifyDialog(strings.TrimSpace(snapshotEntry.Text))
+	})
+	snapshotHint := widget.NewLabel("整理前把待整理的每个文件的路径、大小和SHA-256追加到这个文件，不用撤销也能核对文件是否丢失、找到每个文件现在的位置")
+	snapshotHint.Wrapping = fyne.TextWrapWord
+
+	// 定时整理
+	scheduleIntervalEntry := widget.NewEntry()
+	scheduleIntervalEntry.SetText(strconv.Itoa(fo.ScheduleInterval))
+	scheduleIntervalEntry.Validator = func(text string) error {
+		if n, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || n < 0 {
+			return errors.New("请输入非负整数，0表示关闭")
+		}
+		return nil
+	}
+	hourOptions := make([]string, 24)
+	for h := range hourOptions {
+		hourOptions[h] = fmt.Sprintf("%02d:00", h)
+	}
+	scheduleStartSelect := widget.NewSelect(hourOptions, nil)
+	scheduleStartSelect.SetSelectedIndex(fo.ScheduleStartHour)
+	scheduleEndSelect := widget.NewSelect(hourOptions, nil)
+	scheduleEndSelect.SetSelectedIndex(fo.ScheduleEndHour)
+	digestOptions := append([]string{"不显示"}, hourOptions...)
+	digestSelect := widget.NewSelect(digestOptions, nil)
+	digestSelect.SetSelectedIndex(fo.DigestHour + 1)
+	scheduleHint := widget.NewLabel("按当前设置定时整理所有源文件夹。只在允许的时段内整理，例如 22:00 到 06:00 只在夜间整理；开始和结束相同表示全天")
+	scheduleHint.Wrapping = fyne.TextWrapWord
+
+	// 相机存储卡导入
+	cardDetectionCheck := widget.NewCheck("插入相机存储卡时提示导入", nil)
+	cardDetectionCheck.SetChecked(fo.CardDetection)
+	cardTargetEntry := widget.NewEntry()
+	cardTargetEntry.SetPlaceHolder("导入时选择")
+	cardTargetEntry.SetText(fo.CardImportTarget)
+	cardTargetEntry.Validator = func(text string) error {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return validateTargetDir(strings.TrimSpace(text))
+	}
+	cardCleanupCheck := widget.NewCheck("校验通过后默认清理存储卡", nil)
+	cardCleanupCheck.SetChecked(fo.CardCleanup)
+
+	// 文件名Unicode规范化
+	normalizationForms := map[string]string{
+		"不处理":                  NormalizationNone,
+		"NFC（Windows/Linux常用）": NormalizationNFC,
+		"NFD（macOS常用）":         NormalizationNFD,
+	}
+	normalizationSelect := widget.NewSelect([]string{"不处理", "NFC（Windows/Linux常用）", "NFD（macOS常用）"}, nil)
+	for label, form := range normalizationForms {
+		if form == fo.UnicodeNormalization {
+			normalizationSelect.SetSelected(label)
+		}
+	}
+
+	// 重名文件的时间戳
+	var collisionSeparatorOptions []string
+	for _, s := range collisionSeparatorNames {
+		collisionSeparatorOptions = append(collisionSeparatorOptions, s.name)
+	}
+	collisionSeparatorSelect := widget.NewSelect(collisionSeparatorOptions, nil)
+	for i, s := range collisionSeparatorNames {
+		if s.separator == fo.CollisionSeparator {
+			collisionSeparatorSelect.SetSelectedIndex(i)
+		}
+	}
+	collisionPrefixCheck := widget.NewCheck("时间戳放在文件名前面", nil)
+	collisionPrefixCheck.SetChecked(fo.CollisionPosition == CollisionPrefix)
+
+	// 按内容哈希整理
+	hashShardDepthEntry := widget.NewEntry()
+	hashShardDepthEntry.SetText(strconv.Itoa(fo.HashShardDepth))
+	hashShardDepthEntry.Validator = func(text string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil {
+			return errors.New("请输入整数")
+		}
+		return validateHashShards(n, 1)
+	}
+	hashShardWidthEntry := widget.NewEntry()
+	hashShardWidthEntry.SetText(strconv.Itoa(fo.HashShardWidth))
+	hashShardWidthEntry.Validator = func(text string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil {
+			return errors.New("请输入整数")
+		}
+		return validateHashShards(1, n)
+	}
+	hashRenameCheck := widget.NewCheck("把文件改名为哈希值（内容相同的文件只保留一份）", nil)
+	hashRenameCheck.SetChecked(fo.HashRename)
+
+	// 整理目录
+	catalogCheck := widget.NewCheck("记录每个文件的原路径和最终路径，可在「搜索目录」中查找；撤销文件也依赖这些记录", nil)
+	catalogCheck.SetChecked(fo.CatalogEnabled)
+
+	// 窗口大小
+	windowResizableCheck := widget.NewCheck("允许调整窗口大小（关闭后固定为 880×745）", nil)
+	windowResizableCheck.SetChecked(fo.WindowResizable)
+
+	form := widget.NewForm(
+		widget.NewFormItem("目录结构", layoutSelect),
+		widget.NewFormItem("", layoutHint),
+		widget.NewFormItem("多标签文件", tagModeSelect),
+		widget.NewFormItem("日期文件夹", dateFolderMtimeCheck),
+		widget.NewFormItem("", emailSenderCheck),
+		widget.NewFormItem("打包小文件", packSmallCheck),
+		widget.NewFormItem("小于（KB）", packThresholdEntry),
+		widget.NewFormItem("文本内容（.txt/.md/.pdf/.docx）", textLanguageCheck),
+		widget.NewFormItem("关键词规则（关键词=文件夹，每行一条）", textKeywordsEntry),
+		widget.NewFormItem("", textKeywordCaseCheck),
+		widget.NewFormItem("读取文件开头（KB）", textPrefixEntry),
+		widget.NewFormItem("", textRulesHint),
+		widget.NewFormItem("合并少见后缀（少于N个文件，0不合并）", compactExtensionsEntry),
+		widget.NewFormItem("后缀文件夹序号", extensionRankCheck),
+		widget.NewFormItem("", container.NewHBox(rerankBtn)),
+		widget.NewFormItem("组合规则（第1层 / 第2层 / 第3层）", container.NewGridWithColumns(maxRuleSegments, segmentSelects...)),
+		widget.NewFormItem("组合规则连接成一级文件夹的分隔符", segmentSeparatorEntry),
+		widget.NewFormItem("", segmentPreview),
+		widget.NewFormItem("连拍", burstCheck),
+		widget.NewFormItem("连拍间隔（秒）× 最多张数", container.NewGridWithColumns(2, burstGapEntry, burstFramesEntry)),
+		widget.NewFormItem("文件夹名称模板", folderTemplateEntry),
+		widget.NewFormItem("", folderTemplatePreview),
+		widget.NewFormItem("", folderTemplateHintLabel),
+		widget.NewFormItem("日期来源", dateSourcesEntry),
+		widget.NewFormItem("", dateSourcesHint),
+		widget.NewFormItem("日期时区", dateZoneEntry),
+		widget.NewFormItem("", dateZoneHint),
+		widget.NewFormItem("事件标签", eventLabelsBtn),
+		widget.NewFormItem("多个目标卷", targetVolumesBtn),
+		widget.NewFormItem("按容量分卷（每卷MB，0不分卷）", volumeCapEntry),
+		widget.NewFormItem("", volumeCapHint),
+		widget.NewFormItem("年龄分组名称", container.NewGridWithColumns(ageBucketCount, ageLabelEntries...)),
+		widget.NewFormItem("大小分组上限（MB，从小到大）", sizeBucketsEntry),
+		widget.NewFormItem("目标去重", dedupTargetCheck),
+		widget.NewFormItem("", dedupEmptyFilesCheck),
+		widget.NewFormItem("建立索引的读取速度上限（MB/秒，0不限速）", indexRateEntry),
+		widget.NewFormItem("", targetIndexBtn),
+		widget.NewFormItem("空文件（0字节）", emptyFileSelect),
+		widget.NewFormItem("快捷方式（.lnk/.desktop/.webloc）", shortcutSelect),
+		widget.NewFormItem("", shortcutHint),
+		widget.NewFormItem("程序包（.app 等按一个文件整理）", organizeLibrariesCheck),
+		widget.NewFormItem("目标中已有同名文件", conflictSelect),
+		widget.NewFormItem("", backupReplacedCheck),
+		widget.NewFormItem("", replacedCleanupBtn),
+		widget.NewFormItem("", orphanCleanupBtn),
+		widget.NewFormItem("空文件夹", removeEmptiedDirsCheck),
+		widget.NewFormItem("", moveEmptyDirsCheck),
+		widget.NewFormItem("扩展属性", preserveXattrsCheck),
+		widget.NewFormItem("优先级", lowPriorityCheck),
+		widget.NewFormItem("新增文件", incomingOnlyCheck),
+		widget.NewFormItem("", container.NewBorder(nil, nil, nil, clearIncomingBtn, incomingOnlyHint)),
+		widget.NewFormItem("图片转换", convertImagesCheck),
+		widget.NewFormItem("", keepConvertedCheck),
+		widget.NewFormItem("", converterHint),
+		widget.NewFormItem("整理前合并副本", copyMergeSelect),
+		widget.NewFormItem("副本文件名规则", copyPatternsEntry),
+		widget.NewFormItem("", copyPatternsHint),
+		widget.NewFormItem("Google 相册导出", takeoutCheck),
+		widget.NewFormItem("", takeoutHint),
+		widget.NewFormItem("统计上报地址", statsEndpointEntry),
+		widget.NewFormItem("统计上报令牌", statsTokenEntry),
+		widget.NewFormItem("状态页面地址", statusAddrEntry),
+		widget.NewFormItem("状态页面密码", statusTokenEntry),
+		widget.NewFormItem("", statusHint),
+		widget.NewFormItem("整理后命令", hooksCheck),
+		widget.NewFormItem("整理成功后运行", postRunHookEntry),
+		widget.NewFormItem("每个文件整理后运行", perFileHookEntry),
+		widget.NewFormItem("命令超时（秒）", hookTimeoutEntry),
+		widget.NewFormItem("", hooksHint),
+		widget.NewFormItem("内容哈希分片（层数 × 字符数）", container.NewGridWithColumns(2, hashShardDepthEntry, hashShardWidthEntry)),
+		widget.NewFormItem("", hashRenameCheck),
+		widget.NewFormItem("整理目录", catalogCheck),
+		widget.NewFormItem("校验清单", checksumSelect),
+		widget.NewFormItem("", checksumHint),
+		widget.NewFormItem("源快照", container.NewBorder(nil, nil, nil, container.NewHBox(snapshotBrowseBtn, snapshotVerifyBtn), snapshotEntry)),
+		widget.NewFormItem("", snapshotHint),
+		widget.NewFormItem("扫描", forceFullScanCheck),
+		widget.NewFormItem("", validateExtensionsCheck),
+		widget.NewFormItem("目标检查", dangerousTargetCheck),
+		widget.NewFormItem("同时扫描的文件夹数", scanConcurrencyEntry),
+		widget.NewFormItem("扫描错误上限（0不限制）", scanErrorLimitEntry),
+		widget.NewFormItem("执行前检查（分钟，0不检查）", planStaleEntry),
+		widget.NewFormItem("流式整理阈值（文件数，0不使用）", streamingThresholdEntry),
+		widget.NewFormItem("并行阈值（文件数）", parallelThresholdEntry),
+		widget.NewFormItem("少量文件工作协程数", smallSetWorkersEntry),
+		widget.NewFormItem("日志详细程度", logVerbositySelect),
+		widget.NewFormItem("命令行", logCLICommandCheck),
+		widget.NewFormItem("定时整理（分钟，0关闭）", scheduleIntervalEntry),
+		widget.NewFormItem("今日摘要显示时间", digestSelect),
+		widget.NewFormItem("允许的时段（开始 × 结束）", container.NewGridWithColumns(2, scheduleStartSelect, scheduleEndSelect)),
+		widget.NewFormItem("", scheduleHint),
+		widget.NewFormItem("相机存储卡", cardDetectionCheck),
+		widget.NewFormItem("存储卡导入目标", cardTargetEntry),
+		widget.NewFormItem("", cardCleanupCheck),
+		widget.NewFormItem("文件名规范化", normalizationSelect),
+		widget.NewFormItem("重名文件加时间戳", collisionSeparatorSelect),
+		widget.NewFormItem("", collisionPrefixCheck),
+		widget.NewFormItem("窗口", windowResizableCheck),
+	)
+	if !fileTagsSupported {
+		tagModeSelect.Disable()
+	}
+
+	settingsDialog := dialog.NewCustom("更多设置", "确定", container.NewVScroll(form), fo.Window)
+	settingsDialog.Resize(fyne.NewSize(520, 400))
+	settingsDialog.SetOnClosed(func() {
+		if sources, err := parseDateSources(dateSourcesEntry.Text); err == nil && formatDateSources(sources) != formatDateSources(fo.DateSources) {
+			fo.DateSources = sources
+			fo.log("日期来源顺序: " + formatDateSources(sources))
+		}
+		zone := strings.TrimSpace(dateZoneEntry.Text)
+		if strings.EqualFold(zone, "local") {
+			zone = ""
+		}
+		if zone != fo.DateTimeZone {
+			if _, err := loadDateLocation(zone); err == nil {
+				fo.DateTimeZone = zone
+				if zone == "" {
+					fo.log("日期时区: 使用本机时区")
+				} else {
+					fo.log("日期时区: " + zone)
+				}
+			}
+		}
+		ageLabels := make([]string, ageBucketCount)
+		for i, obj := range ageLabelEntries {
+			labels := make([]string, ageBucketCount)
+			labels[i] = obj.(*widget.Entry).Text
+			ageLabels[i] = ageBucketLabel(i, labels)
+		}
+		if strings.Join(ageLabels, "/") != strings.Join(fo.AgeBucketLabels, "/") {
+			fo.AgeBucketLabels = ageLabels
+			fo.log("年龄分组名称: " + strings.Join(ageLabels, " / "))
+		}
+		if limits, err := parseSizeBuckets(sizeBucketsEntry.Text); err == nil && formatSizeBuckets(limits) != formatSizeBuckets(fo.SizeBucketsMB) {
+			fo.SizeBucketsMB = limits
+			var labels []string
+			for _, bucket := range newSizeBuckets(limits) {
+				labels = append(labels, bucket.label)
+			}
+			fo.log("大小分组: " + strings.Join(labels, " / "))
+		}
+		if layout, ok := layouts[layoutSelect.Selected]; ok && layout != fo.FolderLayout {
+			fo.FolderLayout = layout
+			fo.log(fmt.Sprintf("目录结构: %s", layoutSelect.Selected))
+		}
+		if mode, ok := tagModes[tagModeSelect.Selected]; ok && mode != fo.MultiTagMode {
+			fo.MultiTagMode = mode
+			fo.log(fmt.Sprintf("多标签文件处理方式: %s", tagModeSelect.Selected))
+		}
+		if dateFolderMtimeCheck.Checked != fo.DateFolderMtime {
+			fo.DateFolderMtime = dateFolderMtimeCheck.Checked
+			if fo.DateFolderMtime {
+				fo.log("已开启: 日期文件夹的修改时间将设为对应日期")
+			} else {
+				fo.log("已关闭: 日期文件夹的修改时间设置")
+			}
+		}
+		if text := strings.TrimSpace(folderTemplateEntry.Text); !folderTemplateEntry.Disabled() &&
+			validateFolderTemplate(text) == nil && text != fo.FolderTemplates[string(templateRule)] {
+			fo.setFolderTemplate(templateRule, text)
+			if text == "" {
+				fo.log(fmt.Sprintf("已清除文件夹名称模板（%s）", templateRule))
+			} else {
+				fo.log(fmt.Sprintf("文件夹名称模板（%s）: %s", templateRule, text))
+			}
+		}
+		burstsChanged := false
+		if burstCheck.Checked != fo.BurstDetection {
+			fo.BurstDetection = burstCheck.Checked
+			burstsChanged = true
+			if fo.BurstDetection {
+				fo.log("已开启: 识别连拍照片")
+			} else {
+				fo.log("已关闭: 识别连拍照片")
+			}
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(burstGapEntry.Text)); err == nil && n > 0 && n != fo.BurstMaxGap {
+			fo.BurstMaxGap = n
+			burstsChanged = true
+			fo.log(fmt.Sprintf("连拍中相邻照片最多相隔 %d 秒", n))
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(burstFramesEntry.Text)); err == nil && n >= 2 && n != fo.BurstMaxFrames {
+			fo.BurstMaxFrames = n
+			burstsChanged = true
+			fo.log(fmt.Sprintf("超过 %d 张的序列不当作连拍", n))
+		}
+		if burstsChanged {
+			// 识别连拍需要读取照片日期，在后台进行
+			fo.refreshBursts()
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(compactExtensionsEntry.Text)); err == nil && n >= 0 && n != fo.CompactExtensionsMin {
+			fo.CompactExtensionsMin = n
+			if n == 0 {
+				fo.log("合并少见后缀: 不合并")
+			} else {
+				fo.log(fmt.Sprintf("合并少见后缀: 按后缀整理时文件少于 %d 个的后缀放入「%s」", n, CompactedExtensionsFolderName))
+			}
+		}
+		if extensionRankCheck.Checked != fo.ExtensionRankPrefix {
+			fo.ExtensionRankPrefix = extensionRankCheck.Checked
+			if fo.ExtensionRankPrefix {
+				fo.log("已开启: 按后缀整理时为文件夹加序号前缀")
+			} else {
+				fo.log("已关闭: 按后缀整理时为文件夹加序号前缀")
+			}
+		}
+		if n, err := strconv.ParseInt(strings.TrimSpace(volumeCapEntry.Text), 10, 64); err == nil && n >= 0 && n != fo.VolumeCapMB {
+			fo.VolumeCapMB = n
+			fo.capacityPlan = nil
+			if n == 0 {
+				fo.log("按容量分卷: 不分卷")
+			} else {
+				fo.log(fmt.Sprintf("按容量分卷: 每卷 %s", formatFileSize(n<<20)))
+			}
+		}
+		if segments, err := parseRuleSegments(formatRuleSegments(selectedSegments())); err != nil {
+			fo.log("组合规则未修改: " + err.Error())
+		} else if formatRuleSegments(segments) != formatRuleSegments(fo.RuleSegments) {
+			fo.RuleSegments = segments
+			fo.log("组合规则: " + describeRuleSegments(segments))
+		}
+		if err := validateRuleSegmentSeparator(segmentSeparatorEntry.Text); err != nil {
+			fo.log("组合规则的分隔符未修改: " + err.Error())
+		} else if segmentSeparatorEntry.Text != fo.RuleSegmentSeparator {
+			fo.RuleSegmentSeparator = segmentSeparatorEntry.Text
+			if fo.RuleSegmentSeparator == "" {
+				fo.log("组合规则: 每层一级文件夹")
+			} else {
+				fo.log(fmt.Sprintf("组合规则: 各层用 %q 连接成一级文件夹", fo.RuleSegmentSeparator))
+			}
+		}
+		if emailSenderCheck.Checked != fo.EmailSenderFolders {
+			fo.EmailSenderFolders = emailSenderCheck.Checked
+			if fo.EmailSenderFolders {
+				fo.log("已开启: 按日期整理邮件时按发件人域名分文件夹")
+			} else {
+				fo.log("已关闭: 按发件人域名分文件夹")
+			}
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(packThresholdEntry.Text)); err == nil && n >= 1 && n != fo.PackThresholdKB {
+			fo.PackThresholdKB = n
+			fo.log(fmt.Sprintf("打包小文件: 小于 %d KB 的文件放入压缩包", n))
+		}
+		if packSmallCheck.Checked != fo.PackSmallFiles {
+			fo.PackSmallFiles = packSmallCheck.Checked
+			if fo.PackSmallFiles {
+				fo.log(fmt.Sprintf("已开启: 按日期整理时小于 %d KB 的文件放入日期文件夹对应的压缩包", fo.PackThresholdKB))
+			} else {
+				fo.log("已关闭: 打包小文件")
+			}
+		}
+		if textLanguageCheck.Checked != fo.TextLanguageFolders {
+			fo.TextLanguageFolders = textLanguageCheck.Checked
+			if fo.TextLanguageFolders {
+				fo.log("已开启: 文本文件按语言再分一层")
+			} else {
+				fo.log("已关闭: 文本文件按语言分层")
+			}
+		}
+		if rules, err := parseTextKeywordRules(textKeywordsEntry.Text); err == nil &&
+			formatTextKeywordRules(rules, "\n") != formatTextKeywordRules(fo.TextKeywordRules, "\n") {
+			fo.TextKeywordRules = rules
+			if len(rules) == 0 {
+				fo.log("已清除文本关键词规则")
+			} else {
+				fo.log(fmt.Sprintf("文本关键词规则: %d 条，第一条匹配的规则生效", len(rules)))
+			}
+		}
+		if textKeywordCaseCheck.Checked != fo.TextKeywordCase {
+			fo.TextKeywordCase = textKeywordCaseCheck.Checked
+			if fo.TextKeywordCase {
+				fo.log("文本关键词: 区分大小写")
+			} else {
+				fo.log("文本关键词: 不区分大小写")
+			}
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(textPrefixEntry.Text)); err == nil && n >= 1 && n <= maxTextPrefixKB && n != fo.TextPrefixKB {
+			fo.TextPrefixKB = n
+			fo.log(fmt.Sprintf("按内容整理: 读取文件开头 %d KB", n))
+		}
+		if dedupTargetCheck.Checked != fo.DedupTarget {
+			fo.DedupTarget = dedupTargetCheck.Checked
+			if fo.DedupTarget {
+				fo.log("已开启目标去重")
+			} else {
+				fo.log("已关闭目标去重")
+			}
+		}
+		if dedupEmptyFilesCheck.Checked != fo.DedupEmptyFiles {
+			fo.DedupEmptyFiles = dedupEmptyFilesCheck.Checked
+			if fo.DedupEmptyFiles {
+				fo.log("已开启: 目标去重时空文件视为相同内容")
+			} else {
+				fo.log("已关闭: 目标去重时空文件视为相同内容")
+			}
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(indexRateEntry.Text)); err == nil && n >= 0 && n != fo.TargetIndexRateMB {
+			fo.TargetIndexRateMB = n
+			if b := fo.runningIndexBuilder(); b != nil {
+				b.rateMB.Store(int64(n))
+			}
+			fo.log("建立目标文件索引的读取速度上限: " + describeIndexRate(n))
+		}
+		if policy, ok := emptyFilePolicies[emptyFileSelect.Selected]; ok && policy != fo.EmptyFilePolicy {
+			fo.EmptyFilePolicy = policy
+			fo.log(fmt.Sprintf("空文件处理方式: %s", emptyFileSelect.Selected))
+		}
+		if policy, ok := shortcutPolicies[shortcutSelect.Selected]; ok && policy != fo.ShortcutPolicy {
+			fo.ShortcutPolicy = policy
+			fo.log(fmt.Sprintf("快捷方式处理方式: %s", shortcutSelect.Selected))
+		}
+		if organizeLibrariesCheck.Checked != fo.OrganizeLibraries {
+			fo.OrganizeLibraries = organizeLibrariesCheck.Checked
+			if fo.OrganizeLibraries {
+				fo.log("已开启: 整理照片、音乐等程序的图库")
+			} else {
+				fo.log("已关闭: 整理图库，图库将留在原处")
+			}
+		}
+		conflictPolicy := ConflictRename
+		switch conflictSelect.SelectedIndex() {
+		case 1:
+			conflictPolicy = ConflictOverwrite
+		case 2:
+			conflictPolicy = ConflictMerge
+		}
+		if conflictPolicy != fo.ConflictPolicy || backupReplacedCheck.Checked != fo.BackupReplaced {
+			fo.ConflictPolicy = conflictPolicy
+			fo.BackupReplaced = backupReplacedCheck.Checked
+			switch {
+			case fo.ConflictPolicy == ConflictRename:
+				fo.log("目标中已有同名文件时: 加时间戳，保留两个文件")
+			case fo.ConflictPolicy == ConflictMerge:
+				fo.log("目标中已有同名文件时: 内容相同时跳过，不同时加时间戳")
+			case fo.BackupReplaced:
+				fo.log("目标中已有同名文件时: 覆盖，覆盖前备份到 " + ReplacedFolderName)
+			default:
+				fo.log("目标中已有同名文件时: 覆盖，不备份")
+			}
+		}
+		if path := strings.TrimSpace(snapshotEntry.Text); path != fo.SourceSnapshot {
+			fo.SourceSnapshot = path
+			if path != "" {
+				fo.log("已开启: 整理前记录源快照到 " + path)
+			} else {
+				fo.log("已关闭: 源快照")
+			}
+		}
+		if algorithm, ok := checksumAlgorithms[checksumSelect.Selected]; ok && algorithm != fo.ChecksumAlgorithm {
+			fo.ChecksumAlgorithm = algorithm
+			fo.log(fmt.Sprintf("校验清单: %s", checksumSelect.Selected))
+		}
+		if removeEmptiedDirsCheck.Checked != fo.RemoveEmptiedDirs {
+			fo.RemoveEmptiedDirs = removeEmptiedDirsCheck.Checked
+			if fo.RemoveEmptiedDirs {
+				fo.log("已开启: 删除整理后变空的源子文件夹")
+			} else {
+				fo.log("已关闭: 删除整理后变空的源子文件夹")
+			}
+		}
+		if preserveXattrsCheck.Checked != fo.PreserveXattrs {
+			fo.PreserveXattrs = preserveXattrsCheck.Checked
+			if fo.PreserveXattrs {
+				fo.log("已开启: 跨磁盘移动时保留扩展属性")
+			} else {
+				fo.log("已关闭: 跨磁盘移动时保留扩展属性")
+			}
+		}
+		if lowPriorityCheck.Checked != fo.LowPriority {
+			fo.LowPriority = lowPriorityCheck.Checked
+			if fo.LowPriority {
+				fo.log("已开启: 整理期间使用后台低优先级")
+			} else {
+				fo.log("已关闭: 后台低优先级")
+			}
+		}
+		if incomingOnlyCheck.Checked != fo.IncomingOnly {
+			fo.IncomingOnly = incomingOnlyCheck.Checked
+			if fo.IncomingOnly {
+				fo.log("已开启: 仅处理新增，每次成功整理后记录源文件夹中已有的文件")
+			} else {
+				fo.log("已关闭: 仅处理新增")
+			}
+		}
+		if convertImagesCheck.Checked != fo.ConvertImages {
+			fo.ConvertImages = convertImagesCheck.Checked
+			if fo.ConvertImages {
+				fo.log("已开启: 整理时将HEIC转换为JPEG")
+			} else {
+				fo.log("已关闭: 整理时将HEIC转换为JPEG")
+			}
+		}
+		if keepConvertedCheck.Checked != fo.KeepConverted {
+			fo.KeepConverted = keepConvertedCheck.Checked
+			if fo.KeepConverted {
+				fo.log("转换后保留原始文件")
+			} else {
+				fo.log("转换后删除原始文件")
+			}
+		}
+		if takeoutCheck.Checked != fo.TakeoutMode {
+			fo.TakeoutMode = takeoutCheck.Checked
+			if fo.TakeoutMode {
+				fo.log("已开启: 整理 Google 相册导出，元数据文件随照片移动")
+			} else {
+				fo.log("已关闭: 整理 Google 相册导出")
+			}
+		}
+		if moveEmptyDirsCheck.Checked != fo.MoveEmptyDirs {
+			fo.MoveEmptyDirs = moveEmptyDirsCheck.Checked
+			if fo.MoveEmptyDirs {
+				fo.log("已开启: 移动第一层的空文件夹")
+			} else {
+				fo.log("已关闭: 移动第一层的空文件夹")
+			}
+		}
+		if mode, ok := copyMergeModes[copyMergeSelect.Selected]; ok && mode != fo.CopyMerge {
+			fo.CopyMerge = mode
+			fo.log(fmt.Sprintf("整理前合并副本: %s", copyMergeSelect.Selected))
+		}
+		if copyPatternsEntry.Validate() == nil {
+			var patterns []string
+			for _, line := range strings.Split(copyPatternsEntry.Text, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					patterns = append(patterns, line)
+				}
+			}
+			if strings.Join(patterns, "\n") != strings.Join(fo.CopySuffixPatterns, "\n") {
+				fo.CopySuffixPatterns = patterns
+				fo.log(fmt.Sprintf("副本文件名规则: %d 条", len(patterns)))
+			}
+		}
+		if endpoint := strings.TrimSpace(statsEndpointEntry.Text); statsEndpointEntry.Validate() == nil && endpoint != fo.StatsEndpoint {
+			fo.StatsEndpoint = endpoint
+			if endpoint == "" {
+				fo.log("已关闭统计上报")
+			} else {
+				fo.log("统计上报地址: " + endpoint)
+			}
+		}
+		fo.StatsToken = strings.TrimSpace(statsTokenEntry.Text)
+		statusAddr := strings.TrimSpace(statusAddrEntry.Text)
+		statusToken := strings.TrimSpace(statusTokenEntry.Text)
+		if statusAddrEntry.Validate() == nil && (statusAddr != fo.StatusAddr || statusToken != fo.StatusToken) {
+			fo.StatusAddr, fo.StatusToken = statusAddr, statusToken
+			if statusAddr == "" {
+				fo.log("已关闭状态页面")
+			}
+			fo.applyStatusServer()
+		}
+		if hooksCheck.Checked != fo.HooksEnabled {
+			fo.HooksEnabled = hooksCheck.Checked
+			if fo.HooksEnabled {
+				fo.log("已开启: 整理后运行命令")
+			} else {
+				fo.log("已关闭: 整理后运行命令")
+			}
+		}
+		if command := strings.TrimSpace(postRunHookEntry.Text); postRunHookEntry.Validate() == nil && command != fo.PostRunHook {
+			fo.PostRunHook = command
+			fo.log("整理成功后运行: " + command)
+		}
+		if command := strings.TrimSpace(perFileHookEntry.Text); perFileHookEntry.Validate() == nil && command != fo.PerFileHook {
+			fo.PerFileHook = command
+			fo.log("每个文件整理后运行: " + command)
+		}
+		if timeout, err := strconv.Atoi(strings.TrimSpace(hookTimeoutEntry.Text)); err == nil && timeout > 0 {
+			fo.HookTimeoutSec = timeout
+		}
+		fo.LogCLICommand = logCLICommandCheck.Checked
+		if verbosity, ok := logVerbosities[logVerbositySelect.Selected]; ok && verbosity != fo.LogVerbosity {
+			fo.LogVerbosity = verbosity
+			fo.log(fmt.Sprintf("日志详细程度: %s", logVerbositySelect.Selected))
+		}
+		fo.ValidateExtensions = validateExtensionsCheck.Checked
+		if dangerousTargetCheck.Checked != fo.CheckDangerousTarget {
+			fo.CheckDangerousTarget = dangerousTargetCheck.Checked
+			if fo.CheckDangerousTarget {
+				fo.log("已开启: 危险的目标文件夹需要确认")
+			} else {
+				fo.log("已关闭: 危险的目标文件夹需要确认")
+			}
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(parallelThresholdEntry.Text)); err == nil && n > 0 && n != fo.ParallelThreshold {
+			fo.ParallelThreshold = n
+			fo.log(fmt.Sprintf("并行阈值: 少于 %d 个文件时使用少量文件工作协程数", n))
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(smallSetWorkersEntry.Text)); err == nil && n > 0 && n != fo.SmallSetWorkers {
+			fo.SmallSetWorkers = n
+			fo.log(fmt.Sprintf("少量文件工作协程数: %d", n))
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(scanConcurrencyEntry.Text)); err == nil && n > 0 && n != fo.ScanConcurrency {
+			fo.ScanConcurrency = n
+			fo.log(fmt.Sprintf("同时扫描的文件夹数: %d", n))
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(scanErrorLimitEntry.Text)); err == nil && n >= 0 && n != fo.ScanErrorLimit {
+			fo.ScanErrorLimit = n
+			if n == 0 {
+				fo.log("扫描错误上限: 不限制")
+			} else {
+				fo.log(fmt.Sprintf("扫描错误上限: %d", n))
+			}
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(planStaleEntry.Text)); err == nil && n >= 0 && n != fo.PlanStaleMinutes {
+			fo.PlanStaleMinutes = n
+			if n == 0 {
+				fo.log("执行前检查: 不检查")
+			} else {
+				fo.log(fmt.Sprintf("执行前检查: 扫描结果生成 %d 分钟后执行时先检查文件变化", n))
+			}
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(streamingThresholdEntry.Text)); err == nil && n >= 0 && n != fo.StreamingThreshold {
+			fo.StreamingThreshold = n
+			if n == 0 {
+				fo.log("流式整理: 不使用，始终保留扫描到的文件列表")
+			} else {
+				fo.log(fmt.Sprintf("流式整理阈值: 扫描到的文件超过 %d 个时不保留文件列表，重新扫描后生效", n))
+			}
+		}
+		if forceFullScanCheck.Checked != fo.ForceFullScan {
+			fo.ForceFullScan = forceFullScanCheck.Checked
+			if fo.ForceFullScan {
+				fo.log("已开启强制完全扫描")
+			} else {
+				fo.log("已关闭强制完全扫描，未变化的文件夹将使用扫描缓存")
+			}
+		}
+		depth, depthErr := strconv.Atoi(strings.TrimSpace(hashShardDepthEntry.Text))
+		width, widthErr := strconv.Atoi(strings.TrimSpace(hashShardWidthEntry.Text))
+		if depthErr == nil && widthErr == nil && validateHashShards(depth, width) == nil &&
+			(depth != fo.HashShardDepth || width != fo.HashShardWidth) {
+			fo.HashShardDepth, fo.HashShardWidth = depth, width
+			fo.log(fmt.Sprintf("内容哈希分片: %d 层，每层 %d 个字符", depth, width))
+		}
+		if hashRenameCheck.Checked != fo.HashRename {
+			fo.HashRename = hashRenameCheck.Checked
+			if fo.HashRename {
+				fo.log("已开启: 按内容哈希整理时把文件改名为哈希值")
+			} else {
+				fo.log("已关闭: 按内容哈希整理时把文件改名为哈希值")
+			}
+		}
+		if catalogCheck.Checked != fo.CatalogEnabled {
+			fo.CatalogEnabled = catalogCheck.Checked
+			if fo.CatalogEnabled {
+				fo.log("已开启整理目录: " + catalogPath())
+			} else {
+				fo.log("已关闭整理目录，已有的记录仍可搜索")
+			}
+		}
+		if windowResizableCheck.Checked != fo.WindowResizable {
+			fo.WindowResizable = windowResizableCheck.Checked
+			fo.applyWindowResizable()
+			if fo.WindowResizable {
+				fo.log("已允许调整窗口大小")
+			} else {
+				fo.log("窗口已固定为默认大小")
+			}
+		}
+		scheduleChanged := false
+		if n, err := strconv.Atoi(strings.TrimSpace(scheduleIntervalEntry.Text)); err == nil && n >= 0 && n != fo.ScheduleInterval {
+			fo.ScheduleInterval = n
+			scheduleChanged = true
+		}
+		if start, end := scheduleStartSelect.SelectedIndex(), scheduleEndSelect.SelectedIndex(); start >= 0 && end >= 0 &&
+			(start != fo.ScheduleStartHour || end != fo.ScheduleEndHour) {
+			fo.ScheduleStartHour, fo.ScheduleEndHour = start, end
+			scheduleChanged = true
+		}
+		if index := digestSelect.SelectedIndex(); index >= 0 && index-1 != fo.DigestHour {
+			fo.DigestHour = index - 1
+			if fo.DigestHour == digestHourOff {
+				fo.log("今日摘要: 不定时显示")
+			} else {
+				fo.log(fmt.Sprintf("今日摘要: 每天 %02d:00 显示", fo.DigestHour))
+			}
+			fo.startDigestTimer()
+		}
+		if scheduleChanged {
+			if fo.ScheduleInterval == 0 {
+				fo.stopSchedule()
+				fo.log("[定时] 已关闭定时整理")
+			} else {
+				fo.startSchedule()
+			}
+		}
+		if cardDetectionCheck.Checked != fo.CardDetection {
+			fo.CardDetection = cardDetectionCheck.Checked
+			if fo.CardDetection {
+				fo.startCardDetection()
+				fo.log("已开启相机存储卡检测")
+			} else {
+				fo.stopCardDetection()
+				fo.log("已关闭相机存储卡检测")
+			}
+		}
+		if cardTarget := strings.TrimSpace(cardTargetEntry.Text); cardTargetEntry.Validate() == nil {
+			fo.CardImportTarget = cardTarget
+		}
+		fo.CardCleanup = cardCleanupCheck.Checked
+		if form, ok := normalizationForms[normalizationSelect.Selected]; ok && form != fo.UnicodeNormalization {
+			fo.UnicodeNormalization = form
+			fo.log(fmt.Sprintf("文件名规范化: %s", normalizationSelect.Selected))
+		}
+		collisionPosition := CollisionSuffix
+		if collisionPrefixCheck.Checked {
+			collisionPosition = CollisionPrefix
+		}
+		if index := collisionSeparatorSelect.SelectedIndex(); index >= 0 &&
+			(collisionSeparatorNames[index].separator != fo.CollisionSeparator || collisionPosition != fo.CollisionPosition) {
+			fo.CollisionSeparator = collisionSeparatorNames[index].separator
+			fo.CollisionPosition = collisionPosition
+			fo.log("重名文件的新文件名: " + collisionFileName("name", ".ext", "时间", fo.CollisionSeparator, fo.CollisionPosition))
+		}
+		// 保存用户设置
+		fo.saveUserConfig()
+		fo.refreshFileTable()
+	})
+
+	fo.showDialog(settingsDialog, layoutSelect)
+}
+
+// 界面上的目标文件夹，未填写时使用第一个源文件夹作为目标目录，都没有时返回空字符串
+func (fo *FileOrganizer) selectedTargetDir() string {
+	if fo.TargetDirEntry != nil && strings.TrimSpace(fo.TargetDirEntry.Text) != "" {
+		return filepath.Clean(strings.TrimSpace(fo.TargetDirEntry.Text))
+	}
+	if len(fo.SourceDirs) > 0 {
+		return fo.SourceDirs[0]
+	}
+	return ""
+}
+
+// 根据当前界面设置生成配置
+func (fo *FileOrganizer) currentConfig() Config {
+	targetDir := fo.selectedTargetDir()
+	rareExtensions := fo.currentRareExtensions(fo.RuleSelect.Selected)
+
+	return Config{
+		SourceDir:            targetDir, // 这里仍然使用第一个源文件夹作为配置中的SourceDir
+		TargetDir:            targetDir,
+		FileExtensions:       fo.FileExtensions,
+		FolderDateFormat:     fo.FolderDateFormat,
+		OrganizeRule:         fo.RuleSelect.Selected,
+		ExtensionCase:        fo.ExtensionCase,
+		MultiTagMode:         fo.MultiTagMode,
+		DateFolderMtime:      fo.DateFolderMtime,
+		SourceDirs:           append([]string(nil), fo.SourceDirs...),
+		FolderLayout:         fo.FolderLayout,
+		DedupTarget:          fo.DedupTarget,
+		EventLabels:          append([]EventLabel(nil), fo.EventLabels...),
+		ParallelThreshold:    fo.ParallelThreshold,
+		SmallSetWorkers:      fo.SmallSetWorkers,
+		AgeBucketLabels:      append([]string(nil), fo.AgeBucketLabels...),
+		SizeBucketsMB:        append([]int(nil), fo.SizeBucketsMB...),
+		DateSources:          append([]DateSource(nil), fo.DateSources...),
+		EmptyFilePolicy:      fo.EmptyFilePolicy,
+		ShortcutPolicy:       fo.ShortcutPolicy,
+		OrganizeLibraries:    fo.OrganizeLibraries,
+		TextLanguageFolders:  fo.TextLanguageFolders,
+		TextKeywordRules:     append([]TextKeywordRule(nil), fo.TextKeywordRules...),
+		TextKeywordCase:      fo.TextKeywordCase,
+		TextPrefixKB:         fo.TextPrefixKB,
+		TakeoutMode:          fo.TakeoutMode,
+		LogVerbosity:         fo.LogVerbosity,
+		Location:             fo.dateLocation(),
+		Profile:              fo.activeProfile,
+		ConflictPolicy:       fo.ConflictPolicy,
+		BackupReplaced:       fo.BackupReplaced,
+		DedupEmptyFiles:      fo.DedupEmptyFiles,
+		ChecksumAlgorithm:    fo.ChecksumAlgorithm,
+		SourceSnapshot:       fo.SourceSnapshot,
+		RemoveEmptiedDirs:    fo.RemoveEmptiedDirs,
+		MoveEmptyDirs:        fo.MoveEmptyDirs,
+		PreserveXattrs:       fo.PreserveXattrs,
+		CopyMerge:            fo.CopyMerge,
+		CopySuffixPatterns:   append([]string(nil), fo.CopySuffixPatterns...),
+		StatsEndpoint:        fo.StatsEndpoint,
+		StatsToken:           fo.StatsToken,
+		PostRunHook:          fo.hookCommand(fo.PostRunHook),
+		PerFileHook:          fo.hookCommand(fo.PerFileHook),
+		HookTimeoutSec:       fo.HookTimeoutSec,
+		CatalogEnabled:       fo.CatalogEnabled,
+		Pins:                 fo.pins,
+		HashShardDepth:       fo.HashShardDepth,
+		HashShardWidth:       fo.HashShardWidth,
+		HashRename:           fo.HashRename,
+		EmailSenderFolders:   fo.EmailSenderFolders,
+		PackSmallFiles:       fo.PackSmallFiles,
+		PackThresholdKB:      fo.PackThresholdKB,
+		FolderTemplate:       fo.FolderTemplates[fo.RuleSelect.Selected],
+		Bursts:               fo.bursts,
+		BurstMaxGap:          fo.BurstMaxGap,
+		BurstMaxFrames:       fo.BurstMaxFrames,
+		CompactExtensionsMin: fo.CompactExtensionsMin,
+		RareExtensions:       rareExtensions,
+		ExtensionRanks:       fo.currentExtensionRanks(fo.RuleSelect.Selected, fo.FileExtensions, rareExtensions),
+		RuleSegments:         append([]string(nil), fo.RuleSegments...),
+		RuleSegmentSeparator: fo.RuleSegmentSeparator,
+		ConvertImages:        fo.ConvertImages && fo.imageConverter != nil,
+		KeepConverted:        fo.KeepConverted,
+		Volumes:              fo.currentTargetVolumes(targetDir),
+		VolumePlan:           fo.volumePlan,
+		VolumeCapMB:          fo.VolumeCapMB,
+		CapacityPlan:         fo.capacityPlan,
+		ReadOnlySources:      fo.currentReadOnlySources(),
+		LowPriority:          fo.LowPriority,
+		IncomingOnly:         fo.IncomingOnly,
+	}
+}
+
+// 处理文件
+func (fo *FileOrganizer) processFilesGUI() {
+	if fo.refuseInReadOnlyMode() {
+		return
+	}
+	// 检查源文件夹
+	if len(fo.SourceDirs) == 0 {
+		fo.log("请先选择源文件夹")
+		dialog.ShowError(errors.New("请先选择源文件夹"), fo.Window)
+		return
+	}
+
+	// 流式整理时没有文件列表，处理扫描到的所有后缀，由重新扫描代替执行前检查
+	if fo.streaming {
+		config := fo.currentConfig()
+		config.FileExtensions = fo.streamingExtensions()
+		config.ExcludedFiles = make(map[string]bool, len(fo.excludedFiles))
+		for path := range fo.excludedFiles {
+			config.ExcludedFiles[path] = true
+		}
+		if err := validateStreamingConfig(config); err != nil {
+			fo.log(err.Error())
+			dialog.ShowError(err, fo.Window)
+			return
+		}
+		fo.confirmDangerousTarget(config.TargetDir, func() {
+			fo.executePlan(config)
+		})
+		return
+	}
+
+	if len(fo.FileExtensions) == 0 {
+		dialog.ShowError(errors.New("请先选择文件后缀"), fo.Window)
+		return
+	}
+
+	// 检查选择的后缀是否出现在本次扫描结果中，避免过时的后缀设置导致一个文件都不移动
+	if fo.ValidateExtensions {
+		missing := fo.missingExtensions(fo.FileExtensions)
+		if len(missing) == len(fo.FileExtensions) {
+			fo.log(fmt.Sprintf("警告: 扫描结果中没有任何已选择的文件后缀: %s", strings.Join(missing, ", ")))
+			dialog.ShowInformation("没有可整理的文件",
+				fmt.Sprintf("扫描结果中没有以下任何已选择的文件后缀:\n%s\n\n请重新选择文件后缀。", strings.Join(missing, ", ")),
+				fo.Window)
+			return
+		}
+		if len(missing) > 0 {
+			fo.log(fmt.Sprintf("警告: 以下已选择的文件后缀在扫描结果中不存在: %s", strings.Join(missing, ", ")))
+		}
+	}
+
+	// 创建配置，并带上用户在扫描结果中排除的文件
+	config := fo.currentConfig()
+	config.ExcludedFiles = make(map[string]bool, len(fo.excludedFiles))
+	for path := range fo.excludedFiles {
+		config.ExcludedFiles[path] = true
+	}
 
-	dialog.Show()
+	// 目标文件夹是根目录、主目录或系统文件夹时先确认，再检查扫描结果是否过时
+	fo.confirmDangerousTarget(config.TargetDir, func() {
+		fo.confirmStalePlan(config, func() {
+			fo.executePlan(config)
+		})
+	})
 }
 
-// 处理文件
-func (fo *FileOrganizer) processFilesGUI() {
-	// 检查源文件夹
-	if len(fo.SourceDirs) == 0 {
-		fo.log("请先选择源文件夹")
-		dialog.ShowError(errors.New("请先选择源文件夹"), fo.Window)
+// 检查目标后开始整理
+func (fo *FileOrganizer) executePlan(config Config) {
+	// 只读源文件夹不能作为目标
+	if err := validateReadOnlySources(config); err != nil {
+		fo.log(err.Error())
+		dialog.ShowError(err, fo.Window)
+		return
+	}
+	// 保留目录结构时目标文件夹不能是源文件夹
+	if err := validateLayoutOverlap(config); err != nil {
+		fo.log(err.Error())
+		dialog.ShowError(err, fo.Window)
+		return
+	}
+	// 从预设或同步的设置中载入的按标签整理在不支持标签的系统上不能使用
+	if err := validateTagRule(config); err != nil {
+		fo.log(err.Error())
+		dialog.ShowError(err, fo.Window)
 		return
 	}
 
-	if len(fo.FileExtensions) == 0 {
-		dialog.ShowError(errors.New("请先选择文件后缀"), fo.Window)
+	// 使用多个目标卷时按可用空间为每个新文件夹分配卷
+	plan, err := fo.planVolumes(config, fo.scannedFiles)
+	if err != nil {
+		fo.log("目标卷分配失败: " + err.Error())
+		dialog.ShowError(err, fo.Window)
 		return
 	}
+	config.VolumePlan = plan
+	fo.volumePlan = plan
+	if plan != nil {
+		for _, line := range describeVolumePlan(plan, config.Volumes) {
+			fo.log(line)
+		}
+	}
 
-	// 获取目标文件夹（使用第一个源文件夹作为目标目录）
-	targetDir := fo.SourceDirs[0]
+	// 按容量分卷时为每个文件分配分卷
+	capacity, err := fo.planCapacityVolumes(config, fo.scannedFiles)
+	if err != nil {
+		fo.log("按容量分卷失败: " + err.Error())
+		dialog.ShowError(err, fo.Window)
+		return
+	}
+	config.CapacityPlan = capacity
+	fo.capacityPlan = capacity
+	if capacity != nil {
+		for _, line := range describeCapacityPlan(capacity) {
+			fo.log(line)
+		}
+	}
 
-	// 创建配置
-	config := Config{
-		SourceDir:        targetDir, // 这里仍然使用第一个源文件夹作为配置中的SourceDir
-		TargetDir:        targetDir,
-		FileExtensions:   fo.FileExtensions,
-		FolderDateFormat: fo.FolderDateFormat,
-		OrganizeRule:     fo.RuleSelect.Selected,
-		ExtensionCase:    fo.ExtensionCase,
+	// 预检目标文件夹，避免路径中某一级是普通文件时每个文件都移动失败
+	if err := fo.validatePlannedTargets(config); err != nil {
+		fo.log("目标文件夹检查失败: " + err.Error())
+		dialog.ShowError(err, fo.Window)
+		return
 	}
 
+	// 不区分大小写的卷上只有大小写不同的文件夹会被合并，先提示用户
+	fo.confirmCaseCollisions(config, func() {
+		fo.confirmTargetLock(config.TargetDir, func() {
+			fo.startProcessing(config)
+		})
+	})
+}
+
+// 开始在后台整理文件
+func (fo *FileOrganizer) startProcessing(config Config) {
 	fo.log("开始整理文件...")
 	fo.log(fmt.Sprintf("共 %d 个源文件夹", len(fo.SourceDirs)))
 	for _, dir := range fo.SourceDirs {
 		fo.log(fmt.Sprintf("源文件夹: %s", dir))
 	}
 	fo.log(fmt.Sprintf("整理规则: %s", fo.RuleSelect.Selected))
-	fo.log(fmt.Sprintf("处理的文件后缀: %v", fo.FileExtensions))
+	fo.log(fmt.Sprintf("处理的文件后缀: %v", config.FileExtensions))
+	if len(config.ExcludedFiles) > 0 {
+		fo.log(fmt.Sprintf("已手动排除 %d 个文件", len(config.ExcludedFiles)))
+	}
+	fo.logCompactedExtensions(config)
+	if !config.CatalogEnabled {
+		fo.log("提示: 整理目录未开启，本次整理的文件不能撤销（可在「更多设置」中开启）")
+	}
+	fo.lastCLICommand = cliCommandFor(fo.SourceDirs, config)
+	if fo.LogCLICommand {
+		fo.log("等效命令: " + fo.lastCLICommand)
+	}
 
 	// 添加进度指示器
 	fo.processBtn.Disable()
 
 	// 在goroutine中处理文件
+	streaming, roots, estimate := fo.streaming, append([]string(nil), fo.SourceDirs...), fo.scannedCount()
 	go func() {
-		err := fo.processFiles(config)
+		var err error
+		if streaming {
+			_, err = fo.processStreaming(config, roots, estimate)
+		} else {
+			_, err = fo.processFiles(config, fo.scannedFiles)
+		}
 		fo.safeUpdateUI(func() {
 			if err != nil {
 				fo.log("处理出错: " + err.Error())
@@ -732,6 +3636,82 @@ func (fo *FileOrganizer) processFilesGUI() {
 	}()
 }
 
+// 检查本次整理会用到的所有目标文件夹是否为文件夹或可以创建
+func (fo *FileOrganizer) validatePlannedTargets(config Config) error {
+	if err := validateTargetDir(config.TargetDir); err != nil {
+		return err
+	}
+
+	// 按内容哈希整理时规划目标需要读取每个文件，分片文件夹都在目标文件夹中，只检查目标文件夹
+	if OrganizeRule(config.OrganizeRule) == RuleByHash {
+		return nil
+	}
+
+	checked := map[string]bool{config.TargetDir: true}
+	for _, filePath := range fo.scannedFiles {
+		info := fo.scannedFileInfos[filePath]
+		if info == nil || config.ExcludedFiles[filePath] || config.Pins.matches(filePath, nil) || !fo.isTargetFile(fileExtension(filePath), config.FileExtensions) ||
+			isInsidePreservedTarget(filePath, config) {
+			continue
+		}
+		targetDir := fo.planTargetDir(filePath, info, config)
+		if targetDir == "" || checked[targetDir] {
+			continue
+		}
+		checked[targetDir] = true
+		if err := validateTargetDir(targetDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 检查目标路径是否为已存在的文件夹或可以创建，路径中任何一级是普通文件时返回错误
+func validateTargetDir(dir string) error {
+	if dir == "" {
+		return errors.New("目标文件夹为空")
+	}
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("目标文件夹必须是绝对路径: %s", dir)
+	}
+
+	// 从目标路径逐级向上查找第一个已存在的路径
+	for current := filepath.Clean(dir); ; {
+		info, err := os.Stat(current)
+		if err == nil {
+			if !info.IsDir() {
+				if current == filepath.Clean(dir) {
+					return fmt.Errorf("目标路径 %s 是一个文件，不是文件夹", current)
+				}
+				return fmt.Errorf("无法创建目标文件夹 %s：路径中的 %s 是一个文件", dir, current)
+			}
+			return nil
+		}
+		// 不存在或者上级是文件（ENOTDIR）时继续向上检查
+		if !os.IsNotExist(err) && !errors.Is(err, syscall.ENOTDIR) {
+			return fmt.Errorf("无法访问目标路径 %s: %w", current, err)
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return nil
+		}
+		current = parent
+	}
+}
+
+// 获取已选择但未出现在扫描结果中的文件后缀
+func (fo *FileOrganizer) missingExtensions(selected []string) []string {
+	var missing []string
+	for _, ext := range selected {
+		if fo.scannedFileExtensions[strings.ToLower(ext)] == 0 {
+			missing = append(missing, ext)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
 // 检查文件是否为需要处理的类型
 func (fo *FileOrganizer) isTargetFile(fileExt string, targetExts []string) bool {
 	lowerExt := strings.ToLower(fileExt)
@@ -743,209 +3723,1216 @@ func (fo *FileOrganizer) isTargetFile(fileExt string, targetExts []string) bool
 	return false
 }
 
-// 获取文件修改日期
-func (fo *FileOrganizer) getFileModifyDate(fileInfo os.FileInfo, format string) string {
+// 按配置的日期来源获取文件日期，转换到整理使用的时区
+func (fo *FileOrganizer) fileDate(filePath string, fileInfo os.FileInfo, config Config) time.Time {
+	// 程序包按自身的修改时间整理，不读取其中的文件
+	if fileInfo != nil && fileInfo.IsDir() {
+		return fo.bucketTime(filePath, fileInfo.ModTime(), DateSourceMtime, config)
+	}
+	// FAT/exFAT目标中的文件按整理时记录的日期重新归档，修改时间可能已因时区不同而偏移
+	if date, ok := config.recordedDateOf(filePath, fileInfo); ok {
+		return date
+	}
+	// 连拍序列中的照片按第一张的日期整理，与第一张单独整理时一样划分日期
+	if anchor, date, source, ok := config.Bursts.dateFor(filePath); ok {
+		return fo.bucketTime(anchor, date, source, config)
+	}
+	date, source := fo.dates.Resolve(filePath, fileInfo, effectiveDateSources(config))
+	return fo.bucketTime(filePath, date, source, config)
+}
+
+// 按文件夹命名规则格式化日期，结果只由时间、命名规则和时区决定
+func getFileModifyDate(t time.Time, format string, loc *time.Location) string {
+	t = t.In(loc)
 	switch format {
 	case "YYYY-MM-DD":
-		return fileInfo.ModTime().Format("2006-01-02")
+		return t.Format("2006-01-02")
 	case "YYYYMMDD":
-		return fileInfo.ModTime().Format("20060102")
+		return t.Format("20060102")
 	case "YY-MM-DD":
-		return fileInfo.ModTime().Format("06-01-02")
+		return t.Format("06-01-02")
 	case "YYMMDD":
-		return fileInfo.ModTime().Format("060102")
+		return t.Format("060102")
+	case "YYYY-MM":
+		return t.Format("2006-01")
+	case "YYYYMM":
+		return t.Format("200601")
 	default:
-		return fileInfo.ModTime().Format("2006-01-02")
+		return t.Format("2006-01-02")
+	}
+}
+
+// 根据整理规则计算文件的目标文件夹
+func (fo *FileOrganizer) planTargetDir(filePath string, fileInfo os.FileInfo, config Config) string {
+	ruleFolder := fo.ruleFolderName(filePath, fileInfo, config)
+	if ruleFolder == "" {
+		return ""
+	}
+	// 规则和源文件夹带来的文件夹名也统一规范化，只有编码不同的名称不会变成两个文件夹
+	targetDir := fo.normalizeTargetDir(layoutTargetDir(filePath, ruleFolder, config), config.TargetDir)
+	targetDir = config.VolumePlan.remap(targetDir)
+	return config.CapacityPlan.remap(filePath, targetDir)
+}
+
+// 根据组织规则计算文件所属的规则文件夹名称，设置了文件夹名称模板时按模板生成
+func (fo *FileOrganizer) ruleFolderName(filePath string, fileInfo os.FileInfo, config Config) string {
+	folder := fo.applyFolderTemplate(filePath, fileInfo, fo.defaultRuleFolderName(filePath, fileInfo, config), config)
+	// 文本文件按内容在规则文件夹下再分一层。按哈希分片时文件夹只由内容哈希决定，不再分层
+	if folder != "" && OrganizeRule(config.OrganizeRule) != RuleByHash {
+		if text := fo.textFolders.folder(filePath, config); text != "" {
+			folder = filepath.Join(folder, text)
+		}
+	}
+	return folder
+}
+
+// 规则原本的文件夹名称，例如日期字符串或后缀
+func (fo *FileOrganizer) defaultRuleFolderName(filePath string, fileInfo os.FileInfo, config Config) string {
+	switch OrganizeRule(config.OrganizeRule) {
+	case RuleByDate:
+		// 按日期组织，日期在事件范围内时追加事件标签
+		date := fo.fileDate(filePath, fileInfo, config)
+		folder := getFileModifyDate(date, config.FolderDateFormat, config.location())
+		if label, ok := eventLabelFor(date, config.EventLabels); ok {
+			folder += " " + sanitizeFolderName(label.Label)
+		}
+		// 邮件按发件人域名再分一层，没有发件人时直接放在日期文件夹中
+		if config.EmailSenderFolders {
+			if sender := fo.emailSenders.folder(filePath); sender != "" {
+				return filepath.Join(sender, folder)
+			}
+		}
+		return folder
+	case RuleByExtension:
+		// 按文件后缀组织，文件太少的后缀合并到同一个文件夹
+		fileExt := fileExtension(filePath)
+		if fileExt == "" {
+			return NoExtensionFolderName
+		}
+		if config.RareExtensions[strings.ToLower(fileExt)] {
+			return CompactedExtensionsFolderName
+		}
+		if rank := config.ExtensionRanks[strings.ToLower(fileExt)]; rank > 0 {
+			return rankedFolderName(strings.ToLower(fileExt), rank, config.ExtensionCase)
+		}
+		if config.ExtensionCase == "uppercase" {
+			return strings.ToUpper(fileExt)
+		}
+		return strings.ToLower(fileExt)
+	case RuleByTag:
+		// 按文件标签组织，没有标签或读取失败的文件放入"未标记"
+		tags, err := readFileTags(filePath)
+		if err != nil || len(tags) == 0 {
+			return UntaggedFolderName
+		}
+		return sanitizeFolderName(tags[0])
+	case RuleByAge:
+		// 按文件年龄分组组织，只会产生少量固定的文件夹
+		return ageBucketLabel(ageBucket(fo.fileDate(filePath, fileInfo, config), config.planNow()), config.AgeBucketLabels)
+	case RuleByCamera:
+		// 按EXIF中的相机型号组织，没有型号的文件和非照片放入"未知相机"
+		return fo.cameraModels.folder(filePath)
+	case RuleByTypeSize:
+		// 按文件类别和大小分组组织，例如 图片/1GB以上
+		return typeSizeFolderName(filePath, fileInfo, config)
+	case RuleByHash:
+		// 按内容哈希的前缀分片，例如 ab/cd。无法读取的文件没有目标文件夹
+		hash, err := fo.contentHashes.get(filePath, fileInfo)
+		if err != nil {
+			return ""
+		}
+		return hashShardPath(hash, config.HashShardDepth, config.HashShardWidth)
+	case RuleComposite:
+		// 按组合规则的各层依次生成文件夹，例如 类别/日期
+		return fo.compositeFolderName(filePath, fileInfo, config)
+	}
+	return ""
+}
+
+// 按目录结构设置组合目标文件夹、规则文件夹和文件在源文件夹中的相对目录
+func layoutTargetDir(filePath, ruleFolder string, config Config) string {
+	switch config.FolderLayout {
+	case LayoutRuleFirst:
+		return filepath.Join(config.TargetDir, ruleFolder, sourceRelativeDir(filePath, config.SourceDirs))
+	case LayoutSourceFirst:
+		return filepath.Join(config.TargetDir, sourceRelativeDir(filePath, config.SourceDirs), ruleFolder)
+	case LayoutNamePrefix:
+		// 规则体现在文件名上，文件都放在目标根目录
+		return config.TargetDir
+	}
+	return filepath.Join(config.TargetDir, ruleFolder)
+}
+
+// 获取文件所在目录相对于其源文件夹的路径，直接位于源文件夹根目录时返回空字符串
+func sourceRelativeDir(filePath string, sourceDirs []string) string {
+	dir := filepath.Dir(filePath)
+	best := ""
+	for _, root := range sourceDirs {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		// 源文件夹相互嵌套时使用最近的源文件夹
+		if len(root) > len(best) {
+			best = root
+		}
+	}
+	if best == "" {
+		return ""
 	}
+	rel, _ := filepath.Rel(best, dir)
+	if rel == "." {
+		return ""
+	}
+	return rel
+}
+
+// 清理文件夹名称中不能用于路径的字符
+func sanitizeFolderName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', 0:
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return "_"
+	}
+	return name
 }
 
-// 移动文件到目标目录
-func (fo *FileOrganizer) moveFile(sourcePath, targetDir string) error {
+// 移动文件到目标目录，跨磁盘复制时xattrs为true则同时复制扩展属性
+func (fo *FileOrganizer) moveFile(sourcePath, targetDir string, xattrs bool) (string, error) {
+	if err := fo.checkWritable(); err != nil {
+		return "", err
+	}
 	maxRetries := 3
 
+	// 文件名不安全时不拼接路径
+	if err := checkSourceName(sourcePath); err != nil {
+		return "", err
+	}
+
 	// 确保目标目录存在
 	err := os.MkdirAll(targetDir, 0755)
 	if err != nil {
-		return fmt.Errorf("创建目标目录失败: %w", err)
+		return "", fmt.Errorf("创建目标目录失败: %w", err)
 	}
 
 	// 构建目标文件路径
-	fileName := filepath.Base(sourcePath)
-	targetPath := filepath.Join(targetDir, fileName)
-
-	// 检查目标文件是否已存在
-	if _, statErr := os.Stat(targetPath); statErr == nil {
-		ext := filepath.Ext(fileName)
-		name := fileName[:len(fileName)-len(ext)]
-		timestamp := time.Now().Format("20060102_150405") // 更精确的时间戳避免冲突
-		targetPath = filepath.Join(targetDir, fmt.Sprintf("%s_%s%s", name, timestamp, ext))
+	targetPath := fo.uniqueTargetPath(targetDir, filepath.Base(sourcePath))
+	if err := checkTargetPath(targetDir, targetPath); err != nil {
+		return "", err
 	}
 
 	// 尝试重命名文件
 	for i := 0; i < maxRetries; i++ {
-		err = os.Rename(sourcePath, targetPath)
+		started := time.Now()
+		err = renameFile(sourcePath, targetPath)
 		if err == nil {
-			return nil
+			observePerf(perfOpRename, started)
+			perfCounters.renames.Add(1)
+			return targetPath, nil
 		}
 		// 只有在不是跨设备移动时才重试（使用字符串判断替代os.ErrCrossDevice）
 		if i < maxRetries-1 && !strings.Contains(err.Error(), "cross-device link") {
 			time.Sleep(100 * time.Millisecond)
 		} else {
-			break // 跨设备移动直接尝试复制
+			break // 跨设备移动直接尝试复制
+		}
+	}
+
+	// 如果重命名失败，尝试复制后删除原文件。程序包整个复制后再删除
+	if info, statErr := os.Lstat(sourcePath); statErr == nil && info.IsDir() {
+		if err := fo.copyTree(sourcePath, targetPath, xattrs); err != nil {
+			return "", err
+		}
+		if err := os.RemoveAll(sourcePath); err != nil {
+			fo.log(fmt.Sprintf("警告: 已成功复制程序包但无法删除原程序包 %s: %v", sourcePath, err))
+		}
+		return targetPath, nil
+	}
+	if err := fo.copyFileContents(sourcePath, targetPath); err != nil {
+		return "", err
+	}
+	fo.preserveXattrs(sourcePath, targetPath, xattrs)
+
+	// 复制成功后删除源文件
+	err = os.Remove(sourcePath)
+	if err != nil {
+		// 删除失败时记录警告但不返回错误，因为文件已经成功复制
+		fo.log(fmt.Sprintf("警告: 已成功复制文件但无法删除原文件 %s: %v", sourcePath, err))
+	}
+
+	return targetPath, nil
+}
+
+// 复制文件到目标目录（保留源文件），返回副本的路径
+func (fo *FileOrganizer) copyFile(sourcePath, targetDir string, xattrs bool) (string, error) {
+	if err := fo.checkWritable(); err != nil {
+		return "", err
+	}
+	if err := checkSourceName(sourcePath); err != nil {
+		return "", err
+	}
+	// 确保目标目录存在
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return "", fmt.Errorf("创建目标目录失败: %w", err)
+	}
+
+	targetPath := fo.uniqueTargetPath(targetDir, filepath.Base(sourcePath))
+	if err := checkTargetPath(targetDir, targetPath); err != nil {
+		return "", err
+	}
+	if info, err := os.Lstat(sourcePath); err == nil && info.IsDir() {
+		if err := fo.copyTree(sourcePath, targetPath, xattrs); err != nil {
+			return "", err
+		}
+		return targetPath, nil
+	}
+	if err := fo.copyFileContents(sourcePath, targetPath); err != nil {
+		return "", err
+	}
+	fo.preserveXattrs(sourcePath, targetPath, xattrs)
+	return targetPath, nil
+}
+
+// 复制内容不会带上扩展属性，xattrs为true时单独复制。复制失败时只记录警告，文件内容已完整复制
+func (fo *FileOrganizer) preserveXattrs(sourcePath, targetPath string, xattrs bool) {
+	if !xattrs {
+		return
+	}
+	if err := copyExtendedAttrs(sourcePath, targetPath); err != nil {
+		fo.log(fmt.Sprintf("警告: 保留扩展属性失败 %s: %v", targetPath, err))
+	}
+}
+
+// 计算文件内容的SHA-256哈希值，用于去重和导入校验
+func hashFile(path string) (string, error) {
+	return hashFileWith(path, ChecksumSHA256)
+}
+
+// 计算目标文件路径，目标文件已存在时追加时间戳避免覆盖。开启规范化时记下使用的文件名
+func (fo *FileOrganizer) uniqueTargetPath(targetDir, fileName string) string {
+	targetPath := fo.freeTargetPath(targetDir, fileName)
+	if form, ok := normalizationForm(fo.UnicodeNormalization); ok {
+		fo.nameIndex.add(targetDir, filepath.Base(targetPath), form)
+	}
+	return targetPath
+}
+
+// 目标中没有被占用的文件路径，同名文件已存在时追加时间戳。只读取目标，规划时也使用
+func (fo *FileOrganizer) freeTargetPath(targetDir, fileName string) string {
+	// 开启规范化时目标文件名统一为所选形式，并且只有编码不同的同名文件也视为冲突
+	form, normalize := normalizationForm(fo.UnicodeNormalization)
+	if normalize {
+		fileName = form.String(fileName)
+	}
+	targetPath := filepath.Join(targetDir, fileName)
+
+	// 检查目标文件是否已存在
+	_, statErr := statFile(targetPath)
+	exists := statErr == nil
+	if !exists && normalize {
+		exists = fo.nameIndex.contains(targetDir, fileName, form)
+	}
+	if exists {
+		name, ext := splitExtension(fileName)
+		timestamp := time.Now().Format("20060102_150405") // 更精确的时间戳避免冲突
+		for n := 1; ; n++ {
+			candidate := collisionCandidate(name, ext, timestamp, n, fo.CollisionSeparator, fo.CollisionPosition)
+			targetPath = filepath.Join(targetDir, candidate)
+			if _, err := os.Lstat(targetPath); err == nil {
+				continue
+			}
+			if normalize && fo.nameIndex.contains(targetDir, candidate, form) {
+				continue
+			}
+			break
+		}
+	}
+	return targetPath
+}
+
+// 将源文件内容复制到目标路径，失败时删除不完整的目标文件
+func (fo *FileOrganizer) copyFileContents(sourcePath, targetPath string) (err error) {
+	started := time.Now()
+	sourceFile, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("打开源文件失败: %w", err)
+	}
+	defer sourceFile.Close()
+
+	// 先写到部分文件，完成后再改为最终的名称，中断时不会留下看似完整的文件
+	partialPath := targetPath + partialCopySuffix
+	targetFile, err := os.Create(partialPath)
+	if err != nil {
+		return fmt.Errorf("创建目标文件失败: %w", err)
+	}
+	defer func() {
+		targetFile.Close()
+		// 如果发生错误，删除不完整的部分文件
+		if err != nil {
+			os.Remove(partialPath)
+		}
+	}()
+
+	// 设置与源文件相同的权限
+	sourceInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return fmt.Errorf("获取源文件信息失败: %w", err)
+	}
+	targetFile.Chmod(sourceInfo.Mode())
+
+	// 复制文件内容
+	copied, err := io.Copy(faultyWriter(targetFile), sourceFile)
+	perfCounters.bytesRead.Add(copied)
+	perfCounters.bytesWritten.Add(copied)
+	if err != nil {
+		return fmt.Errorf("复制文件内容失败: %w", err)
+	}
+
+	// 同步文件到磁盘，确保数据写入完成
+	targetFile.Sync()
+
+	// 保留源文件的修改时间，按日期整理时复制后的文件仍然属于同一天。
+	// 需要在关闭文件之后设置，部分系统关闭文件时会更新修改时间
+	if err = targetFile.Close(); err != nil {
+		return fmt.Errorf("写入目标文件失败: %w", err)
+	}
+	if err := os.Chtimes(partialPath, time.Now(), sourceInfo.ModTime()); err != nil {
+		fo.log(fmt.Sprintf("警告: 设置 %s 的修改时间失败: %v", targetPath, err))
+	}
+	if err = os.Rename(partialPath, targetPath); err != nil {
+		return fmt.Errorf("完成复制失败: %w", err)
+	}
+	observePerf(perfOpCopy, started)
+	perfCounters.copies.Add(1)
+	return nil
+}
+
+// processSummary 一次整理的结果统计
+type processSummary struct {
+	Checked    int // 检查的文件数
+	Moved      int // 移动的文件数
+	Copied     int // 从只读源文件夹复制的文件数
+	Refiled    int // 在目标文件夹内重新归档的文件数
+	InPlace    int // 已在正确位置的文件数
+	Duplicates int // 目标中已存在而跳过的文件数
+	Unmatched  int // 后缀不在所选列表中的文件数
+	Failed     int // 处理失败的文件数
+	Aborted    int // 中止或取消后未处理的文件数
+	Stats      RunStats
+}
+
+// resultKind 工作协程处理一个文件的结果类别。汇总计数、状态页面的错误和精简日志都按类别判断，
+// 不依赖结果文字，文件名中含有「失败」「跳过」等字样时不会被误算
+type resultKind int
+
+const (
+	resultInfo           resultKind = iota // 其他结果：待打包、已隔离的空文件等
+	resultMoved                            // 已移动
+	resultCopied                           // 从只读源文件夹复制
+	resultRefiled                          // 在目标文件夹内重新归档
+	resultInPlace                          // 已在正确位置
+	resultPinned                           // 已固定，留在原处
+	resultNestedTarget                     // 已在源文件夹中的目标文件夹里
+	resultUnsafeName                       // 文件名不安全
+	resultDuplicate                        // 目标中已有内容相同的文件
+	resultMergeIdentical                   // 合并时目标中已有同名的相同文件
+	resultSkipped                          // 按设置跳过：后缀不符、已排除、空文件等
+	resultNotice                           // 跳过，但需要用户注意，例如无法解析的快捷方式
+	resultFailed                           // 处理失败
+	resultAborted                          // 中止或取消后未处理
+)
+
+// 精简日志时不逐个列出的结果：按设置跳过、已固定、已在目标文件夹中和合并时的相同文件。
+// 重复文件、需要注意的跳过和失败照常列出
+func quietHides(kind resultKind) bool {
+	switch kind {
+	case resultSkipped, resultPinned, resultNestedTarget, resultMergeIdentical:
+		return true
+	}
+	return false
+}
+
+// fileResult 工作协程处理一个文件的结果
+type fileResult struct {
+	kind    resultKind
+	message string
+}
+
+// 整理指定的文件
+func (fo *FileOrganizer) processFiles(config Config, files []string) (processSummary, error) {
+	return fo.runProcess(config, files, nil)
+}
+
+// fileFeed 流式整理时边扫描边提供要整理的文件，不保留文件列表。emit 返回false时（整理已中止）停止扫描。
+// estimate 是之前扫描到的文件数，扫描完成前用于显示进度
+type fileFeed struct {
+	scan     func(emit func(path string) bool)
+	estimate int
+}
+
+// 整理一次：文件来自列表，或者流式整理时来自 feed（此时 files 为空）。锁定、建立索引、统计和整理后的
+// 处理在整次整理中只进行一次
+func (fo *FileOrganizer) runProcess(config Config, files []string, feed *fileFeed) (_ processSummary, err error) {
+	// 整理没有完成就出错返回时，状态页面显示为失败
+	defer func() {
+		if err != nil {
+			fo.status.processFailed(err)
+		}
+	}()
+	if err := fo.checkWritable(); err != nil {
+		return processSummary{}, err
+	}
+	config = config.frozen()
+
+	// 锁定目标文件夹，其他电脑正在整理同一个目标时不开始
+	runID := newRunID()
+	unlock, err := fo.lockTarget(config.TargetDir, runID)
+	if err != nil {
+		return processSummary{}, err
+	}
+	defer unlock()
+
+	// 记下本次整理的文件，强制退出后下次启动时可以继续
+	journal := fo.beginRunJournal(runID, config, files, feed != nil)
+	defer fo.endRunJournal(journal)
+
+	// 仅处理新增时去掉上次成功整理时已有的文件，在其他预处理之前进行。整理开始时已有的文件作为下次的基准，
+	// 整理期间才出现的文件不在基准中，下次仍按新增文件整理
+	sourceFiles := files
+	incoming := config.IncomingBaseline
+	if config.IncomingOnly && incoming == nil {
+		incoming = fo.incomingBaselineOf(config, files)
+		files = fo.filterIncoming(config, files)
+	}
+
+	// 显示找到的文件总数，流式整理时扫描完成前只有估计的数量
+	total := len(files)
+	if feed != nil {
+		total = feed.estimate
+		fo.log(fmt.Sprintf("流式整理: 边扫描边整理，之前扫描到约 %d 个文件", total))
+	} else {
+		fo.log(fmt.Sprintf("将处理 %d 个文件", len(files)))
+	}
+	fo.ui.ProcessStarted(total)
+
+	// 源快照在任何文件移动之前记录，保存失败时不开始整理
+	if config.SourceSnapshot != "" {
+		if err := fo.takeSourceSnapshot(config, runID, sourceFiles); err != nil {
+			return processSummary{}, fmt.Errorf("%w，未开始整理", err)
+		}
+	}
+
+	// 目标文件夹可能在两次整理之间发生变化，每次整理重新建立文件名索引
+	fo.nameIndex.reset()
+
+	// 按后缀和目标文件夹统计本次整理
+	stats := newRunStatsCollector(config)
+	perf := startPerfRun()
+
+	// Google 相册导出：合并「(n)」副本，元数据文件随照片移动，在其他副本合并之前进行
+	var takeout *takeoutPlan
+	if config.TakeoutMode {
+		files, takeout = fo.planTakeout(config, sourceFiles, files)
+	}
+
+	// 整理前合并内容相同的副本，被处理的副本不再整理，改回原文件名的文件按新路径整理
+	var renamedFrom map[string]string
+	if config.CopyMerge != "" && config.CopyMerge != CopyMergeOff {
+		files, renamedFrom = fo.mergeCopiesBeforeProcessing(config, files, takeout)
+	}
+
+	// 整理快捷方式指向的文件时先解析快捷方式，目标文件加入本次整理
+	var shortcuts *shortcutPlan
+	if config.ShortcutPolicy == ShortcutResolve {
+		files, shortcuts = fo.planShortcuts(config, files)
+	}
+
+	// 定时整理、任务和监视文件夹没有预览，按容量分卷时在这里分配分卷
+	if config.VolumeCapMB > 0 && config.CapacityPlan == nil {
+		plan, err := fo.planCapacityVolumes(config, files)
+		if err != nil {
+			return processSummary{}, fmt.Errorf("按容量分卷失败: %w", err)
+		}
+		config.CapacityPlan = plan
+		for _, line := range describeCapacityPlan(plan) {
+			fo.log(line)
+		}
+	}
+
+	// 创建工作池进行并行处理。流式整理时通道只缓存一部分文件，扫描在整理跟不上时等待
+	bufferSize := len(files)
+	if feed != nil {
+		bufferSize = streamingBufferSize
+	}
+	fileChan := make(chan string, bufferSize)
+	resultChan := make(chan fileResult, bufferSize)
+	var wg sync.WaitGroup
+
+	// 基于CPU核心数和文件数量智能调整工作协程数
+	cpuCount := runtime.NumCPU()
+	numWorkers := cpuCount
+	if total < config.ParallelThreshold {
+		numWorkers = config.SmallSetWorkers
+		fo.log(fmt.Sprintf("文件数 %d 少于并行阈值 %d，使用少量文件的工作协程数", total, config.ParallelThreshold))
+	} else if numWorkers > 10 {
+		numWorkers = 10 // 限制最大工作协程数，避免过多资源消耗
+		fo.log(fmt.Sprintf("文件数 %d 达到并行阈值 %d，按CPU核心数 %d 并行（上限 10）", total, config.ParallelThreshold, cpuCount))
+	} else {
+		fo.log(fmt.Sprintf("文件数 %d 达到并行阈值 %d，按CPU核心数 %d 并行", total, config.ParallelThreshold, cpuCount))
+	}
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	fo.log(fmt.Sprintf("将使用 %d 个工作协程进行处理", numWorkers))
+
+	// 本次待整理的文件
+	pending := make(map[string]bool, len(files))
+	for _, filePath := range files {
+		pending[filePath] = true
+	}
+
+	// 已有的未加标签的日期文件夹中属于事件范围的文件先合并到事件文件夹。流式整理时事先不知道要整理哪些文件
+	if feed != nil && OrganizeRule(config.OrganizeRule) == RuleByDate && len(config.EventLabels) > 0 {
+		fo.log("流式整理时不合并已有的未加标签的日期文件夹，新整理的文件照常放入事件文件夹")
+	}
+	fo.mergeEventFolders(config, pending)
+
+	// 目标去重：整理前加载并更新目标文件夹的大小→哈希索引，本次待整理的文件不计入索引
+	var targetIndex *targetHashIndex
+	if config.DedupTarget {
+		fo.log("正在建立目标文件索引...")
+		building := false
+		if b := fo.runningIndexBuilder(); b != nil && b.root == config.TargetDir {
+			building = true
+		}
+		index, err := loadTargetHashIndex(config.TargetDir, pending, building)
+		if err != nil {
+			fo.log(fmt.Sprintf("目标去重不可用: %v", err))
+		} else {
+			targetIndex = index
+			fo.log(fmt.Sprintf("目标文件索引已就绪，共 %d 个文件", targetIndex.len()))
+			if targetIndex.incomplete() {
+				fo.log("目标文件索引仍在后台建立中，本次只与已计算哈希的文件比较，其余文件不做去重")
+			}
 		}
 	}
 
-	// 如果重命名失败，尝试复制后删除原文件
-	sourceFile, err := os.Open(sourcePath)
-	if err != nil {
-		return fmt.Errorf("打开源文件失败: %w", err)
+	// 记录本次移入文件的日期文件夹及其对应日期，处理完成后统一设置修改时间
+	setDateFolderMtime := config.DateFolderMtime && OrganizeRule(config.OrganizeRule) == RuleByDate && config.FolderLayout != LayoutNamePrefix
+	dateFolders := make(map[string]time.Time)
+	var dateFoldersMu sync.Mutex
+
+	// 各日期来源的使用次数，在总结中显示
+	usesDate := ruleUsesDate(config)
+	dateHits := make(map[DateSource]int)
+	var dateHitsMu sync.Mutex
+
+	// 校验清单：记录每个移入目标的文件的最终路径和哈希值
+	var manifest *checksumManifest
+	if config.ChecksumAlgorithm != "" && config.ChecksumAlgorithm != ChecksumNone {
+		manifest = newChecksumManifest(config.TargetDir, config.ChecksumAlgorithm)
 	}
-	defer sourceFile.Close()
 
-	targetFile, err := os.Create(targetPath)
-	if err != nil {
-		return fmt.Errorf("创建目标文件失败: %w", err)
+	// 目标在FAT/exFAT上时修改时间只精确到2秒，校验修改时间时允许误差，并在整理目录中记录所用的日期
+	targetFS, coarseTarget := coarseMtimeFilesystem(config.TargetDir)
+	if coarseTarget {
+		fo.warnCoarseMtime(config.TargetDir, targetFS)
+		// 重新归档目标中已有的文件时使用整理目录中记录的日期
+		if usesDate {
+			dates, err := loadRecordedDates(catalogPath(), config.TargetDir)
+			if err != nil {
+				fo.log(fmt.Sprintf("读取整理目录中记录的日期失败，重新归档的文件按修改时间整理: %v", err))
+			}
+			config.RecordedDates = dates
+		}
 	}
-	defer func() {
-		targetFile.Close()
-		// 如果发生错误，删除可能创建的不完整目标文件
-		if err != nil && targetPath != "" {
-			os.Remove(targetPath)
+	// 文件名前缀模式下重新归档目标中已有的文件时，按整理目录中记录的前缀去掉以前加上的前缀
+	if config.FolderLayout == LayoutNamePrefix {
+		prefixes, err := loadNamePrefixes(catalogPath(), config.TargetDir)
+		if err != nil {
+			fo.log(fmt.Sprintf("读取整理目录中记录的文件名前缀失败，按前缀的形式识别: %v", err))
 		}
-	}()
-
-	// 设置与源文件相同的权限
-	sourceInfo, err := os.Stat(sourcePath)
-	if err != nil {
-		return fmt.Errorf("获取源文件信息失败: %w", err)
+		config.NamePrefixes = prefixes
 	}
-	targetFile.Chmod(sourceInfo.Mode())
 
-	// 复制文件内容
-	_, err = io.Copy(targetFile, sourceFile)
-	if err != nil {
-		return fmt.Errorf("复制文件内容失败: %w", err)
+	// 整理目录：批量记录每个文件的去向，目录写入失败只停用目录，不影响整理
+	var catalog *fileCatalog
+	if config.CatalogEnabled {
+		if c, err := openCatalog(catalogPath()); err != nil {
+			fo.log(fmt.Sprintf("整理目录不可用: %v", err))
+		} else {
+			catalog = c
+			fo.runCatalog.Store(c)
+		}
 	}
 
-	// 同步文件到磁盘，确保数据写入完成
-	targetFile.Sync()
+	// 打包小文件：工作协程只记下文件，全部处理完后按压缩包写入
+	var packer *smallFilePacker
+	if packsSmallFiles(config) {
+		packer = newSmallFilePacker()
+	}
+	planCtx := planContext{shortcuts: shortcuts, takeout: takeout, targetIndex: targetIndex, packer: packer}
 
-	// 复制成功后删除源文件
-	err = os.Remove(sourcePath)
-	if err != nil {
-		// 删除失败时记录警告但不返回错误，因为文件已经成功复制
-		fo.log(fmt.Sprintf("警告: 已成功复制文件但无法删除原文件 %s: %v", sourcePath, err))
+	// 移出过文件的源子文件夹，整理后检查是否变空
+	movedFromDirs := make(map[string]bool)
+	var movedFromMu sync.Mutex
+	recordMovedFrom := func(filePath string) {
+		if config.RemoveEmptiedDirs {
+			movedFromMu.Lock()
+			movedFromDirs[filepath.Dir(filePath)] = true
+			movedFromMu.Unlock()
+		}
 	}
 
-	return nil
-}
+	// 因后缀不在所选列表中跳过的文件数和符合所选后缀的文件数（按小写的后缀），
+	// 跳过太多并且有所选的后缀没有匹配任何文件时在总结中提示
+	unmatchedExtensions := make(map[string]int)
+	matchedExtensions := make(map[string]int)
+	var unmatchedMu sync.Mutex
 
-// 处理文件夹中的文件
-func (fo *FileOrganizer) processFiles(config Config) error {
-	// 显示找到的文件总数
-	fo.log(fmt.Sprintf("将处理 %d 个文件", len(fo.scannedFiles)))
+	// 空文件的数量，在总结中显示
+	emptyCount := 0
+	var emptyMu sync.Mutex
 
-	// 创建工作池进行并行处理
-	fileChan := make(chan string, len(fo.scannedFiles))
-	resultChan := make(chan string, len(fo.scannedFiles))
-	var wg sync.WaitGroup
+	// 覆盖的同名文件：备份后覆盖的和直接覆盖的分开统计，在总结中显示。
+	// 合并到已有文件夹时内容不同、加了时间戳的同名文件也在这里统计
+	replacedCount, overwrittenCount, mergeRenamedCount := 0, 0, 0
+	var replacedMu sync.Mutex
 
-	// 基于CPU核心数和文件数量智能调整工作协程数
-	cpuCount := runtime.NumCPU()
-	numWorkers := cpuCount
-	if len(fo.scannedFiles) < 20 {
-		numWorkers = 2
-	} else if numWorkers > 10 {
-		numWorkers = 10 // 限制最大工作协程数，避免过多资源消耗
+	// 目标磁盘中途变为只读时暂停整理，由用户选择重试、改用其他目标或中止
+	gate := newReadOnlyGate(config.TargetDir, fo.ui.ReadOnlyTarget, func(newRoot string) (func(), error) {
+		return fo.lockRedirectTarget(config, runID, newRoot)
+	})
+	defer gate.release()
+	abortedCount := 0
+	var abortedMu sync.Mutex
+
+	// 随照片移动或复制的元数据文件记入整理目录，撤销时一起移回或删除
+	recordSidecar := func(sidecar, sidecarPath string, copied bool, runConfig Config) {
+		if catalog == nil {
+			return
+		}
+		size := int64(0)
+		if info, statErr := os.Stat(sidecarPath); statErr == nil {
+			size = info.Size()
+		}
+		entry := CatalogEntry{
+			RunID:        runID,
+			OriginalPath: sidecar,
+			FinalPath:    sidecarPath,
+			Size:         size,
+			Volume:       volumeOf(sidecarPath, runConfig.Volumes),
+			MovedAt:      time.Now(),
+		}
+		if copied {
+			entry.Operation = CatalogCopy
+		}
+		catalog.add(entry)
 	}
 
-	fo.log(fmt.Sprintf("将使用 %d 个工作协程进行处理", numWorkers))
+	// 处理单个文件，retrying为true时表示处理之前因只读错误推迟的文件
+	processOne := func(workerID int, filePath string, retrying bool) {
+		targetRoot, aborted := gate.wait()
+		if aborted || fo.cancelProcessing.Load() {
+			abortedMu.Lock()
+			abortedCount++
+			abortedMu.Unlock()
+			resultChan <- fileResult{resultAborted, fmt.Sprintf("[工作协程 %d] 已中止，未处理: %s", workerID, filePath)}
+			return
+		}
+		// 处理完的文件从整理记录中去掉并丢弃缓存的哈希，推迟重试的文件仍未处理
+		deferred := false
+		defer func() {
+			if !deferred {
+				journal.markDone(filePath)
+				fo.contentHashes.forget(filePath)
+			}
+		}()
+		// 改用其他目标后，剩余文件按新的目标根文件夹重新规划
+		runConfig := config
+		runConfig.TargetDir = targetRoot
 
-	// 启动工作协程
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			for filePath := range fileChan {
-				// 获取文件信息
-				fileInfo, err := os.Stat(filePath)
-				if err != nil {
-					resultChan <- fmt.Sprintf("[工作协程 %d] 获取文件信息失败 %s: %v", workerID, filePath, err)
-					continue
+		// 排除的文件、目标文件夹的锁和部分文件等只由路径决定的跳过，与规则测试共用
+		if reason, kind := pathSkipReason(filePath, runConfig); reason != "" {
+			resultChan <- fileResult{kind, fmt.Sprintf("[工作协程 %d] %s", workerID, reason)}
+			return
+		}
+
+		// 获取文件信息
+		fileInfo, err := os.Stat(filePath)
+		if err != nil {
+			resultChan <- fileResult{resultFailed, fmt.Sprintf("[工作协程 %d] 获取文件信息失败 %s: %v", workerID, filePath, err)}
+			return
+		}
+
+		// 跳过、目标去重、目标文件夹和改名等判断与规则测试共用同一个规划
+		plan := fo.planFile(filePath, fileInfo, runConfig, planCtx)
+		unmatchedMu.Lock()
+		if plan.Unmatched {
+			unmatchedExtensions[strings.ToLower(fileExtension(filePath))]++
+		} else {
+			matchedExtensions[strings.ToLower(fileExtension(filePath))]++
+		}
+		unmatchedMu.Unlock()
+		if plan.Empty {
+			emptyMu.Lock()
+			emptyCount++
+			emptyMu.Unlock()
+		}
+		// 统计按日期整理时各日期来源的使用次数
+		dateSource := plan.DateSource
+		if dateSource != "" {
+			dateHitsMu.Lock()
+			dateHits[dateSource]++
+			dateHitsMu.Unlock()
+		}
+		if plan.Skip != "" {
+			// 目标中已有相同的照片时，Google 相册导出的元数据文件复制到已有的照片旁边，原文件留在跳过的照片旁边
+			if sidecar := takeout.sidecarOf(filePath); sidecar != "" && plan.Existing != "" {
+				if sidecarPath, copied, sidecarErr := fo.adoptTakeoutSidecar(sidecar, plan.Existing, runConfig.PreserveXattrs); sidecarErr != nil {
+					fo.log(fmt.Sprintf("[工作协程 %d] 警告: 元数据文件未能复制到已有的照片旁边 %s: %v", workerID, sidecar, sidecarErr))
+				} else if copied {
+					recordSidecar(sidecar, sidecarPath, true, runConfig)
 				}
+			}
+			resultChan <- fileResult{plan.Kind, fmt.Sprintf("[工作协程 %d] %s", workerID, plan.Skip)}
+			return
+		}
+		targetDir := plan.TargetDir
+		isPackage, readOnlySource, refile := plan.Package, plan.ReadOnly, plan.Refile
+		sourceHash, hashName, prefixedName := plan.SourceHash, plan.HashName, plan.PrefixedName
 
-				// 检查文件后缀
-				fileExt := filepath.Ext(filePath)
-				if !fo.isTargetFile(fileExt, config.FileExtensions) {
-					resultChan <- fmt.Sprintf("[工作协程 %d] 跳过不符合后缀的文件: %s", workerID, filePath)
-					continue
+		// 只读源文件夹中的文件只复制，源文件保持不变；已经在目标文件夹中的文件只在归档内部重新归档
+		transfer := func(sourcePath, targetDir string) (string, error) {
+			return fo.moveFile(sourcePath, targetDir, runConfig.PreserveXattrs)
+		}
+		if readOnlySource {
+			transfer = func(sourcePath, targetDir string) (string, error) {
+				return fo.copyFile(sourcePath, targetDir, runConfig.PreserveXattrs)
+			}
+		}
+		if refile {
+			transfer = func(sourcePath, targetDir string) (string, error) {
+				return fo.refileFile(sourcePath, targetDir, runConfig.PreserveXattrs)
+			}
+		}
+		if plan.RenameInPlace {
+			transfer = func(sourcePath, _ string) (string, error) {
+				return fo.renameInPlace(sourcePath, prefixedName)
+			}
+		}
+
+		// 空文件移到隔离文件夹
+		if plan.Quarantine {
+			if _, err := transfer(filePath, targetDir); err != nil {
+				if isReadOnlyError(err) && !retrying {
+					deferred = true
+					gate.failure(filePath, targetRoot)
+					return
 				}
+				resultChan <- fileResult{resultFailed, fmt.Sprintf("[工作协程 %d] 隔离空文件失败 %s: %v", workerID, filePath, err)}
+				return
+			}
+			gate.success()
+			if !readOnlySource {
+				recordMovedFrom(filePath)
+			}
+			resultChan <- fileResult{resultInfo, fmt.Sprintf("[工作协程 %d] 已隔离空文件: %s -> %s", workerID, filepath.Base(filePath), targetDir)}
+			return
+		}
 
-				// 确定目标文件夹路径
-				targetDir := ""
-				switch OrganizeRule(config.OrganizeRule) {
-				case RuleByDate:
-					// 按日期组织
-					modifyDate := fo.getFileModifyDate(fileInfo, config.FolderDateFormat)
-					targetDir = filepath.Join(config.TargetDir, modifyDate)
-				case RuleByExtension:
-					// 按文件后缀组织
-					tempFileExt := filepath.Ext(filePath) // 使用不同的变量名避免重复定义
-					if config.ExtensionCase == "uppercase" {
-						tempFileExt = strings.ToUpper(tempFileExt)
+		// 多标签文件按设置复制到其余标签对应的文件夹
+		if OrganizeRule(runConfig.OrganizeRule) == RuleByTag && runConfig.MultiTagMode == MultiTagDuplicate {
+			if tags, tagErr := readFileTags(filePath); tagErr == nil && len(tags) > 1 {
+				for _, tag := range tags[1:] {
+					tagDir := layoutTargetDir(filePath, fo.applyFolderTemplate(filePath, fileInfo, sanitizeFolderName(tag), runConfig), runConfig)
+					if tagDir == targetDir {
+						continue
+					}
+					if _, copyErr := fo.copyFile(filePath, tagDir, runConfig.PreserveXattrs); copyErr != nil {
+						fo.log(fmt.Sprintf("[工作协程 %d] 复制到标签文件夹失败 %s: %v", workerID, filePath, copyErr))
 					} else {
-						tempFileExt = strings.ToLower(tempFileExt)
+						fo.log(fmt.Sprintf("[工作协程 %d] 已复制: %s -> %s", workerID, filepath.Base(filePath), tagDir))
 					}
-					targetDir = filepath.Join(config.TargetDir, tempFileExt)
 				}
+			}
+		}
 
-				// 移动文件
-				err = fo.moveFile(filePath, targetDir)
-				if err != nil {
-					resultChan <- fmt.Sprintf("[工作协程 %d] 移动文件失败 %s: %v", workerID, filePath, err)
-					continue
+		// 小文件记下后等全部文件处理完再放入压缩包
+		if plan.Archive != "" {
+			archivePath := plan.Archive
+			item := packItem{source: filePath, folder: targetDir, size: fileInfo.Size(), modTime: fileInfo.ModTime(),
+				mode: fileInfo.Mode(), hash: sourceHash, copyOnly: readOnlySource}
+			if coarseTarget && usesDate {
+				item.date = plan.Date.Format(eventDateLayout)
+			}
+			packer.add(archivePath, item)
+			if targetIndex != nil {
+				targetIndex.addPacked(archivePath, filePath, fileInfo.Size(), sourceHash)
+			}
+			if !readOnlySource {
+				recordMovedFrom(filePath)
+			}
+			resultChan <- fileResult{resultInfo, fmt.Sprintf("[工作协程 %d] 待打包: %s -> %s", workerID, filepath.Base(filePath), archivePath)}
+			return
+		}
+
+		// HEIC等格式先转换后放入目标，原始文件按设置保留或删除；转换失败时按原样整理
+		converted := false
+		if plan.Convert {
+			convertedPath, convErr := fo.convertImage(filePath, targetDir)
+			stats.recordConversion(convErr == nil)
+			if convErr != nil {
+				fo.log(fmt.Sprintf("[工作协程 %d] 警告: %s 转换失败，按原样整理: %v", workerID, filePath, convErr))
+				// 按原样整理时与其他文件一样处理目标中的同名文件
+				fo.planConflict(&plan, filePath, fileInfo, runConfig)
+				if plan.Skip != "" {
+					resultChan <- fileResult{plan.Kind, fmt.Sprintf("[工作协程 %d] %s", workerID, plan.Skip)}
+					return
+				}
+			} else {
+				switch {
+				case readOnlySource:
+					// 只读源文件夹中的原始文件保持不变
+				case runConfig.KeepConverted:
+					originalsDir := convertedOriginalsDir(runConfig.TargetDir, targetDir)
+					if _, keepErr := fo.moveFile(filePath, originalsDir, runConfig.PreserveXattrs); keepErr != nil {
+						fo.log(fmt.Sprintf("[工作协程 %d] 警告: 保留原始文件失败，原始文件留在原处 %s: %v", workerID, filePath, keepErr))
+					}
+				default:
+					if removeErr := fo.removeConvertedSource(filePath); removeErr != nil {
+						fo.log(fmt.Sprintf("[工作协程 %d] 警告: 已转换但无法删除原始文件 %s: %v", workerID, filePath, removeErr))
+					}
+				}
+				// 目标去重按转换后的文件重新计算哈希
+				sourceHash = ""
+				converted = true
+				transfer = func(string, string) (string, error) {
+					return convertedPath, nil
+				}
+			}
+		}
+
+		// 合并模式下目标中已有同名但内容不同的文件，移动时加时间戳
+		mergeRenamed := plan.MergeRenamed
+
+		// 覆盖模式下先把目标中的同名文件移到覆盖备份（或临时改名），新文件随后使用原来的文件名。
+		// 新文件放入失败时把已有文件放回原处。备份记录在整理目录中，撤销时按相反的顺序先移回新文件，再恢复被覆盖的文件
+		var displaced *displacedFile
+		if runConfig.ConflictPolicy == ConflictOverwrite && !refile && hashName == "" && prefixedName == "" && !converted {
+			var replaceErr error
+			displaced, replaceErr = fo.displaceTarget(filePath, filepath.Join(targetDir, fo.normalizeName(filepath.Base(filePath))), runConfig, runID)
+			if replaceErr != nil {
+				resultChan <- fileResult{resultFailed, fmt.Sprintf("[工作协程 %d] 覆盖失败 %s: %v", workerID, filePath, replaceErr)}
+				return
+			}
+		}
+
+		// 移动文件，只读源文件夹中的文件改为复制
+		movedPath, err := transfer(filePath, targetDir)
+		if err != nil && displaced != nil {
+			if restoreErr := fo.restoreDisplaced(displaced); restoreErr != nil {
+				fo.log(fmt.Sprintf("[工作协程 %d] 警告: %v", workerID, restoreErr))
+			}
+		}
+		if err != nil {
+			// 只读错误先推迟，整理结束前再试一次；连续出现时暂停整理
+			if isReadOnlyError(err) && !retrying {
+				deferred = true
+				gate.failure(filePath, targetRoot)
+				return
+			}
+			if readOnlySource {
+				resultChan <- fileResult{resultFailed, fmt.Sprintf("[工作协程 %d] 复制文件失败 %s: %v", workerID, filePath, err)}
+			} else {
+				resultChan <- fileResult{resultFailed, fmt.Sprintf("[工作协程 %d] 移动文件失败 %s: %v", workerID, filePath, err)}
+			}
+			return
+		}
+		gate.success()
+		if !readOnlySource {
+			recordMovedFrom(filePath)
+		}
+		if displaced != nil {
+			if discardErr := fo.discardDisplaced(displaced); discardErr != nil {
+				fo.log(fmt.Sprintf("[工作协程 %d] 警告: %v", workerID, discardErr))
+			}
+			replacedMu.Lock()
+			if displaced.backup {
+				replacedCount++
+			} else {
+				overwrittenCount++
+			}
+			replacedMu.Unlock()
+			if displaced.backup && catalog != nil {
+				size := int64(0)
+				if info, statErr := os.Stat(displaced.moved); statErr == nil {
+					size = info.Size()
 				}
+				catalog.add(CatalogEntry{
+					RunID:        runID,
+					OriginalPath: displaced.original,
+					FinalPath:    displaced.moved,
+					Size:         size,
+					Volume:       volumeOf(displaced.moved, runConfig.Volumes),
+					Replaced:     true,
+					MovedAt:      time.Now(),
+				})
+			}
+			if displaced.backup {
+				fo.log(fmt.Sprintf("[工作协程 %d] 覆盖前已备份: %s -> %s", workerID, displaced.original, displaced.moved))
+			} else {
+				fo.log(fmt.Sprintf("[工作协程 %d] 已删除被覆盖的文件: %s", workerID, displaced.original))
+			}
+		}
+		if mergeRenamed {
+			replacedMu.Lock()
+			mergeRenamedCount++
+			replacedMu.Unlock()
+		}
+		if hashName != "" {
+			// 移动到分片文件夹后在同一文件夹内改名，不会跨设备
+			hashedPath := filepath.Join(targetDir, hashName)
+			if err := os.Rename(movedPath, hashedPath); err != nil {
+				fo.log(fmt.Sprintf("[工作协程 %d] 改名为哈希值失败 %s: %v", workerID, movedPath, err))
+			} else {
+				movedPath = hashedPath
+			}
+		}
+		if prefixedName != "" && !plan.RenameInPlace && filepath.Base(movedPath) != prefixedName {
+			// 移动到目标根目录后再加前缀；转换过的文件使用转换后的后缀
+			stem, _ := splitExtension(prefixedName)
+			_, ext := splitExtension(movedPath)
+			if renamedPath, err := fo.renameInPlace(movedPath, stem+ext); err != nil {
+				fo.log(fmt.Sprintf("[工作协程 %d] %s: %v", workerID, movedPath, err))
+			} else {
+				movedPath = renamedPath
+			}
+		}
+		if targetIndex != nil && !isPackage {
+			targetIndex.add(movedPath, sourceHash)
+		}
+		checksum := ""
+		if manifest != nil && !isPackage {
+			// 去重时已计算过SHA-256，算法相同时直接使用
+			hash := ""
+			if runConfig.ChecksumAlgorithm == ChecksumSHA256 {
+				hash = sourceHash
+			}
+			if hash, err := manifest.add(movedPath, hash); err != nil {
+				fo.log(fmt.Sprintf("[工作协程 %d] 计算校验值失败 %s: %v", workerID, movedPath, err))
+			} else {
+				checksum = runConfig.ChecksumAlgorithm + ":" + hash
+			}
+		}
+
+		if setDateFolderMtime {
+			// 规则优先时日期文件夹是源子目录的上级
+			dateDir := targetDir
+			if runConfig.FolderLayout == LayoutRuleFirst {
+				dateDir = runConfig.VolumePlan.remap(filepath.Join(runConfig.TargetDir, fo.ruleFolderName(filePath, fileInfo, runConfig)))
+				dateDir = runConfig.CapacityPlan.remap(filePath, dateDir)
+			}
+			modTime := fo.fileDate(filePath, fileInfo, runConfig)
+			dateFoldersMu.Lock()
+			dateFolders[dateDir] = time.Date(modTime.Year(), modTime.Month(), modTime.Day(), 0, 0, 0, 0, modTime.Location())
+			dateFoldersMu.Unlock()
+		}
+
+		// 复制的文件应保留源文件的修改时间，否则以后按日期整理时会放到别的日期
+		if movedInfo, statErr := os.Stat(movedPath); statErr == nil && !sameModTime(movedInfo.ModTime(), fileInfo.ModTime(), coarseTarget) {
+			fo.log(fmt.Sprintf("[工作协程 %d] 警告: %s 的修改时间与源文件不同（%s / %s）", workerID, movedPath,
+				movedInfo.ModTime().Format("2006-01-02 15:04:05"), fileInfo.ModTime().Format("2006-01-02 15:04:05")))
+		}
+
+		stats.record(filePath, targetDir, fileInfo.Size())
+		if incoming != nil {
+			incoming.organized(filePath, movedPath, readOnlySource)
+		}
+		if catalog != nil {
+			entry := CatalogEntry{
+				RunID:        runID,
+				OriginalPath: filePath,
+				OriginalName: renamedFrom[filePath],
+				FinalPath:    movedPath,
+				Size:         fileInfo.Size(),
+				Hash:         sourceHash,
+				Checksum:     checksum,
+				Volume:       volumeOf(movedPath, runConfig.Volumes),
+				NamePrefix:   plan.NamePrefix,
+				MovedAt:      time.Now(),
+			}
+			if coarseTarget && usesDate {
+				entry.Date = fo.fileDate(filePath, fileInfo, runConfig).Format(eventDateLayout)
+			}
+			if readOnlySource {
+				entry.Operation = CatalogCopy
+			}
+			catalog.add(entry)
+		}
+		// Google 相册导出的元数据文件放到照片旁边
+		if sidecar := takeout.sidecarOf(filePath); sidecar != "" {
+			sidecarPath, sidecarErr := fo.placeTakeoutSidecar(sidecar, movedPath, readOnlySource, runConfig.PreserveXattrs)
+			if sidecarErr != nil {
+				fo.log(fmt.Sprintf("[工作协程 %d] 警告: 元数据文件未能随照片移动 %s: %v", workerID, sidecar, sidecarErr))
+			} else {
+				recordSidecar(sidecar, sidecarPath, readOnlySource, runConfig)
+			}
+		}
+		if !readOnlySource {
+			fo.updateShortcutsFor(workerID, shortcuts, filePath, movedPath)
+		}
+		fo.runFileHook(runConfig, filePath, movedPath)
+		// 详细日志附带源路径、大小和日期来源
+		detail := ""
+		if runConfig.LogVerbosity == LogVerbose {
+			detail = fmt.Sprintf("（源: %s，%s", filePath, formatFileSize(fileInfo.Size()))
+			if dateSource != "" {
+				detail += "，日期来自" + dateSourceNames[dateSource]
+			}
+			detail += "）"
+		}
+		if refile {
+			stats.recordRefile(fileInfo.Size())
+			resultChan <- fileResult{resultRefiled, fmt.Sprintf("[工作协程 %d] 已重新归档: %s -> %s%s", workerID, filepath.Base(filePath), targetDir, detail)}
+			return
+		}
+		if readOnlySource {
+			resultChan <- fileResult{resultCopied, fmt.Sprintf("[工作协程 %d] 已复制（只读源）: %s -> %s%s", workerID, filepath.Base(filePath), targetDir, detail)}
+			return
+		}
+		resultChan <- fileResult{resultMoved, fmt.Sprintf("[工作协程 %d] 已移动: %s -> %s%s", workerID, filepath.Base(filePath), targetDir, detail)}
+	}
 
-				resultChan <- fmt.Sprintf("[工作协程 %d] 已移动: %s -> %s", workerID, filepath.Base(filePath), targetDir)
+	// 启动工作协程
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			if config.LowPriority {
+				fo.lowerWorkerPriority()
+			}
+			for filePath := range fileChan {
+				started := time.Now()
+				processOne(workerID, filePath, false)
+				observePerf(perfOpFile, started)
+				perf.sampleGoroutines()
 			}
 		}(i + 1) // 传递工作协程ID
 	}
 
-	// 分发任务
-	for _, filePath := range fo.scannedFiles {
-		fileChan <- filePath
+	// 分发任务，与下面的结果处理同时进行。流式整理时边扫描边分发，整理中止后停止扫描
+	var dispatched atomic.Int64
+	var scanDone atomic.Bool
+	go func() {
+		defer close(fileChan)
+		if feed == nil {
+			for _, filePath := range files {
+				fileChan <- filePath
+			}
+			return
+		}
+		feed.scan(func(filePath string) bool {
+			if fo.cancelProcessing.Load() {
+				return false
+			}
+			fileChan <- filePath
+			dispatched.Add(1)
+			return true
+		})
+		scanDone.Store(true)
+	}()
+	// 进度的总数，流式整理时扫描完成前取估计值和已分发的文件数中较大的
+	progressTotal := func() int {
+		if feed == nil {
+			return len(files)
+		}
+		n := int(dispatched.Load())
+		if !scanDone.Load() && n < feed.estimate {
+			return feed.estimate
+		}
+		return n
 	}
-	close(fileChan)
 
-	// 等待所有工作协程完成并关闭结果通道
+	// 等待所有工作协程完成，再处理因只读错误推迟的文件，最后关闭结果通道
 	go func() {
 		wg.Wait()
+		for _, filePath := range gate.takeDeferred() {
+			processOne(0, filePath, true)
+		}
 		close(resultChan)
 	}()
 
 	// 处理结果
 	fileCount := 0
+	copiedCount := 0
+	refiledCount := 0
+	inPlaceCount := 0
+	pinnedCount := 0
+	nestedTargetCount := 0
+	unsafeNameCount := 0
+	duplicateCount := 0
+	mergeIdenticalCount := 0
+	failedCount := 0
 	processedCount := 0
 	updateCounter := 0
 	updateThreshold := 200 // 大幅增加阈值，显著减少UI更新频率
 	logBulkSize := 50      // 每50条结果合并为一条日志
 	var logBuffer strings.Builder
 	logCount := 0
+	quietSkipped := 0 // 精简日志时未逐个列出的跳过记录
 
 	for result := range resultChan {
 		processedCount++
 		updateCounter++
-		if strings.HasPrefix(result, "[工作协程") && strings.Contains(result, "已移动") {
+		switch result.kind {
+		case resultMoved:
 			fileCount++
+		case resultCopied:
+			copiedCount++
+		case resultRefiled:
+			refiledCount++
+		case resultInPlace:
+			inPlaceCount++
+		case resultPinned:
+			pinnedCount++
+		case resultNestedTarget:
+			nestedTargetCount++
+		case resultUnsafeName:
+			unsafeNameCount++
+		case resultDuplicate:
+			duplicateCount++
+		case resultMergeIdentical:
+			mergeIdenticalCount++
+		case resultFailed:
+			failedCount++
+			fo.status.fileFailed(result.message)
+		}
+
+		// 精简日志时跳过的文件只计数，失败和警告照常记录
+		if config.LogVerbosity == LogQuiet && quietHides(result.kind) {
+			quietSkipped++
+			if updateCounter >= updateThreshold {
+				fo.ui.ProcessProgress(processedCount, progressTotal())
+				updateCounter = 0
+			}
+			continue
 		}
 
 		// 批量处理日志
 		logCount++
-		logBuffer.WriteString(result)
+		logBuffer.WriteString(result.message)
 		logBuffer.WriteString("\n")
 
-		// 只对错误/失败日志立即处理，普通日志严格按照批量大小处理
-		if strings.Contains(result, "失败") || strings.Contains(result, "错误") || strings.Contains(result, "警告") {
+		// 只对失败和需要注意的结果立即处理，普通日志严格按照批量大小处理
+		if result.kind == resultFailed || result.kind == resultNotice {
 			// 对于错误/警告日志立即处理
 			fo.log(logBuffer.String())
 			logBuffer.Reset()
@@ -958,9 +4945,7 @@ func (fo *FileOrganizer) processFiles(config Config) error {
 		}
 
 		if updateCounter >= updateThreshold {
-			fo.safeUpdateUI(func() {
-				fo.Window.Content().Refresh()
-			})
+			fo.ui.ProcessProgress(processedCount, progressTotal())
 			updateCounter = 0
 		}
 	}
@@ -970,21 +4955,258 @@ func (fo *FileOrganizer) processFiles(config Config) error {
 		fo.log(logBuffer.String())
 	}
 
-	// 最终UI刷新和总结日志
-	finalFileCount := fileCount
-	finalProcessedCount := processedCount
-	fo.safeUpdateUI(func() {
-		fo.Window.Content().Refresh()
-		fo.log(time.Now().Format("15:04:05") + " - " + fmt.Sprintf("处理完成，共检查了 %d 个文件，移动了 %d 个文件", finalProcessedCount, finalFileCount))
-		fo.processBtn.Enable() // 处理完成后重新启用按钮
-	})
-	return nil
+	// 写入压缩包，写入并校验成功后才删除源文件；失败的文件留在原处
+	if packer != nil {
+		packedCount := 0
+		for _, packed := range fo.writePackedArchives(packer) {
+			item := packed.item
+			if packed.err != nil {
+				failedCount++
+				message := fmt.Sprintf("打包失败 %s: %v", item.source, packed.err)
+				fo.log(message)
+				fo.status.fileFailed(message)
+				continue
+			}
+			packedCount++
+			if item.copyOnly {
+				copiedCount++
+			} else {
+				fileCount++
+			}
+			stats.record(item.source, item.folder, item.size)
+			if catalog != nil {
+				entry := CatalogEntry{
+					RunID:        runID,
+					OriginalPath: item.source,
+					OriginalName: renamedFrom[item.source],
+					FinalPath:    packed.archive,
+					Member:       packed.member,
+					Size:         item.size,
+					Hash:         item.hash,
+					Volume:       volumeOf(packed.archive, config.Volumes),
+					Date:         item.date,
+					MovedAt:      time.Now(),
+				}
+				if item.copyOnly {
+					entry.Operation = CatalogCopy
+				}
+				catalog.add(entry)
+			}
+			fo.runFileHook(config, item.source, packed.archive)
+		}
+		if archives := len(packer.archives()); archives > 0 {
+			fo.log(fmt.Sprintf("打包小文件: %d 个文件放入了 %d 个压缩包", packedCount, archives))
+		}
+	}
+
+	// 所有文件移动完成后再设置日期文件夹的修改时间，避免被后续移入的文件改掉
+	for folder, date := range dateFolders {
+		if err := os.Chtimes(folder, date, date); err != nil {
+			fo.log(fmt.Sprintf("设置文件夹修改时间失败 %s: %v", folder, err))
+		}
+	}
+	if len(dateFolders) > 0 {
+		fo.log(fmt.Sprintf("已将 %d 个日期文件夹的修改时间设为对应日期", len(dateFolders)))
+	}
+
+	// 整理完成后处理空文件夹：先删除变空的子文件夹，再移动原本就为空的第一层文件夹
+	if config.RemoveEmptiedDirs && len(movedFromDirs) > 0 {
+		removed := removeEmptiedDirs(movedFromDirs, config.SourceDirs, config.TargetDir)
+		fo.log(fmt.Sprintf("已删除 %d 个整理后变空的文件夹", removed))
+	}
+	if config.MoveEmptyDirs {
+		if moved := fo.moveEmptyTopLevelDirs(config); moved > 0 {
+			fo.log(fmt.Sprintf("已将 %d 个空文件夹移到 %s", moved, filepath.Join(config.TargetDir, EmptyDirsFolderName)))
+		}
+	}
+
+	if catalog != nil {
+		fo.runCatalog.CompareAndSwap(catalog, nil)
+		written, err := catalog.close()
+		if err != nil {
+			fo.log(fmt.Sprintf("整理目录已停用: %v（已记录 %d 个文件，整理不受影响）", err, written))
+		} else {
+			fo.log(fmt.Sprintf("整理目录已记录 %d 个文件", written))
+		}
+	}
+
+	if manifest != nil && manifest.len() > 0 {
+		if manifestPath, err := manifest.write(time.Now()); err != nil {
+			fo.log(err.Error())
+		} else {
+			fo.log(fmt.Sprintf("已导出 %d 个文件的校验清单: %s", manifest.len(), manifestPath))
+		}
+	}
+
+	if len(dateHits) > 0 {
+		fo.log("日期来源: " + formatDateSourceHits(dateHits))
+	}
+
+	if emptyCount > 0 {
+		switch config.EmptyFilePolicy {
+		case EmptyFileSkip:
+			fo.log(fmt.Sprintf("空文件: 发现 %d 个，已跳过", emptyCount))
+		case EmptyFileQuarantine:
+			fo.log(fmt.Sprintf("空文件: 发现 %d 个，已移到 %s", emptyCount, filepath.Join(config.TargetDir, EmptyFilesFolderName)))
+		default:
+			fo.log(fmt.Sprintf("空文件: 发现 %d 个，已正常整理", emptyCount))
+		}
+	}
+
+	fo.logShortcutReport(shortcuts)
+
+	if replacedCount > 0 {
+		fo.log(fmt.Sprintf("覆盖同名文件: %d 个，覆盖前已备份到 %s", replacedCount, filepath.Join(replacedRoot(config.TargetDir), runID)))
+	}
+	if overwrittenCount > 0 {
+		fo.log(fmt.Sprintf("覆盖同名文件: %d 个，未备份", overwrittenCount))
+	}
+	if config.ConflictPolicy == ConflictMerge {
+		fo.log(fmt.Sprintf("合并到已有文件夹: 跳过了 %d 个同名且内容相同的文件，%d 个同名但内容不同的文件加了时间戳", mergeIdenticalCount, mergeRenamedCount))
+	}
+
+	if abortedCount > 0 {
+		fo.log(fmt.Sprintf("整理已中止，%d 个文件未处理", abortedCount))
+	}
+
+	// 仅处理新增：没有失败或中止时记录整理开始时已有的文件，作为下次整理的基准。
+	// 基准由调用方提供时（流式整理）由调用方在全部批次完成后保存
+	if config.IncomingOnly && config.IncomingBaseline == nil {
+		if failedCount == 0 && abortedCount == 0 {
+			fo.recordIncoming(incoming)
+		} else {
+			fo.log("仅处理新增: 本次整理有失败或中止的文件，未更新记录，下次仍按上次成功整理的记录判断新增文件")
+		}
+	}
+
+	if targetIndex != nil {
+		if err := targetIndex.save(); err != nil {
+			fo.log(err.Error())
+		}
+		fo.log(fmt.Sprintf("目标去重: 跳过了 %d 个目标中已存在的文件", duplicateCount))
+	}
+
+	// 保存统计JSON，配置了上报地址时在后台发送
+	runStats := stats.finish(failedCount)
+	runStats.Duplicates = duplicateCount
+	performance := perf.finish()
+	if previous, ok := previousRunStats(runStatsDir(), runStats); ok {
+		if faster, ok := comparePerformance(runStats, previous); ok {
+			performance.FasterThanPrevious = &faster
+		}
+	}
+	runStats.Performance = &performance
+	if data, err := json.MarshalIndent(runStats, "", "  "); err == nil {
+		// 无界面运行时统计包含在退出前输出的总结JSON中
+		fo.statsMu.Lock()
+		fo.lastStatsJSON = string(data)
+		fo.statsMu.Unlock()
+	}
+	statsPath := filepath.Join(runStatsDir(), fmt.Sprintf("stats_%s.json", runStats.StartedAt.Format("20060102_150405")))
+	if err := writeRunStats(statsPath, runStats); err != nil {
+		fo.log(err.Error())
+	} else {
+		fo.log("统计: " + statsPath)
+	}
+	if config.StatsEndpoint != "" {
+		fo.postRunStats(config.StatsEndpoint, config.StatsToken, runStats)
+	}
+	fo.recordLifetimeStats(runStats)
+	digestFolders := make(map[string]int, len(runStats.Folders))
+	for folder, counter := range runStats.Folders {
+		digestFolders[folder] = counter.Files
+	}
+	fo.recordDigest(digestEvent{Kind: DigestManual, SourceFiles: stats.filesBySource(), Folders: digestFolders,
+		Files: runStats.Files, Errors: runStats.Errors})
+
+	// 总结日志和最终UI刷新
+	fo.log(time.Now().Format("15:04:05") + " - " + fmt.Sprintf("处理完成，共检查了 %d 个文件，移动了 %d 个文件", processedCount, fileCount))
+	if copiedCount > 0 {
+		fo.log(fmt.Sprintf("从只读源文件夹复制了 %d 个文件，源文件保持不变", copiedCount))
+	}
+	if pinnedCount > 0 {
+		fo.log(fmt.Sprintf("跳过了 %d 个已固定的文件", pinnedCount))
+	}
+	if nestedTargetCount > 0 {
+		fo.log(fmt.Sprintf("跳过了 %d 个已在目标文件夹中的文件（目标文件夹位于源文件夹中，保留目录结构时不重新整理）", nestedTargetCount))
+	}
+	if unsafeNameCount > 0 {
+		fo.log(fmt.Sprintf("警告: 跳过了 %d 个文件名包含空字节或路径分隔符的文件", unsafeNameCount))
+	}
+	if quietSkipped > 0 {
+		fo.log(fmt.Sprintf("跳过了 %d 个文件（精简日志，未逐个列出）", quietSkipped))
+	}
+	if config.Pins != nil && config.Pins.takeChanged() {
+		// 固定的文件所在的文件夹改名后已按新路径更新。列表保存在文件中，不经过界面线程
+		if err := config.Pins.save(pinsPath()); err != nil {
+			fo.log(err.Error())
+		}
+	}
+	if hint := extensionMismatchHint(unmatchedExtensions, matchedExtensions, processedCount, config.FileExtensions); hint != "" {
+		fo.log(hint)
+	}
+	if runStats.Converted > 0 || runStats.ConvertFallback > 0 {
+		fo.log(fmt.Sprintf("图片转换: %d 个文件已转换为JPEG，%d 个转换失败后按原样整理", runStats.Converted, runStats.ConvertFallback))
+	}
+	if refiledCount > 0 || inPlaceCount > 0 {
+		fo.log(fmt.Sprintf("重新归档: 目标中已有的 %d 个文件换到了新位置，%d 个文件已在正确位置", refiledCount, inPlaceCount))
+	}
+	for _, line := range describePerformance(runStats) {
+		fo.log(line)
+	}
+	if fo.logFilePath != "" {
+		fo.log("完整日志: " + fo.logFilePath)
+	}
+	fo.ui.ProcessFinished()
+	unmatchedCount := 0
+	for _, n := range unmatchedExtensions {
+		unmatchedCount += n
+	}
+	summary := processSummary{
+		Checked:    processedCount,
+		Moved:      fileCount,
+		Copied:     copiedCount,
+		Refiled:    refiledCount,
+		InPlace:    inPlaceCount,
+		Duplicates: duplicateCount,
+		Unmatched:  unmatchedCount,
+		Failed:     failedCount,
+		Aborted:    abortedCount,
+		Stats:      runStats,
+	}
+	fo.runPostRunHook(config, summary)
+	return summary, nil
 }
 
 func main() {
+	// 带 -headless 参数时不显示界面，按命令行参数整理一次后退出
+	if headlessRequested(os.Args[1:]) {
+		os.Exit(runHeadless(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
+	// 同时只运行一个程序，再次启动时切换到已打开的窗口
+	lock, err := acquireInstanceLock(false)
+	var running *instanceRunningError
+	if errors.As(err, &running) {
+		if focusRunningInstance(running.holder) == nil {
+			return
+		}
+		showInstanceRunning(running.holder)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "警告: %v，不检查是否已有程序在运行\n", err)
+	}
+
 	// 创建文件组织器实例
-	organizer := NewFileOrganizer()
+	organizer := NewFileOrganizer(false)
+	organizer.instanceLock = lock
 
 	// 创建并显示GUI
 	organizer.createGUI()
+	if lock != nil {
+		if err := lock.release(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
 }
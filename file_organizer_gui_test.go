@@ -0,0 +1,148 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 文件名中含有结果文字时仍按实际结果计数
+func TestProcessResultsCountedByKind(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       []string
+		wantMoved   int
+		wantFailed  int
+		wantSkipped int // 后缀不符
+	}{
+		{"含有失败", []string{"移动失败.jpg", "获取文件信息失败.jpg"}, 2, 0, 0},
+		{"含有跳过", []string{"跳过重复文件.jpg", "跳过.txt"}, 1, 0, 1},
+		{"含有已移动", []string{"已移动.txt"}, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			var files []string
+			for _, name := range tt.files {
+				files = append(files, writeTestFile(t, filepath.Join(source, name), name))
+			}
+			config := Config{
+				SourceDir:      source,
+				SourceDirs:     []string{source},
+				TargetDir:      t.TempDir(),
+				FileExtensions: []string{".jpg"},
+				OrganizeRule:   string(RuleByExtension),
+				ExtensionCase:  "lowercase",
+				ExcludedFiles:  map[string]bool{},
+			}
+			summary, err := fo.processFiles(config, files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.Moved != tt.wantMoved || summary.Failed != tt.wantFailed || summary.Unmatched != tt.wantSkipped {
+				t.Fatalf("结果 = %+v", summary)
+			}
+			if s := fetchStatus(t, fo); s.Errors != tt.wantFailed {
+				t.Fatalf("状态页面的错误数 = %d", s.Errors)
+			}
+		})
+	}
+}
+
+// logCapture 记录写入界面的日志
+type logCapture struct {
+	UINotifier
+	mu   sync.Mutex
+	text strings.Builder
+}
+
+func (c *logCapture) AppendLog(text string) {
+	c.mu.Lock()
+	c.text.WriteString(text)
+	c.mu.Unlock()
+	c.UINotifier.AppendLog(text)
+}
+
+// 等待日志中出现指定的文字，返回到那时为止的全部日志
+func (c *logCapture) waitFor(t *testing.T, text string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.mu.Lock()
+		logText := c.text.String()
+		c.mu.Unlock()
+		if strings.Contains(logText, text) {
+			return logText
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("日志中没有 %q:\n%s", text, logText)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// 精简日志按结果类别隐藏跳过的文件，不看结果文字
+func TestQuietHides(t *testing.T) {
+	tests := []struct {
+		kind resultKind
+		want bool
+	}{
+		{resultSkipped, true},
+		{resultPinned, true},
+		{resultNestedTarget, true},
+		{resultMergeIdentical, true},
+		{resultDuplicate, false},
+		{resultNotice, false},
+		{resultUnsafeName, false},
+		{resultFailed, false},
+		{resultMoved, false},
+		{resultInfo, false},
+	}
+	for _, tt := range tests {
+		if got := quietHides(tt.kind); got != tt.want {
+			t.Errorf("quietHides(%d) = %v, 期望 %v", tt.kind, got, tt.want)
+		}
+	}
+}
+
+// 精简日志时文件名含有「跳过」的已移动文件和重复文件照常列出，后缀不符的文件只计数
+func TestQuietLogByKind(t *testing.T) {
+	fo := newTestOrganizer(t)
+	capture := &logCapture{UINotifier: fo.ui}
+	fo.ui = capture
+	source := t.TempDir()
+	target := t.TempDir()
+	writeTestFile(t, filepath.Join(target, "existing.jpg"), "duplicate")
+	files := []string{
+		writeTestFile(t, filepath.Join(source, "跳过.jpg"), "moved"),
+		writeTestFile(t, filepath.Join(source, "copy.jpg"), "duplicate"),
+		writeTestFile(t, filepath.Join(source, "notes.txt"), "unmatched"),
+	}
+	config := Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      target,
+		FileExtensions: []string{".jpg"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		DedupTarget:    true,
+		LogVerbosity:   LogQuiet,
+		ExcludedFiles:  map[string]bool{},
+	}
+	summary, err := fo.processFiles(config, files)
+	if err != nil || summary.Moved != 1 || summary.Duplicates != 1 || summary.Unmatched != 1 {
+		t.Fatalf("整理: %+v, %v", summary, err)
+	}
+	logText := capture.waitFor(t, "跳过了 1 个文件")
+	for _, want := range []string{"跳过.jpg", "copy.jpg"} {
+		if !strings.Contains(logText, want) {
+			t.Errorf("日志中应列出 %s", want)
+		}
+	}
+	if strings.Contains(logText, "notes.txt") {
+		t.Error("精简日志不应列出后缀不符的文件")
+	}
+}
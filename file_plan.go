@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 只由路径决定的跳过原因和对应的结果类别，原因为空时不跳过。返回的文字与整理日志相同，不含工作协程编号
+func pathSkipReason(filePath string, config Config) (string, resultKind) {
+	switch {
+	case config.ExcludedFiles[filePath]:
+		// 跳过用户手动排除的文件
+		return "跳过已排除的文件: " + filePath, resultSkipped
+	case isInsidePreservedTarget(filePath, config):
+		// 源文件夹中的目标文件夹里已经整理过的文件，保留目录结构时重新整理会不断嵌套
+		return "已在目标文件夹中，跳过: " + filePath, resultNestedTarget
+	case config.SourceSnapshot != "" && samePath(filePath, config.SourceSnapshot):
+		// 源快照文件放在源文件夹中时不整理，否则下次整理时找不到快照
+		return "跳过源快照文件: " + filePath, resultSkipped
+	case filepath.Base(filePath) == targetLockName:
+		// 目标文件夹的锁文件不整理
+		return "跳过目标文件夹的锁文件: " + filePath, resultSkipped
+	case strings.HasSuffix(filePath, partialCopySuffix):
+		// 中断的复制留下的部分文件由清理残留文件删除
+		return "跳过未完成的复制: " + filePath, resultSkipped
+	case strings.HasSuffix(filePath, replacingSuffix):
+		// 覆盖中途退出时留下的被覆盖的文件，由用户决定是否恢复
+		return "跳过覆盖时移走的文件: " + filePath, resultSkipped
+	case isWithinAny(filepath.Dir(filePath), []string{replacedRoot(config.TargetDir)}):
+		// 覆盖前的备份留在原处，由清理覆盖备份删除或撤销时恢复
+		return "跳过覆盖前的备份: " + filePath, resultSkipped
+	case isWithinAny(filepath.Dir(filePath), []string{checksumsRoot(config.TargetDir)}):
+		// 之前导出的校验清单不整理，整理后清单中的路径仍然有效
+		return "跳过校验清单: " + filePath, resultSkipped
+	case isConvertedOriginal(filePath, config.TargetDir):
+		// 转换前保留的原始文件不再整理，否则下次整理时会被转换或移出 _originals
+		return "跳过转换前保留的原始文件: " + filePath, resultSkipped
+	}
+	// 文件名包含空字节或路径分隔符时不整理，避免写到目标文件夹之外
+	if err := checkSourceName(filePath); err != nil {
+		return fmt.Sprintf("跳过 %q: %v", filePath, err), resultUnsafeName
+	}
+	return "", resultInfo
+}
+
+// 程序包作为一个整体移动；图库默认跳过，按内容哈希整理时无法计算程序包的哈希
+func packageSkipReason(filePath string, fileInfo os.FileInfo, config Config) string {
+	if !fileInfo.IsDir() {
+		return ""
+	}
+	if isLibraryPackage(filePath) && !config.OrganizeLibraries {
+		return "跳过图库（未开启整理图库）: " + filePath
+	}
+	if OrganizeRule(config.OrganizeRule) == RuleByHash {
+		return "按内容哈希整理时跳过程序包: " + filePath
+	}
+	return ""
+}
+
+// filePlan 一个文件的整理计划
+type filePlan struct {
+	Skip       string     // 不整理的原因，为空时会整理
+	Kind       resultKind // 不整理时的结果类别
+	TargetDir  string     // 目标文件夹，打包的小文件为不打包时所在的日期文件夹
+	TargetName string     // 目标中的文件名
+	DateSource DateSource // 日期来自哪里，规则不使用日期时为空
+	Date       time.Time
+	Notes      []string // 起作用的设置
+
+	Package       bool   // 程序包，作为一个整体移动
+	Link          bool   // 网址快捷方式，放入「链接」文件夹
+	ReadOnly      bool   // 只读源文件夹中的文件，只复制
+	Refile        bool   // 已在目标文件夹中，重新归档
+	Empty         bool   // 空文件
+	Unmatched     bool   // 后缀不在所选后缀中
+	Quarantine    bool   // 空文件，移到隔离文件夹
+	SourceHash    string // 目标去重或按内容哈希改名时计算的源文件哈希
+	HashName      string // 按内容哈希改名后的文件名
+	PrefixedName  string // 文件名前缀模式下的文件名
+	NamePrefix    string // 文件名前缀模式下所加的前缀，记录在整理目录中
+	RenameInPlace bool   // 文件名前缀模式下已在目标文件夹中的文件只改名
+	Archive       string // 放入的压缩包，为空时不打包
+	Convert       bool   // 先转换格式再放入目标
+	MergeRenamed  bool   // 合并模式下目标中已有同名但内容不同的文件，加时间戳
+	Existing      string // 因目标中已有相同的文件而跳过时，已有的文件（不在压缩包中时）
+}
+
+// planContext 整理前为整批文件准备的信息，规划单个文件时使用。规则测试中没有的部分为nil
+type planContext struct {
+	shortcuts   *shortcutPlan
+	takeout     *takeoutPlan
+	targetIndex *targetHashIndex
+	packer      *smallFilePacker
+}
+
+// 是否把小文件放入日期文件夹对应的压缩包
+func packsSmallFiles(config Config) bool {
+	return config.PackSmallFiles && config.PackThresholdKB > 0 && OrganizeRule(config.OrganizeRule) == RuleByDate &&
+		config.FolderLayout != LayoutNamePrefix
+}
+
+// 计算一个文件的整理计划。整理和规则测试都使用这里的判断：跳过的原因、目标去重、目标文件夹、
+// 改名、打包和同名文件的处理。只读取文件和目标，不移动文件、不写入任何记录。
+// 规则测试中文件不必存在：fileInfo可以是示例的大小和修改时间，读取不到内容的日期来源会被跳过
+func (fo *FileOrganizer) planFile(filePath string, fileInfo os.FileInfo, config Config, ctx planContext) filePlan {
+	var plan filePlan
+	skip := func(kind resultKind, reason string) filePlan {
+		plan.Kind, plan.Skip = kind, reason
+		return plan
+	}
+	if reason, kind := pathSkipReason(filePath, config); reason != "" {
+		return skip(kind, reason)
+	}
+	// 固定的文件留在原处
+	if config.Pins.matches(filePath, fileInfo) {
+		return skip(resultPinned, "已固定，跳过: "+filePath)
+	}
+	// Google 相册导出的元数据文件不单独整理，不受所选后缀限制
+	if media := ctx.takeout.mediaOf(filePath); media != "" {
+		return skip(resultInfo, fmt.Sprintf("元数据文件随照片移动: %s -> %s", filePath, media))
+	}
+	// 检查文件后缀，快捷方式指向的文件不受所选后缀限制
+	ext := fileExtension(filePath)
+	switch {
+	case fo.isTargetFile(ext, config.FileExtensions):
+		plan.Notes = append(plan.Notes, fmt.Sprintf("后缀 %s 在所选后缀中", ext))
+	case ctx.shortcuts.isTarget(filePath):
+		plan.Notes = append(plan.Notes, "快捷方式指向的文件，不受所选后缀限制")
+	default:
+		plan.Unmatched = true
+		return skip(resultSkipped, "跳过不符合后缀的文件: "+filePath)
+	}
+	// 快捷方式按设置跳过，或者留在原处、整理其指向的文件
+	if config.ShortcutPolicy == ShortcutSkip && shortcutKind(filePath) != "" {
+		return skip(resultSkipped, "跳过快捷方式: "+filePath)
+	}
+	if reason, ok := ctx.shortcuts.unresolvedReason(filePath); ok {
+		return skip(resultNotice, fmt.Sprintf("跳过无法解析的快捷方式 %s: %s", filePath, reason))
+	}
+	if target, ok := ctx.shortcuts.resolvedTarget(filePath); ok {
+		return skip(resultInfo, fmt.Sprintf("快捷方式留在原处，整理其指向的文件: %s -> %s", filePath, target))
+	}
+	plan.Link = ctx.shortcuts.isLink(filePath)
+
+	// 程序包作为一个整体移动；图库默认跳过，按内容哈希整理时无法计算程序包的哈希
+	if reason := packageSkipReason(filePath, fileInfo, config); reason != "" {
+		return skip(resultSkipped, reason)
+	}
+	plan.Package = fileInfo.IsDir()
+	if plan.Package {
+		plan.Notes = append(plan.Notes, "程序包，作为一个整体移动")
+	}
+	// 只读源文件夹中的文件只复制，已经在目标文件夹中的文件只在归档内部重新归档
+	plan.ReadOnly = isFromReadOnlySource(filePath, config)
+	if plan.ReadOnly {
+		plan.Notes = append(plan.Notes, "只读源文件夹，只复制")
+	}
+	plan.Refile = isRefile(filePath, config)
+	if plan.Refile {
+		plan.Notes = append(plan.Notes, "已在目标文件夹中，重新归档")
+	}
+
+	// 空文件按设置整理、跳过或隔离
+	if fileInfo.Size() == 0 && !plan.Package {
+		plan.Empty = true
+		switch config.EmptyFilePolicy {
+		case EmptyFileSkip:
+			return skip(resultSkipped, "跳过空文件: "+filePath)
+		case EmptyFileQuarantine:
+			quarantineDir := filepath.Join(config.TargetDir, EmptyFilesFolderName)
+			if filepath.Dir(filePath) == quarantineDir {
+				return skip(resultSkipped, "跳过已隔离的空文件: "+filePath)
+			}
+			plan.Quarantine = true
+			plan.TargetDir = quarantineDir
+			plan.TargetName = fo.normalizeName(filepath.Base(filePath))
+			plan.Notes = append(plan.Notes, "空文件，隔离")
+			return plan
+		}
+	}
+	if ruleUsesDate(config) && !plan.Package {
+		_, plan.DateSource = fo.dates.Resolve(filePath, fileInfo, effectiveDateSources(config))
+		plan.Date = fo.fileDate(filePath, fileInfo, config)
+	}
+
+	// 目标中已有内容完全相同的文件时跳过。不同的空文件内容都相同，除非明确开启，否则不视为重复。
+	// 重新归档的文件本身就在目标中，不做去重
+	if config.DedupTarget && !plan.Refile && !plan.Package && (fileInfo.Size() > 0 || config.DedupEmptyFiles) {
+		if ctx.targetIndex == nil {
+			plan.Notes = append(plan.Notes, "目标去重：目标中已有内容相同的文件时跳过")
+		} else {
+			existing, hash, err := ctx.targetIndex.findDuplicate(filePath, fileInfo.Size())
+			if err != nil {
+				return skip(resultFailed, fmt.Sprintf("去重检查失败 %s: %v", filePath, err))
+			}
+			if existing != "" {
+				if info, err := os.Stat(existing); err == nil && info.Mode().IsRegular() {
+					plan.Existing = existing
+				}
+				return skip(resultDuplicate, fmt.Sprintf("跳过重复文件: %s (目标中已有: %s)", filePath, existing))
+			}
+			plan.SourceHash = hash
+		}
+	}
+
+	// 确定目标文件夹路径，网址快捷方式都放在「链接」文件夹中
+	plan.TargetDir = fo.planTargetDir(filePath, fileInfo, config)
+	if plan.Link {
+		plan.TargetDir = filepath.Join(config.TargetDir, LinksFolderName)
+		plan.Notes = append(plan.Notes, "网址快捷方式，放入「"+LinksFolderName+"」")
+	}
+	if plan.TargetDir == "" {
+		return skip(resultFailed, "无法确定目标文件夹，处理失败: "+filePath)
+	}
+	if config.FolderTemplate != "" {
+		plan.Notes = append(plan.Notes, "文件夹名称模板 "+config.FolderTemplate)
+	}
+	if len(config.Volumes) > 0 && volumeOf(plan.TargetDir, config.Volumes) != config.TargetDir {
+		plan.Notes = append(plan.Notes, "目标卷 "+volumeOf(plan.TargetDir, config.Volumes))
+	}
+	plan.TargetName = fo.normalizeName(filepath.Base(filePath))
+
+	// 按内容哈希整理并改名时，同名文件就是内容相同的文件
+	if OrganizeRule(config.OrganizeRule) == RuleByHash && config.HashRename && !plan.Link {
+		hash, err := fo.contentHashes.get(filePath, fileInfo)
+		if err != nil {
+			return skip(resultFailed, fmt.Sprintf("计算内容哈希失败 %s: %v", filePath, err))
+		}
+		if plan.SourceHash == "" {
+			plan.SourceHash = hash
+		}
+		plan.HashName = hashFileName(hash, filePath, config.ExtensionCase)
+		hashedPath := filepath.Join(plan.TargetDir, plan.HashName)
+		if hashedPath == filePath {
+			return skip(resultInPlace, "已在正确位置: "+filePath)
+		}
+		if _, err := os.Stat(hashedPath); err == nil {
+			plan.Existing = hashedPath
+			return skip(resultDuplicate, fmt.Sprintf("跳过重复文件: %s (目标中已有: %s)", filePath, hashedPath))
+		}
+		plan.TargetName = plan.HashName
+		plan.Notes = append(plan.Notes, "按内容哈希改名")
+	}
+	// 文件名前缀模式下规则决定文件名，目标文件夹中的文件改名即可
+	if config.FolderLayout == LayoutNamePrefix && plan.HashName == "" && !plan.Link {
+		name, prefix := fo.prefixedTargetName(filePath, fileInfo, config)
+		plan.PrefixedName, plan.NamePrefix = fo.normalizeName(name), fo.normalizeName(prefix)
+		if filepath.Join(plan.TargetDir, plan.PrefixedName) == filePath {
+			return skip(resultInPlace, "已在正确位置: "+filePath)
+		}
+		plan.RenameInPlace = plan.Refile && filepath.Dir(filePath) == plan.TargetDir
+		plan.TargetName = plan.PrefixedName
+	}
+	if plan.Refile && plan.HashName == "" && plan.PrefixedName == "" && filepath.Dir(filePath) == plan.TargetDir {
+		return skip(resultInPlace, "已在正确位置: "+filePath)
+	}
+
+	// 小文件放入压缩包；要转换格式或带元数据文件的照片照常移动
+	if packsSmallFiles(config) && !plan.Refile && !plan.Link && !plan.Package && plan.PrefixedName == "" &&
+		fileInfo.Size() < int64(config.PackThresholdKB)*1024 && ctx.takeout.sidecarOf(filePath) == "" &&
+		!(config.ConvertImages && convertedExtension(filePath) != "") {
+		archivePath, err := resolvePackArchive(packArchivePath(plan.TargetDir))
+		if ctx.packer != nil {
+			archivePath, err = ctx.packer.archiveFor(plan.TargetDir)
+		}
+		if err != nil {
+			return skip(resultFailed, fmt.Sprintf("打包失败 %s: %v", filePath, err))
+		}
+		// 合并模式下压缩包中已有同名且内容相同的文件时跳过，与未打包的文件一样
+		if config.ConflictPolicy == ConflictMerge && ctx.packer != nil {
+			same, err := ctx.packer.identical(archivePath, filePath, fileInfo.Size())
+			if err != nil {
+				return skip(resultFailed, fmt.Sprintf("比较同名文件失败 %s: %v", filePath, err))
+			}
+			if same {
+				return skip(resultMergeIdentical, fmt.Sprintf("跳过相同文件: %s (压缩包中已有同名的相同文件: %s)", filePath, archivePath))
+			}
+		}
+		plan.Archive = archivePath
+		plan.Notes = append(plan.Notes, fmt.Sprintf("小于 %d KB，放入压缩包", config.PackThresholdKB))
+		return plan
+	}
+
+	// HEIC等格式先转换后放入目标，转换后的文件不与目标中的同名文件比较
+	plan.Convert = config.ConvertImages && !plan.Refile && plan.HashName == "" && convertedExtension(filePath) != ""
+	if plan.Convert {
+		plan.Notes = append(plan.Notes, "转换为 "+convertedExtension(filePath))
+		return plan
+	}
+	if sidecar := ctx.takeout.sidecarOf(filePath); sidecar != "" {
+		plan.Notes = append(plan.Notes, "元数据文件 "+filepath.Base(sidecar)+" 随照片移动")
+	}
+	fo.planConflict(&plan, filePath, fileInfo, config)
+	return plan
+}
+
+// 按冲突设置确定目标中的文件名：合并模式下目标中已有同名且内容相同的文件时跳过，
+// 其余的同名文件加时间戳或覆盖。只读取目标，不改动任何文件。
+// 重新归档、按内容哈希改名和文件名前缀模式的文件名另外确定，不在这里处理
+func (fo *FileOrganizer) planConflict(plan *filePlan, filePath string, fileInfo os.FileInfo, config Config) {
+	if plan.Refile || plan.HashName != "" || plan.PrefixedName != "" {
+		return
+	}
+	name := fo.normalizeName(filepath.Base(filePath))
+	plan.TargetName = name
+	existingPath := filepath.Join(plan.TargetDir, name)
+	switch config.ConflictPolicy {
+	case ConflictMerge:
+		// 重复整理时不再产生加时间戳的副本；内容不同时随后按加时间戳处理
+		exists, same, err := fo.sameNamedTarget(filePath, fileInfo, existingPath)
+		if err != nil {
+			plan.Kind, plan.Skip = resultFailed, fmt.Sprintf("比较同名文件失败 %s: %v", filePath, err)
+			return
+		}
+		if same {
+			plan.Existing = existingPath
+			plan.Kind, plan.Skip = resultMergeIdentical, fmt.Sprintf("跳过相同文件: %s (目标中已有同名的相同文件: %s)", filePath, existingPath)
+			return
+		}
+		plan.MergeRenamed = exists
+		if exists {
+			plan.TargetName = filepath.Base(fo.freeTargetPath(plan.TargetDir, name))
+			plan.Notes = append(plan.Notes, "目标中已有同名但内容不同的文件，加时间戳")
+		}
+	case ConflictOverwrite:
+		if _, err := os.Lstat(existingPath); err == nil {
+			plan.Notes = append(plan.Notes, "覆盖目标中的同名文件")
+		}
+	default:
+		if targetPath := fo.freeTargetPath(plan.TargetDir, name); filepath.Base(targetPath) != name {
+			plan.TargetName = filepath.Base(targetPath)
+			plan.Notes = append(plan.Notes, "目标中已有同名文件，加时间戳")
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 计划中的目标在整理后应真的存在：打包的小文件在压缩包中，其余文件在目标文件夹中
+func checkPlannedTarget(t *testing.T, plan filePlan) {
+	t.Helper()
+	if plan.Archive != "" {
+		for _, name := range zipMemberNames(t, plan.Archive) {
+			if name == plan.TargetName {
+				return
+			}
+		}
+		t.Fatalf("压缩包 %s 中没有 %s", plan.Archive, plan.TargetName)
+	}
+	if _, err := os.Stat(filepath.Join(plan.TargetDir, plan.TargetName)); err != nil {
+		t.Fatalf("整理后计划的目标不存在: %v", err)
+	}
+}
+
+// 规划与实际整理的结果一致：跳过的原因、去重、同名文件、打包、快捷方式、元数据文件和目标卷
+func TestPlanFileMatchesRun(t *testing.T) {
+	date := time.Date(2024, 6, 5, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, source, target string, config *Config) string // 返回要规划的文件
+		wantKind resultKind
+		wantSkip bool
+		want     string // 目标中的相对路径，为空时只检查整理后计划的目标存在
+		wantNote string
+	}{
+		{"按日期", func(t *testing.T, source, target string, config *Config) string {
+			return writeDatedFile(t, filepath.Join(source, "a.jpg"), date)
+		}, resultInfo, false, filepath.Join("2024-06", "a.jpg"), "后缀 .jpg"},
+		{"后缀不符", func(t *testing.T, source, target string, config *Config) string {
+			return writeDatedFile(t, filepath.Join(source, "a.png"), date)
+		}, resultSkipped, true, "", ""},
+		{"同名加时间戳", func(t *testing.T, source, target string, config *Config) string {
+			writeTestFile(t, filepath.Join(target, "2024-06", "a.jpg"), "other")
+			return writeDatedFile(t, filepath.Join(source, "a.jpg"), date)
+		}, resultInfo, false, "", "加时间戳"},
+		{"合并，相同文件", func(t *testing.T, source, target string, config *Config) string {
+			config.ConflictPolicy = ConflictMerge
+			writeTestFile(t, filepath.Join(target, "2024-06", "a.jpg"), "a.jpg")
+			return writeDatedFile(t, filepath.Join(source, "a.jpg"), date)
+		}, resultMergeIdentical, true, "", ""},
+		{"合并，不同文件", func(t *testing.T, source, target string, config *Config) string {
+			config.ConflictPolicy = ConflictMerge
+			writeTestFile(t, filepath.Join(target, "2024-06", "a.jpg"), "other")
+			return writeDatedFile(t, filepath.Join(source, "a.jpg"), date)
+		}, resultInfo, false, "", "内容不同"},
+		{"目标去重", func(t *testing.T, source, target string, config *Config) string {
+			config.DedupTarget = true
+			writeTestFile(t, filepath.Join(target, "old", "b.jpg"), "a.jpg")
+			return writeDatedFile(t, filepath.Join(source, "a.jpg"), date)
+		}, resultDuplicate, true, "", ""},
+		{"打包小文件", func(t *testing.T, source, target string, config *Config) string {
+			config.PackSmallFiles = true
+			config.PackThresholdKB = defaultPackThresholdKB
+			return writeDatedFile(t, filepath.Join(source, "a.txt"), date)
+		}, resultInfo, false, "", "压缩包"},
+		{"空文件隔离", func(t *testing.T, source, target string, config *Config) string {
+			config.EmptyFilePolicy = EmptyFileQuarantine
+			return writeTestFile(t, filepath.Join(source, "a.jpg"), "")
+		}, resultInfo, false, filepath.Join(EmptyFilesFolderName, "a.jpg"), "隔离"},
+		{"网址快捷方式", func(t *testing.T, source, target string, config *Config) string {
+			config.ShortcutPolicy = ShortcutResolve
+			return writeTestFile(t, filepath.Join(source, "site.desktop"), "[Desktop Entry]\nType=Link\nURL=https://example.com\n")
+		}, resultInfo, false, filepath.Join(LinksFolderName, "site.desktop"), "网址快捷方式"},
+		{"Google 相册元数据文件", func(t *testing.T, source, target string, config *Config) string {
+			config.TakeoutMode = true
+			writeDatedFile(t, filepath.Join(source, "a.jpg"), date)
+			return writeTestFile(t, filepath.Join(source, "a.jpg.json"), "{}")
+		}, resultInfo, true, "", ""},
+		{"溢出卷上已有的文件夹", func(t *testing.T, source, target string, config *Config) string {
+			spill := t.TempDir()
+			writeTestFile(t, filepath.Join(spill, "2024-06", "old.jpg"), "old")
+			config.Volumes = []TargetVolume{{Root: target}, {Root: spill}}
+			return writeDatedFile(t, filepath.Join(source, "a.jpg"), date)
+		}, resultInfo, false, "", "目标卷"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			target := t.TempDir()
+			config := Config{
+				SourceDir:        source,
+				SourceDirs:       []string{source},
+				TargetDir:        target,
+				FileExtensions:   []string{".jpg", ".txt", ".desktop"},
+				OrganizeRule:     string(RuleByDate),
+				FolderDateFormat: "YYYY-MM",
+				ExtensionCase:    "lowercase",
+				ConflictPolicy:   ConflictRename,
+				ExcludedFiles:    map[string]bool{},
+			}
+			file := tt.setup(t, source, target, &config)
+			entries, err := os.ReadDir(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, entry := range entries {
+				files = append(files, filepath.Join(source, entry.Name()))
+			}
+			info, err := os.Stat(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var samples []ruleSample
+			for _, path := range files {
+				sampleInfo, _ := os.Stat(path)
+				samples = append(samples, ruleSample{path: path, info: sampleInfo})
+			}
+			config, ctx, err := fo.samplePlanContext(config, samples)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.DedupTarget {
+				if ctx.targetIndex, err = loadTargetHashIndex(target, nil, false); err != nil {
+					t.Fatal(err)
+				}
+			}
+			plan := fo.planFile(file, info, config, ctx)
+			if (plan.Skip != "") != tt.wantSkip || plan.Kind != tt.wantKind {
+				t.Fatalf("计划 = %+v", plan)
+			}
+			if tt.want != "" && displayPlanPath(plan, config) != filepath.Join("目标", tt.want) {
+				t.Fatalf("计划的目标 = %s, 期望 %s", displayPlanPath(plan, config), tt.want)
+			}
+			if tt.wantNote != "" && !strings.Contains(strings.Join(plan.Notes, "；"), tt.wantNote) {
+				t.Fatalf("说明 = %v, 应包含 %q", plan.Notes, tt.wantNote)
+			}
+
+			if _, err := fo.processFiles(config, files); err != nil {
+				t.Fatal(err)
+			}
+			_, statErr := os.Stat(file)
+			switch {
+			case !tt.wantSkip:
+				checkPlannedTarget(t, plan)
+			case plan.Kind != resultInfo && statErr != nil:
+				t.Fatalf("跳过的文件应留在原处: %v", statErr)
+			}
+		})
+	}
+}
+
+// 规则测试显示每个示例的去向，示例文件不必存在
+func TestEvaluateRuleSamples(t *testing.T) {
+	fo := newTestOrganizer(t)
+	config := Config{
+		SourceDirs:       []string{filepath.Join(t.TempDir(), "src")},
+		TargetDir:        filepath.Join(t.TempDir(), "dst"),
+		FileExtensions:   []string{".jpg"},
+		OrganizeRule:     string(RuleByDate),
+		FolderDateFormat: "YYYY-MM",
+		DateSources:      []DateSource{DateSourceMtime},
+		ExcludedFiles:    map[string]bool{},
+	}
+	tests := []struct {
+		sample string
+		want   string
+	}{
+		{"IMG_1.jpg | 2024-03-15 14:30", "→ " + filepath.Join("目标", "2024-03", "IMG_1.jpg")},
+		{"notes.txt", "不整理: 跳过不符合后缀的文件"},
+		{"a.jpg | 不是时间", "错误"},
+	}
+	for _, tt := range tests {
+		if got := fo.evaluateRuleSamples(tt.sample, config); !strings.Contains(got, tt.want) {
+			t.Errorf("%q 的结果 = %q, 应包含 %q", tt.sample, got, tt.want)
+		}
+	}
+}
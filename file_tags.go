@@ -0,0 +1,15 @@
+package main
+
+import "errors"
+
+// 当前系统不支持文件标签时选择按标签整理的错误。其他系统上读不到标签，所有文件都会放入「未标记」，
+// 因此不开始整理，而不是看起来整理成功
+var errFileTagsUnsupported = errors.New("当前系统不支持文件标签（仅支持macOS Finder标签），无法使用按标签整理")
+
+// 按标签整理需要当前系统支持文件标签
+func validateTagRule(config Config) error {
+	if OrganizeRule(config.OrganizeRule) == RuleByTag && !fileTagsSupported {
+		return errFileTagsUnsupported
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+//go:build darwin
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/sys/unix"
+)
+
+// macOS 上支持读取 Finder 标签
+const fileTagsSupported = true
+
+// Finder 标签保存在该扩展属性中，内容为二进制 plist 格式的字符串数组
+const finderTagsXattr = "com.apple.metadata:_kMDItemUserTags"
+
+// 读取文件的 Finder 标签，没有标签时返回空列表
+func readFileTags(path string) ([]string, error) {
+	size, err := unix.Getxattr(path, finderTagsXattr, nil)
+	if err != nil {
+		if errors.Is(err, unix.ENOATTR) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取文件标签失败: %w", err)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+
+	data := make([]byte, size)
+	size, err = unix.Getxattr(path, finderTagsXattr, data)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件标签失败: %w", err)
+	}
+
+	values, err := parseBinaryPlistStrings(data[:size])
+	if err != nil {
+		return nil, err
+	}
+
+	// 标签格式为 "名称\n颜色编号"，只保留名称部分
+	var tags []string
+	for _, value := range values {
+		if idx := strings.IndexByte(value, '\n'); idx >= 0 {
+			value = value[:idx]
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			tags = append(tags, value)
+		}
+	}
+	return tags, nil
+}
+
+// 解析只包含字符串数组的二进制 plist（Finder 标签的存储格式）
+func parseBinaryPlistStrings(data []byte) ([]string, error) {
+	const trailerSize = 32
+	if len(data) < 8+trailerSize || string(data[:8]) != "bplist00" {
+		return nil, errors.New("标签数据不是有效的二进制plist")
+	}
+
+	trailer := data[len(data)-trailerSize:]
+	offsetSize := int(trailer[6])
+	refSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	offsetTableStart := binary.BigEndian.Uint64(trailer[24:32])
+	if offsetSize == 0 || refSize == 0 || topObject >= numObjects ||
+		offsetTableStart+numObjects*uint64(offsetSize) > uint64(len(data)) {
+		return nil, errors.New("标签数据格式错误")
+	}
+
+	readUint := func(b []byte) uint64 {
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v
+	}
+
+	// 获取对象在数据中的偏移
+	objectOffset := func(ref uint64) (int, error) {
+		if ref >= numObjects {
+			return 0, errors.New("标签数据引用越界")
+		}
+		start := offsetTableStart + ref*uint64(offsetSize)
+		offset := readUint(data[start : start+uint64(offsetSize)])
+		if offset >= offsetTableStart {
+			return 0, errors.New("标签数据偏移越界")
+		}
+		return int(offset), nil
+	}
+
+	// 读取对象的类型和长度，长度为0xF时后面紧跟一个整数对象
+	readHeader := func(offset int) (byte, int, int, error) {
+		marker := data[offset]
+		kind, count := marker>>4, int(marker&0x0F)
+		offset++
+		if count == 0x0F {
+			if offset >= len(data) || data[offset]>>4 != 0x1 {
+				return 0, 0, 0, errors.New("标签数据长度格式错误")
+			}
+			intSize := 1 << (data[offset] & 0x0F)
+			offset++
+			if offset+intSize > len(data) {
+				return 0, 0, 0, errors.New("标签数据长度越界")
+			}
+			count = int(readUint(data[offset : offset+intSize]))
+			offset += intSize
+		}
+		return kind, count, offset, nil
+	}
+
+	readString := func(ref uint64) (string, error) {
+		offset, err := objectOffset(ref)
+		if err != nil {
+			return "", err
+		}
+		kind, count, start, err := readHeader(offset)
+		if err != nil {
+			return "", err
+		}
+		switch kind {
+		case 0x5: // ASCII 字符串
+			if start+count > len(data) {
+				return "", errors.New("标签字符串越界")
+			}
+			return string(data[start : start+count]), nil
+		case 0x6: // UTF-16BE 字符串
+			if start+count*2 > len(data) {
+				return "", errors.New("标签字符串越界")
+			}
+			units := make([]uint16, count)
+			for i := range units {
+				units[i] = binary.BigEndian.Uint16(data[start+i*2:])
+			}
+			return string(utf16.Decode(units)), nil
+		}
+		return "", fmt.Errorf("不支持的标签数据类型: 0x%X", kind)
+	}
+
+	offset, err := objectOffset(topObject)
+	if err != nil {
+		return nil, err
+	}
+	kind, count, start, err := readHeader(offset)
+	if err != nil {
+		return nil, err
+	}
+	if kind != 0xA {
+		return nil, errors.New("标签数据不是数组")
+	}
+	if start+count*refSize > len(data) {
+		return nil, errors.New("标签数组越界")
+	}
+
+	values := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		ref := readUint(data[start+i*refSize : start+(i+1)*refSize])
+		value, err := readString(ref)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
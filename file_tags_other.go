@@ -0,0 +1,11 @@
+//go:build !darwin
+
+package main
+
+// 其他系统没有与 Finder 标签对应的机制，按标签整理规则不可用
+const fileTagsSupported = false
+
+// 读取文件标签（当前系统不支持）
+func readFileTags(path string) ([]string, error) {
+	return nil, errFileTagsUnsupported
+}
@@ -0,0 +1,45 @@
+//go:build !darwin
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 不支持文件标签的系统上按标签整理报告不可用，而不是把所有文件放入「未标记」后报告成功
+func TestTagRuleUnsupported(t *testing.T) {
+	for _, rule := range []OrganizeRule{RuleByDate, RuleByExtension, RuleComposite} {
+		if err := validateTagRule(Config{OrganizeRule: string(rule)}); err != nil {
+			t.Fatalf("%s: %v", rule, err)
+		}
+	}
+	if err := validateTagRule(Config{OrganizeRule: string(RuleByTag)}); !errors.Is(err, errFileTagsUnsupported) {
+		t.Fatalf("按标签整理: %v", err)
+	}
+	if _, err := readFileTags(os.Args[0]); !errors.Is(err, errFileTagsUnsupported) {
+		t.Fatalf("读取标签: %v", err)
+	}
+
+	newTestEnv(t)
+	source := t.TempDir()
+	photo := writeTestFile(t, filepath.Join(source, "a.jpg"), "photo")
+	var stdout bytes.Buffer
+	exit := runHeadless([]string{"-headless", "-source", source, "-target", t.TempDir(), "-ext", "jpg", "-rule", "tag"}, &stdout, io.Discard)
+	var summary HeadlessSummary
+	if err := json.Unmarshal(stdout.Bytes(), &summary); err != nil {
+		t.Fatalf("总结JSON: %v\n%s", err, stdout.String())
+	}
+	if exit != exitFatal || summary.Outcome != OutcomeAborted || !strings.Contains(summary.Error, "文件标签") {
+		t.Fatalf("退出码 = %d, 总结 = %+v", exit, summary)
+	}
+	if _, err := os.Stat(photo); err != nil {
+		t.Fatalf("不应移动文件: %v", err)
+	}
+}
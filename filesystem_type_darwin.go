@@ -0,0 +1,18 @@
+//go:build darwin
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// 获取路径所在文件系统的类型，例如 apfs、msdos、exfat
+func filesystemType(path string) (string, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return "", fmt.Errorf("获取 %s 的文件系统类型失败: %w", path, err)
+	}
+	return unix.ByteSliceToString(stat.Fstypename[:]), nil
+}
@@ -0,0 +1,24 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// 获取路径所在文件系统的类型，只识别需要特殊处理的类型，其余返回空字符串
+func filesystemType(path string) (string, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return "", fmt.Errorf("获取 %s 的文件系统类型失败: %w", path, err)
+	}
+	switch int64(stat.Type) {
+	case unix.MSDOS_SUPER_MAGIC:
+		return "vfat", nil
+	case unix.EXFAT_SUPER_MAGIC:
+		return "exfat", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+import "errors"
+
+// 获取路径所在文件系统的类型（当前系统不支持）
+func filesystemType(path string) (string, error) {
+	return "", errors.New("当前系统不支持获取文件系统类型")
+}
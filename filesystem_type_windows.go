@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// 获取路径所在卷的文件系统类型，例如 NTFS、FAT32、exFAT
+func filesystemType(path string) (string, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+	volume := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(pathPtr, &volume[0], uint32(len(volume))); err != nil {
+		return "", fmt.Errorf("获取 %s 所在的卷失败: %w", path, err)
+	}
+	name := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumeInformation(&volume[0], nil, 0, nil, nil, nil, &name[0], uint32(len(name))); err != nil {
+		return "", fmt.Errorf("获取 %s 的文件系统类型失败: %w", path, err)
+	}
+	return windows.UTF16ToString(name), nil
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 两个文件夹的相似度达到这个比例时视为几乎相同
+const folderSimilarityMin = 0.8
+
+// 比较一对候选文件夹时最多抽样计算哈希的文件数
+const folderHashSamples = 32
+
+// folderFingerprint 源文件夹第一层子文件夹的概况：相对路径 → 文件大小
+type folderFingerprint struct {
+	path  string
+	files map[string]int64
+	size  int64
+}
+
+// folderPair 两个几乎相同的文件夹
+type folderPair struct {
+	a, b       *folderFingerprint
+	similarity float64  // 相同文件占两个文件夹所有文件的比例，抽样哈希不同时按比例扣除
+	onlyA      []string // 只在a中的文件（相对路径）
+	onlyB      []string // 只在b中的文件
+	differing  []string // 两边都有但大小或抽样的内容不同的文件
+	sampled    int      // 计算了哈希的文件数
+}
+
+// 冗余的一份默认是文件较少的，文件数相同时是后一个
+func (p *folderPair) defaultRedundant() *folderFingerprint {
+	if len(p.a.files) < len(p.b.files) {
+		return p.a
+	}
+	return p.b
+}
+
+// 统计文件夹中的文件，只读取大小，不读取内容
+func fingerprintFolder(path string) (*folderFingerprint, error) {
+	fp := &folderFingerprint{path: path, files: make(map[string]int64)}
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		fp.files[filepath.ToSlash(rel)] = info.Size()
+		fp.size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("统计文件夹 %s 失败: %w", path, err)
+	}
+	return fp, nil
+}
+
+// 较小值与较大值之比，两者都为0时为1
+func countRatio(a, b int64) float64 {
+	if a == b {
+		return 1
+	}
+	if a > b {
+		a, b = b, a
+	}
+	return float64(a) / float64(b)
+}
+
+// 比较两个文件夹。先比较文件数和总大小，再按相对路径和大小配对，
+// 只有配对后仍然足够相似的文件夹才抽样计算哈希。不够相似时返回nil
+func compareFolders(a, b *folderFingerprint) *folderPair {
+	if len(a.files) == 0 || len(b.files) == 0 {
+		return nil
+	}
+	if countRatio(int64(len(a.files)), int64(len(b.files))) < folderSimilarityMin || countRatio(a.size, b.size) < folderSimilarityMin {
+		return nil
+	}
+
+	pair := &folderPair{a: a, b: b}
+	var same []string
+	for rel, size := range a.files {
+		otherSize, ok := b.files[rel]
+		switch {
+		case !ok:
+			pair.onlyA = append(pair.onlyA, rel)
+		case otherSize != size:
+			pair.differing = append(pair.differing, rel)
+		default:
+			same = append(same, rel)
+		}
+	}
+	for rel := range b.files {
+		if _, ok := a.files[rel]; !ok {
+			pair.onlyB = append(pair.onlyB, rel)
+		}
+	}
+	union := len(same) + len(pair.differing) + len(pair.onlyA) + len(pair.onlyB)
+	if float64(len(same))/float64(union) < folderSimilarityMin {
+		return nil
+	}
+
+	// 均匀抽样计算哈希，抽样中内容不同的比例推算到所有同名同大小的文件
+	sort.Strings(same)
+	step := 1
+	if len(same) > folderHashSamples {
+		step = len(same) / folderHashSamples
+	}
+	mismatched := make(map[string]bool)
+	for i := 0; i < len(same) && pair.sampled < folderHashSamples; i += step {
+		rel := same[i]
+		pair.sampled++
+		hashA, errA := hashFile(filepath.Join(a.path, filepath.FromSlash(rel)))
+		hashB, errB := hashFile(filepath.Join(b.path, filepath.FromSlash(rel)))
+		if errA != nil || errB != nil || hashA != hashB {
+			mismatched[rel] = true
+			pair.differing = append(pair.differing, rel)
+		}
+	}
+	matching := float64(len(same))
+	if pair.sampled > 0 {
+		matching *= 1 - float64(len(mismatched))/float64(pair.sampled)
+	}
+	pair.similarity = matching / float64(union)
+	if pair.similarity < folderSimilarityMin {
+		return nil
+	}
+	sort.Strings(pair.onlyA)
+	sort.Strings(pair.onlyB)
+	sort.Strings(pair.differing)
+	return pair
+}
+
+// 比较各源文件夹第一层的子文件夹，找出几乎相同的文件夹对，按相似度从高到低排列。
+// 隐藏文件夹和本程序的隔离、备份文件夹不参与比较
+func findDuplicateFolders(roots []string, progress func(string)) ([]*folderPair, error) {
+	var folders []*folderFingerprint
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return nil, fmt.Errorf("读取源文件夹 %s 失败: %w", root, err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() || strings.HasPrefix(name, ".") || name == DuplicatesFolderName || name == ReplacedFolderName {
+				continue
+			}
+			fp, err := fingerprintFolder(filepath.Join(root, name))
+			if err != nil {
+				progress(err.Error())
+				continue
+			}
+			folders = append(folders, fp)
+		}
+	}
+	progress(fmt.Sprintf("正在比较 %d 个文件夹...", len(folders)))
+
+	var pairs []*folderPair
+	for i := range folders {
+		for j := i + 1; j < len(folders); j++ {
+			// 嵌套的源文件夹中同一个文件夹会出现两次
+			if isWithinAny(folders[i].path, []string{folders[j].path}) || isWithinAny(folders[j].path, []string{folders[i].path}) {
+				continue
+			}
+			if pair := compareFolders(folders[i], folders[j]); pair != nil {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].similarity > pairs[j].similarity
+	})
+	return pairs, nil
+}
+
+// 描述两个文件夹的差异，用于查看详情
+func describeFolderPair(pair *folderPair) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "A: %s（%d 个文件，%s）\n", pair.a.path, len(pair.a.files), formatFileSize(pair.a.size))
+	fmt.Fprintf(&sb, "B: %s（%d 个文件，%s）\n", pair.b.path, len(pair.b.files), formatFileSize(pair.b.size))
+	fmt.Fprintf(&sb, "相似度 %.1f%%，抽样比较了 %d 个文件的内容\n", pair.similarity*100, pair.sampled)
+	sections := []struct {
+		title string
+		files []string
+	}{
+		{"只在A中", pair.onlyA},
+		{"只在B中", pair.onlyB},
+		{"内容不同", pair.differing},
+	}
+	for _, section := range sections {
+		if len(section.files) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n%s（%d）:\n", section.title, len(section.files))
+		for _, rel := range section.files {
+			fmt.Fprintf(&sb, "  %s\n", rel)
+		}
+	}
+	if len(pair.onlyA)+len(pair.onlyB)+len(pair.differing) == 0 {
+		sb.WriteString("\n抽样比较的文件都相同，没有发现差异\n")
+	}
+	return sb.String()
+}
+
+// 把冗余的文件夹中与保留的一份内容相同的文件移到目标的隔离文件夹中，保持原来的目录结构。
+// 只在冗余的一份中或内容不同的文件留在原处，不会因为抽样比较而丢失。与整理一样锁定目标文件夹，
+// 返回移走和留下的文件数
+func (fo *FileOrganizer) quarantineFolder(folder, keeper, targetRoot string, xattrs bool) (moved, kept int, err error) {
+	if err := fo.checkWritable(); err != nil {
+		return 0, 0, err
+	}
+	unlock, err := fo.lockTarget(targetRoot, newRunID())
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+
+	dest := filepath.Join(targetRoot, DuplicatesFolderName, filepath.Base(folder))
+	if _, err := os.Lstat(dest); err == nil {
+		dest += "_" + time.Now().Format("20060102_150405")
+	}
+	var dirs []string
+	err = filepath.WalkDir(folder, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, p)
+			return nil
+		}
+		rel, err := filepath.Rel(folder, p)
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !sameFileContent(p, filepath.Join(keeper, rel)) {
+			kept++
+			return nil
+		}
+		if _, err := fo.moveFile(p, filepath.Join(dest, filepath.Dir(rel)), xattrs); err != nil {
+			return fmt.Errorf("隔离 %s 失败: %w", p, err)
+		}
+		moved++
+		return nil
+	})
+	// 从最深的文件夹开始删除移空的文件夹，仍有文件的文件夹保留
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Remove(dirs[i])
+	}
+	return moved, kept, err
+}
+
+// 两个文件的大小和内容是否都相同，任一个无法读取时视为不同
+func sameFileContent(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil || !infoB.Mode().IsRegular() || infoA.Size() != infoB.Size() {
+		return false
+	}
+	hashA, errA := hashFile(a)
+	hashB, errB := hashFile(b)
+	return errA == nil && errB == nil && hashA == hashB
+}
+
+// 比较源文件夹中的子文件夹，列出几乎相同的文件夹对，可以查看差异，
+// 并把冗余的一份排除在本次整理之外或整个移到隔离文件夹
+func (fo *FileOrganizer) showFolderDuplicatesDialog() {
+	if len(fo.SourceDirs) == 0 {
+		dialog.ShowInformation("提示", "请先添加源文件夹", fo.Window)
+		return
+	}
+	roots := append([]string(nil), fo.SourceDirs...)
+	config := fo.currentConfig()
+
+	fo.log("正在查找重复的文件夹...")
+	go func() {
+		pairs, err := findDuplicateFolders(roots, fo.log)
+		if err != nil {
+			fo.log(err.Error())
+			fo.safeUpdateUI(func() { dialog.ShowError(err, fo.Window) })
+			return
+		}
+		fo.log(fmt.Sprintf("重复文件夹分析完成: 发现 %d 对相似度不低于 %.0f%% 的文件夹", len(pairs), folderSimilarityMin*100))
+		fo.safeUpdateUI(func() {
+			if len(pairs) == 0 {
+				dialog.ShowInformation("重复的文件夹", "源文件夹的子文件夹中没有几乎相同的文件夹", fo.Window)
+				return
+			}
+			fo.showFolderPairs(pairs, config)
+		})
+	}()
+}
+
+// 显示几乎相同的文件夹对
+func (fo *FileOrganizer) showFolderPairs(pairs []*folderPair, config Config) {
+	redundant := make([]*folderFingerprint, len(pairs))
+	for i, pair := range pairs {
+		redundant[i] = pair.defaultRedundant()
+	}
+	selected := -1
+
+	details := widget.NewLabel("选择左侧的一对文件夹查看差异")
+	details.TextStyle = fyne.TextStyle{Monospace: true}
+	details.Selectable = true
+	redundantLabel := widget.NewLabel("")
+	redundantLabel.Wrapping = fyne.TextWrapWord
+
+	pairList := widget.NewList(
+		func() int { return len(pairs) },
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			pair := pairs[id]
+			o.(*widget.Label).SetText(fmt.Sprintf("%.1f%%  %s ⇄ %s", pair.similarity*100,
+				filepath.Base(pair.a.path), filepath.Base(pair.b.path)))
+		},
+	)
+
+	var swapBtn, excludeBtn, quarantineBtn *widget.Button
+	update := func() {
+		if selected < 0 {
+			swapBtn.Disable()
+			excludeBtn.Disable()
+			quarantineBtn.Disable()
+			return
+		}
+		details.SetText(describeFolderPair(pairs[selected]))
+		redundantLabel.SetText("冗余的一份: " + redundant[selected].path)
+		swapBtn.Enable()
+		excludeBtn.Enable()
+		quarantineBtn.Enable()
+		if isFromReadOnlySource(filepath.Join(redundant[selected].path, "x"), config) {
+			// 只读源文件夹中的文件不能移动
+			quarantineBtn.Disable()
+		}
+	}
+	swapBtn = widget.NewButton("交换保留和冗余", func() {
+		pair := pairs[selected]
+		if redundant[selected] == pair.a {
+			redundant[selected] = pair.b
+		} else {
+			redundant[selected] = pair.a
+		}
+		update()
+	})
+	excludeBtn = widget.NewButton("从本次整理中排除冗余的一份", func() {
+		folder := redundant[selected].path
+		count := 0
+		for _, path := range fo.scannedFiles {
+			if isWithinAny(path, []string{folder}) && !fo.excludedFiles[path] {
+				fo.excludedFiles[path] = true
+				count++
+			}
+		}
+		fo.refreshFileTable()
+		fo.log(fmt.Sprintf("已排除 %s 中的 %d 个文件", folder, count))
+		dialog.ShowInformation("已排除", fmt.Sprintf("已排除 %s 中的 %d 个文件，它们不会在本次整理中被移动", folder, count), fo.Window)
+	})
+	quarantineBtn = widget.NewButton("隔离冗余的一份到 "+DuplicatesFolderName, func() {
+		if fo.refuseInReadOnlyMode() {
+			return
+		}
+		pair := pairs[selected]
+		folder, keeper := pair.b.path, pair.a.path
+		if redundant[selected] == pair.a {
+			folder, keeper = pair.a.path, pair.b.path
+		}
+		dialog.ShowConfirm("隔离文件夹", fmt.Sprintf("将把 %s 中与 %s 内容相同的文件移到 %s，保持原来的目录结构。"+
+			"只在冗余的一份中或内容不同的文件留在原处。确定继续吗？",
+			folder, keeper, filepath.Join(config.TargetDir, DuplicatesFolderName)), func(ok bool) {
+			if !ok {
+				return
+			}
+			if fo.runActive() {
+				dialog.ShowInformation("提示", "正在整理文件，请等待整理完成后再隔离", fo.Window)
+				return
+			}
+			go func() {
+				moved, kept, err := fo.quarantineFolder(folder, keeper, config.TargetDir, config.PreserveXattrs)
+				fo.log(fmt.Sprintf("已将 %s 中的 %d 个文件移到 %s，%d 个只在这里或内容不同的文件留在原处",
+					folder, moved, DuplicatesFolderName, kept))
+				if err != nil {
+					fo.log(err.Error())
+				}
+				fo.safeUpdateUI(func() {
+					if err != nil && !errors.Is(err, errReadOnlyMode) {
+						dialog.ShowError(err, fo.Window)
+					}
+					fo.rescan()
+				})
+			}()
+		}, fo.Window)
+	})
+	quarantineBtn.Importance = widget.WarningImportance
+	pairList.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		update()
+	}
+	update()
+
+	detailScroll := container.NewScroll(details)
+	split := container.NewHSplit(pairList, container.NewBorder(redundantLabel,
+		container.NewHBox(swapBtn, layout.NewSpacer(), excludeBtn, quarantineBtn), nil, nil, detailScroll))
+	split.Offset = 0.35
+	summary := widget.NewLabel(fmt.Sprintf("发现 %d 对相似度不低于 %.0f%% 的文件夹（先比较文件数和总大小，再按路径和大小配对，最后抽样比较内容）",
+		len(pairs), folderSimilarityMin*100))
+	summary.Wrapping = fyne.TextWrapWord
+
+	pairsDialog := dialog.NewCustom("重复的文件夹", "关闭", container.NewBorder(summary, nil, nil, nil, split), fo.Window)
+	pairsDialog.Resize(fyne.NewSize(900, 560))
+	fo.showDialog(pairsDialog, pairList)
+}
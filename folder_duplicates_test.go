@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 隔离冗余的文件夹时只移走与保留的一份内容相同的文件；目标被锁定时不移动任何文件
+func TestQuarantineFolder(t *testing.T) {
+	tests := []struct {
+		name      string
+		locked    bool
+		wantMoved int
+		wantLeft  map[string]string // 冗余文件夹中留下的文件
+	}{
+		{"只移走相同的文件", false, 2, map[string]string{"only.jpg": "only", "sub/diff.jpg": "bbbb"}},
+		{"目标被锁定", true, 0, map[string]string{"same.jpg": "same", "sub/same.jpg": "same2",
+			"only.jpg": "only", "sub/diff.jpg": "bbbb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			root := t.TempDir()
+			target := t.TempDir()
+			keeper := filepath.Join(root, "keep")
+			folder := filepath.Join(root, "copy")
+			for rel, content := range map[string]string{"same.jpg": "same", "sub/same.jpg": "same2", "sub/diff.jpg": "aaaa"} {
+				writeTestFile(t, filepath.Join(keeper, rel), content)
+			}
+			for rel, content := range map[string]string{"same.jpg": "same", "sub/same.jpg": "same2", "sub/diff.jpg": "bbbb", "only.jpg": "only"} {
+				writeTestFile(t, filepath.Join(folder, rel), content)
+			}
+			if tt.locked {
+				lock, err := acquireTargetLock(target, "other-run")
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { lock.release() })
+			}
+
+			moved, _, err := fo.quarantineFolder(folder, keeper, target, false)
+			if (err != nil) != tt.locked || moved != tt.wantMoved {
+				t.Fatalf("移走 %d, %v", moved, err)
+			}
+			left := snapshotTree(t, folder)
+			want := make(map[string]string)
+			for rel, content := range tt.wantLeft {
+				want[filepath.FromSlash(rel)] = content
+			}
+			if !reflect.DeepEqual(left, want) {
+				t.Fatalf("冗余文件夹中留下 %v", left)
+			}
+			if !tt.locked {
+				if got := readTestFile(t, filepath.Join(target, DuplicatesFolderName, "copy", "sub", "same.jpg")); got != "same2" {
+					t.Fatalf("隔离的文件 = %q", got)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(keeper, "same.jpg")); err != nil {
+				t.Fatal("保留的一份被修改")
+			}
+			if fo.runActive() {
+				t.Fatal("隔离结束后仍计为正在整理")
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 新建文件夹超过该数量、并且平均每个文件夹不到folderExplosionMinFiles个文件时提示规则可能产生了过多的文件夹
+const (
+	folderExplosionMinFolders = 50
+	folderExplosionMinFiles   = 2
+)
+
+// plannedFolder 按当前规则会用到的一个目标文件夹
+type plannedFolder struct {
+	path   string
+	files  int
+	exists bool // 文件夹已经存在，整理时不需要新建
+}
+
+// 计算扫描结果中每个文件按当前规则的目标文件夹，按路径排序
+func (fo *FileOrganizer) planFolders(config Config, files []string) []plannedFolder {
+	counts := make(map[string]int)
+	for _, filePath := range files {
+		info := fo.scannedFileInfos[filePath]
+		if info == nil || config.ExcludedFiles[filePath] || config.Pins.matches(filePath, nil) || !fo.isTargetFile(fileExtension(filePath), config.FileExtensions) ||
+			isInsidePreservedTarget(filePath, config) {
+			continue
+		}
+		if targetDir := fo.planTargetDir(filePath, info, config); targetDir != "" {
+			counts[targetDir]++
+		}
+	}
+
+	folders := make([]plannedFolder, 0, len(counts))
+	for path, n := range counts {
+		_, err := os.Stat(path)
+		folders = append(folders, plannedFolder{path: path, files: n, exists: err == nil})
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].path < folders[j].path
+	})
+	return folders
+}
+
+// 文件夹在预览中显示的名称：目标文件夹中的相对路径，溢出到其他卷时注明所在的卷
+func plannedFolderLabel(path string, config Config) string {
+	root := config.TargetDir
+	if volume := volumeOf(path, config.Volumes); volume != "" {
+		root = volume
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	if root != config.TargetDir {
+		return fmt.Sprintf("%s（目标卷: %s）", rel, root)
+	}
+	return rel
+}
+
+// 显示按当前规则会创建的文件夹以及每个文件夹中的文件数
+func (fo *FileOrganizer) showPlannedFoldersDialog() {
+	if len(fo.scannedFiles) == 0 {
+		dialog.ShowInformation("提示", "请先扫描文件", fo.Window)
+		return
+	}
+	if OrganizeRule(fo.RuleSelect.Selected) == RuleByHash {
+		dialog.ShowInformation("目标文件夹", "按内容哈希整理时文件夹由文件内容决定，整理时才计算。", fo.Window)
+		return
+	}
+
+	config := fo.currentConfig()
+	config.ExcludedFiles = make(map[string]bool, len(fo.excludedFiles))
+	for path := range fo.excludedFiles {
+		config.ExcludedFiles[path] = true
+	}
+	progress := widget.NewProgressBarInfinite()
+	computingDialog := dialog.NewCustomWithoutButtons("正在计算目标文件夹", container.NewVBox(
+		widget.NewLabel("正在按当前规则计算每个文件的目标文件夹..."),
+		progress,
+	), fo.Window)
+	computingDialog.Show()
+
+	files := fo.scannedFiles
+	go func() {
+		folders := fo.planFolders(config, files)
+		// 按类别和大小整理时同时统计类别 × 大小的矩阵
+		var matrix *typeSizeMatrix
+		if OrganizeRule(config.OrganizeRule) == RuleByTypeSize {
+			matrix = fo.planTypeSizeMatrix(config, files)
+		}
+		fo.safeUpdateUI(func() {
+			progress.Stop()
+			computingDialog.Hide()
+			fo.showPlannedFolders(folders, config, matrix)
+		})
+	}()
+}
+
+// 显示计算出的目标文件夹，按类别和大小整理时在列表上方显示类别 × 大小的表格
+func (fo *FileOrganizer) showPlannedFolders(folders []plannedFolder, config Config, matrix *typeSizeMatrix) {
+	fileCount, newCount := 0, 0
+	for _, folder := range folders {
+		fileCount += folder.files
+		if !folder.exists {
+			newCount++
+		}
+	}
+	summary := widget.NewLabel(fmt.Sprintf("%d 个文件将放入 %d 个文件夹，其中 %d 个需要新建", fileCount, len(folders), newCount))
+	summary.Wrapping = fyne.TextWrapWord
+	content := container.NewVBox(summary)
+	if newCount > folderExplosionMinFolders && fileCount < newCount*folderExplosionMinFiles {
+		warning := widget.NewLabel(fmt.Sprintf("注意: 将新建 %d 个文件夹，平均每个文件夹不到 %d 个文件，请确认整理规则和文件夹名称模板是否符合预期。",
+			newCount, folderExplosionMinFiles))
+		warning.Wrapping = fyne.TextWrapWord
+		warning.Importance = widget.WarningImportance
+		content.Add(warning)
+	}
+	if matrix != nil && len(matrix.rows) > 0 {
+		content.Add(typeSizeMatrixGrid(matrix))
+	}
+
+	folderList := widget.NewList(
+		func() int { return len(folders) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			folder := folders[i]
+			state := "已存在"
+			if !folder.exists {
+				state = "新建"
+			}
+			o.(*widget.Label).SetText(fmt.Sprintf("%s — %d 个文件（%s）", plannedFolderLabel(folder.path, config), folder.files, state))
+		},
+	)
+
+	foldersDialog := dialog.NewCustom("目标文件夹", "关闭", container.NewBorder(content, nil, nil, nil, folderList), fo.Window)
+	foldersDialog.Resize(fyne.NewSize(640, 480))
+	foldersDialog.Show()
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// 文件夹名称模板中可用的变量，例如 "照片-{year}" 或 "{category}/{ext}文件"
+var folderTemplateTokens = []string{"year", "month", "day", "ext", "category", "size_bucket", "rule"}
+
+// 变量的说明，显示在设置中
+const folderTemplateHint = "可用变量: {year} 年, {month} 月, {day} 日, {ext} 后缀, {category} 文件类别, " +
+	"{size_bucket} 大小分组, {rule} 规则原本的文件夹名称。用 / 分隔多层文件夹，留空使用规则原本的名称"
+
+// 文件类别，按后缀判断
+var fileCategories = map[string]string{}
+
+func init() {
+	for category, exts := range map[string][]string{
+		"图片":  {".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".heic", ".heif", ".tif", ".tiff", ".svg", ".raw", ".dng", ".cr2", ".cr3", ".nef", ".arw", ".orf", ".rw2"},
+		"视频":  {".mp4", ".mov", ".avi", ".mkv", ".wmv", ".flv", ".webm", ".m4v", ".3gp", ".mts", ".m2ts"},
+		"音频":  {".mp3", ".wav", ".flac", ".aac", ".m4a", ".ogg", ".wma", ".opus", ".aiff"},
+		"文档":  {".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".txt", ".md", ".rtf", ".odt", ".ods", ".odp", ".csv", ".epub", ".pages", ".numbers", ".key"},
+		"压缩包": {".zip", ".rar", ".7z", ".tar", ".gz", ".bz2", ".xz", ".zst", ".tar.gz", ".tar.bz2", ".tar.xz", ".tar.zst", ".tar.lz", ".tar.lzma", ".tar.z"},
+		"安装包": {".exe", ".msi", ".dmg", ".pkg", ".deb", ".rpm", ".apk", ".appimage"},
+		"代码":  {".go", ".py", ".js", ".ts", ".java", ".c", ".cpp", ".h", ".rs", ".rb", ".php", ".html", ".css", ".json", ".xml", ".yaml", ".yml", ".sh", ".user.js", ".user.css"},
+		"邮件":  {".eml", ".msg"},
+	} {
+		for _, ext := range exts {
+			fileCategories[ext] = category
+		}
+	}
+}
+
+// 没有对应类别的文件
+const otherCategoryName = "其他"
+
+// 获取文件的类别
+func fileCategory(filePath string) string {
+	if category, ok := fileCategories[strings.ToLower(fileExtension(filePath))]; ok {
+		return category
+	}
+	return otherCategoryName
+}
+
+// 默认的文件大小分组上限（MB）：小于1MB、1MB-10MB、10MB-100MB、100MB-1GB、1GB以上
+var defaultSizeBucketsMB = []int{1, 10, 100, 1024}
+
+// sizeBucket 文件大小分组的上限和名称，最后一组没有上限（limit为-1）
+type sizeBucket struct {
+	limit int64
+	label string
+}
+
+// 按从小到大的上限（MB）生成大小分组，最后一组没有上限
+func newSizeBuckets(limitsMB []int) []sizeBucket {
+	if len(limitsMB) == 0 {
+		limitsMB = defaultSizeBucketsMB
+	}
+	buckets := make([]sizeBucket, 0, len(limitsMB)+1)
+	for i, mb := range limitsMB {
+		label := "小于" + formatSizeLimit(mb)
+		if i > 0 {
+			label = formatSizeLimit(limitsMB[i-1]) + "-" + formatSizeLimit(mb)
+		}
+		buckets = append(buckets, sizeBucket{int64(mb) << 20, label})
+	}
+	return append(buckets, sizeBucket{-1, formatSizeLimit(limitsMB[len(limitsMB)-1]) + "以上"})
+}
+
+// 分组上限的名称，1024的倍数显示为GB
+func formatSizeLimit(mb int) string {
+	if mb%1024 == 0 {
+		return fmt.Sprintf("%dGB", mb/1024)
+	}
+	return fmt.Sprintf("%dMB", mb)
+}
+
+// 解析设置中的大小分组上限，例如 "1, 10, 100, 1024"，必须是从小到大的正整数（MB）
+func parseSizeBuckets(text string) ([]int, error) {
+	var limits []int
+	for _, part := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == '，' || r == ' ' }) {
+		mb, err := strconv.Atoi(part)
+		if err != nil || mb < 1 {
+			return nil, fmt.Errorf("大小分组的上限必须是正整数（MB）: %q", part)
+		}
+		if len(limits) > 0 && mb <= limits[len(limits)-1] {
+			return nil, fmt.Errorf("大小分组的上限必须从小到大排列: %d", mb)
+		}
+		limits = append(limits, mb)
+	}
+	if len(limits) == 0 {
+		return nil, errors.New("请至少输入一个大小分组的上限")
+	}
+	return limits, nil
+}
+
+// 设置中显示的大小分组上限
+func formatSizeBuckets(limitsMB []int) string {
+	parts := make([]string, len(limitsMB))
+	for i, mb := range limitsMB {
+		parts[i] = strconv.Itoa(mb)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// 文件大小所属分组的序号
+func sizeBucketIndex(buckets []sizeBucket, size int64) int {
+	for i, bucket := range buckets {
+		if bucket.limit < 0 || size < bucket.limit {
+			return i
+		}
+	}
+	return len(buckets) - 1
+}
+
+// 获取文件大小所属的分组
+func sizeBucketLabel(buckets []sizeBucket, size int64) string {
+	return buckets[sizeBucketIndex(buckets, size)].label
+}
+
+// 检查文件夹名称模板：括号成对、变量可用、每层文件夹都有名称
+func validateFolderTemplate(template string) error {
+	template = strings.TrimSpace(template)
+	if template == "" {
+		return nil
+	}
+	if strings.Contains(template, `\`) {
+		return errors.New("请用 / 分隔多层文件夹")
+	}
+	for _, segment := range strings.Split(template, "/") {
+		if strings.TrimSpace(segment) == "" {
+			return errors.New("模板中有空的文件夹名称")
+		}
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("不能使用 %s 作为文件夹名称", segment)
+		}
+	}
+	rest := template
+	for {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			return nil
+		}
+		if rest[open] == '}' {
+			return errors.New("模板中有多余的 }")
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] == '{' {
+			return errors.New("模板中的 { 没有对应的 }")
+		}
+		token := rest[open+1 : open+1+end]
+		if !isFolderTemplateToken(token) {
+			return fmt.Errorf("未知的变量 {%s}", token)
+		}
+		rest = rest[open+1+end+1:]
+	}
+}
+
+// 是否为可用的模板变量
+func isFolderTemplateToken(token string) bool {
+	for _, known := range folderTemplateTokens {
+		if token == known {
+			return true
+		}
+	}
+	return false
+}
+
+// 按模板生成文件夹名称（可能包含多层）。ruleFolder为规则原本的文件夹名称，
+// date只在模板用到日期变量时调用
+func expandFolderTemplate(template, filePath string, size int64, buckets []sizeBucket, ruleFolder, extensionCase string, date func() time.Time) string {
+	var resolved time.Time
+	dateResolved := false
+	value := func(token string) string {
+		switch token {
+		case "year", "month", "day":
+			if !dateResolved {
+				resolved = date()
+				dateResolved = true
+			}
+			switch token {
+			case "year":
+				return resolved.Format("2006")
+			case "month":
+				return resolved.Format("01")
+			}
+			return resolved.Format("02")
+		case "ext":
+			ext := strings.TrimPrefix(fileExtension(filePath), ".")
+			if ext == "" {
+				return NoExtensionFolderName
+			}
+			if extensionCase == "uppercase" {
+				return strings.ToUpper(ext)
+			}
+			return strings.ToLower(ext)
+		case "category":
+			return fileCategory(filePath)
+		case "size_bucket":
+			return sizeBucketLabel(buckets, size)
+		case "rule":
+			return ruleFolder
+		}
+		return "{" + token + "}"
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(strings.TrimSpace(template), "/") {
+		var sb strings.Builder
+		rest := segment
+		for {
+			open := strings.IndexByte(rest, '{')
+			end := strings.IndexByte(rest, '}')
+			if open < 0 || end < open {
+				sb.WriteString(rest)
+				break
+			}
+			sb.WriteString(rest[:open])
+			sb.WriteString(value(rest[open+1 : end]))
+			rest = rest[end+1:]
+		}
+		// {rule} 可能本身包含多层文件夹，其余部分中的路径分隔符都替换掉
+		for _, part := range strings.Split(sb.String(), string(os.PathSeparator)) {
+			segments = append(segments, sanitizeFolderName(part))
+		}
+	}
+	return strings.Join(segments, string(os.PathSeparator))
+}
+
+// 对规则原本的文件夹名称应用当前规则的模板，没有模板或按内容哈希整理时保持不变
+func (fo *FileOrganizer) applyFolderTemplate(filePath string, fileInfo os.FileInfo, ruleFolder string, config Config) string {
+	if config.FolderTemplate == "" || ruleFolder == "" || OrganizeRule(config.OrganizeRule) == RuleByHash {
+		return ruleFolder
+	}
+	return expandFolderTemplate(config.FolderTemplate, filePath, fileInfo.Size(), config.sizeBuckets(), ruleFolder, config.ExtensionCase, func() time.Time {
+		return fo.fileDate(filePath, fileInfo, config)
+	})
+}
+
+// 预览模板在一个文件上的结果，没有扫描结果时使用示例文件
+func (fo *FileOrganizer) previewFolderTemplate(template string, config Config) string {
+	if err := validateFolderTemplate(template); err != nil {
+		return err.Error()
+	}
+	if strings.TrimSpace(template) == "" {
+		return "（使用规则原本的文件夹名称）"
+	}
+	for _, filePath := range fo.scannedFiles {
+		info := fo.scannedFileInfos[filePath]
+		if info == nil {
+			continue
+		}
+		config.FolderTemplate = template
+		ruleFolder := fo.ruleFolderName(filePath, info, config)
+		if ruleFolder == "" {
+			continue
+		}
+		return fmt.Sprintf("%s → %s", filePath, ruleFolder)
+	}
+	const sample = "IMG_20240315_101500.jpg"
+	folder := expandFolderTemplate(template, sample, 3<<20, config.sizeBuckets(), "2024-03-15", config.ExtensionCase, func() time.Time {
+		return time.Date(2024, 3, 15, 10, 15, 0, 0, time.Local)
+	})
+	return fmt.Sprintf("示例 %s → %s", sample, folder)
+}
+
+// 加载各规则的文件夹名称模板
+func (fo *FileOrganizer) loadFolderTemplates() {
+	fo.FolderTemplates = make(map[string]string)
+	if data := fyne.CurrentApp().Preferences().StringWithFallback("folder_templates", ""); data != "" {
+		if err := json.Unmarshal([]byte(data), &fo.FolderTemplates); err != nil {
+			fo.log(fmt.Sprintf("加载文件夹名称模板失败: %v", err))
+			fo.FolderTemplates = make(map[string]string)
+		}
+	}
+}
+
+// 保存各规则的文件夹名称模板
+func (fo *FileOrganizer) saveFolderTemplates() {
+	data, err := json.Marshal(fo.FolderTemplates)
+	if err != nil {
+		fo.log(fmt.Sprintf("保存文件夹名称模板失败: %v", err))
+		return
+	}
+	fyne.CurrentApp().Preferences().SetString("folder_templates", string(data))
+}
+
+// 设置规则的文件夹名称模板，留空时恢复规则原本的名称
+func (fo *FileOrganizer) setFolderTemplate(rule OrganizeRule, template string) {
+	template = strings.TrimSpace(template)
+	if fo.FolderTemplates == nil {
+		fo.FolderTemplates = make(map[string]string)
+	}
+	if template == "" {
+		delete(fo.FolderTemplates, string(rule))
+	} else {
+		fo.FolderTemplates[string(rule)] = template
+	}
+	fo.saveFolderTemplates()
+}
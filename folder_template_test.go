@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+// 模板中的变量、括号和文件夹名称都要有效，否则不能保存或用于无界面整理
+func TestValidateFolderTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{"", false},
+		{"照片-{year}", false},
+		{"{category}/{year}/{month}", false},
+		{"{rule} {size_bucket}", false},
+		{"{nope}", true},
+		{"{year", true},
+		{"year}", true},
+		{"{{year}}", true},
+		{`{year}\{month}`, true},
+		{"{year}//{month}", true},
+		{"../{ext}", true},
+		{"{ext}/.", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			if err := validateFolderTemplate(tt.template); (err != nil) != tt.wantErr {
+				t.Fatalf("validateFolderTemplate(%q) = %v, 期望出错 = %v", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
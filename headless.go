@@ -0,0 +1,590 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 无界面运行的退出码，供脚本和定时任务判断结果
+const (
+	exitSuccess        = 0 // 全部整理完成（或跳过的文件未要求视为失败）
+	exitFatal          = 1 // 参数错误、无法开始或整理中止
+	exitFailures       = 2 // 整理完成但失败的文件超过允许的比例，或开启 -fail-on-skip 时有文件被跳过
+	exitNothingMatched = 3 // 没有任何文件符合所选后缀
+)
+
+// 无界面运行的结果，与退出码一一对应
+const (
+	OutcomeSuccess        = "success"
+	OutcomeSkips          = "completed_with_skips"
+	OutcomeFailures       = "completed_with_failures"
+	OutcomeAborted        = "aborted"
+	OutcomeNothingMatched = "nothing_matched"
+)
+
+// 发送统计时使用的令牌从这个环境变量读取，不出现在命令行中
+const statsTokenEnvVar = "FILE_ORGANIZER_STATS_TOKEN"
+
+// 没有用 -profile 指定时无界面整理在统计中记录的配置方案，与界面中的整理分开比较用时
+const headlessProfileName = "无界面"
+
+// 命令行中是否要求无界面运行
+func headlessRequested(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "-headless" || arg == "--headless" || arg == "-headless=true" || arg == "--headless=true" {
+			return true
+		}
+	}
+	return false
+}
+
+// 可重复的字符串参数，例如多个 -source
+type stringListFlag []string
+
+func (l *stringListFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringListFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// headlessOptions 命令行参数解析后的整理配置和退出码阈值
+type headlessOptions struct {
+	config         Config
+	compactMin     int
+	bursts         bool
+	convertImages  bool
+	failOnSkip     bool
+	breakStaleLock bool
+	streamingLimit int
+	minSuccessRate float64
+	statusAddr     string
+	statusToken    string
+}
+
+// 解析 NxM 形式的两个正整数，例如 -hash-shards 2x2
+func parsePair(name, text string) (int, int, error) {
+	first, second, ok := strings.Cut(strings.ToLower(text), "x")
+	a, errA := strconv.Atoi(strings.TrimSpace(first))
+	b, errB := strconv.Atoi(strings.TrimSpace(second))
+	if !ok || errA != nil || errB != nil || a <= 0 || b <= 0 {
+		return 0, 0, fmt.Errorf("-%s 的格式为 NxM，例如 2x2: %q", name, text)
+	}
+	return a, b, nil
+}
+
+// 检查参数的值是否是允许的值之一
+func checkChoice(name, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("-%s 的值 %q 无效，可用: %s", name, value, strings.Join(allowed, ", "))
+}
+
+// 解析命令行参数，参数与 cliCommandFor 生成的命令对应
+func parseHeadlessArgs(args []string, stderr io.Writer) (*headlessOptions, error) {
+	fs := flag.NewFlagSet("file_organizer", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var sources, readOnlySources, spillTargets stringListFlag
+	fs.Bool("headless", false, "不显示界面，整理一次后退出")
+	fs.Var(&sources, "source", "源文件夹，可以重复")
+	fs.Var(&readOnlySources, "read-only-source", "只复制不移动的源文件夹，可以重复")
+	target := fs.String("target", "", "目标文件夹，默认为第一个源文件夹")
+	rule := fs.String("rule", string(RuleByExtension), "整理规则: date、extension、tag、age、hash、camera、type-size 或 composite")
+	extensions := fs.String("ext", "", "整理的文件后缀，逗号分隔，例如 jpg,png")
+	dateFormat := fs.String("date-format", "YYYY-MM-DD", "日期文件夹的命名规则")
+	extCase := fs.String("ext-case", "lowercase", "后缀文件夹的大小写")
+	layout := fs.String("layout", LayoutFlat, "目标文件夹结构: flat、rule_first、source_first 或 name_prefix")
+	multiTag := fs.String("multi-tag", MultiTagFirst, "多个标签的文件: first 或 duplicate")
+	targetMinFree := fs.Int64("target-min-free", 0, "使用多个目标卷时目标文件夹所在磁盘至少保留的MB")
+	fs.Var(&spillTargets, "spill-target", "目标空间不足时使用的其他卷，格式为 路径=保留MB，可以重复")
+	hashShards := fs.String("hash-shards", fmt.Sprintf("%dx%d", defaultHashShardDepth, defaultHashShardWidth), "按内容哈希整理时的分片，层数x每层字符数")
+	hashRename := fs.Bool("hash-rename", false, "按内容哈希整理时把文件改名为哈希值")
+	folderTemplate := fs.String("folder-template", "", "文件夹名称模板")
+	compactMin := fs.Int("compact-extensions", 0, "按后缀整理时文件数少于该值的后缀合并到「"+CompactedExtensionsFolderName+"」")
+	extensionRanks := fs.String("extension-ranks", "", "后缀文件夹的序号，例如 jpg=1,pdf=2")
+	volumeCapMB := fs.Int64("volume-cap-mb", 0, "按容量分卷时每卷的MB，0不分卷")
+	ruleSegments := fs.String("rule-segments", "", "组合规则的层，例如 category,date")
+	segmentSeparator := fs.String("segment-separator", "", "组合规则的分隔符，不为空时各层连接成一级文件夹，例如 \" - \"")
+	ageLabels := fs.String("age-labels", "", "按年龄整理时各分组的文件夹名称，逗号分隔")
+	sizeBuckets := fs.String("size-buckets", "", "按类别和大小整理时各大小分组的上限（MB，从小到大，逗号分隔），默认 "+formatSizeBuckets(defaultSizeBucketsMB))
+	dateSources := fs.String("date-sources", "", "文件日期的来源顺序，例如 exif,filename,mtime")
+	timeZone := fs.String("timezone", "", "划分日期使用的时区: Local（本机时区，默认）、UTC 或 IANA 名称，例如 Asia/Shanghai")
+	bursts := fs.String("bursts", "", "识别连拍，最多间隔秒数x最多张数，例如 2x30")
+	dateFolderMtime := fs.Bool("date-folder-mtime", false, "把日期文件夹的修改时间设为对应的日期")
+	emailSender := fs.Bool("email-sender", false, "按日期整理邮件时先按发件人域名分文件夹")
+	packSmallKB := fs.Int("pack-small-kb", 0, "按日期整理时小于这个大小（KB）的文件放入日期文件夹对应的压缩包，0不打包")
+	convertHEIC := fs.Bool("convert-heic", false, "把HEIC等格式转换为JPEG")
+	discardOriginals := fs.Bool("discard-converted-originals", false, "转换后删除原始文件")
+	lowPriority := fs.Bool("low-priority", false, "整理期间降低进程的CPU和磁盘优先级")
+	incomingOnly := fs.Bool("incoming-only", false, "仅处理新增：跳过上次成功整理时已在源文件夹中的文件")
+	dedup := fs.Bool("dedup", false, "跳过目标中已有内容相同的文件")
+	catalog := fs.Bool("catalog", false, "记录整理目录，可以撤销")
+	dedupEmpty := fs.Bool("dedup-empty", false, "目标去重时把空文件视为相同内容")
+	removeEmptyDirs := fs.Bool("remove-empty-dirs", false, "删除整理后变空的源子文件夹")
+	moveEmptyDirs := fs.Bool("move-empty-dirs", false, "把源文件夹第一层的空文件夹移到目标")
+	postRunHook := fs.String("post-run-hook", "", "整理成功后通过系统shell运行的命令，可用 "+strings.Join(runHookTokens, " "))
+	perFileHook := fs.String("per-file-hook", "", "每个文件整理后通过系统shell运行的命令，可用 "+strings.Join(fileHookTokens, " "))
+	hookTimeout := fs.Int("hook-timeout", defaultHookTimeoutSec, "命令的超时（秒）")
+	statsEndpoint := fs.String("stats-endpoint", "", "整理后把统计发送到这个地址，令牌从环境变量 "+statsTokenEnvVar+" 读取")
+	statusAddr := fs.String("status-addr", "", "整理期间在这个地址提供只读的状态页面和 /status.json，例如 :8383，密码从环境变量 "+statusTokenEnvVar+" 读取")
+	takeout := fs.Bool("takeout", false, "整理 Google 相册导出：JSON 元数据随照片移动，内容相同的「(n)」副本只保留一份")
+	mergeCopies := fs.String("merge-copies", CopyMergeOff, "内容相同的副本: off、quarantine 或 delete")
+	checksum := fs.String("checksum", ChecksumNone, "导出校验清单的算法: none、md5、sha1 或 sha256")
+	snapshot := fs.String("snapshot", "", "整理前把源文件的路径和哈希追加到这个文件")
+	emptyFiles := fs.String("empty-files", EmptyFileOrganize, "空文件: organize、skip 或 quarantine")
+	verbosity := fs.String("verbosity", LogNormal, "日志详细程度: quiet（不列出跳过的文件）、normal 或 verbose")
+	shortcutPolicy := fs.String("shortcuts", ShortcutOrganize, "快捷方式: organize、skip 或 resolve（整理指向的文件）")
+	organizeLibraries := fs.Bool("organize-libraries", false, "也整理照片、音乐等程序的图库（.photoslibrary 等）")
+	textLanguage := fs.Bool("text-language", false, "文本文件（.txt/.md/.pdf/.docx）按识别出的语言再分一层")
+	textKeywords := fs.String("text-keywords", "", "文本文件按关键词再分一层，先于语言，第一条匹配的规则生效，例如 \"发票=财务;contract=合同\"")
+	textCaseSensitive := fs.Bool("text-case-sensitive", false, "文本关键词区分大小写")
+	textPrefixKB := fs.Int("text-prefix-kb", defaultTextPrefixKB, "按内容整理时读取文件开头的KB数")
+	onConflict := fs.String("on-conflict", ConflictRename, "目标中已有同名文件: rename、overwrite 或 merge（内容相同时跳过）")
+	backupReplaced := fs.Bool("backup-replaced", true, "覆盖前把已有文件移到目标的 "+ReplacedFolderName+" 文件夹")
+	parallelThreshold := fs.Int("parallel-threshold", defaultParallelThreshold, "文件数少于该值时使用较少的工作协程")
+	smallSetWorkers := fs.Int("small-set-workers", defaultSmallSetWorkers, "少量文件时的工作协程数")
+	breakStaleLock := fs.Bool("break-stale-lock", false, "目标文件夹的锁已失效（持有者超过 "+targetLockStaleAfter.String()+" 没有刷新）时解除后继续")
+	streamingThreshold := fs.Int("streaming-threshold", defaultStreamingThreshold, "扫描到的文件超过该数量时改用流式整理，边扫描边整理，不保留文件列表，0不使用")
+	failOnSkip := fs.Bool("fail-on-skip", false, "有文件被跳过（重复、固定、空文件等）时以退出码2结束")
+	profile := fs.String("profile", "", "统计中记录的配置方案名称，与同一方案的上一次整理比较用时，默认为「"+headlessProfileName+"」")
+	minSuccessRate := fs.Float64("min-success-rate", 1, "成功整理的文件占比低于该值（0到1）时以退出码2结束")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("无法识别的参数: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("至少需要一个 -source")
+	}
+	config := Config{
+		FolderDateFormat:     *dateFormat,
+		OrganizeRule:         *rule,
+		ExtensionCase:        *extCase,
+		MultiTagMode:         *multiTag,
+		DateFolderMtime:      *dateFolderMtime,
+		PackSmallFiles:       *packSmallKB > 0,
+		PackThresholdKB:      *packSmallKB,
+		FolderLayout:         *layout,
+		DedupTarget:          *dedup,
+		ParallelThreshold:    *parallelThreshold,
+		SmallSetWorkers:      *smallSetWorkers,
+		AgeBucketLabels:      append([]string(nil), defaultAgeBucketLabels...),
+		SizeBucketsMB:        append([]int(nil), defaultSizeBucketsMB...),
+		DateSources:          append([]DateSource(nil), defaultDateSources...),
+		EmptyFilePolicy:      *emptyFiles,
+		ShortcutPolicy:       *shortcutPolicy,
+		OrganizeLibraries:    *organizeLibraries,
+		TextLanguageFolders:  *textLanguage,
+		TextKeywordCase:      *textCaseSensitive,
+		TextPrefixKB:         *textPrefixKB,
+		ConflictPolicy:       *onConflict,
+		BackupReplaced:       *backupReplaced,
+		DedupEmptyFiles:      *dedupEmpty,
+		ChecksumAlgorithm:    *checksum,
+		SourceSnapshot:       *snapshot,
+		RemoveEmptiedDirs:    *removeEmptyDirs,
+		MoveEmptyDirs:        *moveEmptyDirs,
+		CopyMerge:            *mergeCopies,
+		TakeoutMode:          *takeout,
+		LogVerbosity:         *verbosity,
+		CopySuffixPatterns:   append([]string(nil), defaultCopySuffixPatterns...),
+		StatsEndpoint:        *statsEndpoint,
+		PostRunHook:          *postRunHook,
+		PerFileHook:          *perFileHook,
+		HookTimeoutSec:       *hookTimeout,
+		StatsToken:           os.Getenv(statsTokenEnvVar),
+		CatalogEnabled:       *catalog,
+		HashRename:           *hashRename,
+		EmailSenderFolders:   *emailSender,
+		FolderTemplate:       *folderTemplate,
+		CompactExtensionsMin: *compactMin,
+		VolumeCapMB:          *volumeCapMB,
+		KeepConverted:        !*discardOriginals,
+		LowPriority:          *lowPriority,
+		IncomingOnly:         *incomingOnly,
+		Profile:              strings.TrimSpace(*profile),
+	}
+	if config.Profile == "" {
+		config.Profile = headlessProfileName
+	}
+	for _, dir := range sources {
+		config.SourceDirs = append(config.SourceDirs, filepath.Clean(dir))
+	}
+	for _, dir := range readOnlySources {
+		config.ReadOnlySources = append(config.ReadOnlySources, filepath.Clean(dir))
+	}
+	config.TargetDir = config.SourceDirs[0]
+	if strings.TrimSpace(*target) != "" {
+		config.TargetDir = filepath.Clean(strings.TrimSpace(*target))
+	}
+	config.SourceDir = config.TargetDir
+
+	config.FileExtensions = parseExtensionList(*extensions)
+	if len(config.FileExtensions) == 0 {
+		return nil, errors.New("需要用 -ext 指定整理的文件后缀")
+	}
+	if err := checkChoice("rule", *rule, string(RuleByDate), string(RuleByExtension), string(RuleByTag),
+		string(RuleByAge), string(RuleByHash), string(RuleByCamera), string(RuleByTypeSize), string(RuleComposite)); err != nil {
+		return nil, err
+	}
+	if err := validateTagRule(Config{OrganizeRule: *rule}); err != nil {
+		return nil, fmt.Errorf("-rule: %w", err)
+	}
+	if err := checkChoice("date-format", *dateFormat, folderDateFormats...); err != nil {
+		return nil, err
+	}
+	if err := checkChoice("ext-case", *extCase, extensionCases...); err != nil {
+		return nil, err
+	}
+	if err := validateFolderTemplate(*folderTemplate); err != nil {
+		return nil, fmt.Errorf("-folder-template: %w", err)
+	}
+	if err := checkChoice("layout", *layout, LayoutFlat, LayoutRuleFirst, LayoutSourceFirst, LayoutNamePrefix); err != nil {
+		return nil, err
+	}
+	if err := checkChoice("multi-tag", *multiTag, MultiTagFirst, MultiTagDuplicate); err != nil {
+		return nil, err
+	}
+	if err := checkChoice("empty-files", *emptyFiles, EmptyFileOrganize, EmptyFileSkip, EmptyFileQuarantine); err != nil {
+		return nil, err
+	}
+	if err := validateHookCommand(*postRunHook, runHookTokens); err != nil {
+		return nil, fmt.Errorf("-post-run-hook: %w", err)
+	}
+	if err := validateHookCommand(*perFileHook, fileHookTokens); err != nil {
+		return nil, fmt.Errorf("-per-file-hook: %w", err)
+	}
+	if *hookTimeout <= 0 {
+		return nil, errors.New("-hook-timeout 必须大于0")
+	}
+	if err := checkChoice("verbosity", *verbosity, LogQuiet, LogNormal, LogVerbose); err != nil {
+		return nil, err
+	}
+	if err := checkChoice("merge-copies", *mergeCopies, CopyMergeOff, CopyMergeQuarantine, CopyMergeDelete); err != nil {
+		return nil, err
+	}
+	if err := checkChoice("checksum", *checksum, ChecksumNone, ChecksumMD5, ChecksumSHA1, ChecksumSHA256); err != nil {
+		return nil, err
+	}
+	if err := checkChoice("shortcuts", *shortcutPolicy, ShortcutOrganize, ShortcutSkip, ShortcutResolve); err != nil {
+		return nil, err
+	}
+	if err := checkChoice("on-conflict", *onConflict, ConflictRename, ConflictOverwrite, ConflictMerge); err != nil {
+		return nil, err
+	}
+	if *parallelThreshold < 0 || *smallSetWorkers < 1 || *compactMin < 0 || *volumeCapMB < 0 || *targetMinFree < 0 {
+		return nil, errors.New("数值参数不能为负数，-small-set-workers 至少为1")
+	}
+	if *minSuccessRate < 0 || *minSuccessRate > 1 {
+		return nil, fmt.Errorf("-min-success-rate 应在0到1之间: %v", *minSuccessRate)
+	}
+
+	depth, width, err := parsePair("hash-shards", *hashShards)
+	if err != nil {
+		return nil, err
+	}
+	config.HashShardDepth, config.HashShardWidth = depth, width
+	if len(spillTargets) > 0 {
+		config.Volumes = []TargetVolume{{Root: config.TargetDir, MinFreeMB: *targetMinFree}}
+		for _, spill := range spillTargets {
+			root, mb, _ := strings.Cut(spill, "=")
+			minFree, err := strconv.ParseInt(strings.TrimSpace(mb), 10, 64)
+			if strings.TrimSpace(root) == "" || err != nil || minFree < 0 {
+				return nil, fmt.Errorf("-spill-target 的格式为 路径=保留MB: %q", spill)
+			}
+			config.Volumes = append(config.Volumes, TargetVolume{Root: filepath.Clean(strings.TrimSpace(root)), MinFreeMB: minFree})
+		}
+	}
+	if *extensionRanks != "" {
+		if config.ExtensionRanks, err = parseExtensionRanks(*extensionRanks); err != nil {
+			return nil, err
+		}
+	}
+	if *ruleSegments != "" {
+		if config.RuleSegments, err = parseRuleSegments(*ruleSegments); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateRuleSegmentSeparator(*segmentSeparator); err != nil {
+		return nil, fmt.Errorf("-segment-separator: %w", err)
+	}
+	config.RuleSegmentSeparator = *segmentSeparator
+	if config.TextKeywordRules, err = parseTextKeywordRules(*textKeywords); err != nil {
+		return nil, fmt.Errorf("-text-keywords: %w", err)
+	}
+	if *textPrefixKB < 1 || *textPrefixKB > maxTextPrefixKB {
+		return nil, fmt.Errorf("-text-prefix-kb 应在1到%d之间: %d", maxTextPrefixKB, *textPrefixKB)
+	}
+	if err := validateStatusAddr(*statusAddr); err != nil {
+		return nil, fmt.Errorf("-status-addr: %w", err)
+	}
+	if *streamingThreshold < 0 {
+		return nil, fmt.Errorf("-streaming-threshold 不能为负数: %d", *streamingThreshold)
+	}
+	if *packSmallKB < 0 {
+		return nil, fmt.Errorf("-pack-small-kb 不能为负数: %d", *packSmallKB)
+	}
+	if *ageLabels != "" {
+		labels := strings.Split(*ageLabels, ",")
+		if len(labels) != ageBucketCount {
+			return nil, fmt.Errorf("-age-labels 需要 %d 个名称", ageBucketCount)
+		}
+		for i := range labels {
+			labels[i] = sanitizeFolderName(strings.TrimSpace(labels[i]))
+		}
+		config.AgeBucketLabels = labels
+	}
+	if *sizeBuckets != "" {
+		if config.SizeBucketsMB, err = parseSizeBuckets(*sizeBuckets); err != nil {
+			return nil, fmt.Errorf("-size-buckets: %w", err)
+		}
+	}
+	if config.Location, err = loadDateLocation(*timeZone); err != nil {
+		return nil, err
+	}
+	if *dateSources != "" {
+		if config.DateSources, err = parseDateSources(*dateSources); err != nil {
+			return nil, err
+		}
+	}
+	options := &headlessOptions{
+		config:         config,
+		compactMin:     *compactMin,
+		convertImages:  *convertHEIC,
+		failOnSkip:     *failOnSkip,
+		breakStaleLock: *breakStaleLock,
+		streamingLimit: *streamingThreshold,
+		minSuccessRate: *minSuccessRate,
+		statusAddr:     *statusAddr,
+		statusToken:    os.Getenv(statusTokenEnvVar),
+	}
+	if *bursts != "" {
+		gap, frames, err := parsePair("bursts", *bursts)
+		if err != nil {
+			return nil, err
+		}
+		options.bursts = true
+		options.config.BurstMaxGap, options.config.BurstMaxFrames = gap, frames
+	}
+	return options, nil
+}
+
+// HeadlessSummary 无界面运行结束时输出到标准输出的总结JSON
+type HeadlessSummary struct {
+	Outcome     string    `json:"outcome"`
+	ExitCode    int       `json:"exit_code"`
+	Error       string    `json:"error,omitempty"`
+	Checked     int       `json:"checked"`
+	Matched     int       `json:"matched"` // 符合所选后缀的文件数
+	Moved       int       `json:"moved"`
+	Copied      int       `json:"copied"`
+	Refiled     int       `json:"refiled"`
+	InPlace     int       `json:"in_place"`
+	Duplicates  int       `json:"duplicates"`
+	Skipped     int       `json:"skipped"` // 符合后缀但没有整理的文件，包括重复、固定、空文件等
+	Failed      int       `json:"failed"`
+	Aborted     int       `json:"aborted"`
+	SuccessRate float64   `json:"success_rate"`
+	Stats       *RunStats `json:"stats,omitempty"`
+}
+
+// 根据整理结果和阈值确定结果和退出码。失败占比在 -min-success-rate 允许的范围内时
+// 结果仍记为有失败，但退出码为0
+func summarizeHeadless(summary processSummary, runErr error, failOnSkip bool, minSuccessRate float64) HeadlessSummary {
+	result := HeadlessSummary{
+		Checked:    summary.Checked,
+		Matched:    summary.Checked - summary.Unmatched,
+		Moved:      summary.Moved,
+		Copied:     summary.Copied,
+		Refiled:    summary.Refiled,
+		InPlace:    summary.InPlace,
+		Duplicates: summary.Duplicates,
+		Failed:     summary.Failed,
+		Aborted:    summary.Aborted,
+	}
+	succeeded := summary.Moved + summary.Copied + summary.Refiled + summary.InPlace
+	result.Skipped = result.Matched - succeeded - summary.Failed - summary.Aborted
+	if result.Skipped < 0 {
+		result.Skipped = 0
+	}
+	result.SuccessRate = 1
+	if succeeded+summary.Failed > 0 {
+		result.SuccessRate = float64(succeeded) / float64(succeeded+summary.Failed)
+	}
+	if !summary.Stats.StartedAt.IsZero() {
+		stats := summary.Stats
+		result.Stats = &stats
+	}
+
+	switch {
+	case runErr != nil:
+		result.Outcome, result.ExitCode, result.Error = OutcomeAborted, exitFatal, runErr.Error()
+	case summary.Aborted > 0:
+		result.Outcome, result.ExitCode = OutcomeAborted, exitFatal
+	case result.Matched == 0:
+		result.Outcome, result.ExitCode = OutcomeNothingMatched, exitNothingMatched
+	case result.SuccessRate < minSuccessRate:
+		result.Outcome, result.ExitCode = OutcomeFailures, exitFailures
+	case result.Skipped > 0 && failOnSkip:
+		result.Outcome, result.ExitCode = OutcomeSkips, exitFailures
+	case summary.Failed > 0:
+		result.Outcome, result.ExitCode = OutcomeFailures, exitSuccess
+	case result.Skipped > 0:
+		result.Outcome, result.ExitCode = OutcomeSkips, exitSuccess
+	default:
+		result.Outcome, result.ExitCode = OutcomeSuccess, exitSuccess
+	}
+	return result
+}
+
+// 输出总结JSON
+func writeHeadlessSummary(w io.Writer, result HeadlessSummary) {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "输出总结失败: %v\n", err)
+		return
+	}
+	fmt.Fprintln(w, string(data))
+}
+
+// 无界面运行：按命令行参数整理一次，日志输出到标准错误，总结JSON输出到标准输出，返回退出码
+func runHeadless(args []string, stdout, stderr io.Writer) int {
+	options, err := parseHeadlessArgs(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		writeHeadlessSummary(stdout, HeadlessSummary{Outcome: OutcomeAborted, ExitCode: exitFatal, Error: err.Error(), SuccessRate: 1})
+		return exitFatal
+	}
+	config := options.config
+
+	// 已有无界面整理在运行时不整理，避免两个定时任务同时移动同一批文件。打开的窗口不影响无界面整理
+	lock, err := acquireInstanceLock(true)
+	var running *instanceRunningError
+	if errors.As(err, &running) {
+		fmt.Fprintln(stderr, running.Error())
+		writeHeadlessSummary(stdout, HeadlessSummary{Outcome: OutcomeAborted, ExitCode: exitFatal, Error: running.Error(), SuccessRate: 1})
+		return exitFatal
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "警告: %v，不检查是否已有程序在运行\n", err)
+	}
+	if lock != nil {
+		defer func() {
+			if err := lock.release(); err != nil {
+				fmt.Fprintln(stderr, err)
+			}
+		}()
+	}
+
+	fo := NewFileOrganizer(true)
+	fo.logFaults()
+	// 界面中固定的文件在无界面运行时同样跳过
+	fo.loadPins(nil)
+	config.Pins = fo.pins
+	// 状态页面只在这次整理期间提供，整理结束后停止
+	if options.statusAddr != "" {
+		if addr, err := fo.status.serve(options.statusAddr, options.statusToken); err != nil {
+			fo.log("警告: " + err.Error())
+		} else {
+			fo.log("状态页面: " + statusPageURL(addr))
+		}
+	}
+	var summary processSummary
+	runErr := validateTargetDir(config.TargetDir)
+	if runErr == nil {
+		runErr = validateReadOnlySources(config)
+	}
+	if runErr == nil {
+		runErr = validateLayoutOverlap(config)
+	}
+	if runErr == nil && options.breakStaleLock {
+		runErr = fo.breakStaleTargetLock(config.TargetDir)
+	}
+	if runErr == nil {
+		if options.convertImages {
+			config.ConvertImages = fo.imageConverter != nil
+			if !config.ConvertImages {
+				fo.log("警告: 没有可用的图片转换程序，按原样整理")
+			}
+		}
+		fo.ui.ScanStarted()
+		files, streaming := fo.collectFilesUpTo(config.SourceDirs, options.streamingLimit)
+		fo.ui.ScanFinished(OrganizeRule(config.OrganizeRule))
+		if streaming {
+			fo.log(fmt.Sprintf("扫描到的文件超过 %d 个，改用流式整理：不保留文件列表，边扫描边整理", options.streamingLimit))
+			if options.compactMin > 0 || options.bursts {
+				fo.log("流式整理时不合并文件少的后缀，也不识别连拍")
+			}
+			fo.lastCLICommand = cliCommandFor(config.SourceDirs, config)
+			summary, runErr = fo.processStreaming(config, config.SourceDirs, options.streamingLimit)
+		} else {
+			summary, runErr = fo.runHeadlessFiles(options, config, files)
+		}
+	}
+	if runErr != nil {
+		fo.log("整理出错: " + runErr.Error())
+		var locked *targetLockedError
+		if errors.As(runErr, &locked) && locked.stale {
+			fo.log("如果确定没有其他电脑正在整理，可以加上 -break-stale-lock 解除失效的锁")
+		}
+	}
+	// 统计在后台发送，进程退出前等待发送完成（最多两次请求的超时）
+	fo.statsPosts.Wait()
+	fo.status.stopServing()
+	// 等日志全部输出后再输出总结，避免与日志交错
+	fo.stopLogProcessor()
+
+	result := summarizeHeadless(summary, runErr, options.failOnSkip, options.minSuccessRate)
+	writeHeadlessSummary(stdout, result)
+	return result.ExitCode
+}
+
+// 整理扫描到的文件列表：按需要合并文件少的后缀、识别连拍，然后整理
+func (fo *FileOrganizer) runHeadlessFiles(options *headlessOptions, config Config, files []string) (processSummary, error) {
+	fo.log(fmt.Sprintf("扫描完成，共发现 %d 个文件", len(files)))
+	if OrganizeRule(config.OrganizeRule) == RuleByExtension && options.compactMin > 0 {
+		counts := make(map[string]int)
+		for _, path := range files {
+			if ext := strings.ToLower(fileExtension(path)); ext != "" {
+				counts[ext]++
+			}
+		}
+		config.RareExtensions = rareExtensions(counts, options.compactMin)
+	}
+	if options.bursts {
+		infos := make(map[string]os.FileInfo, len(files))
+		for _, path := range files {
+			if info, err := os.Stat(path); err == nil {
+				infos[path] = info
+			}
+		}
+		sources := config.DateSources
+		config.Bursts = detectBursts(files, infos, func(path string, info os.FileInfo) (time.Time, DateSource) {
+			return fo.dates.Resolve(path, info, sources)
+		}, time.Duration(config.BurstMaxGap)*time.Second, config.BurstMaxFrames)
+	}
+	fo.lastCLICommand = cliCommandFor(config.SourceDirs, config)
+	return fo.processFiles(config, files)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 无界面整理的退出码、总结JSON和整理结果；参数错误时不移动任何文件
+func TestRunHeadless(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		faults      string
+		wantExit    int
+		wantOutcome string
+		wantMoved   bool   // a.jpg 移到了目标中
+		wantError   string // 总结中的错误应包含的内容
+		wantProfile string // 统计中记录的配置方案，为空时不检查
+	}{
+		{"整理完成", []string{"-ext", "JPG"}, "", exitSuccess, OutcomeSuccess, true, "", headlessProfileName},
+		{"指定配置方案", []string{"-ext", "jpg", "-profile", "照片"}, "", exitSuccess, OutcomeSuccess, true, "", "照片"},
+		{"按日期整理", []string{"-ext", "jpg", "-rule", "date", "-date-format", "YYYYMM"}, "", exitSuccess, OutcomeSuccess, true, "", ""},
+		{"没有符合后缀的文件", []string{"-ext", "png"}, "", exitNothingMatched, OutcomeNothingMatched, false, "", ""},
+		{"有文件跳过并要求视为失败", []string{"-ext", "jpg,txt", "-fail-on-skip", "-empty-files", "skip"}, "", exitFailures, OutcomeSkips, true, "", ""},
+		{"失败超过允许的比例", []string{"-ext", "jpg"}, "exdev=a.jpg,copy-fail-after=0", exitFailures, OutcomeFailures, false, "", ""},
+		{"缺少后缀", nil, "", exitFatal, OutcomeAborted, false, "-ext", ""},
+		{"无效的日期格式", []string{"-ext", "jpg", "-date-format", "YYYY/MM"}, "", exitFatal, OutcomeAborted, false, "-date-format", ""},
+		{"无效的后缀大小写", []string{"-ext", "jpg", "-ext-case", "upper"}, "", exitFatal, OutcomeAborted, false, "-ext-case", ""},
+		{"按文件夹模板整理", []string{"-ext", "jpg", "-folder-template", "{category}/{ext}"}, "", exitSuccess, OutcomeSuccess, true, "", ""},
+		{"无效的文件夹模板", []string{"-ext", "jpg", "-folder-template", "{nope}"}, "", exitFatal, OutcomeAborted, false, "-folder-template", ""},
+		{"文件夹模板跳出目标", []string{"-ext", "jpg", "-folder-template", "../{ext}"}, "", exitFatal, OutcomeAborted, false, "-folder-template", ""},
+		{"无效的规则", []string{"-ext", "jpg", "-rule", "color"}, "", exitFatal, OutcomeAborted, false, "-rule", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestEnv(t)
+			if tt.faults != "" {
+				useFaults(t, tt.faults)
+			}
+			source := t.TempDir()
+			target := t.TempDir()
+			photo := writeTestFile(t, filepath.Join(source, "a.jpg"), "photo")
+			writeTestFile(t, filepath.Join(source, "empty.txt"), "")
+
+			var stdout bytes.Buffer
+			args := append([]string{"-headless", "-source", source, "-target", target}, tt.args...)
+			exit := runHeadless(args, &stdout, io.Discard)
+
+			var summary HeadlessSummary
+			if err := json.Unmarshal(stdout.Bytes(), &summary); err != nil {
+				t.Fatalf("总结JSON: %v\n%s", err, stdout.String())
+			}
+			if exit != tt.wantExit || summary.ExitCode != exit || summary.Outcome != tt.wantOutcome {
+				t.Fatalf("退出码 = %d, 总结 = %+v, 期望 %d %s", exit, summary, tt.wantExit, tt.wantOutcome)
+			}
+			if !strings.Contains(summary.Error, tt.wantError) {
+				t.Fatalf("错误 = %q, 应包含 %q", summary.Error, tt.wantError)
+			}
+			if tt.wantProfile != "" && (summary.Stats == nil || summary.Stats.Profile != tt.wantProfile) {
+				t.Fatalf("统计 = %+v, 配置方案应为 %q", summary.Stats, tt.wantProfile)
+			}
+			if _, err := os.Stat(photo); (err != nil) != tt.wantMoved {
+				t.Fatalf("a.jpg 移走 = %v, 期望 %v", err != nil, tt.wantMoved)
+			}
+		})
+	}
+}
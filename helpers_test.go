@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+// 测试使用 fyne 的测试应用，主目录和配置文件夹都指向临时文件夹，不影响真实的设置和数据
+func newTestEnv(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("APPDATA", filepath.Join(home, "AppData"))
+	test.NewApp()
+	return home
+}
+
+// 创建一个无界面的整理器，测试结束时停止日志处理
+func newTestOrganizer(t *testing.T) *FileOrganizer {
+	t.Helper()
+	newTestEnv(t)
+	fo := NewFileOrganizer(true)
+	t.Cleanup(fo.stopLogProcessor)
+	return fo
+}
+
+// 测试期间按 spec 注入文件操作失败，格式与环境变量 FILE_ORGANIZER_FAULTS 相同，测试结束时恢复
+func useFaults(t *testing.T, spec string) {
+	t.Helper()
+	injected, err := parseFaults(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := faults
+	faults = injected
+	t.Cleanup(func() { faults = saved })
+}
+
+// 写入测试文件，需要时创建所在的文件夹
+func writeTestFile(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 读取测试文件的内容，不存在时测试失败
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
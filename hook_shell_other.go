@@ -0,0 +1,25 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+)
+
+// 通过 /bin/sh 运行命令
+func hookShellCommand(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "/bin/sh", "-c", command)
+}
+
+// 命令中对环境变量的引用。在双引号外时加上双引号，值中的空格不会拆分参数；在单引号中时先结束单引号
+func hookVarRef(name string, inSingle, inDouble bool) string {
+	ref := "${" + name + "}"
+	switch {
+	case inSingle:
+		return `'"` + ref + `"'`
+	case inDouble:
+		return ref
+	}
+	return `"` + ref + `"`
+}
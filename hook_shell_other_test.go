@@ -0,0 +1,43 @@
+//go:build !windows
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// 通过 /bin/sh 运行时文件名中的特殊字符原样传给命令，无论变量在引号外、双引号中还是单引号中
+func TestRunHookCommandSpecialNames(t *testing.T) {
+	names := []string{
+		`say "hi".jpg`,
+		`%PATH%.jpg`,
+		`a & b; echo c.jpg`,
+		`it's.jpg`,
+		"$(echo x) `id` ${HOME}.jpg",
+		`a  b\n*.jpg`,
+	}
+	templates := []struct {
+		template string
+		prefix   string
+		suffix   string
+	}{
+		{"printf '%s' {path}", "", ""},
+		{`printf '%s' "{path}"`, "", ""},
+		{`printf '%s' '{path}'`, "", ""},
+		{`printf '%s' "[{path}]"`, "[", "]"},
+		{`printf '%s' x{path}y`, "x", "y"},
+		{`printf '%s' \"{path}`, `"`, ""},
+	}
+	for _, name := range names {
+		for _, tt := range templates {
+			t.Run(name+"/"+tt.template, func(t *testing.T) {
+				command, env := expandHookCommand(tt.template, map[string]string{"{path}": "/out/" + name})
+				output, err := runHookCommand(command, env, 10*time.Second)
+				if want := tt.prefix + "/out/" + name + tt.suffix; err != nil || output != want {
+					t.Fatalf("输出 = %q, %v, 期望 %q（命令 %s）", output, err, want, command)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// 通过 cmd 运行命令。cmd 不按 Go 的规则解析转义的引号，整条命令原样传入；
+// /V:ON 开启延迟展开，变量的值在解析命令之后才替换
+func hookShellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "cmd")
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd /V:ON /S /C "` + command + `"`}
+	return cmd
+}
+
+// 命令中对环境变量的引用。使用延迟展开的 !名称!，值中的 & | < > % 等字符不会被当作命令，
+// 也不会再次展开；在双引号外时加上双引号，值中的空格不会拆分参数
+func hookVarRef(name string, _, inDouble bool) string {
+	if inDouble {
+		return "!" + name + "!"
+	}
+	return `"!` + name + `!"`
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 通过 cmd 运行时文件名中的 % & ' ! 等字符原样传给命令，变量在引号外和双引号中都可以
+func TestRunHookCommandSpecialNames(t *testing.T) {
+	names := []string{`%PATH%.txt`, `a & b.txt`, `it's.txt`, `!x! ^y.txt`, `(a) b.txt`}
+	templates := []string{
+		`if exist {path} (echo yes) else (echo no)`,
+		`if exist "{path}" (echo yes) else (echo no)`,
+	}
+	for _, name := range names {
+		path := writeTestFile(t, filepath.Join(t.TempDir(), name), "x")
+		for _, template := range templates {
+			t.Run(name+"/"+template, func(t *testing.T) {
+				command, env := expandHookCommand(template, map[string]string{"{path}": path})
+				if output, err := runHookCommand(command, env, 10*time.Second); err != nil || output != "yes" {
+					t.Fatalf("输出 = %q, %v（命令 %s）", output, err, command)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 转换后保留的原始文件放在目标文件夹下的这个文件夹中，保持与目标相同的子文件夹结构
+const ConvertedOriginalsFolderName = "_originals"
+
+// 单个文件转换的最长时间
+const imageConvertTimeout = 2 * time.Minute
+
+// 整理时可以转换的格式：源后缀 → 转换后的后缀
+var convertibleImageExtensions = map[string]string{
+	".heic": ".jpg",
+	".heif": ".jpg",
+}
+
+// imageConverter 转换HEIC用的外部程序。Go标准库和现有依赖都不能解码HEIC，只能调用外部程序
+type imageConverter struct {
+	name string
+	path string
+	args func(src, dst string) []string
+}
+
+// 查找可用的HEIC转换程序，按 heif-convert（libheif）、ImageMagick、sips（macOS）的顺序，
+// 都不存在时返回nil。这些程序都会保留EXIF，ImageMagick额外按EXIF方向旋转并重置方向标记
+func detectImageConverter() *imageConverter {
+	candidates := []imageConverter{
+		{name: "heif-convert", args: func(src, dst string) []string { return []string{"-q", "92", src, dst} }},
+		{name: "magick", args: func(src, dst string) []string { return []string{src, "-auto-orient", "-quality", "92", dst} }},
+	}
+	// Windows 自带的 convert.exe 是磁盘转换工具，不是 ImageMagick
+	if runtime.GOOS != "windows" {
+		candidates = append(candidates, imageConverter{name: "convert", args: func(src, dst string) []string {
+			return []string{src, "-auto-orient", "-quality", "92", dst}
+		}})
+	}
+	if runtime.GOOS == "darwin" {
+		candidates = append(candidates, imageConverter{name: "sips", args: func(src, dst string) []string {
+			return []string{"-s", "format", "jpeg", "-s", "formatOptions", "92", src, "--out", dst}
+		}})
+	}
+	for _, candidate := range candidates {
+		if path, err := exec.LookPath(candidate.name); err == nil {
+			converter := candidate
+			converter.path = path
+			return &converter
+		}
+	}
+	return nil
+}
+
+// 文件按当前设置需要转换时返回转换后的后缀
+func convertedExtension(filePath string) string {
+	return convertibleImageExtensions[strings.ToLower(fileExtension(filePath))]
+}
+
+// 将图片转换后放入目标文件夹，返回转换后的文件路径。源文件保持不变，由调用方决定删除或保留。
+// 转换后的文件使用源文件的修改时间，转换失败时删除不完整的输出
+func (fo *FileOrganizer) convertImage(sourcePath, targetDir string) (string, error) {
+	if err := fo.checkWritable(); err != nil {
+		return "", err
+	}
+	converter := fo.imageConverter
+	if converter == nil {
+		return "", errors.New("没有可用的图片转换程序")
+	}
+	sourceInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("获取源文件信息失败: %w", err)
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return "", fmt.Errorf("创建目标目录失败: %w", err)
+	}
+
+	stem, _ := splitExtension(filepath.Base(sourcePath))
+	targetPath := fo.uniqueTargetPath(targetDir, stem+convertedExtension(sourcePath))
+	ctx, cancel := context.WithTimeout(context.Background(), imageConvertTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, converter.path, converter.args(sourcePath, targetPath)...).CombinedOutput()
+	if err == nil {
+		err = checkConvertedImage(targetPath)
+	}
+	if err != nil {
+		os.Remove(targetPath)
+		if detail := strings.TrimSpace(string(output)); detail != "" {
+			return "", fmt.Errorf("%s 转换失败: %w（%s）", converter.name, err, detail)
+		}
+		return "", fmt.Errorf("%s 转换失败: %w", converter.name, err)
+	}
+
+	// 按修改时间整理的文件转换后仍然归入同一天
+	if err := os.Chtimes(targetPath, time.Now(), sourceInfo.ModTime()); err != nil {
+		fo.log(fmt.Sprintf("警告: 设置转换后文件的修改时间失败 %s: %v", targetPath, err))
+	}
+	return targetPath, nil
+}
+
+// 检查转换程序是否生成了JPEG文件
+func checkConvertedImage(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("没有生成转换后的文件: %w", err)
+	}
+	defer file.Close()
+	header := make([]byte, 3)
+	if _, err := file.Read(header); err != nil || !bytes.Equal(header, []byte{0xFF, 0xD8, 0xFF}) {
+		return errors.New("转换后的文件不是有效的JPEG")
+	}
+	return nil
+}
+
+// 转换成功且不保留原始文件时删除源文件。只读模式在整理期间开启时不删除
+func (fo *FileOrganizer) removeConvertedSource(filePath string) error {
+	if err := fo.checkWritable(); err != nil {
+		return err
+	}
+	return os.Remove(filePath)
+}
+
+// 是否为转换前保留的原始文件：位于目标根目录的 _originals 中，或者目标不在根目录下时同一文件夹的 _originals 中
+func isConvertedOriginal(filePath, targetRoot string) bool {
+	dir := filepath.Dir(filePath)
+	return filepath.Base(dir) == ConvertedOriginalsFolderName ||
+		isWithinAny(dir, []string{filepath.Join(targetRoot, ConvertedOriginalsFolderName)})
+}
+
+// 转换后保留原始文件的文件夹，与转换后的文件在目标中的位置对应
+func convertedOriginalsDir(targetRoot, targetDir string) string {
+	rel, err := filepath.Rel(targetRoot, targetDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Join(targetDir, ConvertedOriginalsFolderName)
+	}
+	return filepath.Join(targetRoot, ConvertedOriginalsFolderName, rel)
+}
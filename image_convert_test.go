@@ -0,0 +1,47 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsConvertedOriginal(t *testing.T) {
+	root := filepath.Join("target")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(root, "_originals", "a.heic"), true},
+		{filepath.Join(root, "_originals", "2024", "01", "a.heic"), true},
+		{filepath.Join("spill", "2024", "_originals", "a.heic"), true},
+		{filepath.Join(root, "2024", "a.heic"), false},
+		{filepath.Join(root, "_originals_old", "a.heic"), false},
+	}
+	for _, tt := range tests {
+		if got := isConvertedOriginal(tt.path, root); got != tt.want {
+			t.Errorf("isConvertedOriginal(%q) = %v, 期望 %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// 再次整理目标文件夹时，_originals 中保留的原始文件不会被重新归档
+func TestConvertedOriginalsNotRefiled(t *testing.T) {
+	fo := newTestOrganizer(t)
+	target := t.TempDir()
+	original := writeTestFile(t, filepath.Join(target, ConvertedOriginalsFolderName, ".heic", "a.heic"), "heic")
+	config := Config{
+		SourceDir:      target,
+		SourceDirs:     []string{target},
+		TargetDir:      target,
+		FileExtensions: []string{".heic"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		ExcludedFiles:  map[string]bool{},
+	}
+	if _, err := fo.processFiles(config, []string{original}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, original); got != "heic" {
+		t.Fatalf("原始文件 = %q", got)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 每个源文件夹最多记录的文件数，超过时丢弃最早记录的文件，这些文件下次会按新增文件整理
+const maxIncomingEntries = 200000
+
+// incomingEntry 上次成功整理后源文件夹中已有的一个文件
+type incomingEntry struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mtime"` // 修改时间（纳秒）
+	Seen    int64 `json:"seen"`  // 第一次记录的时间（Unix秒），超过上限时先丢弃最早的
+}
+
+// incomingStore 仅处理新增模式的记录：每个源文件夹中上次成功整理后已有的文件，键为相对路径。
+// 记录的是文件本身而不是时间，有人放入修改时间很早的旧文件时仍按新增文件整理
+type incomingStore struct {
+	Roots map[string]map[string]incomingEntry `json:"roots"`
+}
+
+// 读写记录文件时加锁，定时整理和任务队列可能同时整理不同的源文件夹
+var incomingStoreMu sync.Mutex
+
+// 记录文件路径
+func incomingStorePath() string {
+	return filepath.Join(appDataDir(), "incoming_fingerprints.json")
+}
+
+// 加载记录，没有记录文件时返回空记录
+func loadIncomingStore() (*incomingStore, error) {
+	store := &incomingStore{Roots: make(map[string]map[string]incomingEntry)}
+	data, err := os.ReadFile(incomingStorePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return store, fmt.Errorf("读取新增记录失败: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return &incomingStore{Roots: make(map[string]map[string]incomingEntry)}, fmt.Errorf("解析新增记录失败: %w", err)
+	}
+	if store.Roots == nil {
+		store.Roots = make(map[string]map[string]incomingEntry)
+	}
+	return store, nil
+}
+
+// 保存记录，先写临时文件再改名，避免中途退出时留下不完整的记录
+func (s *incomingStore) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("序列化新增记录失败: %w", err)
+	}
+	if err := os.MkdirAll(appDataDir(), 0755); err != nil {
+		return fmt.Errorf("保存新增记录失败: %w", err)
+	}
+	tmpPath := incomingStorePath() + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("保存新增记录失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, incomingStorePath()); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存新增记录失败: %w", err)
+	}
+	return nil
+}
+
+// 文件所在的源文件夹，嵌套时使用最深的一个。不在任何源文件夹中时返回空
+func incomingRootOf(path string, roots []string) (root, rel string) {
+	for _, r := range roots {
+		if !isWithinAny(path, []string{r}) {
+			continue
+		}
+		if len(r) > len(root) {
+			root = r
+		}
+	}
+	if root == "" {
+		return "", ""
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "", ""
+	}
+	return root, rel
+}
+
+// 文件是否在上次成功整理时已经存在，大小或修改时间变化的文件按新增文件处理
+func (s *incomingStore) known(path string, roots []string) bool {
+	root, rel := incomingRootOf(path, roots)
+	entries := s.Roots[filepath.Clean(root)]
+	if entries == nil {
+		return false
+	}
+	entry, ok := entries[rel]
+	if !ok {
+		return false
+	}
+	info, err := os.Lstat(path)
+	return err == nil && info.Size() == entry.Size && info.ModTime().UnixNano() == entry.ModTime
+}
+
+// 仅处理新增：去掉上次成功整理时已经在源文件夹中的文件。没有记录的源文件夹中的文件全部整理
+func (fo *FileOrganizer) filterIncoming(config Config, files []string) []string {
+	incomingStoreMu.Lock()
+	store, err := loadIncomingStore()
+	incomingStoreMu.Unlock()
+	if err != nil {
+		fo.log(fmt.Sprintf("%v，本次按所有文件都是新增文件整理", err))
+		return files
+	}
+	kept := files[:0:0]
+	skipped := 0
+	for _, filePath := range files {
+		if store.known(filePath, config.SourceDirs) {
+			skipped++
+			continue
+		}
+		kept = append(kept, filePath)
+	}
+	fo.log(fmt.Sprintf("仅处理新增: 跳过了 %d 个上次成功整理时已有的文件，%d 个新增文件待整理", skipped, len(kept)))
+	return kept
+}
+
+// incomingBaseline 整理开始时源文件夹中已有的文件，整理成功后作为下次仅处理新增的记录。
+// 整理到源文件夹之内的文件按新位置记录，下次不会再被当作新增文件；
+// 整理期间才出现的文件不在其中，下次仍按新增文件整理
+type incomingBaseline struct {
+	mu      sync.Mutex
+	roots   []string
+	now     time.Time
+	entries map[string]map[string]incomingEntry
+}
+
+// 建立空的基准，之后用 add 加入整理开始时已有的文件
+func newIncomingBaseline(config Config, now time.Time) *incomingBaseline {
+	b := &incomingBaseline{now: now, entries: make(map[string]map[string]incomingEntry)}
+	for _, root := range config.SourceDirs {
+		root = filepath.Clean(root)
+		b.roots = append(b.roots, root)
+		b.entries[root] = make(map[string]incomingEntry)
+	}
+	return b
+}
+
+// 按整理开始时的状态记录待整理的文件，只记录后缀在所选列表中的文件，以后加入其他后缀时这些文件仍会被整理
+func (fo *FileOrganizer) incomingBaselineOf(config Config, files []string) *incomingBaseline {
+	b := newIncomingBaseline(config, time.Now())
+	for _, path := range files {
+		if !fo.isTargetFile(fileExtension(path), config.FileExtensions) {
+			continue
+		}
+		if info, err := os.Lstat(path); err == nil {
+			b.add(path, info)
+		}
+	}
+	return b
+}
+
+// 记录一个文件，嵌套的源文件夹中的文件记在最深的源文件夹下，不在源文件夹中的文件不记录
+func (b *incomingBaseline) add(path string, info os.FileInfo) {
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		return
+	}
+	root, rel := incomingRootOf(path, b.roots)
+	if root == "" {
+		return
+	}
+	b.mu.Lock()
+	b.entries[root][rel] = incomingEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Seen: b.now.Unix()}
+	b.mu.Unlock()
+}
+
+// 文件整理到了新位置：移动的文件去掉原来的记录，新位置仍在源文件夹之内时按新位置记录
+func (b *incomingBaseline) organized(from, to string, copied bool) {
+	if root, rel := incomingRootOf(from, b.roots); root != "" && !copied {
+		b.mu.Lock()
+		delete(b.entries[root], rel)
+		b.mu.Unlock()
+	}
+	if info, err := os.Lstat(to); err == nil {
+		b.add(to, info)
+	}
+}
+
+// 整理成功后保存基准，作为下次仅处理新增的记录。已不存在的文件不再保留，
+// 每个源文件夹最多记录 maxIncomingEntries 个文件
+func (fo *FileOrganizer) recordIncoming(b *incomingBaseline) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snapshot := b.entries
+
+	incomingStoreMu.Lock()
+	defer incomingStoreMu.Unlock()
+	store, err := loadIncomingStore()
+	if err != nil {
+		fo.log(fmt.Sprintf("%v，重新建立记录", err))
+	}
+	total, trimmed := 0, 0
+	for root, entries := range snapshot {
+		// 之前记录过的文件保留第一次记录的时间
+		for rel, entry := range entries {
+			if old, ok := store.Roots[root][rel]; ok && old.Seen > 0 {
+				entry.Seen = old.Seen
+				entries[rel] = entry
+			}
+		}
+		trimmed += trimIncomingEntries(entries, maxIncomingEntries)
+		store.Roots[root] = entries
+		total += len(entries)
+	}
+	if err := store.save(); err != nil {
+		fo.log(err.Error())
+		return
+	}
+	fo.log(fmt.Sprintf("仅处理新增: 已记录源文件夹中的 %d 个文件，下次整理时跳过", total))
+	if trimmed > 0 {
+		fo.log(fmt.Sprintf("警告: 源文件夹中的文件超过 %d 个，最早记录的 %d 个文件未保留，下次会按新增文件整理", maxIncomingEntries, trimmed))
+	}
+}
+
+// 记录超过上限时删除最早记录的文件，返回删除的数量
+func trimIncomingEntries(entries map[string]incomingEntry, limit int) int {
+	if len(entries) <= limit {
+		return 0
+	}
+	rels := make([]string, 0, len(entries))
+	for rel := range entries {
+		rels = append(rels, rel)
+	}
+	sort.Slice(rels, func(i, j int) bool {
+		a, b := entries[rels[i]], entries[rels[j]]
+		if a.Seen != b.Seen {
+			return a.Seen < b.Seen
+		}
+		return rels[i] < rels[j]
+	})
+	excess := len(entries) - limit
+	for _, rel := range rels[:excess] {
+		delete(entries, rel)
+	}
+	return excess
+}
+
+// 清除源文件夹的记录，下次整理时其中的所有文件都按新增文件处理。roots为空时清除全部
+func clearIncomingStore(roots []string) (int, error) {
+	incomingStoreMu.Lock()
+	defer incomingStoreMu.Unlock()
+	store, err := loadIncomingStore()
+	if err != nil && len(roots) > 0 {
+		return 0, err
+	}
+	cleared := 0
+	if len(roots) == 0 {
+		for _, entries := range store.Roots {
+			cleared += len(entries)
+		}
+		store.Roots = make(map[string]map[string]incomingEntry)
+	}
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		cleared += len(store.Roots[root])
+		delete(store.Roots, root)
+	}
+	return cleared, store.save()
+}
+
+// 描述各源文件夹的记录，用于设置中的提示
+func describeIncomingStore(roots []string) string {
+	incomingStoreMu.Lock()
+	store, err := loadIncomingStore()
+	incomingStoreMu.Unlock()
+	if err != nil {
+		return err.Error()
+	}
+	var parts []string
+	for _, root := range roots {
+		if entries, ok := store.Roots[filepath.Clean(root)]; ok {
+			parts = append(parts, fmt.Sprintf("%s: 已记录 %d 个文件", filepath.Base(root), len(entries)))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s: 尚未记录，首次整理全部文件", filepath.Base(root)))
+		}
+	}
+	if len(parts) == 0 {
+		return "尚未选择源文件夹"
+	}
+	return strings.Join(parts, "；")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// startHookNotifier 在整理开始时执行回调，用于模拟整理期间发生的变化
+type startHookNotifier struct {
+	UINotifier
+	onStart func()
+}
+
+func (n *startHookNotifier) ProcessStarted(total int) {
+	n.UINotifier.ProcessStarted(total)
+	if n.onStart != nil {
+		n.onStart()
+		n.onStart = nil
+	}
+}
+
+func incomingTestConfig(source, target string) Config {
+	return Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      target,
+		FileExtensions: []string{".pdf"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		IncomingOnly:   true,
+		ExcludedFiles:  map[string]bool{},
+	}
+}
+
+// 上次已有的文件在副本合并之前就被过滤掉，不会被当作副本删除
+func TestIncomingFilterRunsBeforeCopyMerge(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	original := writeTestFile(t, filepath.Join(source, "report.pdf"), "x")
+	duplicate := writeTestFile(t, filepath.Join(source, "report (1).pdf"), "x")
+	config := incomingTestConfig(source, t.TempDir())
+	fo.recordIncoming(fo.incomingBaselineOf(config, []string{original, duplicate}))
+
+	config.CopyMerge = CopyMergeDelete
+	config.CopySuffixPatterns = defaultCopySuffixPatterns
+	fo.processFiles(config, []string{original, duplicate})
+
+	for _, path := range []string{original, duplicate} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("上次已有的文件 %s 不应被处理: %v", path, err)
+		}
+	}
+}
+
+// 整理期间才出现的文件下次仍是新增文件，整理到源文件夹之内的文件下次不再整理
+func TestIncomingBaselineIsPreRun(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	existing := writeTestFile(t, filepath.Join(source, "a.pdf"), "a")
+	arrived := filepath.Join(source, "b.pdf")
+	fo.ui = &startHookNotifier{UINotifier: fo.ui, onStart: func() {
+		writeTestFile(t, arrived, "b")
+	}}
+
+	config := incomingTestConfig(source, source)
+	summary, err := fo.processFiles(config, []string{existing})
+	if err != nil || summary.Failed != 0 {
+		t.Fatalf("processFiles = %+v, %v", summary, err)
+	}
+
+	store, err := loadIncomingStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := filepath.Join(source, ".pdf", "a.pdf")
+	if !store.known(moved, config.SourceDirs) {
+		t.Error("整理到源文件夹之内的文件应记为已有")
+	}
+	if store.known(arrived, config.SourceDirs) {
+		t.Error("整理期间才出现的文件不应记为已有")
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 程序运行期间放在配置文件夹中的锁文件，防止同时打开两个窗口。无界面整理使用单独的锁文件，
+// 定时任务不会因为窗口开着而无法运行；与窗口同时整理同一个目标时由目标文件夹的锁保护
+const (
+	instanceLockName = "instance.lock"
+	headlessLockName = "headless.lock"
+)
+
+// 锁文件已被其他进程锁定
+var errFileLocked = errors.New("文件已被其他进程锁定")
+
+// 连接已运行的程序时的超时
+const instanceDialTimeout = 2 * time.Second
+
+// InstanceLock 锁文件的内容。有界面的程序在本机回环地址上监听，再次启动时通过该端口切换到已打开的窗口
+type InstanceLock struct {
+	PID       int       `json:"pid"`
+	Port      int       `json:"port,omitempty"` // 无界面运行时为0
+	Token     string    `json:"token,omitempty"`
+	Headless  bool      `json:"headless,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// 描述锁的持有者，用于提示
+func (l InstanceLock) describe() string {
+	kind := "文件整理工具"
+	if l.Headless {
+		kind = "无界面整理"
+	}
+	return fmt.Sprintf("%s（进程 %d）自 %s 起正在运行", kind, l.PID, l.StartedAt.Format("2006-01-02 15:04:05"))
+}
+
+// instanceRunningError 已有程序正在运行
+type instanceRunningError struct {
+	holder InstanceLock
+}
+
+func (e *instanceRunningError) Error() string {
+	return "程序已在运行: " + e.holder.describe()
+}
+
+// instanceLockHandle 本程序持有的锁，有界面时同时持有监听切换窗口请求的端口
+type instanceLockHandle struct {
+	path     string
+	file     *os.File // 锁住的锁文件，程序运行期间保持打开
+	lock     InstanceLock
+	listener net.Listener
+}
+
+// 锁文件的路径，有界面和无界面各用一个
+func instanceLockPath(headless bool) string {
+	if headless {
+		return filepath.Join(appDataDir(), headlessLockName)
+	}
+	return filepath.Join(appDataDir(), instanceLockName)
+}
+
+// 读取锁文件
+func readInstanceLock(path string) (InstanceLock, time.Time, error) {
+	var lock InstanceLock
+	info, err := os.Stat(path)
+	if err != nil {
+		return lock, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lock, info.ModTime(), err
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return lock, info.ModTime(), fmt.Errorf("锁文件内容无效: %w", err)
+	}
+	return lock, info.ModTime(), nil
+}
+
+// 获取单实例锁。锁文件由系统的文件锁保护：打开后直接加锁，不存在先检查再创建之间的空隙，
+// 程序崩溃或被强制结束时系统自动释放，不会留下失效的锁。已有程序在运行时返回 *instanceRunningError。
+// headless为false时监听本机端口，接收切换窗口的请求
+func acquireInstanceLock(headless bool) (*instanceLockHandle, error) {
+	handle := &instanceLockHandle{
+		path: instanceLockPath(headless),
+		lock: InstanceLock{PID: os.Getpid(), Headless: headless, StartedAt: time.Now()},
+	}
+	if err := os.MkdirAll(filepath.Dir(handle.path), 0755); err != nil {
+		return nil, fmt.Errorf("创建单实例锁失败: %w", err)
+	}
+	file, err := os.OpenFile(handle.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("创建单实例锁失败: %w", err)
+	}
+	if err := tryLockFile(file); err != nil {
+		file.Close()
+		if errors.Is(err, errFileLocked) {
+			holder, modTime, readErr := readInstanceLock(handle.path)
+			if readErr != nil {
+				// 持有者刚加锁，还没写完内容
+				holder = InstanceLock{Headless: headless, StartedAt: modTime}
+			}
+			return nil, &instanceRunningError{holder: holder}
+		}
+		return nil, fmt.Errorf("锁定单实例锁失败: %w", err)
+	}
+	handle.file = file
+
+	if !headless {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err == nil {
+			token := make([]byte, 16)
+			rand.Read(token)
+			handle.listener = listener
+			handle.lock.Port = listener.Addr().(*net.TCPAddr).Port
+			handle.lock.Token = hex.EncodeToString(token)
+		}
+	}
+	data, err := json.MarshalIndent(handle.lock, "", "  ")
+	if err == nil {
+		err = file.Truncate(0)
+	}
+	if err == nil {
+		_, err = file.WriteAt(data, 0)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		handle.release()
+		return nil, fmt.Errorf("写入单实例锁失败: %w", err)
+	}
+	return handle, nil
+}
+
+// 停止监听
+func (h *instanceLockHandle) close() {
+	if h.listener != nil {
+		h.listener.Close()
+	}
+}
+
+// 停止监听，清空锁文件后释放锁。锁文件本身保留，删除它会让已经打开它的其他程序锁住一个不再使用的文件
+func (h *instanceLockHandle) release() error {
+	h.close()
+	if h.file == nil {
+		return nil
+	}
+	h.file.Truncate(0)
+	err := unlockFile(h.file)
+	if closeErr := h.file.Close(); err == nil {
+		err = closeErr
+	}
+	h.file = nil
+	if err != nil {
+		return fmt.Errorf("释放单实例锁失败: %w", err)
+	}
+	return nil
+}
+
+// 接收再次启动的程序发来的请求，令牌正确时切换到本窗口
+func (fo *FileOrganizer) serveInstanceFocus(h *instanceLockHandle) {
+	if h == nil || h.listener == nil {
+		return
+	}
+	go func() {
+		for {
+			conn, err := h.listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(instanceDialTimeout))
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			conn.Close()
+			if strings.TrimSpace(line) != h.lock.Token {
+				continue
+			}
+			fo.log("再次启动了文件整理工具，已切换到当前窗口")
+			fo.safeUpdateUI(func() {
+				fo.Window.Show()
+				fo.Window.RequestFocus()
+			})
+		}
+	}()
+}
+
+// 请求已运行的程序切换到它的窗口
+func focusRunningInstance(holder InstanceLock) error {
+	if holder.Port == 0 || holder.Token == "" {
+		return errors.New("已运行的程序没有窗口")
+	}
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", holder.Port), instanceDialTimeout)
+	if err != nil {
+		return fmt.Errorf("连接已运行的程序失败: %w", err)
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintln(conn, holder.Token); err != nil {
+		return fmt.Errorf("连接已运行的程序失败: %w", err)
+	}
+	return nil
+}
+
+// 已有窗口打开但无法切换过去时（例如它没有响应）显示提示后退出
+func showInstanceRunning(holder InstanceLock) {
+	a := app.NewWithID("com.fileorganizer.app")
+	w := a.NewWindow("文件整理工具")
+	message := widget.NewLabel(holder.describe() + "\n\n无法切换到已打开的窗口。同时打开两个窗口整理同一批文件会互相干扰，" +
+		"请关闭已打开的窗口后再打开。程序退出（包括崩溃）后锁会自动释放。")
+	message.Wrapping = fyne.TextWrapWord
+	closeBtn := widget.NewButton("关闭", w.Close)
+	w.SetContent(container.NewBorder(nil, container.NewHBox(layout.NewSpacer(), closeBtn), nil, nil, message))
+	w.Resize(fyne.NewSize(480, 200))
+	w.ShowAndRun()
+}
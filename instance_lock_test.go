@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// 有界面和无界面各自只能运行一个，互不阻塞；释放后可以重新获取
+func TestInstanceLock(t *testing.T) {
+	newTestEnv(t)
+	tests := []struct {
+		name     string
+		held     bool // 先获取的锁
+		acquire  bool // 再获取的锁
+		wantHeld bool // 是否报告已在运行
+	}{
+		{"界面打开时运行无界面整理", false, true, false},
+		{"无界面整理时打开界面", true, false, false},
+		{"再打开一个界面", false, false, true},
+		{"再运行一个无界面整理", true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := acquireInstanceLock(tt.held)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := acquireInstanceLock(tt.acquire)
+			var running *instanceRunningError
+			if errors.As(err, &running) != tt.wantHeld {
+				t.Fatalf("第二个锁: %v", err)
+			}
+			if tt.wantHeld && running.holder.PID != first.lock.PID {
+				t.Fatalf("持有者 = %+v", running.holder)
+			}
+			if second != nil {
+				if err := second.release(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := first.release(); err != nil {
+				t.Fatal(err)
+			}
+			again, err := acquireInstanceLock(tt.acquire)
+			if err != nil {
+				t.Fatalf("释放后重新获取: %v", err)
+			}
+			again.release()
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+// 错误是否表示文件系统不能使用这个文件名（当前系统无法判断）
+func invalidNameError(err error) bool {
+	return false
+}
@@ -0,0 +1,14 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// 错误是否表示文件系统不能使用这个文件名（包含不支持的字符、编码或过长）
+func invalidNameError(err error) bool {
+	return errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EILSEQ) || errors.Is(err, unix.ENAMETOOLONG)
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// QueueJob 任务队列中的一个任务：用指定预设整理指定的源文件夹
+type QueueJob struct {
+	Preset            string   `json:"preset"`
+	SourceDirs        []string `json:"source_dirs"`
+	TargetDir         string   `json:"target_dir,omitempty"` // 为空时使用第一个源文件夹
+	ContinueOnFailure bool     `json:"continue_on_failure"`  // 失败后继续执行后面的任务，否则中止队列
+}
+
+// 任务在统计中记录的配置方案
+func queueProfileName(preset string) string {
+	return "任务队列: " + preset
+}
+
+// 任务在列表中的描述
+func (job QueueJob) describe() string {
+	target := job.TargetDir
+	if target == "" {
+		target = "原文件夹"
+	}
+	policy := "失败时中止队列"
+	if job.ContinueOnFailure {
+		policy = "失败时继续"
+	}
+	return fmt.Sprintf("%s: %s → %s（%s）", job.Preset, strings.Join(job.SourceDirs, ", "), target, policy)
+}
+
+// 加载保存的任务队列
+func (fo *FileOrganizer) loadQueue() {
+	fo.queueJobs = nil
+	if data := fyne.CurrentApp().Preferences().StringWithFallback("job_queue", ""); data != "" {
+		if err := json.Unmarshal([]byte(data), &fo.queueJobs); err != nil {
+			fo.log(fmt.Sprintf("加载任务队列失败: %v", err))
+			fo.queueJobs = nil
+		}
+	}
+}
+
+// 保存任务队列
+func (fo *FileOrganizer) saveQueue() {
+	data, err := json.Marshal(fo.queueJobs)
+	if err != nil {
+		fo.log(fmt.Sprintf("保存任务队列失败: %v", err))
+		return
+	}
+	fyne.CurrentApp().Preferences().SetString("job_queue", string(data))
+}
+
+// 根据任务的预设生成整理配置，预设未指定的设置使用当前设置
+func (fo *FileOrganizer) jobConfig(job QueueJob) (Config, error) {
+	preset, ok := fo.findPreset(job.Preset)
+	if !ok {
+		return Config{}, fmt.Errorf("预设「%s」不存在", job.Preset)
+	}
+	if len(preset.FileExtensions) == 0 {
+		return Config{}, fmt.Errorf("预设「%s」没有选择文件后缀", job.Preset)
+	}
+	if len(job.SourceDirs) == 0 {
+		return Config{}, errors.New("任务没有源文件夹")
+	}
+
+	targetDir := job.TargetDir
+	if targetDir == "" {
+		targetDir = job.SourceDirs[0]
+	}
+	if err := validateTargetDir(targetDir); err != nil {
+		return Config{}, err
+	}
+
+	config := fo.currentConfig()
+	mainTarget := config.TargetDir
+	config.SourceDir = targetDir
+	config.TargetDir = targetDir
+	config.SourceDirs = append([]string(nil), job.SourceDirs...)
+	config.ExcludedFiles = nil
+	config.CapacityPlan = nil
+	config.FileExtensions = normalizeExtensions(preset.FileExtensions)
+	config.OrganizeRule = preset.OrganizeRule
+	// 任务按预设整理，与使用同一预设的任务比较用时，不算作主窗口当前的配置方案
+	config.Profile = queueProfileName(preset.Name)
+	if preset.FolderDateFormat != "" {
+		config.FolderDateFormat = preset.FolderDateFormat
+	}
+	if preset.ExtensionCase != "" {
+		config.ExtensionCase = preset.ExtensionCase
+	}
+	if preset.MultiTagMode != "" {
+		config.MultiTagMode = preset.MultiTagMode
+	}
+	if preset.FolderLayout != "" {
+		config.FolderLayout = preset.FolderLayout
+	}
+	if len(preset.EventLabels) > 0 {
+		config.EventLabels = append([]EventLabel(nil), preset.EventLabels...)
+	}
+	if len(preset.DateSources) > 0 {
+		config.DateSources = append([]DateSource(nil), preset.DateSources...)
+	}
+	if len(preset.RuleSegments) > 0 {
+		config.RuleSegments = append([]string(nil), preset.RuleSegments...)
+		config.RuleSegmentSeparator = preset.SegmentSeparator
+	}
+	if preset.hasTextRules() {
+		config.TextLanguageFolders = preset.TextLanguageFolders
+		config.TextKeywordRules = append([]TextKeywordRule(nil), preset.TextKeywordRules...)
+		config.TextKeywordCase = preset.TextKeywordCase
+	}
+	// 后缀序号属于主窗口的目标文件夹，任务整理到同一目标时沿用已分配的序号
+	config.ExtensionRanks = nil
+	if OrganizeRule(config.OrganizeRule) == RuleByExtension && fo.ExtensionRankPrefix && samePath(targetDir, mainTarget) {
+		config.ExtensionRanks = copyExtensionRanks(fo.ExtensionRanks)
+	}
+	config.ReadOnlySources = nil
+	for _, dir := range job.SourceDirs {
+		if fo.readOnlySources[dir] {
+			config.ReadOnlySources = append(config.ReadOnlySources, dir)
+		}
+	}
+	if err := validateReadOnlySources(config); err != nil {
+		return Config{}, err
+	}
+	if err := validateLayoutOverlap(config); err != nil {
+		return Config{}, err
+	}
+	if err := validateTagRule(config); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// 同步扫描任务的源文件夹，不使用也不更新界面的扫描缓存
+func (fo *FileOrganizer) collectFiles(roots []string) []string {
+	files, _ := fo.collectFilesUpTo(roots, 0)
+	return files
+}
+
+// 扫描源文件夹中的所有文件。limit 大于0且文件数超过它时停止扫描并丢弃文件列表，返回true，由调用方改用流式整理
+func (fo *FileOrganizer) collectFilesUpTo(roots []string, limit int) ([]string, bool) {
+	scanner := newDirScanner("", true)
+	var files []string
+	over := false
+	var mu sync.Mutex
+	for _, root := range roots {
+		scanner.scan(root, func(path string, info os.FileInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			if over {
+				return
+			}
+			files = append(files, path)
+			if limit > 0 && len(files) > limit {
+				over, files = true, nil
+				scanner.abort()
+			}
+		}, func(path string, err error) {
+			fo.log(fmt.Sprintf("扫描 %s 时出错: %v", path, err))
+		})
+	}
+	return files, over
+}
+
+// 保存任务报告，返回报告文件路径
+func writeJobReport(index, total int, job QueueJob, config Config, summary processSummary, runErr error, started time.Time) (string, error) {
+	dir := filepath.Join(appDataDir(), "reports")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建报告文件夹失败: %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "任务 %d/%d: %s\n", index, total, job.Preset)
+	fmt.Fprintf(&sb, "开始时间: %s\n", started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&sb, "结束时间: %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	for _, dir := range config.SourceDirs {
+		fmt.Fprintf(&sb, "源文件夹: %s\n", dir)
+	}
+	fmt.Fprintf(&sb, "目标文件夹: %s\n", config.TargetDir)
+	fmt.Fprintf(&sb, "等效命令: %s\n", cliCommandFor(config.SourceDirs, config))
+	if runErr != nil {
+		fmt.Fprintf(&sb, "错误: %v\n", runErr)
+	}
+	fmt.Fprintf(&sb, "检查: %d\n移动: %d\n复制（只读源）: %d\n重新归档: %d\n跳过重复: %d\n失败: %d\n未处理: %d\n",
+		summary.Checked, summary.Moved, summary.Copied, summary.Refiled, summary.Duplicates, summary.Failed, summary.Aborted)
+	for _, line := range describePerformance(summary.Stats) {
+		sb.WriteString(line + "\n")
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("queue_%s_%d.txt", started.Format("20060102_150405"), index))
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return "", fmt.Errorf("保存任务报告失败: %w", err)
+	}
+	return path, nil
+}
+
+// 按顺序执行队列中的任务，每个任务结束后保存报告，失败时按任务的设置继续或中止队列
+func (fo *FileOrganizer) runQueue(jobs []QueueJob) {
+	if !fo.queueRunning.CompareAndSwap(false, true) {
+		fo.log("任务队列正在执行")
+		return
+	}
+	// 手动整理或其他修改目标的操作正在进行时不开始，避免与队列中的任务同时整理
+	if fo.activeRuns.Load() > 0 {
+		fo.queueRunning.Store(false)
+		dialog.ShowInformation("提示", "正在整理文件，请等待整理完成后再执行任务队列", fo.Window)
+		return
+	}
+	fo.cancelQueue.Store(false)
+	fo.processBtn.Disable()
+
+	// 在界面线程中先生成所有任务的配置，配置错误的任务在执行时按失败处理
+	configs := make([]Config, len(jobs))
+	configErrs := make([]error, len(jobs))
+	for i, job := range jobs {
+		configs[i], configErrs[i] = fo.jobConfig(job)
+	}
+	fo.showQueueProgress(len(jobs))
+	fo.log(fmt.Sprintf("开始执行任务队列，共 %d 个任务", len(jobs)))
+
+	go func() {
+		defer fo.queueRunning.Store(false)
+		succeeded, failed := 0, 0
+		for i, job := range jobs {
+			if fo.cancelQueue.Load() {
+				fo.log(fmt.Sprintf("已取消任务队列，剩余 %d 个任务未执行", len(jobs)-i))
+				break
+			}
+			fo.cancelProcessing.Store(false)
+			fo.updateQueueProgress(i+1, len(jobs), job.Preset)
+			fo.log(fmt.Sprintf("任务 %d/%d: %s", i+1, len(jobs), job.describe()))
+
+			started := time.Now()
+			var summary processSummary
+			config, err := configs[i], configErrs[i]
+			if err == nil {
+				files := fo.collectFiles(config.SourceDirs)
+				fo.log(fmt.Sprintf("扫描完成，共发现 %d 个文件", len(files)))
+				summary, err = fo.processFiles(config, files)
+			}
+			if reportPath, reportErr := writeJobReport(i+1, len(jobs), job, config, summary, err, started); reportErr != nil {
+				fo.log(reportErr.Error())
+			} else {
+				fo.log("任务报告: " + reportPath)
+			}
+
+			if err == nil && summary.Failed == 0 && summary.Aborted == 0 {
+				succeeded++
+				continue
+			}
+			failed++
+			if err != nil {
+				fo.log(fmt.Sprintf("任务 %d/%d 失败: %v", i+1, len(jobs), err))
+			} else {
+				fo.log(fmt.Sprintf("任务 %d/%d 未全部完成: %d 个文件失败，%d 个文件未处理", i+1, len(jobs), summary.Failed, summary.Aborted))
+			}
+			if !job.ContinueOnFailure && !fo.cancelQueue.Load() {
+				fo.log("任务设置为失败时中止队列，剩余任务不再执行")
+				break
+			}
+		}
+		fo.cancelProcessing.Store(false)
+		fo.log(fmt.Sprintf("任务队列结束: %d 个任务成功，%d 个任务失败", succeeded, failed))
+
+		fo.safeUpdateUI(func() {
+			fo.hideQueueProgress()
+			fo.processBtn.Enable()
+			// 队列执行期间界面的扫描结果可能已过期，按当前源文件夹重新扫描
+			if len(fo.SourceDirs) > 0 {
+				fo.rescan()
+			}
+		})
+	}()
+}
+
+// queueProgressView 任务队列的进度对话框
+type queueProgressView struct {
+	dialog   dialog.Dialog
+	jobLabel *widget.Label
+	jobBar   *widget.ProgressBar
+	fileBar  *widget.ProgressBar
+}
+
+// 显示任务队列的进度对话框
+func (fo *FileOrganizer) showQueueProgress(total int) {
+	view := &queueProgressView{
+		jobLabel: widget.NewLabel(fmt.Sprintf("任务 0/%d", total)),
+		jobBar:   widget.NewProgressBar(),
+		fileBar:  widget.NewProgressBar(),
+	}
+	view.jobBar.Max = float64(total)
+	view.jobBar.TextFormatter = func() string {
+		return fmt.Sprintf("%.0f/%d", view.jobBar.Value, total)
+	}
+
+	cancelJobBtn := widget.NewButton("取消当前任务", func() {
+		fo.cancelProcessing.Store(true)
+		fo.log("正在取消当前任务...")
+	})
+	cancelQueueBtn := widget.NewButton("取消整个队列", func() {
+		fo.cancelQueue.Store(true)
+		fo.cancelProcessing.Store(true)
+		fo.log("正在取消任务队列...")
+	})
+	cancelQueueBtn.Importance = widget.DangerImportance
+
+	view.dialog = dialog.NewCustomWithoutButtons("正在执行任务队列", container.NewVBox(
+		view.jobLabel,
+		view.jobBar,
+		view.fileBar,
+		container.NewHBox(layout.NewSpacer(), cancelJobBtn, cancelQueueBtn),
+	), fo.Window)
+	view.dialog.Resize(fyne.NewSize(480, 0))
+	fo.queueProgress = view
+	fo.showDialog(view.dialog, cancelJobBtn)
+}
+
+// 更新当前执行的任务
+func (fo *FileOrganizer) updateQueueProgress(index, total int, name string) {
+	fo.safeUpdateUI(func() {
+		if view := fo.queueProgress; view != nil {
+			view.jobLabel.SetText(fmt.Sprintf("任务 %d/%d: %s", index, total, name))
+			view.jobBar.SetValue(float64(index - 1))
+			view.fileBar.SetValue(0)
+		}
+	})
+}
+
+// 关闭任务队列的进度对话框
+func (fo *FileOrganizer) hideQueueProgress() {
+	if view := fo.queueProgress; view != nil {
+		view.dialog.Hide()
+		fo.queueProgress = nil
+	}
+}
+
+// 显示添加任务的对话框
+func (fo *FileOrganizer) showAddJobDialog(onAdded func()) {
+	names := fo.presetNames()
+	if len(names) == 0 {
+		dialog.ShowInformation("提示", "请先在「规则预设」中保存至少一个预设", fo.Window)
+		return
+	}
+
+	presetSelect := widget.NewSelect(names, nil)
+	presetSelect.SetSelected(names[0])
+	sourcesEntry := widget.NewMultiLineEntry()
+	sourcesEntry.SetPlaceHolder("每行一个源文件夹")
+	sourcesEntry.SetText(strings.Join(fo.SourceDirs, "\n"))
+	sourcesEntry.SetMinRowsVisible(3)
+	sourcesEntry.Validator = func(text string) error {
+		dirs := splitLines(text)
+		if len(dirs) == 0 {
+			return errors.New("请至少填写一个源文件夹")
+		}
+		for _, dir := range dirs {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return fmt.Errorf("源文件夹不存在: %s", dir)
+			}
+		}
+		return nil
+	}
+	targetEntry := widget.NewEntry()
+	targetEntry.SetPlaceHolder("留空则使用第一个源文件夹")
+	targetEntry.Validator = func(text string) error {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return validateTargetDir(strings.TrimSpace(text))
+	}
+	continueCheck := widget.NewCheck("失败时继续执行后面的任务", nil)
+
+	dialog.ShowForm("添加任务", "添加", "取消", []*widget.FormItem{
+		widget.NewFormItem("预设", presetSelect),
+		widget.NewFormItem("源文件夹", sourcesEntry),
+		widget.NewFormItem("目标文件夹", targetEntry),
+		widget.NewFormItem("", continueCheck),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		fo.queueJobs = append(fo.queueJobs, QueueJob{
+			Preset:            presetSelect.Selected,
+			SourceDirs:        splitLines(sourcesEntry.Text),
+			TargetDir:         strings.TrimSpace(targetEntry.Text),
+			ContinueOnFailure: continueCheck.Checked,
+		})
+		fo.saveQueue()
+		onAdded()
+	}, fo.Window)
+}
+
+// 按行拆分文本，忽略空行
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, filepath.Clean(line))
+		}
+	}
+	return lines
+}
+
+// 显示任务队列对话框
+func (fo *FileOrganizer) showQueueDialog() {
+	selected := -1
+	jobList := widget.NewList(
+		func() int {
+			return len(fo.queueJobs)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(fmt.Sprintf("%d. %s", i+1, fo.queueJobs[i].describe()))
+		},
+	)
+	jobList.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+	jobList.OnUnselected = func(id widget.ListItemID) {
+		selected = -1
+	}
+
+	reload := func() {
+		jobList.Refresh()
+	}
+	// 交换两个任务的位置并保持选中
+	move := func(delta int) {
+		to := selected + delta
+		if selected < 0 || to < 0 || to >= len(fo.queueJobs) {
+			return
+		}
+		fo.queueJobs[selected], fo.queueJobs[to] = fo.queueJobs[to], fo.queueJobs[selected]
+		fo.saveQueue()
+		jobList.Select(to)
+		reload()
+	}
+
+	addBtn := widget.NewButtonWithIcon("添加任务", theme.ContentAddIcon(), func() {
+		fo.showAddJobDialog(reload)
+	})
+	upBtn := widget.NewButtonWithIcon("上移", theme.MoveUpIcon(), func() {
+		move(-1)
+	})
+	downBtn := widget.NewButtonWithIcon("下移", theme.MoveDownIcon(), func() {
+		move(1)
+	})
+	removeBtn := widget.NewButtonWithIcon("删除", theme.DeleteIcon(), func() {
+		if selected < 0 || selected >= len(fo.queueJobs) {
+			dialog.ShowInformation("提示", "请先选择一个任务", fo.Window)
+			return
+		}
+		fo.queueJobs = append(fo.queueJobs[:selected], fo.queueJobs[selected+1:]...)
+		fo.saveQueue()
+		jobList.UnselectAll()
+		reload()
+	})
+
+	var queueDialog dialog.Dialog
+	runAllBtn := widget.NewButtonWithIcon("全部执行", theme.MediaPlayIcon(), func() {
+		if len(fo.queueJobs) == 0 {
+			dialog.ShowInformation("提示", "任务队列为空", fo.Window)
+			return
+		}
+		if fo.refuseInReadOnlyMode() {
+			return
+		}
+		queueDialog.Hide()
+		fo.runQueue(append([]QueueJob(nil), fo.queueJobs...))
+	})
+	runAllBtn.Importance = widget.HighImportance
+
+	listScroll := container.NewVScroll(jobList)
+	listScroll.SetMinSize(fyne.NewSize(560, 220))
+	content := container.NewVBox(
+		widget.NewLabel("按顺序执行的任务（队列会自动保存）:"),
+		listScroll,
+		container.NewGridWithColumns(4, addBtn, upBtn, downBtn, removeBtn),
+		widget.NewSeparator(),
+		runAllBtn,
+	)
+
+	queueDialog = dialog.NewCustom("任务队列", "关闭", content, fo.Window)
+	fo.showDialog(queueDialog, jobList)
+}
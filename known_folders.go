@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KnownFolder 系统的常用文件夹
+type KnownFolder int
+
+const (
+	FolderDownloads KnownFolder = iota // 下载
+	FolderDesktop                      // 桌面
+)
+
+// 常用文件夹的显示名称
+var knownFolderNames = map[KnownFolder]string{
+	FolderDownloads: "下载",
+	FolderDesktop:   "桌面",
+}
+
+// 各系统默认使用的英文文件夹名，无法从系统获取时使用
+var knownFolderFallbacks = map[KnownFolder]string{
+	FolderDownloads: "Downloads",
+	FolderDesktop:   "Desktop",
+}
+
+// XDG user-dirs 中对应的键
+var knownFolderXDGKeys = map[KnownFolder]string{
+	FolderDownloads: "XDG_DOWNLOAD_DIR",
+	FolderDesktop:   "XDG_DESKTOP_DIR",
+}
+
+// folderEnv 解析常用文件夹时依赖的环境，界面和命令行使用系统环境
+type folderEnv struct {
+	home     string
+	getenv   func(key string) string
+	readFile func(path string) ([]byte, error)
+}
+
+// 当前系统的环境
+func systemFolderEnv() (folderEnv, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return folderEnv{}, fmt.Errorf("无法获取用户主目录: %w", err)
+	}
+	return folderEnv{home: home, getenv: os.Getenv, readFile: os.ReadFile}, nil
+}
+
+// 获取常用文件夹的路径：优先使用系统设置（Windows已知文件夹、Linux XDG用户目录），
+// 都没有时使用主目录下的默认英文文件夹
+func knownFolderPath(folder KnownFolder) (string, error) {
+	env, err := systemFolderEnv()
+	if err != nil {
+		return "", err
+	}
+	path, ok := platformKnownFolder(env, folder)
+	if !ok {
+		path = filepath.Join(env.home, knownFolderFallbacks[folder])
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("%s文件夹不存在: %w", knownFolderNames[folder], err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s文件夹不是文件夹: %s", knownFolderNames[folder], path)
+	}
+	return path, nil
+}
+
+// 按 XDG user-dirs 规范获取用户目录：环境变量优先，其次是 $XDG_CONFIG_HOME/user-dirs.dirs
+func xdgUserDir(env folderEnv, key string) (string, bool) {
+	if value := env.getenv(key); value != "" {
+		return expandXDGPath(value, env.home)
+	}
+
+	configHome := env.getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(env.home, ".config")
+	}
+	data, err := env.readFile(filepath.Join(configHome, "user-dirs.dirs"))
+	if err != nil {
+		return "", false
+	}
+	value, ok := parseXDGUserDirs(data)[key]
+	if !ok {
+		return "", false
+	}
+	return expandXDGPath(value, env.home)
+}
+
+// 解析 user-dirs.dirs，每行形如 XDG_DOWNLOAD_DIR="$HOME/下载"
+func parseXDGUserDirs(data []byte) map[string]string {
+	dirs := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		dirs[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return dirs
+}
+
+// 展开 XDG 路径中的 $HOME。按规范只允许 $HOME/... 或绝对路径，
+// 指向主目录本身表示该目录被禁用
+func expandXDGPath(value, home string) (string, bool) {
+	switch {
+	case value == "$HOME" || value == "$HOME/":
+		return "", false
+	case strings.HasPrefix(value, "$HOME/"):
+		return filepath.Join(home, value[len("$HOME/"):]), true
+	case filepath.IsAbs(value):
+		return filepath.Clean(value), true
+	}
+	return "", false
+}
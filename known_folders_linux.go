@@ -0,0 +1,8 @@
+//go:build linux
+
+package main
+
+// Linux 上使用 XDG 用户目录，桌面环境会按系统语言本地化文件夹名称
+func platformKnownFolder(env folderEnv, folder KnownFolder) (string, bool) {
+	return xdgUserDir(env, knownFolderXDGKeys[folder])
+}
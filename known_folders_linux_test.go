@@ -0,0 +1,57 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// 使用假的环境变量和 user-dirs.dirs：环境变量优先，其次是配置文件，被禁用或无效的目录交给调用者回退到默认文件夹
+func TestPlatformKnownFolder(t *testing.T) {
+	const userDirs = `# 由 xdg-user-dirs-update 生成
+XDG_DESKTOP_DIR="$HOME/桌面"
+XDG_DOWNLOAD_DIR="$HOME/下载"
+`
+	tests := []struct {
+		name   string
+		folder KnownFolder
+		vars   map[string]string
+		files  map[string]string // 路径 -> 内容
+		want   string
+		wantOK bool
+	}{
+		{"本地化的下载文件夹", FolderDownloads, nil,
+			map[string]string{"/home/alex/.config/user-dirs.dirs": userDirs}, "/home/alex/下载", true},
+		{"本地化的桌面文件夹", FolderDesktop, nil,
+			map[string]string{"/home/alex/.config/user-dirs.dirs": userDirs}, "/home/alex/桌面", true},
+		{"环境变量优先", FolderDownloads, map[string]string{"XDG_DOWNLOAD_DIR": "/data/downloads"},
+			map[string]string{"/home/alex/.config/user-dirs.dirs": userDirs}, "/data/downloads", true},
+		{"XDG_CONFIG_HOME", FolderDownloads, map[string]string{"XDG_CONFIG_HOME": "/etc/alex"},
+			map[string]string{"/etc/alex/user-dirs.dirs": `XDG_DOWNLOAD_DIR="/mnt/dl/"`}, "/mnt/dl", true},
+		{"指向主目录表示禁用", FolderDesktop, nil,
+			map[string]string{"/home/alex/.config/user-dirs.dirs": `XDG_DESKTOP_DIR="$HOME/"`}, "", false},
+		{"相对路径无效", FolderDownloads, map[string]string{"XDG_DOWNLOAD_DIR": "Downloads"}, nil, "", false},
+		{"没有配置文件", FolderDownloads, nil, nil, "", false},
+		{"配置文件中没有该目录", FolderDownloads, nil,
+			map[string]string{"/home/alex/.config/user-dirs.dirs": `XDG_DESKTOP_DIR="$HOME/Desktop"`}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := folderEnv{
+				home:   "/home/alex",
+				getenv: func(key string) string { return tt.vars[key] },
+				readFile: func(path string) ([]byte, error) {
+					if content, ok := tt.files[path]; ok {
+						return []byte(content), nil
+					}
+					return nil, os.ErrNotExist
+				},
+			}
+			got, ok := platformKnownFolder(env, tt.folder)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("路径 = %q, %v, 期望 %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+
+package main
+
+// macOS 的下载和桌面文件夹固定位于主目录下，Finder中显示的本地化名称不影响实际路径
+func platformKnownFolder(env folderEnv, folder KnownFolder) (string, bool) {
+	return "", false
+}
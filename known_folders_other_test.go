@@ -0,0 +1,26 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// macOS 不读取系统设置，即使有 XDG 配置也使用主目录下的默认文件夹
+func TestPlatformKnownFolder(t *testing.T) {
+	env := folderEnv{
+		home:   "/Users/alex",
+		getenv: func(key string) string { return "/data/" + key },
+		readFile: func(string) ([]byte, error) {
+			return nil, os.ErrNotExist
+		},
+	}
+	for _, folder := range []KnownFolder{FolderDownloads, FolderDesktop} {
+		t.Run(knownFolderNames[folder], func(t *testing.T) {
+			if got, ok := platformKnownFolder(env, folder); ok {
+				t.Fatalf("路径 = %q, 期望使用默认文件夹", got)
+			}
+		})
+	}
+}
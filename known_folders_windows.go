@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import "golang.org/x/sys/windows"
+
+// Windows 已知文件夹的ID
+var knownFolderIDs = map[KnownFolder]*windows.KNOWNFOLDERID{
+	FolderDownloads: windows.FOLDERID_Downloads,
+	FolderDesktop:   windows.FOLDERID_Desktop,
+}
+
+// 通过 SHGetKnownFolderPath 获取已知文件夹，用户移动过的文件夹（例如移到D盘或OneDrive）也能找到
+func platformKnownFolder(env folderEnv, folder KnownFolder) (string, bool) {
+	path, err := windows.KnownFolderPath(knownFolderIDs[folder], windows.KF_FLAG_DEFAULT)
+	if err != nil || path == "" {
+		return "", false
+	}
+	return path, true
+}
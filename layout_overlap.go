@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// 目录结构是否保留文件在源文件夹中的相对路径
+func preservesStructure(layout string) bool {
+	return layout == LayoutRuleFirst || layout == LayoutSourceFirst
+}
+
+// 保留目录结构时目标文件夹不能就是源文件夹：整理后的文件仍在源文件夹中，
+// 下次整理时相对路径包含上次的规则文件夹，每整理一次就多嵌套一层
+func validateLayoutOverlap(config Config) error {
+	if !preservesStructure(config.FolderLayout) || config.TargetDir == "" {
+		return nil
+	}
+	target := filepath.Clean(config.TargetDir)
+	for _, dir := range config.SourceDirs {
+		if filepath.Clean(dir) == target {
+			return fmt.Errorf("保留目录结构时目标文件夹不能是源文件夹 %s，请选择其他目标文件夹或使用扁平结构", dir)
+		}
+	}
+	return nil
+}
+
+// 保留目录结构并且目标文件夹位于某个源文件夹中时，目标文件夹里的文件已经整理过，
+// 按源文件夹的相对路径重新整理会嵌套出 目标/.../目标/... 的文件夹，这些文件需要跳过
+func isInsidePreservedTarget(filePath string, config Config) bool {
+	if !preservesStructure(config.FolderLayout) || config.TargetDir == "" {
+		return false
+	}
+	target := []string{config.TargetDir}
+	if !isWithinAny(filepath.Dir(filePath), target) {
+		return false
+	}
+	for _, dir := range config.SourceDirs {
+		// 文件所在的源文件夹包含目标文件夹时，相对路径会带上目标文件夹
+		if isWithinAny(filePath, []string{dir}) && isWithinAny(config.TargetDir, []string{dir}) &&
+			filepath.Clean(dir) != filepath.Clean(config.TargetDir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 保留目录结构时目标文件夹不能就是源文件夹；位于源文件夹中或扁平结构时允许
+func TestValidateLayoutOverlap(t *testing.T) {
+	source := filepath.Join(string(filepath.Separator), "photos")
+	tests := []struct {
+		name    string
+		layout  string
+		target  string
+		wantErr bool
+	}{
+		{"规则优先，目标是源文件夹", LayoutRuleFirst, source, true},
+		{"来源优先，目标是源文件夹", LayoutSourceFirst, source + string(filepath.Separator), true},
+		{"扁平结构，目标是源文件夹", LayoutFlat, source, false},
+		{"目标在源文件夹中", LayoutRuleFirst, filepath.Join(source, "sorted"), false},
+		{"目标在源文件夹外", LayoutRuleFirst, filepath.Join(string(filepath.Separator), "sorted"), false},
+		{"名称相近的文件夹", LayoutRuleFirst, source + "-sorted", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{
+				SourceDirs:   []string{filepath.Join(string(filepath.Separator), "other"), source},
+				TargetDir:    tt.target,
+				FolderLayout: tt.layout,
+			}
+			if err := validateLayoutOverlap(config); (err != nil) != tt.wantErr {
+				t.Fatalf("错误 = %v, 期望出错 = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 目标文件夹位于源文件夹中时，目标中已整理的文件跳过，其余文件按源文件夹的相对路径整理，不会嵌套出 目标/.../目标/...
+func TestPreservedTargetInsideSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+		files  []string // 源文件夹中的文件，sorted 是目标文件夹
+		want   []string // 整理后目标中的文件
+	}{
+		{"规则优先", LayoutRuleFirst,
+			[]string{"a.jpg", "trip/b.jpg", "sorted/.jpg/old.jpg", "sorted/.jpg/trip/older.jpg"},
+			[]string{".jpg/a.jpg", ".jpg/old.jpg", ".jpg/trip/b.jpg", ".jpg/trip/older.jpg"}},
+		{"来源优先", LayoutSourceFirst,
+			[]string{"a.jpg", "trip/b.jpg", "sorted/trip/.jpg/old.jpg"},
+			[]string{".jpg/a.jpg", "trip/.jpg/b.jpg", "trip/.jpg/old.jpg"}},
+		{"目标中只有已整理的文件", LayoutRuleFirst,
+			[]string{"sorted/.jpg/old.jpg"},
+			[]string{".jpg/old.jpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			target := filepath.Join(source, "sorted")
+			var files []string
+			for _, name := range tt.files {
+				files = append(files, writeTestFile(t, filepath.Join(source, filepath.FromSlash(name)), name))
+			}
+			config := Config{
+				SourceDir:      source,
+				SourceDirs:     []string{source},
+				TargetDir:      target,
+				FileExtensions: []string{".jpg"},
+				OrganizeRule:   string(RuleByExtension),
+				FolderLayout:   tt.layout,
+				ExtensionCase:  "lowercase",
+				ConflictPolicy: ConflictRename,
+				ExcludedFiles:  map[string]bool{},
+			}
+			if err := validateLayoutOverlap(config); err != nil {
+				t.Fatal(err)
+			}
+			for _, file := range files {
+				info, err := os.Stat(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := isWithinAny(file, []string{target})
+				if plan := fo.planFile(file, info, config, planContext{}); (plan.Skip != "") != want {
+					t.Fatalf("%s 的计划 = %+v, 期望跳过 = %v", file, plan, want)
+				}
+			}
+			if summary, err := fo.processFiles(config, files); err != nil || summary.Failed != 0 {
+				t.Fatalf("整理: %+v, %v", summary, err)
+			}
+			if got := treeFiles(t, target); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("目标文件夹 = %v, 期望 %v", got, tt.want)
+			}
+			// 源文件夹中除目标文件夹外不再有文件
+			for _, name := range treeFiles(t, source) {
+				if !strings.HasPrefix(name, "sorted/") {
+					t.Fatalf("源文件夹中还有 %s", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 估算节省的时间时，手动整理一个文件平均需要的秒数（找到文件、新建或打开文件夹、拖动）
+const manualSecondsPerFile = 5
+
+// BiggestRun 移动文件最多的一次整理
+type BiggestRun struct {
+	StartedAt time.Time `json:"started_at"`
+	TargetDir string    `json:"target_dir"`
+	Files     int       `json:"files"`
+	Bytes     int64     `json:"bytes"`
+}
+
+// LifetimeStats 累计的整理统计。每次整理结束时累加，保存在偏好设置中，
+// 并在统计文件夹中另存一份快照；两者都丢失时从统计文件夹中的每次整理统计重建
+type LifetimeStats struct {
+	Runs            int        `json:"runs"`
+	Files           int        `json:"files"`
+	Bytes           int64      `json:"bytes"`
+	Duplicates      int        `json:"duplicates"`
+	Refiled         int        `json:"refiled"`
+	Errors          int        `json:"errors"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	BiggestRun      BiggestRun `json:"biggest_run"`
+	// Since 开始累计的时间，重置后只统计这之后的整理
+	Since     time.Time `json:"since"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 累加一次整理的统计
+func (s *LifetimeStats) add(run RunStats) {
+	s.Runs++
+	s.Files += run.Files
+	s.Bytes += run.Bytes
+	s.Duplicates += run.Duplicates
+	s.Refiled += run.Refiled.Files
+	s.Errors += run.Errors
+	s.DurationSeconds += run.DurationSeconds
+	if run.Files > s.BiggestRun.Files {
+		s.BiggestRun = BiggestRun{StartedAt: run.StartedAt, TargetDir: run.TargetDir, Files: run.Files, Bytes: run.Bytes}
+	}
+	if s.Since.IsZero() {
+		s.Since = run.StartedAt
+	}
+	s.UpdatedAt = time.Now()
+}
+
+// 估算节省的时间：手动整理这些文件所需的时间减去实际整理用的时间
+func (s LifetimeStats) timeSaved() time.Duration {
+	saved := time.Duration(s.Files)*manualSecondsPerFile*time.Second - time.Duration(s.DurationSeconds*float64(time.Second))
+	if saved < 0 {
+		return 0
+	}
+	return saved
+}
+
+// 累计统计的读写在整理结束和对话框之间可能同时发生
+var lifetimeStatsMu sync.Mutex
+
+// 每次整理的统计所在的文件夹，累计统计的快照也保存在这里
+func runStatsDir() string {
+	return filepath.Join(appDataDir(), "reports")
+}
+
+// 累计统计快照的路径
+func lifetimeStatsPath() string {
+	return filepath.Join(runStatsDir(), "lifetime.json")
+}
+
+// 从统计文件夹中的每次整理统计重建累计统计，只统计since之后开始的整理
+func rebuildLifetimeStats(dir string, since time.Time) (LifetimeStats, error) {
+	stats := LifetimeStats{}
+	paths, err := filepath.Glob(filepath.Join(dir, "stats_*.json"))
+	if err != nil {
+		return stats, fmt.Errorf("查找整理统计失败: %w", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var run RunStats
+		if json.Unmarshal(data, &run) != nil || run.StartedAt.Before(since) {
+			continue
+		}
+		stats.add(run)
+	}
+	if !since.IsZero() {
+		stats.Since = since
+	}
+	return stats, nil
+}
+
+// 读取累计统计快照
+func readLifetimeStats(path string) (LifetimeStats, error) {
+	var stats LifetimeStats
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return stats, err
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return stats, fmt.Errorf("解析累计统计失败: %w", err)
+	}
+	return stats, nil
+}
+
+// 加载累计统计：先读偏好设置，再读快照，都没有时从每次整理的统计重建
+func (fo *FileOrganizer) loadLifetimeStats() LifetimeStats {
+	if app := fyne.CurrentApp(); app != nil {
+		if data := app.Preferences().StringWithFallback("lifetime_stats", ""); data != "" {
+			var stats LifetimeStats
+			if err := json.Unmarshal([]byte(data), &stats); err == nil {
+				return stats
+			}
+			fo.log("偏好设置中的累计统计无法解析，改为读取快照")
+		}
+	}
+	stats, err := readLifetimeStats(lifetimeStatsPath())
+	if err == nil {
+		return stats
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		fo.log(err.Error())
+	}
+	stats, err = rebuildLifetimeStats(runStatsDir(), time.Time{})
+	if err != nil {
+		fo.log(err.Error())
+	} else if stats.Runs > 0 {
+		fo.log(fmt.Sprintf("已从 %d 次整理的统计重建累计统计", stats.Runs))
+	}
+	return stats
+}
+
+// 保存累计统计到偏好设置和快照
+func (fo *FileOrganizer) saveLifetimeStats(stats LifetimeStats) {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		fo.log(fmt.Sprintf("保存累计统计失败: %v", err))
+		return
+	}
+	if app := fyne.CurrentApp(); app != nil {
+		app.Preferences().SetString("lifetime_stats", string(data))
+	}
+	if err := os.MkdirAll(runStatsDir(), 0755); err != nil {
+		fo.log(fmt.Sprintf("创建统计文件夹失败: %v", err))
+		return
+	}
+	if err := os.WriteFile(lifetimeStatsPath(), data, 0644); err != nil {
+		fo.log(fmt.Sprintf("保存累计统计快照失败: %v", err))
+	}
+}
+
+// 整理结束时把这次的统计累加到累计统计。累计统计丢失时先从历史重建，
+// 重建结果已经包含这次整理刚保存的统计，不再重复累加
+func (fo *FileOrganizer) recordLifetimeStats(run RunStats) {
+	lifetimeStatsMu.Lock()
+	defer lifetimeStatsMu.Unlock()
+	stats := fo.loadLifetimeStats()
+	if stats.UpdatedAt.Before(run.FinishedAt) {
+		stats.add(run)
+	}
+	fo.saveLifetimeStats(stats)
+}
+
+// 描述累计统计，用于对话框
+func describeLifetimeStats(stats LifetimeStats) string {
+	if stats.Runs == 0 {
+		if stats.Since.IsZero() {
+			return "还没有整理记录"
+		}
+		return fmt.Sprintf("自 %s 起还没有整理记录", stats.Since.Format("2006-01-02 15:04"))
+	}
+	text := fmt.Sprintf("自 %s 起共整理 %d 次\n\n", stats.Since.Format("2006-01-02 15:04"), stats.Runs)
+	text += fmt.Sprintf("移动的文件: %d 个（%s）\n", stats.Files, formatFileSize(stats.Bytes))
+	text += fmt.Sprintf("目标中已存在而跳过的文件: %d 个\n", stats.Duplicates)
+	text += fmt.Sprintf("在目标中重新归档的文件: %d 个\n", stats.Refiled)
+	text += fmt.Sprintf("失败的文件: %d 个\n", stats.Errors)
+	text += fmt.Sprintf("整理用时: %s\n", (time.Duration(stats.DurationSeconds) * time.Second).String())
+	text += fmt.Sprintf("估计节省的时间: %s（按手动整理每个文件 %d 秒估算）\n", stats.timeSaved().Round(time.Minute).String(), manualSecondsPerFile)
+	if stats.BiggestRun.Files > 0 {
+		text += fmt.Sprintf("\n最大的一次整理: %s 移动了 %d 个文件（%s）到 %s",
+			stats.BiggestRun.StartedAt.Format("2006-01-02 15:04"), stats.BiggestRun.Files,
+			formatFileSize(stats.BiggestRun.Bytes), stats.BiggestRun.TargetDir)
+	}
+	return text
+}
+
+// 显示累计统计，可以重置或从每次整理的统计重建
+func (fo *FileOrganizer) showLifetimeStatsDialog() {
+	lifetimeStatsMu.Lock()
+	stats := fo.loadLifetimeStats()
+	lifetimeStatsMu.Unlock()
+
+	label := widget.NewLabel(describeLifetimeStats(stats))
+	label.Wrapping = fyne.TextWrapWord
+
+	var statsDialog dialog.Dialog
+	closeBtn := widget.NewButton("关闭", func() {
+		statsDialog.Hide()
+	})
+	rebuildBtn := widget.NewButton("从历史重建", func() {
+		lifetimeStatsMu.Lock()
+		defer lifetimeStatsMu.Unlock()
+		// 重置过的统计只从重置之后的整理重建
+		rebuilt, err := rebuildLifetimeStats(runStatsDir(), fo.loadLifetimeStats().Since)
+		if err != nil {
+			dialog.ShowError(err, fo.Window)
+			return
+		}
+		fo.saveLifetimeStats(rebuilt)
+		label.SetText(describeLifetimeStats(rebuilt))
+		fo.log(fmt.Sprintf("已从 %d 次整理的统计重建累计统计", rebuilt.Runs))
+	})
+	resetBtn := widget.NewButton("重置", func() {
+		dialog.ShowConfirm("重置累计统计", "累计统计将从现在开始重新计算，每次整理的统计文件保留不变。确定继续吗？", func(ok bool) {
+			if !ok {
+				return
+			}
+			lifetimeStatsMu.Lock()
+			defer lifetimeStatsMu.Unlock()
+			now := time.Now()
+			reset := LifetimeStats{Since: now, UpdatedAt: now}
+			fo.saveLifetimeStats(reset)
+			label.SetText(describeLifetimeStats(reset))
+			fo.log("已重置累计统计")
+		}, fo.Window)
+	})
+
+	content := container.NewBorder(nil,
+		container.NewHBox(layout.NewSpacer(), resetBtn, rebuildBtn, closeBtn),
+		nil, nil,
+		label,
+	)
+	statsDialog = dialog.NewCustomWithoutButtons("累计统计", content, fo.Window)
+	statsDialog.Resize(fyne.NewSize(520, 380))
+	fo.showDialog(statsDialog, closeBtn)
+}
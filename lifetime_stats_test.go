@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// 按开始时间写入一组每次整理的统计
+func writeRunHistory(t *testing.T, dir string, runs []RunStats) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, run := range runs {
+		path := filepath.Join(dir, fmt.Sprintf("stats_%s.json", run.StartedAt.Format("20060102_150405")))
+		if err := writeRunStats(path, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// 从合成的历史重建累计统计：汇总各项数量并找出最大的一次整理，重置时间之前的整理和无法解析的文件不计入
+func TestRebuildLifetimeStats(t *testing.T) {
+	day := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	history := []RunStats{
+		{StartedAt: day, TargetDir: "/a", Files: 10, Bytes: 1000, Duplicates: 2, Errors: 1, DurationSeconds: 4},
+		{StartedAt: day.Add(24 * time.Hour), TargetDir: "/b", Files: 30, Bytes: 500, Refiled: StatCounter{Files: 3}, DurationSeconds: 6},
+		{StartedAt: day.Add(48 * time.Hour), TargetDir: "/c", Files: 20, Bytes: 9000, Duplicates: 1},
+		{StartedAt: day.Add(72 * time.Hour), TargetDir: "/d"},
+	}
+	tests := []struct {
+		name        string
+		since       time.Time
+		want        LifetimeStats
+		wantBiggest string
+	}{
+		{"全部历史", time.Time{}, LifetimeStats{Runs: 4, Files: 60, Bytes: 10500, Duplicates: 3, Refiled: 3, Errors: 1,
+			DurationSeconds: 10, Since: day}, "/b"},
+		{"重置之后", day.Add(36 * time.Hour), LifetimeStats{Runs: 2, Files: 20, Bytes: 9000, Duplicates: 1,
+			Since: day.Add(36 * time.Hour)}, "/c"},
+		{"重置时间正好是开始时间", day.Add(24 * time.Hour), LifetimeStats{Runs: 3, Files: 50, Bytes: 9500, Duplicates: 1, Refiled: 3,
+			DurationSeconds: 6, Since: day.Add(24 * time.Hour)}, "/b"},
+		{"重置后没有整理", day.Add(96 * time.Hour), LifetimeStats{Since: day.Add(96 * time.Hour)}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeRunHistory(t, dir, history)
+			writeTestFile(t, filepath.Join(dir, "stats_broken.json"), "{")
+			writeTestFile(t, filepath.Join(dir, "lifetime.json"), `{"runs": 99}`)
+
+			got, err := rebuildLifetimeStats(dir, tt.since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.BiggestRun.TargetDir != tt.wantBiggest {
+				t.Fatalf("最大的一次整理 = %+v, 期望 %s", got.BiggestRun, tt.wantBiggest)
+			}
+			got.BiggestRun, got.UpdatedAt = BiggestRun{}, time.Time{}
+			if !got.Since.Equal(tt.want.Since) {
+				t.Fatalf("开始累计的时间 = %v, 期望 %v", got.Since, tt.want.Since)
+			}
+			got.Since, tt.want.Since = time.Time{}, time.Time{}
+			if got != tt.want {
+				t.Fatalf("累计统计 = %+v, 期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 整理结束时累加到已有的累计统计；偏好设置丢失时使用快照，都丢失时从历史重建，
+// 这次整理已在历史中，不重复累加
+func TestRecordLifetimeStats(t *testing.T) {
+	finished := time.Now()
+	earlier := RunStats{StartedAt: finished.Add(-2 * time.Hour), FinishedAt: finished.Add(-2*time.Hour + time.Minute), Files: 10, Bytes: 100}
+	run := RunStats{StartedAt: finished.Add(-time.Minute), FinishedAt: finished, Files: 4, Bytes: 40}
+	saved := LifetimeStats{Runs: 5, Files: 50, Bytes: 500, Since: earlier.StartedAt, UpdatedAt: earlier.FinishedAt}
+	tests := []struct {
+		name      string
+		prefs     bool // 偏好设置中有累计统计
+		snapshot  bool // 统计文件夹中有快照
+		wantRuns  int
+		wantFiles int
+	}{
+		{"累加到偏好设置中的统计", true, true, 6, 54},
+		{"偏好设置丢失时使用快照", false, true, 6, 54},
+		{"都丢失时从历史重建", false, false, 2, 14},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			writeRunHistory(t, runStatsDir(), []RunStats{earlier, run})
+			data, err := json.Marshal(saved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.prefs {
+				fyne.CurrentApp().Preferences().SetString("lifetime_stats", string(data))
+			}
+			if tt.snapshot {
+				writeTestFile(t, lifetimeStatsPath(), string(data))
+			}
+
+			fo.recordLifetimeStats(run)
+			if stats := fo.loadLifetimeStats(); stats.Runs != tt.wantRuns || stats.Files != tt.wantFiles {
+				t.Fatalf("累计统计 = %d 次 %d 个文件, 期望 %d 次 %d 个文件", stats.Runs, stats.Files, tt.wantRuns, tt.wantFiles)
+			}
+			// 再次记录同一次整理不会重复累加
+			fo.recordLifetimeStats(run)
+			snapshot, err := readLifetimeStats(lifetimeStatsPath())
+			if err != nil || snapshot.Runs != tt.wantRuns || snapshot.Files != tt.wantFiles {
+				t.Fatalf("快照 = %+v, %v", snapshot, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 同类日志合并的时间窗口
+const logCoalesceWindow = 5 * time.Second
+
+// 同时跟踪的同类日志组数上限，超过时最早的组会被提前输出
+const logCoalesceMaxGroups = 256
+
+// 日志中的绝对路径（Unix 路径或 Windows 盘符路径），到冒号或行尾为止
+var logPathPattern = regexp.MustCompile(`(?:[A-Za-z]:\\|/)[^:\n]*`)
+
+// 工作协程编号前缀，不同协程的同类错误视为相同
+var logWorkerPrefixPattern = regexp.MustCompile(`^\[工作协程 \d+\] `)
+
+// logGroup 一组同类日志
+type logGroup struct {
+	head      string // 路径前面的消息部分
+	example   string // 第一条日志中的路径
+	count     int
+	firstSeen time.Time
+}
+
+// logCoalescer 将时间窗口内去掉路径后相同的错误日志合并为一行带计数的日志
+type logCoalescer struct {
+	window    time.Duration
+	maxGroups int
+	groups    map[string]*logGroup
+	order     []string // 按首次出现顺序排列的组
+}
+
+// 创建日志合并器
+func newLogCoalescer(window time.Duration, maxGroups int) *logCoalescer {
+	return &logCoalescer{
+		window:    window,
+		maxGroups: maxGroups,
+		groups:    make(map[string]*logGroup),
+	}
+}
+
+// 是否为需要合并的日志（只合并错误和警告，正常的移动记录保持原样）
+func isCoalescableLog(line string) bool {
+	return strings.Contains(line, "失败") || strings.Contains(line, "错误") ||
+		strings.Contains(line, "出错") || strings.Contains(line, "警告")
+}
+
+// 计算日志的合并键：去掉协程编号，并把路径替换为占位符
+func logCoalesceKey(line string) (key, head, example string) {
+	line = logWorkerPrefixPattern.ReplaceAllString(line, "")
+	loc := logPathPattern.FindStringIndex(line)
+	if loc == nil {
+		return line, line, ""
+	}
+	key = logPathPattern.ReplaceAllString(line, "<路径>")
+	head = strings.TrimSpace(line[:loc[0]])
+	if head == "" {
+		head = key
+	}
+	return key, head, line[loc[0]:loc[1]]
+}
+
+// 添加一段日志（可能包含多行），返回需要立即显示的内容
+func (lc *logCoalescer) Add(text string, now time.Time) string {
+	var out strings.Builder
+	lines := strings.SplitAfter(text, "\n")
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		content := strings.TrimSuffix(line, "\n")
+		if !isCoalescableLog(content) {
+			out.WriteString(line)
+			continue
+		}
+
+		key, head, example := logCoalesceKey(content)
+		if example == "" {
+			// 没有路径的日志无法归类，直接显示
+			out.WriteString(line)
+			continue
+		}
+
+		if group, ok := lc.groups[key]; ok && now.Sub(group.firstSeen) < lc.window {
+			group.count++
+			continue
+		} else if ok {
+			// 窗口已过期，先输出旧组再开始新组
+			out.WriteString(lc.flushGroup(key))
+		}
+
+		// 每组的第一条日志原样显示，后续同类日志只计数
+		if len(lc.order) >= lc.maxGroups {
+			out.WriteString(lc.flushGroup(lc.order[0]))
+		}
+		lc.groups[key] = &logGroup{head: head, example: example, count: 1, firstSeen: now}
+		lc.order = append(lc.order, key)
+		out.WriteString(line)
+	}
+	return out.String()
+}
+
+// 输出已超过时间窗口的日志组；all为true时输出所有组
+func (lc *logCoalescer) Flush(now time.Time, all bool) string {
+	var out strings.Builder
+	for len(lc.order) > 0 {
+		key := lc.order[0]
+		if !all && now.Sub(lc.groups[key].firstSeen) < lc.window {
+			break
+		}
+		out.WriteString(lc.flushGroup(key))
+	}
+	return out.String()
+}
+
+// 移除日志组，如果有被合并的日志则返回汇总行
+func (lc *logCoalescer) flushGroup(key string) string {
+	group := lc.groups[key]
+	delete(lc.groups, key)
+	for i, k := range lc.order {
+		if k == key {
+			lc.order = append(lc.order[:i], lc.order[i+1:]...)
+			break
+		}
+	}
+	if group == nil || group.count <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("%s (同类错误 ×%s, 示例: %s)\n", group.head, formatCount(group.count), group.example)
+}
+
+// 格式化带千分位的数字
+func formatCount(n int) string {
+	digits := fmt.Sprintf("%d", n)
+	if len(digits) <= 3 {
+		return digits
+	}
+	var out strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out.WriteByte(',')
+		}
+		out.WriteRune(c)
+	}
+	return out.String()
+}
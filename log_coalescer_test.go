@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 时间窗口内去掉路径后相同的错误合并为一行带计数的日志，正常的日志和没有路径的错误原样显示
+func TestLogCoalescer(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	denied := func(i int) string {
+		return fmt.Sprintf("获取文件信息失败 /photos/%d.jpg: permission denied\n", i)
+	}
+	tests := []struct {
+		name      string
+		lines     []string
+		gap       time.Duration // 相邻两条日志的间隔
+		maxGroups int
+		want      string // 逐条添加并最后全部输出后显示的内容
+	}{
+		{"正常日志不合并", []string{"移动 /a.jpg -> /b/a.jpg\n", "移动 /a.jpg -> /b/a.jpg\n"}, 0, 4,
+			"移动 /a.jpg -> /b/a.jpg\n移动 /a.jpg -> /b/a.jpg\n"},
+		{"没有路径的错误不合并", []string{"整理出错: 目标已锁定\n", "整理出错: 目标已锁定\n"}, 0, 4,
+			"整理出错: 目标已锁定\n整理出错: 目标已锁定\n"},
+		{"同类错误合并", []string{denied(1), denied(2), denied(3)}, 0, 4,
+			denied(1) + "获取文件信息失败 (同类错误 ×3, 示例: /photos/1.jpg)\n"},
+		{"不同协程的同类错误合并", []string{"[工作协程 1] 移动失败 /a.jpg: busy\n", "[工作协程 2] 移动失败 /b.jpg: busy\n"}, 0, 4,
+			"[工作协程 1] 移动失败 /a.jpg: busy\n移动失败 (同类错误 ×2, 示例: /a.jpg)\n"},
+		{"超过时间窗口重新开始", []string{denied(1), denied(2), denied(3)}, logCoalesceWindow, 4,
+			denied(1) + denied(2) + denied(3)},
+		{"超过组数上限时提前输出最早的组",
+			[]string{denied(1), denied(2), "移动失败 /x/a.jpg: busy\n", "复制失败 /y/b.jpg: busy\n"}, 0, 2,
+			denied(1) + "移动失败 /x/a.jpg: busy\n" + "获取文件信息失败 (同类错误 ×2, 示例: /photos/1.jpg)\n" + "复制失败 /y/b.jpg: busy\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc := newLogCoalescer(logCoalesceWindow, tt.maxGroups)
+			var out strings.Builder
+			now := start
+			for _, line := range tt.lines {
+				out.WriteString(lc.Add(line, now))
+				now = now.Add(tt.gap)
+			}
+			out.WriteString(lc.Flush(now, true))
+			if got := out.String(); got != tt.want {
+				t.Fatalf("显示的日志 =\n%s\n期望\n%s", got, tt.want)
+			}
+			if len(lc.groups) != 0 || len(lc.order) != 0 {
+				t.Fatalf("全部输出后仍有 %d 组", len(lc.groups))
+			}
+		})
+	}
+}
+
+// 大量不同的错误只保留有限的组，内存不随日志数量增长
+func TestLogCoalescerBounded(t *testing.T) {
+	lc := newLogCoalescer(logCoalesceWindow, 8)
+	now := time.Now()
+	for i := 0; i < 1000; i++ {
+		lc.Add(fmt.Sprintf("错误%d /a/%d.jpg\n", i, i), now)
+		if len(lc.groups) > 8 || len(lc.order) > 8 {
+			t.Fatalf("第 %d 条后有 %d 组", i, len(lc.groups))
+		}
+	}
+	if got := lc.Flush(now.Add(logCoalesceWindow), false); got != "" || len(lc.order) != 0 {
+		t.Fatalf("窗口过期后应清空没有合并的组: %q, 剩余 %d 组", got, len(lc.order))
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{{0, "0"}, {999, "999"}, {1000, "1,000"}, {1243, "1,243"}, {1234567, "1,234,567"}}
+	for _, tt := range tests {
+		if got := formatCount(tt.n); got != tt.want {
+			t.Errorf("formatCount(%d) = %q, 期望 %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 文件名Unicode规范化形式
+const (
+	NormalizationNone = "none"
+	NormalizationNFC  = "NFC" // Windows 和 Linux 上常见的组合形式
+	NormalizationNFD  = "NFD" // macOS 文件系统使用的分解形式
+)
+
+// 获取设置对应的规范化形式，不处理时返回false
+func normalizationForm(setting string) (norm.Form, bool) {
+	switch setting {
+	case NormalizationNFC:
+		return norm.NFC, true
+	case NormalizationNFD:
+		return norm.NFD, true
+	}
+	return norm.NFC, false
+}
+
+// 开启规范化时把文件名统一为所选形式，未开启时原样返回
+func (fo *FileOrganizer) normalizeName(name string) string {
+	if form, ok := normalizationForm(fo.UnicodeNormalization); ok {
+		return form.String(name)
+	}
+	return name
+}
+
+// 开启规范化时把目标文件夹中root之下的各级文件夹名统一为所选形式，root本身是用户选择的路径，保持原样
+func (fo *FileOrganizer) normalizeTargetDir(dir, root string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return dir
+	}
+	return filepath.Join(root, fo.normalizeName(rel))
+}
+
+// normalizedNameIndex 记录目标文件夹中已有文件名的规范化形式，
+// 用于发现只有Unicode编码不同、看起来完全相同的文件名
+type normalizedNameIndex struct {
+	mu   sync.Mutex
+	dirs map[string]map[string]bool
+}
+
+// 创建文件名索引
+func newNormalizedNameIndex() *normalizedNameIndex {
+	return &normalizedNameIndex{dirs: make(map[string]map[string]bool)}
+}
+
+// 检查文件夹中是否已有规范化后相同的文件名，首次访问文件夹时读取其内容
+func (idx *normalizedNameIndex) contains(dir, name string, form norm.Form) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.load(dir, form)[form.String(name)]
+}
+
+// 记录新放入文件夹的文件名
+func (idx *normalizedNameIndex) add(dir, name string, form norm.Form) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.load(dir, form)[form.String(name)] = true
+}
+
+// 丢弃某个文件夹的索引，下次访问时重新读取
+func (idx *normalizedNameIndex) forget(dir string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.dirs, dir)
+}
+
+// 清空所有索引
+func (idx *normalizedNameIndex) reset() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.dirs = make(map[string]map[string]bool)
+}
+
+// 获取文件夹的索引（调用方需持有锁）
+func (idx *normalizedNameIndex) load(dir string, form norm.Form) map[string]bool {
+	names, ok := idx.dirs[dir]
+	if ok {
+		return names
+	}
+	names = make(map[string]bool)
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			names[form.String(entry.Name())] = true
+		}
+	}
+	idx.dirs[dir] = names
+	return names
+}
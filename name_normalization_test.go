@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 开启规范化时计划中的文件名和规则、源文件夹带来的文件夹名都统一为所选形式，不只在同名冲突时处理
+func TestNormalizePlannedNames(t *testing.T) {
+	const (
+		nfcName = "Caf\u00e9.jpg"
+		nfdName = "Cafe\u0301.jpg"
+		nfcDir  = "R\u00e9sum\u00e9"
+		nfdDir  = "Re\u0301sume\u0301"
+	)
+	tests := []struct {
+		name     string
+		setting  string
+		existing string // 目标中已有的文件，相对路径
+		want     map[string]string
+	}{
+		{"不处理", NormalizationNone, "", map[string]string{filepath.Join(nfdDir, ".jpg", nfdName): "photo"}},
+		{"NFC", NormalizationNFC, "", map[string]string{filepath.Join(nfcDir, ".jpg", nfcName): "photo"}},
+		{"NFD", NormalizationNFD, "", map[string]string{filepath.Join(nfdDir, ".jpg", nfdName): "photo"}},
+		{"已有NFC文件夹，不再建立NFD文件夹", NormalizationNFC, filepath.Join(nfcDir, ".jpg", "old.jpg"),
+			map[string]string{filepath.Join(nfcDir, ".jpg", "old.jpg"): "old", filepath.Join(nfcDir, ".jpg", nfcName): "photo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			fo.UnicodeNormalization = tt.setting
+			source := t.TempDir()
+			target := t.TempDir()
+			file := writeTestFile(t, filepath.Join(source, nfdDir, nfdName), "photo")
+			if tt.existing != "" {
+				writeTestFile(t, filepath.Join(target, tt.existing), "old")
+			}
+			config := Config{
+				SourceDir:      source,
+				SourceDirs:     []string{source},
+				TargetDir:      target,
+				FileExtensions: []string{".jpg"},
+				OrganizeRule:   string(RuleByExtension),
+				FolderLayout:   LayoutSourceFirst,
+				ExtensionCase:  "lowercase",
+				ConflictPolicy: ConflictRename,
+				ExcludedFiles:  map[string]bool{},
+			}
+			info, err := os.Stat(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan := fo.planFile(file, info, config, planContext{})
+			if plan.Skip != "" {
+				t.Fatalf("计划 = %+v", plan)
+			}
+			if summary, err := fo.processFiles(config, []string{file}); err != nil || summary.Failed != 0 {
+				t.Fatalf("整理: %+v, %v", summary, err)
+			}
+			got := snapshotTree(t, target)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("目标文件夹 = %q, 期望 %q", got, tt.want)
+			}
+			planned, _ := filepath.Rel(target, filepath.Join(plan.TargetDir, plan.TargetName))
+			if _, ok := got[planned]; !ok {
+				t.Fatalf("计划的目标 %q 与整理结果不同", planned)
+			}
+		})
+	}
+}
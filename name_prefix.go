@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 文件名前缀和原文件名之间的分隔符，例如 2024-01-15_photo.jpg
+const namePrefixSeparator = "_"
+
+// 文件名前缀模式下规则文件夹名称转换成的前缀。规则文件夹有多级时（例如发件人域名/日期）用分隔符连接；
+// 去掉开头的点，按后缀整理时前缀是 jpg 而不是会隐藏文件的 .jpg
+func ruleNamePrefix(ruleFolder string) string {
+	var parts []string
+	for _, part := range strings.FieldsFunc(ruleFolder, func(r rune) bool {
+		return r == '/' || r == '\\'
+	}) {
+		if part = strings.TrimLeft(part, "."); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, namePrefixSeparator)
+}
+
+// 加上前缀后的文件名。文件名已经以该前缀开头时保持不变，重复整理不会叠加前缀
+func prefixedFileName(prefix, name string) string {
+	if prefix == "" || strings.HasPrefix(name, prefix+namePrefixSeparator) {
+		return name
+	}
+	return prefix + namePrefixSeparator + name
+}
+
+// 其他规则生成的、与当前前缀形式不同时也能识别的前缀：日期（2024、2024-01、2024-01-15、
+// 按年/月分层的 2024_01）和文件本身的后缀（jpg，或按数量编号的 01_jpg）
+var datePrefixPattern = regexp.MustCompile(`^\d{4}(?:[-._]\d{1,2}){0,2}` + regexp.QuoteMeta(namePrefixSeparator))
+
+// 去掉文件名开头以前整理时加上的前缀。以前的前缀与当前前缀形式相同、只有数字不同，
+// 例如后缀重新编号后的 01_jpg 和 02_jpg，或者日期不同的 2024-01 和 2024-02；
+// 也去掉按日期或按后缀整理时加上的前缀，规则在两者之间切换时不会叠加
+func stripNamePrefix(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	if stripped := stripPrefixShape(prefix, name); stripped != name {
+		return stripped
+	}
+	if ext := strings.TrimLeft(strings.ToLower(fileExtension(name)), "."); ext != "" {
+		if stripped := stripPrefixShape("0_"+ext, name); stripped != name {
+			return stripped
+		}
+		if stripped := stripPrefixShape(ext, name); stripped != name {
+			return stripped
+		}
+	}
+	if stripped := datePrefixPattern.ReplaceAllString(name, ""); stripped != "" {
+		return stripped
+	}
+	return name
+}
+
+// 去掉与给定前缀形式相同、只有数字不同的前缀，前缀中的字母不区分大小写
+func stripPrefixShape(prefix, name string) string {
+	var pattern strings.Builder
+	pattern.WriteString(`(?i)^`)
+	inDigits := false
+	for _, r := range prefix {
+		if unicode.IsDigit(r) {
+			if !inDigits {
+				pattern.WriteString(`\d+`)
+			}
+			inDigits = true
+			continue
+		}
+		inDigits = false
+		pattern.WriteString(regexp.QuoteMeta(string(r)))
+	}
+	pattern.WriteString(regexp.QuoteMeta(namePrefixSeparator))
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return name
+	}
+	// 去掉前缀后没有剩下原文件名时保持不变
+	if stripped := re.ReplaceAllString(name, ""); stripped != "" && stripped != name {
+		return stripped
+	}
+	return name
+}
+
+// 已在目标文件夹中的文件去掉以前整理时加上的前缀。整理目录中记录了所加的前缀时去掉这个前缀，
+// 规则变化后（例如从按日期改为按模板）任何形式的前缀都能去掉；没有记录时按前缀的形式识别
+func (c Config) stripPreviousPrefix(filePath, prefix, name string) string {
+	if recorded := c.NamePrefixes[filePath]; recorded != "" {
+		head := recorded + namePrefixSeparator
+		if len(name) > len(head) && strings.EqualFold(name[:len(head)], head) {
+			return name[len(head):]
+		}
+	}
+	return stripNamePrefix(prefix, name)
+}
+
+// 文件名前缀模式下文件在目标文件夹中的名称和所加的前缀，无法确定规则文件夹时返回空字符串。
+// 已在目标文件夹中的文件先去掉以前的前缀，规则变化后重新整理不会叠加前缀
+func (fo *FileOrganizer) prefixedTargetName(filePath string, fileInfo os.FileInfo, config Config) (name, prefix string) {
+	ruleFolder := fo.ruleFolderName(filePath, fileInfo, config)
+	if ruleFolder == "" {
+		return "", ""
+	}
+	prefix = ruleNamePrefix(ruleFolder)
+	name = filepath.Base(filePath)
+	if isRefile(filePath, config) {
+		name = config.stripPreviousPrefix(filePath, prefix, name)
+	}
+	return prefixedFileName(prefix, name), prefix
+}
+
+// 读取整理目录中位于目标文件夹中的文件记录的文件名前缀（最终路径 → 前缀），同一个路径以最后一次整理为准
+func loadNamePrefixes(path, targetDir string) (map[string]string, error) {
+	low, high := pathPrefixRange(targetDir)
+	entries, err := queryCatalog(path, "member = '' AND final_path >= ? AND final_path < ?", "", 0, low, high)
+	prefixes := make(map[string]string)
+	for _, entry := range entries {
+		if !isWithinAny(entry.FinalPath, []string{targetDir}) {
+			continue
+		}
+		if entry.NamePrefix == "" {
+			delete(prefixes, entry.FinalPath)
+			continue
+		}
+		prefixes[entry.FinalPath] = entry.NamePrefix
+	}
+	return prefixes, err
+}
+
+// 在同一文件夹内把文件改为指定的名称，目标名称已存在时追加时间戳
+func (fo *FileOrganizer) renameInPlace(path, name string) (string, error) {
+	if err := fo.checkWritable(); err != nil {
+		return "", err
+	}
+	if err := checkFileName(name); err != nil {
+		return "", err
+	}
+	targetPath := fo.uniqueTargetPath(filepath.Dir(path), name)
+	if err := renameFile(path, targetPath); err != nil {
+		return "", fmt.Errorf("添加文件名前缀失败: %w", err)
+	}
+	return targetPath, nil
+}
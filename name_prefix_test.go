@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 去掉与当前前缀形式相同的前缀，以及按日期或按后缀整理时加上的前缀，原文件名中的其他部分保持不变
+func TestStripNamePrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		name   string
+		want   string
+	}{
+		{"02_jpg", "01_jpg_a.jpg", "a.jpg"},
+		{"02_JPG", "01_jpg_a.jpg", "a.jpg"},
+		{"02_jpg", "01_png_a.jpg", "01_png_a.jpg"},
+		{"2024-02", "2024-01_trip.jpg", "trip.jpg"},
+		{"2024-02", "2024-01-05_trip.jpg", "trip.jpg"},
+		{"jpg", "jpg_a.jpg", "a.jpg"},
+		{"jpg", "2024-01-15_photo.jpg", "photo.jpg"},
+		{"jpg", "2024_01_photo.jpg", "photo.jpg"},
+		{"2024-01", "jpg_photo.jpg", "photo.jpg"},
+		{"2024-01", "03_JPG_photo.jpg", "photo.jpg"},
+		{"2024-01", "png_photo.jpg", "png_photo.jpg"},
+		{"jpg", "20240115_101500.jpg", "20240115_101500.jpg"},
+		{"照片", "旅行_photo.jpg", "旅行_photo.jpg"},
+		{"02_jpg", "01_jpg_", "01_jpg_"},
+		{"", "01_jpg_a.jpg", "01_jpg_a.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix+"/"+tt.name, func(t *testing.T) {
+			if got := stripNamePrefix(tt.prefix, tt.name); got != tt.want {
+				t.Fatalf("stripNamePrefix(%q, %q) = %q, 期望 %q", tt.prefix, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// 后缀重新编号后再整理目标文件夹，文件名换成新的前缀而不是叠加；从源文件夹整理来的文件保留原名
+func TestNamePrefixRerank(t *testing.T) {
+	tests := []struct {
+		name   string
+		refile bool
+		file   string
+		want   string
+	}{
+		{"重新编号后重新整理", true, "01_jpg_a.jpg", "02_jpg_a.jpg"},
+		{"已是当前前缀", true, "02_jpg_a.jpg", "02_jpg_a.jpg"},
+		{"目标中没有前缀的文件", true, "a.jpg", "02_jpg_a.jpg"},
+		{"源文件夹中的文件保留原名", false, "01_jpg_a.jpg", "02_jpg_01_jpg_a.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			target := t.TempDir()
+			source := t.TempDir()
+			if tt.refile {
+				source = target
+			}
+			file := writeTestFile(t, filepath.Join(source, tt.file), "photo")
+			config := Config{
+				SourceDir:      source,
+				SourceDirs:     []string{source},
+				TargetDir:      target,
+				FileExtensions: []string{".jpg"},
+				OrganizeRule:   string(RuleByExtension),
+				FolderLayout:   LayoutNamePrefix,
+				ExtensionCase:  "lowercase",
+				ExtensionRanks: map[string]int{".jpg": 2},
+				ConflictPolicy: ConflictRename,
+				ExcludedFiles:  map[string]bool{},
+			}
+			if summary, err := fo.processFiles(config, []string{file}); err != nil || summary.Failed != 0 {
+				t.Fatalf("整理: %+v, %v", summary, err)
+			}
+			if got, want := snapshotTree(t, target), map[string]string{tt.want: "photo"}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("目标文件夹 = %v, 期望 %v", got, want)
+			}
+		})
+	}
+}
+
+// 两次整理之间更换规则，第二次整理目标文件夹时换成新的前缀而不是叠加在以前的前缀上。
+// 模板生成的前缀形式任意，只有整理目录中记录了所加的前缀时才能去掉
+func TestNamePrefixRuleChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		catalog  bool
+		first    Config
+		second   Config
+		wantMid  string
+		wantLast string
+	}{
+		{"按日期改为按后缀", false,
+			Config{OrganizeRule: string(RuleByDate), FolderDateFormat: "YYYY-MM-DD"},
+			Config{OrganizeRule: string(RuleByExtension)},
+			"2024-01-15_photo.jpg", "jpg_photo.jpg"},
+		{"按后缀改为按日期", false,
+			Config{OrganizeRule: string(RuleByExtension), ExtensionRanks: map[string]int{".jpg": 1}},
+			Config{OrganizeRule: string(RuleByDate), FolderDateFormat: "YYYY-MM"},
+			"01_jpg_photo.jpg", "2024-01_photo.jpg"},
+		{"模板改为按后缀，整理目录中记录了前缀", true,
+			Config{OrganizeRule: string(RuleByExtension), FolderTemplate: "照片-{year}"},
+			Config{OrganizeRule: string(RuleByExtension)},
+			"照片-2024_photo.jpg", "jpg_photo.jpg"},
+		{"按日期改为模板，整理目录中记录了前缀", true,
+			Config{OrganizeRule: string(RuleByDate), FolderDateFormat: "YYYY-MM-DD"},
+			Config{OrganizeRule: string(RuleByDate), FolderTemplate: "{category}/{year}"},
+			"2024-01-15_photo.jpg", "图片_2024_photo.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			target := t.TempDir()
+			file := writeTestFile(t, filepath.Join(source, "photo.jpg"), "photo")
+			day := time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local)
+			if err := os.Chtimes(file, day, day); err != nil {
+				t.Fatal(err)
+			}
+			organize := func(config Config, dir, want string) {
+				t.Helper()
+				config.SourceDir, config.SourceDirs, config.TargetDir = dir, []string{dir}, target
+				config.FileExtensions = []string{".jpg"}
+				config.FolderLayout = LayoutNamePrefix
+				config.ExtensionCase = "lowercase"
+				config.ConflictPolicy = ConflictRename
+				config.CatalogEnabled = tt.catalog
+				config.ExcludedFiles = map[string]bool{}
+				files := treeFiles(t, dir)
+				for i, name := range files {
+					files[i] = filepath.Join(dir, filepath.FromSlash(name))
+				}
+				if summary, err := fo.processFiles(config, files); err != nil || summary.Failed != 0 {
+					t.Fatalf("整理: %+v, %v", summary, err)
+				}
+				if got, want := snapshotTree(t, target), map[string]string{want: "photo"}; !reflect.DeepEqual(got, want) {
+					t.Fatalf("目标文件夹 = %v, 期望 %v", got, want)
+				}
+			}
+			organize(tt.first, source, tt.wantMid)
+			organize(tt.second, target, tt.wantLast)
+		})
+	}
+}
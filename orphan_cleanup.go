@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 复制文件时先写到带这个后缀的临时文件，完成后改为最终的名称，中断的复制不会留下看似完整的文件
+const partialCopySuffix = ".fileorganizer.partial"
+
+// 保存数据时的临时文件（.tmp）和复制的部分文件超过这么久没有修改才视为残留，
+// 避免删掉正在写入的文件，例如监视模式正在复制的文件
+const orphanTempAge = 10 * time.Minute
+
+// 残留文件的类别
+const (
+	OrphanIndexResumable = "index_resumable" // 尚未建立完成、可以继续的目标文件索引
+	OrphanIndexDead      = "index_dead"      // 目标文件夹已不存在的索引
+	OrphanStaleLock      = "stale_lock"      // 心跳已失效的目标文件夹锁
+	OrphanPartial        = "partial"         // 中断的复制留下的部分文件
+	OrphanTemp           = "temp"            // 保存数据时中断留下的临时文件
+)
+
+// 残留文件的类别，按显示顺序排列
+var orphanCategories = []struct {
+	category string
+	name     string
+	hint     string
+}{
+	{OrphanPartial, "中断的复制留下的部分文件", "复制没有完成，删除不影响已整理的文件"},
+	{OrphanStaleLock, "失效的目标文件夹锁", "整理时程序崩溃或断网留下的锁，删除前会再次确认锁没有被刷新"},
+	{OrphanTemp, "中断保存留下的临时文件", "保存设置、索引或整理目录时中断留下的 .tmp 文件"},
+	{OrphanIndexDead, "已不存在的目标文件夹的索引", "目标文件夹已删除或移走，索引不再使用"},
+	{OrphanIndexResumable, "尚未建立完成的目标文件索引", "可以在「目标文件索引」中继续建立，删除后需要重新开始"},
+}
+
+// orphanArtifact 一个残留文件
+type orphanArtifact struct {
+	category string
+	path     string
+	size     int64
+	detail   string
+	root     string      // 锁和索引对应的目标文件夹
+	lock     *TargetLock // 失效的锁，删除前确认锁文件没有变化
+}
+
+// 扫描配置文件夹和目标文件夹中本程序留下的残留文件
+func (fo *FileOrganizer) scanOrphans(targetRoots []string) []orphanArtifact {
+	var found []orphanArtifact
+	now := time.Now()
+	building := ""
+	if b := fo.runningIndexBuilder(); b != nil {
+		building = b.root
+	}
+
+	// 配置文件夹中的目标文件索引和临时文件
+	entries, _ := os.ReadDir(appDataDir())
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(appDataDir(), entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(entry.Name(), ".tmp"):
+			if now.Sub(info.ModTime()) > orphanTempAge {
+				found = append(found, orphanArtifact{category: OrphanTemp, path: path, size: info.Size(),
+					detail: "修改于 " + info.ModTime().Format("2006-01-02 15:04")})
+			}
+		case strings.HasPrefix(entry.Name(), "target_index_") && strings.HasSuffix(entry.Name(), ".json"):
+			if artifact, ok := classifyTargetIndex(path, info, building); ok {
+				found = append(found, artifact)
+			}
+		}
+	}
+
+	// 目标文件夹中的锁和部分文件
+	for _, root := range targetRoots {
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			continue
+		}
+		lockPath := targetLockPath(root)
+		if holder, err := readTargetLock(lockPath); err == nil && holder.stale(now) {
+			info, _ := os.Stat(lockPath)
+			size := int64(0)
+			if info != nil {
+				size = info.Size()
+			}
+			found = append(found, orphanArtifact{category: OrphanStaleLock, path: lockPath, size: size,
+				detail: holder.describe(), root: root, lock: &holder})
+		}
+		if info, err := os.Stat(lockPath + ".tmp"); err == nil && now.Sub(info.ModTime()) > orphanTempAge {
+			found = append(found, orphanArtifact{category: OrphanTemp, path: lockPath + ".tmp", size: info.Size(),
+				detail: "修改于 " + info.ModTime().Format("2006-01-02 15:04")})
+		}
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(d.Name(), partialCopySuffix) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || now.Sub(info.ModTime()) <= orphanTempAge {
+				return nil
+			}
+			detail := "复制中断，没有完成的文件"
+			if _, err := os.Lstat(strings.TrimSuffix(path, partialCopySuffix)); err == nil {
+				detail = "最终文件已存在"
+			}
+			found = append(found, orphanArtifact{category: OrphanPartial, path: path, size: info.Size(), detail: detail})
+			return nil
+		})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].path < found[j].path })
+	return found
+}
+
+// 判断索引文件是否是残留：目标文件夹不存在时已失效；建立到一半（并且没有正在建立）时可以继续
+func classifyTargetIndex(path string, info os.FileInfo, building string) (orphanArtifact, bool) {
+	artifact := orphanArtifact{path: path, size: info.Size()}
+	var file targetHashIndexFile
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &file) != nil || file.Root == "" {
+		artifact.category = OrphanIndexDead
+		artifact.detail = "无法解析"
+		return artifact, true
+	}
+	artifact.root = file.Root
+	if targetIndexPath(file.Root) != path {
+		artifact.category = OrphanIndexDead
+		artifact.detail = file.Root + "（索引文件名与目标文件夹不对应）"
+		return artifact, true
+	}
+	if rootInfo, err := os.Stat(file.Root); err != nil || !rootInfo.IsDir() {
+		artifact.category = OrphanIndexDead
+		artifact.detail = file.Root
+		return artifact, true
+	}
+	if file.Build != nil && !file.Build.Complete && file.Root != building {
+		artifact.category = OrphanIndexResumable
+		artifact.detail = fmt.Sprintf("%s（开始于 %s）", file.Root, file.Build.Started.Format("2006-01-02 15:04"))
+		return artifact, true
+	}
+	return artifact, false
+}
+
+// 需要检查的目标文件夹：当前目标、溢出目标和配置方案中的目标
+func (fo *FileOrganizer) orphanTargetRoots() []string {
+	seen := make(map[string]bool)
+	var roots []string
+	add := func(root string) {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			return
+		}
+		root = filepath.Clean(root)
+		if !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+	}
+	targetDir := fo.TargetDir
+	if fo.TargetDirEntry != nil && strings.TrimSpace(fo.TargetDirEntry.Text) != "" {
+		targetDir = fo.TargetDirEntry.Text
+	}
+	add(targetDir)
+	for _, volume := range fo.SpillTargets {
+		add(volume.Root)
+	}
+	for _, profile := range fo.profiles {
+		add(profile.TargetDir)
+	}
+	return roots
+}
+
+// 删除一个残留文件。失效的锁按解除锁的方式删除，确认锁没有被其他电脑刷新；
+// 删除可以继续的索引时不再在启动时继续建立
+func removeOrphan(artifact orphanArtifact) error {
+	switch artifact.category {
+	case OrphanStaleLock:
+		return breakTargetLock(artifact.root, *artifact.lock)
+	case OrphanPartial:
+		// 扫描后开始的整理可能又在写入同名的部分文件
+		if info, err := os.Stat(artifact.path); err == nil && time.Since(info.ModTime()) <= orphanTempAge {
+			return fmt.Errorf("%s 正在写入，没有删除", artifact.path)
+		}
+	case OrphanIndexResumable:
+		prefs := fyne.CurrentApp().Preferences()
+		if prefs.StringWithFallback("target_index_build_root", "") == artifact.root {
+			prefs.SetString("target_index_build_root", "")
+		}
+	}
+	if err := os.Remove(artifact.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("删除 %s 失败: %w", artifact.path, err)
+	}
+	// 索引的进度日志随索引一起删除
+	if artifact.category == OrphanIndexResumable || artifact.category == OrphanIndexDead {
+		if artifact.root != "" {
+			os.Remove(targetIndexJournalPath(artifact.root))
+		}
+	}
+	return nil
+}
+
+// 清理残留文件：扫描后按类别列出，每个类别单独确认后删除
+func (fo *FileOrganizer) showOrphanCleanupDialog() {
+	if fo.refuseInReadOnlyMode() {
+		return
+	}
+	if fo.runActive() {
+		dialog.ShowInformation("提示", "正在整理文件，请等待整理完成后再清理", fo.Window)
+		return
+	}
+	roots := fo.orphanTargetRoots()
+	progress := dialog.NewCustomWithoutButtons("清理残留文件", widget.NewProgressBarInfinite(), fo.Window)
+	progress.Show()
+	go func() {
+		found := fo.scanOrphans(roots)
+		fo.safeUpdateUI(func() {
+			progress.Hide()
+			fo.showOrphanResults(roots, found)
+		})
+	}()
+}
+
+// 显示扫描到的残留文件
+func (fo *FileOrganizer) showOrphanResults(roots []string, found []orphanArtifact) {
+	byCategory := make(map[string][]orphanArtifact)
+	for _, artifact := range found {
+		byCategory[artifact.category] = append(byCategory[artifact.category], artifact)
+	}
+
+	checked := "配置文件夹 " + appDataDir()
+	if len(roots) > 0 {
+		checked += "\n目标文件夹 " + strings.Join(roots, "、")
+	}
+	summary := widget.NewLabel(fmt.Sprintf("检查了:\n%s", checked))
+	summary.Wrapping = fyne.TextWrapWord
+
+	var cleanupDialog dialog.Dialog
+	rows := container.NewVBox()
+	for _, c := range orphanCategories {
+		artifacts := byCategory[c.category]
+		if len(artifacts) == 0 {
+			continue
+		}
+		var size int64
+		for _, artifact := range artifacts {
+			size += artifact.size
+		}
+		name := c.name
+		label := widget.NewLabel(fmt.Sprintf("%s: %d 个（%s）\n%s", name, len(artifacts), formatFileSize(size), c.hint))
+		label.Wrapping = fyne.TextWrapWord
+		var cleanBtn *widget.Button
+		cleanBtn = widget.NewButton("清理...", func() {
+			lines := make([]string, 0, len(artifacts))
+			for i, artifact := range artifacts {
+				if i == 10 {
+					lines = append(lines, fmt.Sprintf("……等 %d 个", len(artifacts)))
+					break
+				}
+				lines = append(lines, fmt.Sprintf("%s（%s）", artifact.path, artifact.detail))
+			}
+			dialog.ShowConfirm("清理"+name, fmt.Sprintf("将删除以下 %d 个文件：\n\n%s", len(artifacts), strings.Join(lines, "\n")),
+				func(ok bool) {
+					if !ok {
+						return
+					}
+					if fo.runActive() {
+						dialog.ShowInformation("提示", "正在整理文件，请等待整理完成后再清理", fo.Window)
+						return
+					}
+					removed := 0
+					for _, artifact := range artifacts {
+						if err := removeOrphan(artifact); err != nil {
+							fo.log("清理残留文件: " + err.Error())
+							continue
+						}
+						removed++
+					}
+					fo.log(fmt.Sprintf("清理残留文件: 删除了 %d 个%s", removed, name))
+					cleanBtn.Disable()
+					label.SetText(fmt.Sprintf("%s: 已删除 %d 个", name, removed))
+				}, fo.Window)
+		})
+		rows.Add(container.NewBorder(nil, nil, nil, cleanBtn, label))
+	}
+	if len(rows.Objects) == 0 {
+		rows.Add(widget.NewLabel("没有发现残留文件"))
+	}
+
+	closeBtn := widget.NewButton("关闭", func() {
+		cleanupDialog.Hide()
+	})
+	content := container.NewBorder(summary,
+		container.NewHBox(layout.NewSpacer(), closeBtn),
+		nil, nil,
+		container.NewVScroll(rows),
+	)
+	cleanupDialog = dialog.NewCustomWithoutButtons("清理残留文件", content, fo.Window)
+	cleanupDialog.Resize(fyne.NewSize(620, 420))
+	fo.showDialog(cleanupDialog, closeBtn)
+}
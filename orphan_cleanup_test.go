@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 部分文件很久没有修改才是中断的复制留下的；正在写入的部分文件（例如监视模式正在复制）不列出也不删除
+func TestOrphanPartialFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		age         time.Duration // 扫描时部分文件距上次修改的时间
+		touch       bool          // 扫描后删除前部分文件又被写入
+		wantFound   bool
+		wantRemoved bool
+	}{
+		{"中断的复制", time.Hour, false, true, true},
+		{"正在写入", time.Minute, false, false, false},
+		{"扫描后又开始写入", time.Hour, true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			target := t.TempDir()
+			partial := writeTestFile(t, filepath.Join(target, ".jpg", "a.jpg"+partialCopySuffix), "part")
+			modTime := time.Now().Add(-tt.age)
+			if err := os.Chtimes(partial, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+
+			var found []orphanArtifact
+			for _, artifact := range fo.scanOrphans([]string{target}) {
+				if artifact.category == OrphanPartial {
+					found = append(found, artifact)
+				}
+			}
+			if (len(found) == 1) != tt.wantFound {
+				t.Fatalf("列出的部分文件 = %v, 期望列出 = %v", found, tt.wantFound)
+			}
+			if !tt.wantFound {
+				return
+			}
+			if tt.touch {
+				writeTestFile(t, partial, "more")
+			}
+			err := removeOrphan(found[0])
+			_, statErr := os.Stat(partial)
+			if removed := os.IsNotExist(statErr); removed != tt.wantRemoved || (err == nil) != tt.wantRemoved {
+				t.Fatalf("已删除 = %v (%v), 期望 %v", removed, err, tt.wantRemoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+)
+
+// Outlook 的 .msg 文件是 OLE 复合文档，原始传输头保存在顶层的
+// PR_TRANSPORT_MESSAGE_HEADERS 属性流中（Unicode 或 ANSI 两种形式）。
+// 这里只按需读取目录和这一个流所在的扇区，不读取正文和附件
+const (
+	msgTransportHeadersUnicode = "__substg1.0_007D001F"
+	msgTransportHeadersANSI    = "__substg1.0_007D001E"
+)
+
+// 复合文档的文件头标识
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// 复合文档中的特殊扇区编号
+const (
+	cfbEndOfChain = 0xFFFFFFFE
+	cfbFreeSector = 0xFFFFFFFF
+	cfbNoStream   = 0xFFFFFFFF
+)
+
+// 目录项的类型
+const (
+	cfbTypeStream = 2
+	cfbTypeRoot   = 5
+)
+
+// 复合文档中需要读取的扇区数上限，避免损坏的文件中出现循环的扇区链
+const cfbMaxChainLength = 1 << 20
+
+var errNotCompoundFile = errors.New("不是有效的 Outlook 邮件文件")
+
+// cfbDirEntry 复合文档的目录项
+type cfbDirEntry struct {
+	name        string
+	kind        byte
+	left, right uint32
+	child       uint32
+	start       uint32
+	size        uint64
+}
+
+// cfbReader 只读取需要的部分的复合文档
+type cfbReader struct {
+	r            io.ReaderAt
+	sectorSize   int64
+	miniSize     int64
+	miniCutoff   uint64
+	fat          []uint32
+	miniFAT      []uint32
+	dirs         []cfbDirEntry
+	miniStream   []byte
+	miniStreamOK bool
+}
+
+// 读取 .msg 文件中保存的原始传输头
+func readMsgTransportHeaders(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	cfb, err := openCFB(file)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range []string{msgTransportHeadersUnicode, msgTransportHeadersANSI} {
+		entry, ok := cfb.topLevelStream(name)
+		if !ok {
+			continue
+		}
+		if entry.size > emailHeaderReadLimit {
+			entry.size = emailHeaderReadLimit
+		}
+		data, err := cfb.readStream(entry)
+		if err != nil {
+			return "", err
+		}
+		if name == msgTransportHeadersUnicode {
+			return decodeUTF16LE(data), nil
+		}
+		return string(bytes.TrimRight(data, "\x00")), nil
+	}
+	return "", errors.New("邮件中没有保存传输头")
+}
+
+// 解析复合文档的文件头、扇区分配表和目录
+func openCFB(r io.ReaderAt) (*cfbReader, error) {
+	header := make([]byte, 512)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, errNotCompoundFile
+	}
+	if !bytes.Equal(header[:8], cfbSignature) {
+		return nil, errNotCompoundFile
+	}
+	sectorShift := binary.LittleEndian.Uint16(header[0x1E:])
+	miniShift := binary.LittleEndian.Uint16(header[0x20:])
+	if sectorShift != 9 && sectorShift != 12 || miniShift != 6 {
+		return nil, errNotCompoundFile
+	}
+	c := &cfbReader{
+		r:          r,
+		sectorSize: 1 << sectorShift,
+		miniSize:   1 << miniShift,
+		miniCutoff: uint64(binary.LittleEndian.Uint32(header[0x38:])),
+	}
+	numFATSectors := binary.LittleEndian.Uint32(header[0x2C:])
+	firstDirSector := binary.LittleEndian.Uint32(header[0x30:])
+	firstMiniFATSector := binary.LittleEndian.Uint32(header[0x3C:])
+	firstDIFATSector := binary.LittleEndian.Uint32(header[0x44:])
+
+	// 扇区分配表所在的扇区：文件头中的前109个，其余在DIFAT扇区链中
+	var fatSectors []uint32
+	for i := 0; i < 109 && uint32(len(fatSectors)) < numFATSectors; i++ {
+		fatSectors = append(fatSectors, binary.LittleEndian.Uint32(header[0x4C+4*i:]))
+	}
+	perSector := int(c.sectorSize / 4)
+	for sector := firstDIFATSector; uint32(len(fatSectors)) < numFATSectors && sector != cfbEndOfChain && sector != cfbFreeSector; {
+		data, err := c.readSector(sector)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < perSector-1 && uint32(len(fatSectors)) < numFATSectors; i++ {
+			fatSectors = append(fatSectors, binary.LittleEndian.Uint32(data[4*i:]))
+		}
+		sector = binary.LittleEndian.Uint32(data[4*(perSector-1):])
+	}
+	for _, sector := range fatSectors {
+		data, err := c.readSector(sector)
+		if err != nil {
+			return nil, err
+		}
+		c.fat = append(c.fat, bytesToUint32s(data)...)
+	}
+
+	dirData, err := c.readChain(firstDirSector, 0)
+	if err != nil {
+		return nil, err
+	}
+	for offset := 0; offset+128 <= len(dirData); offset += 128 {
+		c.dirs = append(c.dirs, parseCFBDirEntry(dirData[offset:offset+128]))
+	}
+	if len(c.dirs) == 0 || c.dirs[0].kind != cfbTypeRoot {
+		return nil, errNotCompoundFile
+	}
+
+	if firstMiniFATSector != cfbEndOfChain {
+		miniFATData, err := c.readChain(firstMiniFATSector, 0)
+		if err != nil {
+			return nil, err
+		}
+		c.miniFAT = bytesToUint32s(miniFATData)
+	}
+	return c, nil
+}
+
+// 解析一个目录项
+func parseCFBDirEntry(data []byte) cfbDirEntry {
+	nameLen := int(binary.LittleEndian.Uint16(data[64:]))
+	if nameLen > 64 {
+		nameLen = 64
+	}
+	return cfbDirEntry{
+		name:  decodeUTF16LE(data[:nameLen]),
+		kind:  data[66],
+		left:  binary.LittleEndian.Uint32(data[68:]),
+		right: binary.LittleEndian.Uint32(data[72:]),
+		child: binary.LittleEndian.Uint32(data[76:]),
+		start: binary.LittleEndian.Uint32(data[116:]),
+		size:  binary.LittleEndian.Uint64(data[120:]),
+	}
+}
+
+// 在根目录下（不含附件和嵌入的邮件）查找指定名称的流
+func (c *cfbReader) topLevelStream(name string) (cfbDirEntry, bool) {
+	// 同一层的目录项组成一棵二叉树，从根目录的子项开始遍历
+	stack := []uint32{c.dirs[0].child}
+	visited := make(map[uint32]bool)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == cfbNoStream || int(id) >= len(c.dirs) || visited[id] {
+			continue
+		}
+		visited[id] = true
+		entry := c.dirs[id]
+		if entry.kind == cfbTypeStream && entry.name == name {
+			return entry, true
+		}
+		stack = append(stack, entry.left, entry.right)
+	}
+	return cfbDirEntry{}, false
+}
+
+// 读取一个流的内容，小于阈值的流保存在迷你流中
+func (c *cfbReader) readStream(entry cfbDirEntry) ([]byte, error) {
+	if entry.size < c.miniCutoff {
+		return c.readMiniChain(entry.start, entry.size)
+	}
+	return c.readChain(entry.start, entry.size)
+}
+
+// 读取一个扇区
+func (c *cfbReader) readSector(sector uint32) ([]byte, error) {
+	data := make([]byte, c.sectorSize)
+	if _, err := c.r.ReadAt(data, (int64(sector)+1)*c.sectorSize); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("读取 Outlook 邮件失败: %w", err)
+	}
+	return data, nil
+}
+
+// 沿扇区链读取数据，size为0时读取整条链
+func (c *cfbReader) readChain(start uint32, size uint64) ([]byte, error) {
+	var data []byte
+	for sector, n := start, 0; sector != cfbEndOfChain; n++ {
+		if int(sector) >= len(c.fat) || n > cfbMaxChainLength {
+			return nil, errNotCompoundFile
+		}
+		chunk, err := c.readSector(sector)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+		if size > 0 && uint64(len(data)) >= size {
+			return data[:size], nil
+		}
+		sector = c.fat[sector]
+	}
+	if size > 0 && uint64(len(data)) < size {
+		return nil, errNotCompoundFile
+	}
+	return data, nil
+}
+
+// 沿迷你扇区链读取数据
+func (c *cfbReader) readMiniChain(start uint32, size uint64) ([]byte, error) {
+	if !c.miniStreamOK {
+		root := c.dirs[0]
+		stream, err := c.readChain(root.start, root.size)
+		if err != nil {
+			return nil, err
+		}
+		c.miniStream = stream
+		c.miniStreamOK = true
+	}
+	var data []byte
+	for sector, n := start, 0; sector != cfbEndOfChain && uint64(len(data)) < size; n++ {
+		offset := int64(sector) * c.miniSize
+		if int(sector) >= len(c.miniFAT) || n > cfbMaxChainLength || offset+c.miniSize > int64(len(c.miniStream)) {
+			return nil, errNotCompoundFile
+		}
+		data = append(data, c.miniStream[offset:offset+c.miniSize]...)
+		sector = c.miniFAT[sector]
+	}
+	if uint64(len(data)) < size {
+		return nil, errNotCompoundFile
+	}
+	return data[:size], nil
+}
+
+// 将字节按小端序拆分为uint32
+func bytesToUint32s(data []byte) []uint32 {
+	values := make([]uint32, len(data)/4)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint32(data[4*i:])
+	}
+	return values
+}
+
+// 解码以 \0 结尾的UTF-16LE字符串
+func decodeUTF16LE(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		unit := binary.LittleEndian.Uint16(data[i:])
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,22 @@
+//go:build darwin
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// Finder 信息保存在该扩展属性中，前16字节是 FileInfo/FolderInfo，其中第8-9字节是 Finder 标志
+const finderInfoXattr = "com.apple.FinderInfo"
+
+// Finder 标志中的 kHasBundle，文件夹设置了该标志时 Finder 把它当作一个程序包显示
+const finderHasBundle = 0x2000
+
+// 文件夹是否带有 Finder 的程序包标志
+func hasBundleBit(path string) bool {
+	buf := make([]byte, 32)
+	n, err := unix.Getxattr(path, finderInfoXattr, buf)
+	if err != nil || n < 10 {
+		return false
+	}
+	flags := uint16(buf[8])<<8 | uint16(buf[9])
+	return flags&finderHasBundle != 0
+}
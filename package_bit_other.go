@@ -0,0 +1,8 @@
+//go:build !darwin
+
+package main
+
+// 只有 macOS 的 Finder 会为文件夹设置程序包标志，其他系统只按后缀和 Contents 文件夹判断
+func hasBundleBit(path string) bool {
+	return false
+}
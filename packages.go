@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// 只有 macOS 把程序包显示为一个文件；其他系统上带这些后缀的文件夹是普通文件夹，照常扫描其中的文件
+var packageDirsEnabled = runtime.GOOS == "darwin"
+
+// 程序包的后缀：这些文件夹在 macOS 上显示为一个文件，扫描时不进入其中，作为一个整体整理
+var packageExtensions = map[string]bool{
+	".app":           true,
+	".appex":         true,
+	".bundle":        true,
+	".framework":     true,
+	".plugin":        true,
+	".kext":          true,
+	".xpc":           true,
+	".prefpane":      true,
+	".qlgenerator":   true,
+	".mdimporter":    true,
+	".saver":         true,
+	".component":     true,
+	".band":          true,
+	".logicx":        true,
+	".rtfd":          true,
+	".pages":         true,
+	".numbers":       true,
+	".key":           true,
+	".xcodeproj":     true,
+	".xcworkspace":   true,
+	".playground":    true,
+	".imovielibrary": true,
+	".fcpbundle":     true,
+	".sparsebundle":  true,
+}
+
+// 媒体图库：程序包的一种，其中是由照片、音乐等程序管理的数据库，移动后程序可能找不到图库。
+// 默认跳过，只有明确开启时才整理
+var libraryPackageExtensions = map[string]bool{
+	".photoslibrary":        true,
+	".photolibrary":         true,
+	".migratedphotolibrary": true,
+	".aplibrary":            true,
+	".musiclibrary":         true,
+	".tvlibrary":            true,
+}
+
+// 是否是程序包或图库的后缀
+func isPackageExtension(ext string) bool {
+	ext = strings.ToLower(ext)
+	return packageExtensions[ext] || libraryPackageExtensions[ext]
+}
+
+// 文件夹是否是程序包：后缀是已知的程序包或图库，或者带有 Finder 的程序包标志，
+// 或者有 Contents/Info.plist（程序包的标准结构）。名称中没有 "." 的文件夹不检查，避免每个文件夹都多读一次
+func isPackageDir(path string) bool {
+	if !packageDirsEnabled {
+		return false
+	}
+	name := filepath.Base(path)
+	if !strings.Contains(strings.TrimPrefix(name, "."), ".") {
+		return false
+	}
+	if isPackageExtension(filepath.Ext(name)) {
+		return true
+	}
+	if hasBundleBit(path) {
+		return true
+	}
+	info, err := os.Stat(filepath.Join(path, "Contents", "Info.plist"))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// 是否是照片、音乐等程序的图库
+func isLibraryPackage(path string) bool {
+	return libraryPackageExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// 文件或程序包的大小：程序包是文件夹，按其中所有文件的大小之和计算
+func packageSize(path string, info os.FileInfo) int64 {
+	if !info.IsDir() {
+		return info.Size()
+	}
+	var total int64
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// 复制整个文件夹（程序包）到目标路径：保留文件权限、修改时间和符号链接，最后设置各文件夹的修改时间，
+// 按日期整理时复制后的程序包仍然属于同一天。复制完成后核对每个文件都已复制且大小相同，
+// 移动时核对通过才删除源程序包。失败时删除已复制的部分
+func (fo *FileOrganizer) copyTree(sourceDir, targetDir string, xattrs bool) (err error) {
+	defer func() {
+		if err != nil {
+			os.RemoveAll(targetDir)
+		}
+	}()
+	var dirs []string
+	dirInfos := make(map[string]os.FileInfo)
+	err = filepath.WalkDir(sourceDir, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(targetDir, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			if err := os.Mkdir(target, info.Mode().Perm()|0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, target)
+			dirInfos[target] = info
+		case info.Mode()&os.ModeSymlink != 0:
+			// 程序包中的符号链接（例如 framework 的 Versions/Current）原样复制
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if err := fo.copyFileContents(path, target); err != nil {
+				return err
+			}
+			fo.preserveXattrs(path, target, xattrs)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("复制程序包失败: %w", err)
+	}
+	if err := verifyTreeCopy(sourceDir, targetDir); err != nil {
+		return err
+	}
+	// 先设置里层的文件夹，外层文件夹的修改时间不会再被改变
+	for i := len(dirs) - 1; i >= 0; i-- {
+		info := dirInfos[dirs[i]]
+		os.Chmod(dirs[i], info.Mode().Perm())
+		if err := os.Chtimes(dirs[i], info.ModTime(), info.ModTime()); err != nil {
+			return fmt.Errorf("设置程序包的修改时间失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// 核对复制的程序包：源程序包中的每个文件、文件夹和符号链接在目标中都存在，文件的大小相同
+func verifyTreeCopy(sourceDir, targetDir string) error {
+	err := filepath.WalkDir(sourceDir, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		source, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		target, err := os.Lstat(filepath.Join(targetDir, rel))
+		if err != nil {
+			return err
+		}
+		if source.Mode().Type() != target.Mode().Type() {
+			return fmt.Errorf("%s 的类型不同", rel)
+		}
+		if source.Mode().IsRegular() && source.Size() != target.Size() {
+			return fmt.Errorf("%s 的大小不同（%d / %d）", rel, source.Size(), target.Size())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("核对复制的程序包失败: %w", err)
+	}
+	return nil
+}
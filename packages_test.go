@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 带程序包后缀的文件夹只在 macOS 上当作程序包
+func TestIsPackageDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Tool.app")
+	writeTestFile(t, filepath.Join(dir, "Contents", "Info.plist"), "plist")
+	saved := packageDirsEnabled
+	t.Cleanup(func() { packageDirsEnabled = saved })
+
+	for _, enabled := range []bool{false, true} {
+		packageDirsEnabled = enabled
+		if got := isPackageDir(dir); got != enabled {
+			t.Errorf("程序包支持 %v: isPackageDir = %v", enabled, got)
+		}
+	}
+}
+
+func TestPackageSize(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Tool.app")
+	writeTestFile(t, filepath.Join(dir, "Contents", "Info.plist"), "12345")
+	writeTestFile(t, filepath.Join(dir, "Contents", "MacOS", "tool"), "1234567890")
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := packageSize(dir, info); got != 15 {
+		t.Fatalf("packageSize = %d", got)
+	}
+	file := writeTestFile(t, filepath.Join(t.TempDir(), "a.txt"), "abc")
+	info, _ = os.Stat(file)
+	if got := packageSize(file, info); got != 3 {
+		t.Fatalf("文件的 packageSize = %d", got)
+	}
+}
+
+// 复制的程序包与源程序包不同时核对失败
+func TestVerifyTreeCopy(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := filepath.Join(t.TempDir(), "Tool.app")
+	writeTestFile(t, filepath.Join(source, "Contents", "Info.plist"), "plist")
+	writeTestFile(t, filepath.Join(source, "Contents", "MacOS", "tool"), "binary")
+	target := filepath.Join(t.TempDir(), "Tool.app")
+	if err := fo.copyTree(source, target, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTreeCopy(source, target); err != nil {
+		t.Fatalf("完整的副本核对失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "Contents", "MacOS", "tool"), []byte("bin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTreeCopy(source, target); err == nil {
+		t.Fatal("大小不同的副本应核对失败")
+	}
+	os.Remove(filepath.Join(target, "Contents", "Info.plist"))
+	if err := verifyTreeCopy(source, target); err == nil {
+		t.Fatal("缺少文件的副本应核对失败")
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 每次整理的资源使用和性能报告。计数器是进程内累计的原子变量，整理开始和结束时各取一次快照，
+// 相减得到本次整理的值，开销很小，始终开启。同时运行多个整理（例如监视文件夹和手动整理）时，
+// 读写字节数和各操作的耗时包含同时进行的其他整理
+
+// 整理中计时的操作
+const (
+	perfOpFile   = iota // 一个文件从开始处理到处理完的总耗时
+	perfOpRename        // 重命名移动
+	perfOpCopy          // 复制（跨磁盘移动、只读源文件夹）
+	perfOpHash          // 计算哈希值
+	perfOpCount
+)
+
+// 操作在统计JSON中的键和日志中的名称
+var perfOpKeys = [perfOpCount]string{"file", "rename", "copy", "hash"}
+var perfOpNames = [perfOpCount]string{"每个文件", "重命名", "复制", "哈希"}
+
+// 耗时按对数分桶：第 i 个桶的上限是 1µs × 1.5^i，64个桶覆盖到几十小时
+const latencyBucketCount = 64
+
+// 第 i 个桶的上限
+func latencyBucketBound(i int) time.Duration {
+	return time.Duration(float64(time.Microsecond) * math.Pow(1.5, float64(i)))
+}
+
+// 耗时所在的桶
+func latencyBucket(d time.Duration) int {
+	if d <= time.Microsecond {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(time.Microsecond)) / math.Log(1.5)))
+	return min(i, latencyBucketCount-1)
+}
+
+// latencyHistogram 一种操作的耗时分布，可以并发记录
+type latencyHistogram struct {
+	count   atomic.Int64
+	total   atomic.Int64 // 纳秒
+	buckets [latencyBucketCount]atomic.Int64
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	h.count.Add(1)
+	h.total.Add(int64(d))
+	h.buckets[latencyBucket(d)].Add(1)
+}
+
+// latencySnapshot 某一时刻的耗时分布
+type latencySnapshot struct {
+	count   int64
+	total   time.Duration
+	buckets [latencyBucketCount]int64
+}
+
+func (h *latencyHistogram) snapshot() latencySnapshot {
+	s := latencySnapshot{count: h.count.Load(), total: time.Duration(h.total.Load())}
+	for i := range h.buckets {
+		s.buckets[i] = h.buckets[i].Load()
+	}
+	return s
+}
+
+// 两个快照之间的耗时分布
+func (s latencySnapshot) since(earlier latencySnapshot) latencySnapshot {
+	s.count -= earlier.count
+	s.total -= earlier.total
+	for i := range s.buckets {
+		s.buckets[i] -= earlier.buckets[i]
+	}
+	return s
+}
+
+// 估计的百分位耗时，取所在桶的上限
+func (s latencySnapshot) percentile(p float64) time.Duration {
+	if s.count <= 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p * float64(s.count)))
+	var seen int64
+	for i, n := range s.buckets {
+		seen += n
+		if seen >= rank {
+			return latencyBucketBound(i)
+		}
+	}
+	return latencyBucketBound(latencyBucketCount - 1)
+}
+
+// 进程内累计的性能计数器
+var perfCounters struct {
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+	renames      atomic.Int64
+	copies       atomic.Int64
+	uiWait       atomic.Int64 // 等待界面线程的纳秒数
+	uiCalls      atomic.Int64
+	latency      [perfOpCount]latencyHistogram
+}
+
+// 记录一次操作的耗时
+func observePerf(op int, started time.Time) {
+	perfCounters.latency[op].observe(time.Since(started))
+}
+
+// perfSnapshot 某一时刻的计数器
+type perfSnapshot struct {
+	cpu          time.Duration
+	cpuOK        bool
+	bytesRead    int64
+	bytesWritten int64
+	renames      int64
+	copies       int64
+	uiWait       time.Duration
+	uiCalls      int64
+	latency      [perfOpCount]latencySnapshot
+	totalAlloc   uint64
+	numGC        uint32
+	gcPause      time.Duration
+}
+
+func takePerfSnapshot() perfSnapshot {
+	s := perfSnapshot{
+		bytesRead:    perfCounters.bytesRead.Load(),
+		bytesWritten: perfCounters.bytesWritten.Load(),
+		renames:      perfCounters.renames.Load(),
+		copies:       perfCounters.copies.Load(),
+		uiWait:       time.Duration(perfCounters.uiWait.Load()),
+		uiCalls:      perfCounters.uiCalls.Load(),
+	}
+	s.cpu, s.cpuOK = processCPUTime()
+	for op := range s.latency {
+		s.latency[op] = perfCounters.latency[op].snapshot()
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	s.totalAlloc = mem.TotalAlloc
+	s.numGC = mem.NumGC
+	s.gcPause = time.Duration(mem.PauseTotalNs)
+	return s
+}
+
+// OperationLatency 一种操作的次数和耗时（毫秒）
+type OperationLatency struct {
+	Count int64   `json:"count"`
+	AvgMs float64 `json:"avg_ms"`
+	P95Ms float64 `json:"p95_ms"`
+}
+
+// RunPerformance 一次整理的资源使用，见 RunStats 的说明
+type RunPerformance struct {
+	CPUSeconds     float64                     `json:"cpu_seconds,omitempty"`
+	PeakGoroutines int                         `json:"peak_goroutines"`
+	BytesRead      int64                       `json:"bytes_read"`
+	BytesWritten   int64                       `json:"bytes_written"`
+	Renames        int64                       `json:"renames"`
+	Copies         int64                       `json:"copies"`
+	UIWaitSeconds  float64                     `json:"ui_wait_seconds"`
+	UICalls        int64                       `json:"ui_calls"`
+	AllocatedBytes uint64                      `json:"allocated_bytes"`
+	GCCount        uint32                      `json:"gc_count"`
+	GCPauseSeconds float64                     `json:"gc_pause_seconds"`
+	Operations     map[string]OperationLatency `json:"operations"`
+	// 每个文件的平均用时比同一配置方案的上一次整理快的百分比，慢时为负数，没有上一次时省略
+	FasterThanPrevious *float64 `json:"faster_than_previous_percent,omitempty"`
+}
+
+// perfRun 一次整理的性能记录
+type perfRun struct {
+	start          perfSnapshot
+	peakGoroutines atomic.Int64
+}
+
+func startPerfRun() *perfRun {
+	r := &perfRun{start: takePerfSnapshot()}
+	r.sampleGoroutines()
+	return r
+}
+
+// 记录协程数量的峰值，每处理完一个文件调用一次
+func (r *perfRun) sampleGoroutines() {
+	n := int64(runtime.NumGoroutine())
+	for {
+		peak := r.peakGoroutines.Load()
+		if n <= peak || r.peakGoroutines.CompareAndSwap(peak, n) {
+			return
+		}
+	}
+}
+
+// 结束记录，返回本次整理的资源使用
+func (r *perfRun) finish() RunPerformance {
+	end := takePerfSnapshot()
+	perf := RunPerformance{
+		PeakGoroutines: int(r.peakGoroutines.Load()),
+		BytesRead:      end.bytesRead - r.start.bytesRead,
+		BytesWritten:   end.bytesWritten - r.start.bytesWritten,
+		Renames:        end.renames - r.start.renames,
+		Copies:         end.copies - r.start.copies,
+		UIWaitSeconds:  (end.uiWait - r.start.uiWait).Seconds(),
+		UICalls:        end.uiCalls - r.start.uiCalls,
+		AllocatedBytes: end.totalAlloc - r.start.totalAlloc,
+		GCCount:        end.numGC - r.start.numGC,
+		GCPauseSeconds: (end.gcPause - r.start.gcPause).Seconds(),
+		Operations:     make(map[string]OperationLatency),
+	}
+	if end.cpuOK && r.start.cpuOK {
+		perf.CPUSeconds = (end.cpu - r.start.cpu).Seconds()
+	}
+	for op := range end.latency {
+		s := end.latency[op].since(r.start.latency[op])
+		if s.count <= 0 {
+			continue
+		}
+		perf.Operations[perfOpKeys[op]] = OperationLatency{
+			Count: s.count,
+			AvgMs: durationMs(s.total / time.Duration(s.count)),
+			P95Ms: durationMs(s.percentile(0.95)),
+		}
+	}
+	return perf
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// 格式化毫秒数
+func formatMs(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(10 * time.Microsecond).String()
+}
+
+// 描述一次整理的资源使用，用于日志和任务报告
+func describePerformance(stats RunStats) []string {
+	perf := stats.Performance
+	if perf == nil {
+		return nil
+	}
+	usage := fmt.Sprintf("性能: 用时 %s", secondsDuration(stats.DurationSeconds).Round(time.Millisecond))
+	if perf.CPUSeconds > 0 {
+		usage += fmt.Sprintf("，CPU %s", secondsDuration(perf.CPUSeconds).Round(time.Millisecond))
+	}
+	usage += fmt.Sprintf("，最多 %d 个协程，读取 %s，写入 %s，重命名 %d 次，复制 %d 次，等待界面 %s（%d 次）",
+		perf.PeakGoroutines, formatFileSize(perf.BytesRead), formatFileSize(perf.BytesWritten), perf.Renames, perf.Copies,
+		secondsDuration(perf.UIWaitSeconds).Round(time.Millisecond), perf.UICalls)
+	lines := []string{usage}
+
+	var parts []string
+	for op, key := range perfOpKeys {
+		latency, ok := perf.Operations[key]
+		if !ok {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %d 次，平均 %s，p95 %s", perfOpNames[op], latency.Count, formatMs(latency.AvgMs), formatMs(latency.P95Ms)))
+	}
+	if len(parts) > 0 {
+		lines = append(lines, "耗时: "+strings.Join(parts, "；"))
+	}
+	lines = append(lines, fmt.Sprintf("内存: 分配 %s，垃圾回收 %d 次，暂停 %s", formatFileSize(int64(perf.AllocatedBytes)), perf.GCCount,
+		secondsDuration(perf.GCPauseSeconds).Round(time.Microsecond)))
+	if faster := perf.FasterThanPrevious; faster != nil {
+		switch {
+		case *faster > 0:
+			lines = append(lines, fmt.Sprintf("性能: 比上次快 %.0f%%", *faster))
+		case *faster < 0:
+			lines = append(lines, fmt.Sprintf("性能: 比上次慢 %.0f%%", -*faster))
+		default:
+			lines = append(lines, "性能: 与上次差不多")
+		}
+	}
+	return lines
+}
+
+// 查找上一次整理时最多读取的统计文件数。统计文件不会自动删除，
+// 很久没有用过的配置方案不再比较，避免每次整理结束时读取所有历史统计
+const previousRunStatsLimit = 100
+
+// 统计文件夹中同一配置方案的上一次整理（移动了文件的），只查找最近的 previousRunStatsLimit 次，没有时返回false
+func previousRunStats(dir string, current RunStats) (RunStats, bool) {
+	paths, err := filepath.Glob(filepath.Join(dir, "stats_*.json"))
+	if err != nil {
+		return RunStats{}, false
+	}
+	// 文件名中的时间可以按字符串排序，从新到旧查找
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	if len(paths) > previousRunStatsLimit {
+		paths = paths[:previousRunStatsLimit]
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var run RunStats
+		if json.Unmarshal(data, &run) != nil {
+			continue
+		}
+		if !run.StartedAt.Before(current.StartedAt) || run.Profile != current.Profile || run.Files == 0 {
+			continue
+		}
+		return run, true
+	}
+	return RunStats{}, false
+}
+
+// 与同一配置方案的上一次整理比较每个文件的平均用时，返回快了的百分比（慢时为负数）。
+// 本次或上一次没有移动文件时无法比较
+func comparePerformance(current, previous RunStats) (float64, bool) {
+	if current.Files == 0 || previous.Files == 0 || previous.DurationSeconds <= 0 {
+		return 0, false
+	}
+	now := current.DurationSeconds / float64(current.Files)
+	before := previous.DurationSeconds / float64(previous.Files)
+	return math.Round((before - now) / before * 100), true
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 只与同一配置方案、移动了文件的上一次整理比较，并且只查找最近的 previousRunStatsLimit 次
+func TestPreviousRunStats(t *testing.T) {
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		runs    []RunStats // 从旧到新
+		wantOK  bool
+		wantAgo time.Duration // 找到的整理比本次早多久
+	}{
+		{"同一方案", []RunStats{{Profile: "照片", Files: 3}, {Profile: "文档", Files: 5}}, true, 2 * time.Hour},
+		{"没有移动文件的不比较", []RunStats{{Profile: "照片", Files: 3}, {Profile: "照片"}}, true, 2 * time.Hour},
+		{"没有同一方案", []RunStats{{Profile: "文档", Files: 5}}, false, 0},
+		{"超出查找范围", append([]RunStats{{Profile: "照片", Files: 3}},
+			make([]RunStats, previousRunStatsLimit)...), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i, run := range tt.runs {
+				run.StartedAt = started.Add(-time.Duration(len(tt.runs)-i) * time.Hour)
+				path := filepath.Join(dir, fmt.Sprintf("stats_%s.json", run.StartedAt.Format("20060102_150405")))
+				if err := writeRunStats(path, run); err != nil {
+					t.Fatal(err)
+				}
+			}
+			previous, ok := previousRunStats(dir, RunStats{Profile: "照片", StartedAt: started, Files: 1})
+			if ok != tt.wantOK || (ok && started.Sub(previous.StartedAt) != tt.wantAgo) {
+				t.Fatalf("上一次整理 = %v %v, 期望 %v（%v 前）", previous.StartedAt, ok, tt.wantOK, tt.wantAgo)
+			}
+		})
+	}
+}
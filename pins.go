@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Pin 固定的文件或文件夹，整理和监视时始终跳过。
+// 文件同时记录大小和修改时间，上级文件夹改名后仍能按名称、大小和修改时间重新找到
+type Pin struct {
+	Path    string `json:"path"`
+	IsDir   bool   `json:"is_dir,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	ModTime int64  `json:"mtime,omitempty"`
+}
+
+// 按名称、大小和修改时间识别文件
+type pinFingerprint struct {
+	name    string
+	size    int64
+	modTime int64
+}
+
+// 固定列表保存在应用数据目录中，界面和无界面运行共用，整理协程也可以直接保存
+func pinsPath() string {
+	return filepath.Join(appDataDir(), "pins.json")
+}
+
+// pinList 固定列表，整理时各工作协程并发读取
+type pinList struct {
+	mu      sync.Mutex
+	saveMu  sync.Mutex // 界面和整理协程可能同时保存，按顺序写入
+	byPath  map[string]*Pin
+	byPrint map[pinFingerprint]*Pin
+	changed bool // 重新识别后路径有变化，需要保存
+}
+
+// 创建固定列表
+func newPinList(pins []Pin) *pinList {
+	l := &pinList{}
+	l.replace(pins)
+	return l
+}
+
+// 替换整个固定列表
+func (l *pinList) replace(pins []Pin) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.byPath = make(map[string]*Pin, len(pins))
+	l.byPrint = make(map[pinFingerprint]*Pin)
+	for _, pin := range pins {
+		pin := pin
+		pin.Path = filepath.Clean(pin.Path)
+		l.byPath[pin.Path] = &pin
+		if !pin.IsDir && pin.ModTime != 0 {
+			l.byPrint[pinFingerprint{filepath.Base(pin.Path), pin.Size, pin.ModTime}] = &pin
+		}
+	}
+	l.changed = false
+}
+
+// 固定一个文件或文件夹
+func (l *pinList) add(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("无法固定 %s: %w", path, err)
+	}
+	pin := &Pin{Path: filepath.Clean(path), IsDir: info.IsDir()}
+	if !pin.IsDir {
+		pin.Size = info.Size()
+		pin.ModTime = info.ModTime().UnixNano()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if old, ok := l.byPath[pin.Path]; ok && !old.IsDir {
+		delete(l.byPrint, pinFingerprint{filepath.Base(old.Path), old.Size, old.ModTime})
+	}
+	l.byPath[pin.Path] = pin
+	if !pin.IsDir {
+		l.byPrint[pinFingerprint{filepath.Base(pin.Path), pin.Size, pin.ModTime}] = pin
+	}
+	return nil
+}
+
+// 取消固定
+func (l *pinList) remove(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pin, ok := l.byPath[path]
+	if !ok {
+		return
+	}
+	delete(l.byPath, path)
+	if !pin.IsDir {
+		delete(l.byPrint, pinFingerprint{filepath.Base(pin.Path), pin.Size, pin.ModTime})
+	}
+}
+
+// 文件是否被固定：文件本身或某个上级文件夹在列表中，或者名称、大小和修改时间与固定的文件相同
+// （上级文件夹改名后，更新记录的路径）。info为nil时只按路径判断。列表为nil时没有固定的文件
+func (l *pinList) matches(path string, info os.FileInfo) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	path = filepath.Clean(path)
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, ok := l.byPath[dir]; ok {
+			return true
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	if info == nil {
+		return false
+	}
+	pin, ok := l.byPrint[pinFingerprint{filepath.Base(path), info.Size(), info.ModTime().UnixNano()}]
+	if !ok {
+		return false
+	}
+	// 原路径上仍有文件时是另一个同样的文件，不算同一个
+	if _, err := os.Stat(pin.Path); err == nil {
+		return false
+	}
+	delete(l.byPath, pin.Path)
+	pin.Path = path
+	l.byPath[path] = pin
+	l.changed = true
+	return true
+}
+
+// 按路径排序的固定列表
+func (l *pinList) list() []Pin {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pins := make([]Pin, 0, len(l.byPath))
+	for _, pin := range l.byPath {
+		pins = append(pins, *pin)
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		return pins[i].Path < pins[j].Path
+	})
+	return pins
+}
+
+// 取出并清除「重新识别后有变化」的标记
+func (l *pinList) takeChanged() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	changed := l.changed
+	l.changed = false
+	return changed
+}
+
+// 保存固定列表，先写临时文件再改名，避免中途退出时留下不完整的列表
+func (l *pinList) save(path string) error {
+	l.saveMu.Lock()
+	defer l.saveMu.Unlock()
+	data, err := json.Marshal(l.list())
+	if err != nil {
+		return fmt.Errorf("保存固定列表失败: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("保存固定列表失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存固定列表失败: %w", err)
+	}
+	return nil
+}
+
+// 读取保存的固定列表，还没有保存过时返回空列表
+func readPins(path string) ([]Pin, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("加载固定列表失败: %w", err)
+	}
+	var pins []Pin
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, true, fmt.Errorf("加载固定列表失败: %w", err)
+	}
+	return pins, true, nil
+}
+
+// 加载固定列表，无界面运行时也调用。旧版本保存在设置中，有界面运行时迁移到文件
+func (fo *FileOrganizer) loadPins(prefs fyne.Preferences) {
+	pins, exists, err := readPins(pinsPath())
+	if err != nil {
+		fo.log(err.Error())
+	}
+	if !exists && prefs != nil {
+		if data := prefs.StringWithFallback("pinned_paths", ""); data != "" {
+			if err := json.Unmarshal([]byte(data), &pins); err != nil {
+				fo.log(fmt.Sprintf("加载固定列表失败: %v", err))
+				pins = nil
+			} else {
+				fo.pins = newPinList(pins)
+				fo.savePins()
+				prefs.RemoveValue("pinned_paths")
+			}
+		}
+	}
+	fo.pins = newPinList(pins)
+}
+
+// 保存固定列表
+func (fo *FileOrganizer) savePins() {
+	if err := fo.pins.save(pinsPath()); err != nil {
+		fo.log(err.Error())
+	}
+}
+
+// 固定或取消固定扫描结果中的一个文件，折叠的连拍序列固定其中的所有帧
+func (fo *FileOrganizer) setFilePinned(filePath string, pinned bool) {
+	files := []string{filePath}
+	if frames := fo.collapsedBurstFrames(filePath); frames != nil {
+		files = frames
+	}
+	if pinned {
+		fo.pinPaths(files)
+		return
+	}
+	for _, path := range files {
+		fo.pins.remove(filepath.Clean(path))
+	}
+	fo.savePins()
+	fo.log(fmt.Sprintf("已取消固定 %d 个文件", len(files)))
+	fo.refreshFileTable()
+}
+
+// 固定一组文件或文件夹，返回成功固定的数量
+func (fo *FileOrganizer) pinPaths(paths []string) int {
+	pinned := 0
+	for _, path := range paths {
+		if err := fo.pins.add(path); err != nil {
+			fo.log(err.Error())
+			continue
+		}
+		pinned++
+	}
+	if pinned > 0 {
+		fo.savePins()
+		fo.log(fmt.Sprintf("已固定 %d 个文件或文件夹，整理和监视时会跳过它们", pinned))
+		fo.refreshFileTable()
+	}
+	return pinned
+}
+
+// 显示固定列表管理对话框。打开时可以把文件或文件夹拖到窗口上进行固定
+func (fo *FileOrganizer) showPinsDialog() {
+	pins := fo.pins.list()
+	selected := -1
+
+	pinList := widget.NewList(
+		func() int {
+			return len(pins)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			text := pins[i].Path
+			if pins[i].IsDir {
+				text += "（文件夹）"
+			}
+			o.(*widget.Label).SetText(text)
+		},
+	)
+	pinList.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+	pinList.OnUnselected = func(id widget.ListItemID) {
+		selected = -1
+	}
+	reloadList := func() {
+		pins = fo.pins.list()
+		selected = -1
+		pinList.UnselectAll()
+		pinList.Refresh()
+	}
+
+	addFileBtn := widget.NewButton("固定文件...", func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			reader.Close()
+			fo.pinPaths([]string{reader.URI().Path()})
+			reloadList()
+		}, fo.Window)
+	})
+	addFolderBtn := widget.NewButton("固定文件夹...", func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err != nil || dir == nil {
+				return
+			}
+			fo.pinPaths([]string{dir.Path()})
+			reloadList()
+		}, fo.Window)
+	})
+	removeBtn := widget.NewButton("取消固定", func() {
+		if selected < 0 || selected >= len(pins) {
+			dialog.ShowInformation("提示", "请先选择要取消固定的项", fo.Window)
+			return
+		}
+		path := pins[selected].Path
+		fo.pins.remove(path)
+		fo.savePins()
+		fo.log("已取消固定: " + path)
+		fo.refreshFileTable()
+		reloadList()
+	})
+
+	// 打开对话框期间拖到窗口上的文件或文件夹直接固定
+	fo.Window.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		var paths []string
+		for _, uri := range uris {
+			if uri.Scheme() == "file" {
+				paths = append(paths, uri.Path())
+			}
+		}
+		fo.pinPaths(paths)
+		reloadList()
+	})
+
+	listScroll := container.NewVScroll(pinList)
+	listScroll.SetMinSize(fyne.NewSize(520, 240))
+	content := container.NewVBox(
+		widget.NewLabel("固定的文件和文件夹不会被整理（可以把文件或文件夹拖到窗口上来固定）:"),
+		listScroll,
+		container.NewGridWithColumns(3, addFileBtn, addFolderBtn, removeBtn),
+	)
+
+	pinsDialog := dialog.NewCustom("固定列表", "关闭", content, fo.Window)
+	pinsDialog.SetOnClosed(func() {
+		fo.Window.SetOnDropped(nil)
+	})
+	fo.showDialog(pinsDialog, pinList)
+}
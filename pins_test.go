@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fyne.io/fyne/v2"
+)
+
+// 旧版本保存在设置中的固定列表迁移到文件，之后无界面运行也能读取
+func TestLoadPinsMigratesPreferences(t *testing.T) {
+	fo := newTestOrganizer(t)
+	pinned := writeTestFile(t, filepath.Join(t.TempDir(), "keep.jpg"), "keep")
+	data, _ := json.Marshal([]Pin{{Path: pinned}})
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetString("pinned_paths", string(data))
+
+	fo.loadPins(prefs)
+	if !fo.pins.matches(pinned, nil) {
+		t.Fatal("迁移后文件未固定")
+	}
+	if prefs.String("pinned_paths") != "" {
+		t.Fatal("设置中仍保存着固定列表")
+	}
+
+	headless := NewFileOrganizer(true)
+	t.Cleanup(headless.stopLogProcessor)
+	headless.loadPins(nil)
+	if !headless.pins.matches(pinned, nil) {
+		t.Fatal("无界面运行时没有读取固定列表")
+	}
+}
+
+// 整理时按名称、大小和修改时间重新识别的固定文件，新路径直接保存到文件
+func TestPinsSavedAfterRelocation(t *testing.T) {
+	fo := newTestOrganizer(t)
+	fo.loadPins(nil)
+	source := t.TempDir()
+	oldPath := writeTestFile(t, filepath.Join(source, "old", "keep.jpg"), "keep")
+	if fo.pinPaths([]string{oldPath}) != 1 {
+		t.Fatal("固定失败")
+	}
+	newPath := filepath.Join(source, "new", "keep.jpg")
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      t.TempDir(),
+		FileExtensions: []string{".jpg"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		Pins:           fo.pins,
+		ExcludedFiles:  map[string]bool{},
+	}
+	if _, err := fo.processFiles(config, []string{newPath}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, newPath); got != "keep" {
+		t.Fatalf("固定的文件 = %q", got)
+	}
+	pins, _, err := readPins(pinsPath())
+	if err != nil || len(pins) != 1 || pins[0].Path != newPath {
+		t.Fatalf("保存的固定列表 = %+v, %v", pins, err)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 整理后运行的命令默认的超时（秒）
+const defaultHookTimeoutSec = 60
+
+// 日志中最多记录的命令输出字节数
+const hookOutputLimit = 4096
+
+// 整理后运行的命令中可用的变量。值通过环境变量传给命令，不拼接到命令中，
+// 文件名中的引号、$、%、& 等字符不会被shell解释
+var runHookTokens = []string{"{target}", "{checked}", "{moved}", "{copied}", "{duplicates}", "{failed}"}
+var fileHookTokens = []string{"{source}", "{path}", "{folder}", "{target}"}
+
+// 变量的值所在的环境变量，例如 {path} 对应 FILEORGANIZER_PATH，脚本中也可以直接读取
+func hookEnvName(token string) string {
+	return "FILEORGANIZER_" + strings.ToUpper(strings.Trim(token, "{}"))
+}
+
+// 把命令模板中的变量替换为对环境变量的引用，返回命令和要设置的环境变量。
+// 引用按变量所在位置的引号写出，值中有空格时仍是一个参数；shell 的 ${VAR} 保持原样
+func expandHookCommand(template string, values map[string]string) (string, []string) {
+	windows := runtime.GOOS == "windows"
+	var command strings.Builder
+	inSingle, inDouble := false, false
+	for i := 0; i < len(template); {
+		c := template[i]
+		if c == '{' && (i == 0 || template[i-1] != '$') {
+			if end := strings.IndexByte(template[i:], '}'); end > 0 {
+				token := template[i : i+end+1]
+				if _, ok := values[token]; ok {
+					command.WriteString(hookVarRef(hookEnvName(token), inSingle, inDouble))
+					i += end + 1
+					continue
+				}
+			}
+		}
+		switch {
+		case c == '\\' && !inSingle && !windows && i+1 < len(template):
+			// 转义的引号不改变引号状态
+			command.WriteString(template[i : i+2])
+			i += 2
+			continue
+		case c == '\'' && !inDouble && !windows:
+			inSingle = !inSingle
+		case c == '"' && !inSingle:
+			inDouble = !inDouble
+		}
+		command.WriteByte(c)
+		i++
+	}
+	env := make([]string, 0, len(values))
+	for token, value := range values {
+		env = append(env, hookEnvName(token)+"="+value)
+	}
+	return command.String(), env
+}
+
+// 检查命令模板：{名称} 形式的变量只能是可用的变量。单引号中的内容（例如 awk '{print}'）、
+// shell 的 ${VAR} 和名称不是字母、下划线的花括号（例如 { a; b; }）不是变量，不检查
+func validateHookCommand(template string, tokens []string) error {
+	inSingle, inDouble := false, false
+	for i := 0; i < len(template); i++ {
+		switch c := template[i]; {
+		case c == '\'' && !inDouble:
+			inSingle = !inSingle
+		case c == '"' && !inSingle:
+			inDouble = !inDouble
+		case c == '{' && !inSingle && (i == 0 || template[i-1] != '$'):
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return nil
+			}
+			token := template[i : i+end+1]
+			if !isHookTokenName(token[1 : len(token)-1]) {
+				continue
+			}
+			known := false
+			for _, t := range tokens {
+				if token == t {
+					known = true
+					break
+				}
+			}
+			if !known {
+				return fmt.Errorf("未知的变量 %s，可用: %s", token, strings.Join(tokens, " "))
+			}
+			i += end
+		}
+	}
+	return nil
+}
+
+// 花括号中的名称是否像变量：只有小写字母和下划线
+func isHookTokenName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// 日志中显示的命令：只显示程序名，命令的参数中可能有密码或令牌
+func hookCommandLabel(template string) string {
+	fields := strings.Fields(template)
+	if len(fields) == 0 {
+		return ""
+	}
+	if len(fields) == 1 {
+		return fields[0]
+	}
+	return fields[0] + " …"
+}
+
+// 通过系统shell运行命令，env是变量的值，超时后结束。返回合并的标准输出和错误输出
+func runHookCommand(command string, env []string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := hookShellCommand(ctx, command)
+	cmd.Env = append(append(os.Environ(), "FILEORGANIZER_HOOK=1"), env...)
+	// 超时结束shell后，命令启动的子进程可能仍占用输出，不再等待
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("超过 %s 未结束，已停止", timeout)
+	}
+	text := strings.TrimSpace(string(output))
+	if len(text) > hookOutputLimit {
+		text = text[:hookOutputLimit] + "…（输出过长，已截断）"
+	}
+	return text, err
+}
+
+// 命令的超时
+func hookTimeout(config Config) time.Duration {
+	if config.HookTimeoutSec > 0 {
+		return time.Duration(config.HookTimeoutSec) * time.Second
+	}
+	return defaultHookTimeoutSec * time.Second
+}
+
+// 运行命令并把输出和结果写入日志
+func (fo *FileOrganizer) runHook(kind, template string, values map[string]string, config Config) {
+	command, env := expandHookCommand(template, values)
+	fo.log(fmt.Sprintf("[%s] 运行: %s", kind, hookCommandLabel(template)))
+	output, err := runHookCommand(command, env, hookTimeout(config))
+	if output != "" {
+		fo.log(fmt.Sprintf("[%s] 输出:\n%s", kind, output))
+	}
+	if err != nil {
+		fo.log(fmt.Sprintf("[%s] 警告: 命令运行失败: %v", kind, err))
+	}
+}
+
+// 开启了运行命令时返回命令模板，否则返回空字符串
+func (fo *FileOrganizer) hookCommand(command string) string {
+	if !fo.HooksEnabled {
+		return ""
+	}
+	return command
+}
+
+// 整理成功（没有失败或中止的文件）后运行设置的命令
+func (fo *FileOrganizer) runPostRunHook(config Config, summary processSummary) {
+	if config.PostRunHook == "" {
+		return
+	}
+	if summary.Failed > 0 || summary.Aborted > 0 {
+		fo.log(fmt.Sprintf("[整理后命令] 有 %d 个文件失败、%d 个文件中止，未运行", summary.Failed, summary.Aborted))
+		return
+	}
+	fo.runHook("整理后命令", config.PostRunHook, map[string]string{
+		"{target}":     config.TargetDir,
+		"{checked}":    strconv.Itoa(summary.Checked),
+		"{moved}":      strconv.Itoa(summary.Moved),
+		"{copied}":     strconv.Itoa(summary.Copied),
+		"{duplicates}": strconv.Itoa(summary.Duplicates),
+		"{failed}":     strconv.Itoa(summary.Failed),
+	}, config)
+}
+
+// 每个文件整理后运行设置的命令，在处理该文件的工作协程中运行
+func (fo *FileOrganizer) runFileHook(config Config, sourcePath, finalPath string) {
+	if config.PerFileHook == "" {
+		return
+	}
+	fo.runHook("文件命令", config.PerFileHook, map[string]string{
+		"{source}": sourcePath,
+		"{path}":   finalPath,
+		"{folder}": filepath.Dir(finalPath),
+		"{target}": config.TargetDir,
+	}, config)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// 只检查 {名称} 形式的变量，shell 变量、单引号中的 awk 程序和命令组不当作变量
+func TestValidateHookCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		wantErr string // 为空表示通过
+	}{
+		{"notify-send 整理完成 {target}", ""},
+		{"echo {path} >> \"${HOME}/organized.log\"", ""},
+		{"ls {folder} | awk '{print $1}'", ""},
+		{"awk '{print}' {path}", ""},
+		{"{ echo a; echo b; } > /tmp/x", ""},
+		{"echo \"{path}\"", ""},
+		{"echo {pth}", "{pth}"},
+		{"echo \"{moved}\"", "{moved}"},
+		{"echo '{x}' {bogus}", "{bogus}"},
+	}
+	for _, tt := range tests {
+		err := validateHookCommand(tt.command, fileHookTokens)
+		if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%q: %v, 期望 %q", tt.command, err, tt.wantErr)
+		}
+	}
+}
+
+// 日志中只显示程序名，不显示可能含有密码的参数
+func TestHookCommandLabel(t *testing.T) {
+	tests := []struct {
+		command, want string
+	}{
+		{"curl -H 'Authorization: Bearer secret' https://example.com", "curl …"},
+		{"  ./notify.sh  ", "./notify.sh"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := hookCommandLabel(tt.command); got != tt.want {
+			t.Errorf("%q: %q, 期望 %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+// 变量替换为环境变量的引用，值不出现在命令中；未知的花括号和 shell 的 ${VAR} 保持原样
+func TestExpandHookCommand(t *testing.T) {
+	values := map[string]string{
+		"{path}":   `/out/it's "a" & %PATH% $(id).jpg`,
+		"{target}": "/out",
+	}
+	tests := []string{
+		"echo {path}",
+		`echo "{path}" '{target}'`,
+		`awk '{print}' {path} > "${HOME}/{target}.log"`,
+	}
+	for _, template := range tests {
+		command, env := expandHookCommand(template, values)
+		if strings.Contains(command, "/out") || strings.Contains(command, "{path}") || strings.Contains(command, "{target}") {
+			t.Errorf("%q 展开为 %q，值或变量不应出现在命令中", template, command)
+		}
+		if strings.Contains(template, "{print}") && !strings.Contains(command, "'{print}'") {
+			t.Errorf("%q 展开为 %q，awk 程序应保持原样", template, command)
+		}
+		if strings.Contains(template, "${HOME}") && !strings.Contains(command, "${HOME}") {
+			t.Errorf("%q 展开为 %q，shell 变量应保持原样", template, command)
+		}
+		sort.Strings(env)
+		want := []string{"FILEORGANIZER_PATH=" + values["{path}"], "FILEORGANIZER_TARGET=/out"}
+		if !reflect.DeepEqual(env, want) {
+			t.Errorf("%q 的环境变量 = %q, 期望 %q", template, env, want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Preset 保存的整理规则预设
+type Preset struct {
+	Name             string       `json:"name"`
+	OrganizeRule     string       `json:"organize_rule"`
+	FileExtensions   []string     `json:"file_extensions"`
+	FolderDateFormat string       `json:"folder_date_format"`
+	ExtensionCase    string       `json:"extension_case"`
+	MultiTagMode     string       `json:"multi_tag_mode"`
+	FolderLayout     string       `json:"folder_layout,omitempty"`
+	EventLabels      []EventLabel `json:"event_labels,omitempty"`
+	DateSources      []DateSource `json:"date_sources,omitempty"`
+	FolderTemplate   string       `json:"folder_template,omitempty"`
+	RuleSegments     []string     `json:"rule_segments,omitempty"`
+	SegmentSeparator string       `json:"rule_segment_separator,omitempty"`
+
+	TextLanguageFolders bool              `json:"text_language_folders,omitempty"`
+	TextKeywordRules    []TextKeywordRule `json:"text_keyword_rules,omitempty"`
+	TextKeywordCase     bool              `json:"text_keyword_case,omitempty"`
+}
+
+// 预设是否指定了按内容整理文本文件的规则，没有指定时使用当前设置
+func (p Preset) hasTextRules() bool {
+	return p.TextLanguageFolders || len(p.TextKeywordRules) > 0
+}
+
+// 根据预设生成整理指定文件夹的配置
+func (p Preset) config(root string) Config {
+	return Config{
+		SourceDir:            root,
+		TargetDir:            root,
+		FileExtensions:       normalizeExtensions(p.FileExtensions),
+		FolderDateFormat:     p.FolderDateFormat,
+		OrganizeRule:         p.OrganizeRule,
+		ExtensionCase:        p.ExtensionCase,
+		MultiTagMode:         p.MultiTagMode,
+		SourceDirs:           []string{root},
+		FolderLayout:         p.FolderLayout,
+		EventLabels:          p.EventLabels,
+		DateSources:          p.DateSources,
+		FolderTemplate:       p.FolderTemplate,
+		RuleSegments:         p.RuleSegments,
+		RuleSegmentSeparator: p.SegmentSeparator,
+		TextLanguageFolders:  p.TextLanguageFolders,
+		TextKeywordRules:     append([]TextKeywordRule(nil), p.TextKeywordRules...),
+		TextKeywordCase:      p.TextKeywordCase,
+	}
+}
+
+// 加载保存的预设
+func (fo *FileOrganizer) loadPresets() {
+	prefs := fyne.CurrentApp().Preferences()
+	fo.presets = nil
+	if data := prefs.StringWithFallback("presets", ""); data != "" {
+		if err := json.Unmarshal([]byte(data), &fo.presets); err != nil {
+			fo.log(fmt.Sprintf("加载预设失败: %v", err))
+			fo.presets = nil
+		}
+	}
+	// 旧版本或手动编辑的预设中可能有 "JPG" 这样的后缀
+	for i := range fo.presets {
+		fo.presets[i].FileExtensions = normalizeExtensions(fo.presets[i].FileExtensions)
+	}
+}
+
+// 保存预设
+func (fo *FileOrganizer) savePresets() {
+	data, err := json.Marshal(fo.presets)
+	if err != nil {
+		fo.log(fmt.Sprintf("保存预设失败: %v", err))
+		return
+	}
+	fyne.CurrentApp().Preferences().SetString("presets", string(data))
+}
+
+// 按名称查找预设
+func (fo *FileOrganizer) findPreset(name string) (Preset, bool) {
+	for _, preset := range fo.presets {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return Preset{}, false
+}
+
+// 获取所有预设名称（按名称排序）
+func (fo *FileOrganizer) presetNames() []string {
+	names := make([]string, 0, len(fo.presets))
+	for _, preset := range fo.presets {
+		names = append(names, preset.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 将当前界面设置保存为预设，同名预设会被覆盖
+func (fo *FileOrganizer) saveCurrentAsPreset(name string) {
+	preset := Preset{
+		Name:             name,
+		OrganizeRule:     fo.RuleSelect.Selected,
+		FileExtensions:   append([]string(nil), fo.FileExtensions...),
+		FolderDateFormat: fo.FolderDateFormat,
+		ExtensionCase:    fo.ExtensionCase,
+		MultiTagMode:     fo.MultiTagMode,
+		FolderLayout:     fo.FolderLayout,
+		EventLabels:      append([]EventLabel(nil), fo.EventLabels...),
+		DateSources:      append([]DateSource(nil), fo.DateSources...),
+		FolderTemplate:   fo.FolderTemplates[fo.RuleSelect.Selected],
+		RuleSegments:     append([]string(nil), fo.RuleSegments...),
+		SegmentSeparator: fo.RuleSegmentSeparator,
+
+		TextLanguageFolders: fo.TextLanguageFolders,
+		TextKeywordRules:    append([]TextKeywordRule(nil), fo.TextKeywordRules...),
+		TextKeywordCase:     fo.TextKeywordCase,
+	}
+
+	for i, existing := range fo.presets {
+		if existing.Name == name {
+			fo.presets[i] = preset
+			fo.savePresets()
+			fo.log(fmt.Sprintf("已更新预设: %s", name))
+			return
+		}
+	}
+	fo.presets = append(fo.presets, preset)
+	fo.savePresets()
+	fo.log(fmt.Sprintf("已保存预设: %s", name))
+}
+
+// 将预设应用到当前界面设置
+func (fo *FileOrganizer) applyPreset(preset Preset) {
+	fo.FileExtensions = normalizeExtensions(preset.FileExtensions)
+	if preset.FolderDateFormat != "" {
+		fo.FolderDateFormat = preset.FolderDateFormat
+	}
+	if preset.ExtensionCase != "" {
+		fo.ExtensionCase = preset.ExtensionCase
+	}
+	if preset.MultiTagMode != "" {
+		fo.MultiTagMode = preset.MultiTagMode
+	}
+	if preset.FolderLayout != "" {
+		fo.FolderLayout = preset.FolderLayout
+	}
+	if len(preset.DateSources) > 0 {
+		fo.DateSources = append([]DateSource(nil), preset.DateSources...)
+	}
+	if len(preset.RuleSegments) > 0 {
+		fo.RuleSegments = append([]string(nil), preset.RuleSegments...)
+		fo.RuleSegmentSeparator = preset.SegmentSeparator
+	}
+	if len(preset.EventLabels) > 0 {
+		fo.EventLabels = append([]EventLabel(nil), preset.EventLabels...)
+		fo.saveEventLabels()
+	}
+	if preset.FolderTemplate != "" && preset.OrganizeRule != "" {
+		fo.setFolderTemplate(OrganizeRule(preset.OrganizeRule), preset.FolderTemplate)
+	}
+	if preset.hasTextRules() {
+		fo.TextLanguageFolders = preset.TextLanguageFolders
+		fo.TextKeywordRules = append([]TextKeywordRule(nil), preset.TextKeywordRules...)
+		fo.TextKeywordCase = preset.TextKeywordCase
+	}
+	fo.saveUserConfig()
+	fo.log(fmt.Sprintf("已应用预设: %s", preset.Name))
+
+	// 规则变化时会自动重新扫描
+	if preset.OrganizeRule != "" && preset.OrganizeRule != fo.RuleSelect.Selected {
+		fo.RuleSelect.SetSelected(preset.OrganizeRule)
+		// 尚未选择源文件夹时规则选择没有回调，这里同步记录的规则
+		fo.OrganizeRule = OrganizeRule(fo.RuleSelect.Selected)
+	} else {
+		fo.refreshFileTable()
+	}
+}
+
+// 删除预设，如果预设已绑定到监视文件夹则先确认并解除绑定
+func (fo *FileOrganizer) deletePreset(name string, onDeleted func()) {
+	remove := func() {
+		var remaining []Preset
+		for _, preset := range fo.presets {
+			if preset.Name != name {
+				remaining = append(remaining, preset)
+			}
+		}
+		fo.presets = remaining
+		fo.savePresets()
+		fo.unbindPresetFromWatchRoots(name)
+		fo.log(fmt.Sprintf("已删除预设: %s", name))
+		if onDeleted != nil {
+			onDeleted()
+		}
+	}
+
+	boundRoots := fo.watchRootsBoundTo(name)
+	if len(boundRoots) == 0 {
+		remove()
+		return
+	}
+
+	dialog.ShowConfirm("预设正在使用",
+		fmt.Sprintf("预设「%s」已绑定到以下监视文件夹:\n%s\n\n删除后这些文件夹将改用当前设置，是否继续？",
+			name, strings.Join(boundRoots, "\n")),
+		func(confirm bool) {
+			if confirm {
+				remove()
+			}
+		}, fo.Window)
+}
+
+// 显示预设管理对话框
+func (fo *FileOrganizer) showPresetsDialog() {
+	names := fo.presetNames()
+	selected := -1
+
+	presetList := widget.NewList(
+		func() int {
+			return len(names)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(names[i])
+		},
+	)
+	presetList.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+	presetList.OnUnselected = func(id widget.ListItemID) {
+		selected = -1
+	}
+
+	reloadList := func() {
+		names = fo.presetNames()
+		selected = -1
+		presetList.UnselectAll()
+		presetList.Refresh()
+	}
+
+	applyBtn := widget.NewButton("应用选中预设", func() {
+		if selected < 0 || selected >= len(names) {
+			dialog.ShowInformation("提示", "请先选择一个预设", fo.Window)
+			return
+		}
+		if preset, ok := fo.findPreset(names[selected]); ok {
+			fo.applyPreset(preset)
+		}
+	})
+	deleteBtn := widget.NewButton("删除选中预设", func() {
+		if selected < 0 || selected >= len(names) {
+			dialog.ShowInformation("提示", "请先选择一个预设", fo.Window)
+			return
+		}
+		fo.deletePreset(names[selected], reloadList)
+	})
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("预设名称")
+	saveBtn := widget.NewButton("保存当前设置为预设", func() {
+		name := strings.TrimSpace(nameEntry.Text)
+		if name == "" {
+			dialog.ShowInformation("提示", "请输入预设名称", fo.Window)
+			return
+		}
+		save := func() {
+			fo.saveCurrentAsPreset(name)
+			nameEntry.SetText("")
+			reloadList()
+		}
+		if _, exists := fo.findPreset(name); exists {
+			dialog.ShowConfirm("覆盖预设", fmt.Sprintf("预设「%s」已存在，是否覆盖？", name), func(confirm bool) {
+				if confirm {
+					save()
+				}
+			}, fo.Window)
+			return
+		}
+		save()
+	})
+
+	listScroll := container.NewVScroll(presetList)
+	listScroll.SetMinSize(fyne.NewSize(400, 200))
+	content := container.NewVBox(
+		widget.NewLabel("已保存的预设:"),
+		listScroll,
+		container.NewGridWithColumns(2, applyBtn, deleteBtn),
+		widget.NewSeparator(),
+		container.NewBorder(nil, nil, nil, saveBtn, nameEntry),
+	)
+
+	fo.showDialog(dialog.NewCustom("规则预设", "关闭", content, fo.Window), presetList)
+}
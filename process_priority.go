@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+)
+
+// processPriority 后台低优先级整理的状态。只降低整理专用的线程，界面线程和扫描保持正常优先级
+type processPriority struct {
+	warned atomic.Bool // 已经提示过无法降低优先级，之后不再重复提示
+}
+
+// 把当前协程固定在一个线程上并降低这个线程的优先级，只在整理专用的协程（工作协程、监视模式的定时器回调）中调用。
+// 协程结束时不解除固定，线程随协程一起退出，低优先级不会留给之后在这个线程上运行的其他协程。
+// 降低失败时按正常优先级整理
+func (fo *FileOrganizer) lowerWorkerPriority() {
+	runtime.LockOSThread()
+	if err := lowerThreadPriority(); err != nil && fo.priority.warned.CompareAndSwap(false, true) {
+		fo.log(fmt.Sprintf("降低整理线程的优先级失败，按正常优先级整理: %v", err))
+	}
+}
@@ -0,0 +1,24 @@
+//go:build darwin
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// setpriority 的 PRIO_DARWIN_THREAD 和 PRIO_DARWIN_BG（sys/resource.h），x/sys/unix 中没有定义
+const (
+	prioDarwinThread = 3
+	prioDarwinBG     = 0x1000
+)
+
+// 把当前线程设为后台线程，macOS 同时降低它的CPU和磁盘优先级。
+// PRIO_PROCESS 的nice值对整个进程生效，会连界面一起变慢，所以只设置整理的线程
+func lowerThreadPriority() error {
+	if err := unix.Setpriority(prioDarwinThread, 0, prioDarwinBG); err != nil {
+		return fmt.Errorf("设置后台线程失败: %w", err)
+	}
+	return nil
+}
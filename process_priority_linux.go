@@ -0,0 +1,37 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// 后台整理使用的nice值和磁盘调度类别
+const (
+	lowPriorityNice  = 10
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioClassIdle  = 3
+)
+
+// 把当前线程的nice值提高到10，磁盘调度改为空闲类（只在磁盘空闲时读写）。
+// Linux上nice值和磁盘优先级按线程设置，PRIO_PROCESS 加线程号只影响这个线程
+func lowerThreadPriority() error {
+	tid := unix.Gettid()
+	prio, err := unix.Getpriority(unix.PRIO_PROCESS, tid)
+	if err != nil {
+		return fmt.Errorf("读取nice值失败: %w", err)
+	}
+	// 系统调用返回的是 20-nice
+	if 20-prio < lowPriorityNice {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, tid, lowPriorityNice); err != nil {
+			return fmt.Errorf("设置nice值失败: %w", err)
+		}
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassIdle<<ioprioClassShift); errno != 0 {
+		return fmt.Errorf("设置磁盘优先级失败: %w", errno)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build linux
+
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// 降低工作协程的优先级不影响调用者所在的线程
+func TestLowerWorkerPriorityOnlyWorkerThread(t *testing.T) {
+	fo := newTestOrganizer(t)
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	tid := unix.Gettid()
+	before, err := unix.Getpriority(unix.PRIO_PROCESS, tid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workerPrio := make(chan int)
+	go func() {
+		fo.lowerWorkerPriority()
+		prio, _ := unix.Getpriority(unix.PRIO_PROCESS, unix.Gettid())
+		workerPrio <- prio
+	}()
+	if prio := <-workerPrio; 20-prio < lowPriorityNice {
+		t.Fatalf("工作线程的nice值 = %d", 20-prio)
+	}
+	if after, _ := unix.Getpriority(unix.PRIO_PROCESS, tid); after != before {
+		t.Fatalf("调用者线程的优先级从 %d 变为 %d", before, after)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !linux && !windows
+
+package main
+
+import "errors"
+
+// 降低线程优先级（当前系统不支持）
+func lowerThreadPriority() error {
+	return errors.New("当前系统不支持降低线程优先级")
+}
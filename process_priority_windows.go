@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// SetThreadPriority 的 THREAD_MODE_BACKGROUND_BEGIN，同时降低线程的CPU、磁盘和内存优先级
+const threadModeBackgroundBegin = 0x00010000
+
+var procSetThreadPriority = modkernel32.NewProc("SetThreadPriority")
+
+// 当前线程进入后台处理模式。PROCESS_MODE_BACKGROUND_BEGIN 对整个进程生效，会连界面一起变慢，所以只设置整理的线程
+func lowerThreadPriority() error {
+	thread, err := windows.GetCurrentThread()
+	if err != nil {
+		return fmt.Errorf("获取当前线程失败: %w", err)
+	}
+	if r, _, err := procSetThreadPriority.Call(uintptr(thread), threadModeBackgroundBegin); r == 0 {
+		return fmt.Errorf("进入后台处理模式失败: %w", err)
+	}
+	return nil
+}
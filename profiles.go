@@ -0,0 +1,622 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 配置方案文件名，位于应用数据文件夹中
+const profilesFileName = "profiles.json"
+
+// Profile 完整的配置方案：源文件夹、目标文件夹、整理规则和各项选项。
+// 与只包含规则的预设不同，切换方案会替换源文件夹并重新扫描。
+// 存储卡、窗口和统计上报令牌等与本机相关的设置不属于方案
+type Profile struct {
+	Name            string         `json:"name"`
+	SourceDirs      []string       `json:"source_dirs"`
+	TargetDir       string         `json:"target_dir,omitempty"`
+	SpillTargets    []TargetVolume `json:"spill_targets,omitempty"`
+	TargetMinFreeMB int64          `json:"target_min_free_mb,omitempty"`
+	ExtensionRanks  map[string]int `json:"extension_ranks,omitempty"`
+	ReadOnlySources []string       `json:"read_only_sources,omitempty"`
+	Pins            []Pin          `json:"pins,omitempty"`
+	Rules           Preset         `json:"rules"`
+	Options         ProfileOptions `json:"options"`
+}
+
+// ProfileOptions 方案中保存的「更多设置」选项
+type ProfileOptions struct {
+	DateFolderMtime      bool              `json:"date_folder_mtime"`
+	EmailSenderFolders   bool              `json:"email_sender_folders"`
+	BurstDetection       bool              `json:"burst_detection"`
+	BurstMaxGap          int               `json:"burst_max_gap,omitempty"`
+	BurstMaxFrames       int               `json:"burst_max_frames,omitempty"`
+	PackSmallFiles       bool              `json:"pack_small_files,omitempty"`
+	PackThresholdKB      int               `json:"pack_threshold_kb,omitempty"`
+	DateTimeZone         string            `json:"date_time_zone,omitempty"`
+	CompactExtensionsMin int               `json:"compact_extensions_min"`
+	ExtensionRankPrefix  bool              `json:"extension_rank_prefix"`
+	AgeBucketLabels      []string          `json:"age_bucket_labels,omitempty"`
+	SizeBucketsMB        []int             `json:"size_buckets_mb,omitempty"`
+	DedupTarget          bool              `json:"dedup_target"`
+	DedupEmptyFiles      bool              `json:"dedup_empty_files"`
+	EmptyFilePolicy      string            `json:"empty_file_policy,omitempty"`
+	ShortcutPolicy       string            `json:"shortcut_policy,omitempty"`
+	OrganizeLibraries    bool              `json:"organize_libraries"`
+	TextLanguageFolders  bool              `json:"text_language_folders"`
+	TextKeywordRules     []TextKeywordRule `json:"text_keyword_rules,omitempty"`
+	TextKeywordCase      bool              `json:"text_keyword_case"`
+	TextPrefixKB         int               `json:"text_prefix_kb,omitempty"`
+	TakeoutMode          bool              `json:"takeout_mode"`
+	ConflictPolicy       string            `json:"conflict_policy,omitempty"`
+	BackupReplaced       bool              `json:"backup_replaced,omitempty"`
+	ChecksumAlgorithm    string            `json:"checksum_algorithm,omitempty"`
+	RemoveEmptiedDirs    bool              `json:"remove_emptied_dirs"`
+	MoveEmptyDirs        bool              `json:"move_empty_dirs"`
+	PreserveXattrs       bool              `json:"preserve_xattrs"`
+	LowPriority          bool              `json:"low_priority"`
+	IncomingOnly         bool              `json:"incoming_only"`
+	ConvertImages        bool              `json:"convert_images"`
+	KeepConverted        bool              `json:"keep_converted_originals"`
+	CopyMerge            string            `json:"copy_merge,omitempty"`
+	CopySuffixPatterns   []string          `json:"copy_suffix_patterns,omitempty"`
+	CatalogEnabled       bool              `json:"catalog_enabled"`
+	ForceFullScan        bool              `json:"force_full_scan"`
+	ValidateExtensions   bool              `json:"validate_extensions"`
+	ScanConcurrency      int               `json:"scan_concurrency,omitempty"`
+	ScanErrorLimit       int               `json:"scan_error_limit"`
+	StreamingThreshold   int               `json:"streaming_threshold,omitempty"`
+	ParallelThreshold    int               `json:"parallel_threshold,omitempty"`
+	SmallSetWorkers      int               `json:"small_set_workers,omitempty"`
+	UnicodeNormalization string            `json:"unicode_normalization,omitempty"`
+	CollisionSeparator   string            `json:"collision_separator,omitempty"`
+	CollisionPosition    string            `json:"collision_position,omitempty"`
+	VolumeCapMB          int64             `json:"volume_cap_mb,omitempty"`
+}
+
+// 配置方案文件路径
+func profilesPath() string {
+	return filepath.Join(appDataDir(), profilesFileName)
+}
+
+// 加载配置方案，文件不存在时没有方案
+func (fo *FileOrganizer) loadProfiles() {
+	fo.profiles = nil
+	data, err := os.ReadFile(profilesPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fo.log(fmt.Sprintf("加载配置方案失败: %v", err))
+		}
+		return
+	}
+	profiles, err := parseProfiles(data)
+	if err != nil {
+		fo.log(fmt.Sprintf("加载配置方案失败: %v", err))
+		return
+	}
+	fo.profiles = profiles
+}
+
+// 保存配置方案
+func (fo *FileOrganizer) saveProfiles() error {
+	data, err := json.MarshalIndent(fo.profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化配置方案失败: %w", err)
+	}
+	if err := os.WriteFile(profilesPath(), data, 0644); err != nil {
+		return fmt.Errorf("保存配置方案失败: %w", err)
+	}
+	return nil
+}
+
+// 解析配置方案列表，跳过没有名称的方案
+func parseProfiles(data []byte) ([]Profile, error) {
+	var profiles []Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("配置方案格式错误: %w", err)
+	}
+	valid := profiles[:0]
+	for _, profile := range profiles {
+		profile.Name = strings.TrimSpace(profile.Name)
+		profile.Rules.FileExtensions = normalizeExtensions(profile.Rules.FileExtensions)
+		if profile.Name != "" {
+			valid = append(valid, profile)
+		}
+	}
+	return valid, nil
+}
+
+// 按名称查找配置方案
+func (fo *FileOrganizer) findProfile(name string) (Profile, bool) {
+	for _, profile := range fo.profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// 获取所有配置方案名称（按名称排序）
+func (fo *FileOrganizer) profileNames() []string {
+	names := make([]string, 0, len(fo.profiles))
+	for _, profile := range fo.profiles {
+		names = append(names, profile.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 添加或替换同名的配置方案
+func (fo *FileOrganizer) putProfile(profile Profile) {
+	for i, existing := range fo.profiles {
+		if existing.Name == profile.Name {
+			fo.profiles[i] = profile
+			return
+		}
+	}
+	fo.profiles = append(fo.profiles, profile)
+}
+
+// 将当前的全部设置保存为配置方案
+func (fo *FileOrganizer) currentProfile(name string) Profile {
+	return Profile{
+		Name:            name,
+		SourceDirs:      append([]string(nil), fo.SourceDirs...),
+		TargetDir:       strings.TrimSpace(fo.TargetDirEntry.Text),
+		SpillTargets:    append([]TargetVolume(nil), fo.SpillTargets...),
+		TargetMinFreeMB: fo.TargetMinFreeMB,
+		ExtensionRanks:  copyExtensionRanks(fo.ExtensionRanks),
+		ReadOnlySources: fo.currentReadOnlySources(),
+		Pins:            fo.pins.list(),
+		Rules: Preset{
+			Name:             name,
+			OrganizeRule:     fo.RuleSelect.Selected,
+			FileExtensions:   append([]string(nil), fo.FileExtensions...),
+			FolderDateFormat: fo.FolderDateFormat,
+			ExtensionCase:    fo.ExtensionCase,
+			MultiTagMode:     fo.MultiTagMode,
+			FolderLayout:     fo.FolderLayout,
+			EventLabels:      append([]EventLabel(nil), fo.EventLabels...),
+			DateSources:      append([]DateSource(nil), fo.DateSources...),
+			FolderTemplate:   fo.FolderTemplates[fo.RuleSelect.Selected],
+			RuleSegments:     append([]string(nil), fo.RuleSegments...),
+			SegmentSeparator: fo.RuleSegmentSeparator,
+		},
+		Options: ProfileOptions{
+			DateFolderMtime:      fo.DateFolderMtime,
+			EmailSenderFolders:   fo.EmailSenderFolders,
+			BurstDetection:       fo.BurstDetection,
+			BurstMaxGap:          fo.BurstMaxGap,
+			BurstMaxFrames:       fo.BurstMaxFrames,
+			PackSmallFiles:       fo.PackSmallFiles,
+			PackThresholdKB:      fo.PackThresholdKB,
+			DateTimeZone:         fo.DateTimeZone,
+			CompactExtensionsMin: fo.CompactExtensionsMin,
+			ExtensionRankPrefix:  fo.ExtensionRankPrefix,
+			AgeBucketLabels:      append([]string(nil), fo.AgeBucketLabels...),
+			SizeBucketsMB:        append([]int(nil), fo.SizeBucketsMB...),
+			DedupTarget:          fo.DedupTarget,
+			DedupEmptyFiles:      fo.DedupEmptyFiles,
+			EmptyFilePolicy:      fo.EmptyFilePolicy,
+			ShortcutPolicy:       fo.ShortcutPolicy,
+			OrganizeLibraries:    fo.OrganizeLibraries,
+			TextLanguageFolders:  fo.TextLanguageFolders,
+			TextKeywordRules:     append([]TextKeywordRule(nil), fo.TextKeywordRules...),
+			TextKeywordCase:      fo.TextKeywordCase,
+			TextPrefixKB:         fo.TextPrefixKB,
+			TakeoutMode:          fo.TakeoutMode,
+			ConflictPolicy:       fo.ConflictPolicy,
+			BackupReplaced:       fo.BackupReplaced,
+			ChecksumAlgorithm:    fo.ChecksumAlgorithm,
+			RemoveEmptiedDirs:    fo.RemoveEmptiedDirs,
+			MoveEmptyDirs:        fo.MoveEmptyDirs,
+			PreserveXattrs:       fo.PreserveXattrs,
+			LowPriority:          fo.LowPriority,
+			IncomingOnly:         fo.IncomingOnly,
+			ConvertImages:        fo.ConvertImages,
+			KeepConverted:        fo.KeepConverted,
+			CopyMerge:            fo.CopyMerge,
+			CopySuffixPatterns:   append([]string(nil), fo.CopySuffixPatterns...),
+			CatalogEnabled:       fo.CatalogEnabled,
+			ForceFullScan:        fo.ForceFullScan,
+			ValidateExtensions:   fo.ValidateExtensions,
+			ScanConcurrency:      fo.ScanConcurrency,
+			ScanErrorLimit:       fo.ScanErrorLimit,
+			StreamingThreshold:   fo.StreamingThreshold,
+			ParallelThreshold:    fo.ParallelThreshold,
+			SmallSetWorkers:      fo.SmallSetWorkers,
+			UnicodeNormalization: fo.UnicodeNormalization,
+			CollisionSeparator:   fo.CollisionSeparator,
+			CollisionPosition:    fo.CollisionPosition,
+			VolumeCapMB:          fo.VolumeCapMB,
+		},
+	}
+}
+
+// 应用配置方案的选项，未保存的数值选项保持当前值
+func (fo *FileOrganizer) applyProfileOptions(options ProfileOptions) {
+	fo.DateFolderMtime = options.DateFolderMtime
+	fo.EmailSenderFolders = options.EmailSenderFolders
+	fo.BurstDetection = options.BurstDetection
+	if options.BurstMaxGap > 0 {
+		fo.BurstMaxGap = options.BurstMaxGap
+	}
+	if options.BurstMaxFrames > 1 {
+		fo.BurstMaxFrames = options.BurstMaxFrames
+	}
+	fo.PackSmallFiles = options.PackSmallFiles
+	// 方案中的时区在其他电脑上无法识别时保留当前设置
+	if _, err := loadDateLocation(options.DateTimeZone); err == nil {
+		fo.DateTimeZone = options.DateTimeZone
+	} else {
+		fo.log(fmt.Sprintf("配置方案中的%v，保留当前的日期时区", err))
+	}
+	if options.PackThresholdKB > 0 {
+		fo.PackThresholdKB = options.PackThresholdKB
+	}
+	fo.CompactExtensionsMin = options.CompactExtensionsMin
+	fo.ExtensionRankPrefix = options.ExtensionRankPrefix
+	if len(options.AgeBucketLabels) == ageBucketCount {
+		fo.AgeBucketLabels = append([]string(nil), options.AgeBucketLabels...)
+	}
+	if len(options.SizeBucketsMB) > 0 {
+		if limits, err := parseSizeBuckets(formatSizeBuckets(options.SizeBucketsMB)); err == nil {
+			fo.SizeBucketsMB = limits
+		}
+	}
+	fo.DedupTarget = options.DedupTarget
+	fo.DedupEmptyFiles = options.DedupEmptyFiles
+	if options.EmptyFilePolicy != "" {
+		fo.EmptyFilePolicy = options.EmptyFilePolicy
+	}
+	if options.ShortcutPolicy != "" {
+		fo.ShortcutPolicy = options.ShortcutPolicy
+	}
+	fo.OrganizeLibraries = options.OrganizeLibraries
+	fo.TextLanguageFolders = options.TextLanguageFolders
+	fo.TextKeywordRules = append([]TextKeywordRule(nil), options.TextKeywordRules...)
+	fo.TextKeywordCase = options.TextKeywordCase
+	if options.TextPrefixKB > 0 {
+		fo.TextPrefixKB = options.TextPrefixKB
+	}
+	fo.TakeoutMode = options.TakeoutMode
+	// 旧的配置方案没有这两项，保持当前设置
+	if options.ConflictPolicy != "" {
+		fo.ConflictPolicy = options.ConflictPolicy
+		fo.BackupReplaced = options.BackupReplaced
+	}
+	if options.ChecksumAlgorithm != "" {
+		fo.ChecksumAlgorithm = options.ChecksumAlgorithm
+	}
+	fo.RemoveEmptiedDirs = options.RemoveEmptiedDirs
+	fo.MoveEmptyDirs = options.MoveEmptyDirs
+	fo.PreserveXattrs = options.PreserveXattrs
+	fo.LowPriority = options.LowPriority
+	fo.IncomingOnly = options.IncomingOnly
+	fo.ConvertImages = options.ConvertImages
+	fo.KeepConverted = options.KeepConverted
+	if options.CopyMerge != "" {
+		fo.CopyMerge = options.CopyMerge
+	}
+	if _, err := compileCopyPatterns(options.CopySuffixPatterns); err == nil && len(options.CopySuffixPatterns) > 0 {
+		fo.CopySuffixPatterns = append([]string(nil), options.CopySuffixPatterns...)
+	}
+	fo.CatalogEnabled = options.CatalogEnabled
+	fo.ForceFullScan = options.ForceFullScan
+	fo.ValidateExtensions = options.ValidateExtensions
+	if options.ScanConcurrency > 0 {
+		fo.ScanConcurrency = options.ScanConcurrency
+	}
+	if options.ScanErrorLimit >= 0 {
+		fo.ScanErrorLimit = options.ScanErrorLimit
+	}
+	if options.StreamingThreshold > 0 {
+		fo.StreamingThreshold = options.StreamingThreshold
+	}
+	if options.ParallelThreshold > 0 {
+		fo.ParallelThreshold = options.ParallelThreshold
+	}
+	if options.SmallSetWorkers > 0 {
+		fo.SmallSetWorkers = options.SmallSetWorkers
+	}
+	fo.VolumeCapMB = options.VolumeCapMB
+	fo.capacityPlan = nil
+	if options.UnicodeNormalization != "" {
+		fo.UnicodeNormalization = options.UnicodeNormalization
+	}
+	if validCollisionSeparator(options.CollisionSeparator) {
+		fo.CollisionSeparator = options.CollisionSeparator
+	}
+	if options.CollisionPosition == CollisionPrefix || options.CollisionPosition == CollisionSuffix {
+		fo.CollisionPosition = options.CollisionPosition
+	}
+}
+
+// 切换到配置方案：替换源文件夹、目标文件夹和全部设置，然后重新扫描
+func (fo *FileOrganizer) applyProfile(profile Profile) {
+	if fo.isScanning.Load() {
+		dialog.ShowInformation("提示", "正在扫描，请等待扫描完成后再切换配置方案", fo.Window)
+		return
+	}
+	if len(profile.SourceDirs) == 0 {
+		dialog.ShowInformation("提示", fmt.Sprintf("配置方案「%s」没有源文件夹", profile.Name), fo.Window)
+		return
+	}
+
+	// 源文件夹变化后停止监视，避免继续整理之前的文件夹
+	if fo.isWatching() {
+		fo.stopWatching()
+		fo.updateWatchButton()
+	}
+
+	fo.applyProfileOptions(profile.Options)
+
+	// 只读标记按方案中的源文件夹更新
+	readOnly := make(map[string]bool)
+	for _, dir := range profile.ReadOnlySources {
+		readOnly[dir] = true
+	}
+	for _, dir := range profile.SourceDirs {
+		if readOnly[dir] {
+			fo.readOnlySources[dir] = true
+		} else {
+			delete(fo.readOnlySources, dir)
+		}
+	}
+	fo.saveReadOnlySources()
+
+	// 固定列表随方案切换
+	fo.pins.replace(profile.Pins)
+	fo.savePins()
+
+	// 溢出目标和目标文件夹一起随方案切换
+	fo.SpillTargets = append([]TargetVolume(nil), profile.SpillTargets...)
+	fo.TargetMinFreeMB = profile.TargetMinFreeMB
+	fo.volumePlan = nil
+	fo.saveSpillTargets()
+	// 后缀序号属于方案的目标文件夹，随方案切换
+	fo.ExtensionRanks = copyExtensionRanks(profile.ExtensionRanks)
+	fo.saveExtensionRanks()
+
+	// 规则预设负责后缀、命名规则等；规则本身在添加源文件夹前设置，避免重复扫描
+	rules := profile.Rules
+	rules.Name = profile.Name
+	ruleCallback := fo.RuleSelect.OnChanged
+	fo.RuleSelect.OnChanged = nil
+	fo.applyPreset(rules)
+	fo.RuleSelect.OnChanged = ruleCallback
+	fo.TargetDirEntry.SetText(profile.TargetDir)
+
+	fo.SourceDirs = nil
+	fo.selectedSourceDirs = make(map[int]bool)
+	fo.SourceDirsList.UnselectAll()
+	fo.addSourceDirs(profile.SourceDirs)
+
+	fo.activeProfile = profile.Name
+	fyne.CurrentApp().Preferences().SetString("active_profile", profile.Name)
+	fo.log(fmt.Sprintf("已切换到配置方案: %s", profile.Name))
+}
+
+// 从文件导入配置方案，同名方案会被覆盖，返回导入的数量
+func (fo *FileOrganizer) importProfiles(reader io.Reader) (int, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, fmt.Errorf("读取配置方案文件失败: %w", err)
+	}
+	profiles, err := parseProfiles(data)
+	if err != nil {
+		return 0, err
+	}
+	for _, profile := range profiles {
+		fo.putProfile(profile)
+	}
+	return len(profiles), fo.saveProfiles()
+}
+
+// 刷新主界面的配置方案下拉框
+func (fo *FileOrganizer) refreshProfileSelect() {
+	if fo.profileSelect == nil {
+		return
+	}
+	fo.profileSelect.Options = fo.profileNames()
+	callback := fo.profileSelect.OnChanged
+	fo.profileSelect.OnChanged = nil
+	if _, ok := fo.findProfile(fo.activeProfile); ok {
+		fo.profileSelect.SetSelected(fo.activeProfile)
+	} else {
+		fo.profileSelect.ClearSelected()
+	}
+	fo.profileSelect.OnChanged = callback
+	fo.profileSelect.Refresh()
+}
+
+// 创建主界面的配置方案下拉框
+func (fo *FileOrganizer) newProfileSelect() *widget.Select {
+	fo.activeProfile = fyne.CurrentApp().Preferences().StringWithFallback("active_profile", "")
+	fo.profileSelect = widget.NewSelect(nil, func(name string) {
+		if profile, ok := fo.findProfile(name); ok && name != "" {
+			fo.applyProfile(profile)
+		}
+		// 切换失败时下拉框恢复为当前方案
+		fo.refreshProfileSelect()
+	})
+	fo.profileSelect.PlaceHolder = "选择配置方案"
+	fo.refreshProfileSelect()
+	return fo.profileSelect
+}
+
+// 显示配置方案管理对话框：保存、删除、导入和导出
+func (fo *FileOrganizer) showProfilesDialog() {
+	names := fo.profileNames()
+	selected := -1
+
+	profileList := widget.NewList(
+		func() int {
+			return len(names)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			profile, _ := fo.findProfile(names[i])
+			o.(*widget.Label).SetText(fmt.Sprintf("%s（%d 个源文件夹，%s）", profile.Name, len(profile.SourceDirs), profile.Rules.OrganizeRule))
+		},
+	)
+	profileList.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+	profileList.OnUnselected = func(id widget.ListItemID) {
+		selected = -1
+	}
+
+	reloadList := func() {
+		names = fo.profileNames()
+		selected = -1
+		profileList.UnselectAll()
+		profileList.Refresh()
+		fo.refreshProfileSelect()
+	}
+
+	applyBtn := widget.NewButton("切换到选中方案", func() {
+		if selected < 0 || selected >= len(names) {
+			dialog.ShowInformation("提示", "请先选择一个配置方案", fo.Window)
+			return
+		}
+		if profile, ok := fo.findProfile(names[selected]); ok {
+			fo.applyProfile(profile)
+			fo.refreshProfileSelect()
+		}
+	})
+	deleteBtn := widget.NewButton("删除选中方案", func() {
+		if selected < 0 || selected >= len(names) {
+			dialog.ShowInformation("提示", "请先选择一个配置方案", fo.Window)
+			return
+		}
+		name := names[selected]
+		dialog.ShowConfirm("删除配置方案", fmt.Sprintf("确定要删除配置方案「%s」吗？", name), func(confirm bool) {
+			if !confirm {
+				return
+			}
+			var remaining []Profile
+			for _, profile := range fo.profiles {
+				if profile.Name != name {
+					remaining = append(remaining, profile)
+				}
+			}
+			fo.profiles = remaining
+			if err := fo.saveProfiles(); err != nil {
+				dialog.ShowError(err, fo.Window)
+			}
+			fo.log(fmt.Sprintf("已删除配置方案: %s", name))
+			reloadList()
+		}, fo.Window)
+	})
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("方案名称，例如 照片、文档、下载")
+	saveBtn := widget.NewButton("保存当前设置为方案", func() {
+		name := strings.TrimSpace(nameEntry.Text)
+		if name == "" {
+			dialog.ShowInformation("提示", "请输入方案名称", fo.Window)
+			return
+		}
+		if len(fo.SourceDirs) == 0 {
+			dialog.ShowInformation("提示", "请先选择源文件夹，方案会保存当前的源文件夹", fo.Window)
+			return
+		}
+		save := func() {
+			fo.putProfile(fo.currentProfile(name))
+			if err := fo.saveProfiles(); err != nil {
+				dialog.ShowError(err, fo.Window)
+				return
+			}
+			fo.activeProfile = name
+			fyne.CurrentApp().Preferences().SetString("active_profile", name)
+			fo.log(fmt.Sprintf("已保存配置方案: %s", name))
+			nameEntry.SetText("")
+			reloadList()
+		}
+		if _, exists := fo.findProfile(name); exists {
+			dialog.ShowConfirm("覆盖配置方案", fmt.Sprintf("配置方案「%s」已存在，是否覆盖？", name), func(confirm bool) {
+				if confirm {
+					save()
+				}
+			}, fo.Window)
+			return
+		}
+		save()
+	})
+
+	importBtn := widget.NewButton("导入...", func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, fo.Window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+			count, err := fo.importProfiles(reader)
+			if err != nil {
+				dialog.ShowError(err, fo.Window)
+				return
+			}
+			fo.log(fmt.Sprintf("已从 %s 导入 %d 个配置方案", reader.URI().Path(), count))
+			reloadList()
+		}, fo.Window)
+	})
+	exportBtn := widget.NewButton("导出...", func() {
+		if len(fo.profiles) == 0 {
+			dialog.ShowInformation("提示", "还没有配置方案", fo.Window)
+			return
+		}
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, fo.Window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			data, err := json.MarshalIndent(fo.profiles, "", "  ")
+			if err == nil {
+				_, err = writer.Write(data)
+			}
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("导出配置方案失败: %w", err), fo.Window)
+				return
+			}
+			fo.log(fmt.Sprintf("已导出 %d 个配置方案到: %s", len(fo.profiles), writer.URI().Path()))
+		}, fo.Window)
+		saveDialog.SetFileName(fmt.Sprintf("file_organizer_profiles_%s.json", time.Now().Format("20060102")))
+		saveDialog.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+		saveDialog.Show()
+	})
+
+	listScroll := container.NewVScroll(profileList)
+	listScroll.SetMinSize(fyne.NewSize(440, 200))
+	content := container.NewVBox(
+		widget.NewLabel("已保存的配置方案（包含源文件夹、目标文件夹、整理规则和更多设置）:"),
+		listScroll,
+		container.NewGridWithColumns(2, applyBtn, deleteBtn),
+		container.NewGridWithColumns(2, importBtn, exportBtn),
+		widget.NewSeparator(),
+		container.NewBorder(nil, nil, nil, saveBtn, nameEntry),
+	)
+
+	fo.showDialog(dialog.NewCustom("配置方案", "关闭", content, fo.Window), profileList)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 只读模式下所有会移动、复制或删除文件的操作都返回这个错误
+var errReadOnlyMode = errors.New("只读模式已开启，不移动、复制或删除任何文件")
+
+// 只读模式下拒绝修改磁盘。移动、复制、改名和删除的底层函数都先检查这里，
+// 即使某个入口漏掉了检查也不会修改文件
+func (fo *FileOrganizer) checkWritable() error {
+	if fo.ReadOnlyMode {
+		return errReadOnlyMode
+	}
+	return nil
+}
+
+// 只读模式下提示用户并返回true，用于整理、撤销、清理等入口
+func (fo *FileOrganizer) refuseInReadOnlyMode() bool {
+	if !fo.ReadOnlyMode {
+		return false
+	}
+	fo.log("只读模式: 已拒绝会修改文件的操作")
+	dialog.ShowInformation("只读模式", "只读模式已开启，扫描、预览和报告照常进行，但不会移动、复制或删除任何文件。\n\n"+
+		"如需整理，请先关闭主窗口上的「只读模式」。", fo.Window)
+	return true
+}
+
+// 创建主窗口上的只读模式开关和提示横幅
+func (fo *FileOrganizer) createReadOnlyModeWidgets() (*widget.Check, *widget.Label) {
+	banner := widget.NewLabelWithStyle("只读模式：只扫描、预览和生成报告，不会移动或删除任何文件",
+		fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	banner.Importance = widget.DangerImportance
+	check := widget.NewCheck("只读模式", nil)
+	check.SetChecked(fo.ReadOnlyMode)
+	update := func() {
+		if fo.ReadOnlyMode {
+			banner.Show()
+		} else {
+			banner.Hide()
+		}
+	}
+	update()
+	check.OnChanged = func(on bool) {
+		if on == fo.ReadOnlyMode {
+			return
+		}
+		if on && fo.isWatching() {
+			fo.stopWatching()
+			fo.updateWatchButton()
+			fo.log("只读模式: 已停止监视模式")
+		}
+		fo.ReadOnlyMode = on
+		if on {
+			fo.log("已开启只读模式: 不会移动、复制或删除任何文件")
+		} else {
+			fo.log("已关闭只读模式")
+		}
+		fo.saveUserConfig()
+		update()
+	}
+	return check, banner
+}
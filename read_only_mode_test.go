@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 只读模式下重新编号、大小写合并、移动空文件夹和覆盖都不修改任何文件
+func TestReadOnlyModeRefusesChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(fo *FileOrganizer, root string) error
+	}{
+		{"重新编号后缀文件夹", func(fo *FileOrganizer, root string) error {
+			_, _, err := fo.renumberExtensionFolders(root, "lowercase", map[string]int{".jpg": 1}, map[string]int{".jpg": 2}, false)
+			return err
+		}},
+		{"大小写合并", func(fo *FileOrganizer, root string) error {
+			return fo.mergeCaseCollision(caseCollision{parent: root, existing: "photos", planned: "Photos"})
+		}},
+		{"移动空文件夹", func(fo *FileOrganizer, root string) error {
+			if moved := fo.moveEmptyTopLevelDirs(Config{SourceDirs: []string{root}, TargetDir: filepath.Join(root, "target")}); moved != 0 {
+				return errors.New("移动了空文件夹")
+			}
+			return errReadOnlyMode
+		}},
+		{"覆盖", func(fo *FileOrganizer, root string) error {
+			_, err := fo.displaceTarget(filepath.Join(root, "new.jpg"), filepath.Join(root, "photos", "a.jpg"),
+				Config{TargetDir: root}, "20240101_000000")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			fo.ReadOnlyMode = true
+			root := t.TempDir()
+			writeTestFile(t, filepath.Join(root, "photos", "a.jpg"), "a")
+			writeTestFile(t, filepath.Join(root, rankedFolderName(".jpg", 1, "lowercase"), "b.jpg"), "b")
+			emptyDir := filepath.Join(root, "empty")
+			if err := os.Mkdir(emptyDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			writeTestFile(t, filepath.Join(root, "new.jpg"), "new")
+			before := snapshotTree(t, root)
+
+			if err := tt.run(fo, root); !errors.Is(err, errReadOnlyMode) {
+				t.Fatalf("错误 = %v", err)
+			}
+			if got := snapshotTree(t, root); !reflect.DeepEqual(got, before) {
+				t.Fatalf("文件夹被修改: %v", got)
+			}
+			if _, err := os.Stat(emptyDir); err != nil {
+				t.Fatalf("空文件夹: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// 加载标记为只读的源文件夹，同一文件夹以后再次添加时仍然是只读的
+func (fo *FileOrganizer) loadReadOnlySources() {
+	fo.readOnlySources = make(map[string]bool)
+	for _, dir := range fyne.CurrentApp().Preferences().StringList("read_only_sources") {
+		fo.readOnlySources[dir] = true
+	}
+}
+
+// 保存标记为只读的源文件夹
+func (fo *FileOrganizer) saveReadOnlySources() {
+	dirs := make([]string, 0, len(fo.readOnlySources))
+	for dir := range fo.readOnlySources {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	fyne.CurrentApp().Preferences().SetStringList("read_only_sources", dirs)
+}
+
+// 当前源文件夹中标记为只读的文件夹
+func (fo *FileOrganizer) currentReadOnlySources() []string {
+	var dirs []string
+	for _, dir := range fo.SourceDirs {
+		if fo.readOnlySources[dir] {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// 源文件夹列表中显示的文字
+func (fo *FileOrganizer) sourceDirLabel(dir string) string {
+	if fo.readOnlySources[dir] {
+		return "[只读] " + dir
+	}
+	return dir
+}
+
+// 切换选中源文件夹的只读标记。只读源文件夹中的文件只复制到目标，不会被移动或修改
+func (fo *FileOrganizer) toggleSelectedReadOnly() {
+	if len(fo.selectedSourceDirs) == 0 {
+		dialog.ShowInformation("提示", "请先选择要设为只读的源文件夹", fo.Window)
+		return
+	}
+	for idx := range fo.selectedSourceDirs {
+		if idx >= len(fo.SourceDirs) {
+			continue
+		}
+		dir := fo.SourceDirs[idx]
+		if fo.readOnlySources[dir] {
+			delete(fo.readOnlySources, dir)
+			fo.log("已取消只读: " + dir)
+		} else {
+			fo.readOnlySources[dir] = true
+			fo.log("已设为只读（文件将被复制而不是移动）: " + dir)
+		}
+	}
+	fo.saveReadOnlySources()
+	fo.SourceDirsList.Refresh()
+}
+
+// 目标文件夹不能位于只读源文件夹之中，否则整理会修改只读源
+func validateReadOnlySources(config Config) error {
+	for _, dir := range config.ReadOnlySources {
+		if isWithinAny(config.TargetDir, []string{dir}) {
+			return fmt.Errorf("目标文件夹 %s 位于只读源文件夹 %s 中，请选择其他目标文件夹", config.TargetDir, dir)
+		}
+	}
+	return nil
+}
+
+// 文件是否来自只读源文件夹
+func isFromReadOnlySource(filePath string, config Config) bool {
+	return len(config.ReadOnlySources) > 0 && isWithinAny(filepath.Dir(filePath), config.ReadOnlySources)
+}
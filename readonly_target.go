@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 同一目标连续出现多少次只读错误后暂停整理
+const readOnlyPauseThreshold = 5
+
+// 目标变为只读后用户的选择
+type readOnlyAction int
+
+const (
+	readOnlyRetry    readOnlyAction = iota // 重新挂载后重试
+	readOnlyRedirect                       // 剩余文件改用其他目标文件夹
+	readOnlyAbort                          // 中止整理
+)
+
+// 是否为目标卷只读导致的错误
+func isReadOnlyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.EROFS) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "read-only file system") || strings.Contains(msg, "write protected")
+}
+
+// readOnlyGate 协调各工作协程在目标变为只读时暂停、重试、改用其他目标或中止
+type readOnlyGate struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	targetRoot  string   // 当前的目标根文件夹，改用其他目标后会变化
+	consecutive int      // 当前目标连续出现的只读错误数
+	paused      bool     // 等待用户选择时暂停所有工作协程
+	aborted     bool     // 用户选择了中止
+	deferred    []string // 因只读错误推迟的文件，整理结束前再处理一次
+	ask         func(root string, failures int, decide func(action readOnlyAction, newRoot string) error)
+	redirect    func(newRoot string) (release func(), err error) // 改用其他目标前检查并锁定新的目标，为nil时不检查
+	releases    []func()                                         // 释放改用其他目标时获取的锁
+}
+
+// 创建只读保护，ask用于询问用户如何处理，redirect在改用其他目标前检查并锁定新的目标
+func newReadOnlyGate(targetRoot string, ask func(root string, failures int, decide func(action readOnlyAction, newRoot string) error),
+	redirect func(newRoot string) (func(), error)) *readOnlyGate {
+	gate := &readOnlyGate{targetRoot: targetRoot, ask: ask, redirect: redirect}
+	gate.cond = sync.NewCond(&gate.mu)
+	return gate
+}
+
+// 等待暂停结束，返回当前的目标根文件夹以及是否已中止
+func (g *readOnlyGate) wait() (root string, aborted bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.paused {
+		g.cond.Wait()
+	}
+	return g.targetRoot, g.aborted
+}
+
+// 记录一次成功的移动，连续错误计数清零
+func (g *readOnlyGate) success() {
+	g.mu.Lock()
+	g.consecutive = 0
+	g.mu.Unlock()
+}
+
+// 记录一次只读错误并推迟该文件。同一目标连续错误达到阈值时暂停整理并询问用户
+func (g *readOnlyGate) failure(filePath, root string) {
+	g.mu.Lock()
+	g.deferred = append(g.deferred, filePath)
+	// 已经改用其他目标后，旧目标的错误不再计数
+	if root != g.targetRoot || g.paused || g.aborted {
+		g.mu.Unlock()
+		return
+	}
+	g.consecutive++
+	if g.consecutive < readOnlyPauseThreshold {
+		g.mu.Unlock()
+		return
+	}
+	g.paused = true
+	failures := g.consecutive
+	g.mu.Unlock()
+
+	g.ask(root, failures, g.resolve)
+}
+
+// 用户做出选择后恢复整理。新的目标没有通过检查或无法锁定时返回错误，整理保持暂停，用户可以另选目标或中止
+func (g *readOnlyGate) resolve(action readOnlyAction, newRoot string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch action {
+	case readOnlyRedirect:
+		if g.redirect != nil {
+			release, err := g.redirect(newRoot)
+			if err != nil {
+				return err
+			}
+			g.releases = append(g.releases, release)
+		}
+		g.targetRoot = newRoot
+	case readOnlyAbort:
+		g.aborted = true
+	}
+	g.consecutive = 0
+	g.paused = false
+	g.cond.Broadcast()
+	return nil
+}
+
+// 释放改用其他目标时获取的锁，整理结束时调用。之后的选择不再锁定目标
+func (g *readOnlyGate) release() {
+	g.mu.Lock()
+	releases := g.releases
+	g.releases, g.redirect = nil, nil
+	g.mu.Unlock()
+	for _, release := range releases {
+		release()
+	}
+}
+
+// 目标变为只读后改用其他目标前，按开始整理时的检查验证新的目标文件夹：可以创建、不是危险的文件夹、
+// 不是只读的源文件夹、保留目录结构时不是源文件夹。通过后锁定新的目标直到整理结束，返回的函数释放锁
+func (fo *FileOrganizer) lockRedirectTarget(config Config, runID, newRoot string) (func(), error) {
+	if err := validateTargetDir(newRoot); err != nil {
+		return nil, err
+	}
+	if reason := fo.dangerousTargetCheck(newRoot); reason != "" {
+		return nil, fmt.Errorf("%s: %s", reason, newRoot)
+	}
+	config.TargetDir = newRoot
+	if err := validateReadOnlySources(config); err != nil {
+		return nil, err
+	}
+	if err := validateLayoutOverlap(config); err != nil {
+		return nil, err
+	}
+	return fo.lockTarget(newRoot, runID)
+}
+
+// 取出推迟的文件
+func (g *readOnlyGate) takeDeferred() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	deferred := g.deferred
+	g.deferred = nil
+	return deferred
+}
+
+// 目标变为只读时显示对话框，让用户选择重试、改用其他目标或中止
+func (n *fyneNotifier) ReadOnlyTarget(root string, failures int, decide func(action readOnlyAction, newRoot string) error) {
+	fo := n.fo
+	fo.log(fmt.Sprintf("警告: 目标 %s 连续 %d 次写入失败（只读文件系统），整理已暂停", root, failures))
+	fo.safeUpdateUI(func() {
+		message := widget.NewLabel(fmt.Sprintf(
+			"目标文件夹所在的磁盘变成了只读状态，连续 %d 个文件写入失败:\n%s\n\n"+
+				"这通常是外接磁盘出现读写错误后被系统改为只读。整理已暂停，可以重新挂载磁盘后重试，"+
+				"也可以把剩余文件整理到其他目标文件夹，或中止本次整理。",
+			failures, root))
+		message.Wrapping = fyne.TextWrapWord
+
+		var readOnlyDialog dialog.Dialog
+		decided := false
+		choose := func(action readOnlyAction, newRoot string) bool {
+			if decided {
+				return false
+			}
+			// 新的目标不能使用时对话框保持打开，可以另选目标或中止
+			if err := decide(action, newRoot); err != nil {
+				fo.log("不能改用该目标文件夹: " + err.Error())
+				dialog.ShowError(err, fo.Window)
+				return false
+			}
+			decided = true
+			readOnlyDialog.Hide()
+			return true
+		}
+
+		retryBtn := widget.NewButton("已重新挂载，重试", func() {
+			fo.log("继续整理: 重试只读目标 " + root)
+			choose(readOnlyRetry, "")
+		})
+		retryBtn.Importance = widget.HighImportance
+		redirectBtn := widget.NewButton("改用其他目标文件夹...", func() {
+			dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+				if err != nil || dir == nil {
+					return
+				}
+				if choose(readOnlyRedirect, dir.Path()) {
+					fo.log("继续整理: 剩余文件改为整理到 " + dir.Path())
+				}
+			}, fo.Window)
+		})
+		abortBtn := widget.NewButton("中止整理", func() {
+			fo.log("已中止整理: 目标只读")
+			choose(readOnlyAbort, "")
+		})
+
+		readOnlyDialog = dialog.NewCustomWithoutButtons("目标磁盘变为只读",
+			container.NewVBox(message, container.NewHBox(layout.NewSpacer(), abortBtn, redirectBtn, retryBtn)), fo.Window)
+		readOnlyDialog.Resize(fyne.NewSize(560, 0))
+		fo.showDialog(readOnlyDialog, retryBtn)
+	})
+}
+
+// 无界面运行时无法询问用户，直接中止
+func (consoleNotifier) ReadOnlyTarget(root string, failures int, decide func(action readOnlyAction, newRoot string) error) {
+	fmt.Fprintf(os.Stderr, "目标 %s 连续 %d 次写入失败（只读文件系统），已中止整理\n", root, failures)
+	decide(readOnlyAbort, "")
+}
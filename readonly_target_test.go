@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 改用其他目标时新的目标要通过开始整理时的检查并被锁定；不能使用时整理保持暂停，目标不变
+func TestReadOnlyGateRedirect(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, fo *FileOrganizer, config *Config) string // 返回用户选择的新目标
+		wantErr bool
+	}{
+		{"可以使用的目标", func(t *testing.T, fo *FileOrganizer, config *Config) string {
+			return t.TempDir()
+		}, false},
+		{"只读源文件夹中的目标", func(t *testing.T, fo *FileOrganizer, config *Config) string {
+			config.ReadOnlySources = []string{config.SourceDir}
+			return filepath.Join(config.SourceDir, "sorted")
+		}, true},
+		{"保留目录结构时目标是源文件夹", func(t *testing.T, fo *FileOrganizer, config *Config) string {
+			config.FolderLayout = LayoutRuleFirst
+			return config.SourceDir
+		}, true},
+		{"危险的目标", func(t *testing.T, fo *FileOrganizer, config *Config) string {
+			fo.CheckDangerousTarget = true
+			return string(filepath.Separator)
+		}, true},
+		{"目标是文件", func(t *testing.T, fo *FileOrganizer, config *Config) string {
+			return writeTestFile(t, filepath.Join(t.TempDir(), "file"), "x")
+		}, true},
+		{"其他电脑正在整理的目标", func(t *testing.T, fo *FileOrganizer, config *Config) string {
+			dir := t.TempDir()
+			now := time.Now()
+			if err := writeTargetLock(targetLockPath(dir), TargetLock{Host: "other-host", PID: 1, RunID: "other", StartedAt: now, Heartbeat: now}); err != nil {
+				t.Fatal(err)
+			}
+			return dir
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			root := t.TempDir()
+			config := Config{SourceDir: source, SourceDirs: []string{source}, TargetDir: root, FolderLayout: LayoutFlat}
+			newRoot := tt.setup(t, fo, &config)
+
+			var decide func(action readOnlyAction, newRoot string) error
+			gate := newReadOnlyGate(root, func(_ string, _ int, d func(action readOnlyAction, newRoot string) error) {
+				decide = d
+			}, func(newRoot string) (func(), error) {
+				return fo.lockRedirectTarget(config, "run", newRoot)
+			})
+			for i := 0; i < readOnlyPauseThreshold; i++ {
+				gate.failure(filepath.Join(source, "a.jpg"), root)
+			}
+			if decide == nil || !gate.paused {
+				t.Fatal("连续只读错误后应暂停并询问用户")
+			}
+
+			err := decide(readOnlyRedirect, newRoot)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("改用 %s: %v, 期望出错 = %v", newRoot, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !gate.paused || gate.targetRoot != root {
+					t.Fatalf("不能使用的目标: 暂停 = %v, 目标 = %s", gate.paused, gate.targetRoot)
+				}
+				// 仍可以选择中止
+				if err := decide(readOnlyAbort, ""); err != nil || gate.paused || !gate.aborted {
+					t.Fatalf("中止: %v", err)
+				}
+				return
+			}
+			if current, aborted := gate.wait(); current != newRoot || aborted {
+				t.Fatalf("目标 = %s, 中止 = %v", current, aborted)
+			}
+			lock, err := readTargetLock(targetLockPath(newRoot))
+			if err != nil || lock.RunID != "run" {
+				t.Fatalf("新的目标应被锁定: %+v, %v", lock, err)
+			}
+			gate.release()
+			if _, err := os.Stat(targetLockPath(newRoot)); !os.IsNotExist(err) {
+				t.Fatalf("整理结束后应释放新的目标的锁: %v", err)
+			}
+		})
+	}
+}
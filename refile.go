@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 是否为目标文件夹内的重新归档：文件已经在目标根文件夹或某个溢出目标卷中（例如改变整理规则后重新整理），
+// 只需要在归档内部换个位置，而不是作为新文件导入。只读源文件夹中的文件始终按复制处理
+func isRefile(filePath string, config Config) bool {
+	if config.TargetDir == "" || isFromReadOnlySource(filePath, config) {
+		return false
+	}
+	roots := []string{config.TargetDir}
+	for _, volume := range config.Volumes {
+		roots = append(roots, volume.Root)
+	}
+	return isWithinAny(filepath.Dir(filePath), roots)
+}
+
+// 在目标文件夹内部移动文件。同一个卷内只使用重命名，不会退回到复制后删除；
+// 文件要换到另一个目标卷时按普通移动处理
+func (fo *FileOrganizer) refileFile(sourcePath, targetDir string, xattrs bool) (string, error) {
+	if err := fo.checkWritable(); err != nil {
+		return "", err
+	}
+	if err := checkSourceName(sourcePath); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return "", fmt.Errorf("创建目标目录失败: %w", err)
+	}
+	targetPath := fo.uniqueTargetPath(targetDir, filepath.Base(sourcePath))
+	if err := checkTargetPath(targetDir, targetPath); err != nil {
+		return "", err
+	}
+	if err := renameFile(sourcePath, targetPath); err != nil {
+		if strings.Contains(err.Error(), "cross-device link") {
+			return fo.moveFile(sourcePath, targetDir, xattrs)
+		}
+		return "", fmt.Errorf("重新归档失败: %w", err)
+	}
+	return targetPath, nil
+}
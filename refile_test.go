@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// 目标文件夹和溢出目标卷中的文件都按重新归档处理，只读源文件夹中的文件除外
+func TestIsRefile(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "target")
+	spill := filepath.Join(t.TempDir(), "spill")
+	source := filepath.Join(t.TempDir(), "source")
+	volumes := []TargetVolume{{Root: target}, {Root: spill}}
+	tests := []struct {
+		name     string
+		path     string
+		volumes  []TargetVolume
+		readOnly []string
+		want     bool
+	}{
+		{"源文件夹", filepath.Join(source, "a.jpg"), volumes, nil, false},
+		{"目标文件夹", filepath.Join(target, ".jpg", "a.jpg"), nil, nil, true},
+		{"溢出目标卷", filepath.Join(spill, ".jpg", "a.jpg"), volumes, nil, true},
+		{"没有设置溢出目标", filepath.Join(spill, ".jpg", "a.jpg"), nil, nil, false},
+		{"只读的目标文件夹", filepath.Join(target, "a.jpg"), nil, []string{target}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{TargetDir: target, Volumes: tt.volumes, ReadOnlySources: tt.readOnly}
+			if got := isRefile(tt.path, config); got != tt.want {
+				t.Fatalf("isRefile = %v", got)
+			}
+		})
+	}
+}
+
+// 重新归档在同一个卷内重命名，换到另一个卷时按移动处理
+func TestRefileFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		exdev bool
+	}{
+		{"同一个卷", false},
+		{"换到另一个卷", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			root := filepath.Join(t.TempDir(), "refile-root")
+			source := writeTestFile(t, filepath.Join(root, "old", "a.jpg"), "content")
+			if tt.exdev {
+				useFaults(t, "exdev=refile-root")
+			}
+			moved, err := fo.refileFile(source, filepath.Join(root, "new"), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if moved != filepath.Join(root, "new", "a.jpg") || readTestFile(t, moved) != "content" {
+				t.Fatalf("重新归档到 %s", moved)
+			}
+			if got := snapshotTree(t, root); len(got) != 1 {
+				t.Fatalf("重新归档后 = %v", got)
+			}
+		})
+	}
+}
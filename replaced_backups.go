@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 目标中已有同名文件时的处理方式
+const (
+	ConflictRename    = "rename"    // 新文件加时间戳，已有文件保持不变
+	ConflictOverwrite = "overwrite" // 新文件替换已有文件
+	ConflictMerge     = "merge"     // 内容相同时跳过新文件，内容不同时新文件加时间戳
+)
+
+// 覆盖前备份的文件所在的文件夹，每次整理一个子文件夹：目标/_replaced/<整理编号>/<原相对路径>
+const ReplacedFolderName = "_replaced"
+
+// 清理覆盖备份的默认条件
+const (
+	defaultReplacedKeepDays = 30
+	defaultReplacedMaxMB    = 0 // 不限制总大小
+)
+
+// 覆盖备份的根文件夹
+func replacedRoot(targetDir string) string {
+	return filepath.Join(targetDir, ReplacedFolderName)
+}
+
+// 未开启覆盖备份时，被覆盖的文件在新文件放入前临时改名的后缀，新文件放入后删除
+const replacingSuffix = ".fileorganizer.replacing"
+
+// displacedFile 覆盖时为新文件腾出位置而移走的已有文件。新文件放入后调用 discardDisplaced，
+// 放入失败时调用 restoreDisplaced 移回原处，任何时候都不会两个文件都丢失
+type displacedFile struct {
+	original string // 已有文件原来的位置
+	moved    string // 移走后的位置：覆盖备份，或者未开启备份时同一文件夹中的临时文件
+	backup   bool   // moved 是覆盖备份，新文件放入后保留
+}
+
+// 为新文件腾出目标位置：目标中已有同名的普通文件时，开启备份则移到覆盖备份文件夹中，
+// 否则在同一文件夹中改为临时文件名，等新文件放入后再删除。备份使用与整理相同的 moveFile，
+// 备份文件夹中重名时同样加时间戳。没有同名文件时返回nil
+func (fo *FileOrganizer) displaceTarget(sourcePath, targetPath string, config Config, runID string) (*displacedFile, error) {
+	if err := fo.checkWritable(); err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(targetPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取目标中的同名文件失败: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		// 同名的文件夹或链接不覆盖，由整理时加时间戳
+		return nil, nil
+	}
+	if sourceInfo, err := os.Stat(sourcePath); err == nil && os.SameFile(sourceInfo, info) {
+		return nil, nil
+	}
+	if !config.BackupReplaced {
+		tmpPath := targetPath + "." + runID + replacingSuffix
+		if _, err := os.Lstat(tmpPath); err == nil {
+			return nil, fmt.Errorf("目标中已有覆盖时留下的临时文件 %s", tmpPath)
+		}
+		if err := renameFile(targetPath, tmpPath); err != nil {
+			return nil, fmt.Errorf("移走目标中的同名文件失败: %w", err)
+		}
+		return &displacedFile{original: targetPath, moved: tmpPath}, nil
+	}
+	rel, err := filepath.Rel(config.TargetDir, filepath.Dir(targetPath))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = ""
+	}
+	backupPath, err := fo.moveFile(targetPath, filepath.Join(replacedRoot(config.TargetDir), runID, rel), config.PreserveXattrs)
+	if err != nil {
+		return nil, fmt.Errorf("覆盖前备份失败: %w", err)
+	}
+	return &displacedFile{original: targetPath, moved: backupPath, backup: true}, nil
+}
+
+// 新文件已放入：未开启备份时删除移走的已有文件，备份保留在覆盖备份文件夹中。
+// 只读模式在此期间开启时不删除，被覆盖的文件留在临时文件中
+func (fo *FileOrganizer) discardDisplaced(d *displacedFile) error {
+	if d.backup {
+		return nil
+	}
+	if err := fo.checkWritable(); err != nil {
+		return fmt.Errorf("被覆盖的文件保留在 %s: %w", d.moved, err)
+	}
+	if err := os.Remove(d.moved); err != nil {
+		return fmt.Errorf("删除被覆盖的文件 %s 失败: %w", d.moved, err)
+	}
+	return nil
+}
+
+// 新文件没有放入：把移走的已有文件放回原来的位置。备份在其他卷上时复制回来后删除备份。
+// 这是撤回刚才的修改，只读模式下也照常放回
+func (fo *FileOrganizer) restoreDisplaced(d *displacedFile) error {
+	err := renameFile(d.moved, d.original)
+	if err != nil && strings.Contains(err.Error(), "cross-device link") {
+		if err = fo.copyFileContents(d.moved, d.original); err == nil {
+			os.Remove(d.moved)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("放回被覆盖的文件失败，文件在 %s: %w", d.moved, err)
+	}
+	return nil
+}
+
+// 合并到已有文件夹时比较目标中的同名文件：不存在时 exists 为 false；是内容相同的普通文件时 same 为 true。
+// 先比较大小，大小相同时再比较哈希值
+func (fo *FileOrganizer) sameNamedTarget(sourcePath string, sourceInfo os.FileInfo, targetPath string) (exists, same bool, err error) {
+	info, err := os.Lstat(targetPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("读取目标中的同名文件失败: %w", err)
+	}
+	if !info.Mode().IsRegular() || info.Size() != sourceInfo.Size() {
+		return true, false, nil
+	}
+	if os.SameFile(sourceInfo, info) {
+		return true, true, nil
+	}
+	sourceHash, err := fo.contentHashes.get(sourcePath, sourceInfo)
+	if err != nil {
+		return true, false, err
+	}
+	targetHash, err := fo.contentHashes.get(targetPath, info)
+	if err != nil {
+		return true, false, err
+	}
+	return true, sourceHash == targetHash, nil
+}
+
+// replacedRun 一次整理留下的覆盖备份
+type replacedRun struct {
+	dir   string
+	time  time.Time
+	size  int64
+	files int
+}
+
+// 列出覆盖备份，按整理时间从早到晚排列。文件夹名称不是整理编号时使用修改时间
+func listReplacedRuns(targetDir string) ([]replacedRun, error) {
+	entries, err := os.ReadDir(replacedRoot(targetDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取覆盖备份失败: %w", err)
+	}
+	var runs []replacedRun
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		run := replacedRun{dir: filepath.Join(replacedRoot(targetDir), entry.Name())}
+		if t, ok := parseRunID(entry.Name()); ok {
+			run.time = t
+		} else if info, err := entry.Info(); err == nil {
+			run.time = info.ModTime()
+		}
+		err := filepath.WalkDir(run.dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			if info, err := d.Info(); err == nil {
+				run.size += info.Size()
+				run.files++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("统计覆盖备份 %s 失败: %w", entry.Name(), err)
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].time.Before(runs[j].time)
+	})
+	return runs, nil
+}
+
+// 清理覆盖备份：删除超过 keepDays 天的整理留下的备份，总大小仍超过 maxBytes 时从最早的开始删除。
+// keepDays 或 maxBytes 为0时不按该条件清理
+func pruneReplacedBackups(targetDir string, keepDays int, maxBytes int64, now time.Time) (removed, files int, freed int64, err error) {
+	runs, err := listReplacedRuns(targetDir)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var total int64
+	for _, run := range runs {
+		total += run.size
+	}
+	cutoff := now.AddDate(0, 0, -keepDays)
+	for _, run := range runs {
+		expired := keepDays > 0 && run.time.Before(cutoff)
+		oversize := maxBytes > 0 && total > maxBytes
+		if !expired && !oversize {
+			continue
+		}
+		if err := os.RemoveAll(run.dir); err != nil {
+			return removed, files, freed, fmt.Errorf("删除覆盖备份 %s 失败: %w", run.dir, err)
+		}
+		removed++
+		files += run.files
+		freed += run.size
+		total -= run.size
+	}
+	if entries, err := os.ReadDir(replacedRoot(targetDir)); err == nil && len(entries) == 0 {
+		os.Remove(replacedRoot(targetDir))
+	}
+	return removed, files, freed, nil
+}
+
+// 显示目标中的覆盖备份，按保留天数和总大小清理
+func (fo *FileOrganizer) showReplacedCleanupDialog() {
+	targetDir := fo.selectedTargetDir()
+	if targetDir == "" {
+		dialog.ShowInformation("提示", "请先选择目标文件夹", fo.Window)
+		return
+	}
+	runs, err := listReplacedRuns(targetDir)
+	if err != nil {
+		dialog.ShowError(err, fo.Window)
+		return
+	}
+	var total int64
+	files := 0
+	for _, run := range runs {
+		total += run.size
+		files += run.files
+	}
+	summary := widget.NewLabel(fmt.Sprintf("%s 中有 %d 次整理留下的覆盖备份，共 %d 个文件（%s）",
+		replacedRoot(targetDir), len(runs), files, formatFileSize(total)))
+	summary.Wrapping = fyne.TextWrapWord
+
+	keepDaysEntry := widget.NewEntry()
+	keepDaysEntry.SetText(strconv.Itoa(fo.ReplacedKeepDays))
+	keepDaysEntry.Validator = func(text string) error {
+		if days, err := strconv.Atoi(strings.TrimSpace(text)); err != nil || days < 0 {
+			return errors.New("请输入不小于0的整数")
+		}
+		return nil
+	}
+	maxMBEntry := widget.NewEntry()
+	maxMBEntry.SetText(strconv.FormatInt(fo.ReplacedMaxMB, 10))
+	maxMBEntry.Validator = func(text string) error {
+		if mb, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64); err != nil || mb < 0 {
+			return errors.New("请输入不小于0的整数")
+		}
+		return nil
+	}
+	form := widget.NewForm(
+		widget.NewFormItem("保留天数（0不限）", keepDaysEntry),
+		widget.NewFormItem("总大小上限（MB，0不限）", maxMBEntry),
+	)
+
+	var cleanupDialog dialog.Dialog
+	closeBtn := widget.NewButton("关闭", func() {
+		cleanupDialog.Hide()
+	})
+	pruneBtn := widget.NewButton("清理", func() {
+		if keepDaysEntry.Validate() != nil || maxMBEntry.Validate() != nil || fo.refuseInReadOnlyMode() {
+			return
+		}
+		if fo.runActive() {
+			dialog.ShowInformation("提示", "正在整理文件，请等待整理完成后再清理", fo.Window)
+			return
+		}
+		keepDays, _ := strconv.Atoi(strings.TrimSpace(keepDaysEntry.Text))
+		maxMB, _ := strconv.ParseInt(strings.TrimSpace(maxMBEntry.Text), 10, 64)
+		fo.ReplacedKeepDays = keepDays
+		fo.ReplacedMaxMB = maxMB
+		fo.saveUserConfig()
+		cleanupDialog.Hide()
+		go func() {
+			removed, files, freed, err := pruneReplacedBackups(targetDir, keepDays, maxMB<<20, time.Now())
+			fo.log(fmt.Sprintf("清理覆盖备份: 删除了 %d 次整理的 %d 个文件，释放 %s", removed, files, formatFileSize(freed)))
+			fo.safeUpdateUI(func() {
+				if err != nil {
+					fo.log(err.Error())
+					dialog.ShowError(err, fo.Window)
+					return
+				}
+				dialog.ShowInformation("清理完成", fmt.Sprintf("删除了 %d 次整理留下的 %d 个文件，释放 %s。\n被删除的备份无法再通过撤销恢复。",
+					removed, files, formatFileSize(freed)), fo.Window)
+			})
+		}()
+	})
+
+	content := container.NewBorder(summary,
+		container.NewHBox(layout.NewSpacer(), pruneBtn, closeBtn),
+		nil, nil,
+		form,
+	)
+	cleanupDialog = dialog.NewCustomWithoutButtons("清理覆盖备份", content, fo.Window)
+	cleanupDialog.Resize(fyne.NewSize(520, 260))
+	fo.showDialog(cleanupDialog, closeBtn)
+}
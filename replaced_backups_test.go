@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDisplaceTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		backup  bool
+		restore bool
+	}{
+		{"不备份，放入成功后删除", false, false},
+		{"不备份，放入失败时放回", false, true},
+		{"备份，放入成功后保留备份", true, false},
+		{"备份，放入失败时放回", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			target := t.TempDir()
+			existing := writeTestFile(t, filepath.Join(target, ".jpg", "a.jpg"), "old")
+			source := writeTestFile(t, filepath.Join(t.TempDir(), "a.jpg"), "new")
+			config := Config{TargetDir: target, BackupReplaced: tt.backup}
+
+			displaced, err := fo.displaceTarget(source, existing, config, "20240101_000000")
+			if err != nil || displaced == nil {
+				t.Fatalf("displaceTarget = %v, %v", displaced, err)
+			}
+			if _, err := os.Lstat(existing); !os.IsNotExist(err) {
+				t.Fatal("已有文件应已移走")
+			}
+			if got := readTestFile(t, displaced.moved); got != "old" {
+				t.Fatalf("移走的文件内容 = %q", got)
+			}
+
+			if tt.restore {
+				if err := fo.restoreDisplaced(displaced); err != nil {
+					t.Fatal(err)
+				}
+				if got := readTestFile(t, existing); got != "old" {
+					t.Fatalf("放回后的内容 = %q", got)
+				}
+				if _, err := os.Lstat(displaced.moved); !os.IsNotExist(err) {
+					t.Fatal("放回后不应留下移走的文件")
+				}
+				return
+			}
+			if err := fo.discardDisplaced(displaced); err != nil {
+				t.Fatal(err)
+			}
+			_, err = os.Lstat(displaced.moved)
+			if tt.backup && err != nil {
+				t.Fatalf("备份应保留: %v", err)
+			}
+			if !tt.backup && !os.IsNotExist(err) {
+				t.Fatal("未开启备份时应删除移走的文件")
+			}
+		})
+	}
+}
+
+// 覆盖时新文件放入失败（跨设备复制中途出错），目标中原有的文件必须保留
+func TestOverwriteKeepsExistingWhenMoveFails(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := filepath.Join(t.TempDir(), "incoming-src")
+	target := t.TempDir()
+	sourceFile := writeTestFile(t, filepath.Join(source, "a.jpg"), "new content")
+	existing := writeTestFile(t, filepath.Join(target, ".jpg", "a.jpg"), "old content")
+
+	useFaults(t, "exdev=incoming-src,copy-fail-after=1")
+
+	config := Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      target,
+		FileExtensions: []string{".jpg"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		ConflictPolicy: ConflictOverwrite,
+		ExcludedFiles:  map[string]bool{},
+	}
+	fo.processFiles(config, []string{sourceFile})
+
+	if got := readTestFile(t, existing); got != "old content" {
+		t.Fatalf("目标中原有的文件 = %q", got)
+	}
+	if got := readTestFile(t, sourceFile); got != "new content" {
+		t.Fatalf("源文件 = %q", got)
+	}
+	entries, _ := os.ReadDir(filepath.Join(target, ".jpg"))
+	if len(entries) != 1 {
+		t.Fatalf("目标文件夹中应只有原来的文件，实际 %d 个", len(entries))
+	}
+}
+
+func TestWatchModeOverwrite(t *testing.T) {
+	fo := newTestOrganizer(t)
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, ".jpg", "a.jpg"), "old")
+	incoming := writeTestFile(t, filepath.Join(root, "a.jpg"), "new")
+
+	wr := &watchedRoot{root: root, pending: make(map[string]*time.Timer), config: Config{
+		SourceDir:      root,
+		SourceDirs:     []string{root},
+		TargetDir:      root,
+		FileExtensions: []string{".jpg"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		ConflictPolicy: ConflictOverwrite,
+		BackupReplaced: true,
+		ExcludedFiles:  map[string]bool{},
+	}}
+	fo.handleWatchedFile(wr, incoming)
+
+	if got := readTestFile(t, filepath.Join(root, ".jpg", "a.jpg")); got != "new" {
+		t.Fatalf("覆盖后的文件 = %q", got)
+	}
+	runs, err := listReplacedRuns(root)
+	if err != nil || len(runs) != 1 || runs[0].files != 1 {
+		t.Fatalf("覆盖备份 = %+v, %v", runs, err)
+	}
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 撤销时用来确定一条记录的键。同一次整理中最终路径不会重复，
+// 打包的小文件共用一个压缩包，按压缩包中的名称区分
+type catalogKey struct {
+	runID     string
+	finalPath string
+	member    string
+}
+
+func (e CatalogEntry) key() catalogKey {
+	return catalogKey{runID: e.RunID, finalPath: e.FinalPath, member: e.Member}
+}
+
+// 读取目录中最近一次整理的记录，按整理顺序排列。目录为空时返回空的RunID
+func lastCatalogRun(path string) (string, []CatalogEntry, error) {
+	db, err := openCatalogForRead(path)
+	if err != nil || db == nil {
+		return "", nil, err
+	}
+	// RunID 是整理开始的时间，按字符串比较即按时间比较，最大值从索引中读取
+	var lastRun sql.NullString
+	err = db.QueryRow("SELECT max(run_id) FROM entries").Scan(&lastRun)
+	db.Close()
+	if err != nil {
+		return "", nil, catalogError("读取整理目录失败", err)
+	}
+	if !lastRun.Valid {
+		return "", nil, nil
+	}
+	run, err := queryCatalog(path, "run_id = ?", "", 0, lastRun.String)
+	return lastRun.String, run, err
+}
+
+// 从目录中删除指定的记录，在一个事务中完成
+func removeCatalogEntries(path string, remove map[catalogKey]bool) error {
+	if len(remove) == 0 {
+		return nil
+	}
+	db, err := openCatalogDB(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return catalogError("更新整理目录失败", err)
+	}
+	defer tx.Rollback()
+	for key := range remove {
+		if _, err := tx.Exec("DELETE FROM entries WHERE run_id = ? AND final_path = ? AND member = ?",
+			key.runID, key.finalPath, key.member); err != nil {
+			return catalogError("更新整理目录失败", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return catalogError("更新整理目录失败", err)
+	}
+	return nil
+}
+
+// restoreSlot 撤销时文件要移回的位置
+type restoreSlot struct {
+	path      string
+	displaced *displacedFile // 为移回腾出位置而移走的已有文件，移回成功后丢弃，移回失败时放回原处
+	same      bool           // 合并时原位置已有内容相同的文件，不需要移回
+}
+
+// 撤销时原位置已有文件，按冲突设置处理：加时间戳时换一个不冲突的文件名；覆盖时先把已有的文件
+// 移到覆盖备份（未开启备份时改为临时文件名），同名的是文件夹或链接时仍加时间戳；合并时内容相同
+// 则不需要移回，内容不同时加时间戳
+func (fo *FileOrganizer) clearRestorePath(entry CatalogEntry, restorePath string, config Config, undoID string) (restoreSlot, error) {
+	info, err := os.Lstat(restorePath)
+	if err != nil {
+		return restoreSlot{path: restorePath}, nil
+	}
+	// 没有目标文件夹时不知道覆盖备份放在哪里，不覆盖
+	if config.ConflictPolicy == ConflictOverwrite && (config.TargetDir != "" || !config.BackupReplaced) {
+		displaced, err := fo.displaceTarget(entry.FinalPath, restorePath, config, undoID)
+		if err != nil {
+			return restoreSlot{}, err
+		}
+		if displaced != nil {
+			return restoreSlot{path: restorePath, displaced: displaced}, nil
+		}
+	}
+	// 打包的小文件不在磁盘上，无法比较内容，按加时间戳处理
+	if config.ConflictPolicy == ConflictMerge && entry.Member == "" && info.Mode().IsRegular() {
+		if finalInfo, err := os.Stat(entry.FinalPath); err == nil {
+			if _, same, err := fo.sameNamedTarget(entry.FinalPath, finalInfo, restorePath); err == nil && same {
+				return restoreSlot{path: restorePath, same: true}, nil
+			}
+		}
+	}
+	renamed, err := fo.uniqueRestorePath(restorePath)
+	if err != nil {
+		return restoreSlot{}, err
+	}
+	fo.log(fmt.Sprintf("[撤销] 原位置已有 %s，移回为 %s", restorePath, filepath.Base(renamed)))
+	return restoreSlot{path: renamed}, nil
+}
+
+// 撤销时加时间戳的文件名。时间戳只精确到秒，同一秒内移回多个同名文件时再加序号
+func (fo *FileOrganizer) uniqueRestorePath(restorePath string) (string, error) {
+	stem, ext := splitExtension(filepath.Base(restorePath))
+	timestamp := time.Now().Format("20060102_150405")
+	for n := 1; n <= 1000; n++ {
+		renamed := filepath.Join(filepath.Dir(restorePath), collisionCandidate(stem, ext, timestamp, n, fo.CollisionSeparator, fo.CollisionPosition))
+		if _, err := os.Lstat(renamed); errors.Is(err, os.ErrNotExist) {
+			return renamed, nil
+		}
+	}
+	return "", errors.New("原位置已有同名文件")
+}
+
+// 把整理过的文件移回原来的文件夹，恢复整理开始时的文件名，返回移回后的路径。
+// 原位置已有文件时按冲突设置处理；原来的文件系统不能使用原文件名时保留整理后的文件名
+func (fo *FileOrganizer) moveBack(entry CatalogEntry, config Config, undoID string) (string, error) {
+	if err := fo.checkWritable(); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(entry.FinalPath); err != nil {
+		return "", fmt.Errorf("文件已不在记录的位置: %w", err)
+	}
+	// 整理目录中的文件名可能被改动过，只移回到原文件夹中
+	if err := checkFileName(entry.originalName()); err != nil {
+		return "", err
+	}
+	dir := filepath.Dir(entry.OriginalPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建原目录失败: %w", err)
+	}
+	slot, err := fo.clearRestorePath(entry, filepath.Join(dir, entry.originalName()), config, undoID)
+	if err != nil {
+		return "", err
+	}
+	if slot.same {
+		// 原位置的文件与整理后的文件内容相同，删除整理后的文件即可
+		if err := os.Remove(entry.FinalPath); err != nil {
+			return "", fmt.Errorf("删除整理后的文件失败: %w", err)
+		}
+		fo.log(fmt.Sprintf("[撤销] 原位置已有内容相同的 %s，删除整理后的文件", slot.path))
+		return slot.path, nil
+	}
+	err = fo.placeSlot(entry, slot)
+	if err != nil && invalidNameError(err) && entry.finalName() != entry.originalName() {
+		fallback, clearErr := fo.clearRestorePath(entry, filepath.Join(dir, entry.finalName()), config, undoID)
+		if clearErr != nil {
+			return "", clearErr
+		}
+		fo.log(fmt.Sprintf("[撤销] 警告: %s 所在的文件系统不能使用原文件名 %q（%v），保留整理后的文件名 %s",
+			dir, entry.originalName(), err, filepath.Base(fallback.path)))
+		slot = fallback
+		err = fo.placeSlot(entry, slot)
+	}
+	if err != nil {
+		return "", err
+	}
+	return slot.path, nil
+}
+
+// 把文件放到腾出的位置。放入失败时把移走的已有文件放回原处，成功后才删除未备份的已有文件
+func (fo *FileOrganizer) placeSlot(entry CatalogEntry, slot restoreSlot) error {
+	if err := fo.placeBack(entry, slot.path); err != nil {
+		if slot.displaced != nil {
+			if restoreErr := fo.restoreDisplaced(slot.displaced); restoreErr != nil {
+				fo.log(fmt.Sprintf("[撤销] 错误: %v", restoreErr))
+			}
+		}
+		return err
+	}
+	if slot.displaced == nil {
+		return nil
+	}
+	if slot.displaced.backup {
+		fo.log(fmt.Sprintf("[撤销] 原位置已有 %s，已移到覆盖备份 %s", slot.path, slot.displaced.moved))
+		return nil
+	}
+	if err := fo.discardDisplaced(slot.displaced); err != nil {
+		fo.log(fmt.Sprintf("[撤销] 警告: %v", err))
+	}
+	fo.log(fmt.Sprintf("[撤销] 原位置已有 %s，已覆盖", slot.path))
+	return nil
+}
+
+// 撤销一条记录，返回文件移回后的路径。复制的文件在源文件仍在原处时删除副本，返回空字符串；
+// 源文件已不在时按移动的文件移回
+func (fo *FileOrganizer) revertEntry(entry CatalogEntry, config Config, undoID string) (string, error) {
+	if entry.Operation == CatalogCopy {
+		if _, err := os.Lstat(entry.OriginalPath); err == nil {
+			return "", fo.removeCopy(entry)
+		}
+		fo.log(fmt.Sprintf("[撤销] 复制的源文件已不在 %s，把副本移回", entry.OriginalPath))
+	}
+	return fo.moveBack(entry, config, undoID)
+}
+
+// 删除从只读源文件夹复制到目标的副本。打包的小文件由 rollbackEntries 最后按压缩包一起删除
+func (fo *FileOrganizer) removeCopy(entry CatalogEntry) error {
+	if err := fo.checkWritable(); err != nil {
+		return err
+	}
+	if entry.Member != "" {
+		return nil
+	}
+	if err := os.Remove(entry.FinalPath); err != nil {
+		return fmt.Errorf("删除副本失败: %w", err)
+	}
+	return nil
+}
+
+// 把文件从整理后的位置放到指定路径：打包的小文件从压缩包中取出（由 rollbackEntries 最后从压缩包中删除），
+// 跨卷时复制后删除
+func (fo *FileOrganizer) placeBack(entry CatalogEntry, restorePath string) error {
+	if entry.Member != "" {
+		return extractZipMember(entry.FinalPath, entry.Member, restorePath)
+	}
+
+	err := renameFile(entry.FinalPath, restorePath)
+	if err == nil {
+		return nil
+	}
+	if !strings.Contains(err.Error(), "cross-device link") {
+		return fmt.Errorf("移回失败: %w", err)
+	}
+	// 跨卷时复制后删除
+	if err := fo.copyFileContents(entry.FinalPath, restorePath); err != nil {
+		return err
+	}
+	if err := os.Remove(entry.FinalPath); err != nil {
+		fo.log(fmt.Sprintf("警告: 已复制回原位置但无法删除整理后的文件 %s: %v", entry.FinalPath, err))
+	}
+	return nil
+}
+
+// 撤销选中的记录：按整理的相反顺序逐个移回原位置，成功移回的记录从目录中删除。
+// 覆盖过的位置先移走新文件，再恢复被覆盖的文件。原位置的冲突按config中的冲突设置处理
+func (fo *FileOrganizer) rollbackEntries(entries []CatalogEntry, config Config) (reverted, failed int, err error) {
+	// 撤销时覆盖的文件备份到以撤销时间命名的文件夹
+	undoID := newRunID()
+	// 撤销与整理一样修改目标文件夹，锁定后其他电脑不能同时整理
+	unlock, err := fo.lockTarget(config.TargetDir, undoID)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+	removed := make(map[catalogKey]bool, len(entries))
+	// 打包的小文件取出后按压缩包一起删除，每个压缩包只重写一次
+	packed := make(map[string][]CatalogEntry)
+	packedCopies := make(map[catalogKey]bool)
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		restoredPath, moveErr := fo.revertEntry(entry, config, undoID)
+		if moveErr != nil {
+			failed++
+			fo.log(fmt.Sprintf("[撤销] 跳过 %s: %v", entry.displayPath(), moveErr))
+			continue
+		}
+		if entry.Member != "" {
+			packed[entry.FinalPath] = append(packed[entry.FinalPath], entry)
+			if restoredPath == "" {
+				packedCopies[entry.key()] = true
+				continue
+			}
+		}
+		reverted++
+		removed[entry.key()] = true
+		if restoredPath == "" {
+			fo.log(fmt.Sprintf("[撤销] 已删除副本 %s，源文件 %s 保留在原处", entry.displayPath(), entry.OriginalPath))
+		} else {
+			fo.log(fmt.Sprintf("[撤销] 已移回 %s → %s", entry.displayPath(), restoredPath))
+		}
+	}
+	archives := make([]string, 0, len(packed))
+	for archivePath := range packed {
+		archives = append(archives, archivePath)
+	}
+	sort.Strings(archives)
+	for _, archivePath := range archives {
+		members := make(map[string]bool, len(packed[archivePath]))
+		for _, entry := range packed[archivePath] {
+			members[entry.Member] = true
+		}
+		removeErr := removeZipMembers(archivePath, members)
+		for _, entry := range packed[archivePath] {
+			if !packedCopies[entry.key()] {
+				// 已取出到原位置，压缩包中留下的副本不影响撤销
+				if removeErr != nil {
+					fo.log(fmt.Sprintf("警告: 已取出到原位置但无法从压缩包中删除 %s: %v", entry.displayPath(), removeErr))
+				}
+				continue
+			}
+			if removeErr != nil {
+				failed++
+				fo.log(fmt.Sprintf("[撤销] 跳过 %s: %v", entry.displayPath(), removeErr))
+				continue
+			}
+			reverted++
+			removed[entry.key()] = true
+			fo.log(fmt.Sprintf("[撤销] 已删除副本 %s，源文件 %s 保留在原处", entry.displayPath(), entry.OriginalPath))
+		}
+	}
+	if len(removed) == 0 {
+		return reverted, failed, nil
+	}
+	return reverted, failed, removeCatalogEntries(catalogPath(), removed)
+}
+
+// 显示最近一次整理的记录，选择其中的文件移回原位置，其余的文件保持不变
+func (fo *FileOrganizer) showRollbackDialog() {
+	if fo.runActive() {
+		dialog.ShowInformation("提示", "正在整理文件，请等待整理完成后再撤销", fo.Window)
+		return
+	}
+	runID, entries, err := lastCatalogRun(catalogPath())
+	if err != nil && !errors.Is(err, errCatalogCorrupt) {
+		dialog.ShowError(err, fo.Window)
+		return
+	}
+	if len(entries) == 0 && !fo.CatalogEnabled {
+		// 撤销依赖整理目录，整理目录默认关闭，在这里提供开启，之后的整理就可以撤销
+		dialog.ShowConfirm("开启整理目录",
+			"撤销文件依赖整理目录中的记录，整理目录没有开启，以前的整理无法撤销。\n\n现在开启整理目录吗？开启后的整理可以撤销。",
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				fo.CatalogEnabled = true
+				fo.saveUserConfig()
+				fo.log("已开启整理目录: " + catalogPath())
+			}, fo.Window)
+		return
+	}
+	if len(entries) == 0 {
+		dialog.ShowInformation("提示", "整理目录中没有可以撤销的记录", fo.Window)
+		return
+	}
+
+	selected := make([]bool, len(entries))
+	status := widget.NewLabel("")
+	updateStatus := func() {
+		count := 0
+		for _, s := range selected {
+			if s {
+				count++
+			}
+		}
+		text := fmt.Sprintf("最近一次整理（%s）共 %d 个文件，已选择 %d 个", runID, len(entries), count)
+		if !fo.CatalogEnabled {
+			text += "。整理目录已关闭，这是关闭前记录的最后一次整理，之后的整理没有记录"
+		}
+		status.SetText(text)
+	}
+	updateStatus()
+	entryList := widget.NewList(
+		func() int { return len(entries) },
+		func() fyne.CanvasObject {
+			return widget.NewCheck("", nil)
+		},
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			entry := entries[id]
+			check := o.(*widget.Check)
+			check.OnChanged = nil
+			check.Text = fmt.Sprintf("%s  ←  %s", entry.displayPath(), entry.OriginalPath)
+			if entry.Replaced {
+				check.Text += "（覆盖前备份）"
+			}
+			if entry.Operation == CatalogCopy {
+				check.Text += "（复制，撤销时删除副本）"
+			}
+			check.SetChecked(selected[id])
+			check.OnChanged = func(checked bool) {
+				selected[id] = checked
+				updateStatus()
+			}
+		},
+	)
+	setAll := func(checked bool) {
+		for i := range selected {
+			selected[i] = checked
+		}
+		entryList.Refresh()
+		updateStatus()
+	}
+
+	var rollbackDialog dialog.Dialog
+	closeBtn := widget.NewButton("关闭", func() {
+		rollbackDialog.Hide()
+	})
+	revertBtn := widget.NewButton("撤销所选文件", func() {
+		if fo.refuseInReadOnlyMode() {
+			return
+		}
+		var chosen []CatalogEntry
+		for i, s := range selected {
+			if s {
+				chosen = append(chosen, entries[i])
+			}
+		}
+		if len(chosen) == 0 {
+			dialog.ShowInformation("提示", "请先选择要撤销的文件", fo.Window)
+			return
+		}
+		dialog.ShowConfirm("撤销所选文件", fmt.Sprintf("将把 %d 个文件移回整理前的位置，其余文件保持不变。确定继续吗？", len(chosen)),
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				if fo.runActive() {
+					dialog.ShowInformation("提示", "正在整理文件，请等待整理完成后再撤销", fo.Window)
+					return
+				}
+				rollbackDialog.Hide()
+				config := fo.currentConfig()
+				go func() {
+					reverted, failed, err := fo.rollbackEntries(chosen, config)
+					fo.log(fmt.Sprintf("[撤销] 完成: 移回 %d 个文件，%d 个失败", reverted, failed))
+					fo.safeUpdateUI(func() {
+						if err != nil && reverted+failed == 0 {
+							// 没有开始撤销，例如目标文件夹正在被其他电脑整理
+							dialog.ShowError(err, fo.Window)
+							return
+						}
+						if err != nil {
+							dialog.ShowError(fmt.Errorf("文件已移回，但%w", err), fo.Window)
+							return
+						}
+						dialog.ShowInformation("撤销完成", fmt.Sprintf("已移回 %d 个文件，%d 个失败（原因见日志）", reverted, failed), fo.Window)
+					})
+				}()
+			}, fo.Window)
+	})
+	revertBtn.Importance = widget.HighImportance
+
+	content := container.NewBorder(
+		container.NewVBox(status, container.NewHBox(
+			widget.NewButton("全选", func() { setAll(true) }),
+			widget.NewButton("全不选", func() { setAll(false) }),
+		)),
+		container.NewHBox(layout.NewSpacer(), closeBtn, revertBtn),
+		nil, nil,
+		entryList,
+	)
+	rollbackDialog = dialog.NewCustomWithoutButtons("撤销部分文件", content, fo.Window)
+	rollbackDialog.Resize(fyne.NewSize(760, 520))
+	fo.showDialog(rollbackDialog, closeBtn)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 文件夹中所有文件的相对路径和内容
+func snapshotTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	tree := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		tree[rel] = readTestFile(t, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// 整理同名和带特殊字符的文件后全部撤销，源文件夹应与整理前完全相同
+func TestUndoRestoresOriginalNames(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	target := t.TempDir()
+	names := []string{
+		"a/photo.jpg",
+		"b/photo.jpg",
+		"c/photo.jpg",
+		`a/what?:*|<>".jpg`,
+		"a/  spaces  .jpg",
+		`a/back\slash.jpg`, // 反斜杠在Linux上是普通字符
+		"b/\u00e9t\u00e9.jpg",
+		"b/e\u0301te\u0301.jpg", // 与上一个只有 Unicode 编码不同
+	}
+	var files []string
+	for _, name := range names {
+		files = append(files, writeTestFile(t, filepath.Join(source, name), "content of "+name))
+	}
+	before := snapshotTree(t, source)
+
+	config := Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      target,
+		FileExtensions: []string{".jpg"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		ConflictPolicy: ConflictRename,
+		CatalogEnabled: true,
+		ExcludedFiles:  map[string]bool{},
+	}
+	summary, err := fo.processFiles(config, files)
+	if err != nil || summary.Moved != len(files) {
+		t.Fatalf("整理: %+v, %v", summary, err)
+	}
+	if got := snapshotTree(t, source); len(got) != 0 {
+		t.Fatalf("整理后源文件夹中仍有文件: %v", got)
+	}
+
+	_, entries, err := lastCatalogRun(catalogPath())
+	if err != nil || len(entries) != len(files) {
+		t.Fatalf("整理目录: %d 条, %v", len(entries), err)
+	}
+	reverted, failed, err := fo.rollbackEntries(entries, config)
+	if err != nil || reverted != len(files) || failed != 0 {
+		t.Fatalf("撤销: 移回 %d, 失败 %d, %v", reverted, failed, err)
+	}
+	if got := snapshotTree(t, source); !reflect.DeepEqual(got, before) {
+		t.Fatalf("撤销后的源文件夹 = %v, 期望 %v", got, before)
+	}
+	if got := snapshotTree(t, target); len(got) != 0 {
+		t.Fatalf("撤销后目标文件夹中仍有文件: %v", got)
+	}
+}
+
+// 原位置已被占用时按冲突设置撤销
+func TestUndoConflictAtOriginalLocation(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		backup    bool
+		occupant  string
+		failMove  bool
+		wantErr   bool
+		wantFiles map[string]string // 撤销后原文件夹中的文件，时间戳文件名用 * 表示
+		wantFinal bool              // 整理后的文件仍在目标中
+	}{
+		{"加时间戳", ConflictRename, false, "other", false, false,
+			map[string]string{"a.jpg": "other", "*": "organized"}, false},
+		{"覆盖，不备份", ConflictOverwrite, false, "other", false, false,
+			map[string]string{"a.jpg": "organized"}, false},
+		{"覆盖，移回失败时保留已有文件", ConflictOverwrite, false, "other", true, true,
+			map[string]string{"a.jpg": "other"}, true},
+		{"合并，内容相同", ConflictMerge, false, "organized", false, false,
+			map[string]string{"a.jpg": "organized"}, false},
+		{"合并，内容不同", ConflictMerge, false, "other", false, false,
+			map[string]string{"a.jpg": "other", "*": "organized"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			target := filepath.Join(t.TempDir(), "organized-target")
+			final := writeTestFile(t, filepath.Join(target, ".jpg", "a.jpg"), "organized")
+			original := writeTestFile(t, filepath.Join(source, "a.jpg"), tt.occupant)
+			if tt.failMove {
+				useFaults(t, "exdev=organized-target,copy-fail-after=1")
+			}
+			entry := CatalogEntry{RunID: "20240101_000000", OriginalPath: original, FinalPath: final, Size: 9}
+			config := Config{TargetDir: target, ConflictPolicy: tt.policy, BackupReplaced: tt.backup}
+
+			_, err := fo.moveBack(entry, config, "20240102_000000")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("moveBack 错误 = %v", err)
+			}
+			got := snapshotTree(t, source)
+			if len(got) != len(tt.wantFiles) {
+				t.Fatalf("原文件夹 = %v", got)
+			}
+			for name, content := range got {
+				want, ok := tt.wantFiles[name]
+				if !ok {
+					want, ok = tt.wantFiles["*"]
+					if !ok || !strings.HasPrefix(name, "a_") {
+						t.Fatalf("原文件夹中多了 %s", name)
+					}
+				}
+				if content != want {
+					t.Fatalf("%s = %q, 期望 %q", name, content, want)
+				}
+			}
+			if _, err := os.Stat(final); (err == nil) != tt.wantFinal {
+				t.Fatalf("整理后的文件: %v", err)
+			}
+		})
+	}
+}
+
+// 同一秒内撤销多个原文件名相同、原位置都已被占用的文件，每个都应移回到不同的名称
+func TestUndoCollisionsWithinOneSecond(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	target := t.TempDir()
+	original := writeTestFile(t, filepath.Join(source, "a.jpg"), "occupant")
+	var entries []CatalogEntry
+	for _, name := range []string{"a.jpg", "a_1.jpg", "a_2.jpg"} {
+		final := writeTestFile(t, filepath.Join(target, name), "organized "+name)
+		entries = append(entries, CatalogEntry{RunID: "20240101_000000", OriginalPath: original, FinalPath: final})
+	}
+	config := Config{TargetDir: target, ConflictPolicy: ConflictRename}
+	for _, entry := range entries {
+		if _, err := fo.moveBack(entry, config, "20240102_000000"); err != nil {
+			t.Fatalf("移回 %s: %v", entry.FinalPath, err)
+		}
+	}
+	if got := snapshotTree(t, source); len(got) != len(entries)+1 {
+		t.Fatalf("原文件夹 = %v", got)
+	}
+}
+
+// 从只读源文件夹复制的文件，撤销时删除副本，源文件不动；源文件已不在时把副本移回
+func TestUndoCopiedFiles(t *testing.T) {
+	for _, sourceGone := range []bool{false, true} {
+		fo := newTestOrganizer(t)
+		source := t.TempDir()
+		target := t.TempDir()
+		file := writeTestFile(t, filepath.Join(source, "a.jpg"), "camera")
+		config := Config{
+			SourceDir:       source,
+			SourceDirs:      []string{source},
+			ReadOnlySources: []string{source},
+			TargetDir:       target,
+			FileExtensions:  []string{".jpg"},
+			OrganizeRule:    string(RuleByExtension),
+			ExtensionCase:   "lowercase",
+			CatalogEnabled:  true,
+			ExcludedFiles:   map[string]bool{},
+		}
+		summary, err := fo.processFiles(config, []string{file})
+		if err != nil || summary.Copied != 1 {
+			t.Fatalf("整理: %+v, %v", summary, err)
+		}
+		_, entries, err := lastCatalogRun(catalogPath())
+		if err != nil || len(entries) != 1 || entries[0].Operation != CatalogCopy {
+			t.Fatalf("整理目录: %+v, %v", entries, err)
+		}
+		if sourceGone {
+			os.Remove(file)
+		}
+		if reverted, failed, err := fo.rollbackEntries(entries, config); err != nil || reverted != 1 || failed != 0 {
+			t.Fatalf("撤销: 移回 %d, 失败 %d, %v", reverted, failed, err)
+		}
+		if got := readTestFile(t, file); got != "camera" {
+			t.Fatalf("源文件 = %q", got)
+		}
+		if got := snapshotTree(t, target); len(got) != 0 {
+			t.Fatalf("撤销后目标中仍有副本: %v", got)
+		}
+	}
+}
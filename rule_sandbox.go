@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 规则测试中最多计算的示例路径数
+const ruleTestSampleLimit = 200
+
+// 规则测试窗口打开时重新计算的间隔，主窗口和设置中的改动随后显示出来
+const ruleTestRefreshInterval = time.Second
+
+// sampleFileInfo 规则测试中示例文件的信息
+type sampleFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (s sampleFileInfo) Name() string       { return s.name }
+func (s sampleFileInfo) Size() int64        { return s.size }
+func (s sampleFileInfo) Mode() fs.FileMode  { return 0644 }
+func (s sampleFileInfo) ModTime() time.Time { return s.modTime }
+func (s sampleFileInfo) IsDir() bool        { return false }
+func (s sampleFileInfo) Sys() any           { return nil }
+
+// 示例中的时间格式
+var sampleTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// 解析示例大小，例如 120、12KB、3.5MB
+func parseSampleSize(text string) (int64, bool) {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix string
+		value  float64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if number, ok := strings.CutSuffix(text, unit.suffix); ok {
+			text, multiplier = strings.TrimSpace(number), unit.value
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return int64(n * multiplier), true
+}
+
+// 解析一行示例：路径 | 修改时间 | 大小，时间和大小可以省略，顺序不限。
+// 相对路径按第一个源文件夹计算。没有写时间和大小并且文件存在时使用文件的实际信息
+func parseRuleSample(line string, config Config, now time.Time) (string, os.FileInfo, error) {
+	fields := strings.Split(line, "|")
+	path := strings.TrimSpace(fields[0])
+	if path == "" {
+		return "", nil, errors.New("缺少路径")
+	}
+	if !filepath.IsAbs(path) && len(config.SourceDirs) > 0 {
+		path = filepath.Join(config.SourceDirs[0], path)
+	}
+	path = filepath.Clean(path)
+	info := sampleFileInfo{name: filepath.Base(path), size: 1024, modTime: now}
+	faked := false
+	for _, field := range fields[1:] {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parsed := false
+		for _, layout := range sampleTimeLayouts {
+			if t, err := time.ParseInLocation(layout, field, config.location()); err == nil {
+				info.modTime, parsed = t, true
+				break
+			}
+		}
+		if !parsed {
+			size, ok := parseSampleSize(field)
+			if !ok {
+				return "", nil, fmt.Errorf("无法识别 %q，应为时间（例如 2024-03-15 14:30）或大小（例如 3.5MB）", field)
+			}
+			info.size = size
+		}
+		faked = true
+	}
+	if !faked {
+		if statInfo, err := os.Stat(path); err == nil {
+			return path, statInfo, nil
+		}
+	}
+	return path, info, nil
+}
+
+// 目标路径相对于目标文件夹显示，打包的小文件显示为 压缩包 › 文件名
+func displayPlanPath(plan filePlan, config Config) string {
+	target := filepath.Join(plan.TargetDir, plan.TargetName)
+	if plan.Archive != "" {
+		target = plan.Archive
+	}
+	if rel, err := filepath.Rel(config.TargetDir, target); err == nil && !strings.HasPrefix(rel, "..") {
+		target = filepath.Join("目标", rel)
+	}
+	if plan.Archive != "" {
+		target += " › " + plan.TargetName
+	}
+	return target
+}
+
+// ruleSample 规则测试中的一行示例
+type ruleSample struct {
+	line string
+	path string
+	info os.FileInfo
+	err  error
+}
+
+// 为示例准备与整理时相同的整批信息：快捷方式、Google 相册导出的元数据文件和目标卷的分配。
+// 目标去重需要读取整个目标文件夹，规则测试中只提示
+func (fo *FileOrganizer) samplePlanContext(config Config, samples []ruleSample) (Config, planContext, error) {
+	var ctx planContext
+	var paths []string
+	infos := make(map[string]os.FileInfo)
+	for _, sample := range samples {
+		if sample.err == nil {
+			paths = append(paths, sample.path)
+			infos[sample.path] = sample.info
+		}
+	}
+	if config.ShortcutPolicy == ShortcutResolve {
+		_, ctx.shortcuts = fo.resolveShortcuts(config, paths)
+	}
+	if config.TakeoutMode {
+		ctx.takeout = newTakeoutPlan(fo.takeoutCandidates(config, paths))
+	}
+	if len(config.Volumes) > 0 && config.CapacityPlan == nil {
+		plan, err := fo.planVolumesFor(config, paths, func(path string) os.FileInfo { return infos[path] })
+		if err != nil {
+			return config, ctx, err
+		}
+		config.VolumePlan = plan
+	}
+	return config, ctx, nil
+}
+
+// 对每行示例计算整理计划，返回显示的文字
+func (fo *FileOrganizer) evaluateRuleSamples(text string, config Config) string {
+	now := config.planNow()
+	var sb strings.Builder
+	var samples []ruleSample
+	count := 0
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		count++
+		if count > ruleTestSampleLimit {
+			break
+		}
+		sample := ruleSample{line: line}
+		sample.path, sample.info, sample.err = parseRuleSample(line, config, now)
+		samples = append(samples, sample)
+	}
+	if count == 0 {
+		return "在左侧输入示例路径，每行一个"
+	}
+	config, ctx, err := fo.samplePlanContext(config, samples)
+	if err != nil {
+		fmt.Fprintf(&sb, "目标卷: %v\n\n", err)
+	}
+	for _, sample := range samples {
+		if sample.err != nil {
+			fmt.Fprintf(&sb, "%s\n  错误: %v\n\n", sample.line, sample.err)
+			continue
+		}
+		path := sample.path
+		plan := fo.planFile(path, sample.info, config, ctx)
+		fmt.Fprintf(&sb, "%s\n", path)
+		if plan.Skip != "" {
+			fmt.Fprintf(&sb, "  不整理: %s\n", strings.TrimSuffix(strings.TrimSuffix(plan.Skip, path), ": "))
+		} else {
+			fmt.Fprintf(&sb, "  → %s\n", displayPlanPath(plan, config))
+		}
+		if plan.DateSource != "" {
+			fmt.Fprintf(&sb, "  日期: %s（来自%s）\n", plan.Date.In(config.location()).Format("2006-01-02 15:04:05"), dateSourceNames[plan.DateSource])
+		}
+		if len(plan.Notes) > 0 {
+			fmt.Fprintf(&sb, "  设置: %s\n", strings.Join(plan.Notes, "；"))
+		}
+		sb.WriteString("\n")
+	}
+	if count > ruleTestSampleLimit {
+		fmt.Fprintf(&sb, "……只计算前 %d 行\n", ruleTestSampleLimit)
+	}
+	return sb.String()
+}
+
+// 显示规则测试窗口：输入示例路径，按当前的规则、模板和筛选条件显示每个文件的去向。
+// 不需要先扫描，窗口打开时主窗口和设置中的改动会自动重新计算
+func (fo *FileOrganizer) showRuleTestWindow() {
+	if fo.ruleTestWindow != nil {
+		fo.ruleTestWindow.RequestFocus()
+		return
+	}
+	w := fyne.CurrentApp().NewWindow("规则测试")
+	fo.ruleTestWindow = w
+
+	input := widget.NewMultiLineEntry()
+	input.SetPlaceHolder("每行一个路径，可以附加修改时间和大小，例如:\nDCIM/IMG_20240315_143000.jpg\n/Users/me/Downloads/report.pdf | 2023-11-02 09:30 | 2.5MB\n# 开头的行是注释")
+	input.Wrapping = fyne.TextWrapOff
+	output := widget.NewLabel("")
+	output.Wrapping = fyne.TextWrapWord
+	status := widget.NewLabel("")
+
+	last := ""
+	evaluate := func() {
+		config := fo.currentConfig().frozen()
+		if strings.TrimSpace(config.TargetDir) == "" {
+			config.TargetDir = string(filepath.Separator) + "目标"
+		}
+		result := fo.evaluateRuleSamples(input.Text, config)
+		if result != last {
+			last = result
+			output.SetText(result)
+		}
+		status.SetText(fmt.Sprintf("按当前设置计算，规则: %s，目标: %s", config.OrganizeRule, config.TargetDir))
+	}
+	input.OnChanged = func(string) { evaluate() }
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ruleTestRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fyne.Do(evaluate)
+			case <-done:
+				return
+			}
+		}
+	}()
+	w.SetOnClosed(func() {
+		close(done)
+		fo.ruleTestWindow = nil
+	})
+
+	split := container.NewHSplit(input, container.NewVScroll(output))
+	split.Offset = 0.4
+	w.SetContent(container.NewBorder(nil, status, nil, nil, split))
+	w.Resize(fyne.NewSize(900, 560))
+	evaluate()
+	w.Show()
+}
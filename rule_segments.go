@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 组合规则中可用的层，每层按对应的规则生成一级文件夹
+const (
+	SegmentDate      = "date"      // 日期文件夹，使用日期格式和事件标签
+	SegmentExtension = "extension" // 后缀文件夹，使用后缀大小写设置
+	SegmentCategory  = "category"  // 文件类别，例如 图片、文档
+	SegmentAge       = "age"       // 年龄分组，使用年龄分组名称
+	SegmentCamera    = "camera"    // 照片EXIF中的相机型号
+)
+
+// 组合规则最多的层数，避免生成过深的文件夹
+const maxRuleSegments = 3
+
+// 可用的层及其名称，按显示顺序排列
+var ruleSegmentNames = []struct {
+	segment string
+	name    string
+}{
+	{SegmentDate, "日期"},
+	{SegmentExtension, "后缀"},
+	{SegmentCategory, "类别"},
+	{SegmentAge, "年龄"},
+	{SegmentCamera, "相机"},
+}
+
+// 没有设置组合规则时使用的层：日期/后缀
+var defaultRuleSegments = []string{SegmentDate, SegmentExtension}
+
+// 层的名称，未知的层返回原值
+func ruleSegmentName(segment string) string {
+	for _, s := range ruleSegmentNames {
+		if s.segment == segment {
+			return s.name
+		}
+	}
+	return segment
+}
+
+// 根据名称查找层
+func ruleSegmentByName(name string) (string, bool) {
+	for _, s := range ruleSegmentNames {
+		if s.name == name {
+			return s.segment, true
+		}
+	}
+	return "", false
+}
+
+// 解析逗号分隔的组合规则，例如 "category,date"。至少一层，最多 maxRuleSegments 层，不能重复
+func parseRuleSegments(text string) ([]string, error) {
+	var segments []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(text, ",") {
+		segment := strings.ToLower(strings.TrimSpace(part))
+		if segment == "" {
+			continue
+		}
+		if ruleSegmentName(segment) == segment {
+			return nil, fmt.Errorf("未知的层 %q，可用: date, extension, category, age, camera", segment)
+		}
+		if seen[segment] {
+			return nil, fmt.Errorf("层 %s 重复", segment)
+		}
+		seen[segment] = true
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return nil, errors.New("组合规则至少需要一层")
+	}
+	if len(segments) > maxRuleSegments {
+		return nil, fmt.Errorf("组合规则最多 %d 层", maxRuleSegments)
+	}
+	return segments, nil
+}
+
+// 格式化组合规则，与 parseRuleSegments 对应
+func formatRuleSegments(segments []string) string {
+	return strings.Join(segments, ",")
+}
+
+// 描述组合规则，用于日志，例如 "类别/日期"
+func describeRuleSegments(segments []string) string {
+	names := make([]string, len(segments))
+	for i, segment := range segments {
+		names[i] = ruleSegmentName(segment)
+	}
+	return strings.Join(names, "/")
+}
+
+// 实际使用的组合规则，没有设置时使用默认的层
+func effectiveRuleSegments(config Config) []string {
+	if len(config.RuleSegments) == 0 {
+		return defaultRuleSegments
+	}
+	return config.RuleSegments
+}
+
+// 规则是否需要文件日期
+func ruleUsesDate(config Config) bool {
+	switch OrganizeRule(config.OrganizeRule) {
+	case RuleByDate, RuleByAge:
+		return true
+	case RuleComposite:
+		for _, segment := range effectiveRuleSegments(config) {
+			if segment == SegmentDate || segment == SegmentAge {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 按组合规则依次生成每一层的文件夹并连接起来，例如 图片/2024-03-15。
+// 设置了分隔符时各层连接成一级文件夹，例如 图片 - 2024-03-15
+func (fo *FileOrganizer) compositeFolderName(filePath string, fileInfo os.FileInfo, config Config) string {
+	var parts []string
+	for _, segment := range effectiveRuleSegments(config) {
+		var part string
+		switch segment {
+		case SegmentCategory:
+			part = fileCategory(filePath)
+		case SegmentDate, SegmentExtension, SegmentAge, SegmentCamera:
+			// 日期、后缀、年龄和相机与对应的单独规则生成相同的文件夹
+			segmentConfig := config
+			segmentConfig.OrganizeRule = segment
+			part = fo.defaultRuleFolderName(filePath, fileInfo, segmentConfig)
+		}
+		if part == "" {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	if config.RuleSegmentSeparator != "" {
+		return joinRuleSegments(parts, config.RuleSegmentSeparator)
+	}
+	return filepath.Join(parts...)
+}
+
+// 组合规则连接成一级文件夹时分隔符的最大长度
+const maxRuleSegmentSeparator = 8
+
+// 检查组合规则的分隔符。为空表示每层一级文件夹，否则分隔符不能含有路径分隔符或文件名中不允许的字符
+func validateRuleSegmentSeparator(separator string) error {
+	if utf8.RuneCountInString(separator) > maxRuleSegmentSeparator {
+		return fmt.Errorf("分隔符最多 %d 个字符", maxRuleSegmentSeparator)
+	}
+	for _, r := range separator {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || unicode.IsControl(r) {
+			return fmt.Errorf("分隔符不能包含 %q", r)
+		}
+	}
+	return nil
+}
+
+// 把各层的值用分隔符连接成一级文件夹，例如 2024-03 - 图片。
+// 层的值中的路径分隔符（例如邮件的发件人文件夹）替换为下划线，结果再按文件夹名称规则清理
+func joinRuleSegments(parts []string, separator string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	flat := make([]string, len(parts))
+	for i, part := range parts {
+		flat[i] = strings.NewReplacer("/", "_", `\`, "_").Replace(part)
+	}
+	return sanitizeFolderName(strings.Join(flat, separator))
+}
+
+// 预览组合规则生成的文件夹，优先使用已扫描的文件，否则使用示例值
+func (fo *FileOrganizer) previewRuleSegments(segments []string, separator string, config Config) string {
+	if err := validateRuleSegmentSeparator(separator); err != nil {
+		return err.Error()
+	}
+	if len(segments) == 0 {
+		return "组合规则至少需要一层"
+	}
+	config.OrganizeRule = string(RuleComposite)
+	config.RuleSegments = segments
+	config.RuleSegmentSeparator = separator
+	for _, filePath := range fo.scannedFiles {
+		info := fo.scannedFileInfos[filePath]
+		if info == nil {
+			continue
+		}
+		if folder := fo.compositeFolderName(filePath, info, config); folder != "" {
+			return fmt.Sprintf("%s → %s", filePath, folder)
+		}
+	}
+	const sample = "IMG_20240315_101500.jpg"
+	parts := make([]string, len(segments))
+	for i, segment := range segments {
+		switch segment {
+		case SegmentDate:
+			parts[i] = "2024-03-15"
+		case SegmentExtension:
+			parts[i] = "jpg"
+		case SegmentCategory:
+			parts[i] = fileCategory(sample)
+		case SegmentAge:
+			parts[i] = defaultAgeBucketLabels[len(defaultAgeBucketLabels)-1]
+		case SegmentCamera:
+			parts[i] = "Canon EOS R5"
+		}
+	}
+	folder := filepath.Join(parts...)
+	if separator != "" {
+		folder = joinRuleSegments(parts, separator)
+	}
+	return fmt.Sprintf("示例 %s → %s", sample, folder)
+}
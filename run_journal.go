@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+)
+
+// runJournal 正在进行的整理的记录，每次整理一个文件。整理结束时删除；强制退出前写入尚未处理的文件，
+// 程序崩溃时保留开始时的文件列表，已移走的文件在继续时不再存在，自然跳过。下次启动时可以继续整理
+type runJournal struct {
+	RunID      string    `json:"run_id"`
+	Started    time.Time `json:"started"`
+	SourceDirs []string  `json:"source_dirs"`
+	TargetDir  string    `json:"target_dir"`
+	Streaming  bool      `json:"streaming,omitempty"` // 流式整理没有文件列表，继续时重新扫描源文件夹
+	Pending    []string  `json:"pending,omitempty"`
+
+	mu   sync.Mutex
+	done map[string]bool // 已处理的文件，强制退出前从Pending中去掉
+}
+
+// 记录文件所在的文件夹
+func runJournalDir() string {
+	return filepath.Join(appDataDir(), "journal")
+}
+
+// 开始整理时写入记录。写入失败只记录日志，不影响整理，返回nil
+func (fo *FileOrganizer) beginRunJournal(runID string, config Config, files []string, streaming bool) *runJournal {
+	j := &runJournal{
+		RunID:      runID,
+		Started:    time.Now(),
+		SourceDirs: append([]string(nil), config.SourceDirs...),
+		TargetDir:  config.TargetDir,
+		Streaming:  streaming,
+		Pending:    files,
+	}
+	if !streaming {
+		j.done = make(map[string]bool)
+	}
+	if err := j.save(); err != nil {
+		fo.log(fmt.Sprintf("警告: %v，强制退出后不能继续本次整理", err))
+		return nil
+	}
+	fo.runJournals.Store(runID, j)
+	return j
+}
+
+// 整理结束（包括出错和取消）时删除记录
+func (fo *FileOrganizer) endRunJournal(j *runJournal) {
+	if j == nil {
+		return
+	}
+	fo.runJournals.Delete(j.RunID)
+	if err := os.Remove(j.path()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fo.log(fmt.Sprintf("删除整理记录失败: %v", err))
+	}
+}
+
+// 强制退出前写入所有正在进行的整理尚未处理的文件
+func (fo *FileOrganizer) syncRunJournals() {
+	fo.runJournals.Range(func(_, value any) bool {
+		if err := value.(*runJournal).sync(); err != nil {
+			fo.log(err.Error())
+		}
+		return true
+	})
+}
+
+func (j *runJournal) path() string {
+	return filepath.Join(runJournalDir(), j.RunID+".json")
+}
+
+// 记下处理完的文件，推迟重试和中止的文件不记
+func (j *runJournal) markDone(path string) {
+	if j == nil || j.done == nil {
+		return
+	}
+	j.mu.Lock()
+	j.done[path] = true
+	j.mu.Unlock()
+}
+
+// 去掉已处理的文件后重新写入
+func (j *runJournal) sync() error {
+	j.mu.Lock()
+	if j.done != nil {
+		var pending []string
+		for _, path := range j.Pending {
+			if !j.done[path] {
+				pending = append(pending, path)
+			}
+		}
+		j.Pending = pending
+		j.done = make(map[string]bool)
+	}
+	j.mu.Unlock()
+	return j.save()
+}
+
+// 写入记录，先写临时文件再改名，避免中途退出时留下不完整的记录
+func (j *runJournal) save() error {
+	j.mu.Lock()
+	data, err := json.Marshal(j)
+	j.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("序列化整理记录失败: %w", err)
+	}
+	if err := os.MkdirAll(runJournalDir(), 0755); err != nil {
+		return fmt.Errorf("保存整理记录失败: %w", err)
+	}
+	tmpPath := j.path() + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("保存整理记录失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.path()); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存整理记录失败: %w", err)
+	}
+	return nil
+}
+
+// 读取上次没有正常结束的整理，按开始时间排列。本机正在进行的整理不计入
+func (fo *FileOrganizer) interruptedRuns() ([]*runJournal, error) {
+	entries, err := os.ReadDir(runJournalDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取整理记录失败: %w", err)
+	}
+	var runs []*runJournal
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		if _, running := fo.runJournals.Load(strings.TrimSuffix(entry.Name(), ".json")); running {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(runJournalDir(), entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取整理记录失败: %w", err)
+		}
+		j := &runJournal{}
+		if err := json.Unmarshal(data, j); err != nil || j.RunID == "" {
+			fo.log(fmt.Sprintf("忽略无法解析的整理记录 %s", entry.Name()))
+			continue
+		}
+		runs = append(runs, j)
+	}
+	sort.Slice(runs, func(a, b int) bool { return runs[a].Started.Before(runs[b].Started) })
+	return runs, nil
+}
+
+// 继续整理时的文件：仍在源文件夹中的未处理文件，流式整理重新扫描源文件夹
+func (fo *FileOrganizer) resumeFiles(j *runJournal) []string {
+	if j.Streaming {
+		return fo.collectFiles(j.SourceDirs)
+	}
+	var files []string
+	for _, path := range j.Pending {
+		if _, err := os.Lstat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// 启动时提示上次被中断的整理，可以按当前设置继续整理尚未处理的文件，或者放弃
+func (fo *FileOrganizer) offerRunResume() {
+	runs, err := fo.interruptedRuns()
+	if err != nil {
+		fo.log(err.Error())
+		return
+	}
+	if len(runs) == 0 {
+		return
+	}
+	var lines []string
+	for _, j := range runs {
+		count := fmt.Sprintf("%d 个文件未处理", len(j.Pending))
+		if j.Streaming {
+			count = "重新扫描源文件夹"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s → %s（%s）",
+			j.Started.Format("2006-01-02 15:04"), strings.Join(j.SourceDirs, ", "), j.TargetDir, count))
+	}
+	message := fmt.Sprintf("上次有 %d 次整理没有完成:\n%s\n\n是否按当前设置继续整理尚未处理的文件？",
+		len(runs), strings.Join(lines, "\n"))
+	dialog.ShowConfirm("继续未完成的整理", message, func(resume bool) {
+		if !resume {
+			for _, j := range runs {
+				fo.endRunJournal(j)
+			}
+			fo.log(fmt.Sprintf("已放弃 %d 次未完成的整理", len(runs)))
+			return
+		}
+		fo.resumeRuns(runs)
+	}, fo.Window)
+}
+
+// 依次继续未完成的整理，每次整理开始时写入新的记录，旧的记录随后删除
+func (fo *FileOrganizer) resumeRuns(runs []*runJournal) {
+	if fo.runActive() {
+		dialog.ShowInformation("提示", "正在整理文件，请等待整理完成后再继续", fo.Window)
+		return
+	}
+	configs := make([]Config, len(runs))
+	for i, j := range runs {
+		config := fo.currentConfig()
+		config.SourceDir = j.TargetDir
+		config.TargetDir = j.TargetDir
+		config.SourceDirs = append([]string(nil), j.SourceDirs...)
+		// 预览时的排除、目标卷分配、容量分卷和连拍序列属于当前的扫描结果
+		config.ExcludedFiles = nil
+		config.VolumePlan = nil
+		config.CapacityPlan = nil
+		config.Bursts = nil
+		configs[i] = config
+	}
+	fo.processBtn.Disable()
+	go func() {
+		for i, j := range runs {
+			config := configs[i]
+			if err := validateTargetDir(config.TargetDir); err != nil {
+				fo.log(fmt.Sprintf("无法继续整理到 %s: %v", config.TargetDir, err))
+				continue
+			}
+			files := fo.resumeFiles(j)
+			fo.log(fmt.Sprintf("继续整理 %s → %s，共 %d 个文件", strings.Join(j.SourceDirs, ", "), j.TargetDir, len(files)))
+			summary, err := fo.processFiles(config, files)
+			if err != nil {
+				// 没有开始整理（例如目标被其他电脑锁定），保留记录下次再试
+				fo.log("继续整理出错: " + err.Error())
+				continue
+			}
+			fo.endRunJournal(j)
+			fo.log(fmt.Sprintf("继续整理结束: 移动 %d 个文件，%d 个失败", summary.Moved, summary.Failed))
+		}
+		fo.safeUpdateUI(func() {
+			fo.processBtn.Enable()
+		})
+	}()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 整理期间有整理记录，本机正在进行的整理不算中断；整理结束后删除记录
+func TestRunJournalDuringRun(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	target := t.TempDir()
+	files := []string{writeTestFile(t, filepath.Join(source, "a.jpg"), "a")}
+
+	var onDisk int
+	var interrupted []*runJournal
+	fo.ui = &startHookNotifier{UINotifier: fo.ui, onStart: func() {
+		entries, _ := os.ReadDir(runJournalDir())
+		onDisk = len(entries)
+		interrupted, _ = fo.interruptedRuns()
+	}}
+	config := incomingTestConfig(source, target)
+	config.FileExtensions = []string{".jpg"}
+	if _, err := fo.processFiles(config, files); err != nil {
+		t.Fatal(err)
+	}
+	if onDisk != 1 || len(interrupted) != 0 {
+		t.Fatalf("整理期间: %d 个记录文件，%d 次中断的整理", onDisk, len(interrupted))
+	}
+	if runs, err := fo.interruptedRuns(); err != nil || len(runs) != 0 {
+		t.Fatalf("整理结束后仍有记录: %d, %v", len(runs), err)
+	}
+}
+
+// 强制退出时记录未处理的文件，下次继续时只整理仍在源文件夹中的；流式整理重新扫描源文件夹
+func TestRunJournalResume(t *testing.T) {
+	tests := []struct {
+		name      string
+		streaming bool
+		done      []string // 强制退出前已处理的文件
+		moved     []string // 强制退出后不在源文件夹中的文件
+		want      []string
+	}{
+		{"去掉已处理的文件", false, []string{"a.jpg"}, nil, []string{"b.jpg", "c.jpg"}},
+		{"未记下但已移走的文件", false, nil, []string{"b.jpg"}, []string{"a.jpg", "c.jpg"}},
+		{"流式整理", true, []string{"a.jpg"}, []string{"c.jpg"}, []string{"a.jpg", "b.jpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			path := func(name string) string { return filepath.Join(source, name) }
+			var files []string
+			for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+				files = append(files, writeTestFile(t, path(name), name))
+			}
+			var listed []string
+			if !tt.streaming {
+				listed = files
+			}
+			config := Config{SourceDirs: []string{source}, TargetDir: t.TempDir()}
+			j := fo.beginRunJournal(newRunID(), config, listed, tt.streaming)
+			if j == nil {
+				t.Fatal("没有写入整理记录")
+			}
+			for _, name := range tt.done {
+				j.markDone(path(name))
+			}
+			fo.syncRunJournals()
+			// 模拟强制退出：正在进行的整理随程序结束
+			fo.runJournals.Delete(j.RunID)
+			for _, name := range tt.moved {
+				if err := os.Remove(path(name)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			runs, err := fo.interruptedRuns()
+			if err != nil || len(runs) != 1 {
+				t.Fatalf("中断的整理 = %d, %v", len(runs), err)
+			}
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, path(name))
+			}
+			if got := fo.resumeFiles(runs[0]); !reflect.DeepEqual(got, want) {
+				t.Fatalf("继续整理的文件 = %v, 期望 %v", got, want)
+			}
+			fo.endRunJournal(runs[0])
+			if runs, _ := fo.interruptedRuns(); len(runs) != 0 {
+				t.Fatal("放弃后仍有记录")
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// 发送统计带上令牌，失败时重试一次；等待 statsPosts 后发送已经结束
+func TestPostRunStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		statuses []int
+		want     int // 服务器收到的请求数
+	}{
+		{"成功", "secret", []int{http.StatusOK}, 1},
+		{"没有令牌", "", []int{http.StatusNoContent}, 1},
+		{"失败后重试成功", "secret", []int{http.StatusInternalServerError, http.StatusOK}, 2},
+		{"两次都失败", "", []int{http.StatusBadGateway, http.StatusBadGateway}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				want := ""
+				if tt.token != "" {
+					want = "Bearer " + tt.token
+				}
+				if got := r.Header.Get("Authorization"); got != want {
+					t.Errorf("Authorization = %q, 期望 %q", got, want)
+				}
+				var stats RunStats
+				if err := json.NewDecoder(r.Body).Decode(&stats); err != nil || stats.Files != 3 {
+					t.Errorf("请求内容: %+v, %v", stats, err)
+				}
+				w.WriteHeader(tt.statuses[min(requests, len(tt.statuses)-1)])
+				requests++
+			}))
+			defer server.Close()
+
+			fo := newTestOrganizer(t)
+			fo.postRunStats(server.URL, tt.token, RunStats{Files: 3})
+			fo.statsPosts.Wait()
+			mu.Lock()
+			defer mu.Unlock()
+			if requests != tt.want {
+				t.Fatalf("请求数 = %d, 期望 %d", requests, tt.want)
+			}
+		})
+	}
+}
+
+// 整理完成后可以读取统计JSON，配置了上报地址时等待后已经发送
+func TestRunStatsAfterProcessing(t *testing.T) {
+	received := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	file := writeTestFile(t, filepath.Join(source, "a.jpg"), "a")
+	config := Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      t.TempDir(),
+		FileExtensions: []string{".jpg"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		StatsEndpoint:  server.URL,
+		ExcludedFiles:  map[string]bool{},
+	}
+	if _, err := fo.processFiles(config, []string{file}); err != nil {
+		t.Fatal(err)
+	}
+	fo.statsPosts.Wait()
+	select {
+	case <-received:
+	default:
+		t.Fatal("等待后统计仍未发送")
+	}
+
+	fo.statsMu.Lock()
+	statsJSON := fo.lastStatsJSON
+	fo.statsMu.Unlock()
+	var stats RunStats
+	if err := json.Unmarshal([]byte(statsJSON), &stats); err != nil || stats.Files != 1 {
+		t.Fatalf("统计JSON: %+v, %v", stats, err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 文件名不能安全地用在目标路径中，这样的文件记录到日志后跳过
+var errUnsafeFileName = errors.New("文件名不安全")
+
+// 检查将要在目标文件夹中使用的文件名：不能为空、不能是 . 或 ..，不能包含空字节或路径分隔符。
+// 这样的名称只会来自损坏的文件系统、整理目录中被改动的记录或刻意构造的文件，拼接路径后可能写到别处。
+// 反斜杠只在Windows上是分隔符（filepath.Separator），在Linux和macOS上是文件名中的普通字符
+func checkFileName(name string) error {
+	switch {
+	case name == "" || name == "." || name == "..":
+		return fmt.Errorf("%w: %q", errUnsafeFileName, name)
+	case strings.ContainsRune(name, 0):
+		return fmt.Errorf("%w: %q 包含空字节", errUnsafeFileName, name)
+	case strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator):
+		return fmt.Errorf("%w: %q 包含路径分隔符", errUnsafeFileName, name)
+	}
+	return nil
+}
+
+// 检查源文件路径的文件名。filepath.Base 会去掉末尾的分隔符，所以先检查路径本身：
+// 以分隔符结尾的路径没有文件名，取 Base 后会变成上一级文件夹的名称
+func checkSourceName(path string) error {
+	if path == "" || os.IsPathSeparator(path[len(path)-1]) {
+		return fmt.Errorf("%w: %q 没有文件名", errUnsafeFileName, path)
+	}
+	return checkFileName(filepath.Base(path))
+}
+
+// 检查文件将放入的路径确实在目标文件夹中，防止文件名绕过 checkFileName 后写到别处
+func checkTargetPath(targetDir, targetPath string) error {
+	if filepath.Dir(targetPath) != filepath.Clean(targetDir) {
+		return fmt.Errorf("%w: %s 不在目标文件夹 %s 中", errUnsafeFileName, targetPath, targetDir)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// 不安全的文件名会被拒绝，反斜杠只在Windows上是分隔符
+func TestCheckFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool // 是否接受
+	}{
+		{"photo.jpg", true},
+		{"  spaces  .jpg", true},
+		{`what?:*|<>".jpg`, true},
+		{"..hidden", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"a\x00.jpg", false},
+		{"a/b.jpg", false},
+		{"../escape.jpg", false},
+		{`back\slash.jpg`, runtime.GOOS != "windows"},
+	}
+	for _, tt := range tests {
+		err := checkFileName(tt.name)
+		if (err == nil) != tt.want {
+			t.Errorf("checkFileName(%q) = %v，期望接受: %v", tt.name, err, tt.want)
+		}
+		if err != nil && !errors.Is(err, errUnsafeFileName) {
+			t.Errorf("checkFileName(%q) = %v，应为 errUnsafeFileName", tt.name, err)
+		}
+	}
+}
+
+// 源文件路径在取文件名之前检查，以分隔符结尾的路径没有文件名
+func TestCheckSourceName(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(dir, "a.jpg"), true},
+		{filepath.Join(dir, "a") + string(filepath.Separator), false},
+		{"", false},
+		{filepath.Join(dir, "a\x00.jpg"), false},
+	}
+	for _, tt := range tests {
+		if err := checkSourceName(tt.path); (err == nil) != tt.want {
+			t.Errorf("checkSourceName(%q) = %v，期望接受: %v", tt.path, err, tt.want)
+		}
+	}
+}
+
+// 撤销时整理目录中被改动过的原文件名不会写到原文件夹之外
+func TestUndoRejectsUnsafeOriginalName(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	target := t.TempDir()
+	final := writeTestFile(t, filepath.Join(target, "a.jpg"), "organized")
+	entry := CatalogEntry{RunID: "20240101_000000", OriginalPath: filepath.Join(source, "a.jpg"),
+		OriginalName: "../escape.jpg", FinalPath: final}
+	config := Config{TargetDir: target, ConflictPolicy: ConflictRename}
+	if _, err := fo.moveBack(entry, config, "20240102_000000"); !errors.Is(err, errUnsafeFileName) {
+		t.Fatalf("moveBack 错误 = %v", err)
+	}
+	if got := readTestFile(t, final); got != "organized" {
+		t.Fatalf("整理后的文件 = %q", got)
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dirFingerprint 文件夹指纹：修改时间、条目数量和条目名称的哈希。
+// 文件夹中增删或改名条目时指纹都会变化
+type dirFingerprint struct {
+	ModTime    int64  `json:"mtime"`
+	EntryCount int    `json:"count"`
+	NameHash   string `json:"names"`
+}
+
+// cachedFile 缓存的文件信息
+type cachedFile struct {
+	Name    string      `json:"name"`
+	Size    int64       `json:"size"`
+	Mode    fs.FileMode `json:"mode"`
+	ModTime int64       `json:"mtime"`
+}
+
+// cachedDir 缓存的文件夹扫描结果（只包含直接位于该文件夹中的文件）
+type cachedDir struct {
+	Fingerprint dirFingerprint `json:"fingerprint"`
+	Files       []cachedFile   `json:"files"`
+}
+
+// scanCacheFile 扫描缓存文件的内容
+type scanCacheFile struct {
+	Key  string                `json:"key"` // 过滤条件和排除列表的摘要，变化时缓存失效
+	Dirs map[string]*cachedDir `json:"dirs"`
+}
+
+// cachedFileInfo 用缓存数据实现 os.FileInfo
+type cachedFileInfo struct {
+	file cachedFile
+}
+
+func (c cachedFileInfo) Name() string       { return c.file.Name }
+func (c cachedFileInfo) Size() int64        { return c.file.Size }
+func (c cachedFileInfo) Mode() fs.FileMode  { return c.file.Mode }
+func (c cachedFileInfo) ModTime() time.Time { return time.Unix(0, c.file.ModTime) }
+func (c cachedFileInfo) IsDir() bool        { return c.file.Mode.IsDir() }
+func (c cachedFileInfo) Sys() any           { return nil }
+
+// dirScanner 增量扫描器：指纹未变化的文件夹直接复用缓存的文件列表，只遍历发生变化的文件夹
+type dirScanner struct {
+	mu     sync.Mutex
+	old    map[string]*cachedDir
+	new    map[string]*cachedDir
+	walked atomic.Int64 // 重新遍历的文件夹数量
+	reused atomic.Int64 // 复用缓存的文件夹数量
+	stop   atomic.Bool  // 中止扫描，尚未开始的文件夹不再遍历
+	noKeep atomic.Bool  // 不保留扫描结果，用于流式整理
+}
+
+// 扫描缓存文件路径
+func scanCachePath() string {
+	return filepath.Join(appDataDir(), "scan_cache.json")
+}
+
+// 扫描缓存的格式版本，扫描方式变化时（例如程序包作为一个文件）旧缓存失效
+const scanCacheVersion = 2
+
+// 计算扫描缓存的键：文件后缀过滤条件或排除列表变化时缓存失效
+func scanCacheKey(extensions []string, excluded map[string]bool) string {
+	exts := append([]string(nil), extensions...)
+	sort.Strings(exts)
+	paths := make([]string, 0, len(excluded))
+	for path := range excluded {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "v%d\x00", scanCacheVersion)
+	hasher.Write([]byte(strings.Join(exts, ",")))
+	hasher.Write([]byte{0})
+	hasher.Write([]byte(strings.Join(paths, "\n")))
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// 创建增量扫描器，forceFull为true或缓存键不一致时不使用旧缓存
+func newDirScanner(key string, forceFull bool) *dirScanner {
+	scanner := &dirScanner{
+		old: make(map[string]*cachedDir),
+		new: make(map[string]*cachedDir),
+	}
+	if forceFull {
+		return scanner
+	}
+	data, err := os.ReadFile(scanCachePath())
+	if err != nil {
+		return scanner
+	}
+	var file scanCacheFile
+	if err := json.Unmarshal(data, &file); err == nil && file.Key == key && file.Dirs != nil {
+		scanner.old = file.Dirs
+	}
+	return scanner
+}
+
+// 计算文件夹指纹
+func fingerprintDir(dirInfo os.FileInfo, entries []os.DirEntry) dirFingerprint {
+	hasher := sha256.New()
+	for _, entry := range entries {
+		hasher.Write([]byte(entry.Name()))
+		if entry.IsDir() {
+			hasher.Write([]byte{'/'})
+		}
+		hasher.Write([]byte{0})
+	}
+	return dirFingerprint{
+		ModTime:    dirInfo.ModTime().UnixNano(),
+		EntryCount: len(entries),
+		NameHash:   hex.EncodeToString(hasher.Sum(nil)),
+	}
+}
+
+// 扫描文件夹及其子文件夹，对每个文件调用visit。出错的条目调用onErr后只跳过该条目，
+// 只有文件夹本身无法打开时才跳过整个文件夹
+func (s *dirScanner) scan(dir string, visit func(path string, info os.FileInfo), onErr func(path string, err error)) {
+	if s.stop.Load() {
+		return
+	}
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		onErr(dir, err)
+		return
+	}
+	// 读取到一半出错时仍然处理已读取的条目
+	entries, err := os.ReadDir(dir)
+	complete := err == nil
+	if err != nil {
+		onErr(dir, err)
+		if len(entries) == 0 {
+			return
+		}
+	}
+
+	fingerprint := fingerprintDir(dirInfo, entries)
+	s.mu.Lock()
+	cached := s.old[dir]
+	s.mu.Unlock()
+
+	record := &cachedDir{Fingerprint: fingerprint}
+	if complete && cached != nil && cached.Fingerprint == fingerprint {
+		// 文件夹没有变化，直接使用缓存的文件信息
+		s.reused.Add(1)
+		record.Files = cached.Files
+		for _, file := range cached.Files {
+			visit(filepath.Join(dir, file.Name), cachedFileInfo{file: file})
+		}
+	} else {
+		s.walked.Add(1)
+		for _, entry := range entries {
+			if s.stop.Load() {
+				return
+			}
+			path := filepath.Join(dir, entry.Name())
+			// 程序包作为一个文件记录，其他文件夹在下面逐个扫描
+			if entry.IsDir() && !isPackageDir(path) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				onErr(path, err)
+				complete = false
+				continue
+			}
+			record.Files = append(record.Files, cachedFile{
+				Name:    info.Name(),
+				Size:    info.Size(),
+				Mode:    info.Mode(),
+				ModTime: info.ModTime().UnixNano(),
+			})
+			visit(path, info)
+		}
+	}
+
+	// 有条目读取失败时不缓存该文件夹，下次扫描时重新读取，文件恢复可读后不会被缓存漏掉
+	if complete && !s.noKeep.Load() {
+		s.mu.Lock()
+		s.new[dir] = record
+		s.mu.Unlock()
+	}
+
+	// 子文件夹的变化不会影响上级文件夹的指纹，需要逐个检查。不进入程序包
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if path := filepath.Join(dir, entry.Name()); !isPackageDir(path) {
+				s.scan(path, visit, onErr)
+			}
+		}
+	}
+}
+
+// 丢弃已读取的缓存并不再保留扫描结果，文件数很多时避免占用过多内存。之后的文件夹都重新遍历
+func (s *dirScanner) discard() {
+	s.noKeep.Store(true)
+	s.mu.Lock()
+	s.old = make(map[string]*cachedDir)
+	s.new = make(map[string]*cachedDir)
+	s.mu.Unlock()
+}
+
+// 中止扫描，正在遍历的文件夹处理完当前条目后返回
+func (s *dirScanner) abort() {
+	s.stop.Store(true)
+}
+
+// 保存本次扫描的结果，不在本次扫描范围内的旧缓存保留，供以后再次添加这些文件夹时使用
+func (s *dirScanner) save(key string, roots []string) error {
+	s.mu.Lock()
+	dirs := make(map[string]*cachedDir, len(s.new))
+	for dir, record := range s.old {
+		if !isWithinAny(dir, roots) {
+			dirs[dir] = record
+		}
+	}
+	for dir, record := range s.new {
+		dirs[dir] = record
+	}
+	s.mu.Unlock()
+
+	data, err := json.Marshal(scanCacheFile{Key: key, Dirs: dirs})
+	if err != nil {
+		return fmt.Errorf("序列化扫描缓存失败: %w", err)
+	}
+	tmpPath := scanCachePath() + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("保存扫描缓存失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, scanCachePath()); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存扫描缓存失败: %w", err)
+	}
+	return nil
+}
+
+// 路径是否位于任一根文件夹之内（含根文件夹本身）
+func isWithinAny(path string, roots []string) bool {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
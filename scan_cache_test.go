@@ -0,0 +1,141 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// 再次扫描时只遍历有变化的文件夹，其余文件夹使用缓存的文件列表，结果与完全扫描相同
+func TestScanCacheRewalksChangedDirs(t *testing.T) {
+	dirs := []string{"", "a", "a/b", "a/b/c", "x", "x/y"}
+	tests := []struct {
+		name       string
+		change     func(t *testing.T, root string)
+		key        string // 第二次扫描的缓存键，为空时与第一次相同
+		forceFull  bool
+		wantWalked int64
+	}{
+		{"没有变化", func(*testing.T, string) {}, "", false, 0},
+		{"深层文件夹新增文件", func(t *testing.T, root string) {
+			writeTestFile(t, filepath.Join(root, "a", "b", "c", "new.jpg"), "new")
+		}, "", false, 1},
+		{"删除文件", func(t *testing.T, root string) {
+			if err := os.Remove(filepath.Join(root, "x", "y", "f.jpg")); err != nil {
+				t.Fatal(err)
+			}
+		}, "", false, 1},
+		{"新增子文件夹", func(t *testing.T, root string) {
+			writeTestFile(t, filepath.Join(root, "x", "z", "new.jpg"), "new")
+		}, "", false, 2},
+		{"过滤条件变化", func(*testing.T, string) {}, "other", false, int64(len(dirs))},
+		{"强制完全扫描", func(*testing.T, string) {}, "", true, int64(len(dirs))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestEnv(t)
+			root := t.TempDir()
+			for _, dir := range dirs {
+				writeTestFile(t, filepath.Join(root, filepath.FromSlash(dir), "f.jpg"), dir)
+			}
+			scan := func(key string, forceFull bool) (*dirScanner, []string) {
+				scanner := newDirScanner(key, forceFull)
+				var files []string
+				scanner.scan(root, func(path string, info os.FileInfo) {
+					files = append(files, path)
+				}, func(path string, err error) {
+					t.Fatalf("扫描 %s 出错: %v", path, err)
+				})
+				if err := scanner.save(key, []string{root}); err != nil {
+					t.Fatal(err)
+				}
+				sort.Strings(files)
+				return scanner, files
+			}
+
+			if first, _ := scan("key", false); first.walked.Load() != int64(len(dirs)) {
+				t.Fatalf("第一次扫描遍历了 %d 个文件夹", first.walked.Load())
+			}
+			tt.change(t, root)
+			key := tt.key
+			if key == "" {
+				key = "key"
+			}
+			second, files := scan(key, tt.forceFull)
+			if second.walked.Load() != tt.wantWalked || second.walked.Load()+second.reused.Load() < int64(len(dirs)) {
+				t.Fatalf("遍历 %d 个、复用 %d 个文件夹，期望遍历 %d 个", second.walked.Load(), second.reused.Load(), tt.wantWalked)
+			}
+			if _, full := scan("full", true); !reflect.DeepEqual(files, full) {
+				t.Fatalf("使用缓存的扫描结果 = %v, 完全扫描 = %v", files, full)
+			}
+		})
+	}
+}
+
+// 无法读取的条目只跳过该条目，同一文件夹中其他可读的文件和子文件夹照常扫描；
+// 恢复权限后再次扫描能找到之前无法读取的文件
+func TestScanSkipsUnreadableEntries(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root 用户可以读取没有权限的文件夹")
+	}
+	tests := []struct {
+		name     string
+		files    []string
+		locked   []string // 去掉所有权限的条目
+		want     []string // 锁定时扫描到的文件
+		wantErrs []string // 锁定时出错的条目
+	}{
+		{"无法读取的子文件夹", []string{"a.jpg", "locked/b.jpg", "other/c.jpg"}, []string{"locked"},
+			[]string{"a.jpg", "other/c.jpg"}, []string{"locked"}},
+		{"深层的子文件夹", []string{"sub/a.jpg", "sub/locked/b.jpg", "sub/locked/deep/c.jpg"}, []string{"sub/locked"},
+			[]string{"sub/a.jpg"}, []string{"sub/locked"}},
+		{"无法读取的文件仍然列出", []string{"a.jpg", "secret.jpg"}, []string{"secret.jpg"},
+			[]string{"a.jpg", "secret.jpg"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestEnv(t)
+			root := t.TempDir()
+			for _, name := range tt.files {
+				writeTestFile(t, filepath.Join(root, filepath.FromSlash(name)), name)
+			}
+			setMode := func(mode os.FileMode) {
+				for _, name := range tt.locked {
+					if err := os.Chmod(filepath.Join(root, filepath.FromSlash(name)), mode); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			scan := func() (files, errs []string) {
+				scanner := newDirScanner("key", false)
+				rel := func(path string) string {
+					rel, _ := filepath.Rel(root, path)
+					return filepath.ToSlash(rel)
+				}
+				scanner.scan(root, func(path string, info os.FileInfo) {
+					files = append(files, rel(path))
+				}, func(path string, err error) {
+					errs = append(errs, rel(path))
+				})
+				if err := scanner.save("key", []string{root}); err != nil {
+					t.Fatal(err)
+				}
+				sort.Strings(files)
+				return files, errs
+			}
+
+			setMode(0)
+			t.Cleanup(func() { setMode(0755) })
+			files, errs := scan()
+			if !reflect.DeepEqual(files, tt.want) || !reflect.DeepEqual(errs, tt.wantErrs) {
+				t.Fatalf("扫描到 %v, 出错 %v, 期望 %v, %v", files, errs, tt.want, tt.wantErrs)
+			}
+			setMode(0755)
+			if files, errs := scan(); !reflect.DeepEqual(files, tt.files) || errs != nil {
+				t.Fatalf("恢复权限后扫描到 %v, 出错 %v, 期望 %v", files, errs, tt.files)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// 定时整理的默认允许时段（开始时等于结束时表示全天）
+const (
+	defaultScheduleStartHour = 0
+	defaultScheduleEndHour   = 0
+)
+
+// 当前时间是否在允许整理的时段内。开始时等于结束时表示全天；
+// 开始时大于结束时表示跨过午夜，例如 22 到 6 表示晚上10点到早上6点
+func inScheduleWindow(now time.Time, startHour, endHour int) bool {
+	if startHour == endHour {
+		return true
+	}
+	hour := now.Hour()
+	if startHour < endHour {
+		return hour >= startHour && hour < endHour
+	}
+	return hour >= startHour || hour < endHour
+}
+
+// 描述允许的时段，用于日志
+func describeScheduleWindow(startHour, endHour int) string {
+	if startHour == endHour {
+		return "全天"
+	}
+	return fmt.Sprintf("%02d:00-%02d:00", startHour, endHour)
+}
+
+// 按设置开始定时整理，间隔为0时不启动。已在运行时先停止，使用新的间隔和时段
+func (fo *FileOrganizer) startSchedule() {
+	fo.stopSchedule()
+	if fo.ScheduleInterval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	fo.scheduleStop = stop
+	interval := time.Duration(fo.ScheduleInterval) * time.Minute
+	startHour, endHour := fo.ScheduleStartHour, fo.ScheduleEndHour
+	fo.log(fmt.Sprintf("[定时] 每 %d 分钟整理一次，允许的时段: %s", fo.ScheduleInterval, describeScheduleWindow(startHour, endHour)))
+	go fo.runSchedule(stop, interval, startHour, endHour)
+}
+
+// 停止定时整理
+func (fo *FileOrganizer) stopSchedule() {
+	if fo.scheduleStop == nil {
+		return
+	}
+	close(fo.scheduleStop)
+	fo.scheduleStop = nil
+}
+
+// 每个间隔检查一次，在允许的时段内并且没有其他整理在进行时按当前设置整理源文件夹
+func (fo *FileOrganizer) runSchedule(stop chan struct{}, interval time.Duration, startHour, endHour int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if !inScheduleWindow(now, startHour, endHour) {
+				fo.log(fmt.Sprintf("[定时] 跳过 %s: 不在允许的时段 %s 内", now.Format("15:04"), describeScheduleWindow(startHour, endHour)))
+				continue
+			}
+			if fo.runActive() || fo.isScanning.Load() {
+				fo.log(fmt.Sprintf("[定时] 跳过 %s: 正在扫描或整理", now.Format("15:04")))
+				continue
+			}
+			fo.runScheduledOrganize()
+		}
+	}
+}
+
+// 按当前设置整理一次所有源文件夹
+func (fo *FileOrganizer) runScheduledOrganize() {
+	if fo.ReadOnlyMode {
+		fo.log("[定时] 跳过: 只读模式已开启")
+		return
+	}
+	var config Config
+	fo.safeUpdateUI(func() {
+		config = fo.currentConfig()
+	})
+	// 预览时的目标卷分配、容量分卷和连拍序列属于上一次扫描，定时整理的文件重新收集，不使用它们
+	config.VolumePlan = nil
+	config.CapacityPlan = nil
+	config.Bursts = nil
+	if len(config.SourceDirs) == 0 || len(config.FileExtensions) == 0 {
+		fo.log("[定时] 跳过: 没有源文件夹或没有选择文件后缀")
+		return
+	}
+	if err := validateReadOnlySources(config); err != nil {
+		fo.log("[定时] 跳过: " + err.Error())
+		return
+	}
+	if err := validateLayoutOverlap(config); err != nil {
+		fo.log("[定时] 跳过: " + err.Error())
+		return
+	}
+	if err := validateTagRule(config); err != nil {
+		fo.log("[定时] 跳过: " + err.Error())
+		return
+	}
+	if err := validateTargetDir(config.TargetDir); err != nil {
+		fo.log("[定时] 跳过: " + err.Error())
+		return
+	}
+	// 定时整理无人确认，危险的目标文件夹直接跳过，需要时手动整理并确认
+	if reason := fo.dangerousTargetCheck(config.TargetDir); reason != "" {
+		fo.log(fmt.Sprintf("[定时] 跳过: %s: %s", reason, config.TargetDir))
+		return
+	}
+
+	fo.log("[定时] 开始整理")
+	files := fo.collectFiles(config.SourceDirs)
+	summary, err := fo.processFiles(config, files)
+	if err != nil {
+		fo.log("[定时] 整理出错: " + err.Error())
+		return
+	}
+	fo.log(fmt.Sprintf("[定时] 整理结束: 移动 %d 个文件，%d 个失败", summary.Moved, summary.Failed))
+}
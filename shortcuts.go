@@ -0,0 +1,561 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// 快捷方式（.lnk、.desktop、.webloc）的处理方式
+const (
+	ShortcutOrganize = "organize" // 与其他文件一样按自身的日期和后缀整理
+	ShortcutSkip     = "skip"     // 快捷方式留在原处
+	ShortcutResolve  = "resolve"  // 整理快捷方式指向的文件，网址快捷方式放入「链接」文件夹
+)
+
+// 网址快捷方式所在的文件夹
+const LinksFolderName = "链接"
+
+// 快捷方式的格式，按小写后缀识别
+var shortcutExtensions = map[string]string{
+	".lnk":     "lnk",
+	".desktop": "desktop",
+	".webloc":  "webloc",
+}
+
+// 快捷方式的格式，不是快捷方式时返回空字符串
+func shortcutKind(path string) string {
+	return shortcutExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// shortcutLink 快捷方式指向的网址或文件，两者只有一个不为空
+type shortcutLink struct {
+	URL  string
+	Path string
+}
+
+// 快捷方式文件最多读取的字节数，三种格式的目标都在文件开头附近
+const maxShortcutSize = 64 << 10
+
+// 解析快捷方式指向的目标
+func resolveShortcut(path string) (shortcutLink, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return shortcutLink{}, fmt.Errorf("读取快捷方式失败: %w", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxShortcutSize))
+	if err != nil {
+		return shortcutLink{}, fmt.Errorf("读取快捷方式失败: %w", err)
+	}
+
+	var link shortcutLink
+	switch shortcutKind(path) {
+	case "lnk":
+		link, err = parseLnk(data, filepath.Dir(path))
+	case "desktop":
+		link, err = parseDesktopEntry(data)
+	case "webloc":
+		link, err = parseWebloc(data)
+	default:
+		err = errors.New("不是快捷方式")
+	}
+	if err != nil {
+		return shortcutLink{}, err
+	}
+	if link.URL != "" {
+		// file:// 网址指向的是本地文件
+		if p, ok := fileURLPath(link.URL); ok {
+			link = shortcutLink{Path: p}
+		}
+	}
+	if link.Path != "" {
+		link.Path = filepath.Clean(link.Path)
+	}
+	return link, nil
+}
+
+// file:// 网址对应的本地路径
+func fileURLPath(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || !strings.EqualFold(u.Scheme, "file") || u.Path == "" {
+		return "", false
+	}
+	p := u.Path
+	if runtime.GOOS == "windows" && len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		// file:///C:/Users → C:/Users
+		p = p[1:]
+	}
+	return filepath.FromSlash(p), true
+}
+
+// 本地路径对应的 file:// 网址
+func pathFileURL(path string) string {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// Windows快捷方式（MS-SHLLINK）的文件头
+var lnkCLSID = []byte{0x01, 0x14, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+
+// Windows快捷方式的LinkFlags
+const (
+	lnkHasTargetIDList = 1 << 0
+	lnkHasLinkInfo     = 1 << 1
+	lnkHasName         = 1 << 2
+	lnkHasRelativePath = 1 << 3
+	lnkIsUnicode       = 1 << 7
+)
+
+// lnkReader 按偏移读取快捷方式中的字段，越界时返回false
+type lnkReader []byte
+
+func (r lnkReader) u16(off int) (int, bool) {
+	if off < 0 || off+2 > len(r) {
+		return 0, false
+	}
+	return int(binary.LittleEndian.Uint16(r[off:])), true
+}
+
+func (r lnkReader) u32(off int) (int, bool) {
+	if off < 0 || off+4 > len(r) {
+		return 0, false
+	}
+	return int(binary.LittleEndian.Uint32(r[off:])), true
+}
+
+// 以0结尾的单字节字符串，编码按UTF-8处理
+func (r lnkReader) cString(off int) (string, bool) {
+	if off < 0 || off >= len(r) {
+		return "", false
+	}
+	end := bytes.IndexByte(r[off:], 0)
+	if end < 0 {
+		return "", false
+	}
+	return string(r[off : off+end]), true
+}
+
+// 以0结尾的UTF-16字符串
+func (r lnkReader) cStringUTF16(off int) (string, bool) {
+	var units []uint16
+	for ; ; off += 2 {
+		u, ok := r.u16(off)
+		if !ok {
+			return "", false
+		}
+		if u == 0 {
+			return string(utf16.Decode(units)), true
+		}
+		units = append(units, uint16(u))
+	}
+}
+
+// 解析Windows快捷方式：优先使用LinkInfo中的本地路径或网络路径，
+// 该路径在当前系统上不存在时（例如在其他系统上整理Windows磁盘）使用相对于快捷方式的相对路径
+func parseLnk(data []byte, dir string) (shortcutLink, error) {
+	r := lnkReader(data)
+	headerSize, ok := r.u32(0)
+	if !ok || headerSize != 0x4C || len(data) < 0x4C || !bytes.Equal(data[4:20], lnkCLSID) {
+		return shortcutLink{}, errors.New("不是有效的Windows快捷方式")
+	}
+	flags, _ := r.u32(0x14)
+	pos := 0x4C
+	if flags&lnkHasTargetIDList != 0 {
+		size, ok := r.u16(pos)
+		if !ok {
+			return shortcutLink{}, errors.New("Windows快捷方式已损坏")
+		}
+		pos += 2 + size
+	}
+
+	var candidates []string
+	if flags&lnkHasLinkInfo != 0 {
+		size, ok := r.u32(pos)
+		if !ok || size < 0x1C {
+			return shortcutLink{}, errors.New("Windows快捷方式已损坏")
+		}
+		if p := lnkLinkInfoPath(r, pos); p != "" {
+			candidates = append(candidates, p)
+		}
+		pos += size
+	}
+
+	// StringData：名称在相对路径之前
+	readString := func() (string, bool) {
+		count, ok := r.u16(pos)
+		if !ok {
+			return "", false
+		}
+		pos += 2
+		if flags&lnkIsUnicode != 0 {
+			if pos+count*2 > len(r) {
+				return "", false
+			}
+			units := make([]uint16, count)
+			for i := range units {
+				units[i] = binary.LittleEndian.Uint16(r[pos+i*2:])
+			}
+			pos += count * 2
+			return string(utf16.Decode(units)), true
+		}
+		if pos+count > len(r) {
+			return "", false
+		}
+		s := string(r[pos : pos+count])
+		pos += count
+		return s, true
+	}
+	if flags&lnkHasName != 0 {
+		if _, ok := readString(); !ok {
+			return shortcutLink{}, errors.New("Windows快捷方式已损坏")
+		}
+	}
+	if flags&lnkHasRelativePath != 0 {
+		if rel, ok := readString(); ok && rel != "" {
+			candidates = append(candidates, filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(rel, `\`, "/"))))
+		}
+	}
+
+	if len(candidates) == 0 {
+		return shortcutLink{}, errors.New("Windows快捷方式中没有文件路径（可能指向控制面板等特殊位置）")
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return shortcutLink{Path: candidate}, nil
+		}
+	}
+	return shortcutLink{}, fmt.Errorf("快捷方式的目标不存在: %s", candidates[0])
+}
+
+// LinkInfo中的本地路径（LocalBasePath + CommonPathSuffix）或网络路径（NetName\CommonPathSuffix）
+func lnkLinkInfoPath(r lnkReader, start int) string {
+	headerSize, _ := r.u32(start + 4)
+	infoFlags, _ := r.u32(start + 8)
+	localOff, _ := r.u32(start + 16)
+	networkOff, _ := r.u32(start + 20)
+	suffixOff, _ := r.u32(start + 24)
+
+	suffix, _ := r.cString(start + suffixOff)
+	base, hasBase := "", false
+	if infoFlags&1 != 0 {
+		base, hasBase = r.cString(start + localOff)
+	}
+	// LinkInfo头较长时有Unicode版本的路径
+	if headerSize >= 0x24 {
+		if localUniOff, ok := r.u32(start + 28); ok && infoFlags&1 != 0 && localUniOff > 0 {
+			if s, ok := r.cStringUTF16(start + localUniOff); ok {
+				base, hasBase = s, true
+			}
+		}
+		if suffixUniOff, ok := r.u32(start + 32); ok && suffixUniOff > 0 {
+			if s, ok := r.cStringUTF16(start + suffixUniOff); ok {
+				suffix = s
+			}
+		}
+	}
+	if hasBase {
+		return base + suffix
+	}
+	if infoFlags&2 != 0 {
+		// CommonNetworkRelativeLink：NetName的偏移相对于该结构的开头
+		cnrl := start + networkOff
+		if netNameOff, ok := r.u32(cnrl + 8); ok {
+			if netName, ok := r.cString(cnrl + netNameOff); ok && netName != "" {
+				if suffix == "" {
+					return netName
+				}
+				return netName + `\` + suffix
+			}
+		}
+	}
+	return ""
+}
+
+// 解析Linux桌面快捷方式：Type=Link 使用URL，Type=Application 使用Exec中的程序路径
+func parseDesktopEntry(data []byte) (shortcutLink, error) {
+	values := make(map[string]string)
+	inEntry := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inEntry = line == "[Desktop Entry]"
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && inEntry {
+			// 本地化的键（例如 Name[zh_CN]）不需要
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	switch values["Type"] {
+	case "Link":
+		if values["URL"] == "" {
+			return shortcutLink{}, errors.New("桌面快捷方式中没有URL")
+		}
+		if strings.HasPrefix(values["URL"], "/") {
+			return shortcutLink{Path: values["URL"]}, nil
+		}
+		return shortcutLink{URL: values["URL"]}, nil
+	case "Application":
+		program := desktopExecProgram(values["Exec"])
+		if !filepath.IsAbs(program) {
+			return shortcutLink{}, fmt.Errorf("桌面快捷方式的启动命令不是文件路径: %q", values["Exec"])
+		}
+		return shortcutLink{Path: program}, nil
+	}
+	return shortcutLink{}, fmt.Errorf("不支持的桌面快捷方式类型: %q", values["Type"])
+}
+
+// Exec中的程序，即第一个参数，可以用双引号括起来
+func desktopExecProgram(exec string) string {
+	exec = strings.TrimSpace(exec)
+	if strings.HasPrefix(exec, `"`) {
+		if end := strings.Index(exec[1:], `"`); end >= 0 {
+			return strings.ReplaceAll(exec[1:end+1], `\\`, `\`)
+		}
+		return ""
+	}
+	program, _, _ := strings.Cut(exec, " ")
+	return program
+}
+
+// 解析macOS网址快捷方式：XML格式的属性列表中的URL键，或二进制属性列表中的网址字符串
+func parseWebloc(data []byte) (shortcutLink, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		if u := binaryPlistURL(data); u != "" {
+			return shortcutLink{URL: u}, nil
+		}
+		return shortcutLink{}, errors.New("网址快捷方式中没有URL")
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	lastKey, inKey, inString := "", false, false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return shortcutLink{}, errors.New("网址快捷方式中没有URL")
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			inKey, inString = t.Name.Local == "key", t.Name.Local == "string"
+		case xml.EndElement:
+			inKey, inString = false, false
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if inKey {
+				lastKey = text
+			} else if inString && lastKey == "URL" && text != "" {
+				return shortcutLink{URL: text}, nil
+			}
+		}
+	}
+}
+
+// 在二进制属性列表中查找第一个带协议的ASCII字符串。网址快捷方式只有一个URL键，不需要解析对象表
+func binaryPlistURL(data []byte) string {
+	for i := 8; i < len(data); i++ {
+		if data[i]>>4 != 0x5 {
+			continue
+		}
+		length, start := int(data[i]&0x0F), i+1
+		if length == 0x0F {
+			// 长度超过14时，后面跟一个1字节的整数对象
+			if i+2 >= len(data) || data[i+1] != 0x10 {
+				continue
+			}
+			length, start = int(data[i+2]), i+3
+		}
+		if start+length > len(data) {
+			continue
+		}
+		s := string(data[start : start+length])
+		if u, err := url.Parse(s); err == nil && u.Scheme != "" && strings.Contains(s, "://") {
+			return s
+		}
+	}
+	return ""
+}
+
+// 快捷方式不支持更新时返回的错误
+var errShortcutNotUpdatable = errors.New("这种快捷方式不能自动更新")
+
+// 目标文件移动后更新文本格式的快捷方式（.desktop 和 XML 格式的 .webloc），保持快捷方式的修改时间。
+// Windows快捷方式和二进制的 .webloc 不更新
+func updateShortcut(path, oldTarget, newTarget string) error {
+	kind := shortcutKind(path)
+	if kind != "desktop" && kind != "webloc" {
+		return errShortcutNotUpdatable
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("读取快捷方式失败: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取快捷方式失败: %w", err)
+	}
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return errShortcutNotUpdatable
+	}
+	text := string(data)
+	updated := strings.ReplaceAll(text, pathFileURL(oldTarget), pathFileURL(newTarget))
+	if kind == "desktop" {
+		updated = strings.ReplaceAll(updated, oldTarget, newTarget)
+	}
+	if updated == text {
+		return errors.New("快捷方式中没有找到原来的路径")
+	}
+	if err := os.WriteFile(path, []byte(updated), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("更新快捷方式失败: %w", err)
+	}
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+	return nil
+}
+
+// shortcutPlan 整理前解析快捷方式的结果，整理期间只读
+type shortcutPlan struct {
+	links       map[string]bool     // 网址快捷方式，放入「链接」文件夹
+	targets     map[string][]string // 被整理的目标文件 → 指向它的快捷方式
+	leftInPlace map[string]string   // 目标已加入整理的快捷方式 → 目标文件
+	unresolved  map[string]string   // 无法解析或目标不能整理的快捷方式 → 原因
+}
+
+func (p *shortcutPlan) isLink(path string) bool {
+	return p != nil && p.links[path]
+}
+
+func (p *shortcutPlan) isTarget(path string) bool {
+	return p != nil && len(p.targets[path]) > 0
+}
+
+// 目标已加入整理、自身留在原处的快捷方式指向的文件
+func (p *shortcutPlan) resolvedTarget(path string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	target, ok := p.leftInPlace[path]
+	return target, ok
+}
+
+// 无法解析而跳过的快捷方式的原因
+func (p *shortcutPlan) unresolvedReason(path string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	reason, ok := p.unresolved[path]
+	return reason, ok
+}
+
+// 整理前解析本次整理中的快捷方式：网址快捷方式随后放入「链接」文件夹，
+// 指向源文件夹中文件的快捷方式留在原处，目标文件加入本次整理（不受所选后缀限制），
+// 无法解析的或目标在源文件夹之外的快捷方式跳过并在总结中报告
+func (fo *FileOrganizer) planShortcuts(config Config, files []string) ([]string, *shortcutPlan) {
+	files, plan := fo.resolveShortcuts(config, files)
+	if n := len(plan.links) + len(plan.leftInPlace) + len(plan.unresolved); n > 0 {
+		fo.log(fmt.Sprintf("快捷方式: 发现 %d 个，%d 个网址放入「%s」，%d 个整理其指向的文件，%d 个无法解析",
+			n, len(plan.links), LinksFolderName, len(plan.leftInPlace), len(plan.unresolved)))
+	}
+	return files, plan
+}
+
+// 解析快捷方式，只读取快捷方式和目标文件，规则测试中也使用
+func (fo *FileOrganizer) resolveShortcuts(config Config, files []string) ([]string, *shortcutPlan) {
+	plan := &shortcutPlan{
+		links:       make(map[string]bool),
+		targets:     make(map[string][]string),
+		leftInPlace: make(map[string]string),
+		unresolved:  make(map[string]string),
+	}
+	present := make(map[string]bool, len(files))
+	for _, path := range files {
+		present[path] = true
+	}
+	for _, path := range files {
+		if shortcutKind(path) == "" || !fo.isTargetFile(fileExtension(path), config.FileExtensions) {
+			continue
+		}
+		link, err := resolveShortcut(path)
+		if err != nil {
+			plan.unresolved[path] = err.Error()
+			continue
+		}
+		if link.URL != "" {
+			plan.links[path] = true
+			continue
+		}
+		info, err := os.Stat(link.Path)
+		switch {
+		case err != nil:
+			plan.unresolved[path] = "快捷方式的目标不存在: " + link.Path
+		case !info.Mode().IsRegular():
+			plan.unresolved[path] = "快捷方式指向文件夹: " + link.Path
+		case !isWithinAny(link.Path, config.SourceDirs):
+			plan.unresolved[path] = "快捷方式的目标不在源文件夹中: " + link.Path
+		case shortcutKind(link.Path) != "":
+			plan.unresolved[path] = "快捷方式指向另一个快捷方式: " + link.Path
+		default:
+			plan.leftInPlace[path] = link.Path
+			plan.targets[link.Path] = append(plan.targets[link.Path], path)
+			if !present[link.Path] {
+				present[link.Path] = true
+				files = append(files, link.Path)
+			}
+		}
+	}
+	return files, plan
+}
+
+// 目标文件移动后更新指向它的快捷方式
+func (fo *FileOrganizer) updateShortcutsFor(workerID int, plan *shortcutPlan, oldTarget, newTarget string) {
+	if plan == nil || oldTarget == newTarget {
+		return
+	}
+	for _, shortcut := range plan.targets[oldTarget] {
+		if err := updateShortcut(shortcut, oldTarget, newTarget); err != nil {
+			fo.log(fmt.Sprintf("[工作协程 %d] 警告: 快捷方式 %s 未更新，仍指向原来的位置: %v", workerID, shortcut, err))
+		} else {
+			fo.log(fmt.Sprintf("[工作协程 %d] 已更新快捷方式: %s -> %s", workerID, shortcut, newTarget))
+		}
+	}
+}
+
+// 在总结中列出无法解析而跳过的快捷方式
+func (fo *FileOrganizer) logShortcutReport(plan *shortcutPlan) {
+	if plan == nil || len(plan.unresolved) == 0 {
+		return
+	}
+	const maxListed = 20
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "跳过了 %d 个无法解析的快捷方式:", len(plan.unresolved))
+	paths := make([]string, 0, len(plan.unresolved))
+	for path := range plan.unresolved {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for i, path := range paths {
+		if i == maxListed {
+			fmt.Fprintf(&sb, "\n  等 %d 个", len(paths))
+			break
+		}
+		fmt.Fprintf(&sb, "\n  %s（%s）", path, plan.unresolved[path])
+	}
+	fo.log(sb.String())
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 取消整理后等待工作协程结束的最长时间，超时后写入整理目录并强制退出
+const shutdownTimeout = 30 * time.Second
+
+// 是否有整理正在进行（包括任务队列中两个任务之间）
+func (fo *FileOrganizer) runActive() bool {
+	return fo.activeRuns.Load() > 0 || fo.queueRunning.Load()
+}
+
+// 关闭窗口时调用：没有整理在进行时直接退出，否则由用户选择等待完成、取消整理或返回
+func (fo *FileOrganizer) confirmClose() {
+	if fo.quitting.Load() {
+		return
+	}
+	if !fo.runActive() {
+		fo.Window.Close()
+		return
+	}
+
+	message := widget.NewLabel("正在整理文件，现在退出会中断正在移动的文件。\n" +
+		"可以等待整理完成后退出，或者取消剩余的文件后退出（正在移动的文件会先完成）。")
+	message.Wrapping = fyne.TextWrapWord
+	var closeDialog dialog.Dialog
+	backBtn := widget.NewButton("返回", func() {
+		closeDialog.Hide()
+	})
+	cancelBtn := widget.NewButton("取消整理并退出", func() {
+		closeDialog.Hide()
+		fo.shutdown(true)
+	})
+	cancelBtn.Importance = widget.DangerImportance
+	waitBtn := widget.NewButton("等待完成后退出", func() {
+		closeDialog.Hide()
+		fo.shutdown(false)
+	})
+	waitBtn.Importance = widget.HighImportance
+	closeDialog = dialog.NewCustomWithoutButtons("整理仍在进行",
+		container.NewVBox(message, container.NewHBox(layout.NewSpacer(), backBtn, cancelBtn, waitBtn)), fo.Window)
+	fo.showDialog(closeDialog, backBtn)
+}
+
+// 等待整理结束后退出。cancel为true时先取消剩余的文件，等待超过shutdownTimeout后强制退出；
+// 等待完成时可以随时改为取消
+func (fo *FileOrganizer) shutdown(cancel bool) {
+	fo.quitting.Store(true)
+	status := widget.NewLabel("正在等待整理完成...")
+	status.Wrapping = fyne.TextWrapWord
+	progress := widget.NewProgressBarInfinite()
+
+	cancelled := make(chan struct{}, 1)
+	requestCancel := func() {
+		fo.cancelQueue.Store(true)
+		fo.cancelProcessing.Store(true)
+		fo.log("正在退出: 已取消剩余的文件，等待正在移动的文件完成")
+		status.SetText("正在取消整理，等待正在移动的文件完成...")
+		cancelled <- struct{}{}
+	}
+	var cancelBtn *widget.Button
+	cancelBtn = widget.NewButton("取消整理并退出", func() {
+		cancelBtn.Disable()
+		requestCancel()
+	})
+	cancelBtn.Importance = widget.DangerImportance
+	waitDialog := dialog.NewCustomWithoutButtons("正在退出",
+		container.NewVBox(status, progress, container.NewHBox(layout.NewSpacer(), cancelBtn)), fo.Window)
+	fo.showDialog(waitDialog, cancelBtn)
+	if cancel {
+		cancelBtn.Disable()
+		requestCancel()
+	} else {
+		fo.log("正在退出: 等待整理完成")
+	}
+
+	go func() {
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		var deadline <-chan time.Time
+		for fo.runActive() {
+			select {
+			case <-cancelled:
+				deadline = time.After(shutdownTimeout)
+			case <-deadline:
+				// 工作协程仍未结束（例如在等待目标磁盘恢复），把已移动的文件写入整理目录后强制退出
+				if catalog := fo.runCatalog.Load(); catalog != nil {
+					catalog.sync()
+				}
+				fo.syncRunJournals()
+				fo.targetLocks.Range(func(_, value any) bool {
+					value.(*targetLockHandle).release()
+					return true
+				})
+				fo.log(fmt.Sprintf("等待 %v 后整理仍未结束，强制退出；已移动的文件已写入整理目录，下次启动时可以继续整理其余的文件", shutdownTimeout))
+				fo.safeUpdateUI(func() {
+					progress.Stop()
+					fo.Window.Close()
+				})
+				return
+			case <-ticker.C:
+			}
+		}
+		fo.safeUpdateUI(func() {
+			progress.Stop()
+			waitDialog.Hide()
+			fo.Window.Close()
+		})
+	}()
+}
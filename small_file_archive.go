@@ -0,0 +1,529 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 打包小文件时默认的大小上限（KB），小于这个大小的文件放入日期文件夹对应的压缩包
+const defaultPackThresholdKB = 64
+
+// 日期文件夹对应的压缩包的路径：与日期文件夹同名，放在日期文件夹旁边
+func packArchivePath(targetDir string) string {
+	return filepath.Clean(targetDir) + ".zip"
+}
+
+// 写入压缩包的注释，用来区分本程序打包的压缩包和用户自己放在目标中的同名压缩包
+const packArchiveComment = "FileOrganizer packed small files"
+
+// 是否是本程序打包小文件的压缩包
+func isPackArchive(path string) bool {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return false
+	}
+	defer reader.Close()
+	return reader.Comment == packArchiveComment
+}
+
+// 日期文件夹实际使用的压缩包。同名的压缩包不是本程序打包的（例如用户自己的 2024-01-01.zip）时
+// 不追加到其中，改用 2024-01-01 (1).zip 等不重名的压缩包
+func resolvePackArchive(archivePath string) (string, error) {
+	stem := strings.TrimSuffix(archivePath, ".zip")
+	for i := 0; ; i++ {
+		candidate := archivePath
+		if i > 0 {
+			candidate = fmt.Sprintf("%s (%d).zip", stem, i)
+		}
+		_, err := os.Lstat(candidate)
+		if errors.Is(err, os.ErrNotExist) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("读取压缩包失败: %w", err)
+		}
+		if isPackArchive(candidate) {
+			return candidate, nil
+		}
+	}
+}
+
+// packedMember 压缩包中的一个文件。source 不为空时是本次整理中等待打包的文件
+type packedMember struct {
+	name   string
+	size   int64
+	crc    uint32
+	source string
+}
+
+// 读取压缩包中的文件列表
+func readPackMembers(archivePath string) ([]packedMember, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取压缩包失败: %w", err)
+	}
+	defer reader.Close()
+	members := make([]packedMember, 0, len(reader.File))
+	for _, f := range reader.File {
+		members = append(members, packedMember{name: f.Name, size: int64(f.UncompressedSize64), crc: f.CRC32})
+	}
+	return members, nil
+}
+
+// 压缩包中的文件与源文件内容是否相同：先比较CRC32，相同时再逐字节比较
+func zipMemberEquals(archivePath string, member packedMember, sourcePath string) (bool, error) {
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return false, fmt.Errorf("读取源文件失败: %w", err)
+	}
+	if int64(len(data)) != member.size || crc32.ChecksumIEEE(data) != member.crc {
+		return false, nil
+	}
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return false, fmt.Errorf("读取压缩包失败: %w", err)
+	}
+	defer reader.Close()
+	for _, f := range reader.File {
+		if f.Name != member.name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return false, fmt.Errorf("读取压缩包失败: %w", err)
+		}
+		packed, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return false, fmt.Errorf("读取压缩包失败: %w", err)
+		}
+		return bytes.Equal(packed, data), nil
+	}
+	return false, nil
+}
+
+// 计算压缩包中一个文件内容的SHA-256，与 hashFile 的结果可以直接比较
+func hashZipMember(archivePath, member string) (string, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("读取压缩包失败: %w", err)
+	}
+	defer reader.Close()
+	for _, f := range reader.File {
+		if f.Name != member {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", fmt.Errorf("读取压缩包失败: %w", err)
+		}
+		defer rc.Close()
+		hasher := sha256.New()
+		if _, err := io.Copy(hasher, rc); err != nil {
+			return "", fmt.Errorf("读取压缩包失败: %w", err)
+		}
+		return hex.EncodeToString(hasher.Sum(nil)), nil
+	}
+	return "", fmt.Errorf("压缩包中已没有 %s", member)
+}
+
+// packItem 等待放入压缩包的一个小文件
+type packItem struct {
+	source   string
+	folder   string // 不打包时文件所在的日期文件夹
+	size     int64
+	modTime  time.Time
+	mode     os.FileMode
+	hash     string
+	date     string // 目标修改时间精度低时记录在整理目录中的日期
+	copyOnly bool   // 只读源文件夹中的文件，打包后保留源文件
+}
+
+// packedFile 打包的结果
+type packedFile struct {
+	item    packItem
+	archive string
+	member  string // 压缩包中的文件名
+	err     error
+}
+
+// smallFilePacker 整理时收集小文件，工作协程处理完后按压缩包一次写入
+type smallFilePacker struct {
+	mu       sync.Mutex
+	groups   map[string][]packItem     // 压缩包路径 -> 文件
+	resolved map[string]string         // 日期文件夹对应的压缩包 -> 实际使用的压缩包
+	members  map[string][]packedMember // 压缩包中已有的和等待打包的文件
+}
+
+func newSmallFilePacker() *smallFilePacker {
+	return &smallFilePacker{
+		groups:   make(map[string][]packItem),
+		resolved: make(map[string]string),
+		members:  make(map[string][]packedMember),
+	}
+}
+
+// 日期文件夹的小文件实际放入的压缩包，第一次用到时确定并读取其中已有的文件
+func (p *smallFilePacker) archiveFor(targetDir string) (string, error) {
+	archivePath := packArchivePath(targetDir)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if resolved, ok := p.resolved[archivePath]; ok {
+		return resolved, nil
+	}
+	resolved, err := resolvePackArchive(archivePath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(resolved); err == nil {
+		members, err := readPackMembers(resolved)
+		if err != nil {
+			return "", err
+		}
+		p.members[resolved] = members
+	}
+	p.resolved[archivePath] = resolved
+	return resolved, nil
+}
+
+// 压缩包中是否已有同名且内容相同的文件（包括本次等待打包的文件）
+func (p *smallFilePacker) identical(archivePath, sourcePath string, size int64) (bool, error) {
+	name := filepath.Base(sourcePath)
+	p.mu.Lock()
+	var candidates []packedMember
+	for _, member := range p.members[archivePath] {
+		if member.name == name && member.size == size {
+			candidates = append(candidates, member)
+		}
+	}
+	p.mu.Unlock()
+	for _, member := range candidates {
+		if member.source != "" {
+			if sameFileContent(sourcePath, member.source) {
+				return true, nil
+			}
+			continue
+		}
+		same, err := zipMemberEquals(archivePath, member, sourcePath)
+		if err != nil || same {
+			return same, err
+		}
+	}
+	return false, nil
+}
+
+// 记录一个要放入压缩包的文件
+func (p *smallFilePacker) add(archivePath string, item packItem) {
+	p.mu.Lock()
+	p.groups[archivePath] = append(p.groups[archivePath], item)
+	p.members[archivePath] = append(p.members[archivePath], packedMember{name: filepath.Base(item.source), size: item.size, source: item.source})
+	p.mu.Unlock()
+}
+
+// 按路径排列的压缩包
+func (p *smallFilePacker) archives() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	archives := make([]string, 0, len(p.groups))
+	for archivePath := range p.groups {
+		archives = append(archives, archivePath)
+	}
+	sort.Strings(archives)
+	return archives
+}
+
+func (p *smallFilePacker) items(archivePath string) []packItem {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.groups[archivePath]
+}
+
+// 压缩包中不重名的文件名，重名时追加序号
+func uniqueMemberName(name string, taken map[string]bool) string {
+	if !taken[name] {
+		taken[name] = true
+		return name
+	}
+	stem, ext := splitExtension(name)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		if !taken[candidate] {
+			taken[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// 压缩包中添加一个文件，返回写入内容的CRC32
+func addZipMember(w *zip.Writer, name string, item packItem) (uint32, error) {
+	source, err := os.Open(item.source)
+	if err != nil {
+		return 0, fmt.Errorf("打开源文件失败: %w", err)
+	}
+	defer source.Close()
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: item.modTime}
+	header.SetMode(item.mode)
+	member, err := w.CreateHeader(header)
+	if err != nil {
+		return 0, fmt.Errorf("写入压缩包失败: %w", err)
+	}
+	checksum := crc32.NewIEEE()
+	if _, err := io.Copy(io.MultiWriter(member, checksum), source); err != nil {
+		return 0, fmt.Errorf("写入压缩包失败: %w", err)
+	}
+	return checksum.Sum32(), nil
+}
+
+// 重新打开写好的压缩包，逐个读出新加入的文件，确认大小和CRC32与源文件一致
+func verifyZipMembers(path string, expected map[string]packItem, checksums map[string]uint32) error {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("校验压缩包失败: %w", err)
+	}
+	defer reader.Close()
+	found := 0
+	for _, f := range reader.File {
+		item, ok := expected[f.Name]
+		if !ok {
+			continue
+		}
+		found++
+		if f.CRC32 != checksums[f.Name] || int64(f.UncompressedSize64) != item.size {
+			return fmt.Errorf("校验压缩包失败: %s 与源文件不一致", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("校验压缩包失败: %w", err)
+		}
+		// 读到末尾时会检查CRC32
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("校验压缩包失败: %s: %w", f.Name, err)
+		}
+	}
+	if found != len(expected) {
+		return errors.New("校验压缩包失败: 有文件没有写入")
+	}
+	return nil
+}
+
+// 重写压缩包：zip格式不能安全地原地追加，先把已有的内容和新文件写到临时文件，
+// 同步到磁盘并校验后再替换原来的压缩包。返回每个文件在压缩包中的名称
+func writePackArchive(archivePath string, items []packItem) (map[string]string, error) {
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return nil, fmt.Errorf("创建目标目录失败: %w", err)
+	}
+	var existing *zip.ReadCloser
+	if _, err := os.Stat(archivePath); err == nil {
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf("读取已有的压缩包失败: %w", err)
+		}
+		existing = r
+		defer existing.Close()
+	}
+
+	// 写到部分文件，中断时可以在「清理残留文件」中删除
+	partialPath := archivePath + partialCopySuffix
+	file, err := os.Create(partialPath)
+	if err != nil {
+		return nil, fmt.Errorf("创建压缩包失败: %w", err)
+	}
+	done := false
+	defer func() {
+		if !done {
+			file.Close()
+			os.Remove(partialPath)
+		}
+	}()
+
+	w := zip.NewWriter(faultyWriter(file))
+	if err := w.SetComment(packArchiveComment); err != nil {
+		return nil, fmt.Errorf("写入压缩包失败: %w", err)
+	}
+	taken := make(map[string]bool)
+	if existing != nil {
+		for _, f := range existing.File {
+			// 已有的文件原样复制，不重新压缩
+			if err := w.Copy(f); err != nil {
+				return nil, fmt.Errorf("复制已有的压缩包内容失败: %w", err)
+			}
+			taken[f.Name] = true
+		}
+	}
+	members := make(map[string]string, len(items))
+	expected := make(map[string]packItem, len(items))
+	checksums := make(map[string]uint32, len(items))
+	for _, item := range items {
+		name := uniqueMemberName(filepath.Base(item.source), taken)
+		checksum, err := addZipMember(w, name, item)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", item.source, err)
+		}
+		members[item.source] = name
+		expected[name] = item
+		checksums[name] = checksum
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("写入压缩包失败: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("写入压缩包失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("写入压缩包失败: %w", err)
+	}
+	if err := verifyZipMembers(partialPath, expected, checksums); err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		existing.Close()
+	}
+	if err := os.Rename(partialPath, archivePath); err != nil {
+		return nil, fmt.Errorf("替换压缩包失败: %w", err)
+	}
+	done = true
+	return members, nil
+}
+
+// 写入本次收集的所有压缩包，写入并校验成功后才删除源文件（只读源文件夹中的文件保留）。
+// 一个压缩包失败时其中的文件都留在原处
+func (fo *FileOrganizer) writePackedArchives(packer *smallFilePacker) []packedFile {
+	var results []packedFile
+	for _, archivePath := range packer.archives() {
+		items := packer.items(archivePath)
+		if err := fo.checkWritable(); err != nil {
+			for _, item := range items {
+				results = append(results, packedFile{item: item, archive: archivePath, err: err})
+			}
+			continue
+		}
+		members, err := writePackArchive(archivePath, items)
+		for _, item := range items {
+			if err != nil {
+				results = append(results, packedFile{item: item, archive: archivePath, err: err})
+				continue
+			}
+			if !item.copyOnly {
+				if removeErr := os.Remove(item.source); removeErr != nil {
+					fo.log(fmt.Sprintf("警告: 已放入压缩包但无法删除原文件 %s: %v", item.source, removeErr))
+				}
+			}
+			results = append(results, packedFile{item: item, archive: archivePath, member: members[item.source]})
+		}
+	}
+	return results
+}
+
+// 从压缩包中取出一个文件到指定路径，保留压缩包中记录的修改时间
+func extractZipMember(archivePath, member, targetPath string) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("打开压缩包失败: %w", err)
+	}
+	defer reader.Close()
+	for _, f := range reader.File {
+		if f.Name != member {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("读取压缩包失败: %w", err)
+		}
+		defer rc.Close()
+		partialPath := targetPath + partialCopySuffix
+		out, err := os.OpenFile(partialPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode().Perm()|0200)
+		if err != nil {
+			return fmt.Errorf("创建文件失败: %w", err)
+		}
+		_, err = io.Copy(out, rc)
+		if err == nil {
+			err = out.Sync()
+		}
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chtimes(partialPath, time.Now(), f.Modified)
+		}
+		if err == nil {
+			err = os.Rename(partialPath, targetPath)
+		}
+		if err != nil {
+			os.Remove(partialPath)
+			return fmt.Errorf("从压缩包取出文件失败: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("压缩包中已没有 %s", member)
+}
+
+// 从压缩包中删除一批文件，只重写一次压缩包；压缩包变空时删除压缩包
+func removeZipMembers(archivePath string, members map[string]bool) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("打开压缩包失败: %w", err)
+	}
+	defer reader.Close()
+	remaining := 0
+	for _, f := range reader.File {
+		if !members[f.Name] {
+			remaining++
+		}
+	}
+	if remaining == len(reader.File) {
+		return nil
+	}
+	if remaining == 0 {
+		reader.Close()
+		return os.Remove(archivePath)
+	}
+
+	partialPath := archivePath + partialCopySuffix
+	file, err := os.Create(partialPath)
+	if err != nil {
+		return fmt.Errorf("重写压缩包失败: %w", err)
+	}
+	w := zip.NewWriter(file)
+	err = w.SetComment(reader.Comment)
+	for _, f := range reader.File {
+		if err != nil {
+			break
+		}
+		if members[f.Name] {
+			continue
+		}
+		err = w.Copy(f)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		reader.Close()
+		err = os.Rename(partialPath, archivePath)
+	}
+	if err != nil {
+		os.Remove(partialPath)
+		return fmt.Errorf("重写压缩包失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// 压缩包中的文件名，按名称排列
+func zipMemberNames(t *testing.T, archivePath string) []string {
+	t.Helper()
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var names []string
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 按日期整理并打包小文件的设置
+func packTestConfig(source, target string) Config {
+	return Config{
+		SourceDir:        source,
+		SourceDirs:       []string{source},
+		TargetDir:        target,
+		FileExtensions:   []string{".txt"},
+		OrganizeRule:     string(RuleByDate),
+		FolderDateFormat: "YYYY-MM",
+		PackSmallFiles:   true,
+		PackThresholdKB:  defaultPackThresholdKB,
+		ConflictPolicy:   ConflictRename,
+		CatalogEnabled:   true,
+		ExcludedFiles:    map[string]bool{},
+	}
+}
+
+// 一次删除一批文件，压缩包只重写一次并保留注释，全部删除时删除压缩包
+func TestRemoveZipMembers(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove []string
+		want   []string // nil 表示压缩包已删除
+	}{
+		{"删除部分文件", []string{"a.txt", "c.txt"}, []string{"b.txt"}},
+		{"删除不存在的文件", []string{"x.txt"}, []string{"a.txt", "b.txt", "c.txt"}},
+		{"全部删除", []string{"a.txt", "b.txt", "c.txt"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, "2024-06.zip")
+			var items []packItem
+			for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+				path := writeTestFile(t, filepath.Join(dir, "src", name), name)
+				items = append(items, packItem{source: path, size: int64(len(name)), modTime: time.Now(), mode: 0644})
+			}
+			if _, err := writePackArchive(archivePath, items); err != nil {
+				t.Fatal(err)
+			}
+			remove := make(map[string]bool)
+			for _, name := range tt.remove {
+				remove[name] = true
+			}
+			if err := removeZipMembers(archivePath, remove); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if _, err := os.Stat(archivePath); !os.IsNotExist(err) {
+					t.Fatalf("压缩包应已删除: %v", err)
+				}
+				return
+			}
+			if got := zipMemberNames(t, archivePath); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("剩余 %v, 期望 %v", got, tt.want)
+			}
+			if !isPackArchive(archivePath) {
+				t.Fatal("重写后的压缩包应保留注释")
+			}
+		})
+	}
+}
+
+// 目标中已有用户自己的同名压缩包时不追加到其中
+func TestPackAvoidsUserArchive(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	target := t.TempDir()
+	date := time.Date(2024, 6, 5, 12, 0, 0, 0, time.Local)
+	userArchive := writeTestFile(t, filepath.Join(target, "2024-06.zip"), "not really a zip")
+	config := packTestConfig(source, target)
+
+	for i, name := range []string{"a.txt", "b.txt"} {
+		file := writeDatedFile(t, filepath.Join(source, name), date)
+		if summary, err := fo.processFiles(config, []string{file}); err != nil || summary.Failed != 0 {
+			t.Fatalf("第 %d 次整理: %+v, %v", i+1, summary, err)
+		}
+	}
+	if got := readTestFile(t, userArchive); got != "not really a zip" {
+		t.Fatalf("用户的压缩包被修改: %q", got)
+	}
+	packed := filepath.Join(target, "2024-06 (1).zip")
+	if got := zipMemberNames(t, packed); !reflect.DeepEqual(got, []string{"a.txt", "b.txt"}) {
+		t.Fatalf("%s 中的文件 = %v", packed, got)
+	}
+}
+
+// 压缩包中已有的文件参与合并模式的相同文件跳过和目标去重
+func TestPackedDuplicates(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		dedup    bool
+		fileName string
+		content  string
+		wantKept bool     // 源文件保留在原处
+		wantZip  []string // 整理后压缩包中的文件
+	}{
+		{"合并，同名相同内容", ConflictMerge, false, "a.txt", "same", true, []string{"a.txt"}},
+		{"合并，同名不同内容", ConflictMerge, false, "a.txt", "other", false, []string{"a (1).txt", "a.txt"}},
+		{"去重，不同名相同内容", ConflictRename, true, "b.txt", "same", true, []string{"a.txt"}},
+		{"去重，不同名不同内容", ConflictRename, true, "b.txt", "other", false, []string{"a.txt", "b.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			target := t.TempDir()
+			date := time.Date(2024, 6, 5, 12, 0, 0, 0, time.Local)
+			config := packTestConfig(source, target)
+			config.ConflictPolicy = tt.policy
+			config.DedupTarget = tt.dedup
+
+			first := writeTestFile(t, filepath.Join(source, "first", "a.txt"), "same")
+			os.Chtimes(first, date, date)
+			if _, err := fo.processFiles(config, []string{first}); err != nil {
+				t.Fatal(err)
+			}
+			second := writeTestFile(t, filepath.Join(source, "second", tt.fileName), tt.content)
+			os.Chtimes(second, date, date)
+			if summary, err := fo.processFiles(config, []string{second}); err != nil || summary.Failed != 0 {
+				t.Fatalf("整理: %+v, %v", summary, err)
+			}
+			if _, err := os.Stat(second); (err == nil) != tt.wantKept {
+				t.Fatalf("源文件保留 = %v, 期望 %v", err == nil, tt.wantKept)
+			}
+			if got := zipMemberNames(t, filepath.Join(target, "2024-06.zip")); !reflect.DeepEqual(got, tt.wantZip) {
+				t.Fatalf("压缩包中的文件 = %v, 期望 %v", got, tt.wantZip)
+			}
+		})
+	}
+}
+
+// 撤销打包的小文件时全部取出，压缩包变空后删除
+func TestUndoPackedFiles(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	target := t.TempDir()
+	date := time.Date(2024, 6, 5, 12, 0, 0, 0, time.Local)
+	var files []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		files = append(files, writeDatedFile(t, filepath.Join(source, name), date))
+	}
+	before := snapshotTree(t, source)
+	config := packTestConfig(source, target)
+	if _, err := fo.processFiles(config, files); err != nil {
+		t.Fatal(err)
+	}
+	_, entries, err := lastCatalogRun(catalogPath())
+	if err != nil || len(entries) != len(files) {
+		t.Fatalf("整理目录: %d 条, %v", len(entries), err)
+	}
+	if reverted, failed, err := fo.rollbackEntries(entries, config); err != nil || reverted != len(files) || failed != 0 {
+		t.Fatalf("撤销: 移回 %d, 失败 %d, %v", reverted, failed, err)
+	}
+	if got := snapshotTree(t, source); !reflect.DeepEqual(got, before) {
+		t.Fatalf("撤销后的源文件夹 = %v, 期望 %v", got, before)
+	}
+	if got := snapshotTree(t, target); len(got) != 0 {
+		t.Fatalf("撤销后目标文件夹中仍有文件: %v", got)
+	}
+}
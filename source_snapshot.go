@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 源快照：整理前记录待整理的每个源文件的路径、大小、修改时间和SHA-256，
+// 不依赖整理目录也能核对文件有没有丢失，并找到每个文件现在的位置。
+// 快照以JSON Lines追加到用户选择的文件中，每次整理先写一行快照头，以前的快照都保留
+
+// snapshotRecord 快照文件中的一行：快照头（Sources不为空）或一个源文件
+type snapshotRecord struct {
+	Snapshot string    `json:"snapshot"` // 整理的RunID
+	Sources  []string  `json:"sources,omitempty"`
+	Created  time.Time `json:"created,omitzero"`
+	Path     string    `json:"path,omitempty"`
+	Size     int64     `json:"size,omitempty"`
+	ModTime  time.Time `json:"mtime,omitzero"`
+	Hash     string    `json:"sha256,omitempty"`
+	Package  bool      `json:"package,omitempty"` // 程序包和图库作为整体移动，不计算哈希
+	Error    string    `json:"error,omitempty"`   // 无法读取时的原因
+}
+
+// sourceSnapshot 快照文件中的一次快照
+type sourceSnapshot struct {
+	header  snapshotRecord
+	entries []snapshotRecord
+}
+
+// 计算待整理文件的快照记录，多个协程并行计算哈希
+func (fo *FileOrganizer) snapshotRecords(runID string, files []string) []snapshotRecord {
+	records := make([]snapshotRecord, len(files))
+	workers := min(runtime.NumCPU(), 4)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				path := files[i]
+				record := snapshotRecord{Snapshot: runID, Path: path}
+				info, err := os.Lstat(path)
+				if err != nil {
+					record.Error = err.Error()
+					records[i] = record
+					continue
+				}
+				record.Size, record.ModTime = info.Size(), info.ModTime()
+				if info.IsDir() {
+					record.Package = true
+				} else if info.Mode().IsRegular() {
+					if record.Hash, err = fo.contentHashes.get(path, info); err != nil {
+						record.Error = err.Error()
+					}
+				}
+				records[i] = record
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return records
+}
+
+// 把一次快照追加到快照文件并同步到磁盘
+func appendSourceSnapshot(path string, header snapshotRecord, records []snapshotRecord) error {
+	var sb strings.Builder
+	for _, record := range append([]snapshotRecord{header}, records...) {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("保存源快照失败: %w", err)
+		}
+		sb.Write(data)
+		sb.WriteByte('\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("保存源快照失败: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("保存源快照失败: %w", err)
+	}
+	_, err = file.WriteString(sb.String())
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("保存源快照失败: %w", err)
+	}
+	return nil
+}
+
+// 整理前记录源快照。快照保存失败时返回错误，不开始整理
+func (fo *FileOrganizer) takeSourceSnapshot(config Config, runID string, files []string) error {
+	// 快照文件本身在源文件夹中时不记录，整理时也会跳过
+	recorded := make([]string, 0, len(files))
+	for _, path := range files {
+		if !samePath(path, config.SourceSnapshot) {
+			recorded = append(recorded, path)
+		}
+	}
+	files = recorded
+	fo.log(fmt.Sprintf("正在记录源快照（%d 个文件）...", len(files)))
+	records := fo.snapshotRecords(runID, files)
+	var total int64
+	unreadable := 0
+	for _, record := range records {
+		total += record.Size
+		if record.Error != "" {
+			unreadable++
+		}
+	}
+	header := snapshotRecord{Snapshot: runID, Sources: config.SourceDirs, Created: time.Now()}
+	if len(header.Sources) == 0 {
+		header.Sources = []string{config.SourceDir}
+	}
+	if err := appendSourceSnapshot(config.SourceSnapshot, header, records); err != nil {
+		return err
+	}
+	fo.log(fmt.Sprintf("源快照已保存: %s（%d 个文件，共 %s）", config.SourceSnapshot, len(records), formatFileSize(total)))
+	if unreadable > 0 {
+		fo.log(fmt.Sprintf("源快照: 警告: %d 个文件无法读取，快照中只记录了路径", unreadable))
+	}
+	return nil
+}
+
+// 读取快照文件中的所有快照，按保存顺序排列。无法解析的行被跳过
+func readSourceSnapshots(path string) ([]sourceSnapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开源快照失败: %w", err)
+	}
+	defer file.Close()
+	var snapshots []sourceSnapshot
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record snapshotRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil || record.Snapshot == "" {
+			continue
+		}
+		if record.Path == "" {
+			snapshots = append(snapshots, sourceSnapshot{header: record})
+			continue
+		}
+		if len(snapshots) == 0 || snapshots[len(snapshots)-1].header.Snapshot != record.Snapshot {
+			// 快照头损坏时按RunID单独成组
+			snapshots = append(snapshots, sourceSnapshot{header: snapshotRecord{Snapshot: record.Snapshot}})
+		}
+		last := &snapshots[len(snapshots)-1]
+		last.entries = append(last.entries, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return snapshots, fmt.Errorf("读取源快照失败: %w", err)
+	}
+	return snapshots, nil
+}
+
+// 核对结果
+const (
+	SnapshotInPlace = "in_place" // 仍在原位置，内容相同
+	SnapshotFound   = "found"    // 在目标中找到了内容相同的文件
+	SnapshotChanged = "changed"  // 原位置的文件内容已改变
+	SnapshotMissing = "missing"  // 没有找到
+)
+
+var snapshotStatusNames = map[string]string{
+	SnapshotInPlace: "仍在原位置",
+	SnapshotFound:   "已找到",
+	SnapshotChanged: "原位置的内容已改变",
+	SnapshotMissing: "未找到",
+}
+
+// snapshotCheck 快照中一个文件的核对结果
+type snapshotCheck struct {
+	entry    snapshotRecord
+	status   string
+	location string // 找到的位置，打包的小文件为 压缩包 › 文件名
+}
+
+// 核对快照：先看原位置，再按整理目录中的记录，最后在目标文件夹中按大小和哈希查找
+func (fo *FileOrganizer) verifySourceSnapshot(snapshot sourceSnapshot, roots []string) []snapshotCheck {
+	checks := make([]snapshotCheck, len(snapshot.entries))
+	var unresolved []int
+	for i, entry := range snapshot.entries {
+		checks[i] = snapshotCheck{entry: entry, status: SnapshotMissing}
+		info, err := os.Lstat(entry.Path)
+		if err != nil {
+			unresolved = append(unresolved, i)
+			continue
+		}
+		if entry.Package || entry.Hash == "" {
+			checks[i].status, checks[i].location = SnapshotInPlace, entry.Path
+			continue
+		}
+		if hash, err := hashFile(entry.Path); err == nil && hash == entry.Hash && info.Size() == entry.Size {
+			checks[i].status, checks[i].location = SnapshotInPlace, entry.Path
+			continue
+		}
+		checks[i].status = SnapshotChanged
+		unresolved = append(unresolved, i)
+	}
+	if len(unresolved) == 0 {
+		return checks
+	}
+
+	// 整理目录中记录的去向（同一个原路径以最近的记录为准），确认文件仍在记录的位置
+	originals := make([]string, len(unresolved))
+	for n, i := range unresolved {
+		originals[n] = checks[i].entry.Path
+	}
+	moved, _ := latestCatalogMoves(catalogPath(), originals)
+	var remaining []int
+	for _, i := range unresolved {
+		entry, ok := moved[checks[i].entry.Path]
+		if ok && (checks[i].entry.Hash == "" || entry.Hash == "" || entry.Hash == checks[i].entry.Hash) {
+			if _, err := os.Stat(entry.FinalPath); err == nil {
+				checks[i].status, checks[i].location = SnapshotFound, entry.displayPath()
+				continue
+			}
+		}
+		remaining = append(remaining, i)
+	}
+
+	// 在目标文件夹中查找大小相同的文件，再比较哈希
+	wanted := make(map[int64]bool)
+	for _, i := range remaining {
+		if checks[i].entry.Hash != "" {
+			wanted[checks[i].entry.Size] = true
+		}
+	}
+	if len(wanted) == 0 {
+		return checks
+	}
+	candidates := make(map[int64][]string)
+	for _, root := range roots {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil && info.Mode().IsRegular() && wanted[info.Size()] {
+				candidates[info.Size()] = append(candidates[info.Size()], path)
+			}
+			return nil
+		})
+	}
+	hashes := make(map[string]string)
+	used := make(map[string]bool)
+	for _, i := range remaining {
+		entry := checks[i].entry
+		for _, path := range candidates[entry.Size] {
+			if used[path] {
+				continue
+			}
+			hash, ok := hashes[path]
+			if !ok {
+				hash, _ = hashFile(path)
+				hashes[path] = hash
+			}
+			if hash == entry.Hash {
+				used[path] = true
+				checks[i].status, checks[i].location = SnapshotFound, path
+				break
+			}
+		}
+	}
+	return checks
+}
+
+// 把核对结果保存为对照表：每行 状态、原路径、现在的位置，用制表符分隔
+func writeSnapshotReport(path string, checks []snapshotCheck) error {
+	var sb strings.Builder
+	sb.WriteString("状态\t原路径\t现在的位置\n")
+	for _, check := range checks {
+		fmt.Fprintf(&sb, "%s\t%s\t%s\n", snapshotStatusNames[check.status], check.entry.Path, check.location)
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("保存对照表失败: %w", err)
+	}
+	return nil
+}
+
+// 核对快照文件中最近的一次快照
+func (fo *FileOrganizer) showSnapshotVerifyDialog(snapshotPath string) {
+	if strings.TrimSpace(snapshotPath) == "" {
+		dialog.ShowInformation("提示", "请先选择源快照文件", fo.Window)
+		return
+	}
+	snapshots, err := readSourceSnapshots(snapshotPath)
+	if err != nil && len(snapshots) == 0 {
+		dialog.ShowError(err, fo.Window)
+		return
+	}
+	if len(snapshots) == 0 {
+		dialog.ShowInformation("提示", "源快照文件中没有快照", fo.Window)
+		return
+	}
+	snapshot := snapshots[len(snapshots)-1]
+	roots := fo.orphanTargetRoots()
+	progress := dialog.NewCustomWithoutButtons("核对源快照", widget.NewProgressBarInfinite(), fo.Window)
+	progress.Show()
+	go func() {
+		checks := fo.verifySourceSnapshot(snapshot, roots)
+		fo.safeUpdateUI(func() {
+			progress.Hide()
+			fo.showSnapshotResults(snapshot, checks)
+		})
+	}()
+}
+
+// 显示核对结果：各状态的数量，以及没有找到和内容改变的文件
+func (fo *FileOrganizer) showSnapshotResults(snapshot sourceSnapshot, checks []snapshotCheck) {
+	counts := make(map[string]int)
+	var problems []snapshotCheck
+	for _, check := range checks {
+		counts[check.status]++
+		if check.status == SnapshotMissing || check.status == SnapshotChanged {
+			problems = append(problems, check)
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].entry.Path < problems[j].entry.Path })
+	fo.log(fmt.Sprintf("核对源快照 %s: %d 个文件仍在原位置，%d 个已找到，%d 个原位置的内容已改变，%d 个未找到",
+		snapshot.header.Snapshot, counts[SnapshotInPlace], counts[SnapshotFound], counts[SnapshotChanged], counts[SnapshotMissing]))
+
+	created := snapshot.header.Snapshot
+	if !snapshot.header.Created.IsZero() {
+		created = snapshot.header.Created.Local().Format("2006-01-02 15:04:05")
+	}
+	summary := widget.NewLabel(fmt.Sprintf("快照时间: %s，共 %d 个文件\n仍在原位置 %d 个，在目标中找到 %d 个，原位置的内容已改变 %d 个，未找到 %d 个",
+		created, len(checks), counts[SnapshotInPlace], counts[SnapshotFound], counts[SnapshotChanged], counts[SnapshotMissing]))
+	summary.Wrapping = fyne.TextWrapWord
+
+	var list fyne.CanvasObject = widget.NewLabel("所有文件都在原位置或已在目标中找到")
+	if len(problems) > 0 {
+		problemList := widget.NewList(
+			func() int { return len(problems) },
+			func() fyne.CanvasObject {
+				label := widget.NewLabel("")
+				label.Truncation = fyne.TextTruncateEllipsis
+				return label
+			},
+			func(id widget.ListItemID, o fyne.CanvasObject) {
+				check := problems[id]
+				o.(*widget.Label).SetText(fmt.Sprintf("%s（%s，%s）", check.entry.Path,
+					snapshotStatusNames[check.status], formatFileSize(check.entry.Size)))
+			},
+		)
+		list = problemList
+	}
+
+	var resultDialog dialog.Dialog
+	closeBtn := widget.NewButton("关闭", func() {
+		resultDialog.Hide()
+	})
+	saveBtn := widget.NewButton("保存对照表...", func() {
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, fo.Window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			writer.Close()
+			if err := writeSnapshotReport(writer.URI().Path(), checks); err != nil {
+				dialog.ShowError(err, fo.Window)
+				return
+			}
+			fo.log("源快照对照表已保存到: " + writer.URI().Path())
+		}, fo.Window)
+		saveDialog.SetFileName(fmt.Sprintf("snapshot_report_%s.tsv", snapshot.header.Snapshot))
+		saveDialog.Show()
+	})
+	content := container.NewBorder(summary,
+		container.NewHBox(layout.NewSpacer(), saveBtn, closeBtn),
+		nil, nil,
+		list,
+	)
+	resultDialog = dialog.NewCustomWithoutButtons("核对源快照", content, fo.Window)
+	resultDialog.Resize(fyne.NewSize(760, 480))
+	fo.showDialog(resultDialog, closeBtn)
+}
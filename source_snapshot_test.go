@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// 源快照文件放在源文件夹中时不整理，也不记录到快照中
+func TestSnapshotInsideSourceIsNotOrganized(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	target := t.TempDir()
+	photo := writeTestFile(t, filepath.Join(source, "a.jpg"), "a")
+	snapshot := writeTestFile(t, filepath.Join(source, "source_snapshot.jsonl"), "")
+	config := Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      target,
+		FileExtensions: []string{".jpg", ".jsonl"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		SourceSnapshot: snapshot,
+		ExcludedFiles:  map[string]bool{},
+	}
+	summary, err := fo.processFiles(config, []string{photo, snapshot})
+	if err != nil || summary.Moved != 1 {
+		t.Fatalf("整理: %+v, %v", summary, err)
+	}
+	snapshots, err := readSourceSnapshots(snapshot)
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("快照: %v, %v", snapshots, err)
+	}
+	if entries := snapshots[0].entries; len(entries) != 1 || entries[0].Path != photo {
+		t.Fatalf("快照中的文件 = %+v", entries)
+	}
+}
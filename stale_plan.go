@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 默认在扫描结果生成这么多分钟后，执行前先检查文件是否有变化，0表示不检查
+const defaultPlanStaleMinutes = 30
+
+// 执行前检查时单个文件的状态
+type planFileState int
+
+const (
+	planFileUnchanged planFileState = iota
+	planFileModified                // 大小或修改时间有变化，执行时按新的信息重新规划
+	planFileMissing                 // 文件已不存在，从本次整理中去掉
+)
+
+// 执行前检查的结果
+type planCheck struct {
+	Unchanged   int
+	Modified    int
+	Missing     int
+	TargetTaken int // 目标位置已出现同名文件，执行时按冲突设置自动重命名或去重
+	infos       map[string]os.FileInfo
+	missing     map[string]bool
+}
+
+// 扫描结果是否已经过时，需要在执行前检查
+func (fo *FileOrganizer) planIsStale() bool {
+	if fo.PlanStaleMinutes <= 0 || fo.scannedAt.IsZero() {
+		return false
+	}
+	return time.Since(fo.scannedAt) >= time.Duration(fo.PlanStaleMinutes)*time.Minute
+}
+
+// 重新检查本次要整理的每个文件：源文件是否存在、大小和修改时间是否不变、目标位置是否仍然空闲。
+// 只读取文件信息并与扫描结果比较，多个协程并行检查。stop为true时尽快返回
+func (fo *FileOrganizer) checkPlan(config Config, files []string, stop *atomic.Bool) planCheck {
+	var candidates []string
+	for _, filePath := range files {
+		if fo.scannedFileInfos[filePath] == nil || config.ExcludedFiles[filePath] ||
+			config.Pins.matches(filePath, nil) || !fo.isTargetFile(fileExtension(filePath), config.FileExtensions) {
+			continue
+		}
+		candidates = append(candidates, filePath)
+	}
+
+	states := make([]planFileState, len(candidates))
+	infos := make([]os.FileInfo, len(candidates))
+	taken := make([]bool, len(candidates))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(candidates) || stop.Load() {
+					return
+				}
+				filePath := candidates[i]
+				scanned := fo.scannedFileInfos[filePath]
+				info, err := os.Lstat(filePath)
+				if err != nil {
+					states[i] = planFileMissing
+					continue
+				}
+				infos[i] = info
+				if info.Size() != scanned.Size() || !info.ModTime().Equal(scanned.ModTime()) {
+					states[i] = planFileModified
+				}
+				// 按内容哈希整理时规划目标需要读取整个文件，不在这里检查目标
+				if OrganizeRule(config.OrganizeRule) == RuleByHash {
+					continue
+				}
+				if targetDir := fo.planTargetDir(filePath, info, config); targetDir != "" {
+					targetPath := filepath.Join(targetDir, filepath.Base(filePath))
+					if targetPath != filePath {
+						if _, err := os.Lstat(targetPath); err == nil {
+							taken[i] = true
+						}
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	check := planCheck{infos: make(map[string]os.FileInfo), missing: make(map[string]bool)}
+	for i, filePath := range candidates {
+		switch states[i] {
+		case planFileUnchanged:
+			check.Unchanged++
+		case planFileModified:
+			check.Modified++
+			check.infos[filePath] = infos[i]
+		case planFileMissing:
+			check.Missing++
+			check.missing[filePath] = true
+		}
+		if taken[i] {
+			check.TargetTaken++
+		}
+	}
+	return check
+}
+
+// 把检查结果应用到扫描结果：去掉已不存在的文件，更新有变化的文件信息
+func (fo *FileOrganizer) applyPlanCheck(check planCheck) {
+	if len(check.missing) > 0 {
+		kept := fo.scannedFiles[:0]
+		for _, filePath := range fo.scannedFiles {
+			if check.missing[filePath] {
+				delete(fo.scannedFileInfos, filePath)
+				continue
+			}
+			kept = append(kept, filePath)
+		}
+		fo.scannedFiles = kept
+	}
+	for filePath, info := range check.infos {
+		fo.scannedFileInfos[filePath] = info
+	}
+	fo.scannedAt = time.Now()
+	fo.refreshFileTable()
+}
+
+// 扫描结果过时时先检查再执行，proceed在用户确认后调用。检查期间可以选择「强制执行」跳过检查
+func (fo *FileOrganizer) confirmStalePlan(config Config, proceed func()) {
+	if !fo.planIsStale() {
+		proceed()
+		return
+	}
+
+	age := time.Since(fo.scannedAt).Round(time.Minute)
+	fo.log(fmt.Sprintf("扫描结果已生成 %v，执行前检查文件是否有变化...", age))
+
+	var stop atomic.Bool
+	var checkingDialog dialog.Dialog
+	forceBtn := widget.NewButton("强制执行", func() {
+		if stop.Swap(true) {
+			return
+		}
+		checkingDialog.Hide()
+		fo.log("已跳过执行前检查，按过时的扫描结果强制执行")
+		proceed()
+	})
+	forceBtn.Importance = widget.DangerImportance
+	cancelBtn := widget.NewButton("取消", func() {
+		if stop.Swap(true) {
+			return
+		}
+		checkingDialog.Hide()
+	})
+	progress := widget.NewProgressBarInfinite()
+	checkingDialog = dialog.NewCustomWithoutButtons("正在检查扫描结果", container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("扫描结果已生成 %v，正在检查文件是否有变化...", age)),
+		progress,
+		container.NewHBox(layout.NewSpacer(), cancelBtn, forceBtn),
+	), fo.Window)
+	fo.showDialog(checkingDialog, cancelBtn)
+
+	files := fo.scannedFiles
+	go func() {
+		check := fo.checkPlan(config, files, &stop)
+		fo.safeUpdateUI(func() {
+			// 用户已选择强制执行或取消
+			if stop.Swap(true) {
+				return
+			}
+			progress.Stop()
+			checkingDialog.Hide()
+			fo.showPlanCheckReport(check, proceed)
+		})
+	}()
+}
+
+// 显示执行前检查的结果，由用户选择按检查结果执行或重新扫描
+func (fo *FileOrganizer) showPlanCheckReport(check planCheck, proceed func()) {
+	summary := fmt.Sprintf("未变化 %d 个，源文件有修改 %d 个（将重新规划），源文件已不存在 %d 个（将跳过）",
+		check.Unchanged, check.Modified, check.Missing)
+	if check.TargetTaken > 0 {
+		summary += fmt.Sprintf("，目标已有同名文件 %d 个（将按冲突设置处理）", check.TargetTaken)
+	}
+	fo.log("执行前检查: " + summary)
+
+	// 没有任何变化时直接执行
+	if check.Modified == 0 && check.Missing == 0 && check.TargetTaken == 0 {
+		fo.applyPlanCheck(check)
+		proceed()
+		return
+	}
+
+	message := widget.NewLabel("扫描结果生成后有文件发生了变化:\n\n" + summary +
+		"\n\n可以按检查结果继续执行，也可以重新扫描后再查看整理计划。")
+	message.Wrapping = fyne.TextWrapWord
+
+	var reportDialog dialog.Dialog
+	executeBtn := widget.NewButton("按检查结果执行", func() {
+		reportDialog.Hide()
+		fo.applyPlanCheck(check)
+		proceed()
+	})
+	executeBtn.Importance = widget.HighImportance
+	rescanBtn := widget.NewButton("重新扫描", func() {
+		reportDialog.Hide()
+		fo.rescan()
+	})
+	cancelBtn := widget.NewButton("取消", func() {
+		reportDialog.Hide()
+	})
+
+	reportDialog = dialog.NewCustomWithoutButtons("执行前检查",
+		container.NewVBox(message, container.NewHBox(layout.NewSpacer(), cancelBtn, rescanBtn, executeBtn)), fo.Window)
+	reportDialog.Resize(fyne.NewSize(560, 0))
+	fo.showDialog(reportDialog, executeBtn)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 入门预设整理时不选择的后缀：未下载完的临时文件、快捷方式和系统文件
+var starterJunkExtensions = map[string]bool{
+	".lnk":      true, // Windows 快捷方式
+	".url":      true, // 网页快捷方式
+	".webloc":   true, // macOS 网页快捷方式
+	".desktop":  true, // Linux 启动器
+	".ini":      true, // desktop.ini
+	".ds_store": true,
+}
+
+// 是否为入门预设不整理的文件后缀
+func isStarterJunkExtension(ext string) bool {
+	ext = strings.ToLower(ext)
+	return starterJunkExtensions[ext] || partialFileExtensions[ext]
+}
+
+// 创建没有源文件夹时显示的入门面板
+func (fo *FileOrganizer) newWelcomePanel() fyne.CanvasObject {
+	hint := widget.NewLabel("还没有选择源文件夹。可以点击右上角的「选择源文件夹」，或者从常用文件夹开始:")
+	hint.Wrapping = fyne.TextWrapWord
+	hint.Alignment = fyne.TextAlignCenter
+
+	downloadsBtn := widget.NewButtonWithIcon("整理下载文件夹", theme.DownloadIcon(), func() {
+		fo.startStarterPreset(FolderDownloads)
+	})
+	desktopBtn := widget.NewButtonWithIcon("整理桌面", theme.ComputerIcon(), func() {
+		fo.startStarterPreset(FolderDesktop)
+	})
+	note := widget.NewLabel("按文件后缀分类，跳过快捷方式和未下载完的文件，扫描后先预览再整理")
+	note.Alignment = fyne.TextAlignCenter
+	note.Importance = widget.LowImportance
+
+	return container.NewCenter(container.NewVBox(
+		hint,
+		container.NewCenter(container.NewHBox(downloadsBtn, desktopBtn)),
+		note,
+	))
+}
+
+// 有源文件夹时隐藏入门面板
+func (fo *FileOrganizer) updateWelcomePanel() {
+	if fo.welcomePanel == nil {
+		return
+	}
+	if len(fo.SourceDirs) == 0 {
+		fo.welcomePanel.Show()
+	} else {
+		fo.welcomePanel.Hide()
+	}
+}
+
+// 用入门预设整理常用文件夹：按后缀整理到原文件夹中，扫描后自动选择后缀（排除快捷方式等）并打开预览
+func (fo *FileOrganizer) startStarterPreset(folder KnownFolder) {
+	path, err := knownFolderPath(folder)
+	if err != nil {
+		fo.log("无法找到常用文件夹: " + err.Error())
+		dialog.ShowError(err, fo.Window)
+		return
+	}
+
+	// 入门预设：按后缀整理，整理到原文件夹中，不保留子文件夹结构
+	fo.FolderLayout = LayoutFlat
+	fo.FileExtensions = nil
+	if fo.TargetDirEntry != nil {
+		fo.TargetDirEntry.SetText("")
+	}
+	// 临时移除回调，避免切换规则时提前扫描
+	callback := fo.RuleSelect.OnChanged
+	fo.RuleSelect.OnChanged = nil
+	fo.RuleSelect.SetSelected(string(RuleByExtension))
+	fo.RuleSelect.OnChanged = callback
+	fo.OrganizeRule = RuleByExtension
+	fo.saveUserConfig()
+	fo.log(fmt.Sprintf("使用入门预设整理%s文件夹: %s", knownFolderNames[folder], path))
+
+	fo.afterScan = func() {
+		var extensions, skipped []string
+		for ext := range fo.scannedFileExtensions {
+			if isStarterJunkExtension(ext) {
+				skipped = append(skipped, ext)
+			} else {
+				extensions = append(extensions, ext)
+			}
+		}
+		sort.Strings(extensions)
+		sort.Strings(skipped)
+		if len(extensions) == 0 {
+			dialog.ShowInformation("提示", fmt.Sprintf("%s文件夹中没有需要整理的文件", knownFolderNames[folder]), fo.Window)
+			return
+		}
+		fo.FileExtensions = extensions
+		fo.log(fmt.Sprintf("已选择 %d 种文件后缀", len(extensions)))
+		if len(skipped) > 0 {
+			fo.log("不整理的后缀: " + strings.Join(skipped, ", "))
+		}
+		fo.processBtn.Enable()
+		fo.showScannedFilesDialog()
+	}
+	fo.addSourceDirs([]string{path})
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 状态页面：可选的内置HTTP服务，在手机等设备上查看整理进度。只提供一个只读的页面和 /status.json，
+// 没有任何控制接口。设置了令牌时需要HTTP基本认证，用户名任意，密码是令牌
+
+// 状态JSON的格式版本，字段含义变化或删除字段时递增，只新增字段时不变
+const statusSchemaVersion = 1
+
+// 状态中保留的最近的错误条数，每条最多保留的字符数
+const (
+	statusRecentErrors = 20
+	statusErrorRunes   = 500
+)
+
+// 无界面运行时状态页面的令牌从这个环境变量读取，不出现在命令行中
+const statusTokenEnvVar = "FILE_ORGANIZER_STATUS_TOKEN"
+
+// 整理的状态
+const (
+	StatusIdle       = "idle"       // 没有在扫描或整理
+	StatusScanning   = "scanning"   // 正在扫描源文件夹
+	StatusProcessing = "processing" // 正在整理
+	StatusPaused     = "paused"     // 目标连续出现只读错误，等待处理
+	StatusFinished   = "finished"   // 整理完成
+	StatusFailed     = "failed"     // 整理没有完成就出错结束，原因见 error
+)
+
+// StatusSnapshot /status.json 的内容（schema_version 1）:
+//
+//	{
+//	  "schema_version": 1,
+//	  "state": "processing",
+//	  "processed": 1200,
+//	  "total": 5000,
+//	  "errors": 2,
+//	  "bytes_read": 734003200,
+//	  "bytes_written": 209715200,
+//	  "started_at": "2026-03-01T10:00:00+08:00",
+//	  "updated_at": "2026-03-01T10:02:00+08:00",
+//	  "elapsed_seconds": 120,
+//	  "eta_seconds": 380,
+//	  "paused_target": "",
+//	  "error": "",
+//	  "recent_errors": ["[工作协程 2] 移动文件失败 ..."]
+//	}
+//
+// errors 是本次整理中处理失败的文件数；读写字节数包括哈希和复制，重命名移动不计入；
+// eta_seconds 按已处理文件的平均速度估计，还没有处理任何文件、不在整理时或流式整理时（总数未知）省略；
+// 流式整理时 total 是已扫描到的文件数；paused_target 是状态为 paused 时变为只读的目标文件夹；
+// error 是状态为 failed 时整理结束的原因
+type StatusSnapshot struct {
+	SchemaVersion  int       `json:"schema_version"`
+	State          string    `json:"state"`
+	Processed      int       `json:"processed"`
+	Total          int       `json:"total"`
+	Errors         int       `json:"errors"`
+	BytesRead      int64     `json:"bytes_read"`
+	BytesWritten   int64     `json:"bytes_written"`
+	StartedAt      time.Time `json:"started_at,omitzero"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+	ETASeconds     *float64  `json:"eta_seconds,omitempty"`
+	PausedTarget   string    `json:"paused_target,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	RecentErrors   []string  `json:"recent_errors"`
+}
+
+// statusNotifier 包装界面的 UINotifier，记录进度供状态页面读取，其余原样转发。
+// 记录只是在锁内更新几个字段，不开启状态页面时也一直记录
+type statusNotifier struct {
+	inner UINotifier
+
+	mu           sync.Mutex
+	state        string
+	processed    int
+	total        int
+	errors       int
+	started      time.Time
+	finished     time.Time
+	bytesRead    int64 // 整理开始时的读写计数，状态中显示与它的差
+	bytesWritten int64
+	pausedTarget string
+	lastError    string
+	recent       []string
+	streaming    bool // 流式整理：文件总数在扫描完成前只是估计值，不估计剩余时间
+
+	serverMu sync.Mutex
+	server   *http.Server
+}
+
+func newStatusNotifier(inner UINotifier) *statusNotifier {
+	return &statusNotifier{inner: inner, state: StatusIdle}
+}
+
+func (n *statusNotifier) AppendLog(text string) {
+	n.inner.AppendLog(text)
+}
+
+// 记录一条最近的错误，过长时按字符截断。调用时需持有 n.mu
+func (n *statusNotifier) addRecentLocked(line string) {
+	if runes := []rune(line); len(runes) > statusErrorRunes {
+		line = string(runes[:statusErrorRunes]) + "…"
+	}
+	n.recent = append(n.recent, line)
+	if len(n.recent) > statusRecentErrors {
+		n.recent = n.recent[len(n.recent)-statusRecentErrors:]
+	}
+}
+
+// 整理中一个文件处理失败，由整理的结果统计调用，result 是该文件的结果
+func (n *statusNotifier) fileFailed(result string) {
+	n.mu.Lock()
+	n.errors++
+	n.addRecentLocked(result)
+	n.mu.Unlock()
+}
+
+// 整理没有完成就出错结束（例如无法锁定目标、无法保存源快照）
+func (n *statusNotifier) processFailed(err error) {
+	n.mu.Lock()
+	if n.state != StatusProcessing && n.state != StatusPaused {
+		// 还没有开始整理，不显示上一次整理的进度
+		n.resetLocked(0)
+	}
+	n.state = StatusFailed
+	n.finished = time.Now()
+	n.lastError = err.Error()
+	n.addRecentLocked(err.Error())
+	n.mu.Unlock()
+}
+
+// 开始流式整理，直到 endStreaming 都不估计剩余时间
+func (n *statusNotifier) beginStreaming() {
+	n.mu.Lock()
+	n.streaming = true
+	n.mu.Unlock()
+}
+
+// 流式整理结束
+func (n *statusNotifier) endStreaming() {
+	n.mu.Lock()
+	n.streaming = false
+	n.mu.Unlock()
+}
+
+func (n *statusNotifier) ClearLog() {
+	n.inner.ClearLog()
+}
+
+func (n *statusNotifier) setState(state string) {
+	n.mu.Lock()
+	n.state = state
+	n.mu.Unlock()
+}
+
+func (n *statusNotifier) ScanStarted() {
+	n.setState(StatusScanning)
+	n.inner.ScanStarted()
+}
+
+func (n *statusNotifier) ScanFinished(rule OrganizeRule) {
+	n.setState(StatusIdle)
+	n.inner.ScanFinished(rule)
+}
+
+func (n *statusNotifier) ScanAborted(errorCount int, badSource string, sourceErrors int) {
+	n.setState(StatusIdle)
+	n.inner.ScanAborted(errorCount, badSource, sourceErrors)
+}
+
+func (n *statusNotifier) SourcesMissing(dirs []string) {
+	n.inner.SourcesMissing(dirs)
+}
+
+// 重新开始计数。调用时需持有 n.mu
+func (n *statusNotifier) resetLocked(total int) {
+	n.state = StatusProcessing
+	n.processed, n.total, n.errors = 0, total, 0
+	n.started, n.finished = time.Now(), time.Time{}
+	n.bytesRead = perfCounters.bytesRead.Load()
+	n.bytesWritten = perfCounters.bytesWritten.Load()
+	n.pausedTarget = ""
+	n.lastError = ""
+	n.recent = nil
+}
+
+// 开始整理时重新计数
+func (n *statusNotifier) ProcessStarted(total int) {
+	n.mu.Lock()
+	n.resetLocked(total)
+	n.mu.Unlock()
+	n.inner.ProcessStarted(total)
+}
+
+func (n *statusNotifier) ProcessProgress(processed, total int) {
+	n.mu.Lock()
+	n.processed, n.total = processed, total
+	n.mu.Unlock()
+	n.inner.ProcessProgress(processed, total)
+}
+
+// 整理完成。出错结束的整理保持失败状态
+func (n *statusNotifier) ProcessFinished() {
+	n.mu.Lock()
+	if n.state != StatusFailed {
+		n.state = StatusFinished
+		n.finished = time.Now()
+	}
+	n.mu.Unlock()
+	n.inner.ProcessFinished()
+}
+
+// 暂停期间显示变为只读的目标，做出选择后恢复为整理中
+func (n *statusNotifier) ReadOnlyTarget(root string, failures int, decide func(action readOnlyAction, newRoot string) error) {
+	n.mu.Lock()
+	n.state = StatusPaused
+	n.pausedTarget = root
+	n.mu.Unlock()
+	n.inner.ReadOnlyTarget(root, failures, func(action readOnlyAction, newRoot string) error {
+		if err := decide(action, newRoot); err != nil {
+			return err
+		}
+		n.mu.Lock()
+		n.state = StatusProcessing
+		n.pausedTarget = ""
+		n.mu.Unlock()
+		return nil
+	})
+}
+
+// 当前的状态
+func (n *statusNotifier) snapshot() StatusSnapshot {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := time.Now()
+	s := StatusSnapshot{
+		SchemaVersion: statusSchemaVersion,
+		State:         n.state,
+		Processed:     n.processed,
+		Total:         n.total,
+		Errors:        n.errors,
+		StartedAt:     n.started,
+		UpdatedAt:     now,
+		PausedTarget:  n.pausedTarget,
+		Error:         n.lastError,
+		RecentErrors:  append([]string{}, n.recent...),
+	}
+	if n.started.IsZero() {
+		return s
+	}
+	s.BytesRead = perfCounters.bytesRead.Load() - n.bytesRead
+	s.BytesWritten = perfCounters.bytesWritten.Load() - n.bytesWritten
+	end := now
+	if !n.finished.IsZero() {
+		end = n.finished
+	}
+	elapsed := end.Sub(n.started)
+	s.ElapsedSeconds = elapsed.Seconds()
+	if (n.state == StatusProcessing || n.state == StatusPaused) && !n.streaming && n.processed > 0 && n.total >= n.processed {
+		eta := (elapsed / time.Duration(n.processed) * time.Duration(n.total-n.processed)).Seconds()
+		s.ETASeconds = &eta
+	}
+	return s
+}
+
+// 检查HTTP基本认证的密码，令牌为空时不需要认证
+func requireStatusToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="FileOrganizer", charset="UTF-8"`)
+			http.Error(w, "需要令牌", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 状态页面的处理程序：只接受GET（和HEAD），其他方法返回405
+func (n *statusNotifier) handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(n.snapshot())
+	})
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		fmt.Fprint(w, statusPageHTML)
+	})
+	return requireStatusToken(token, mux)
+}
+
+// 在指定的地址上开始提供状态页面，已在提供时先停止。返回实际监听的地址
+func (n *statusNotifier) serve(addr, token string) (string, error) {
+	n.stopServing()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("启动状态页面失败: %w", err)
+	}
+	server := &http.Server{
+		Handler:           n.handler(token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	n.serverMu.Lock()
+	n.server = server
+	n.serverMu.Unlock()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			n.AppendLog(fmt.Sprintf("状态页面已停止: %v\n", err))
+		}
+	}()
+	return listener.Addr().String(), nil
+}
+
+// 停止状态页面，等待正在进行的请求完成（最多几秒）
+func (n *statusNotifier) stopServing() {
+	n.serverMu.Lock()
+	server := n.server
+	n.server = nil
+	n.serverMu.Unlock()
+	if server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+	}
+}
+
+// 状态页面的浏览器地址，监听所有地址时显示为本机
+func statusPageURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr + "/"
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/"
+}
+
+// 检查状态页面的监听地址，例如 :8383 或 127.0.0.1:8383
+func validateStatusAddr(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+		return errors.New("请输入 主机:端口，例如 :8383 或 127.0.0.1:8383")
+	}
+	return nil
+}
+
+// 开启或停止状态页面，按当前的设置
+func (fo *FileOrganizer) applyStatusServer() {
+	if fo.StatusAddr == "" {
+		fo.status.stopServing()
+		return
+	}
+	addr, err := fo.status.serve(fo.StatusAddr, fo.StatusToken)
+	if err != nil {
+		fo.log(err.Error())
+		return
+	}
+	fo.log("状态页面: " + statusPageURL(addr))
+}
+
+// 状态页面，每两秒读取一次 /status.json
+const statusPageHTML = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>文件整理进度</title>
+<style>
+body { font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; margin: 1.5em; color: #222; }
+h1 { font-size: 1.3em; }
+progress { width: 100%; height: 1.4em; }
+table { border-collapse: collapse; margin: 1em 0; }
+td { padding: 0.25em 1em 0.25em 0; }
+td:first-child { color: #666; }
+#errors li { font-family: monospace; font-size: 0.85em; word-break: break-all; margin-bottom: 0.4em; }
+#offline { color: #b00; }
+</style>
+</head>
+<body>
+<h1>文件整理进度</h1>
+<progress id="bar" value="0" max="1"></progress>
+<table>
+<tr><td>状态</td><td id="state">-</td></tr>
+<tr><td>进度</td><td id="count">-</td></tr>
+<tr><td>已用时间</td><td id="elapsed">-</td></tr>
+<tr><td>预计剩余</td><td id="eta">-</td></tr>
+<tr><td>读取 / 写入</td><td id="bytes">-</td></tr>
+<tr><td>错误</td><td id="errorCount">-</td></tr>
+</table>
+<p id="offline"></p>
+<h2 style="font-size:1.1em">最近的错误</h2>
+<ul id="errors"></ul>
+<script>
+const states = {idle: "空闲", scanning: "正在扫描", processing: "正在整理", paused: "已暂停（目标只读）", finished: "已完成", failed: "出错结束"};
+function size(n) {
+  const units = ["B", "KB", "MB", "GB", "TB"];
+  let i = 0;
+  while (n >= 1024 && i < units.length - 1) { n /= 1024; i++; }
+  return n.toFixed(i ? 1 : 0) + " " + units[i];
+}
+function duration(s) {
+  s = Math.round(s);
+  const h = Math.floor(s / 3600), m = Math.floor(s % 3600 / 60);
+  return (h ? h + " 小时 " : "") + (h || m ? m + " 分 " : "") + s % 60 + " 秒";
+}
+function text(id, value) { document.getElementById(id).textContent = value; }
+async function refresh() {
+  try {
+    const r = await fetch("status.json", {cache: "no-store"});
+    const s = await r.json();
+    let state = states[s.state] || s.state;
+    if (s.paused_target) state += "：" + s.paused_target;
+    if (s.error) state += "：" + s.error;
+    text("state", state);
+    text("count", s.processed + " / " + s.total);
+    text("elapsed", duration(s.elapsed_seconds));
+    text("eta", s.eta_seconds === undefined ? "-" : duration(s.eta_seconds));
+    text("bytes", size(s.bytes_read) + " / " + size(s.bytes_written));
+    text("errorCount", s.errors);
+    const bar = document.getElementById("bar");
+    bar.max = Math.max(s.total, 1);
+    bar.value = s.processed;
+    const list = document.getElementById("errors");
+    list.replaceChildren(...s.recent_errors.slice().reverse().map(e => {
+      const li = document.createElement("li");
+      li.textContent = e;
+      return li;
+    }));
+    text("offline", "");
+  } catch (e) {
+    text("offline", "无法连接，整理可能已经结束");
+  }
+}
+refresh();
+setInterval(refresh, 2000);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// 请求 /status.json，检查返回的字段都符合 schema_version 1
+func fetchStatus(t *testing.T, fo *FileOrganizer) StatusSnapshot {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	fo.status.handler("").ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d", recorder.Code)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(recorder.Body.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"schema_version", "state", "processed", "total", "errors", "bytes_read",
+		"bytes_written", "updated_at", "elapsed_seconds", "recent_errors"} {
+		if _, ok := fields[name]; !ok {
+			t.Fatalf("缺少字段 %s: %s", name, recorder.Body.String())
+		}
+	}
+	var s StatusSnapshot
+	if err := json.Unmarshal(recorder.Body.Bytes(), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.SchemaVersion != statusSchemaVersion {
+		t.Fatalf("schema_version = %d", s.SchemaVersion)
+	}
+	return s
+}
+
+// 整理期间和整理完成后的状态，失败的文件按结果计数，日志中提到「失败」的其他行不计入
+func TestStatusDuringRun(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := filepath.Join(t.TempDir(), "status-src")
+	target := t.TempDir()
+	files := []string{
+		writeTestFile(t, filepath.Join(source, "a.jpg"), "a"),
+		writeTestFile(t, filepath.Join(source, "b.jpg"), "b"),
+		writeTestFile(t, filepath.Join(source, "broken", "c.jpg"), "ccccc"),
+	}
+	useFaults(t, "exdev=broken,copy-fail-after=1")
+
+	var during StatusSnapshot
+	fo.ui = &startHookNotifier{UINotifier: fo.ui, onStart: func() {
+		fo.log("这一行提到失败但不是文件的结果")
+		during = fetchStatus(t, fo)
+	}}
+	config := Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      target,
+		FileExtensions: []string{".jpg"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		ExcludedFiles:  map[string]bool{},
+	}
+	summary, err := fo.processFiles(config, files)
+	if err != nil || summary.Failed != 1 {
+		t.Fatalf("整理: %+v, %v", summary, err)
+	}
+
+	if during.State != StatusProcessing || during.Total != len(files) || during.Errors != 0 || during.StartedAt.IsZero() {
+		t.Fatalf("整理期间的状态 = %+v", during)
+	}
+	after := fetchStatus(t, fo)
+	if after.State != StatusFinished || after.Errors != 1 || len(after.RecentErrors) != 1 || after.ETASeconds != nil {
+		t.Fatalf("整理完成后的状态 = %+v", after)
+	}
+	if !strings.Contains(after.RecentErrors[0], "c.jpg") {
+		t.Fatalf("最近的错误 = %q", after.RecentErrors[0])
+	}
+}
+
+// 没有开始整理就出错返回时显示为失败
+func TestStatusFailedBeforeStart(t *testing.T) {
+	fo := newTestOrganizer(t)
+	target := t.TempDir()
+	lock, err := acquireTargetLock(target, "other-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lock.release() })
+
+	config := Config{TargetDir: target, SourceDirs: []string{t.TempDir()}, ExcludedFiles: map[string]bool{}}
+	if _, err := fo.processFiles(config, nil); err == nil {
+		t.Fatal("目标已被锁定时应返回错误")
+	}
+	s := fetchStatus(t, fo)
+	if s.State != StatusFailed || s.Error == "" || s.Total != 0 {
+		t.Fatalf("状态 = %+v", s)
+	}
+}
+
+// 流式整理的文件总数只是估计值，不估计剩余时间
+func TestStatusStreaming(t *testing.T) {
+	fo := newTestOrganizer(t)
+	fo.status.beginStreaming()
+	fo.status.ProcessStarted(10)
+	fo.status.ProcessProgress(4, 10)
+
+	s := fetchStatus(t, fo)
+	if s.State != StatusProcessing || s.Processed != 4 || s.Total != 10 || s.ETASeconds != nil {
+		t.Fatalf("流式整理期间的状态 = %+v", s)
+	}
+	fo.status.ProcessProgress(12, 12)
+	fo.status.ProcessFinished()
+	fo.status.endStreaming()
+	if s := fetchStatus(t, fo); s.State != StatusFinished || s.Processed != 12 {
+		t.Fatalf("流式整理结束后的状态 = %+v", s)
+	}
+}
+
+// 过长的错误按字符截断，不会截断多字节字符
+func TestStatusRecentErrorTruncation(t *testing.T) {
+	fo := newTestOrganizer(t)
+	fo.status.ProcessStarted(1)
+	fo.status.fileFailed(strings.Repeat("失败", statusErrorRunes))
+	fo.status.processFailed(errors.New("错误"))
+
+	s := fetchStatus(t, fo)
+	if len(s.RecentErrors) != 2 || s.Errors != 1 {
+		t.Fatalf("状态 = %+v", s)
+	}
+	line := s.RecentErrors[0]
+	if !utf8.ValidString(line) || utf8.RuneCountInString(line) != statusErrorRunes+1 {
+		t.Fatalf("截断后 %d 个字符，UTF-8 有效: %v", utf8.RuneCountInString(line), utf8.ValidString(line))
+	}
+}
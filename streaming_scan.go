@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// 扫描到的文件数超过该值时改用流式整理，不再保留文件列表
+const defaultStreamingThreshold = 1000000
+
+// 流式整理时扫描到但还没有整理的文件最多缓存这么多，整理跟不上时扫描等待
+const streamingBufferSize = 20000
+
+// 扫描到的文件数超过阈值：丢弃已保留的文件列表和扫描缓存，之后只统计数量和后缀。
+// 调用时需持有扫描的锁
+func (fo *FileOrganizer) enterStreamingMode(scanner *dirScanner) {
+	fo.streaming = true
+	fo.streamedCount = len(fo.scannedFiles)
+	fo.scannedFiles = []string{}
+	fo.scannedFileInfos = make(map[string]os.FileInfo)
+	scanner.discard()
+	fo.log(fmt.Sprintf("扫描到的文件超过 %d 个，改用流式整理：不保留文件列表，整理时边扫描边处理", fo.StreamingThreshold))
+}
+
+// 扫描到的文件数，流式整理时文件列表为空，使用扫描时的计数
+func (fo *FileOrganizer) scannedCount() int {
+	if fo.streaming {
+		return fo.streamedCount
+	}
+	return len(fo.scannedFiles)
+}
+
+// 流式整理时处理的后缀：不能按扫描结果选择后缀，使用扫描到的所有后缀，没有后缀的文件也整理
+func (fo *FileOrganizer) streamingExtensions() []string {
+	exts := make([]string, 0, len(fo.scannedFileExtensions)+1)
+	exts = append(exts, "")
+	for ext := range fo.scannedFileExtensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// 流式整理不支持需要事先知道全部文件的功能
+func validateStreamingConfig(config Config) error {
+	var feature string
+	switch {
+	case len(config.Volumes) > 0:
+		feature = "多个目标卷"
+	case config.VolumeCapMB > 0:
+		feature = "按容量分卷"
+	case config.TakeoutMode:
+		feature = "整理 Google 相册导出"
+	case config.CopyMerge != "" && config.CopyMerge != CopyMergeOff:
+		feature = "合并内容相同的副本"
+	case config.ShortcutPolicy == ShortcutResolve:
+		feature = "整理快捷方式指向的文件"
+	case config.SourceSnapshot != "":
+		feature = "整理前记录源快照"
+	default:
+		return nil
+	}
+	return fmt.Errorf("流式整理时不支持%s，请调高流式整理阈值或减少源文件夹中的文件", feature)
+}
+
+// 流式整理：重新扫描源文件夹，扫描到的文件直接交给整理，作为一次整理进行，不保留文件列表。
+// estimate 是之前扫描到的文件数，用于显示进度。仅处理新增时按记录跳过已有的文件在扫描时进行，
+// 记录在整理没有失败时才更新
+func (fo *FileOrganizer) processStreaming(config Config, roots []string, estimate int) (processSummary, error) {
+	if err := validateStreamingConfig(config); err != nil {
+		return processSummary{}, err
+	}
+	fo.status.beginStreaming()
+	defer fo.status.endStreaming()
+
+	// 扫描到的文件就是整理开始时已有的文件，直接加入基准，整理到源文件夹之内的文件由整理时更新
+	var store *incomingStore
+	var incoming *incomingBaseline
+	if config.IncomingOnly {
+		incoming = newIncomingBaseline(config, time.Now())
+		incomingStoreMu.Lock()
+		s, err := loadIncomingStore()
+		incomingStoreMu.Unlock()
+		if err != nil {
+			fo.log(fmt.Sprintf("%v，本次按所有文件都是新增文件整理", err))
+		} else {
+			store = s
+		}
+	}
+	runConfig := config
+	runConfig.IncomingBaseline = incoming
+
+	known := 0
+	feed := &fileFeed{estimate: estimate, scan: func(emit func(string) bool) {
+		scanner := newDirScanner("", true)
+		scanner.discard()
+		for _, root := range roots {
+			scanner.scan(root, func(path string, info os.FileInfo) {
+				if incoming != nil && fo.isTargetFile(fileExtension(path), config.FileExtensions) {
+					incoming.add(path, info)
+				}
+				if store != nil && store.known(path, config.SourceDirs) {
+					known++
+					return
+				}
+				if !emit(path) {
+					scanner.abort()
+				}
+			}, func(path string, err error) {
+				fo.log(fmt.Sprintf("扫描 %s 时出错: %v", path, err))
+			})
+		}
+	}}
+	total, runErr := fo.runProcess(runConfig, nil, feed)
+
+	if store != nil {
+		fo.log(fmt.Sprintf("仅处理新增: 跳过了 %d 个上次成功整理时已有的文件", known))
+	}
+	if config.IncomingOnly {
+		if runErr == nil && total.Failed == 0 && total.Aborted == 0 {
+			fo.recordIncoming(incoming)
+		} else {
+			fo.log("仅处理新增: 本次整理有失败或中止的文件，未更新记录，下次仍按上次成功整理的记录判断新增文件")
+		}
+	}
+	return total, runErr
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 流式整理作为一次整理进行：只开始一次、只保存一份统计，没有后缀的文件也整理
+func TestProcessStreamingSingleRun(t *testing.T) {
+	fo := newTestOrganizer(t)
+	source := t.TempDir()
+	target := t.TempDir()
+	names := []string{"a.jpg", "b.txt", "sub/c.jpg", "README"}
+	for _, name := range names {
+		writeTestFile(t, filepath.Join(source, name), "content of "+name)
+	}
+	starts := 0
+	fo.ui = &countingNotifier{UINotifier: fo.ui, starts: &starts}
+
+	config := Config{
+		SourceDir:      source,
+		SourceDirs:     []string{source},
+		TargetDir:      target,
+		FileExtensions: []string{"", ".jpg", ".txt"},
+		OrganizeRule:   string(RuleByExtension),
+		ExtensionCase:  "lowercase",
+		ExcludedFiles:  map[string]bool{},
+	}
+	summary, err := fo.processStreaming(config, []string{source}, 1)
+	if err != nil || summary.Moved != len(names) || summary.Checked != len(names) {
+		t.Fatalf("流式整理: %+v, %v", summary, err)
+	}
+	if starts != 1 {
+		t.Fatalf("开始了 %d 次整理", starts)
+	}
+	for _, path := range []string{".jpg/a.jpg", ".jpg/c.jpg", ".txt/b.txt", "无后缀/README"} {
+		if _, err := os.Stat(filepath.Join(target, filepath.FromSlash(path))); err != nil {
+			t.Fatalf("目标中没有 %s: %v", path, err)
+		}
+	}
+	stats, _ := filepath.Glob(filepath.Join(runStatsDir(), "stats_*.json"))
+	if len(stats) != 1 {
+		t.Fatalf("统计文件 = %v", stats)
+	}
+	if fo.runActive() {
+		t.Fatal("流式整理结束后仍计为正在整理")
+	}
+}
+
+// countingNotifier 统计整理开始的次数
+type countingNotifier struct {
+	UINotifier
+	starts *int
+}
+
+func (n *countingNotifier) ProcessStarted(total int) {
+	*n.starts++
+	n.UINotifier.ProcessStarted(total)
+}
+
+// 需要事先知道全部文件的功能不能用于流式整理
+func TestValidateStreamingConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"普通整理", Config{}, false},
+		{"副本合并关闭", Config{CopyMerge: CopyMergeOff}, false},
+		{"多个目标卷", Config{Volumes: []TargetVolume{{Root: "a"}}}, true},
+		{"按容量分卷", Config{VolumeCapMB: 100}, true},
+		{"相册导出", Config{TakeoutMode: true}, true},
+		{"合并副本", Config{CopyMerge: CopyMergeDelete}, true},
+		{"整理快捷方式指向的文件", Config{ShortcutPolicy: ShortcutResolve}, true},
+		{"源快照", Config{SourceSnapshot: "snapshot.jsonl"}, true},
+	}
+	for _, tt := range tests {
+		if err := validateStreamingConfig(tt.config); (err != nil) != tt.wantErr {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+// 无界面运行时文件数超过 -streaming-threshold 改用流式整理
+func TestHeadlessStreaming(t *testing.T) {
+	newTestEnv(t)
+	source := t.TempDir()
+	target := t.TempDir()
+	for i := 0; i < 3; i++ {
+		writeTestFile(t, filepath.Join(source, fmt.Sprintf("%d.jpg", i)), "photo")
+	}
+	var stdout, stderr bytes.Buffer
+	args := []string{"-headless", "-source", source, "-target", target, "-ext", "jpg", "-streaming-threshold", "1"}
+	if code := runHeadless(args, &stdout, &stderr); code != exitSuccess {
+		t.Fatalf("退出码 = %d\n%s", code, stderr.String())
+	}
+	if got := snapshotTree(t, filepath.Join(target, ".jpg")); len(got) != 3 {
+		t.Fatalf("目标中的文件 = %v", got)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build darwin || linux
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// 将所有文件系统的缓存数据写入磁盘
+func syncFilesystems() error {
+	unix.Sync()
+	return nil
+}
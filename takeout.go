@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Google 相册导出的元数据文件名（去掉 .json）最多的字符数，超过时被截断
+const takeoutSidecarNameLimit = 46
+
+// Google 相册导出中同名文件的序号，例如 IMG_0001(1).jpg
+var takeoutCopyPattern = regexp.MustCompile(`^(.+)\((\d+)\)$`)
+
+// 编辑过的照片的后缀，例如 IMG_0001-edited.jpg，与原照片共用一个元数据文件
+var takeoutEditedSuffixes = []string{"-edited", "-bearbeitet", "-modifié", "-editado", "-編集済み"}
+
+// takeoutPlan Google 相册导出的整理计划：每个照片或视频的元数据文件随其移动
+type takeoutPlan struct {
+	sidecars map[string]string // 媒体文件 -> 元数据文件
+	media    map[string]string // 元数据文件 -> 媒体文件
+}
+
+// 媒体文件对应的元数据文件，没有时返回空字符串
+func (p *takeoutPlan) sidecarOf(path string) string {
+	if p == nil {
+		return ""
+	}
+	return p.sidecars[path]
+}
+
+// 元数据文件所属的媒体文件，不是随照片移动的元数据文件时返回空字符串
+func (p *takeoutPlan) mediaOf(path string) string {
+	if p == nil {
+		return ""
+	}
+	return p.media[path]
+}
+
+// 为每个照片和视频找到元数据文件，一个元数据文件只属于一个照片
+func newTakeoutPlan(media []string) *takeoutPlan {
+	plan := &takeoutPlan{sidecars: make(map[string]string), media: make(map[string]string)}
+	for _, path := range media {
+		if sidecar := findTakeoutSidecar(path, false); sidecar != "" && plan.media[sidecar] == "" {
+			plan.sidecars[path] = sidecar
+			plan.media[sidecar] = path
+		}
+	}
+	return plan
+}
+
+// Google 相册导出中的照片和视频：所选后缀的非元数据文件。排除、固定或不是新增的照片也认领其元数据文件，
+// 元数据文件留在照片旁边，不单独整理
+func (fo *FileOrganizer) takeoutCandidates(config Config, files []string) []string {
+	var media []string
+	for _, path := range files {
+		if !isJSONFile(path) && fo.isTargetFile(fileExtension(path), config.FileExtensions) {
+			media = append(media, path)
+		}
+	}
+	return media
+}
+
+// Google 相册导出中要整理的照片和视频：未排除和固定的照片和视频
+func (fo *FileOrganizer) takeoutMedia(config Config, files []string) []string {
+	var media []string
+	for _, path := range fo.takeoutCandidates(config, files) {
+		if !config.ExcludedFiles[path] && !config.Pins.matches(path, nil) {
+			media = append(media, path)
+		}
+	}
+	return media
+}
+
+// 截断过长的元数据文件名，与 Google 相册导出的规则相同
+func truncateTakeoutName(name string) string {
+	runes := []rune(name)
+	if len(runes) > takeoutSidecarNameLimit {
+		return string(runes[:takeoutSidecarNameLimit])
+	}
+	return name
+}
+
+// 元数据文件可能的名称：photo.jpg.json、photo.jpg.supplemental-metadata.json（较新的导出），
+// 名称过长时被截断；同名照片的序号写在 .json 之前，例如 photo.jpg(1).json
+func takeoutSidecarCandidates(name string) []string {
+	stem, ext := splitExtension(name)
+	suffix := ""
+	if match := takeoutCopyPattern.FindStringSubmatch(stem); match != nil {
+		stem, suffix = match[1], "("+match[2]+")"
+	}
+	original := stem + ext
+	var candidates []string
+	for _, base := range []string{original + ".supplemental-metadata", original, stem} {
+		candidates = append(candidates, truncateTakeoutName(base)+suffix+".json")
+	}
+	if suffix != "" {
+		// 有的导出把序号留在照片名称中
+		candidates = append(candidates, truncateTakeoutName(name)+".json")
+	}
+	return candidates
+}
+
+// 查找媒体文件的元数据文件。edited为true时编辑过的照片使用原照片的元数据文件
+func findTakeoutSidecar(path string, edited bool) string {
+	dir, name := filepath.Split(path)
+	names := []string{name}
+	if edited {
+		stem, ext := splitExtension(name)
+		for _, suffix := range takeoutEditedSuffixes {
+			if original, ok := strings.CutSuffix(stem, suffix); ok && original != "" {
+				names = append(names, original+ext)
+			}
+		}
+	}
+	for _, n := range names {
+		for _, candidate := range takeoutSidecarCandidates(n) {
+			sidecar := filepath.Join(dir, candidate)
+			if sidecar == path {
+				continue
+			}
+			if info, err := os.Stat(sidecar); err == nil && info.Mode().IsRegular() {
+				return sidecar
+			}
+		}
+	}
+	return ""
+}
+
+// 是否是 .json 文件
+func isJSONFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// Google 相册导出时优先使用元数据文件中的拍摄时间，导出的文件修改时间是导出的时间。
+// 导出的邮件同样如此，来源顺序中没有邮件头时放在文件时间之前，只对 .eml/.msg 文件起作用
+func effectiveDateSources(config Config) []DateSource {
+	sources := withEmailDateSource(config.DateSources)
+	if !config.TakeoutMode {
+		return sources
+	}
+	for _, source := range sources {
+		if source == DateSourceSidecar {
+			return sources
+		}
+	}
+	return append([]DateSource{DateSourceSidecar}, sources...)
+}
+
+// 整理 Google 相册导出前的准备：先合并本次要整理的照片中内容相同的「(1)」副本，再为扫描到的每个照片和视频
+// 找到元数据文件。scanned 是扫描到的全部文件，files 是其中本次要整理的文件（仅处理新增时已去掉原有的文件）。
+// 找到的元数据文件不再单独整理，随照片移动，照片不整理时留在照片旁边；内容不同的同名照片都保留。
+// 返回更新后的待整理文件列表
+func (fo *FileOrganizer) planTakeout(config Config, scanned, files []string) ([]string, *takeoutPlan) {
+	media := fo.takeoutMedia(config, files)
+
+	// 「(1)」副本：内容相同时只保留一份，元数据文件一起处理
+	changed := make(map[string]string)
+	if groups := findCopyGroups(media, []*regexp.Regexp{takeoutCopyPattern}); len(groups) > 0 {
+		identical, different := summarizeCopyGroups(groups)
+		fo.log(fmt.Sprintf("Google 相册导出: 发现 %d 个内容相同的「(n)」副本，%d 个内容不同的同名照片（都保留）", identical, different))
+		changed = fo.mergeTakeoutCopies(groups, config)
+	}
+
+	var kept []string
+	for _, path := range fo.takeoutCandidates(config, scanned) {
+		if newPath, ok := changed[path]; ok {
+			if newPath == "" {
+				continue
+			}
+			path = newPath
+		}
+		kept = append(kept, path)
+	}
+	plan := newTakeoutPlan(kept)
+
+	remaining := make([]string, 0, len(files))
+	for _, path := range files {
+		if newPath, ok := changed[path]; ok {
+			if newPath == "" {
+				continue
+			}
+			path = newPath
+		}
+		if plan.media[path] == "" {
+			remaining = append(remaining, path)
+		}
+	}
+	fo.log(fmt.Sprintf("Google 相册导出: %d 个照片和视频找到了元数据文件，元数据文件随其移动", len(plan.sidecars)))
+	return remaining, plan
+}
+
+// 处理内容相同的「(n)」副本：副本和它的元数据文件一起隔离或删除（按副本合并的设置，默认隔离）。
+// 保留的照片没有元数据文件时改用副本的元数据文件。返回路径变化，被处理的文件对应空字符串
+func (fo *FileOrganizer) mergeTakeoutCopies(groups []copyGroup, config Config) map[string]string {
+	changed := make(map[string]string)
+	if err := fo.checkWritable(); err != nil {
+		fo.log("Google 相册导出: 处理副本: " + err.Error())
+		return changed
+	}
+	mode := CopyMergeQuarantine
+	if config.CopyMerge == CopyMergeDelete {
+		mode = CopyMergeDelete
+	}
+	quarantineDir := filepath.Join(config.TargetDir, DuplicatesFolderName)
+	for _, group := range groups {
+		for _, set := range group.Identical {
+			keeper := set[0]
+			if isFromReadOnlySource(keeper, config) {
+				continue
+			}
+			keeperSidecar := findTakeoutSidecar(keeper, false)
+			for _, duplicate := range set[1:] {
+				if isFromReadOnlySource(duplicate, config) {
+					continue
+				}
+				// 先处理副本，副本没能删除或隔离时元数据文件留给副本
+				if err := fo.discardTakeoutCopy(duplicate, mode, quarantineDir, config.PreserveXattrs); err != nil {
+					fo.log(fmt.Sprintf("处理副本失败 %s: %v", duplicate, err))
+					continue
+				}
+				changed[duplicate] = ""
+				if sidecar := findTakeoutSidecar(duplicate, false); sidecar != "" {
+					// 保留的照片没有元数据文件时改用副本的元数据文件，已有同名文件时不覆盖
+					adopted := filepath.Join(filepath.Dir(keeper), filepath.Base(keeper)+".json")
+					if _, err := os.Lstat(adopted); keeperSidecar == "" && os.IsNotExist(err) {
+						if err := renameFile(sidecar, adopted); err != nil {
+							fo.log(fmt.Sprintf("Google 相册导出: 移动元数据文件失败 %s: %v", sidecar, err))
+						} else {
+							changed[sidecar] = ""
+							keeperSidecar, sidecar = adopted, ""
+						}
+					}
+					if sidecar != "" {
+						if err := fo.discardTakeoutCopy(sidecar, mode, quarantineDir, config.PreserveXattrs); err != nil {
+							fo.log(fmt.Sprintf("处理副本的元数据文件失败 %s: %v", sidecar, err))
+						} else {
+							changed[sidecar] = ""
+						}
+					}
+				}
+				if mode == CopyMergeDelete {
+					fo.log(fmt.Sprintf("已删除副本: %s（与 %s 内容相同）", duplicate, keeper))
+				} else {
+					fo.log(fmt.Sprintf("已隔离副本: %s（与 %s 内容相同）", duplicate, keeper))
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// 删除或隔离一个副本
+func (fo *FileOrganizer) discardTakeoutCopy(path, mode, quarantineDir string, xattrs bool) error {
+	if mode == CopyMergeDelete {
+		return os.Remove(path)
+	}
+	_, err := fo.moveFile(path, quarantineDir, xattrs)
+	return err
+}
+
+// 照片因目标中已有相同的照片而跳过时，把元数据文件复制到已有的照片旁边。已有的照片已经有元数据文件时不复制，
+// 返回false
+func (fo *FileOrganizer) adoptTakeoutSidecar(sidecar, existing string, xattrs bool) (string, bool, error) {
+	if findTakeoutSidecar(existing, false) != "" {
+		return "", false, nil
+	}
+	path, err := fo.placeTakeoutSidecar(sidecar, existing, true, xattrs)
+	if err != nil {
+		return "", false, err
+	}
+	return path, true, nil
+}
+
+// 把元数据文件放到整理后的照片旁边，并改名为 照片名.json，照片重名改名后仍能对应。
+// 只读源文件夹中的元数据文件只复制
+func (fo *FileOrganizer) placeTakeoutSidecar(sidecar, mediaPath string, copyOnly, xattrs bool) (string, error) {
+	transfer := fo.moveFile
+	if copyOnly {
+		transfer = fo.copyFile
+	}
+	movedPath, err := transfer(sidecar, filepath.Dir(mediaPath), xattrs)
+	if err != nil {
+		return "", err
+	}
+	name := filepath.Base(mediaPath) + ".json"
+	if filepath.Base(movedPath) == name {
+		return movedPath, nil
+	}
+	if _, err := os.Lstat(filepath.Join(filepath.Dir(mediaPath), name)); err == nil {
+		// 目标中已有同名的元数据文件，保留移动后的名称
+		return movedPath, nil
+	}
+	// 改名失败时保留移动后的名称，读取日期时仍能按导出的命名规则找到
+	renamedPath := filepath.Join(filepath.Dir(mediaPath), name)
+	if err := renameFile(movedPath, renamedPath); err != nil {
+		return movedPath, nil
+	}
+	return renamedPath, nil
+}
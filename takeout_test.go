@@ -0,0 +1,90 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Google 相册导出的元数据文件随照片移动；照片不整理时元数据文件留在照片旁边，
+// 目标中已有相同的照片时复制到已有的照片旁边
+func TestTakeoutSidecars(t *testing.T) {
+	jpg := func(name string) string { return filepath.Join(".jpg", name) }
+	tests := []struct {
+		name       string
+		source     map[string]string
+		target     map[string]string // 整理前目标中已有的文件
+		setup      func(config *Config, source string)
+		wantSource map[string]string
+		wantTarget map[string]string
+	}{
+		{"随照片移动",
+			map[string]string{"a.jpg": "photo", "a.jpg.json": "meta"}, nil, nil,
+			map[string]string{},
+			map[string]string{jpg("a.jpg"): "photo", jpg("a.jpg.json"): "meta"}},
+		{"排除的照片，元数据文件不单独整理",
+			map[string]string{"a.jpg": "photo", "a.jpg.json": "meta"}, nil,
+			func(config *Config, source string) { config.ExcludedFiles[filepath.Join(source, "a.jpg")] = true },
+			map[string]string{"a.jpg": "photo", "a.jpg.json": "meta"},
+			map[string]string{}},
+		{"合并模式下目标中已有相同的照片",
+			map[string]string{"a.jpg": "photo", "a.jpg.json": "meta"},
+			map[string]string{jpg("a.jpg"): "photo"},
+			func(config *Config, source string) { config.ConflictPolicy = ConflictMerge },
+			map[string]string{"a.jpg": "photo", "a.jpg.json": "meta"},
+			map[string]string{jpg("a.jpg"): "photo", jpg("a.jpg.json"): "meta"}},
+		{"已有的相同照片已有元数据文件",
+			map[string]string{"a.jpg": "photo", "a.jpg.json": "meta"},
+			map[string]string{jpg("a.jpg"): "photo", jpg("a.jpg.json"): "old"},
+			func(config *Config, source string) { config.ConflictPolicy = ConflictMerge },
+			map[string]string{"a.jpg": "photo", "a.jpg.json": "meta"},
+			map[string]string{jpg("a.jpg"): "photo", jpg("a.jpg.json"): "old"}},
+		{"副本合并不改名带元数据文件的照片",
+			map[string]string{"b - Copy.jpg": "same", "b - Copy.jpg.json": "meta", "b - 副本.jpg": "same"}, nil,
+			func(config *Config, source string) { config.CopyMerge = CopyMergeQuarantine },
+			map[string]string{},
+			map[string]string{jpg("b - Copy.jpg"): "same", jpg("b - Copy.jpg.json"): "meta", jpg("b - 副本.jpg"): "same"}},
+		{"内容相同的副本，保留的照片改用副本的元数据文件",
+			map[string]string{"c.jpg": "same", "c(1).jpg": "same", "c.jpg(1).json": "meta"}, nil, nil,
+			map[string]string{},
+			map[string]string{jpg("c.jpg"): "same", jpg("c.jpg.json"): "meta", filepath.Join(DuplicatesFolderName, "c(1).jpg"): "same"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			target := t.TempDir()
+			var files []string
+			for name, content := range tt.source {
+				files = append(files, writeTestFile(t, filepath.Join(source, name), content))
+			}
+			for name, content := range tt.target {
+				writeTestFile(t, filepath.Join(target, name), content)
+			}
+			config := Config{
+				SourceDir:          source,
+				SourceDirs:         []string{source},
+				TargetDir:          target,
+				FileExtensions:     []string{".jpg", ".json"},
+				OrganizeRule:       string(RuleByExtension),
+				ExtensionCase:      "lowercase",
+				ConflictPolicy:     ConflictRename,
+				TakeoutMode:        true,
+				CopySuffixPatterns: defaultCopySuffixPatterns,
+				ExcludedFiles:      map[string]bool{},
+			}
+			if tt.setup != nil {
+				tt.setup(&config, source)
+			}
+			if summary, err := fo.processFiles(config, files); err != nil || summary.Failed != 0 {
+				t.Fatalf("整理: %+v, %v", summary, err)
+			}
+			if got := snapshotTree(t, source); !reflect.DeepEqual(got, tt.wantSource) {
+				t.Errorf("源文件夹 = %v, 期望 %v", got, tt.wantSource)
+			}
+			if got := snapshotTree(t, target); !reflect.DeepEqual(got, tt.wantTarget) {
+				t.Errorf("目标文件夹 = %v, 期望 %v", got, tt.wantTarget)
+			}
+		})
+	}
+}
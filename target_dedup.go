@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// targetHashEntry 目标文件夹中一个文件的索引记录
+type targetHashEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`          // 修改时间（纳秒）
+	Hash    string `json:"hash,omitempty"` // 内容哈希，只有出现同样大小的源文件时才计算
+}
+
+// targetHashIndex 目标文件夹的 大小→哈希 索引，用于发现内容完全相同的文件
+type targetHashIndex struct {
+	mu      sync.Mutex
+	root    string
+	entries map[string]*targetHashEntry
+	bySize  map[int64][]string
+	packed  map[int64][]*targetPackedMember // 压缩包中打包的小文件，按大小分组
+	partial bool                            // 本进程正在后台为该目标建立索引：尚未计算哈希的文件不临时计算，不做去重
+}
+
+// targetPackedMember 目标中压缩包里打包的一个小文件。source 不为空时是本次整理中等待打包的文件
+type targetPackedMember struct {
+	archive string
+	name    string
+	source  string
+	hash    string
+}
+
+// 显示用的路径，与整理目录中的写法相同
+func (m *targetPackedMember) displayPath() string {
+	return m.archive + " › " + m.name
+}
+
+// 索引缓存文件的内容
+type targetHashIndexFile struct {
+	Root    string                      `json:"root"`
+	Entries map[string]*targetHashEntry `json:"entries"`
+	Build   *targetIndexBuild           `json:"build,omitempty"` // 后台建立索引的进度
+}
+
+// 整理和后台建立索引都会写入索引文件，读写时加锁
+var targetIndexFileMu sync.Mutex
+
+// 读取目标文件夹的索引文件，没有或无法解析时返回空的索引
+func readTargetIndexFile(root string) (*targetHashIndexFile, error) {
+	targetIndexFileMu.Lock()
+	defer targetIndexFileMu.Unlock()
+	return readTargetIndexFileLocked(root)
+}
+
+// 读取索引文件并重放日志中追加的记录，调用时需持有 targetIndexFileMu
+func readTargetIndexFileLocked(root string) (*targetHashIndexFile, error) {
+	file := &targetHashIndexFile{Root: root, Entries: make(map[string]*targetHashEntry)}
+	data, err := os.ReadFile(targetIndexPath(root))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return file, fmt.Errorf("读取目标文件索引失败: %w", err)
+	}
+	var cached targetHashIndexFile
+	if err == nil && json.Unmarshal(data, &cached) == nil && cached.Root == root {
+		if cached.Entries != nil {
+			file.Entries = cached.Entries
+		}
+		file.Build = cached.Build
+	}
+	if err := replayTargetIndexJournal(root, file.Entries); err != nil {
+		return file, err
+	}
+	return file, nil
+}
+
+// targetIndexJournalRecord 索引日志中的一行：建立索引时新计算的一个哈希
+type targetIndexJournalRecord struct {
+	Path string `json:"path"`
+	targetHashEntry
+}
+
+// 索引日志的路径。建立索引的进度追加到日志中，不必每次重写整个索引文件；
+// 下次完整保存索引时合并进索引文件并删除日志
+func targetIndexJournalPath(root string) string {
+	return strings.TrimSuffix(targetIndexPath(root), ".json") + ".journal"
+}
+
+// 把新计算的哈希追加到索引日志，一次写入一批
+func appendTargetIndexJournal(root string, entries map[string]*targetHashEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for path, entry := range entries {
+		if err := encoder.Encode(targetIndexJournalRecord{Path: path, targetHashEntry: *entry}); err != nil {
+			return fmt.Errorf("序列化目标文件索引失败: %w", err)
+		}
+	}
+	targetIndexFileMu.Lock()
+	defer targetIndexFileMu.Unlock()
+	if err := os.MkdirAll(appDataDir(), 0755); err != nil {
+		return fmt.Errorf("保存目标文件索引失败: %w", err)
+	}
+	journal, err := os.OpenFile(targetIndexJournalPath(root), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("保存目标文件索引失败: %w", err)
+	}
+	if _, err := journal.Write(buf.Bytes()); err != nil {
+		journal.Close()
+		return fmt.Errorf("保存目标文件索引失败: %w", err)
+	}
+	if err := journal.Close(); err != nil {
+		return fmt.Errorf("保存目标文件索引失败: %w", err)
+	}
+	return nil
+}
+
+// 把索引日志中的记录按顺序应用到entries。中途退出时最后一行可能不完整，无法解析的行跳过
+func replayTargetIndexJournal(root string, entries map[string]*targetHashEntry) error {
+	journal, err := os.Open(targetIndexJournalPath(root))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取目标文件索引失败: %w", err)
+	}
+	defer journal.Close()
+	scanner := bufio.NewScanner(journal)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var record targetIndexJournalRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil || record.Path == "" {
+			continue
+		}
+		entry := record.targetHashEntry
+		entries[record.Path] = &entry
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取目标文件索引失败: %w", err)
+	}
+	return nil
+}
+
+// 读取索引文件（包括日志），修改后写回并删除已合并的日志。先写临时文件再改名，避免中途退出留下损坏的索引
+func updateTargetIndexFile(root string, update func(file *targetHashIndexFile)) error {
+	targetIndexFileMu.Lock()
+	defer targetIndexFileMu.Unlock()
+	file, err := readTargetIndexFileLocked(root)
+	if err != nil {
+		return err
+	}
+	update(file)
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("序列化目标文件索引失败: %w", err)
+	}
+	if err := os.MkdirAll(appDataDir(), 0755); err != nil {
+		return fmt.Errorf("保存目标文件索引失败: %w", err)
+	}
+	path := targetIndexPath(root)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("保存目标文件索引失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存目标文件索引失败: %w", err)
+	}
+	// 日志已合并进索引文件。删除失败时下次读取会重放相同的记录，结果不变
+	os.Remove(targetIndexJournalPath(root))
+	return nil
+}
+
+// 获取目标文件夹对应的索引缓存文件路径
+func targetIndexPath(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(appDataDir(), "target_index_"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// 加载目标文件夹的索引缓存并与当前文件同步：大小或修改时间变化的文件需要重新计算哈希，
+// 已不存在的文件从索引中移除。skip中的文件（本次待整理的源文件）不加入索引。
+// building 表示本进程正在后台为该目标建立索引，此时尚未计算的文件留给后台计算，不临时计算；
+// 建立停止或中断后索引记录仍未完成，但照常在需要时临时计算哈希
+func loadTargetHashIndex(root string, skip map[string]bool, building bool) (*targetHashIndex, error) {
+	idx := &targetHashIndex{
+		root:    root,
+		entries: make(map[string]*targetHashEntry),
+		bySize:  make(map[int64][]string),
+		packed:  make(map[int64][]*targetPackedMember),
+	}
+
+	file, err := readTargetIndexFile(root)
+	if err != nil {
+		return nil, err
+	}
+	cached := file.Entries
+	idx.partial = building && file.Build != nil && !file.Build.Complete
+
+	err = walkTargetFiles(root, skip, func(path string, info os.FileInfo) error {
+		entry := &targetHashEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+		if old, ok := cached[path]; ok && old.Size == entry.Size && old.ModTime == entry.ModTime {
+			entry.Hash = old.Hash
+		}
+		idx.entries[path] = entry
+		idx.bySize[entry.Size] = append(idx.bySize[entry.Size], path)
+		// 打包的小文件也参与去重，压缩包的哈希不缓存，需要时从压缩包中读出计算
+		if strings.EqualFold(filepath.Ext(path), ".zip") && isPackArchive(path) {
+			members, err := readPackMembers(path)
+			if err != nil {
+				return nil
+			}
+			for _, member := range members {
+				idx.packed[member.size] = append(idx.packed[member.size], &targetPackedMember{archive: path, name: member.name})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("建立目标文件索引失败: %w", err)
+	}
+	return idx, nil
+}
+
+// 索引中的文件数量
+func (idx *targetHashIndex) len() int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return len(idx.entries)
+}
+
+// 查找与源文件内容完全相同的目标文件，返回已有副本的路径（没有时为空）和源文件的哈希值
+func (idx *targetHashIndex) findDuplicate(sourcePath string, size int64) (existing, sourceHash string, err error) {
+	idx.mu.Lock()
+	candidates := append([]string(nil), idx.bySize[size]...)
+	packed := append([]*targetPackedMember(nil), idx.packed[size]...)
+	idx.mu.Unlock()
+	if len(candidates) == 0 && len(packed) == 0 {
+		return "", "", nil
+	}
+
+	sourceHash, err = hashFile(sourcePath)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, candidate := range candidates {
+		if candidate == sourcePath {
+			continue
+		}
+		idx.mu.Lock()
+		entry := idx.entries[candidate]
+		hash := ""
+		if entry != nil {
+			hash = entry.Hash
+		}
+		idx.mu.Unlock()
+		if entry == nil {
+			continue
+		}
+
+		// 计算哈希后被修改过的文件，哈希已过期，这次重新计算
+		stale := false
+		if hash != "" {
+			info, statErr := os.Stat(candidate)
+			if statErr != nil || info.Size() != size {
+				continue
+			}
+			if info.ModTime().UnixNano() != entry.ModTime {
+				hash, stale = "", true
+				idx.mu.Lock()
+				entry.ModTime, entry.Hash = info.ModTime().UnixNano(), ""
+				idx.mu.Unlock()
+			}
+		}
+		if hash == "" {
+			// 后台建立索引期间不临时计算尚未计算的文件，整理不必等待
+			if idx.partial && !stale {
+				continue
+			}
+			// 目标文件的哈希在第一次需要时才计算，之后缓存在索引中
+			hash, err = hashFile(candidate)
+			if err != nil {
+				continue
+			}
+			idx.mu.Lock()
+			entry.Hash = hash
+			idx.mu.Unlock()
+		}
+		if hash == sourceHash {
+			return candidate, sourceHash, nil
+		}
+	}
+	for _, member := range packed {
+		if member.source == sourcePath {
+			continue
+		}
+		idx.mu.Lock()
+		hash := member.hash
+		idx.mu.Unlock()
+		if hash == "" {
+			if member.source != "" {
+				hash, err = hashFile(member.source)
+			} else {
+				hash, err = hashZipMember(member.archive, member.name)
+			}
+			if err != nil {
+				continue
+			}
+			idx.mu.Lock()
+			member.hash = hash
+			idx.mu.Unlock()
+		}
+		if hash == sourceHash {
+			return member.displayPath(), sourceHash, nil
+		}
+	}
+	return "", sourceHash, nil
+}
+
+// 将等待打包的小文件加入索引，本次整理中后面内容相同的文件视为重复。hash可以为空
+func (idx *targetHashIndex) addPacked(archivePath, sourcePath string, size int64, hash string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	member := &targetPackedMember{archive: archivePath, name: filepath.Base(sourcePath), source: sourcePath, hash: hash}
+	idx.packed[size] = append(idx.packed[size], member)
+}
+
+// 将新移入目标文件夹的文件加入索引，hash可以为空
+func (idx *targetHashIndex) add(path, hash string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if _, exists := idx.entries[path]; !exists {
+		idx.bySize[info.Size()] = append(idx.bySize[info.Size()], path)
+	}
+	idx.entries[path] = &targetHashEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Hash: hash}
+}
+
+// 索引是否还在后台建立中
+func (idx *targetHashIndex) incomplete() bool {
+	return idx.partial
+}
+
+// 保存索引，供下次整理时增量更新。整理期间后台建立索引保存的哈希合并进来，不会被覆盖
+func (idx *targetHashIndex) save() error {
+	return updateTargetIndexFile(idx.root, func(file *targetHashIndexFile) {
+		idx.mu.Lock()
+		defer idx.mu.Unlock()
+		for path, entry := range idx.entries {
+			if old, ok := file.Entries[path]; ok && entry.Hash == "" && old.Hash != "" && old.Size == entry.Size && old.ModTime == entry.ModTime {
+				entry.Hash = old.Hash
+			}
+		}
+		file.Entries = idx.entries
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTargetIndexJournalReplay(t *testing.T) {
+	newTestEnv(t)
+	root := t.TempDir()
+
+	if err := updateTargetIndexFile(root, func(file *targetHashIndexFile) {
+		file.Entries["/a"] = &targetHashEntry{Size: 1, ModTime: 1}
+		file.Build = &targetIndexBuild{Started: time.Now()}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendTargetIndexJournal(root, map[string]*targetHashEntry{"/a": {Size: 1, ModTime: 1, Hash: "aa"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendTargetIndexJournal(root, map[string]*targetHashEntry{"/b": {Size: 2, ModTime: 2, Hash: "bb"}}); err != nil {
+		t.Fatal(err)
+	}
+	// 中途退出留下的不完整的最后一行
+	journal, err := os.OpenFile(targetIndexJournalPath(root), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.WriteString(`{"path":"/c","size":3,"mt`)
+	journal.Close()
+
+	file, err := readTargetIndexFile(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Entries["/a"].Hash != "aa" || file.Entries["/b"].Hash != "bb" {
+		t.Fatalf("日志没有重放: %+v %+v", file.Entries["/a"], file.Entries["/b"])
+	}
+	if _, ok := file.Entries["/c"]; ok {
+		t.Fatal("不完整的记录不应加入索引")
+	}
+	if file.Build == nil || file.Build.Complete {
+		t.Fatalf("建立进度丢失: %+v", file.Build)
+	}
+
+	// 完整保存时合并日志并删除
+	if err := updateTargetIndexFile(root, func(file *targetHashIndexFile) {}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(targetIndexJournalPath(root)); !os.IsNotExist(err) {
+		t.Fatalf("合并后日志应被删除: %v", err)
+	}
+	file, err = readTargetIndexFile(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Entries["/b"] == nil || file.Entries["/b"].Hash != "bb" {
+		t.Fatal("合并后的索引缺少日志中的记录")
+	}
+}
+
+func TestStoppedIndexBuildKeepsDedup(t *testing.T) {
+	newTestEnv(t)
+	root := t.TempDir()
+	existing := writeTestFile(t, filepath.Join(root, "photos", "a.jpg"), "same content")
+	source := writeTestFile(t, filepath.Join(t.TempDir(), "b.jpg"), "same content")
+
+	// 停止的建立：索引记录未完成，已有文件还没有哈希
+	if err := updateTargetIndexFile(root, func(file *targetHashIndexFile) {
+		file.Build = &targetIndexBuild{Started: time.Now()}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		building bool
+		want     string
+	}{
+		{"停止后临时计算哈希", false, existing},
+		{"后台建立中不临时计算", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, err := loadTargetHashIndex(root, nil, tt.building)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if idx.incomplete() != tt.building {
+				t.Fatalf("incomplete() = %v", idx.incomplete())
+			}
+			got, _, err := idx.findDuplicate(source, int64(len("same content")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("findDuplicate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
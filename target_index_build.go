@@ -0,0 +1,531 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 建立目标文件索引时默认的读取速度上限（MB/秒），0表示不限制
+const defaultTargetIndexRateMB = 50
+
+// 每计算这么多个文件的哈希保存一次进度
+const targetIndexCheckpointFiles = 500
+
+// 距上次保存超过这个时间也保存一次进度，大文件较多时中途退出损失不多
+const targetIndexCheckpointInterval = 30 * time.Second
+
+// 停止建立索引
+var errIndexBuildStopped = errors.New("已停止建立索引")
+
+// targetIndexBuild 索引文件中记录的建立进度。没有该记录的索引（较小的目标）在需要时临时计算哈希
+type targetIndexBuild struct {
+	Started   time.Time `json:"started"`
+	Complete  bool      `json:"complete"`
+	Completed time.Time `json:"completed,omitempty"`
+}
+
+// targetIndexBuilder 在后台为目标文件夹中的所有文件计算哈希，可以暂停，进度定期写入索引文件，
+// 退出后下次启动时继续。索引建立完成前整理照常进行，只是与尚未计算哈希的文件不做去重
+type targetIndexBuilder struct {
+	root   string
+	rateMB atomic.Int64 // 读取速度上限（MB/秒），0不限制
+	paused atomic.Bool
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu          sync.Mutex
+	walking     bool // 正在统计目标文件夹中的文件
+	totalFiles  int
+	hashedFiles int
+	totalBytes  int64
+	hashedBytes int64
+	err         error
+
+	// 本轮（开始或继续后）读取的字节数，用于限速和估算剩余时间
+	sessionStart time.Time
+	sessionBytes int64
+}
+
+// targetIndexItem 等待计算哈希的文件
+type targetIndexItem struct {
+	path    string
+	size    int64
+	modTime int64
+}
+
+// 开始为目标文件夹建立索引，已在建立时返回正在进行的任务。记住目标文件夹，下次启动时自动继续
+func (fo *FileOrganizer) startTargetIndexBuild(root string, paused bool) *targetIndexBuilder {
+	fo.indexBuildMu.Lock()
+	defer fo.indexBuildMu.Unlock()
+	if b := fo.indexBuilder; b != nil {
+		select {
+		case <-b.done:
+		default:
+			return b
+		}
+	}
+	b := &targetIndexBuilder{root: root, stop: make(chan struct{}), done: make(chan struct{})}
+	b.rateMB.Store(int64(fo.TargetIndexRateMB))
+	b.paused.Store(paused)
+	fo.indexBuilder = b
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetString("target_index_build_root", root)
+	prefs.SetBool("target_index_build_paused", paused)
+	go fo.runTargetIndexBuild(b)
+	return b
+}
+
+// 正在进行的建立索引任务，没有时返回nil
+func (fo *FileOrganizer) runningIndexBuilder() *targetIndexBuilder {
+	fo.indexBuildMu.Lock()
+	defer fo.indexBuildMu.Unlock()
+	if b := fo.indexBuilder; b != nil {
+		select {
+		case <-b.done:
+		default:
+			return b
+		}
+	}
+	return nil
+}
+
+// 启动时继续上次未完成的建立索引
+func (fo *FileOrganizer) resumeTargetIndexBuild() {
+	prefs := fyne.CurrentApp().Preferences()
+	root := prefs.StringWithFallback("target_index_build_root", "")
+	if root == "" {
+		return
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		fo.log(fmt.Sprintf("[目标索引] 目标文件夹 %s 不可用，暂不继续建立索引", root))
+		return
+	}
+	paused := prefs.BoolWithFallback("target_index_build_paused", false)
+	fo.startTargetIndexBuild(root, paused)
+	if paused {
+		fo.log(fmt.Sprintf("[目标索引] %s 的索引尚未建立完成，已暂停，可在「更多设置」中继续", root))
+	} else {
+		fo.log(fmt.Sprintf("[目标索引] 继续建立 %s 的索引", root))
+	}
+}
+
+// 暂停或继续，暂停状态在下次启动时保持
+func (fo *FileOrganizer) setIndexBuildPaused(b *targetIndexBuilder, paused bool) {
+	b.paused.Store(paused)
+	if !paused {
+		// 继续后重新计算速度，暂停的时间不计入限速
+		b.mu.Lock()
+		b.sessionStart, b.sessionBytes = time.Now(), 0
+		b.mu.Unlock()
+	}
+	fyne.CurrentApp().Preferences().SetBool("target_index_build_paused", paused)
+}
+
+// 停止建立索引，已计算的哈希保留在索引中，下次开始时从未计算的文件继续
+func (fo *FileOrganizer) stopTargetIndexBuild(b *targetIndexBuilder) {
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
+	fyne.CurrentApp().Preferences().SetString("target_index_build_root", "")
+}
+
+// 建立索引：统计目标文件夹中的文件，跳过已有哈希且未修改的文件，其余的按速度上限依次计算哈希
+func (fo *FileOrganizer) runTargetIndexBuild(b *targetIndexBuilder) {
+	defer close(b.done)
+	fo.log(fmt.Sprintf("[目标索引] 开始建立 %s 的索引", b.root))
+
+	b.mu.Lock()
+	b.walking = true
+	b.mu.Unlock()
+	var items []targetIndexItem
+	err := walkTargetFiles(b.root, nil, func(path string, info os.FileInfo) error {
+		select {
+		case <-b.stop:
+			return errIndexBuildStopped
+		default:
+		}
+		items = append(items, targetIndexItem{path: path, size: info.Size(), modTime: info.ModTime().UnixNano()})
+		return nil
+	})
+	if err != nil {
+		b.finish(fo, err)
+		return
+	}
+
+	// 已有哈希并且大小和修改时间都没有变化的文件不再计算
+	var todo []targetIndexItem
+	var totalBytes, hashedBytes int64
+	err = updateTargetIndexFile(b.root, func(file *targetHashIndexFile) {
+		for _, item := range items {
+			totalBytes += item.size
+			if old, ok := file.Entries[item.path]; ok && old.Hash != "" && old.Size == item.size && old.ModTime == item.modTime {
+				hashedBytes += item.size
+				continue
+			}
+			todo = append(todo, item)
+		}
+		if file.Build == nil || file.Build.Complete {
+			file.Build = &targetIndexBuild{Started: time.Now()}
+		}
+	})
+	if err != nil {
+		b.finish(fo, err)
+		return
+	}
+	b.mu.Lock()
+	b.walking = false
+	b.totalFiles, b.hashedFiles = len(items), len(items)-len(todo)
+	b.totalBytes, b.hashedBytes = totalBytes, hashedBytes
+	b.sessionStart, b.sessionBytes = time.Now(), 0
+	b.mu.Unlock()
+	fo.log(fmt.Sprintf("[目标索引] 共 %d 个文件（%s），其中 %d 个需要计算哈希", len(items), formatFileSize(totalBytes), len(todo)))
+
+	// 进度追加到索引日志，每次只写入新计算的一批，完成时再合并进索引文件
+	batch := make(map[string]*targetHashEntry)
+	lastCheckpoint := time.Now()
+	checkpoint := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := appendTargetIndexJournal(b.root, batch)
+		batch = make(map[string]*targetHashEntry)
+		lastCheckpoint = time.Now()
+		return err
+	}
+
+	for _, item := range todo {
+		hash, err := b.hashFile(item)
+		if errors.Is(err, errIndexBuildStopped) {
+			b.finish(fo, checkpoint())
+			return
+		}
+		if err == nil && hash != "" {
+			batch[item.path] = &targetHashEntry{Size: item.size, ModTime: item.modTime, Hash: hash}
+		}
+		// 无法读取或计算期间被修改的文件留到第一次用到时再计算
+		b.mu.Lock()
+		b.hashedFiles++
+		b.hashedBytes += item.size
+		b.mu.Unlock()
+		if len(batch) >= targetIndexCheckpointFiles || time.Since(lastCheckpoint) >= targetIndexCheckpointInterval {
+			if err := checkpoint(); err != nil {
+				b.finish(fo, err)
+				return
+			}
+		}
+	}
+	if err := checkpoint(); err != nil {
+		b.finish(fo, err)
+		return
+	}
+	err = updateTargetIndexFile(b.root, func(file *targetHashIndexFile) {
+		if file.Build == nil {
+			file.Build = &targetIndexBuild{Started: time.Now()}
+		}
+		file.Build.Complete = true
+		file.Build.Completed = time.Now()
+	})
+	b.finish(fo, err)
+}
+
+// 结束建立索引并记录结果。完成或出错时不再在启动时继续，停止时已由停止操作清除
+func (b *targetIndexBuilder) finish(fo *FileOrganizer, err error) {
+	b.mu.Lock()
+	b.walking = false
+	if err != nil && !errors.Is(err, errIndexBuildStopped) {
+		b.err = err
+	}
+	hashed, total := b.hashedFiles, b.totalFiles
+	b.mu.Unlock()
+	switch {
+	case errors.Is(err, errIndexBuildStopped) || b.stopped():
+		fo.log(fmt.Sprintf("[目标索引] 已停止，已计算 %d/%d 个文件的哈希，下次从未计算的文件继续", hashed, total))
+	case err != nil:
+		fo.log(fmt.Sprintf("[目标索引] 建立索引失败: %v", err))
+		fyne.CurrentApp().Preferences().SetString("target_index_build_root", "")
+	default:
+		fo.log(fmt.Sprintf("[目标索引] %s 的索引已建立完成，共 %d 个文件", b.root, total))
+		fyne.CurrentApp().Preferences().SetString("target_index_build_root", "")
+	}
+}
+
+// 是否已要求停止
+func (b *targetIndexBuilder) stopped() bool {
+	select {
+	case <-b.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// 按速度上限计算一个文件的哈希。计算前后文件的大小或修改时间变化时放弃，返回空哈希
+func (b *targetIndexBuilder) hashFile(item targetIndexItem) (string, error) {
+	file, err := os.Open(item.path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, &throttledReader{r: file, b: b}); err != nil {
+		return "", err
+	}
+	info, err := os.Stat(item.path)
+	if err != nil || info.Size() != item.size || info.ModTime().UnixNano() != item.modTime {
+		return "", nil
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// 等待继续，暂停期间每秒检查一次是否停止
+func (b *targetIndexBuilder) waitWhilePaused() error {
+	for b.paused.Load() {
+		select {
+		case <-b.stop:
+			return errIndexBuildStopped
+		case <-time.After(time.Second):
+		}
+	}
+	if b.stopped() {
+		return errIndexBuildStopped
+	}
+	return nil
+}
+
+// 读取了n个字节后按速度上限等待
+func (b *targetIndexBuilder) throttle(n int) error {
+	b.mu.Lock()
+	b.sessionBytes += int64(n)
+	sessionBytes, sessionStart := b.sessionBytes, b.sessionStart
+	b.mu.Unlock()
+	rate := b.rateMB.Load()
+	if rate <= 0 {
+		return nil
+	}
+	expected := time.Duration(float64(sessionBytes) / float64(rate<<20) * float64(time.Second))
+	if wait := expected - time.Since(sessionStart); wait > 0 {
+		select {
+		case <-b.stop:
+			return errIndexBuildStopped
+		case <-time.After(wait):
+		}
+	}
+	return nil
+}
+
+// throttledReader 按建立索引的速度上限读取文件，暂停时等待，停止时返回错误
+type throttledReader struct {
+	r io.Reader
+	b *targetIndexBuilder
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if err := t.b.waitWhilePaused(); err != nil {
+		return 0, err
+	}
+	// 每次最多读取1MB，限速较低时也能及时暂停
+	if len(p) > 1<<20 {
+		p = p[:1<<20]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if throttleErr := t.b.throttle(n); throttleErr != nil {
+			return n, throttleErr
+		}
+	}
+	return n, err
+}
+
+// 建立索引的进度：已计算的比例（按字节）、说明文字，以及是否已结束
+func (b *targetIndexBuilder) progress() (float64, string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	finished := false
+	select {
+	case <-b.done:
+		finished = true
+	default:
+	}
+	switch {
+	case b.err != nil:
+		return 0, fmt.Sprintf("建立索引失败: %v", b.err), finished
+	case b.walking:
+		return 0, "正在统计目标文件夹中的文件...", finished
+	}
+	fraction := 1.0
+	if b.totalBytes > 0 {
+		fraction = float64(b.hashedBytes) / float64(b.totalBytes)
+	}
+	text := fmt.Sprintf("已计算 %d/%d 个文件，%s / %s", b.hashedFiles, b.totalFiles, formatFileSize(b.hashedBytes), formatFileSize(b.totalBytes))
+	switch {
+	case finished && b.stopped():
+		text += "，已停止"
+	case finished:
+		text += "，已完成"
+	case b.paused.Load():
+		text += "，已暂停"
+	default:
+		if elapsed := time.Since(b.sessionStart).Seconds(); elapsed > 1 && b.sessionBytes > 0 {
+			speed := float64(b.sessionBytes) / elapsed
+			remaining := time.Duration(float64(b.totalBytes-b.hashedBytes) / speed * float64(time.Second))
+			text += fmt.Sprintf("，%s/秒，预计还需 %s", formatFileSize(int64(speed)), remaining.Round(time.Minute))
+		}
+	}
+	return fraction, text, finished
+}
+
+// 显示目标文件索引的进度面板：开始、暂停、继续或停止建立索引
+func (fo *FileOrganizer) showTargetIndexDialog() {
+	root := fo.selectedTargetDir()
+	if b := fo.runningIndexBuilder(); b != nil {
+		root = b.root
+	}
+	if root == "" {
+		dialog.ShowInformation("提示", "请先选择目标文件夹", fo.Window)
+		return
+	}
+
+	intro := widget.NewLabel(fmt.Sprintf("为 %s 中已有的文件计算哈希，供目标去重使用。在后台按速度上限（%s）进行，"+
+		"可以暂停，退出后下次启动时继续；建立完成前整理照常进行，只是与尚未计算的文件不做去重", root, describeIndexRate(fo.TargetIndexRateMB)))
+	intro.Wrapping = fyne.TextWrapWord
+	bar := widget.NewProgressBar()
+	status := widget.NewLabel(describeTargetIndexFile(root))
+	status.Wrapping = fyne.TextWrapWord
+
+	var startBtn, pauseBtn, stopBtn *widget.Button
+	refresh := func() {
+		b := fo.runningIndexBuilder()
+		if b == nil || b.root != root {
+			startBtn.Enable()
+			pauseBtn.Disable()
+			stopBtn.Disable()
+			return
+		}
+		fraction, text, _ := b.progress()
+		bar.SetValue(fraction)
+		status.SetText(text)
+		startBtn.Disable()
+		pauseBtn.Enable()
+		stopBtn.Enable()
+		if b.paused.Load() {
+			pauseBtn.SetText("继续")
+		} else {
+			pauseBtn.SetText("暂停")
+		}
+	}
+	startBtn = widget.NewButton("开始建立索引", func() {
+		if b := fo.runningIndexBuilder(); b != nil && b.root != root {
+			dialog.ShowInformation("提示", fmt.Sprintf("正在为 %s 建立索引，请先停止", b.root), fo.Window)
+			return
+		}
+		fo.startTargetIndexBuild(root, false)
+		refresh()
+	})
+	startBtn.Importance = widget.HighImportance
+	pauseBtn = widget.NewButton("暂停", func() {
+		if b := fo.runningIndexBuilder(); b != nil {
+			fo.setIndexBuildPaused(b, !b.paused.Load())
+			refresh()
+		}
+	})
+	stopBtn = widget.NewButton("停止", func() {
+		if b := fo.runningIndexBuilder(); b != nil {
+			fo.stopTargetIndexBuild(b)
+		}
+	})
+
+	closeDone := make(chan struct{})
+	d := dialog.NewCustom("目标文件索引", "关闭", container.NewVBox(
+		intro,
+		bar,
+		status,
+		container.NewHBox(layout.NewSpacer(), stopBtn, pauseBtn, startBtn),
+	), fo.Window)
+	d.SetOnClosed(func() { close(closeDone) })
+	d.Resize(fyne.NewSize(520, 0))
+	refresh()
+	fo.showDialog(d, startBtn)
+
+	// 对话框打开期间每秒更新一次进度
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closeDone:
+				return
+			case <-ticker.C:
+				fo.safeUpdateUI(refresh)
+			}
+		}
+	}()
+}
+
+// 描述读取速度上限
+func describeIndexRate(rateMB int) string {
+	if rateMB <= 0 {
+		return "不限速"
+	}
+	return strconv.Itoa(rateMB) + " MB/秒"
+}
+
+// 描述索引文件中已保存的进度，用于还没有开始建立时的说明
+func describeTargetIndexFile(root string) string {
+	file, err := readTargetIndexFile(root)
+	if err != nil {
+		return err.Error()
+	}
+	hashed := 0
+	for _, entry := range file.Entries {
+		if entry.Hash != "" {
+			hashed++
+		}
+	}
+	switch {
+	case file.Build != nil && file.Build.Complete:
+		return fmt.Sprintf("索引已于 %s 建立完成，记录了 %d 个文件", file.Build.Completed.Format("2006-01-02 15:04"), len(file.Entries))
+	case file.Build != nil:
+		return fmt.Sprintf("索引尚未建立完成，已计算 %d 个文件的哈希", hashed)
+	case len(file.Entries) > 0:
+		return fmt.Sprintf("索引中有 %d 个文件，%d 个已计算哈希，其余在去重需要时临时计算", len(file.Entries), hashed)
+	}
+	return "尚未建立索引"
+}
+
+// 遍历目标文件夹中会加入索引的文件：跳过以 "." 开头的文件和文件夹，skip中的文件不加入
+func walkTargetFiles(root string, skip map[string]bool, visit func(path string, info os.FileInfo) error) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// 无法读取的子文件夹不影响其余文件的索引
+			return nil
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || skip[path] || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		return visit(path, info)
+	})
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+)
+
+// 整理期间放在目标文件夹中的锁文件，防止多台电脑同时整理到同一个归档（例如NAS上的文件夹）
+const targetLockName = ".fileorganizer.lock"
+
+// 整理期间每隔这么久刷新一次锁文件中的心跳时间
+const targetLockHeartbeat = 30 * time.Second
+
+// 心跳超过这么久没有刷新的锁视为已失效（持有者崩溃或断网）
+const targetLockStaleAfter = 5 * time.Minute
+
+// TargetLock 锁文件的内容
+type TargetLock struct {
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	RunID     string    `json:"run_id"`
+	StartedAt time.Time `json:"started_at"`
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+// 锁的心跳是否已超过失效时间
+func (l TargetLock) stale(now time.Time) bool {
+	return now.Sub(l.Heartbeat) > targetLockStaleAfter
+}
+
+// 描述锁的持有者，用于日志和对话框
+func (l TargetLock) describe() string {
+	host := l.Host
+	if host == "" {
+		host = "未知电脑"
+	}
+	return fmt.Sprintf("%s（进程 %d）自 %s 起正在整理，最近一次心跳 %s",
+		host, l.PID, l.StartedAt.Format("2006-01-02 15:04:05"), l.Heartbeat.Format("2006-01-02 15:04:05"))
+}
+
+// targetLockedError 目标文件夹已被其他整理锁定
+type targetLockedError struct {
+	path   string
+	holder TargetLock
+	stale  bool
+}
+
+func (e *targetLockedError) Error() string {
+	if e.stale {
+		return fmt.Sprintf("目标文件夹的锁已失效但仍存在: %s（%s）", e.holder.describe(), e.path)
+	}
+	return fmt.Sprintf("目标文件夹正在被整理: %s", e.holder.describe())
+}
+
+// 锁文件的路径
+func targetLockPath(targetDir string) string {
+	return filepath.Join(targetDir, targetLockName)
+}
+
+// 本机名称，获取失败时为空
+func lockHostName() string {
+	host, _ := os.Hostname()
+	return host
+}
+
+// 读取锁文件。内容无法解析（例如写到一半时断电）时以文件的修改时间作为心跳
+func readTargetLock(path string) (TargetLock, error) {
+	var lock TargetLock
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lock, err
+	}
+	if json.Unmarshal(data, &lock) != nil || lock.Heartbeat.IsZero() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return lock, err
+		}
+		lock = TargetLock{Heartbeat: info.ModTime(), StartedAt: info.ModTime()}
+	}
+	return lock, nil
+}
+
+// 把锁写到临时文件后替换，刷新心跳时不会留下写了一半的锁文件
+func writeTargetLock(path string, lock TargetLock) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// targetLockHandle 本次整理持有的锁，整理期间在后台刷新心跳
+type targetLockHandle struct {
+	path string
+	lock TargetLock
+	stop chan struct{}
+	done chan struct{}
+}
+
+// 获取目标文件夹的锁。锁文件用排他方式创建，多台电脑同时获取时只有一台成功。
+// 已有未失效的锁时返回 *targetLockedError；本机的失效锁（上次整理时程序崩溃）直接接管，
+// 其他电脑的失效锁需要用户确认后用 breakTargetLock 解除
+func acquireTargetLock(targetDir, runID string) (*targetLockHandle, error) {
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建目标文件夹失败: %w", err)
+	}
+	path := targetLockPath(targetDir)
+	now := time.Now()
+	lock := TargetLock{Host: lockHostName(), PID: os.Getpid(), RunID: runID, StartedAt: now, Heartbeat: now}
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = file.Write(data)
+			if syncErr := file.Sync(); err == nil {
+				err = syncErr
+			}
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("写入目标文件夹的锁失败: %w", err)
+			}
+			return &targetLockHandle{path: path, lock: lock}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("创建目标文件夹的锁失败: %w", err)
+		}
+
+		holder, err := readTargetLock(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// 持有者刚好释放了锁，重新创建
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取目标文件夹的锁失败: %w", err)
+		}
+		stale := holder.stale(time.Now())
+		if !stale || holder.Host == "" || holder.Host != lock.Host {
+			return nil, &targetLockedError{path: path, holder: holder, stale: stale}
+		}
+		// 本机上次整理留下的失效锁，解除后重新获取
+		if err := breakTargetLock(targetDir, holder); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("获取目标文件夹的锁失败: %s 被反复创建", path)
+}
+
+// 解除失效的锁。解除前确认锁文件仍是之前看到的那个，避免删掉刚被其他电脑刷新或重新获取的锁
+func breakTargetLock(targetDir string, expected TargetLock) error {
+	path := targetLockPath(targetDir)
+	current, err := readTargetLock(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取目标文件夹的锁失败: %w", err)
+	}
+	if current.RunID != expected.RunID || !current.Heartbeat.Equal(expected.Heartbeat) {
+		return &targetLockedError{path: path, holder: current, stale: current.stale(time.Now())}
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("解除目标文件夹的锁失败: %w", err)
+	}
+	return nil
+}
+
+// 开始修改目标文件夹：计入正在进行的整理并锁定目标文件夹，本机的其他操作和其他电脑的整理都要等待。
+// 整理和其他修改目标文件夹的操作（重新编号等）都通过这里获取锁，返回的函数释放锁
+func (fo *FileOrganizer) lockTarget(targetDir, runID string) (func(), error) {
+	fo.activeRuns.Add(1)
+	lock, err := acquireTargetLock(targetDir, runID)
+	if err != nil {
+		fo.activeRuns.Add(-1)
+		return nil, err
+	}
+	lock.startHeartbeat(fo.log)
+	fo.targetLocks.Store(lock.path, lock)
+	return func() {
+		fo.targetLocks.Delete(lock.path)
+		if err := lock.release(); err != nil {
+			fo.log(err.Error())
+		}
+		fo.activeRuns.Add(-1)
+	}, nil
+}
+
+// 无界面运行时按 -break-stale-lock 解除失效的锁，包括其他电脑留下的。锁仍在刷新时保持不变，
+// 由整理开始时获取锁来报告
+func (fo *FileOrganizer) breakStaleTargetLock(targetDir string) error {
+	holder, err := readTargetLock(targetLockPath(targetDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取目标文件夹的锁失败: %w", err)
+	}
+	if !holder.stale(time.Now()) {
+		return nil
+	}
+	if err := breakTargetLock(targetDir, holder); err != nil {
+		return err
+	}
+	fo.log("已解除失效的目标文件夹锁: " + holder.describe())
+	return nil
+}
+
+// 在后台定期刷新心跳。锁文件被其他电脑解除或替换后停止刷新并记录警告
+func (h *targetLockHandle) startHeartbeat(logf func(string)) {
+	h.stop = make(chan struct{})
+	h.done = make(chan struct{})
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(targetLockHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.stop:
+				return
+			case now := <-ticker.C:
+				current, err := readTargetLock(h.path)
+				if err != nil || current.RunID != h.lock.RunID || current.Host != h.lock.Host || current.PID != h.lock.PID {
+					logf(fmt.Sprintf("警告: 目标文件夹的锁已被解除或替换，停止刷新: %s", h.path))
+					return
+				}
+				h.lock.Heartbeat = now
+				if err := writeTargetLock(h.path, h.lock); err != nil {
+					logf(fmt.Sprintf("警告: 刷新目标文件夹的锁失败: %v", err))
+				}
+			}
+		}
+	}()
+}
+
+// 停止刷新心跳并删除锁文件。锁文件已经属于其他整理时保持不变
+func (h *targetLockHandle) release() error {
+	if h.stop != nil {
+		close(h.stop)
+		<-h.done
+		h.stop = nil
+	}
+	current, err := readTargetLock(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取目标文件夹的锁失败: %w", err)
+	}
+	if current.RunID != h.lock.RunID || current.Host != h.lock.Host || current.PID != h.lock.PID {
+		return nil
+	}
+	if err := os.Remove(h.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("删除目标文件夹的锁失败: %w", err)
+	}
+	return nil
+}
+
+// 整理前检查目标文件夹的锁：其他电脑正在整理时显示持有者并拒绝开始，
+// 锁已失效时由用户确认后解除。没有锁或是本机的失效锁时直接继续
+func (fo *FileOrganizer) confirmTargetLock(targetDir string, proceed func()) {
+	holder, err := readTargetLock(targetLockPath(targetDir))
+	if err != nil {
+		// 没有锁，或者无法读取时由整理开始时获取锁来报告
+		proceed()
+		return
+	}
+	now := time.Now()
+	if !holder.stale(now) {
+		fo.log("目标文件夹正在被整理: " + holder.describe())
+		dialog.ShowInformation("目标文件夹正在被整理",
+			fmt.Sprintf("%s\n\n请等待对方整理完成后再试。锁文件: %s", holder.describe(), targetLockPath(targetDir)), fo.Window)
+		return
+	}
+	if holder.Host != "" && holder.Host == lockHostName() {
+		proceed()
+		return
+	}
+	dialog.ShowConfirm("解除失效的锁",
+		fmt.Sprintf("目标文件夹有一个已失效的锁:\n%s\n\n心跳已超过 %v 没有刷新，对方可能已崩溃或断开连接。"+
+			"如果确定没有其他电脑正在整理，可以解除锁后继续。确定解除吗？", holder.describe(), targetLockStaleAfter),
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := breakTargetLock(targetDir, holder); err != nil {
+				fo.log(err.Error())
+				dialog.ShowError(err, fo.Window)
+				return
+			}
+			fo.log("已解除失效的目标文件夹锁: " + holder.describe())
+			proceed()
+		}, fo.Window)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 目标文件夹正在被其他整理锁定时，监视和撤销都不修改目标，监视的文件稍后再试
+func TestTargetLockBlocksWriters(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, fo *FileOrganizer, source, target string) error
+	}{
+		{"监视", func(t *testing.T, fo *FileOrganizer, source, target string) error {
+			wr := &watchedRoot{root: source, pending: make(map[string]*time.Timer), config: Config{
+				SourceDir:      source,
+				SourceDirs:     []string{source},
+				TargetDir:      target,
+				FileExtensions: []string{".jpg"},
+				OrganizeRule:   string(RuleByExtension),
+				ExtensionCase:  "lowercase",
+				ExcludedFiles:  map[string]bool{},
+			}}
+			fo.handleWatchedFile(wr, filepath.Join(source, "a.jpg"))
+			wr.mu.Lock()
+			defer wr.mu.Unlock()
+			timer, retried := wr.pending[filepath.Join(source, "a.jpg")]
+			if !retried {
+				t.Fatal("目标被锁定时应稍后再整理")
+			}
+			timer.Stop()
+			return nil
+		}},
+		{"撤销", func(t *testing.T, fo *FileOrganizer, source, target string) error {
+			final := filepath.Join(target, "b.jpg")
+			entry := CatalogEntry{RunID: "20240101_000000", OriginalPath: filepath.Join(source, "b.jpg"), FinalPath: final}
+			_, _, err := fo.rollbackEntries([]CatalogEntry{entry}, Config{TargetDir: target, ConflictPolicy: ConflictRename})
+			if err == nil {
+				t.Fatal("目标被锁定时撤销应返回错误")
+			}
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			source := t.TempDir()
+			target := t.TempDir()
+			writeTestFile(t, filepath.Join(source, "a.jpg"), "camera")
+			writeTestFile(t, filepath.Join(target, "b.jpg"), "organized")
+			lock, err := acquireTargetLock(target, "other-run")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { lock.release() })
+			before := snapshotTree(t, target)
+
+			if err := tt.run(t, fo, source, target); err != nil {
+				t.Fatal(err)
+			}
+			if got := snapshotTree(t, target); !reflect.DeepEqual(got, before) {
+				t.Fatalf("目标文件夹被修改: %v", got)
+			}
+			if fo.runActive() {
+				t.Fatal("结束后仍计为正在整理")
+			}
+		})
+	}
+}
+
+// 无界面运行遇到其他电脑留下的失效锁时退出，加上 -break-stale-lock 后解除并整理
+func TestHeadlessBreakStaleLock(t *testing.T) {
+	tests := []struct {
+		name     string
+		flag     bool
+		stale    bool
+		wantExit int
+	}{
+		{"失效的锁，不解除", false, true, exitFatal},
+		{"失效的锁，解除", true, true, exitSuccess},
+		{"仍在刷新的锁不解除", true, false, exitFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestEnv(t)
+			source := t.TempDir()
+			target := t.TempDir()
+			writeTestFile(t, filepath.Join(source, "a.jpg"), "camera")
+			heartbeat := time.Now()
+			if tt.stale {
+				heartbeat = heartbeat.Add(-2 * targetLockStaleAfter)
+			}
+			held := TargetLock{Host: "other-host", PID: 1, RunID: "old", StartedAt: heartbeat, Heartbeat: heartbeat}
+			if err := writeTargetLock(targetLockPath(target), held); err != nil {
+				t.Fatal(err)
+			}
+
+			args := []string{"-headless", "-source", source, "-target", target, "-ext", "jpg"}
+			if tt.flag {
+				args = append(args, "-break-stale-lock")
+			}
+			var stdout, stderr bytes.Buffer
+			if code := runHeadless(args, &stdout, &stderr); code != tt.wantExit {
+				t.Fatalf("退出码 = %d, 期望 %d\n%s", code, tt.wantExit, stderr.String())
+			}
+			var summary HeadlessSummary
+			if err := json.Unmarshal(stdout.Bytes(), &summary); err != nil {
+				t.Fatal(err)
+			}
+			_, err := os.Stat(filepath.Join(target, ".jpg", "a.jpg"))
+			if (err == nil) != (tt.wantExit == exitSuccess) {
+				t.Fatalf("整理后的文件: %v，总结 %+v", err, summary)
+			}
+		})
+	}
+}
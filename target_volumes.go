@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// TargetVolume 一个目标卷：整理到的根文件夹，以及该磁盘上至少保留的可用空间。
+// 目标文件夹是第一个卷，其余卷在前面的卷空间不足时依次使用
+type TargetVolume struct {
+	Root      string `json:"root"`
+	MinFreeMB int64  `json:"min_free_mb"`
+}
+
+// 保留空间（字节）
+func (v TargetVolume) minFree() int64 {
+	return v.MinFreeMB << 20
+}
+
+// volumePlan 每个第一层目标文件夹（例如日期文件夹）分配到的卷。
+// 同一个文件夹整体放在一个卷上，不会拆到多个卷
+type volumePlan struct {
+	primary string            // 目标文件夹，规则计算出的路径都在它下面
+	folders map[string]string // 第一层文件夹名称 → 卷的根文件夹
+}
+
+// 将目标文件夹下的路径换到分配的卷上，没有分配时保持不变
+func (p *volumePlan) remap(targetDir string) string {
+	if p == nil {
+		return targetDir
+	}
+	top, rel, ok := topFolder(p.primary, targetDir)
+	if !ok {
+		return targetDir
+	}
+	if root, ok := p.folders[top]; ok && root != p.primary {
+		return filepath.Join(root, rel)
+	}
+	return targetDir
+}
+
+// 路径在目标文件夹下的第一层文件夹名称和完整的相对路径
+func topFolder(primary, targetDir string) (string, string, bool) {
+	rel, err := filepath.Rel(primary, targetDir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", false
+	}
+	top, _, _ := strings.Cut(rel, string(filepath.Separator))
+	return top, rel, true
+}
+
+// 本次整理使用的全部目标卷，没有设置溢出目标时为空
+func (fo *FileOrganizer) currentTargetVolumes(targetDir string) []TargetVolume {
+	if len(fo.SpillTargets) == 0 {
+		return nil
+	}
+	volumes := []TargetVolume{{Root: targetDir, MinFreeMB: fo.TargetMinFreeMB}}
+	return append(volumes, fo.SpillTargets...)
+}
+
+// 路径所在的卷，不在任何卷中时返回空字符串
+func volumeOf(path string, volumes []TargetVolume) string {
+	for _, volume := range volumes {
+		if isWithinAny(path, []string{volume.Root}) {
+			return volume.Root
+		}
+	}
+	return ""
+}
+
+// 获取卷的可用空间，卷的文件夹尚未创建时查看最近的已存在的上级文件夹
+func volumeFreeSpace(root string) (int64, error) {
+	for dir := filepath.Clean(root); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			return diskFreeSpace(dir)
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return diskFreeSpace(dir)
+		}
+	}
+}
+
+// 为本次整理的每个第一层目标文件夹分配卷：
+// 已经存在于某个卷上的文件夹，或整理目录中记录过去向的文件夹，继续放在同一个卷上；
+// 新的文件夹按顺序放到第一个放得下（放入后仍保留设置的可用空间）的卷上。
+// 任何卷预计剩余空间低于保留值时返回错误
+func (fo *FileOrganizer) planVolumes(config Config, files []string) (*volumePlan, error) {
+	return fo.planVolumesFor(config, files, func(path string) os.FileInfo { return fo.scannedFileInfos[path] })
+}
+
+// 按 infoOf 提供的文件信息分配目标卷，规则测试中使用示例的大小
+func (fo *FileOrganizer) planVolumesFor(config Config, files []string, infoOf func(path string) os.FileInfo) (*volumePlan, error) {
+	if len(config.Volumes) == 0 {
+		return nil, nil
+	}
+	if OrganizeRule(config.OrganizeRule) == RuleByHash {
+		return nil, errors.New("按内容哈希整理时不支持多个目标卷")
+	}
+	for _, volume := range config.Volumes {
+		if err := validateTargetDir(volume.Root); err != nil {
+			return nil, err
+		}
+	}
+
+	// 统计每个第一层文件夹需要的空间
+	planConfig := config
+	planConfig.VolumePlan = nil
+	sizes := make(map[string]int64)
+	for _, filePath := range files {
+		info := infoOf(filePath)
+		if info == nil || config.ExcludedFiles[filePath] || config.Pins.matches(filePath, nil) ||
+			!fo.isTargetFile(fileExtension(filePath), config.FileExtensions) || isRefile(filePath, config) {
+			continue
+		}
+		if top, _, ok := topFolder(config.TargetDir, fo.planTargetDir(filePath, info, planConfig)); ok {
+			sizes[top] += packageSize(filePath, info)
+		}
+	}
+
+	plan := &volumePlan{primary: config.TargetDir, folders: make(map[string]string)}
+	free := make(map[string]int64, len(config.Volumes))
+	for _, volume := range config.Volumes {
+		space, err := volumeFreeSpace(volume.Root)
+		if err != nil {
+			return nil, err
+		}
+		free[volume.Root] = space
+	}
+
+	// 以前整理到某个卷上的文件夹继续放在那里
+	history := fo.volumeHistory(config.Volumes)
+	var newFolders []string
+	for top, size := range sizes {
+		root := ""
+		for _, volume := range config.Volumes {
+			if _, err := os.Stat(filepath.Join(volume.Root, top)); err == nil {
+				root = volume.Root
+				break
+			}
+		}
+		if root == "" {
+			root = history[top]
+		}
+		if root == "" {
+			newFolders = append(newFolders, top)
+			continue
+		}
+		plan.folders[top] = root
+		free[root] -= size
+	}
+
+	// 新文件夹从大到小依次放到第一个放得下的卷上
+	sort.Slice(newFolders, func(i, j int) bool {
+		if sizes[newFolders[i]] != sizes[newFolders[j]] {
+			return sizes[newFolders[i]] > sizes[newFolders[j]]
+		}
+		return newFolders[i] < newFolders[j]
+	})
+	for _, top := range newFolders {
+		assigned := false
+		for _, volume := range config.Volumes {
+			if free[volume.Root]-sizes[top] >= volume.minFree() {
+				plan.folders[top] = volume.Root
+				free[volume.Root] -= sizes[top]
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			return nil, fmt.Errorf("所有目标卷的空间都不足以放下文件夹 %s（%s）", top, formatFileSize(sizes[top]))
+		}
+	}
+
+	// 已有文件夹继续追加到原来的卷上，该卷可能因此低于保留的空间
+	for _, volume := range config.Volumes {
+		if free[volume.Root] < volume.minFree() {
+			return nil, fmt.Errorf("目标卷 %s 整理后预计只剩 %s，低于设置保留的 %s",
+				volume.Root, formatFileSize(max(free[volume.Root], 0)), formatFileSize(volume.minFree()))
+		}
+	}
+	return plan, nil
+}
+
+// 在后台计算扫描结果的目标卷分配，完成后刷新计划目标列
+func (fo *FileOrganizer) previewVolumePlan() {
+	fo.volumePlan = nil
+	config := fo.currentConfig()
+	if len(config.Volumes) == 0 || OrganizeRule(config.OrganizeRule) == RuleByHash {
+		return
+	}
+	files := fo.scannedFiles
+	go func() {
+		plan, err := fo.planVolumes(config, files)
+		fo.safeUpdateUI(func() {
+			if err != nil {
+				fo.log("目标卷分配预览失败: " + err.Error())
+				return
+			}
+			fo.volumePlan = plan
+			fo.refreshFileTable()
+		})
+	}()
+}
+
+// 从整理目录中读取以前每个第一层文件夹整理到的卷，同一个文件夹以最近的记录为准
+func (fo *FileOrganizer) volumeHistory(volumes []TargetVolume) map[string]string {
+	history := make(map[string]string)
+	entries, err := queryCatalog(catalogPath(), "volume != ''", "", 0)
+	if err != nil && !errors.Is(err, errCatalogCorrupt) {
+		fo.log(fmt.Sprintf("读取目标卷记录失败: %v", err))
+	}
+	known := make(map[string]bool, len(volumes))
+	for _, volume := range volumes {
+		known[volume.Root] = true
+	}
+	for _, entry := range entries {
+		if !known[entry.Volume] {
+			continue
+		}
+		if top, _, ok := topFolder(entry.Volume, filepath.Dir(entry.FinalPath)); ok {
+			history[top] = entry.Volume
+		}
+	}
+	return history
+}
+
+// 描述各卷分配到的文件夹数量，用于日志
+func describeVolumePlan(plan *volumePlan, volumes []TargetVolume) []string {
+	counts := make(map[string]int)
+	for _, root := range plan.folders {
+		counts[root]++
+	}
+	var lines []string
+	for _, volume := range volumes {
+		lines = append(lines, fmt.Sprintf("目标卷 %s: %d 个文件夹", volume.Root, counts[volume.Root]))
+	}
+	return lines
+}
+
+// 加载溢出目标
+func (fo *FileOrganizer) loadSpillTargets() {
+	prefs := fyne.CurrentApp().Preferences()
+	fo.SpillTargets = nil
+	if data := prefs.StringWithFallback("spill_targets", ""); data != "" {
+		if err := json.Unmarshal([]byte(data), &fo.SpillTargets); err != nil {
+			fo.log(fmt.Sprintf("加载溢出目标失败: %v", err))
+			fo.SpillTargets = nil
+		}
+	}
+	fo.TargetMinFreeMB = int64(prefs.IntWithFallback("target_min_free_mb", 0))
+}
+
+// 保存溢出目标
+func (fo *FileOrganizer) saveSpillTargets() {
+	prefs := fyne.CurrentApp().Preferences()
+	data, err := json.Marshal(fo.SpillTargets)
+	if err != nil {
+		fo.log(fmt.Sprintf("保存溢出目标失败: %v", err))
+		return
+	}
+	prefs.SetString("spill_targets", string(data))
+	prefs.SetInt("target_min_free_mb", int(fo.TargetMinFreeMB))
+}
+
+// 显示目标卷管理对话框
+func (fo *FileOrganizer) showTargetVolumesDialog() {
+	targets := append([]TargetVolume(nil), fo.SpillTargets...)
+	selected := -1
+
+	describe := func(volume TargetVolume) string {
+		if space, err := volumeFreeSpace(volume.Root); err == nil {
+			return fmt.Sprintf("%s（保留 %d MB，当前可用 %s）", volume.Root, volume.MinFreeMB, formatFileSize(space))
+		}
+		return fmt.Sprintf("%s（保留 %d MB）", volume.Root, volume.MinFreeMB)
+	}
+	targetList := widget.NewList(
+		func() int { return len(targets) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(fmt.Sprintf("%d. %s", i+2, describe(targets[i])))
+		},
+	)
+	targetList.OnSelected = func(id widget.ListItemID) { selected = id }
+	targetList.OnUnselected = func(id widget.ListItemID) { selected = -1 }
+
+	mbValidator := func(text string) error {
+		if n, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64); err != nil || n < 0 {
+			return errors.New("请输入非负整数（MB）")
+		}
+		return nil
+	}
+	primaryMinFree := widget.NewEntry()
+	primaryMinFree.SetText(strconv.FormatInt(fo.TargetMinFreeMB, 10))
+	primaryMinFree.Validator = mbValidator
+	newMinFree := widget.NewEntry()
+	newMinFree.SetText("10240")
+	newMinFree.Validator = mbValidator
+
+	addBtn := widget.NewButton("添加溢出目标...", func() {
+		mb, err := strconv.ParseInt(strings.TrimSpace(newMinFree.Text), 10, 64)
+		if err != nil || mb < 0 {
+			dialog.ShowError(errors.New("请先填写有效的保留空间"), fo.Window)
+			return
+		}
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err != nil || dir == nil {
+				return
+			}
+			root := filepath.Clean(dir.Path())
+			if isWithinAny(root, []string{fo.TargetDirEntry.Text}) || volumeOf(root, targets) != "" {
+				dialog.ShowError(fmt.Errorf("%s 已在目标卷中", root), fo.Window)
+				return
+			}
+			targets = append(targets, TargetVolume{Root: root, MinFreeMB: mb})
+			targetList.Refresh()
+		}, fo.Window)
+	})
+	removeBtn := widget.NewButton("删除", func() {
+		if selected < 0 || selected >= len(targets) {
+			return
+		}
+		targets = append(targets[:selected], targets[selected+1:]...)
+		selected = -1
+		targetList.UnselectAll()
+		targetList.Refresh()
+	})
+	upBtn := widget.NewButton("上移", func() {
+		if selected <= 0 || selected >= len(targets) {
+			return
+		}
+		targets[selected-1], targets[selected] = targets[selected], targets[selected-1]
+		targetList.Select(selected - 1)
+		targetList.Refresh()
+	})
+
+	listScroll := container.NewVScroll(targetList)
+	listScroll.SetMinSize(fyne.NewSize(560, 180))
+	hint := widget.NewLabel("目标文件夹所在的磁盘空间不足时，新的文件夹按顺序放到下面的溢出目标中。" +
+		"同一个文件夹（例如日期文件夹）始终放在同一个磁盘上，以后整理时继续追加到原来的磁盘。")
+	hint.Wrapping = fyne.TextWrapWord
+	form := widget.NewForm(
+		widget.NewFormItem("目标文件夹保留空间（MB）", primaryMinFree),
+		widget.NewFormItem("新溢出目标保留空间（MB）", newMinFree),
+	)
+	content := container.NewVBox(
+		hint,
+		widget.NewLabel("1. 目标文件夹"),
+		listScroll,
+		form,
+		container.NewGridWithColumns(3, addBtn, upBtn, removeBtn),
+	)
+
+	volumesDialog := dialog.NewCustomConfirm("多个目标卷", "保存", "取消", content, func(save bool) {
+		if !save {
+			return
+		}
+		if mb, err := strconv.ParseInt(strings.TrimSpace(primaryMinFree.Text), 10, 64); err == nil && mb >= 0 {
+			fo.TargetMinFreeMB = mb
+		}
+		fo.SpillTargets = targets
+		fo.volumePlan = nil
+		fo.saveSpillTargets()
+		if len(targets) == 0 {
+			fo.log("已清除溢出目标，只使用目标文件夹")
+		} else {
+			fo.log(fmt.Sprintf("已设置 %d 个溢出目标", len(targets)))
+		}
+		fo.refreshFileTable()
+	}, fo.Window)
+	fo.showDialog(volumesDialog, primaryMinFree)
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 按内容整理时默认读取的文本长度（KB），只读取文件开头
+const defaultTextPrefixKB = 64
+
+// 按内容整理时最多读取的文本长度（KB）
+const maxTextPrefixKB = 4096
+
+// 识别语言至少需要的字母数，太短的文本不分语言文件夹
+const minLanguageLetters = 20
+
+// TextKeywordRule 关键词规则：文本中出现关键词时放入对应的文件夹
+type TextKeywordRule struct {
+	Keyword string `json:"keyword"`
+	Folder  string `json:"folder"`
+}
+
+// 按内容整理的文本文件后缀
+var textRuleExtensions = map[string]bool{
+	".txt":      true,
+	".md":       true,
+	".markdown": true,
+	".pdf":      true,
+	".docx":     true,
+}
+
+// 识别出的语言使用的文件夹名称
+var languageFolderNames = map[string]string{
+	"zh": "中文",
+	"ja": "日本語",
+	"ko": "한국어",
+	"ru": "Русский",
+	"en": "English",
+	"de": "Deutsch",
+	"fr": "Français",
+	"es": "Español",
+}
+
+// 区分拉丁字母语言的常用词
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "for", "with", "this"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "ein", "sie", "auf"},
+	"fr": {"le", "les", "et", "est", "des", "une", "pour", "dans", "pas", "que"},
+	"es": {"el", "los", "que", "y", "es", "por", "una", "del", "las", "con"},
+}
+
+// 内容不是文本的文件，例如改了后缀的二进制文件
+var errBinaryText = errors.New("不是文本文件")
+
+// 是否开启了按内容整理文本文件
+func textRulesEnabled(config Config) bool {
+	return config.TextLanguageFolders || len(config.TextKeywordRules) > 0
+}
+
+// 解析关键词规则，每行或每个分号一条，格式为 关键词=文件夹
+func parseTextKeywordRules(text string) ([]TextKeywordRule, error) {
+	var rules []TextKeywordRule
+	for _, line := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == ';' }) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		keyword, folder, ok := strings.Cut(line, "=")
+		keyword, folder = strings.TrimSpace(keyword), strings.TrimSpace(folder)
+		if !ok || keyword == "" || folder == "" {
+			return nil, fmt.Errorf("关键词规则的格式为 关键词=文件夹: %q", line)
+		}
+		if sanitizeFolderName(folder) != folder {
+			return nil, fmt.Errorf("文件夹名称中有不能使用的字符: %q", folder)
+		}
+		rules = append(rules, TextKeywordRule{Keyword: keyword, Folder: folder})
+	}
+	return rules, nil
+}
+
+// 关键词规则的文本形式，每条规则一行
+func formatTextKeywordRules(rules []TextKeywordRule, sep string) string {
+	lines := make([]string, len(rules))
+	for i, rule := range rules {
+		lines[i] = rule.Keyword + "=" + rule.Folder
+	}
+	return strings.Join(lines, sep)
+}
+
+// 读取的文本长度（字节）
+func textPrefixBytes(config Config) int {
+	kb := config.TextPrefixKB
+	if kb <= 0 {
+		kb = defaultTextPrefixKB
+	}
+	if kb > maxTextPrefixKB {
+		kb = maxTextPrefixKB
+	}
+	return kb * 1024
+}
+
+// 按内容确定的文件夹：依次检查关键词规则（第一条匹配的规则生效），再识别语言，
+// 都没有结果时返回空字符串，文件直接放在规则文件夹中
+func classifyText(text string, config Config) string {
+	if len(config.TextKeywordRules) > 0 {
+		haystack := text
+		if !config.TextKeywordCase {
+			haystack = strings.ToLower(text)
+		}
+		for _, rule := range config.TextKeywordRules {
+			keyword := rule.Keyword
+			if !config.TextKeywordCase {
+				keyword = strings.ToLower(keyword)
+			}
+			if strings.Contains(haystack, keyword) {
+				return rule.Folder
+			}
+		}
+	}
+	if config.TextLanguageFolders {
+		if lang := detectLanguage(text); lang != "" {
+			return languageFolderNames[lang]
+		}
+	}
+	return ""
+}
+
+// 按字符的书写系统和常用词识别语言，无法确定时返回空字符串
+func detectLanguage(text string) string {
+	var han, kana, hangul, cyrillic, latin, total int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		default:
+			continue
+		}
+		total++
+	}
+	if total < minLanguageLetters {
+		return ""
+	}
+	// 日文中汉字通常比假名多，假名达到一定比例即认为是日文
+	if kana*10 >= total {
+		return "ja"
+	}
+	switch max(han, hangul, cyrillic, latin) {
+	case hangul:
+		return "ko"
+	case han:
+		return "zh"
+	case cyrillic:
+		return "ru"
+	}
+	return detectLatinLanguage(text)
+}
+
+// 按常用词出现的次数区分拉丁字母的语言，没有常用词时无法确定
+func detectLatinLanguage(text string) string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		counts[word]++
+	}
+	best, bestScore := "", 0
+	for _, lang := range []string{"en", "de", "fr", "es"} {
+		score := 0
+		for _, word := range languageStopwords[lang] {
+			score += counts[word]
+		}
+		if score > bestScore {
+			best, bestScore = lang, score
+		}
+	}
+	return best
+}
+
+// 读取文件开头的文本，二进制或无法读取的文件返回错误
+func readTextPrefix(path string, limit int) (string, error) {
+	switch strings.ToLower(fileExtension(path)) {
+	case ".pdf":
+		return readPDFText(path, limit)
+	case ".docx":
+		return readDocxText(path, limit)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, int64(limit)))
+	if err != nil {
+		return "", err
+	}
+	return decodeTextBytes(data)
+}
+
+// 把文件内容解码为文本：UTF-16（有BOM时）、UTF-8，再尝试GB18030。含有NUL字节的内容当作二进制
+func decodeTextBytes(data []byte) (string, error) {
+	if len(data) >= 2 && (data[0] == 0xFF && data[1] == 0xFE || data[0] == 0xFE && data[1] == 0xFF) {
+		return decodeUTF16(data[2:], data[0] == 0xFE), nil
+	}
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", errBinaryText
+	}
+	// 读取的开头可能截断了最后一个字符
+	valid := data
+	for i := 0; i < utf8.UTFMax && len(valid) > 0 && !utf8.Valid(valid); i++ {
+		valid = valid[:len(valid)-1]
+	}
+	if utf8.Valid(valid) {
+		return string(valid), nil
+	}
+	decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+	if err != nil || strings.Count(string(decoded), "�")*100 > len(decoded) {
+		return "", errBinaryText
+	}
+	return string(decoded), nil
+}
+
+// 解码UTF-16文本
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// 读取 .docx 正文中的文字，最多 limit 字节
+func readDocxText(path string, limit int) (string, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("读取 docx 失败: %w", err)
+	}
+	defer reader.Close()
+	for _, entry := range reader.File {
+		if entry.Name != "word/document.xml" {
+			continue
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return "", fmt.Errorf("读取 docx 失败: %w", err)
+		}
+		defer rc.Close()
+		var text strings.Builder
+		inText := false
+		decoder := xml.NewDecoder(rc)
+		for text.Len() < limit {
+			token, err := decoder.Token()
+			if err != nil {
+				break
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				inText = t.Name.Local == "t"
+			case xml.EndElement:
+				switch t.Name.Local {
+				case "t":
+					inText = false
+				case "p":
+					text.WriteByte('\n')
+				}
+			case xml.CharData:
+				if inText {
+					text.Write(t)
+				}
+			}
+		}
+		return text.String(), nil
+	}
+	return "", errors.New("docx 中没有正文")
+}
+
+// PDF 中的数据流和文本对象
+var (
+	pdfStreamPattern = regexp.MustCompile(`<<((?:[^<>]|<<[^<>]*>>|<[^<>]*>)*)>>\s*stream\r?\n`)
+	pdfTextPattern   = regexp.MustCompile(`(?s)BT(.*?)ET`)
+)
+
+// 读取 PDF 开头部分的文字层：解压其中的内容流，取出文本对象中的字符串。
+// 只处理直接写在内容流中的字符串，扫描件等没有文字层的 PDF 没有结果
+func readPDFText(path string, limit int) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	// 文字可能在字体和图片之后，读取的文件内容比文本长度多一些
+	data, err := io.ReadAll(io.LimitReader(file, int64(limit)*8))
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return "", errBinaryText
+	}
+	var text strings.Builder
+	for _, match := range pdfStreamPattern.FindAllSubmatchIndex(data, -1) {
+		if text.Len() >= limit {
+			break
+		}
+		dict := data[match[2]:match[3]]
+		start := match[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		content := data[start : start+end]
+		if bytes.Contains(dict, []byte("/Subtype/Image")) || bytes.Contains(dict, []byte("/Subtype /Image")) {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			zr, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			content, _ = io.ReadAll(io.LimitReader(zr, int64(limit)*4))
+			zr.Close()
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+		for _, block := range pdfTextPattern.FindAllSubmatch(content, -1) {
+			text.WriteString(pdfStrings(block[1]))
+			text.WriteByte('\n')
+		}
+	}
+	return text.String(), nil
+}
+
+// 取出PDF文本对象中括号内的字符串，处理转义
+func pdfStrings(data []byte) string {
+	var text strings.Builder
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if depth == 0 {
+			if c == '(' {
+				depth = 1
+			}
+			continue
+		}
+		switch c {
+		case '\\':
+			if i+1 >= len(data) {
+				continue
+			}
+			i++
+			switch data[i] {
+			case 'n', 'r':
+				text.WriteByte(' ')
+			case 't':
+				text.WriteByte('\t')
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				// 八进制字符，最多三位
+				v := 0
+				j := i
+				for ; j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7'; j++ {
+					v = v*8 + int(data[j]-'0')
+				}
+				i = j - 1
+				text.WriteRune(rune(v & 0xFF))
+			default:
+				text.WriteByte(data[i])
+			}
+		case '(':
+			depth++
+			text.WriteByte(c)
+		case ')':
+			depth--
+			if depth == 0 {
+				text.WriteByte(' ')
+			} else {
+				text.WriteByte(c)
+			}
+		default:
+			text.WriteByte(c)
+		}
+	}
+	return text.String()
+}
+
+// textFolderCache 缓存按内容确定的文件夹，规划目标和预览时每个文件只读取一次
+type textFolderCache struct {
+	mu      sync.Mutex
+	folders map[string]textFolderEntry // 键为路径
+}
+
+// textFolderEntry 按某一组规则判断出的文件夹，规则改变后重新判断
+type textFolderEntry struct {
+	signature string
+	folder    string
+}
+
+// 创建按内容整理的缓存
+func newTextFolderCache() *textFolderCache {
+	return &textFolderCache{folders: make(map[string]textFolderEntry)}
+}
+
+// 规则的签名，规则改变后重新判断
+func textRulesSignature(config Config) string {
+	return fmt.Sprintf("%t|%t|%d|%s", config.TextLanguageFolders, config.TextKeywordCase, textPrefixBytes(config),
+		formatTextKeywordRules(config.TextKeywordRules, "\n"))
+}
+
+// 文件是否需要按内容判断文件夹
+func needsTextFolder(path string, config Config) bool {
+	return textRulesEnabled(config) && textRuleExtensions[strings.ToLower(fileExtension(path))]
+}
+
+// 文件按内容确定的文件夹名称，没有开启、不是文本文件或没有匹配时返回空字符串。
+// 二进制或无法读取的文件跳过内容规则
+func (c *textFolderCache) folder(path string, config Config) string {
+	if !needsTextFolder(path, config) {
+		return ""
+	}
+	if folder, ok := c.cached(path, config); ok {
+		return folder
+	}
+	folder := ""
+	if text, err := readTextPrefix(path, textPrefixBytes(config)); err == nil {
+		folder = classifyText(text, config)
+	}
+	c.mu.Lock()
+	c.folders[path] = textFolderEntry{signature: textRulesSignature(config), folder: folder}
+	c.mu.Unlock()
+	return folder
+}
+
+// 已按当前规则判断过的文件夹，不读取文件
+func (c *textFolderCache) cached(path string, config Config) (string, bool) {
+	signature := textRulesSignature(config)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.folders[path]
+	if !ok || entry.signature != signature {
+		return "", false
+	}
+	return entry.folder, true
+}
+
+// 丢弃一个文件的结果，同一路径上出现新文件时重新读取
+func (c *textFolderCache) forget(path string) {
+	c.mu.Lock()
+	delete(c.folders, path)
+	c.mu.Unlock()
+}
+
+// 清空缓存，每次扫描后重新读取
+func (c *textFolderCache) reset() {
+	c.mu.Lock()
+	c.folders = make(map[string]textFolderEntry)
+	c.mu.Unlock()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 关键词规则先于语言，第一条匹配的规则生效，默认不区分大小写
+func TestClassifyTextOrder(t *testing.T) {
+	english := "The report is in the folder and this is the summary of that work for the team with notes."
+	rules := []TextKeywordRule{{Keyword: "Invoice", Folder: "财务"}, {Keyword: "report", Folder: "报告"}}
+	tests := []struct {
+		name      string
+		text      string
+		language  bool
+		rules     []TextKeywordRule
+		matchCase bool
+		want      string
+	}{
+		{"关键词先于语言", english, true, rules, false, "报告"},
+		{"第一条匹配的规则生效", "invoice for the report", false, rules, false, "财务"},
+		{"区分大小写时不匹配", "invoice for the REPORT", true, rules, true, ""},
+		{"没有匹配的关键词时识别语言", english, true, []TextKeywordRule{{Keyword: "发票", Folder: "财务"}}, false, "English"},
+		{"没有开启语言", english, false, nil, false, ""},
+		{"文本太短不识别语言", "the and of", true, nil, false, ""},
+	}
+	for _, tt := range tests {
+		config := Config{TextLanguageFolders: tt.language, TextKeywordRules: tt.rules, TextKeywordCase: tt.matchCase}
+		if got := classifyText(tt.text, config); got != tt.want {
+			t.Errorf("%s: %q, 期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 按内容的一层放在规则文件夹（包括模板生成的文件夹）之下；非文本后缀和二进制文件不分层
+func TestRuleFolderTextLayer(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		rule     OrganizeRule
+		template string
+		want     string
+	}{
+		{"按后缀", "a.txt", "发票 2024", RuleByExtension, "", filepath.Join(".txt", "财务")},
+		{"模板之后", "a.md", "发票 2024", RuleByExtension, "文档-{ext}", filepath.Join("文档-md", "财务")},
+		{"没有匹配", "a.txt", "hello", RuleByExtension, "", ".txt"},
+		{"不是文本后缀", "a.csv", "发票 2024", RuleByExtension, "", ".csv"},
+		{"二进制文件", "a.txt", "发票\x00\x01\x02\x03", RuleByExtension, "", ".txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			path := writeTestFile(t, filepath.Join(t.TempDir(), tt.file), tt.content)
+			config := Config{
+				OrganizeRule:     string(tt.rule),
+				ExtensionCase:    "lowercase",
+				FolderTemplate:   tt.template,
+				TextKeywordRules: []TextKeywordRule{{Keyword: "发票", Folder: "财务"}},
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fo.ruleFolderName(path, info, config); got != tt.want {
+				t.Fatalf("规则文件夹 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 同一路径上的新文件和改变的规则都重新读取内容，只查询缓存时不读取文件
+func TestTextFolderCache(t *testing.T) {
+	path := writeTestFile(t, filepath.Join(t.TempDir(), "a.txt"), "发票")
+	config := Config{TextKeywordRules: []TextKeywordRule{{Keyword: "发票", Folder: "财务"}}}
+	cache := newTextFolderCache()
+	if _, ok := cache.cached(path, config); ok {
+		t.Fatal("没有读取过的文件不应有结果")
+	}
+	if got := cache.folder(path, config); got != "财务" {
+		t.Fatalf("文件夹 = %q", got)
+	}
+
+	writeTestFile(t, path, "合同")
+	if got, ok := cache.cached(path, config); !ok || got != "财务" {
+		t.Fatalf("缓存 = %q, %v", got, ok)
+	}
+	changed := Config{TextKeywordRules: []TextKeywordRule{{Keyword: "合同", Folder: "法务"}}}
+	if got := cache.folder(path, changed); got != "法务" {
+		t.Fatalf("规则改变后 = %q", got)
+	}
+	cache.forget(path)
+	if got := cache.folder(path, config); got != "" {
+		t.Fatalf("丢弃后重新读取 = %q", got)
+	}
+}
+
+// 预设指定的文本规则用于绑定的监视文件夹，没有指定时使用当前设置
+func TestPresetTextRules(t *testing.T) {
+	current := []TextKeywordRule{{Keyword: "合同", Folder: "法务"}}
+	tests := []struct {
+		name   string
+		preset Preset
+		want   []TextKeywordRule
+	}{
+		{"预设指定", Preset{Name: "p", TextKeywordRules: []TextKeywordRule{{Keyword: "发票", Folder: "财务"}}},
+			[]TextKeywordRule{{Keyword: "发票", Folder: "财务"}}},
+		{"预设没有指定", Preset{Name: "p"}, current},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			fo.TextKeywordRules = current
+			fo.presets = []Preset{tt.preset}
+			root := t.TempDir()
+			fo.watchBindings = map[string]string{root: "p"}
+			config, _ := fo.watchBaseConfig(root)
+			if formatTextKeywordRules(config.TextKeywordRules, ";") != formatTextKeywordRules(tt.want, ";") {
+				t.Fatalf("规则 = %v, 期望 %v", config.TextKeywordRules, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 按类别和大小整理的文件夹：类别在上一层，大小分组在下一层，例如 图片/10MB-100MB。
+// 类别按后缀判断（见 fileCategories），大小分组按设置中的上限划分（默认见 defaultSizeBucketsMB）
+func typeSizeFolderName(filePath string, fileInfo os.FileInfo, config Config) string {
+	return filepath.Join(fileCategory(filePath), sizeBucketLabel(config.sizeBuckets(), fileInfo.Size()))
+}
+
+// 整理使用的大小分组，没有设置时使用默认的上限
+func (c Config) sizeBuckets() []sizeBucket {
+	return newSizeBuckets(c.SizeBucketsMB)
+}
+
+// typeSizeRow 矩阵中一个类别的各大小分组
+type typeSizeRow struct {
+	category string
+	files    int
+	bytes    int64
+	buckets  []int // 与 labels 对应的文件数
+}
+
+// typeSizeMatrix 扫描结果按类别和大小分组的文件数，每个非空的格子是一个目标文件夹
+type typeSizeMatrix struct {
+	labels  []string // 大小分组的名称
+	rows    []*typeSizeRow
+	columns []bool // 与 labels 对应，该大小分组是否有文件
+	folders int
+}
+
+// 统计要整理的文件在类别和大小分组中的分布，跳过排除、固定和未选择后缀的文件
+func (fo *FileOrganizer) planTypeSizeMatrix(config Config, files []string) *typeSizeMatrix {
+	buckets := config.sizeBuckets()
+	matrix := &typeSizeMatrix{columns: make([]bool, len(buckets))}
+	for _, bucket := range buckets {
+		matrix.labels = append(matrix.labels, bucket.label)
+	}
+	rows := make(map[string]*typeSizeRow)
+	for _, filePath := range files {
+		if config.ExcludedFiles[filePath] || config.Pins.matches(filePath, nil) ||
+			!fo.isTargetFile(fileExtension(filePath), config.FileExtensions) {
+			continue
+		}
+		info := fo.scannedFileInfos[filePath]
+		if info == nil {
+			var err error
+			if info, err = os.Stat(filePath); err != nil {
+				continue
+			}
+		}
+		category := fileCategory(filePath)
+		row := rows[category]
+		if row == nil {
+			row = &typeSizeRow{category: category, buckets: make([]int, len(buckets))}
+			rows[category] = row
+			matrix.rows = append(matrix.rows, row)
+		}
+		bucket := sizeBucketIndex(buckets, info.Size())
+		if row.buckets[bucket] == 0 {
+			matrix.folders++
+		}
+		row.buckets[bucket]++
+		row.files++
+		row.bytes += info.Size()
+		matrix.columns[bucket] = true
+	}
+	// 文件多的类别在前，"其他"放在最后
+	sort.Slice(matrix.rows, func(i, j int) bool {
+		a, b := matrix.rows[i], matrix.rows[j]
+		if (a.category == otherCategoryName) != (b.category == otherCategoryName) {
+			return b.category == otherCategoryName
+		}
+		if a.files != b.files {
+			return a.files > b.files
+		}
+		return a.category < b.category
+	})
+	return matrix
+}
+
+// 矩阵的表格内容：第一行是有文件的大小分组，之后每个类别一行，没有文件的格子显示为 -
+func typeSizeMatrixCells(matrix *typeSizeMatrix) [][]string {
+	header := []string{"类别"}
+	for i, label := range matrix.labels {
+		if matrix.columns[i] {
+			header = append(header, label)
+		}
+	}
+	cells := [][]string{header}
+	for _, row := range matrix.rows {
+		line := []string{fmt.Sprintf("%s（%d 个，%s）", row.category, row.files, formatFileSize(row.bytes))}
+		for i := range matrix.labels {
+			if !matrix.columns[i] {
+				continue
+			}
+			count := "-"
+			if row.buckets[i] > 0 {
+				count = fmt.Sprint(row.buckets[i])
+			}
+			line = append(line, count)
+		}
+		cells = append(cells, line)
+	}
+	return cells
+}
+
+// 预览中显示的类别 × 大小表格，每个非空的格子是一个目标文件夹
+func typeSizeMatrixGrid(matrix *typeSizeMatrix) fyne.CanvasObject {
+	cells := typeSizeMatrixCells(matrix)
+	grid := container.NewGridWithColumns(len(cells[0]))
+	for r, row := range cells {
+		for _, text := range row {
+			label := widget.NewLabel(text)
+			if r == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+			}
+			grid.Add(label)
+		}
+	}
+	title := widget.NewLabel(fmt.Sprintf("类别 × 大小（%d 个类别，%d 个文件夹）", len(matrix.rows), matrix.folders))
+	return container.NewVBox(title, container.NewHScroll(grid))
+}
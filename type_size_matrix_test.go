@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 大小分组的上限必须是从小到大的正整数，名称按上限生成
+func TestParseSizeBuckets(t *testing.T) {
+	tests := []struct {
+		text       string
+		wantErr    bool
+		wantLabels []string
+	}{
+		{"1, 10, 100, 1024", false, []string{"小于1MB", "1MB-10MB", "10MB-100MB", "100MB-1GB", "1GB以上"}},
+		{"5，2048", false, []string{"小于5MB", "5MB-2GB", "2GB以上"}},
+		{"500", false, []string{"小于500MB", "500MB以上"}},
+		{"", true, nil},
+		{"10, 1", true, nil},
+		{"1, 1", true, nil},
+		{"0", true, nil},
+		{"大", true, nil},
+	}
+	for _, tt := range tests {
+		limits, err := parseSizeBuckets(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: 错误 = %v", tt.text, err)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		var labels []string
+		for _, bucket := range newSizeBuckets(limits) {
+			labels = append(labels, bucket.label)
+		}
+		if !reflect.DeepEqual(labels, tt.wantLabels) {
+			t.Errorf("%q: 分组 = %v, 期望 %v", tt.text, labels, tt.wantLabels)
+		}
+	}
+}
+
+// 按设置的上限划分大小分组，矩阵只显示有文件的分组
+func TestTypeSizeMatrix(t *testing.T) {
+	fo := newTestOrganizer(t)
+	dir := t.TempDir()
+	config := Config{
+		FileExtensions: []string{".jpg", ".txt"},
+		OrganizeRule:   string(RuleByTypeSize),
+		SizeBucketsMB:  []int{1, 2},
+		ExcludedFiles:  map[string]bool{},
+	}
+	files := map[string]int{"a.jpg": 10, "b.jpg": 1<<20 + 1, "c.jpg": 3 << 20, "d.txt": 5}
+	var paths []string
+	for name, size := range files {
+		path := writeTestFile(t, filepath.Join(dir, name), string(make([]byte, size)))
+		paths = append(paths, path)
+	}
+	wantFolders := map[string]string{
+		"a.jpg": filepath.Join("图片", "小于1MB"),
+		"b.jpg": filepath.Join("图片", "1MB-2MB"),
+		"c.jpg": filepath.Join("图片", "2MB以上"),
+	}
+	for _, path := range paths {
+		want, ok := wantFolders[filepath.Base(path)]
+		if !ok {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fo.ruleFolderName(path, info, config); got != want {
+			t.Errorf("%s 的文件夹 = %s, 期望 %s", filepath.Base(path), got, want)
+		}
+	}
+
+	matrix := fo.planTypeSizeMatrix(config, paths)
+	cells := typeSizeMatrixCells(matrix)
+	if matrix.folders != 4 || len(cells) != 3 {
+		t.Fatalf("矩阵 = %v，%d 个文件夹", cells, matrix.folders)
+	}
+	if want := []string{"类别", "小于1MB", "1MB-2MB", "2MB以上"}; !reflect.DeepEqual(cells[0], want) {
+		t.Fatalf("表头 = %v, 期望 %v", cells[0], want)
+	}
+	if want := []string{"1", "1", "1"}; !reflect.DeepEqual(cells[1][1:], want) {
+		t.Fatalf("图片 = %v, 期望 %v", cells[1], want)
+	}
+	if want := []string{"1", "-", "-"}; !reflect.DeepEqual(cells[2][1:], want) {
+		t.Fatalf("文档 = %v, 期望 %v", cells[2], want)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2/dialog"
+)
+
+// UINotifier 整理引擎向界面报告进度的接口。
+// 日志处理器、scanFiles 和 processFiles 只通过该接口更新界面，
+// 这样在没有图形界面（没有运行中的事件循环）时也可以使用引擎。
+type UINotifier interface {
+	// 追加一段日志（可能包含多行）
+	AppendLog(text string)
+	// 清空界面上的日志
+	ClearLog()
+	// 开始扫描，扫描结果已被清空
+	ScanStarted()
+	// 扫描完成，rule为当前的整理规则
+	ScanFinished(rule OrganizeRule)
+	// 扫描错误过多而中止，badSource是错误最多的源文件夹
+	ScanAborted(errorCount int, badSource string, sourceErrors int)
+	// 扫描过程中有源文件夹被删除或所在磁盘被卸载，其余源文件夹的扫描结果不受影响
+	SourcesMissing(dirs []string)
+	// 开始整理，total为待处理的文件数
+	ProcessStarted(total int)
+	// 整理进度
+	ProcessProgress(processed, total int)
+	// 整理完成
+	ProcessFinished()
+	// 目标连续出现只读错误，整理已暂停，用户做出选择后调用decide。改用的目标不能使用时decide返回错误，整理仍然暂停
+	ReadOnlyTarget(root string, failures int, decide func(action readOnlyAction, newRoot string) error)
+}
+
+// fyneNotifier 通过 fyne.DoAndWait 在界面线程中更新Fyne界面
+type fyneNotifier struct {
+	fo *FileOrganizer
+	// 日志控件创建之前收到的日志
+	pendingLog string
+}
+
+// 追加日志，界面创建前的日志先暂存
+func (n *fyneNotifier) AppendLog(text string) {
+	fo := n.fo
+	if fo.LogTextLabel == nil {
+		n.pendingLog += text
+		return
+	}
+	text = n.pendingLog + text
+	n.pendingLog = ""
+	fo.safeUpdateUI(func() {
+		fo.LogTextLabel.SetText(fo.LogTextLabel.Text + text)
+		// 限制日志长度，避免内存占用过大
+		const maxLogLength = 1024 * 200 // 增大到200KB
+		if len(fo.LogTextLabel.Text) > maxLogLength {
+			// 保留最后一部分日志
+			fo.LogTextLabel.SetText("[日志过长，已截断前部分]\n" +
+				fo.LogTextLabel.Text[len(fo.LogTextLabel.Text)-maxLogLength/2:])
+		}
+	})
+}
+
+// 清空日志
+func (n *fyneNotifier) ClearLog() {
+	fo := n.fo
+	fo.safeUpdateUI(func() {
+		if fo.LogTextLabel != nil {
+			fo.LogTextLabel.SetText("")
+		}
+	})
+}
+
+// 扫描开始时禁用依赖扫描结果的按钮
+func (n *fyneNotifier) ScanStarted() {
+	fo := n.fo
+	fo.safeUpdateUI(func() {
+		fo.selectExtensionsBtn.Disable()
+		fo.selectDateFormatBtn.Disable()
+		fo.selectExtensionCaseBtn.Disable()
+		fo.processBtn.Disable()
+		fo.browseFilesBtn.Disable()
+		fo.rescanBtn.Disable()
+		fo.refreshFileTable()
+	})
+}
+
+// 扫描完成后根据选择的规则显示相应的选项
+func (n *fyneNotifier) ScanFinished(rule OrganizeRule) {
+	fo := n.fo
+	fo.safeUpdateUI(func() {
+		switch rule {
+		case RuleByDate:
+			fo.selectExtensionsBtn.Enable()
+			fo.selectDateFormatBtn.Enable()
+			fo.selectExtensionCaseBtn.Disable()
+		case RuleByExtension, RuleByHash:
+			fo.selectExtensionsBtn.Enable()
+			fo.selectDateFormatBtn.Disable()
+			fo.selectExtensionCaseBtn.Enable()
+		case RuleComposite:
+			fo.selectExtensionsBtn.Enable()
+			fo.selectDateFormatBtn.Enable()
+			fo.selectExtensionCaseBtn.Enable()
+		case RuleByTag, RuleByAge, RuleByCamera, RuleByTypeSize:
+			fo.selectExtensionsBtn.Enable()
+			fo.selectDateFormatBtn.Disable()
+			fo.selectExtensionCaseBtn.Disable()
+		}
+		fo.browseFilesBtn.Enable()
+		fo.rescanBtn.Enable()
+		// 已有选择的后缀（例如来自预设）时直接允许开始整理
+		if len(fo.FileExtensions) > 0 {
+			fo.processBtn.Enable()
+		}
+		// 流式整理时没有文件列表，不能选择后缀或浏览文件，整理所有后缀
+		if fo.streaming {
+			fo.selectExtensionsBtn.Disable()
+			fo.browseFilesBtn.Disable()
+			fo.processBtn.Enable()
+			dialog.ShowInformation("流式整理", fmt.Sprintf("扫描到 %d 个文件，超过流式整理阈值 %d。\n\n"+
+				"为节省内存没有保留文件列表，整理时将重新扫描并分批处理所有后缀的文件，无法选择后缀、浏览或排除单个文件。",
+				fo.scannedCount(), fo.StreamingThreshold), fo.Window)
+		}
+		fo.refreshFileTable()
+		// 保存当前规则选择
+		fo.saveUserConfig()
+		if afterScan := fo.afterScan; afterScan != nil {
+			fo.afterScan = nil
+			afterScan()
+		}
+	})
+}
+
+// 扫描中止后提示用户检查出错的源文件夹，只允许重新扫描
+func (n *fyneNotifier) ScanAborted(errorCount int, badSource string, sourceErrors int) {
+	fo := n.fo
+	fo.safeUpdateUI(func() {
+		fo.afterScan = nil
+		fo.rescanBtn.Enable()
+		fo.refreshFileTable()
+		dialog.ShowError(fmt.Errorf("扫描时出现 %d 个错误，已中止扫描。\n\n错误最多的源文件夹:\n%s（%d 个错误）\n\n"+
+			"该文件夹所在的磁盘可能有故障或已断开，请检查后点击「重新扫描」，或在更多设置中调高扫描错误上限。",
+			errorCount, badSource, sourceErrors), fo.Window)
+	})
+}
+
+// 提示源文件夹已不可用，并询问是否从列表中移除
+func (n *fyneNotifier) SourcesMissing(dirs []string) {
+	fo := n.fo
+	fo.safeUpdateUI(func() {
+		message := fmt.Sprintf("以下 %d 个源文件夹已不可用（可能已被删除、改名或所在磁盘已断开），其中的文件不会被整理:\n\n%s\n\n是否从源文件夹列表中移除？",
+			len(dirs), strings.Join(dirs, "\n"))
+		confirm := dialog.NewConfirm("源文件夹已不可用", message, func(remove bool) {
+			if !remove {
+				return
+			}
+			toRemove := make(map[string]bool, len(dirs))
+			for _, dir := range dirs {
+				toRemove[dir] = true
+			}
+			fo.removeSourceDirs(toRemove)
+		}, fo.Window)
+		confirm.SetConfirmText("从列表中移除")
+		confirm.SetDismissText("保留")
+		confirm.Show()
+	})
+}
+
+// 开始整理时界面不需要更新，按钮已在点击时禁用
+func (n *fyneNotifier) ProcessStarted(total int) {}
+
+// 整理过程中定期刷新界面
+func (n *fyneNotifier) ProcessProgress(processed, total int) {
+	fo := n.fo
+	fo.safeUpdateUI(func() {
+		if view := fo.queueProgress; view != nil && total > 0 {
+			view.fileBar.Max = float64(total)
+			view.fileBar.SetValue(float64(processed))
+		}
+		fo.Window.Content().Refresh()
+	})
+}
+
+// 整理完成后重新启用按钮
+func (n *fyneNotifier) ProcessFinished() {
+	fo := n.fo
+	fo.safeUpdateUI(func() {
+		fo.Window.Content().Refresh()
+		// 任务队列执行期间由队列在全部任务结束后启用按钮
+		if !fo.queueRunning.Load() {
+			fo.processBtn.Enable()
+		}
+	})
+}
+
+// consoleNotifier 无界面运行时使用，日志输出到标准错误（标准输出留给总结JSON），其余通知忽略
+type consoleNotifier struct{}
+
+// 将日志输出到标准错误
+func (consoleNotifier) AppendLog(text string) {
+	fmt.Fprint(os.Stderr, text)
+}
+
+func (consoleNotifier) ClearLog()                                                      {}
+func (consoleNotifier) ScanStarted()                                                   {}
+func (consoleNotifier) ScanFinished(rule OrganizeRule)                                 {}
+func (consoleNotifier) ScanAborted(errorCount int, badSource string, sourceErrors int) {}
+func (consoleNotifier) SourcesMissing(dirs []string)                                   {}
+func (consoleNotifier) ProcessStarted(total int)                                       {}
+func (consoleNotifier) ProcessProgress(processed, total int)                           {}
+func (consoleNotifier) ProcessFinished()                                               {}
+
+// 确保各个实现都满足接口
+var (
+	_ UINotifier = (*fyneNotifier)(nil)
+	_ UINotifier = consoleNotifier{}
+	_ UINotifier = (*statusNotifier)(nil)
+)
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventNotifier 记下引擎发出的通知，扫描结束时关闭scanned
+type eventNotifier struct {
+	UINotifier
+	mu      sync.Mutex
+	events  []string
+	scanned chan struct{}
+}
+
+func (n *eventNotifier) record(event string) {
+	n.mu.Lock()
+	n.events = append(n.events, event)
+	n.mu.Unlock()
+}
+
+func (n *eventNotifier) ScanStarted() { n.record("扫描开始") }
+func (n *eventNotifier) ScanFinished(rule OrganizeRule) {
+	n.record("扫描完成")
+	close(n.scanned)
+}
+func (n *eventNotifier) ProcessStarted(total int) { n.record("整理开始") }
+func (n *eventNotifier) ProcessFinished()         { n.record("整理完成") }
+
+// 没有图形界面时完整地扫描和整理一个临时文件夹，界面通知按顺序发出，不会卡在等待界面线程上
+func TestHeadlessPipeline(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      []string
+		extensions []string
+		want       []string // 整理后目标中的文件
+		wantSource []string // 整理后留在源文件夹中的文件
+	}{
+		{"按后缀整理", []string{"a.jpg", "b.PNG", "sub/c.jpg"}, []string{".jpg", ".png"},
+			[]string{".jpg/a.jpg", ".jpg/c.jpg", ".png/b.PNG"}, nil},
+		{"只整理所选后缀", []string{"a.jpg", "notes.txt", "deep/er/d.jpg"}, []string{".jpg"},
+			[]string{".jpg/a.jpg", ".jpg/d.jpg"}, []string{"notes.txt"}},
+		{"没有文件", nil, []string{".jpg"}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			notifier := &eventNotifier{UINotifier: fo.ui, scanned: make(chan struct{})}
+			fo.ui = notifier
+			source := t.TempDir()
+			target := t.TempDir()
+			for _, name := range tt.files {
+				writeTestFile(t, filepath.Join(source, filepath.FromSlash(name)), name)
+			}
+			fo.SourceDirs = []string{source}
+			fo.FileExtensions = tt.extensions
+			fo.OrganizeRule = RuleByExtension
+
+			fo.scanFiles()
+			select {
+			case <-notifier.scanned:
+			case <-time.After(5 * time.Second):
+				t.Fatal("扫描没有结束")
+			}
+			if len(fo.scannedFiles) != len(tt.files) {
+				t.Fatalf("扫描到 %d 个文件，期望 %d", len(fo.scannedFiles), len(tt.files))
+			}
+
+			config := Config{
+				SourceDir:      source,
+				SourceDirs:     []string{source},
+				TargetDir:      target,
+				FileExtensions: tt.extensions,
+				OrganizeRule:   string(RuleByExtension),
+				ExtensionCase:  "lowercase",
+				ExcludedFiles:  map[string]bool{},
+			}
+			if summary, err := fo.processFiles(config, fo.scannedFiles); err != nil || summary.Failed != 0 {
+				t.Fatalf("整理: %+v, %v", summary, err)
+			}
+			notifier.mu.Lock()
+			events := strings.Join(notifier.events, " → ")
+			notifier.mu.Unlock()
+			if events != "扫描开始 → 扫描完成 → 整理开始 → 整理完成" {
+				t.Fatalf("通知顺序 = %s", events)
+			}
+			if got := treeFiles(t, target); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("目标文件夹 = %v, 期望 %v", got, tt.want)
+			}
+			if got := treeFiles(t, source); !reflect.DeepEqual(got, tt.wantSource) {
+				t.Fatalf("源文件夹 = %v, 期望 %v", got, tt.wantSource)
+			}
+		})
+	}
+}
+
+// 文件夹中所有文件的相对路径（用 / 分隔），按名称排列，没有文件时为nil
+func treeFiles(t *testing.T, root string) []string {
+	t.Helper()
+	var files []string
+	for rel := range snapshotTree(t, root) {
+		files = append(files, filepath.ToSlash(rel))
+	}
+	sort.Strings(files)
+	return files
+}
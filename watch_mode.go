@@ -0,0 +1,683 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/fsnotify/fsnotify"
+)
+
+// 文件出现后等待多久没有新的写入才开始整理，避免处理仍在写入的文件
+const watchSettleDelay = 2 * time.Second
+
+// 监视文件夹未绑定预设时在界面上显示的名称
+const watchCurrentSettings = "当前设置"
+
+// 下载或同步过程中的临时文件后缀，这些文件还没有写完，不做处理
+var partialFileExtensions = map[string]bool{
+	".crdownload": true,
+	".part":       true,
+	".partial":    true,
+	".download":   true,
+	".tmp":        true,
+}
+
+// watchedRoot 一个被监视的源文件夹及其独立的配置和状态
+type watchedRoot struct {
+	root    string
+	watcher *fsnotify.Watcher
+
+	mu             sync.Mutex
+	presetName     string // 为空表示使用当前设置
+	config         Config
+	pending        map[string]*time.Timer
+	closed         bool // 已停止监视，不再安排新的整理
+	lastEvent      string
+	lastEventTime  time.Time
+	statusDay      string
+	organizedToday int
+	errorCount     int
+	lastError      string
+}
+
+// 加载监视文件夹与预设的绑定关系
+func (fo *FileOrganizer) loadWatchBindings() {
+	fo.watchBindings = make(map[string]string)
+	prefs := fyne.CurrentApp().Preferences()
+	if data := prefs.StringWithFallback("watch_bindings", ""); data != "" {
+		if err := json.Unmarshal([]byte(data), &fo.watchBindings); err != nil {
+			fo.log(fmt.Sprintf("加载监视绑定失败: %v", err))
+			fo.watchBindings = make(map[string]string)
+		}
+	}
+}
+
+// 保存监视文件夹与预设的绑定关系
+func (fo *FileOrganizer) saveWatchBindings() {
+	data, err := json.Marshal(fo.watchBindings)
+	if err != nil {
+		fo.log(fmt.Sprintf("保存监视绑定失败: %v", err))
+		return
+	}
+	fyne.CurrentApp().Preferences().SetString("watch_bindings", string(data))
+}
+
+// 获取监视文件夹使用的配置：绑定了预设时使用预设，否则使用当前设置
+func (fo *FileOrganizer) watchConfigFor(root string) (Config, string) {
+	config, name := fo.watchBaseConfig(root)
+	// 只读的监视文件夹中的新文件只复制到目标，源文件保持不变
+	config.ReadOnlySources = nil
+	if fo.readOnlySources[root] {
+		config.ReadOnlySources = []string{root}
+	}
+	return config, name
+}
+
+// 监视文件夹绑定的预设或当前设置
+func (fo *FileOrganizer) watchBaseConfig(root string) (Config, string) {
+	if name := fo.watchBindings[root]; name != "" {
+		if preset, ok := fo.findPreset(name); ok {
+			config := preset.config(root)
+			config.EmptyFilePolicy = fo.EmptyFilePolicy
+			config.ShortcutPolicy = fo.ShortcutPolicy
+			config.OrganizeLibraries = fo.OrganizeLibraries
+			if !preset.hasTextRules() {
+				config.TextLanguageFolders = fo.TextLanguageFolders
+				config.TextKeywordRules = append([]TextKeywordRule(nil), fo.TextKeywordRules...)
+				config.TextKeywordCase = fo.TextKeywordCase
+			}
+			config.TextPrefixKB = fo.TextPrefixKB
+			config.TakeoutMode = fo.TakeoutMode
+			config.LogVerbosity = fo.LogVerbosity
+			config.LowPriority = fo.LowPriority
+			config.HashShardDepth = fo.HashShardDepth
+			config.HashShardWidth = fo.HashShardWidth
+			config.HashRename = fo.HashRename
+			config.EmailSenderFolders = fo.EmailSenderFolders
+			config.ConflictPolicy = fo.ConflictPolicy
+			config.BackupReplaced = fo.BackupReplaced
+			config.PostRunHook = fo.hookCommand(fo.PostRunHook)
+			config.PerFileHook = fo.hookCommand(fo.PerFileHook)
+			config.HookTimeoutSec = fo.HookTimeoutSec
+			config.PreserveXattrs = fo.PreserveXattrs
+			return config, name
+		}
+	}
+
+	config := fo.currentConfig()
+	config.SourceDir = root
+	config.TargetDir = root
+	config.SourceDirs = []string{root}
+	config.CapacityPlan = nil
+	// 监视模式本身只处理新出现的文件，不使用仅处理新增的记录
+	config.IncomingOnly = false
+	return config, ""
+}
+
+// 获取绑定到指定预设的监视文件夹
+func (fo *FileOrganizer) watchRootsBoundTo(presetName string) []string {
+	var roots []string
+	for root, name := range fo.watchBindings {
+		if name == presetName {
+			roots = append(roots, root)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// 解除预设与监视文件夹的绑定，正在监视的文件夹改用当前设置
+func (fo *FileOrganizer) unbindPresetFromWatchRoots(presetName string) {
+	for _, root := range fo.watchRootsBoundTo(presetName) {
+		delete(fo.watchBindings, root)
+		fo.log(fmt.Sprintf("监视文件夹 %s 已解除与预设「%s」的绑定", root, presetName))
+	}
+	fo.saveWatchBindings()
+
+	fo.watchMu.Lock()
+	defer fo.watchMu.Unlock()
+	for _, wr := range fo.watchRoots {
+		fo.rebindWatchedRoot(wr)
+	}
+}
+
+// 根据当前绑定关系刷新监视文件夹的配置
+func (fo *FileOrganizer) rebindWatchedRoot(wr *watchedRoot) {
+	config, presetName := fo.watchConfigFor(wr.root)
+	wr.mu.Lock()
+	wr.config = config
+	wr.presetName = presetName
+	wr.mu.Unlock()
+}
+
+// 是否正在监视
+func (fo *FileOrganizer) isWatching() bool {
+	fo.watchMu.Lock()
+	defer fo.watchMu.Unlock()
+	return len(fo.watchRoots) > 0
+}
+
+// 开始监视所有源文件夹，每个文件夹使用独立的监视器和配置
+func (fo *FileOrganizer) startWatching() error {
+	if err := fo.checkWritable(); err != nil {
+		return err
+	}
+	fo.watchMu.Lock()
+	defer fo.watchMu.Unlock()
+
+	if len(fo.watchRoots) > 0 {
+		return nil
+	}
+
+	var roots []*watchedRoot
+	for _, root := range fo.SourceDirs {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			closeWatchedRoots(roots)
+			return fmt.Errorf("创建监视器失败: %w", err)
+		}
+		// 只监视源文件夹的第一层，整理后进入子文件夹的文件不会再次触发事件，
+		// 嵌套的监视文件夹也只会收到各自目录中的事件
+		if err := watcher.Add(root); err != nil {
+			watcher.Close()
+			closeWatchedRoots(roots)
+			return fmt.Errorf("监视 %s 失败: %w", root, err)
+		}
+
+		wr := &watchedRoot{
+			root:    root,
+			watcher: watcher,
+			pending: make(map[string]*time.Timer),
+		}
+		fo.rebindWatchedRoot(wr)
+		roots = append(roots, wr)
+		// 只读文件夹不能整理到自身之中，需要绑定目标在别处的预设
+		if err := validateReadOnlySources(wr.config); err != nil {
+			closeWatchedRoots(roots)
+			return fmt.Errorf("无法监视只读文件夹 %s: %w", root, err)
+		}
+		// 与定时整理一样，没有选择后缀时不开始
+		if len(wr.config.FileExtensions) == 0 {
+			closeWatchedRoots(roots)
+			return fmt.Errorf("无法监视 %s: 没有选择文件后缀", root)
+		}
+		if err := validateTagRule(wr.config); err != nil {
+			closeWatchedRoots(roots)
+			return fmt.Errorf("无法监视 %s: %w", root, err)
+		}
+	}
+
+	fo.watchRoots = roots
+	for _, wr := range roots {
+		go fo.runWatcher(wr)
+		presetName := wr.presetName
+		if presetName == "" {
+			presetName = watchCurrentSettings
+		}
+		fo.log(fmt.Sprintf("[监视] 开始监视 %s（%s）", wr.root, presetName))
+	}
+	return nil
+}
+
+// 停止所有监视
+func (fo *FileOrganizer) stopWatching() {
+	fo.watchMu.Lock()
+	roots := fo.watchRoots
+	fo.watchRoots = nil
+	fo.watchMu.Unlock()
+
+	if len(roots) == 0 {
+		return
+	}
+	closeWatchedRoots(roots)
+	fo.log("[监视] 已停止监视")
+}
+
+// 关闭监视器并取消尚未执行的整理任务
+func closeWatchedRoots(roots []*watchedRoot) {
+	for _, wr := range roots {
+		wr.watcher.Close()
+		wr.mu.Lock()
+		wr.closed = true
+		for path, timer := range wr.pending {
+			timer.Stop()
+			delete(wr.pending, path)
+		}
+		wr.mu.Unlock()
+	}
+}
+
+// 处理单个监视文件夹的事件
+func (fo *FileOrganizer) runWatcher(wr *watchedRoot) {
+	for {
+		select {
+		case event, ok := <-wr.watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			// 只处理属于本文件夹的事件
+			if filepath.Dir(event.Name) != filepath.Clean(wr.root) {
+				continue
+			}
+
+			wr.mu.Lock()
+			wr.lastEvent = filepath.Base(event.Name)
+			wr.lastEventTime = time.Now()
+			// 文件仍在写入时会持续产生事件，每次都重新计时
+			if timer, exists := wr.pending[event.Name]; exists {
+				timer.Reset(watchSettleDelay)
+			} else {
+				fo.scheduleWatchedFile(wr, event.Name)
+			}
+			wr.mu.Unlock()
+		case err, ok := <-wr.watcher.Errors:
+			if !ok {
+				return
+			}
+			wr.recordError(err.Error())
+			fo.log(fmt.Sprintf("[监视] %s 监视出错: %v", wr.root, err))
+		}
+	}
+}
+
+// 在文件稳定后整理，调用时需持有 wr.mu
+func (fo *FileOrganizer) scheduleWatchedFile(wr *watchedRoot, path string) {
+	wr.pending[path] = time.AfterFunc(watchSettleDelay, func() {
+		// 定时器的回调在单独的协程中运行，降低优先级只影响这个协程所在的线程
+		wr.mu.Lock()
+		lowPriority := wr.config.LowPriority
+		wr.mu.Unlock()
+		if lowPriority {
+			fo.lowerWorkerPriority()
+		}
+		fo.handleWatchedFile(wr, path)
+	})
+}
+
+// 整理监视文件夹中新出现的文件
+func (fo *FileOrganizer) handleWatchedFile(wr *watchedRoot, filePath string) {
+	wr.mu.Lock()
+	delete(wr.pending, filePath)
+	config := wr.config
+	wr.mu.Unlock()
+
+	// 处理期间计入正在进行的整理，关闭窗口时等待移动完成；正在退出时不再开始，文件留给下次监视
+	fo.activeRuns.Add(1)
+	defer fo.activeRuns.Add(-1)
+	if fo.quitting.Load() {
+		return
+	}
+
+	fileInfo, err := os.Stat(filePath)
+	if err != nil || fileInfo.IsDir() {
+		// 文件已被删除或移走，或者是文件夹
+		return
+	}
+
+	// 预设的绑定可能在监视期间改变，只读文件夹的目标仍不能位于其中，规则也仍要可用
+	if err := validateReadOnlySources(config); err != nil {
+		wr.recordError(err.Error())
+		fo.log("[监视] " + err.Error())
+		return
+	}
+	if err := validateTagRule(config); err != nil {
+		wr.recordError(err.Error())
+		fo.log("[监视] " + err.Error())
+		return
+	}
+
+	// 固定的文件留在原处
+	if fo.pins.matches(filePath, fileInfo) {
+		fo.log("[监视] 已固定，跳过: " + filePath)
+		return
+	}
+
+	// 跳过隐藏文件和仍在下载的临时文件
+	fileName := filepath.Base(filePath)
+	if strings.HasPrefix(fileName, ".") || partialFileExtensions[strings.ToLower(filepath.Ext(fileName))] {
+		return
+	}
+
+	// 同一路径上可能先后出现内容不同的文件，按内容判断的文件夹只在处理这个文件期间缓存
+	fo.textFolders.forget(filePath)
+	defer fo.textFolders.forget(filePath)
+	defer fo.contentHashes.forget(filePath)
+
+	// 与整理一样只处理选择的后缀，没有选择后缀时不整理任何文件
+	if !fo.isTargetFile(fileExtension(filePath), config.FileExtensions) {
+		return
+	}
+
+	// 快捷方式按设置跳过；整理指向的文件时监视模式只把网址快捷方式放入「链接」，其余留在原处
+	var targetDir string
+	if shortcutKind(filePath) != "" {
+		switch config.ShortcutPolicy {
+		case ShortcutSkip:
+			fo.log("[监视] 跳过快捷方式: " + filePath)
+			return
+		case ShortcutResolve:
+			link, err := resolveShortcut(filePath)
+			if err != nil || link.URL == "" {
+				fo.log("[监视] 快捷方式留在原处: " + filePath)
+				return
+			}
+			targetDir = filepath.Join(config.TargetDir, LinksFolderName)
+		}
+	}
+
+	// 空文件按设置跳过或隔离
+	switch {
+	case targetDir != "":
+		// 网址快捷方式已确定目标文件夹
+	case fileInfo.Size() == 0 && config.EmptyFilePolicy == EmptyFileSkip:
+		fo.log("[监视] 跳过空文件: " + filePath)
+		return
+	case fileInfo.Size() == 0 && config.EmptyFilePolicy == EmptyFileQuarantine:
+		targetDir = filepath.Join(config.TargetDir, EmptyFilesFolderName)
+	default:
+		targetDir = fo.planTargetDir(filePath, fileInfo, config)
+	}
+	if targetDir == "" || filepath.Clean(targetDir) == filepath.Dir(filePath) {
+		return
+	}
+
+	// 与整理一样锁定目标文件夹。本机或其他电脑正在整理同一个目标时稍后再试，锁已失效时需要用户解除
+	unlock, err := fo.lockTarget(config.TargetDir, newRunID())
+	if err != nil {
+		var locked *targetLockedError
+		if errors.As(err, &locked) && !locked.stale {
+			wr.mu.Lock()
+			if _, exists := wr.pending[filePath]; !exists && !wr.closed {
+				fo.scheduleWatchedFile(wr, filePath)
+			}
+			wr.mu.Unlock()
+			return
+		}
+		wr.recordError(fmt.Sprintf("%s: %v", fileName, err))
+		fo.log(fmt.Sprintf("[监视] 无法锁定目标文件夹，跳过 %s: %v", filePath, err))
+		return
+	}
+	defer unlock()
+
+	// 按容量分卷时放入分卷文件夹
+	if targetDir, err = watchCapacityVolume(config, filePath, fileInfo, targetDir); err != nil {
+		wr.recordError(fmt.Sprintf("%s: %v", fileName, err))
+		fo.log(fmt.Sprintf("[监视] 按容量分卷失败，跳过 %s: %v", filePath, err))
+		return
+	}
+	if filepath.Clean(targetDir) == filepath.Dir(filePath) {
+		return
+	}
+
+	// 监视期间目标文件夹可能被外部修改，移动前丢弃该文件夹的文件名索引
+	fo.nameIndex.forget(targetDir)
+
+	// 按内容哈希改名时，目标中已有同名文件就是内容相同的文件，新文件留在原处。哈希在规划目标时已计算
+	hashName := ""
+	if OrganizeRule(config.OrganizeRule) == RuleByHash && config.HashRename {
+		hash, err := fo.contentHashes.get(filePath, fileInfo)
+		if err != nil {
+			wr.recordError(fmt.Sprintf("%s: %v", fileName, err))
+			fo.log(fmt.Sprintf("[监视] 计算内容哈希失败 %s: %v", filePath, err))
+			return
+		}
+		hashName = hashFileName(hash, filePath, config.ExtensionCase)
+		if _, err := os.Stat(filepath.Join(targetDir, hashName)); err == nil {
+			fo.log(fmt.Sprintf("[监视] 跳过重复文件: %s (目标中已有: %s)", filePath, filepath.Join(targetDir, hashName)))
+			return
+		}
+	}
+
+	// 合并模式下目标中已有同名且内容相同的文件时跳过新文件，内容不同时与加时间戳一样处理
+	if config.ConflictPolicy == ConflictMerge {
+		plan := filePlan{TargetDir: targetDir}
+		fo.planConflict(&plan, filePath, fileInfo, config)
+		if plan.Kind == resultFailed {
+			wr.recordError(fmt.Sprintf("%s: %s", fileName, plan.Skip))
+		}
+		if plan.Skip != "" {
+			fo.log("[监视] " + plan.Skip)
+			return
+		}
+	}
+
+	// 覆盖模式下先移走目标中的同名文件，移动失败时放回原处
+	var displaced *displacedFile
+	if config.ConflictPolicy == ConflictOverwrite {
+		displaced, err = fo.displaceTarget(filePath, filepath.Join(targetDir, fo.normalizeName(fileName)), config, newRunID())
+		if err != nil {
+			wr.recordError(fmt.Sprintf("%s: %v", fileName, err))
+			fo.log(fmt.Sprintf("[监视] 覆盖失败 %s: %v", filePath, err))
+			fo.recordDigest(digestEvent{Kind: DigestAuto, Errors: 1})
+			return
+		}
+	}
+	// 只读监视文件夹中的文件改为复制
+	readOnlySource := isFromReadOnlySource(filePath, config)
+	transfer, action := fo.moveFile, "移动"
+	if readOnlySource {
+		transfer, action = fo.copyFile, "复制"
+	}
+	movedPath, err := transfer(filePath, targetDir, config.PreserveXattrs)
+	if err != nil && displaced != nil {
+		if restoreErr := fo.restoreDisplaced(displaced); restoreErr != nil {
+			fo.log("[监视] 警告: " + restoreErr.Error())
+		}
+	}
+	if err != nil {
+		wr.recordError(fmt.Sprintf("%s: %v", fileName, err))
+		fo.log(fmt.Sprintf("[监视] %s文件失败 %s: %v", action, filePath, err))
+		fo.recordDigest(digestEvent{Kind: DigestAuto, Errors: 1})
+		return
+	}
+	if displaced != nil {
+		if err := fo.discardDisplaced(displaced); err != nil {
+			fo.log("[监视] 警告: " + err.Error())
+		}
+		if displaced.backup {
+			fo.log(fmt.Sprintf("[监视] 覆盖前已备份: %s -> %s", displaced.original, displaced.moved))
+		} else {
+			fo.log("[监视] 已删除被覆盖的文件: " + displaced.original)
+		}
+	}
+	if hashName != "" {
+		// 移动到分片文件夹后在同一文件夹内改名，不会跨设备
+		hashedPath := filepath.Join(targetDir, hashName)
+		if err := os.Rename(movedPath, hashedPath); err != nil {
+			fo.log(fmt.Sprintf("[监视] 改名为哈希值失败 %s: %v", movedPath, err))
+		} else {
+			movedPath = hashedPath
+		}
+	}
+
+	if config.DateFolderMtime && OrganizeRule(config.OrganizeRule) == RuleByDate {
+		modTime := fo.fileDate(filePath, fileInfo, config)
+		date := time.Date(modTime.Year(), modTime.Month(), modTime.Day(), 0, 0, 0, 0, modTime.Location())
+		os.Chtimes(targetDir, date, date)
+	}
+
+	wr.mu.Lock()
+	today := time.Now().Format("2006-01-02")
+	if wr.statusDay != today {
+		wr.statusDay = today
+		wr.organizedToday = 0
+	}
+	wr.organizedToday++
+	wr.mu.Unlock()
+	folder, err := filepath.Rel(config.TargetDir, targetDir)
+	if err != nil {
+		folder = targetDir
+	}
+	fo.recordDigest(digestEvent{Kind: DigestAuto, SourceFiles: map[string]int{wr.root: 1},
+		Folders: map[string]int{filepath.ToSlash(folder): 1}, Files: 1})
+	fo.log(fmt.Sprintf("[监视] 已%s: %s -> %s", action, fileName, targetDir))
+	fo.runFileHook(config, filePath, movedPath)
+}
+
+// 记录监视文件夹的错误
+func (wr *watchedRoot) recordError(message string) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	wr.errorCount++
+	wr.lastError = message
+}
+
+// 生成监视文件夹的状态描述
+func (wr *watchedRoot) statusText() string {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	lastEvent := "无"
+	if !wr.lastEventTime.IsZero() {
+		lastEvent = fmt.Sprintf("%s %s", wr.lastEventTime.Format("15:04:05"), wr.lastEvent)
+	}
+	organizedToday := 0
+	if wr.statusDay == time.Now().Format("2006-01-02") {
+		organizedToday = wr.organizedToday
+	}
+	status := fmt.Sprintf("最近事件: %s | 今日已整理: %d | 错误: %d", lastEvent, organizedToday, wr.errorCount)
+	if wr.lastError != "" {
+		status += "\n最近错误: " + wr.lastError
+	}
+	return status
+}
+
+// 更新监视按钮的显示
+func (fo *FileOrganizer) updateWatchButton() {
+	if fo.watchBtn == nil {
+		return
+	}
+	if fo.isWatching() {
+		fo.watchBtn.SetText("监视中")
+	} else {
+		fo.watchBtn.SetText("监视模式")
+	}
+}
+
+// 显示监视模式面板
+func (fo *FileOrganizer) showWatchDialog() {
+	if len(fo.SourceDirs) == 0 {
+		dialog.ShowInformation("提示", "请先选择源文件夹", fo.Window)
+		return
+	}
+
+	options := append([]string{watchCurrentSettings}, fo.presetNames()...)
+	statusLabels := make(map[string]*widget.Label)
+	rows := container.NewVBox()
+
+	for _, root := range fo.SourceDirs {
+		root := root
+		presetSelect := widget.NewSelect(options, nil)
+		if name := fo.watchBindings[root]; name != "" {
+			presetSelect.SetSelected(name)
+		} else {
+			presetSelect.SetSelected(watchCurrentSettings)
+		}
+		presetSelect.OnChanged = func(value string) {
+			if value == watchCurrentSettings {
+				delete(fo.watchBindings, root)
+			} else {
+				fo.watchBindings[root] = value
+			}
+			fo.saveWatchBindings()
+
+			// 正在监视时立即使用新的配置
+			fo.watchMu.Lock()
+			for _, wr := range fo.watchRoots {
+				if wr.root == root {
+					fo.rebindWatchedRoot(wr)
+				}
+			}
+			fo.watchMu.Unlock()
+			fo.log(fmt.Sprintf("[监视] %s 使用: %s", root, value))
+		}
+
+		statusLabel := widget.NewLabel("未在监视")
+		statusLabel.Wrapping = fyne.TextWrapWord
+		statusLabels[root] = statusLabel
+
+		rows.Add(widget.NewCard("", root, container.NewVBox(
+			container.NewBorder(nil, nil, widget.NewLabel("使用预设:"), nil, presetSelect),
+			statusLabel,
+		)))
+	}
+
+	refreshStatus := func() {
+		fo.watchMu.Lock()
+		roots := fo.watchRoots
+		fo.watchMu.Unlock()
+		for root, label := range statusLabels {
+			label.SetText("未在监视")
+			for _, wr := range roots {
+				if wr.root == root {
+					label.SetText(wr.statusText())
+				}
+			}
+		}
+	}
+
+	var toggleBtn *widget.Button
+	toggleBtn = widget.NewButton("", func() {
+		if fo.isWatching() {
+			fo.stopWatching()
+		} else if err := fo.startWatching(); err != nil {
+			dialog.ShowError(err, fo.Window)
+		}
+		if fo.isWatching() {
+			toggleBtn.SetText("停止监视")
+		} else {
+			toggleBtn.SetText("开始监视")
+		}
+		fo.updateWatchButton()
+		refreshStatus()
+	})
+	if fo.isWatching() {
+		toggleBtn.SetText("停止监视")
+	} else {
+		toggleBtn.SetText("开始监视")
+	}
+
+	hint := widget.NewLabel("监视源文件夹中新出现的文件，并按各文件夹使用的预设整理到该文件夹内。\n未选择文件后缀的配置会整理所有文件。")
+	hint.Wrapping = fyne.TextWrapWord
+
+	scroll := container.NewVScroll(rows)
+	scroll.SetMinSize(fyne.NewSize(600, 320))
+	digestBtn := widget.NewButton("今日摘要", fo.showDailyDigest)
+	content := container.NewBorder(hint, container.NewGridWithColumns(2, toggleBtn, digestBtn), nil, nil, scroll)
+
+	// 面板打开期间每秒刷新一次状态
+	stopRefresh := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fo.safeUpdateUI(refreshStatus)
+			case <-stopRefresh:
+				return
+			}
+		}
+	}()
+
+	watchDialog := dialog.NewCustom("监视模式", "关闭", content, fo.Window)
+	watchDialog.SetOnClosed(func() {
+		close(stopRefresh)
+	})
+	refreshStatus()
+	fo.showDialog(watchDialog, nil)
+}
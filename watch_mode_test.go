@@ -0,0 +1,286 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 只读的监视文件夹中的新文件只复制，目标位于只读文件夹之中时不整理
+func TestWatchReadOnlySource(t *testing.T) {
+	tests := []struct {
+		name         string
+		targetInRoot bool
+		wantCopy     bool
+	}{
+		{"目标在别处", false, true},
+		{"目标在只读文件夹中", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			root := t.TempDir()
+			target := t.TempDir()
+			if tt.targetInRoot {
+				target = root
+			}
+			incoming := writeTestFile(t, filepath.Join(root, "a.jpg"), "camera")
+			wr := &watchedRoot{root: root, pending: make(map[string]*time.Timer), config: Config{
+				SourceDir:       root,
+				SourceDirs:      []string{root},
+				ReadOnlySources: []string{root},
+				TargetDir:       target,
+				FileExtensions:  []string{".jpg"},
+				OrganizeRule:    string(RuleByExtension),
+				ExtensionCase:   "lowercase",
+				ExcludedFiles:   map[string]bool{},
+			}}
+			fo.handleWatchedFile(wr, incoming)
+
+			if got := readTestFile(t, incoming); got != "camera" {
+				t.Fatalf("源文件 = %q", got)
+			}
+			_, err := os.Stat(filepath.Join(target, ".jpg", "a.jpg"))
+			if (err == nil) != tt.wantCopy {
+				t.Fatalf("目标中的副本: %v", err)
+			}
+			if !tt.wantCopy && wr.errorCount != 1 {
+				t.Fatalf("错误数 = %d", wr.errorCount)
+			}
+		})
+	}
+}
+
+// 合并模式下目标中已有同名且内容相同的文件时新文件留在原处，内容不同时加时间戳
+func TestWatchMergeIdentical(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  string
+		wantKept  bool // 新文件留在监视文件夹中
+		wantFiles int  // 整理后目标文件夹中的文件数
+	}{
+		{"内容相同", "camera", true, 1},
+		{"内容不同", "other", false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			root := t.TempDir()
+			target := t.TempDir()
+			writeTestFile(t, filepath.Join(target, ".jpg", "a.jpg"), tt.existing)
+			incoming := writeTestFile(t, filepath.Join(root, "a.jpg"), "camera")
+			wr := &watchedRoot{root: root, pending: make(map[string]*time.Timer), config: Config{
+				SourceDir:      root,
+				SourceDirs:     []string{root},
+				TargetDir:      target,
+				FileExtensions: []string{".jpg"},
+				OrganizeRule:   string(RuleByExtension),
+				ExtensionCase:  "lowercase",
+				ConflictPolicy: ConflictMerge,
+				ExcludedFiles:  map[string]bool{},
+			}}
+			fo.handleWatchedFile(wr, incoming)
+
+			if _, err := os.Stat(incoming); (err == nil) != tt.wantKept {
+				t.Fatalf("新文件留在原处 = %v, 期望 %v", err == nil, tt.wantKept)
+			}
+			entries, err := os.ReadDir(filepath.Join(target, ".jpg"))
+			if err != nil || len(entries) != tt.wantFiles {
+				t.Fatalf("目标文件夹中有 %d 个文件, 期望 %d: %v", len(entries), tt.wantFiles, err)
+			}
+			if readTestFile(t, filepath.Join(target, ".jpg", "a.jpg")) != tt.existing {
+				t.Fatal("目标中已有的文件被修改")
+			}
+		})
+	}
+}
+
+// 监视模式按容量分卷：新文件放入第一个放得下的分卷，都放不下时新建分卷，已在分卷中的文件不换分卷
+func TestWatchCapacityVolume(t *testing.T) {
+	const kb = 1 << 10
+	tests := []struct {
+		name     string
+		existing map[string]int // 目标中已有的文件 → 大小（KB）
+		file     string         // 相对于监视文件夹
+		size     int
+		want     string
+	}{
+		{"没有分卷", nil, "a.jpg", 100, filepath.Join("VOL_001", ".jpg", "a.jpg")},
+		{"补入已有的分卷", map[string]int{filepath.Join("VOL_001", "x.bin"): 500}, "a.jpg", 100, filepath.Join("VOL_001", ".jpg", "a.jpg")},
+		{"已有的分卷放不下", map[string]int{filepath.Join("VOL_001", "x.bin"): 1000}, "a.jpg", 100, filepath.Join("VOL_002", ".jpg", "a.jpg")},
+		{"已在分卷中", map[string]int{filepath.Join("VOL_001", "x.bin"): 1000}, filepath.Join("VOL_002", "a.jpg"), 100, filepath.Join("VOL_002", ".jpg", "a.jpg")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			root := t.TempDir()
+			for name, size := range tt.existing {
+				writeTestFile(t, filepath.Join(root, name), strings.Repeat("x", size*kb))
+			}
+			incoming := writeTestFile(t, filepath.Join(root, tt.file), strings.Repeat("x", tt.size*kb))
+			wr := &watchedRoot{root: root, pending: make(map[string]*time.Timer), config: Config{
+				SourceDir:      root,
+				SourceDirs:     []string{root},
+				TargetDir:      root,
+				FileExtensions: []string{".jpg"},
+				OrganizeRule:   string(RuleByExtension),
+				ExtensionCase:  "lowercase",
+				VolumeCapMB:    1,
+				ExcludedFiles:  map[string]bool{},
+			}}
+			fo.handleWatchedFile(wr, incoming)
+			if _, err := os.Stat(filepath.Join(root, tt.want)); err != nil {
+				t.Fatalf("整理后的文件: %v", err)
+			}
+		})
+	}
+}
+
+// 绑定了预设的监视文件夹同样在每个文件整理后运行文件命令
+func TestWatchPerFileHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("命令使用 POSIX shell 语法")
+	}
+	fo := newTestOrganizer(t)
+	root := t.TempDir()
+	fo.HooksEnabled = true
+	fo.PerFileHook = "touch {path}.hooked"
+	fo.presets = []Preset{{Name: "p", OrganizeRule: string(RuleByExtension), FileExtensions: []string{"jpg"}, ExtensionCase: "lowercase"}}
+	fo.watchBindings = map[string]string{root: "p"}
+	config, _ := fo.watchConfigFor(root)
+	incoming := writeTestFile(t, filepath.Join(root, "a.jpg"), "photo")
+	wr := &watchedRoot{root: root, pending: make(map[string]*time.Timer), config: config}
+	fo.handleWatchedFile(wr, incoming)
+	if _, err := os.Stat(filepath.Join(root, ".jpg", "a.jpg.hooked")); err != nil {
+		t.Fatalf("文件命令没有运行: %v", err)
+	}
+}
+
+// 正在退出时监视文件夹中的新文件留在原处，处理期间计入正在进行的整理
+func TestWatchWhileQuitting(t *testing.T) {
+	tests := []struct {
+		name     string
+		quitting bool
+		wantMove bool
+	}{
+		{"正常监视", false, true},
+		{"正在退出", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			fo.quitting.Store(tt.quitting)
+			root := t.TempDir()
+			incoming := writeTestFile(t, filepath.Join(root, "a.jpg"), "camera")
+			wr := &watchedRoot{root: root, pending: make(map[string]*time.Timer), config: Config{
+				SourceDir:      root,
+				SourceDirs:     []string{root},
+				TargetDir:      root,
+				FileExtensions: []string{".jpg"},
+				OrganizeRule:   string(RuleByExtension),
+				ExtensionCase:  "lowercase",
+				ExcludedFiles:  map[string]bool{},
+			}}
+			fo.handleWatchedFile(wr, incoming)
+			if _, err := os.Stat(filepath.Join(root, ".jpg", "a.jpg")); (err == nil) != tt.wantMove {
+				t.Fatalf("移动 = %v, 期望 %v", err == nil, tt.wantMove)
+			}
+			if fo.runActive() {
+				t.Fatal("处理结束后仍计为正在整理")
+			}
+		})
+	}
+}
+
+// 没有选择后缀时监视不整理任何文件，也不能开始监视
+func TestWatchExtensions(t *testing.T) {
+	tests := []struct {
+		name       string
+		extensions []string
+		wantMove   bool
+	}{
+		{"选择了后缀", []string{".jpg"}, true},
+		{"后缀不符", []string{".png"}, false},
+		{"没有选择后缀", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			root := t.TempDir()
+			incoming := writeTestFile(t, filepath.Join(root, "a.jpg"), "camera")
+			wr := &watchedRoot{root: root, pending: make(map[string]*time.Timer), config: Config{
+				SourceDir:      root,
+				SourceDirs:     []string{root},
+				TargetDir:      root,
+				FileExtensions: tt.extensions,
+				OrganizeRule:   string(RuleByExtension),
+				ExtensionCase:  "lowercase",
+				ExcludedFiles:  map[string]bool{},
+			}}
+			fo.handleWatchedFile(wr, incoming)
+			if _, err := os.Stat(filepath.Join(root, ".jpg", "a.jpg")); (err == nil) != tt.wantMove {
+				t.Fatalf("移动 = %v, 期望 %v", err == nil, tt.wantMove)
+			}
+
+			fo.SourceDirs = []string{root}
+			fo.presets = []Preset{{Name: "p", OrganizeRule: string(RuleByExtension), FileExtensions: tt.extensions, ExtensionCase: "lowercase"}}
+			fo.watchBindings = map[string]string{root: "p"}
+			err := fo.startWatching()
+			fo.stopWatching()
+			if (err == nil) != (len(tt.extensions) > 0) {
+				t.Fatalf("开始监视: %v", err)
+			}
+		})
+	}
+}
+
+// 监视按内容哈希改名时，目标中已有相同内容的文件则新文件留在原处，不在目标中留下重复的文件
+func TestWatchHashRename(t *testing.T) {
+	const hashA = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	hashed := filepath.Join("ca", "97", hashA+".jpg")
+	tests := []struct {
+		name     string
+		existing bool
+		wantKept bool
+	}{
+		{"改名为哈希值", false, false},
+		{"目标中已有相同内容", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fo := newTestOrganizer(t)
+			root := t.TempDir()
+			target := t.TempDir()
+			if tt.existing {
+				writeTestFile(t, filepath.Join(target, hashed), "a")
+			}
+			incoming := writeTestFile(t, filepath.Join(root, "a.jpg"), "a")
+			wr := &watchedRoot{root: root, pending: make(map[string]*time.Timer), config: Config{
+				SourceDir:      root,
+				SourceDirs:     []string{root},
+				TargetDir:      target,
+				FileExtensions: []string{".jpg"},
+				OrganizeRule:   string(RuleByHash),
+				HashShardDepth: 2,
+				HashShardWidth: 2,
+				HashRename:     true,
+				ExtensionCase:  "lowercase",
+				ExcludedFiles:  map[string]bool{},
+			}}
+			fo.handleWatchedFile(wr, incoming)
+
+			if _, err := os.Stat(incoming); (err == nil) != tt.wantKept {
+				t.Fatalf("源文件保留 = %v, 期望 %v", err == nil, tt.wantKept)
+			}
+			if got := snapshotTree(t, target); len(got) != 1 || got[hashed] != "a" {
+				t.Fatalf("目标文件夹 = %v", got)
+			}
+			if n := len(fo.contentHashes.hashes); n != 0 {
+				t.Fatalf("处理后仍缓存了 %d 个哈希", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+)
+
+// 窗口的默认大小，固定大小模式下始终使用这个大小
+const (
+	defaultWindowWidth  = 880
+	defaultWindowHeight = 745
+)
+
+// 可调整大小时窗口的最小大小，再小布局就放不下了，内容会出现滚动条
+const (
+	minWindowWidth  = 640
+	minWindowHeight = 480
+)
+
+// 启动时的窗口大小：可调整大小时恢复上次关闭时的大小
+func (fo *FileOrganizer) initialWindowSize() fyne.Size {
+	size := fyne.NewSize(defaultWindowWidth, defaultWindowHeight)
+	if !fo.WindowResizable {
+		return size
+	}
+	prefs := fyne.CurrentApp().Preferences()
+	width := float32(prefs.FloatWithFallback("window_width", 0))
+	height := float32(prefs.FloatWithFallback("window_height", 0))
+	if width >= minWindowWidth && height >= minWindowHeight {
+		size = fyne.NewSize(width, height)
+	}
+	return size
+}
+
+// 保存当前窗口大小，下次启动时恢复
+func (fo *FileOrganizer) saveWindowSize() {
+	if !fo.WindowResizable {
+		return
+	}
+	size := fo.Window.Canvas().Size()
+	if size.Width < minWindowWidth || size.Height < minWindowHeight {
+		return
+	}
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetFloat("window_width", float64(size.Width))
+	prefs.SetFloat("window_height", float64(size.Height))
+}
+
+// 按设置切换固定大小或可调整大小，切换到固定大小时恢复默认大小
+func (fo *FileOrganizer) applyWindowResizable() {
+	fo.Window.SetFixedSize(!fo.WindowResizable)
+	if !fo.WindowResizable {
+		fo.Window.Resize(fyne.NewSize(defaultWindowWidth, defaultWindowHeight))
+	}
+}
+
+// 给窗口内容加上最小大小，避免窗口被缩得过小
+func withMinWindowSize(content fyne.CanvasObject) fyne.CanvasObject {
+	minSize := canvas.NewRectangle(color.Transparent)
+	minSize.SetMinSize(fyne.NewSize(minWindowWidth, minWindowHeight))
+	return container.NewStack(minSize, content)
+}